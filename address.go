@@ -0,0 +1,214 @@
+package poodle
+
+import (
+	"encoding/json"
+	"net/mail"
+	"strings"
+)
+
+// Address is an email address with an optional display name
+type Address struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email"`
+}
+
+// ParseAddress parses a single address such as "jane@example.com" or
+// "Jane Doe <jane@example.com>" and validates it
+func ParseAddress(s string) (*Address, error) {
+	parsed, err := mail.ParseAddress(s)
+	if err != nil {
+		return nil, NewValidationError("Invalid address", map[string][]string{
+			"email": {"Address is not a valid email"},
+		})
+	}
+
+	address := AddressFromMail(parsed)
+	if err := address.Validate(); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// MustParseAddress is like ParseAddress but panics if the address is
+// invalid. It is meant for addresses known at compile time.
+func MustParseAddress(s string) *Address {
+	address, err := ParseAddress(s)
+	if err != nil {
+		panic("poodle: invalid address " + s + ": " + err.Error())
+	}
+	return address
+}
+
+// AddressFromMail converts a net/mail address. net/mail removes the quotes
+// from a local part such as "john smith", so they are added back.
+func AddressFromMail(m *mail.Address) *Address {
+	return &Address{Name: m.Name, Email: quoteLocalPart(m.Address)}
+}
+
+// MailAddress converts the address to a net/mail address
+func (a *Address) MailAddress() *mail.Address {
+	return &mail.Address{Name: a.Name, Address: unquoteLocalPart(a.Email)}
+}
+
+// Validate checks that the email is valid, with DefaultValidator, and that
+// the name cannot break out of the header it is used in
+func (a *Address) Validate() error {
+	return a.validate(DefaultValidator)
+}
+
+// validate is Validate with the given address validator
+func (a *Address) validate(validator *Validator) error {
+	errors := make(map[string][]string)
+
+	if strings.TrimSpace(a.Email) == "" {
+		errors["email"] = append(errors["email"], "Address is required")
+	} else if !validator.IsValid(a.Email) {
+		errors["email"] = append(errors["email"], "Address is not a valid email")
+	}
+
+	if strings.ContainsAny(a.Name, "\r\n") {
+		errors["name"] = append(errors["name"], "Name must not contain line breaks")
+	}
+
+	if len(errors) > 0 {
+		return NewValidationError("Invalid address", errors)
+	}
+	return nil
+}
+
+// String returns the address in the form Name <email>, quoting the name if
+// it contains characters other than letters, digits, spaces and the
+// symbols allowed in an unquoted name. Without a name only the email is
+// returned. Non-ASCII names are kept as UTF-8.
+func (a *Address) String() string {
+	email := strings.TrimSpace(a.Email)
+	name := strings.TrimSpace(a.Name)
+	if name == "" {
+		return email
+	}
+
+	if !needsQuoting(name) {
+		return name + " <" + email + ">"
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range name {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString(`" <`)
+	b.WriteString(email)
+	b.WriteByte('>')
+	return b.String()
+}
+
+// MarshalJSON encodes the address as a string, the form the API accepts
+// for the from and to fields
+func (a Address) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON decodes an address from a string such as
+// "Jane <jane@example.com>" or from an object with name and email fields.
+// The address is not validated, so that stored emails always decode;
+// Validate reports invalid addresses.
+func (a *Address) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = addressOf(s)
+		return nil
+	}
+
+	type plain Address
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*a = Address(p)
+	return nil
+}
+
+// addressOf parses s leniently: if s is not a parsable address, the
+// trimmed string is used as the email, for Validate to report
+func addressOf(s string) Address {
+	if parsed, err := mail.ParseAddress(s); err == nil {
+		return *AddressFromMail(parsed)
+	}
+	return Address{Email: strings.TrimSpace(s)}
+}
+
+// quoteLocalPart quotes the local part of an address if it is not a
+// dot-atom (RFC 5322 section 3.4.1), as net/mail returns it unquoted
+func quoteLocalPart(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 || isDotAtom(email[:at]) {
+		return email
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range email[:at] {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	b.WriteString(email[at:])
+	return b.String()
+}
+
+// unquoteLocalPart reverses quoteLocalPart
+func unquoteLocalPart(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 2 || email[0] != '"' || email[at-1] != '"' {
+		return email
+	}
+
+	var b strings.Builder
+	escaped := false
+	for _, r := range email[1 : at-1] {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	b.WriteString(email[at:])
+	return b.String()
+}
+
+// isDotAtom reports whether s is a dot-atom, which needs no quotes
+func isDotAtom(s string) bool {
+	if s == "" || strings.HasPrefix(s, ".") || strings.HasSuffix(s, ".") || strings.Contains(s, "..") {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune(".!#$%&'*+-/=?^_`{|}~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// needsQuoting reports whether a display name contains characters that
+// are only allowed in a quoted string (RFC 5322 section 3.2.3)
+func needsQuoting(name string) bool {
+	for _, r := range name {
+		switch {
+		case r >= 0x80, r == ' ':
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r):
+		default:
+			return true
+		}
+	}
+	return false
+}
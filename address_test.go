@@ -0,0 +1,175 @@
+package poodle
+
+import (
+	"encoding/json"
+	"net/mail"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Address
+		valid bool
+	}{
+		{"jane@example.com", Address{Email: "jane@example.com"}, true},
+		{"Jane Doe <jane@example.com>", Address{Name: "Jane Doe", Email: "jane@example.com"}, true},
+		{`"Doe, Jane" <jane@example.com>`, Address{Name: "Doe, Jane", Email: "jane@example.com"}, true},
+		{"Zoë <zoe@example.com>", Address{Name: "Zoë", Email: "zoe@example.com"}, true},
+		{"Jane <jane@localhost>", Address{}, false},
+		{"not an address", Address{}, false},
+		{"", Address{}, false},
+	}
+
+	for _, tt := range tests {
+		address, err := ParseAddress(tt.input)
+		if !tt.valid {
+			if _, ok := err.(*ValidationError); !ok {
+				t.Errorf("ParseAddress(%q): expected ValidationError, got %v", tt.input, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAddress(%q): unexpected error: %v", tt.input, err)
+			continue
+		}
+		if *address != tt.want {
+			t.Errorf("ParseAddress(%q) = %+v, want %+v", tt.input, *address, tt.want)
+		}
+	}
+}
+
+func TestMustParseAddressPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected MustParseAddress to panic on an invalid address")
+		}
+	}()
+	MustParseAddress("invalid")
+}
+
+func TestAddressString(t *testing.T) {
+	tests := []struct {
+		address Address
+		want    string
+	}{
+		{Address{Email: "jane@example.com"}, "jane@example.com"},
+		{Address{Name: "Jane Doe", Email: "jane@example.com"}, "Jane Doe <jane@example.com>"},
+		{Address{Name: "Doe, Jane", Email: "jane@example.com"}, `"Doe, Jane" <jane@example.com>`},
+		{Address{Name: `Jane "JD" Doe`, Email: "jane@example.com"}, `"Jane \"JD\" Doe" <jane@example.com>`},
+		{Address{Name: "Zoë", Email: "zoe@example.com"}, "Zoë <zoe@example.com>"},
+	}
+
+	for _, tt := range tests {
+		got := tt.address.String()
+		if got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+
+		parsed, err := ParseAddress(got)
+		if err != nil || *parsed != tt.address {
+			t.Errorf("Expected %s to parse back to %+v, got %+v (%v)", got, tt.address, parsed, err)
+		}
+	}
+}
+
+func TestAddressValidate(t *testing.T) {
+	if err := (&Address{Name: "Jane", Email: "jane@example.com"}).Validate(); err != nil {
+		t.Errorf("Expected valid address, got: %v", err)
+	}
+
+	err := (&Address{Name: "Jane\r\nBcc: x@example.com", Email: "jane"}).Validate()
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %T", err)
+	}
+	if len(validationErr.Errors["email"]) != 1 || len(validationErr.Errors["name"]) != 1 {
+		t.Errorf("Expected errors on email and name, got %v", validationErr.Errors)
+	}
+}
+
+func TestAddressJSON(t *testing.T) {
+	data, err := json.Marshal(Address{Name: "Jane Doe", Email: "jane@example.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil || s != "Jane Doe <jane@example.com>" {
+		t.Errorf("Expected address to marshal as a string, got %s", data)
+	}
+
+	want := Address{Name: "Jane Doe", Email: "jane@example.com"}
+	for _, input := range []string{
+		string(data),
+		`"Jane Doe <jane@example.com>"`,
+		`{"name":"Jane Doe","email":"jane@example.com"}`,
+	} {
+		var address Address
+		if err := json.Unmarshal([]byte(input), &address); err != nil {
+			t.Errorf("Unmarshal(%s): unexpected error: %v", input, err)
+			continue
+		}
+		if address != want {
+			t.Errorf("Unmarshal(%s) = %+v, want %+v", input, address, want)
+		}
+	}
+
+	var address Address
+	if err := json.Unmarshal([]byte(`42`), &address); err == nil {
+		t.Error("Expected an error for a number")
+	}
+}
+
+func TestAddressMailConversion(t *testing.T) {
+	address := AddressFromMail(&mail.Address{Name: "Jane", Address: "jane@example.com"})
+	if *address != (Address{Name: "Jane", Email: "jane@example.com"}) {
+		t.Errorf("Unexpected address %+v", address)
+	}
+	if m := address.MailAddress(); m.Name != "Jane" || m.Address != "jane@example.com" {
+		t.Errorf("Unexpected mail address %+v", m)
+	}
+}
+
+func TestEmailAddressFields(t *testing.T) {
+	email := NewTextEmail("ignored", "", "Subject", "Hello")
+	email.FromAddress = MustParseAddress("Acme Support <support@example.com>")
+	email.ToAddress = &Address{Name: "Doe, Jane", Email: "jane@example.com"}
+
+	if err := email.Validate(); err != nil {
+		t.Fatalf("Expected address fields to take precedence, got: %v", err)
+	}
+
+	data, err := json.Marshal(email)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var wire map[string]interface{}
+	json.Unmarshal(data, &wire)
+	if wire["from"] != "Acme Support <support@example.com>" || wire["to"] != `"Doe, Jane" <jane@example.com>` {
+		t.Errorf("Expected address fields on the wire, got %s", data)
+	}
+
+	// The encoded email decodes into string fields that validate the same
+	var decoded Email
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := decoded.Validate(); err != nil {
+		t.Errorf("Expected decoded email to be valid, got: %v", err)
+	}
+	if !decoded.Equal(email) {
+		t.Error("Expected decoded email to have the same fingerprint")
+	}
+
+	clone := email.clone()
+	clone.ToAddress.Name = "Changed"
+	if email.ToAddress.Name != "Doe, Jane" {
+		t.Error("Expected clone to copy the address")
+	}
+
+	email.ToAddress.Email = "invalid"
+	validationErr, ok := email.Validate().(*ValidationError)
+	if !ok || len(validationErr.Errors["to"]) != 1 {
+		t.Errorf("Expected an error on the to field, got %v", email.Validate())
+	}
+}
@@ -0,0 +1,246 @@
+package poodle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultArchiveQueueSize is the number of sent emails buffered for the
+// archiver before further emails are dropped
+const DefaultArchiveQueueSize = 1000
+
+// Errors reported to Config.OnArchiveError for emails that were sent but
+// not handed to the archiver
+var (
+	ErrArchiveQueueFull = errors.New("poodle: archive queue is full")
+	ErrClientClosed     = errors.New("poodle: client is closed")
+)
+
+// Archiver persists a copy of every successfully sent email. Store is
+// called from a single background goroutine in send order.
+type Archiver interface {
+	Store(ctx context.Context, email *Email, resp *EmailResponse) error
+}
+
+// ArchiveRecord is a single line written by WriterArchiver and FileArchiver
+type ArchiveRecord struct {
+	ArchivedAt time.Time      `json:"archived_at"`
+	Email      *Email         `json:"email"`
+	Response   *EmailResponse `json:"response"`
+}
+
+// WriterArchiver writes archived emails to an io.Writer as JSON lines,
+// e.g. to feed an existing log pipeline
+type WriterArchiver struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterArchiver creates an archiver writing JSON lines to w
+func NewWriterArchiver(w io.Writer) *WriterArchiver {
+	return &WriterArchiver{writer: w}
+}
+
+// Store implements Archiver
+func (a *WriterArchiver) Store(ctx context.Context, email *Email, resp *EmailResponse) error {
+	line, err := encodeArchiveRecord(email, resp)
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	_, err = a.writer.Write(line)
+	return err
+}
+
+// FileArchiver appends archived emails to a JSON-lines file. When the file
+// would grow beyond MaxBytes it is renamed with a timestamp suffix and a new
+// file is started.
+type FileArchiver struct {
+	path     string
+	maxBytes int64
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+// NewFileArchiver opens or creates the archive file at path. A maxBytes of
+// zero disables rotation.
+func NewFileArchiver(path string, maxBytes int64) (*FileArchiver, error) {
+	a := &FileArchiver{path: path, maxBytes: maxBytes}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Store implements Archiver
+func (a *FileArchiver) Store(ctx context.Context, email *Email, resp *EmailResponse) error {
+	line, err := encodeArchiveRecord(email, resp)
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.file == nil {
+		return fmt.Errorf("poodle: archive file %s is closed", a.path)
+	}
+
+	if a.maxBytes > 0 && a.size > 0 && a.size+int64(len(line)) > a.maxBytes {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	return err
+}
+
+// Close closes the archive file
+func (a *FileArchiver) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// open opens the archive file for appending and records its size
+func (a *FileArchiver) open() error {
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	a.file = file
+	a.size = info.Size()
+	return nil
+}
+
+// rotate moves the current file aside and starts a new one
+func (a *FileArchiver) rotate() error {
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	a.file = nil
+
+	rotated := a.path + "." + time.Now().UTC().Format("20060102T150405.000000000")
+	if err := os.Rename(a.path, rotated); err != nil {
+		return err
+	}
+
+	return a.open()
+}
+
+func encodeArchiveRecord(email *Email, resp *EmailResponse) ([]byte, error) {
+	line, err := json.Marshal(ArchiveRecord{
+		ArchivedAt: time.Now().UTC(),
+		Email:      email,
+		Response:   resp,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// archiveItem is a sent email waiting to be archived
+type archiveItem struct {
+	email *Email
+	resp  *EmailResponse
+}
+
+// archiveQueue hands sent emails to an Archiver on a background goroutine
+// so that archival never blocks or fails a send
+type archiveQueue struct {
+	archiver Archiver
+	onError  func(*Email, error)
+	items    chan archiveItem
+	done     chan struct{}
+
+	mutex  sync.RWMutex
+	closed bool
+}
+
+func newArchiveQueue(archiver Archiver, size int, onError func(*Email, error)) *archiveQueue {
+	if size <= 0 {
+		size = DefaultArchiveQueueSize
+	}
+
+	q := &archiveQueue{
+		archiver: archiver,
+		onError:  onError,
+		items:    make(chan archiveItem, size),
+		done:     make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// enqueue queues a sent email for archival. If the queue is full the email
+// is dropped and reported to the error callback.
+func (q *archiveQueue) enqueue(email *Email, resp *EmailResponse) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	if q.closed {
+		q.report(email, ErrClientClosed)
+		return
+	}
+
+	select {
+	case q.items <- archiveItem{email: email.clone(), resp: resp}:
+	default:
+		q.report(email, ErrArchiveQueueFull)
+	}
+}
+
+func (q *archiveQueue) run() {
+	defer close(q.done)
+
+	for item := range q.items {
+		if err := q.archiver.Store(context.Background(), item.email, item.resp); err != nil {
+			q.report(item.email, err)
+		}
+	}
+}
+
+func (q *archiveQueue) report(email *Email, err error) {
+	if q.onError != nil {
+		q.onError(email, err)
+	}
+}
+
+// close stops accepting emails and waits until the queued ones are archived
+func (q *archiveQueue) close() {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.items)
+	}
+	q.mutex.Unlock()
+
+	<-q.done
+}
@@ -0,0 +1,177 @@
+package poodle
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingArchiver records archived emails and blocks until released
+type blockingArchiver struct {
+	mutex   sync.Mutex
+	release chan struct{}
+	stored  []string
+}
+
+func (a *blockingArchiver) Store(ctx context.Context, email *Email, resp *EmailResponse) error {
+	<-a.release
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.stored = append(a.stored, email.Subject)
+	return nil
+}
+
+func newArchiveTestClient(config *Config) *Client {
+	config.APIKey = "test_api_key"
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+	return client
+}
+
+func TestArchiveOrdering(t *testing.T) {
+	var buffer bytes.Buffer
+	config := NewConfig()
+	config.Archive = NewWriterArchiver(&buffer)
+	client := newArchiveTestClient(config)
+
+	for i := 0; i < 20; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", fmt.Sprintf("Email %d", i), "Hello"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Expected no error closing client, got: %v", err)
+	}
+
+	scanner := bufio.NewScanner(&buffer)
+	i := 0
+	for scanner.Scan() {
+		var record ArchiveRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Expected a JSON line, got: %v", err)
+		}
+		if record.Email.Subject != fmt.Sprintf("Email %d", i) {
+			t.Errorf("Expected 'Email %d', got '%s'", i, record.Email.Subject)
+		}
+		if record.Response == nil || !record.Response.Success {
+			t.Errorf("Expected the response to be archived, got %+v", record.Response)
+		}
+		i++
+	}
+	if i != 20 {
+		t.Errorf("Expected 20 archived emails, got %d", i)
+	}
+}
+
+func TestArchiveBackpressure(t *testing.T) {
+	archiver := &blockingArchiver{release: make(chan struct{})}
+
+	var mutex sync.Mutex
+	var dropped []error
+	config := NewConfig()
+	config.Archive = archiver
+	config.ArchiveQueueSize = 2
+	config.OnArchiveError = func(email *Email, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		dropped = append(dropped, err)
+	}
+	client := newArchiveTestClient(config)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			if _, err := client.SendText("from@example.com", "to@example.com", fmt.Sprintf("Email %d", i), "Hello"); err != nil {
+				t.Errorf("Expected archival not to fail the send, got: %v", err)
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a slow archiver not to block sends")
+	}
+
+	close(archiver.release)
+	client.Close()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	archiver.mutex.Lock()
+	defer archiver.mutex.Unlock()
+
+	// One email is held by the archiver and two are queued
+	if len(archiver.stored)+len(dropped) != 10 || len(archiver.stored) > 3 {
+		t.Errorf("Expected overflow to be dropped, got %d stored and %d dropped", len(archiver.stored), len(dropped))
+	}
+	for _, err := range dropped {
+		if !errors.Is(err, ErrArchiveQueueFull) {
+			t.Errorf("Expected ErrArchiveQueueFull, got: %v", err)
+		}
+	}
+}
+
+func TestArchiveAfterClose(t *testing.T) {
+	var buffer bytes.Buffer
+	var reported error
+	config := NewConfig()
+	config.Archive = NewWriterArchiver(&buffer)
+	config.OnArchiveError = func(email *Email, err error) { reported = err }
+	client := newArchiveTestClient(config)
+
+	client.Close()
+	client.Close()
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected the send to succeed after Close, got: %v", err)
+	}
+	if !errors.Is(reported, ErrClientClosed) || buffer.Len() != 0 {
+		t.Errorf("Expected the email not to be archived after Close, got %v", reported)
+	}
+}
+
+func TestFileArchiverRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "archive.jsonl")
+
+	archiver, err := NewFileArchiver(path, 400)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer archiver.Close()
+
+	email := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	resp := NewEmailResponse(true, "Email queued")
+	for i := 0; i < 5; i++ {
+		if err := archiver.Store(context.Background(), email, resp); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "archive.jsonl*"))
+	if len(files) < 2 {
+		t.Fatalf("Expected the archive to be rotated, got %v", files)
+	}
+	for _, file := range files {
+		info, _ := os.Stat(file)
+		if info.Size() > 400 {
+			t.Errorf("Expected %s to stay within 400 bytes, got %d", file, info.Size())
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package poodle
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides the delay before each retry of a failed send. The
+// client only consults it for retryable errors (see IsRetryable), within
+// Config.MaxRetries, and not for rate limits unless WaitOnRateLimit is set.
+// Policies are shared by concurrent sends and must be safe for concurrent
+// use.
+type RetryPolicy interface {
+	// NextDelay returns how long to wait after the given attempt, starting
+	// at 1, failed with err, and false to stop retrying
+	NextDelay(attempt int, err error) (time.Duration, bool)
+}
+
+// RetryAfter returns the delay the API asked for with a Retry-After
+// header, on a RateLimitError or QueueError, and false if err has none.
+// For a RateLimitError without Retry-After, it is the time until the
+// rate-limit window resets, if known. Policies use it to wait as long as
+// the API says.
+func RetryAfter(err error) (time.Duration, bool) {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) && (rateLimitErr.RetryAfter > 0 || rateLimitErr.ResetIn > 0) {
+		return rateLimitErr.RetryDelay(), true
+	}
+
+	var queueErr *QueueError
+	if errors.As(err, &queueErr) && queueErr.RetryAfter > 0 {
+		return time.Duration(queueErr.RetryAfter) * time.Second, true
+	}
+
+	return 0, false
+}
+
+// ExponentialBackoff doubles the delay on every retry, from Base up to Max.
+// It is the default policy, with Base set to Config.RetryBackoff and no
+// jitter. A Retry-After delay from the API is used as it is.
+type ExponentialBackoff struct {
+	// Base is the delay before the first retry. Zero uses
+	// DefaultRetryBackoff.
+	Base time.Duration
+	// Max caps the delay. Zero uses MaxRetryBackoff.
+	Max time.Duration
+	// Jitter is the fraction (0-1) by which each delay is randomly
+	// shortened, so that clients that failed together do not retry
+	// together. 0.5 waits between half and all of the delay.
+	Jitter float64
+	// Rand returns random numbers in [0, 1) for the jitter. Nil uses
+	// math/rand. It must be safe for concurrent use if the policy is
+	// shared.
+	Rand func() float64
+}
+
+// NextDelay implements RetryPolicy
+func (b ExponentialBackoff) NextDelay(attempt int, err error) (time.Duration, bool) {
+	if delay, ok := RetryAfter(err); ok {
+		return delay, true
+	}
+
+	limit := b.Max
+	if limit <= 0 {
+		limit = MaxRetryBackoff
+	}
+	base := b.Base
+	if base <= 0 {
+		base = DefaultRetryBackoff
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	if b.Jitter > 0 {
+		random := rand.Float64
+		if b.Rand != nil {
+			random = b.Rand
+		}
+		jitter := b.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= time.Duration(float64(delay) * jitter * random())
+	}
+	return delay, true
+}
+
+// ConstantBackoff waits the same delay before every retry, or the
+// Retry-After delay from the API if there is one
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// NextDelay implements RetryPolicy
+func (b ConstantBackoff) NextDelay(attempt int, err error) (time.Duration, bool) {
+	if delay, ok := RetryAfter(err); ok {
+		return delay, true
+	}
+	return b.Delay, true
+}
+
+// NoRetry never retries, whatever Config.MaxRetries says
+type NoRetry struct{}
+
+// NextDelay implements RetryPolicy
+func (NoRetry) NextDelay(attempt int, err error) (time.Duration, bool) {
+	return 0, false
+}
+
+// WithRetryPolicy retries the email with the policy instead of
+// Config.RetryPolicy
+func WithRetryPolicy(policy RetryPolicy) SendOption {
+	return func(o *sendOptions) {
+		o.retryPolicy = policy
+	}
+}
@@ -0,0 +1,164 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestExponentialBackoffSequence(t *testing.T) {
+	err := NewNetworkError("connection reset", "")
+
+	tests := []struct {
+		name     string
+		policy   ExponentialBackoff
+		expected []time.Duration
+	}{
+		{"defaults", ExponentialBackoff{}, []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second}},
+		{"capped", ExponentialBackoff{Base: time.Second, Max: 3 * time.Second}, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{"default cap", ExponentialBackoff{Base: 10 * time.Second}, []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		var delays []time.Duration
+		for attempt := 1; attempt <= len(tt.expected); attempt++ {
+			delay, ok := tt.policy.NextDelay(attempt, err)
+			if !ok {
+				t.Fatalf("%s: expected a retry after attempt %d", tt.name, attempt)
+			}
+			delays = append(delays, delay)
+		}
+		if !reflect.DeepEqual(delays, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, delays)
+		}
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	sequence := func() []time.Duration {
+		policy := ExponentialBackoff{Base: time.Second, Max: 8 * time.Second, Jitter: 0.5, Rand: rand.New(rand.NewSource(42)).Float64}
+		var delays []time.Duration
+		for attempt := 1; attempt <= 5; attempt++ {
+			delay, _ := policy.NextDelay(attempt, nil)
+			delays = append(delays, delay)
+		}
+		return delays
+	}
+
+	first, second := sequence(), sequence()
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same delays from the same seed, got %v and %v", first, second)
+	}
+
+	unjittered := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second}
+	for i, delay := range first {
+		if delay > unjittered[i] || delay < unjittered[i]/2 {
+			t.Errorf("Expected attempt %d to wait between %v and %v, got %v", i+1, unjittered[i]/2, unjittered[i], delay)
+		}
+	}
+	if reflect.DeepEqual(first, unjittered) {
+		t.Errorf("Expected jittered delays, got %v", first)
+	}
+}
+
+func TestExponentialBackoffNeverExceedsMax(t *testing.T) {
+	property := func(base, limit uint32, attempt uint8, jitter float64, seed int64) bool {
+		policy := ExponentialBackoff{
+			Base:   time.Duration(base) * time.Microsecond,
+			Max:    time.Duration(limit) * time.Microsecond,
+			Jitter: jitter,
+			Rand:   rand.New(rand.NewSource(seed)).Float64,
+		}
+		effectiveMax := policy.Max
+		if effectiveMax <= 0 {
+			effectiveMax = MaxRetryBackoff
+		}
+
+		delay, ok := policy.NextDelay(int(attempt)+1, errors.New("failed"))
+		return ok && delay >= 0 && delay <= effectiveMax
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryPoliciesHonorRetryAfter(t *testing.T) {
+	rateLimitErr := NewRateLimitError("slow down", 7, 0, 0, 0)
+	queueErr := NewQueueError("queue full", "queue_full", true, 4)
+
+	policies := []RetryPolicy{ExponentialBackoff{Max: time.Second}, ConstantBackoff{Delay: time.Second}}
+	for _, policy := range policies {
+		if delay, ok := policy.NextDelay(1, rateLimitErr); !ok || delay != 7*time.Second {
+			t.Errorf("%T: expected the Retry-After of a rate limit, got %v", policy, delay)
+		}
+		if delay, ok := policy.NextDelay(3, queueErr); !ok || delay != 4*time.Second {
+			t.Errorf("%T: expected the Retry-After of a queue error, got %v", policy, delay)
+		}
+	}
+
+	if delay, ok := (ConstantBackoff{Delay: time.Second}).NextDelay(5, NewNetworkError("", "")); !ok || delay != time.Second {
+		t.Errorf("Expected a constant delay, got %v", delay)
+	}
+	if _, ok := (NoRetry{}).NextDelay(1, rateLimitErr); ok {
+		t.Error("Expected NoRetry to stop")
+	}
+}
+
+// rateLimitPolicy retries rate limits after their Retry-After and nothing
+// else
+type rateLimitPolicy struct{}
+
+func (rateLimitPolicy) NextDelay(attempt int, err error) (time.Duration, bool) {
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return 0, false
+	}
+	return RetryAfter(err)
+}
+
+func TestConfigRetryPolicy(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 3
+	config.RetryPolicy = ConstantBackoff{Delay: 250 * time.Millisecond}
+
+	client, calls, clock := newRetryTestClient(config, http.StatusServiceUnavailable, http.StatusBadGateway)
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected success after retries, got: %v", err)
+	}
+	if *calls != 3 || !reflect.DeepEqual(clock.Sleeps(), []time.Duration{250 * time.Millisecond, 250 * time.Millisecond}) {
+		t.Errorf("Expected 2 constant delays, got %d calls and %v", *calls, clock.Sleeps())
+	}
+
+	// The policy sees the typed error
+	config.RetryPolicy = rateLimitPolicy{}
+	config.WaitOnRateLimit = true
+	client, calls, clock = newRetryTestClient(config, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err == nil {
+		t.Fatal("Expected the 503 not to be retried")
+	}
+	if *calls != 2 || !reflect.DeepEqual(clock.Sleeps(), []time.Duration{3 * time.Second}) {
+		t.Errorf("Expected one retry after the Retry-After, got %d calls and %v", *calls, clock.Sleeps())
+	}
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 3
+
+	client, calls, _ := newRetryTestClient(config, http.StatusServiceUnavailable)
+	email := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	if _, err := client.SendWith(context.Background(), email, WithRetryPolicy(NoRetry{})); err == nil || *calls != 1 {
+		t.Errorf("Expected a single attempt with NoRetry, got %d and %v", *calls, err)
+	}
+	if client.GetConfig().RetryPolicy != nil {
+		t.Error("Expected the per-call policy to leave the config unchanged")
+	}
+}
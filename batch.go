@@ -0,0 +1,289 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// DefaultBatchConcurrency is the number of emails SendAll sends at once
+const DefaultBatchConcurrency = 4
+
+// ErrSkipped is the error of an email that was not sent because the batch
+// was cancelled, by its context or by its FailureMode
+var ErrSkipped = errors.New("poodle: email skipped because the batch was cancelled")
+
+// SendResult is the outcome of sending one email of a batch
+type SendResult struct {
+	// Index is the position of the email in the batch
+	Index int
+	// Recipient is the address the email was sent to, set by
+	// SendIndividually
+	Recipient string
+	Email     *Email
+	Response  *EmailResponse
+	Err       error
+	// Attempts is the number of requests made for the email, including
+	// retries (see SendOutcome.Attempts)
+	Attempts int
+}
+
+// OK returns true if the email was sent
+func (r SendResult) OK() bool {
+	return r.Err == nil
+}
+
+// Skipped returns true if the email was not attempted because the batch
+// was cancelled
+func (r SendResult) Skipped() bool {
+	return errors.Is(r.Err, ErrSkipped)
+}
+
+// MultiError reports the failed emails of a batch. It unwraps to the
+// individual errors, so errors.Is and errors.As find e.g. a RateLimitError
+// among the failures.
+type MultiError struct {
+	// Total is the number of emails in the batch
+	Total int
+	// Failures are the results of the failed and skipped emails, in batch
+	// order
+	Failures []SendResult
+}
+
+// newMultiError returns a MultiError for the failed results, or nil if
+// every email was sent
+func newMultiError(results []SendResult) error {
+	multiErr := &MultiError{Total: len(results)}
+	for _, result := range results {
+		if result.Err != nil {
+			multiErr.Failures = append(multiErr.Failures, result)
+		}
+	}
+
+	if len(multiErr.Failures) == 0 {
+		return nil
+	}
+	return multiErr
+}
+
+func (e *MultiError) Error() string {
+	noun := "emails"
+	if e.Total == 1 {
+		noun = "email"
+	}
+	failed := e.Failed()
+	message := fmt.Sprintf("%d of %d %s failed", len(failed), e.Total, noun)
+	if skipped := len(e.Failures) - len(failed); skipped > 0 {
+		message += fmt.Sprintf(", %d skipped", skipped)
+	}
+
+	// Report the first email that was attempted, if any
+	for _, failure := range e.Failures {
+		if !failure.Skipped() || len(failed) == 0 {
+			message += ": " + failure.Err.Error()
+			if len(failed) > 1 {
+				message += fmt.Sprintf(" (and %d more)", len(failed)-1)
+			}
+			break
+		}
+	}
+	return message
+}
+
+// Unwrap returns the errors of the failed emails
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+	return errs
+}
+
+// Failed returns the batch indices of the emails that were attempted and
+// failed
+func (e *MultiError) Failed() []int {
+	indices := make([]int, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		if !failure.Skipped() {
+			indices = append(indices, failure.Index)
+		}
+	}
+	return indices
+}
+
+// Skipped returns the batch indices of the emails that were not attempted
+// because the batch was cancelled
+func (e *MultiError) Skipped() []int {
+	var indices []int
+	for _, failure := range e.Failures {
+		if failure.Skipped() {
+			indices = append(indices, failure.Index)
+		}
+	}
+	return indices
+}
+
+// ByType groups the failures by their error_type, e.g.
+// "rate_limit_exceeded" or "validation_error". Skipped emails are grouped
+// as "skipped".
+func (e *MultiError) ByType() map[string][]SendResult {
+	groups := make(map[string][]SendResult)
+	for _, failure := range e.Failures {
+		errorType := errorTypeOf(failure.Err)
+		if failure.Skipped() {
+			errorType = "skipped"
+		}
+		groups[errorType] = append(groups[errorType], failure)
+	}
+	return groups
+}
+
+// FailureMode decides whether a failed email cancels the rest of a batch
+type FailureMode int
+
+const (
+	// ContinueOnError sends every email regardless of failures
+	ContinueOnError FailureMode = iota
+	// FailFast cancels the rest of the batch when an email fails with an
+	// error that is not retryable (see IsRetryable). Rate limits and
+	// server errors do not cancel the batch.
+	FailFast
+	// FailFastOnAuth cancels the rest of the batch only when an email
+	// fails with a 401 or 403 response, which every other email of the
+	// batch would fail with too
+	FailFastOnAuth
+)
+
+// cancels reports whether err cancels the rest of a batch
+func (m FailureMode) cancels(err error) bool {
+	switch m {
+	case FailFast:
+		return !IsRetryable(err)
+	case FailFastOnAuth:
+		var poodleErr PoodleError
+		if errors.As(err, &poodleErr) {
+			status := poodleErr.StatusCode()
+			return status == http.StatusUnauthorized || status == http.StatusForbidden
+		}
+	}
+	return false
+}
+
+// BatchOption configures a batch send
+type BatchOption func(*batchOptions)
+
+// batchOptions holds the settings of a batch send
+type batchOptions struct {
+	concurrency      int
+	failureMode      FailureMode
+	progress         func(Progress)
+	progressSettings progressSettings
+	report           *SendReport
+
+	// Used by SendIndividually only
+	keepDuplicates     bool
+	recipientVariables map[string]map[string]string
+}
+
+// WithConcurrency sets how many emails of a batch are sent at once
+func WithConcurrency(n int) BatchOption {
+	return func(o *batchOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithFailureMode sets whether a failed email cancels the rest of the
+// batch. Emails that are not attempted once the batch is cancelled fail
+// with ErrSkipped; emails already being sent complete. The default is
+// ContinueOnError.
+func WithFailureMode(mode FailureMode) BatchOption {
+	return func(o *batchOptions) {
+		o.failureMode = mode
+	}
+}
+
+func newBatchOptions(opts []BatchOption) *batchOptions {
+	options := &batchOptions{concurrency: DefaultBatchConcurrency}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// SendAll sends the emails concurrently and returns a result for each, in
+// the order given. Emails are dispatched by Email.Priority. Unless
+// WithFailureMode says otherwise, a failed email does not stop the others.
+// Emails not yet attempted when ctx is cancelled are skipped. If any email
+// failed or was skipped the returned error is a *MultiError.
+func (c *Client) SendAll(ctx context.Context, emails []*Email, opts ...BatchOption) ([]SendResult, error) {
+	options := newBatchOptions(opts)
+	results := make([]SendResult, len(emails))
+
+	// Cancelling the batch stops new sends; sends in flight use ctx and
+	// complete
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var progress *progressTracker
+	if options.progress != nil {
+		progress = newProgressTracker(c.httpClient.clock, options.progress, options.progressSettings, func(done int) int {
+			return len(emails) - done
+		})
+	}
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < options.concurrency && w < len(emails); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if batchCtx.Err() != nil {
+					results[i] = SendResult{Index: i, Email: emails[i], Err: ErrSkipped}
+				} else {
+					outcome, err := c.SendDetailed(ctx, emails[i])
+					results[i] = SendResult{Index: i, Email: emails[i], Response: outcome.Response, Err: err, Attempts: outcome.Attempts}
+					if err != nil && options.failureMode.cancels(err) {
+						cancel()
+					}
+				}
+				if options.report != nil {
+					options.report.Add(results[i])
+				}
+				if progress != nil {
+					progress.record(results[i].Err)
+				}
+			}
+		}()
+	}
+
+	// Dispatch higher-priority emails first
+	var waiting [priorityClasses][]int
+	for i, email := range emails {
+		rank := PriorityNormal.rank()
+		if email != nil {
+			rank = email.Priority.rank()
+		}
+		waiting[rank] = append(waiting[rank], i)
+	}
+	guard := starvationGuard{limit: DefaultStarvationLimit}
+	for {
+		rank, ok := guard.pick([priorityClasses]int{len(waiting[0]), len(waiting[1]), len(waiting[2])})
+		if !ok {
+			break
+		}
+		indices <- waiting[rank][0]
+		waiting[rank] = waiting[rank][1:]
+	}
+	close(indices)
+	wg.Wait()
+	if progress != nil {
+		progress.close()
+	}
+
+	return results, newMultiError(results)
+}
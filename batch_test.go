@@ -0,0 +1,305 @@
+package poodle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSendAll(t *testing.T) {
+	client := NewClient("test_api_key")
+
+	var inFlight, maxInFlight int32
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			highest := atomic.LoadInt32(&maxInFlight)
+			if current <= highest || atomic.CompareAndSwapInt32(&maxInFlight, highest, current) {
+				break
+			}
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		switch {
+		case strings.Contains(string(body), "limited@"):
+			response := jsonResponse(http.StatusTooManyRequests, `{"message":"Rate limit exceeded"}`)
+			response.Header.Set("Retry-After", "30")
+			return response, nil
+		case strings.Contains(string(body), "broken@"):
+			return jsonResponse(http.StatusInternalServerError, `{"message":"Server error"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	var emails []*Email
+	for i := 0; i < 10; i++ {
+		to := fmt.Sprintf("user%d@example.com", i)
+		switch i {
+		case 3:
+			to = "limited@example.com"
+		case 5:
+			to = "broken@example.com"
+		case 7:
+			to = "invalid"
+		}
+		emails = append(emails, NewTextEmail("from@example.com", to, "Subject", "Hello"))
+	}
+
+	results, err := client.SendAll(context.Background(), emails, WithConcurrency(3))
+	if len(results) != 10 {
+		t.Fatalf("Expected 10 results, got %d", len(results))
+	}
+	for i, result := range results {
+		if result.Index != i || result.Email != emails[i] {
+			t.Errorf("Expected result %d to match its email, got index %d", i, result.Index)
+		}
+	}
+	if maxInFlight > 3 {
+		t.Errorf("Expected at most 3 concurrent sends, got %d", maxInFlight)
+	}
+
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("Expected MultiError, got %T", err)
+	}
+	if multiErr.Error() != "3 of 10 emails failed: Rate limit exceeded (and 2 more)" {
+		t.Errorf("Unexpected error message: %s", multiErr.Error())
+	}
+	if failed := multiErr.Failed(); fmt.Sprint(failed) != "[3 5 7]" {
+		t.Errorf("Expected failed indices [3 5 7], got %v", failed)
+	}
+
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != 30 {
+		t.Errorf("Expected errors.As to find the RateLimitError, got %v", rateLimitErr)
+	}
+
+	groups := multiErr.ByType()
+	if len(groups["rate_limit_exceeded"]) != 1 || len(groups["http_error"]) != 1 || len(groups["validation_error"]) != 1 {
+		t.Errorf("Unexpected grouping: %v", groups)
+	}
+}
+
+func TestSendAllSuccess(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	emails := []*Email{
+		NewTextEmail("from@example.com", "a@example.com", "Subject", "Hello"),
+		NewTextEmail("from@example.com", "b@example.com", "Subject", "Hello"),
+	}
+
+	results, err := client.SendAll(context.Background(), emails)
+	if err != nil {
+		t.Fatalf("Expected a nil error, got: %v", err)
+	}
+	for _, result := range results {
+		if !result.OK() || result.Response == nil {
+			t.Errorf("Expected result %d to succeed", result.Index)
+		}
+	}
+
+	if _, err := client.SendAll(context.Background(), nil); err != nil {
+		t.Errorf("Expected an empty batch to succeed, got: %v", err)
+	}
+}
+
+func TestSendAllFailureModes(t *testing.T) {
+	unauthorized := func() *http.Response {
+		return jsonResponse(http.StatusUnauthorized, `{"message":"Invalid API Key"}`)
+	}
+	rateLimited := func() *http.Response {
+		return jsonResponse(http.StatusTooManyRequests, `{"message":"Rate limit exceeded"}`)
+	}
+
+	tests := []struct {
+		name    string
+		mode    FailureMode
+		failure func() *http.Response // response for the fourth email; nil for an invalid email
+		skipped int
+	}{
+		{"continue on auth", ContinueOnError, unauthorized, 0},
+		{"fail fast on auth", FailFast, unauthorized, 6},
+		{"fail fast on auth only", FailFastOnAuth, unauthorized, 6},
+		{"fail fast on validation", FailFast, nil, 6},
+		{"auth only ignores validation", FailFastOnAuth, nil, 0},
+		{"fail fast ignores rate limits", FailFast, rateLimited, 0},
+	}
+
+	for _, tt := range tests {
+		client := NewClient("test_api_key")
+		client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			if strings.Contains(string(body), "user3@") {
+				return tt.failure(), nil
+			}
+			return acceptedResponse(), nil
+		})
+
+		emails := make([]*Email, 10)
+		for i := range emails {
+			emails[i] = NewTextEmail("from@example.com", fmt.Sprintf("user%d@example.com", i), "Subject", "Hello")
+		}
+		if tt.failure == nil {
+			emails[3].To = "invalid"
+		}
+
+		results, err := client.SendAll(context.Background(), emails, WithConcurrency(1), WithFailureMode(tt.mode))
+		var multiErr *MultiError
+		if !errors.As(err, &multiErr) {
+			t.Fatalf("%s: expected MultiError, got %T", tt.name, err)
+		}
+		if failed := multiErr.Failed(); fmt.Sprint(failed) != "[3]" {
+			t.Errorf("%s: expected only email 3 to fail, got %v", tt.name, failed)
+		}
+		if skipped := multiErr.Skipped(); len(skipped) != tt.skipped {
+			t.Errorf("%s: expected %d skipped, got %v", tt.name, tt.skipped, skipped)
+		}
+		for _, result := range results[4:] {
+			if result.Skipped() != (tt.skipped > 0) {
+				t.Errorf("%s: unexpected result %d: %v", tt.name, result.Index, result.Err)
+			}
+		}
+		if tt.skipped > 0 && len(multiErr.ByType()["skipped"]) != tt.skipped {
+			t.Errorf("%s: expected skipped emails grouped, got %v", tt.name, multiErr.ByType())
+		}
+	}
+}
+
+func TestSendAllCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sent := 0
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		sent++
+		if sent == 2 {
+			cancel()
+		}
+		return acceptedResponse(), nil
+	})
+
+	emails := make([]*Email, 5)
+	for i := range emails {
+		emails[i] = NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	}
+
+	_, err := client.SendAll(ctx, emails, WithConcurrency(1))
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) || !errors.Is(err, ErrSkipped) {
+		t.Fatalf("Expected a MultiError of skipped emails, got %v", err)
+	}
+	if len(multiErr.Failed()) != 0 || fmt.Sprint(multiErr.Skipped()) != "[2 3 4]" {
+		t.Errorf("Expected emails 2-4 to be skipped, got failed %v and skipped %v", multiErr.Failed(), multiErr.Skipped())
+	}
+	if multiErr.Error() != "0 of 5 emails failed, 3 skipped: "+ErrSkipped.Error() {
+		t.Errorf("Unexpected error message: %s", multiErr.Error())
+	}
+}
+
+func TestSendIndividually(t *testing.T) {
+	var mutex sync.Mutex
+	sent := make(map[string]int)
+	var inFlight, maxInFlight int32
+	client := NewClient("test_api_key", WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			highest := atomic.LoadInt32(&maxInFlight)
+			if current <= highest || atomic.CompareAndSwapInt32(&maxInFlight, highest, current) {
+				break
+			}
+		}
+
+		var payload struct{ To, Subject, Text string }
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		mutex.Lock()
+		sent[payload.To+"|"+payload.Subject+"|"+payload.Text]++
+		mutex.Unlock()
+		if strings.Contains(payload.To, "broken@") {
+			return jsonResponse(http.StatusInternalServerError, `{"message":"Server error"}`), nil
+		}
+		return acceptedResponse(), nil
+	})))
+
+	base := NewTextEmail("from@example.com", "", "Hello {{first_name}}", "Sent to {{email}}")
+	base.Variables = map[string]string{"first_name": "there"}
+	recipients := []string{
+		"a@example.com",
+		"Bea <b@example.com>",
+		"broken@example.com",
+		" A@Example.com ",
+		"c@example.com",
+	}
+
+	results, err := client.SendIndividually(context.Background(), base, recipients,
+		WithConcurrency(2),
+		WithRecipientVariables(map[string]map[string]string{
+			"b@example.com": {"first_name": "Bea"},
+			"C@Example.com": {"first_name": "Cy"},
+		}),
+	)
+	if len(results) != 4 {
+		t.Fatalf("Expected a duplicate to be collapsed into 4 results, got %d", len(results))
+	}
+	if want := "[a@example.com b@example.com broken@example.com c@example.com]"; fmt.Sprint(recipientsOf(results)) != want {
+		t.Errorf("Expected results %s, got %v", want, recipientsOf(results))
+	}
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 concurrent sends, got %d", maxInFlight)
+	}
+	for _, want := range []string{
+		"a@example.com|Hello there|Sent to a@example.com",
+		"Bea <b@example.com>|Hello Bea|Sent to b@example.com",
+		"c@example.com|Hello Cy|Sent to c@example.com",
+	} {
+		if sent[want] != 1 {
+			t.Errorf("Expected one email %q, got %v", want, sent)
+		}
+	}
+	if base.To != "" || base.Variables["email"] != "" {
+		t.Errorf("Expected the base email to be left alone, got %+v", base)
+	}
+
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("Expected MultiError, got %T", err)
+	}
+	if len(multiErr.Failures) != 1 || multiErr.Failures[0].Recipient != "broken@example.com" {
+		t.Errorf("Expected the failure to name its recipient, got %+v", multiErr.Failures)
+	}
+
+	results, err = client.SendIndividually(context.Background(), base, recipients[:1:1], WithDuplicateRecipients())
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected a single result, got %v, %v", results, err)
+	}
+	results, _ = client.SendIndividually(context.Background(), base, []string{"c@example.com", "c@example.com"}, WithDuplicateRecipients())
+	if len(results) != 2 {
+		t.Errorf("Expected duplicates to be kept, got %d results", len(results))
+	}
+
+	if _, err := client.SendIndividually(context.Background(), nil, recipients); err == nil {
+		t.Error("Expected an error for a nil base email")
+	}
+}
+
+func recipientsOf(results []SendResult) []string {
+	var recipients []string
+	for _, result := range results {
+		recipients = append(recipients, result.Recipient)
+	}
+	return recipients
+}
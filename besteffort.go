@@ -0,0 +1,74 @@
+package poodle
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DefaultBestEffortTimeout bounds a BestEffort send unless
+// Config.BestEffortTimeout says otherwise
+const DefaultBestEffortTimeout = 5 * time.Second
+
+// SendOption changes how a single email is sent by SendWith
+type SendOption func(*sendOptions)
+
+// sendOptions holds the settings of a SendWith call
+type sendOptions struct {
+	bestEffort  bool
+	retryPolicy RetryPolicy
+}
+
+// BestEffort sends the email once, without retries, within
+// Config.BestEffortTimeout, and drops it if that fails with a retryable
+// error such as a network error, timeout, rate limit or 5xx response. A
+// dropped email is passed to Config.OnDrop, and SendWith returns a
+// response with Dropped set instead of the error. Other errors, such as
+// validation and authentication errors, are still returned since they
+// indicate a bug. Use it for non-critical emails that should not hold up
+// or fail a request path.
+func BestEffort() SendOption {
+	return func(o *sendOptions) {
+		o.bestEffort = true
+	}
+}
+
+// SendWith sends the email like SendContext, changed by the options
+func (c *Client) SendWith(ctx context.Context, email *Email, opts ...SendOption) (*EmailResponse, error) {
+	var options sendOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	config := c.snapshotConfig()
+	if options.retryPolicy != nil {
+		config.RetryPolicy = options.retryPolicy
+	}
+	if !options.bestEffort {
+		return c.sendContext(ctx, config, email)
+	}
+
+	config.MaxRetries = 0
+	config.WaitOnRateLimit = false
+	config.OverallDeadline = config.BestEffortTimeout
+	if config.OverallDeadline <= 0 {
+		config.OverallDeadline = DefaultBestEffortTimeout
+	}
+
+	response, err := c.sendContext(ctx, config, email)
+	if err == nil || !IsRetryable(err) {
+		return response, err
+	}
+
+	err = config.PIIMode.scrubError(err)
+	if config.OnDrop != nil {
+		config.OnDrop(email, err)
+	} else if config.logs(LogLevelError) {
+		log.Printf("Poodle API Dropped: best-effort email was not sent: %s", err.Error())
+	}
+
+	response = NewEmailResponse(false, "dropped")
+	response.Dropped = true
+	response.DropError = err
+	return response, nil
+}
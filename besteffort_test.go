@@ -0,0 +1,124 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func bestEffortTestClient(status int) (*Client, *int, *[]*Email) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 3
+	config.WaitOnRateLimit = true
+
+	var dropped []*Email
+	config.OnDrop = func(email *Email, err error) {
+		dropped = append(dropped, email)
+	}
+
+	requests := 0
+	client := NewClientWithConfig(config, WithClock(newTestClock()))
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		if status == http.StatusAccepted {
+			return acceptedResponse(), nil
+		}
+		return jsonResponse(status, `{"message":"Service unavailable"}`), nil
+	})
+	return client, &requests, &dropped
+}
+
+func TestBestEffortDropsRetryableErrors(t *testing.T) {
+	client, requests, dropped := bestEffortTestClient(http.StatusServiceUnavailable)
+	email := NewTextEmail("from@example.com", "to@example.com", "Someone liked your post", "Hi")
+
+	response, err := client.SendWith(context.Background(), email, BestEffort())
+	if err != nil {
+		t.Fatalf("Expected the error to be swallowed, got: %v", err)
+	}
+	if response == nil || !response.Dropped || response.Success {
+		t.Fatalf("Expected a dropped response, got %+v", response)
+	}
+	var httpErr *HTTPError
+	if !errors.As(response.DropError, &httpErr) || httpErr.StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("Expected the 503 in DropError, got %v", response.DropError)
+	}
+	if *requests != 1 {
+		t.Errorf("Expected a single attempt despite MaxRetries, got %d", *requests)
+	}
+	if len(*dropped) != 1 || (*dropped)[0] != email {
+		t.Errorf("Expected OnDrop to receive the email, got %v", *dropped)
+	}
+
+	// Without the option the error is returned and retried
+	if _, err := client.SendWith(context.Background(), email); err == nil {
+		t.Error("Expected the error without BestEffort")
+	}
+	if *requests != 5 {
+		t.Errorf("Expected 4 more attempts without BestEffort, got %d", *requests-1)
+	}
+}
+
+func TestBestEffortReturnsBugs(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		email  *Email
+	}{
+		{"validation", http.StatusAccepted, NewTextEmail("from@example.com", "not-an-address", "Hello", "Hi")},
+		{"authentication", http.StatusUnauthorized, NewTextEmail("from@example.com", "to@example.com", "Hello", "Hi")},
+		{"bad request", http.StatusBadRequest, NewTextEmail("from@example.com", "to@example.com", "Hello", "Hi")},
+	}
+
+	for _, tt := range tests {
+		client, _, dropped := bestEffortTestClient(tt.status)
+		response, err := client.SendWith(context.Background(), tt.email, BestEffort())
+		if err == nil || response != nil {
+			t.Errorf("%s: expected the error to be returned, got %+v", tt.name, response)
+		}
+		if len(*dropped) != 0 {
+			t.Errorf("%s: expected no drop, got %d", tt.name, len(*dropped))
+		}
+	}
+}
+
+func TestBestEffortTimeout(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BestEffortTimeout = 20 * time.Millisecond
+	config.LogLevel = LogLevelError
+
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	logs := captureLog(t)
+
+	started := time.Now()
+	response, err := client.SendWith(context.Background(), NewTextEmail("from@example.com", "to@example.com", "Hello", "Hi"), BestEffort())
+	if err != nil || !response.Dropped {
+		t.Fatalf("Expected the slow send to be dropped, got %+v and %v", response, err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Expected the send to be cut off after the best-effort timeout, took %v", elapsed)
+	}
+	if !strings.Contains(logs.String(), "best-effort email was not sent") {
+		t.Errorf("Expected the drop to be logged without OnDrop, got %q", logs.String())
+	}
+}
+
+func TestBestEffortSuccess(t *testing.T) {
+	client, _, dropped := bestEffortTestClient(http.StatusAccepted)
+	response, err := client.SendWith(context.Background(), NewTextEmail("from@example.com", "to@example.com", "Hello", "Hi"), BestEffort())
+	if err != nil || !response.Success || response.Dropped {
+		t.Errorf("Expected a normal response, got %+v and %v", response, err)
+	}
+	if len(*dropped) != 0 {
+		t.Errorf("Expected no drop, got %d", len(*dropped))
+	}
+}
@@ -0,0 +1,49 @@
+package poodle
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MarshalCanonical encodes the email as sent to the API in a canonical
+// form, so that the same email always yields the same bytes, e.g. for a
+// proxy that signs request bodies. The canonical form is the JSON of
+// MarshalJSON with:
+//
+//   - the keys of every object sorted by their UTF-8 bytes
+//   - no whitespace between tokens and no trailing newline
+//   - <, > and & written as they are rather than as \u003c, \u003e and
+//     \u0026
+//
+// Strings are otherwise escaped as by encoding/json: quotes, backslashes
+// and control characters are escaped, U+2028 and U+2029 are written as
+// \u2028 and \u2029, and invalid UTF-8 is replaced with U+FFFD. Strings are
+// not Unicode-normalized; normalize them, e.g. to NFC, before building the
+// email if the signature requires it.
+func (e *Email) MarshalCanonical() ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalJSON(data)
+}
+
+// canonicalJSON re-encodes a JSON document in the canonical form described
+// by Email.MarshalCanonical
+func canonicalJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	// Maps are encoded with sorted keys
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,124 @@
+package poodle
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// canonicalEmails are the emails whose canonical encoding is pinned by the
+// golden files in testdata/canonical
+var canonicalEmails = map[string]func() *Email{
+	"text": func() *Email {
+		return NewTextEmail("from@example.com", "to@example.com", "Hello", "Hi there")
+	},
+	"both": func() *Email {
+		email := NewEmailWithBoth("from@example.com", "to@example.com", "Sale", `<p class="x">50% & more</p>`, "50% & more")
+		email.SetHeader("x-tenant", "acme")
+		email.SetHeader("List-Unsubscribe", "<mailto:unsubscribe@example.com>")
+		email.SetHeader("X-Campaign", "spring")
+		return email
+	},
+	"addresses": func() *Email {
+		email := NewHTMLEmail("", "", "Welcome", "<h1>Hi</h1>")
+		email.FromAddress = &Address{Name: "Acme, Inc.", Email: "from@example.com"}
+		email.ToAddress = &Address{Name: "Jane Doe", Email: "jane@example.com"}
+		email.Priority = PriorityHigh
+		email.Variables = map[string]string{"ignored": "not sent"}
+		return email
+	},
+	"unicode": func() *Email {
+		return NewTextEmail("from@example.com", "to@example.com", "Grüße 🎉", "Line 1\nLine\t2 \"quoted\" \\ \u2028 \x01 \xff")
+	},
+}
+
+func TestMarshalCanonicalGolden(t *testing.T) {
+	for name, build := range canonicalEmails {
+		t.Run(name, func(t *testing.T) {
+			got, err := build().MarshalCanonical()
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			golden := filepath.Join("testdata", "canonical", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Canonical encoding differs from %s\nwant: %s\ngot:  %s", golden, want, got)
+			}
+
+			// The canonical form is valid JSON with the same content as
+			// the regular encoding
+			var canonical, regular interface{}
+			plain, _ := json.Marshal(build())
+			if json.Unmarshal(got, &canonical) != nil || json.Unmarshal(plain, &regular) != nil || !reflect.DeepEqual(canonical, regular) {
+				t.Errorf("Expected the canonical form to decode like %s, got %s", plain, got)
+			}
+		})
+	}
+}
+
+// TestMarshalCanonicalFields fails when a field is added to Email, as a
+// reminder to extend canonicalEmails and regenerate the golden files with
+// go test -run Canonical -update
+func TestMarshalCanonicalFields(t *testing.T) {
+	expected := []string{"From", "To", "Subject", "HTML", "Text", "FromAddress", "ToAddress", "Headers", "Priority", "Variables"}
+
+	var fields []string
+	emailType := reflect.TypeOf(Email{})
+	for i := 0; i < emailType.NumField(); i++ {
+		fields = append(fields, emailType.Field(i).Name)
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Email fields changed from %v to %v: cover the new fields in canonicalEmails and update the golden files", expected, fields)
+	}
+}
+
+func TestMarshalCanonicalStable(t *testing.T) {
+	first, _ := canonicalEmails["both"]().MarshalCanonical()
+	for i := 0; i < 50; i++ {
+		// A fresh email gets a fresh header map with its own iteration order
+		again, _ := canonicalEmails["both"]().MarshalCanonical()
+		if !bytes.Equal(again, first) {
+			t.Fatalf("Expected stable bytes, got %s and %s", first, again)
+		}
+	}
+}
+
+func TestCanonicalPayloads(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.CanonicalPayloads = true
+
+	var body []byte
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ = io.ReadAll(req.Body)
+		return acceptedResponse(), nil
+	})
+
+	if _, err := client.Send(canonicalEmails["both"]()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("testdata", "canonical", "both.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, want) {
+		t.Errorf("Expected the canonical request body\nwant: %s\ngot:  %s", want, body)
+	}
+}
@@ -1,83 +1,330 @@
 package poodle
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
+// Sender sends emails. Client implements it; depending on Sender instead
+// of *Client lets tests substitute poodletest.MockSender.
+type Sender interface {
+	Send(email *Email) (*EmailResponse, error)
+	SendContext(ctx context.Context, email *Email) (*EmailResponse, error)
+}
+
 // Client is the main Poodle SDK client
 type Client struct {
 	config     *Config
 	httpClient *HTTPClient
 	mutex      sync.RWMutex
+	domains    domainCache
+	dedupe     DedupeStore
+	flights    dedupeFlights
+
+	// levelBeforeDebug is the log level SetDebug(false) restores, if
+	// hasLevelBeforeDebug is set
+	levelBeforeDebug    LogLevel
+	hasLevelBeforeDebug bool
 }
 
 // NewClient creates a new Poodle client with the provided API key
-func NewClient(apiKey string) *Client {
+func NewClient(apiKey string, opts ...Option) *Client {
 	config := NewConfig()
 	config.APIKey = apiKey
-	return NewClientWithConfig(config)
+	return NewClientWithConfig(config, opts...)
 }
 
 // NewClientFromEnv creates a new Poodle client using environment variables
-func NewClientFromEnv() *Client {
+func NewClientFromEnv(opts ...Option) *Client {
 	config := NewConfigFromEnv()
-	return NewClientWithConfig(config)
+	return NewClientWithConfig(config, opts...)
 }
 
 // NewClientWithConfig creates a new Poodle client with custom configuration
-func NewClientWithConfig(config *Config) *Client {
+func NewClientWithConfig(config *Config, opts ...Option) *Client {
 	if err := config.Validate(); err != nil {
 		panic(err) // In Go 1.20, we don't have better error handling for constructors
 	}
 
-	return &Client{
+	if config.logs(LogLevelError) {
+		for _, warning := range config.Warnings() {
+			log.Printf("Poodle Config Warning: %s", warning)
+		}
+	}
+
+	client := &Client{
 		config:     config,
 		httpClient: NewHTTPClient(config),
 	}
+
+	if config.Archive != nil {
+		client.httpClient.archive = newArchiveQueue(config.Archive, config.ArchiveQueueSize, config.OnArchiveError)
+	}
+
+	defaultDoer := client.httpClient.httpClient
+	for _, opt := range opts {
+		opt(client)
+	}
+	if config.HardenedTransport {
+		if doer, ok := client.httpClient.httpClient.(*http.Client); !ok || doer != defaultDoer {
+			client.httpClient.httpClient = newHardenedDoer(client.httpClient.httpClient)
+		}
+	}
+
+	if config.DedupeWindow > 0 {
+		client.dedupe = config.DedupeStore
+		if client.dedupe == nil {
+			client.dedupe = newMemoryDedupeStore(DefaultDedupeCapacity, client.httpClient.clock)
+		}
+	}
+
+	if config.WarmupOnCreate {
+		go func() {
+			if err := client.Warmup(context.Background()); err != nil && config.logs(LogLevelError) {
+				log.Printf("Poodle Warmup: %s", err.Error())
+			}
+		}()
+	}
+
+	return client
 }
 
 // Send sends an email using the Email model
 func (c *Client) Send(email *Email) (*EmailResponse, error) {
+	return c.SendContext(context.Background(), email)
+}
+
+// SendContext sends an email using the Email model. The context controls
+// cancellation of the request in addition to the configured timeout.
+//
+// The email is copied before it is validated, so changing or reusing it
+// once SendContext has been called, even from another goroutine while the
+// request is in flight, does not affect what is sent. A nil email fails
+// with a ValidationError.
+func (c *Client) SendContext(ctx context.Context, email *Email) (*EmailResponse, error) {
+	return c.sendContext(ctx, c.snapshotConfig(), email)
+}
+
+// sendContext sends an email with the given configuration snapshot
+func (c *Client) sendContext(ctx context.Context, config *Config, email *Email) (*EmailResponse, error) {
+	email, err := c.prepareEmail(ctx, config, email)
+	if err != nil {
+		c.httpClient.observeSend(config, err)
+		return nil, err
+	}
+	if config.WarnUnverifiedFrom {
+		c.checkFromDomain(config, email)
+	}
+	if c.dedupe != nil && config.DedupeWindow > 0 {
+		return c.sendDeduplicated(ctx, config, email)
+	}
+	return c.httpClient.sendEmail(ctx, config, email)
+}
+
+// prepareEmail returns a copy of the email with the configured defaults,
+// normalization and rendering applied
+func (c *Client) prepareEmail(ctx context.Context, config *Config, email *Email) (*Email, error) {
+	if email == nil {
+		return nil, newNilEmailError()
+	}
+	// Work on a snapshot so that later changes by the caller cannot reach
+	// the request
+	email = email.clone()
+	recordEmail(ctx, email)
+
+	if defaulted := applyDefaults(ctx, config, email); defaulted != email {
+		email = defaulted
+		recordEmail(ctx, email)
+	}
+	if config.AutoNormalize {
+		email = email.clone().Normalize()
+		recordEmail(ctx, email)
+	}
+	if config.AutoRender && email.Variables != nil {
+		rendered, err := email.Render()
+		if err != nil {
+			return nil, err
+		}
+		email = rendered
+		recordEmail(ctx, email)
+	}
+	return email, nil
+}
+
+// validateEmail checks the email as a send would before the request is
+// built: after the configured defaults, rendering and minification, with
+// Config.AddressValidator
+func (c *Client) validateEmail(ctx context.Context, email *Email) error {
+	config := c.snapshotConfig()
+	prepared, err := c.prepareEmail(ctx, config, email)
+	if err != nil {
+		return err
+	}
+	if config.AutoMinifyHTML && len(prepared.HTML) > MaxContentSize {
+		prepared = prepared.clone().MinifyHTML()
+	}
+	return prepared.validate(config.addressValidator())
+}
+
+// BuildRequest returns the request SendContext would make for the email,
+// without making it, e.g. to inspect it or to sign it at a proxy. The
+// email goes through the same defaults, validation and preparation as a
+// send. See HTTPClient.NewSendRequest.
+func (c *Client) BuildRequest(ctx context.Context, email *Email) (*http.Request, error) {
+	config := c.snapshotConfig()
+	email, err := c.prepareEmail(ctx, config, email)
+	if err != nil {
+		return nil, err
+	}
+	return c.httpClient.newSendRequest(ctx, config, email)
+}
+
+// snapshotConfig returns a copy of the current configuration so that a
+// request is not affected by concurrent configuration changes.
+func (c *Client) snapshotConfig() *Config {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	return c.httpClient.SendEmail(email)
+	configCopy := *c.config
+	return &configCopy
 }
 
 // SendHTML sends an HTML email
 func (c *Client) SendHTML(from, to, subject, html string) (*EmailResponse, error) {
-	email := NewHTMLEmail(from, to, subject, html)
-	return c.Send(email)
+	return c.SendContent(from, to, subject, HTML(html))
 }
 
 // SendText sends a plain text email
 func (c *Client) SendText(from, to, subject, text string) (*EmailResponse, error) {
-	email := NewTextEmail(from, to, subject, text)
-	return c.Send(email)
+	return c.SendContent(from, to, subject, Text(text))
 }
 
 // SendWithBoth sends an email with both HTML and text content
 func (c *Client) SendWithBoth(from, to, subject, html, text string) (*EmailResponse, error) {
-	email := NewEmailWithBoth(from, to, subject, html, text)
-	return c.Send(email)
+	return c.SendContent(from, to, subject, HTML(html), Text(text))
 }
 
-// GetConfig returns the client configuration (read-only)
+// GetConfig returns the client configuration (read-only).
+// The copy contains the real API key; use Config.Masked or print it with
+// %v when logging.
 func (c *Client) GetConfig() *Config {
+	// Return a copy to prevent external modification
+	return c.snapshotConfig()
+}
+
+// SetDebug enables or disables debug logging. Enabling it sets the log
+// level to LogLevelTrace; disabling it restores the level set before it
+// was enabled, or LogLevelError if debug logging was enabled by the
+// configuration.
+func (c *Client) SetDebug(debug bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if debug {
+		if level := c.config.effectiveLogLevel(); level < LogLevelTrace {
+			c.levelBeforeDebug = level
+			c.hasLevelBeforeDebug = true
+		}
+		c.config.Debug = true
+		c.config.LogLevel = LogLevelTrace
+		return
+	}
+
+	if c.config.effectiveLogLevel() < LogLevelTrace {
+		c.config.Debug = false
+		return
+	}
+	level := LogLevelError
+	if c.hasLevelBeforeDebug {
+		level = c.levelBeforeDebug
+	}
+	c.config.Debug = false
+	c.config.LogLevel = level
+	c.hasLevelBeforeDebug = false
+}
+
+// SetLogLevel changes the log level used for subsequent requests. It
+// returns a ValidationError if level is not one of the LogLevel constants.
+func (c *Client) SetLogLevel(level LogLevel) error {
+	if level < LogLevelOff || level > LogLevelTrace {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Log level is invalid"},
+			Errors: map[string][]string{
+				"log_level": {"Log level must be one of LogLevelOff, LogLevelError, LogLevelInfo or LogLevelTrace"},
+			},
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.config.LogLevel = level
+	c.config.Debug = level >= LogLevelTrace
+	c.hasLevelBeforeDebug = false
+	return nil
+}
+
+// LogLevel returns the current log level
+func (c *Client) LogLevel() LogLevel {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	// Return a copy to prevent external modification
-	configCopy := *c.config
-	return &configCopy
+	return c.config.effectiveLogLevel()
 }
 
-// SetDebug enables or disables debug logging
-func (c *Client) SetDebug(debug bool) {
+// SetBaseURL changes the API base URL used for subsequent requests
+func (c *Client) SetBaseURL(baseURL string) error {
+	if err := validateBaseURL(baseURL); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.config.HardenedTransport {
+		if err := checkHTTPS(baseURL); err != nil {
+			return err
+		}
+	}
+
+	c.config.BaseURL = strings.TrimRight(baseURL, "/")
+	return nil
+}
+
+// SetTimeout changes the total request timeout used for subsequent requests
+func (c *Client) SetTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Timeout must be greater than 0"},
+			Errors: map[string][]string{
+				"timeout": {"Timeout must be greater than 0"},
+			},
+		}
+	}
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	c.config.Debug = debug
+	c.config.Timeout = timeout
+	return nil
+}
+
+// Close flushes emails waiting to be archived and releases idle
+// connections. Emails sent after Close are no longer archived.
+func (c *Client) Close() error {
+	if c.httpClient.archive != nil {
+		c.httpClient.archive.close()
+	}
+
+	if closer, ok := c.httpClient.httpClient.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+
+	return nil
 }
 
 // IsDebug returns whether debug logging is enabled
@@ -85,5 +332,5 @@ func (c *Client) IsDebug() bool {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	return c.config.Debug
+	return c.config.effectiveLogLevel() >= LogLevelTrace
 }
@@ -1,9 +1,13 @@
 package poodle
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -19,6 +23,21 @@ func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	return m.response, m.err
 }
 
+// mockDoerFunc adapts a function to the HTTPDoer interface for testing.
+type mockDoerFunc func(req *http.Request) (*http.Response, error)
+
+func (f mockDoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// acceptedResponse returns a successful 202 response for use in mocks.
+func acceptedResponse() *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusAccepted,
+		Body:       io.NopCloser(strings.NewReader(`{"success": true, "message": "Email queued"}`)),
+	}
+}
+
 func TestNewClient(t *testing.T) {
 	apiKey := "test_api_key_123"
 	client := NewClient(apiKey)
@@ -181,6 +200,99 @@ func TestClientConcurrency(t *testing.T) {
 	// If we get here without a race condition, the test passes
 }
 
+func TestClientSetBaseURL(t *testing.T) {
+	client := NewClient("test_api_key")
+
+	var requestedURL string
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		requestedURL = req.URL.String()
+		return acceptedResponse(), nil
+	})
+
+	if err := client.SetBaseURL("api.eu.usepoodle.com"); err == nil {
+		t.Error("Expected error for base URL without scheme")
+	}
+	if client.GetConfig().BaseURL != DefaultBaseURL {
+		t.Error("Expected invalid base URL to leave the configuration unchanged")
+	}
+
+	if err := client.SetBaseURL("https://api.eu.usepoodle.com/"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if requestedURL != "https://api.eu.usepoodle.com/v1/send-email" {
+		t.Errorf("Expected request to use the new base URL, got '%s'", requestedURL)
+	}
+}
+
+func TestClientSetTimeout(t *testing.T) {
+	client := NewClient("test_api_key")
+
+	if err := client.SetTimeout(0); err == nil {
+		t.Error("Expected error for zero timeout")
+	}
+
+	if err := client.SetTimeout(20 * time.Millisecond); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	start := time.Now()
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	if err == nil {
+		t.Fatal("Expected timeout error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the per-request deadline to apply, request took %v", elapsed)
+	}
+
+	timeoutErr, ok := err.(*TimeoutError)
+	if !ok {
+		t.Fatalf("Expected TimeoutError, got %T", err)
+	}
+	if timeoutErr.StatusCode() != http.StatusRequestTimeout {
+		t.Errorf("Expected status code %d, got %d", http.StatusRequestTimeout, timeoutErr.StatusCode())
+	}
+	if timeoutErr.Phase != TimeoutPhaseTotal {
+		t.Errorf("Expected phase total, got %q", timeoutErr.Phase)
+	}
+}
+
+func TestClientRuntimeSettersConcurrency(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	done := make(chan bool, 2)
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			_ = client.SetBaseURL("https://api.eu.usepoodle.com")
+			_ = client.SetTimeout(time.Duration(i+1) * time.Second)
+		}
+		done <- true
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			_, _ = client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+		}
+		done <- true
+	}()
+
+	<-done
+	<-done
+}
+
 // Note: We can't easily test the actual Send methods without mocking the HTTP client
 // or setting up integration tests. For now, we'll test that the methods exist and
 // can be called with valid parameters.
@@ -255,7 +367,7 @@ func TestClientSendMethods(t *testing.T) {
 				return c.Send(email)
 			},
 			expectError: true,
-			errorType:  &ValidationError{},
+			errorType:   &ValidationError{},
 		},
 		{
 			name: "Send - API Validation Error",
@@ -268,7 +380,7 @@ func TestClientSendMethods(t *testing.T) {
 				return c.Send(email)
 			},
 			expectError: true,
-			errorType:  &ValidationError{},
+			errorType:   &ValidationError{},
 		},
 		{
 			name: "Send - Authentication Error",
@@ -281,7 +393,7 @@ func TestClientSendMethods(t *testing.T) {
 				return c.Send(email)
 			},
 			expectError: true,
-			errorType:  &AuthenticationError{},
+			errorType:   &AuthenticationError{},
 		},
 		{
 			name: "Send - Rate Limit Error",
@@ -300,17 +412,17 @@ func TestClientSendMethods(t *testing.T) {
 				return c.Send(email)
 			},
 			expectError: true,
-			errorType:  &RateLimitError{},
+			errorType:   &RateLimitError{},
 		},
 		{
-			name: "Send - Network Error (simulated by mockErr)",
+			name:    "Send - Network Error (simulated by mockErr)",
 			mockErr: NewNetworkError("simulated network problem", "http://fakeurl.com"),
 			sendAction: func(c *Client) (*EmailResponse, error) {
 				email := NewHTMLEmail(from, to, subject, htmlBody)
 				return c.Send(email)
 			},
 			expectError: true,
-			errorType:  &NetworkError{},
+			errorType:   &NetworkError{},
 		},
 		{
 			name: "Send - HTTP Error (generic)",
@@ -323,7 +435,7 @@ func TestClientSendMethods(t *testing.T) {
 				return c.Send(email)
 			},
 			expectError: true,
-			errorType:  &HTTPError{},
+			errorType:   &HTTPError{},
 		},
 	}
 
@@ -392,3 +504,451 @@ func TestClientSendMethods(t *testing.T) {
 		})
 	}
 }
+
+// failingReader returns an error after the first read
+type failingReader struct {
+	read bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.read = true
+	return copy(p, `{"success":`), nil
+}
+
+func TestClientAcceptedWithoutJSONBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		message string
+		raw     string
+	}{
+		{"Empty body", "", "accepted", ""},
+		{"Whitespace body", " \r\n\t", "accepted", ""},
+		{"HTML body", "<html><body>Accepted</body></html>", "accepted", "<html><body>Accepted</body></html>"},
+		{"JSON body", `{"success":true,"message":"Email queued"}`, "Email queued", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient("test_api_key")
+			client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusAccepted,
+					Body:       io.NopCloser(strings.NewReader(tt.body)),
+				}, nil
+			})
+
+			response, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if !response.Success || response.Message != tt.message || response.RawBody != tt.raw {
+				t.Errorf("Unexpected response: %+v", response)
+			}
+		})
+	}
+}
+
+func TestClientAcceptedBodyReadFailure(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 2
+	config.RetryBackoff = time.Millisecond
+	client := NewClientWithConfig(config)
+	attempts := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{
+			StatusCode: http.StatusAccepted,
+			Body:       io.NopCloser(&failingReader{}),
+		}, nil
+	})
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	readErr, ok := err.(*ResponseReadError)
+	if !ok {
+		t.Fatalf("Expected ResponseReadError for a failed body read, got %T", err)
+	}
+	if readErr.StatusCode() != http.StatusAccepted {
+		t.Errorf("Expected the status of the response, got %d", readErr.StatusCode())
+	}
+	// The email was accepted, so sending it again would deliver it twice
+	if IsRetryable(err) || attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d (retryable %t)", attempts, IsRetryable(err))
+	}
+}
+
+func TestClientAutoNormalize(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AutoNormalize = true
+	client := NewClientWithConfig(config)
+
+	var body string
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		body = string(data)
+		return acceptedResponse(), nil
+	})
+
+	email := NewTextEmail("from@example.com", " Bob@EXAMPLE.COM ", "Subject", "Hello")
+	if _, err := client.Send(email); err != nil {
+		t.Fatalf("Expected the normalized email to be valid, got: %v", err)
+	}
+
+	if !strings.Contains(body, `"to":"Bob@example.com"`) {
+		t.Errorf("Expected the normalized address to be sent, got %s", body)
+	}
+	if email.To != " Bob@EXAMPLE.COM " {
+		t.Errorf("Expected the caller's email to be unchanged, got '%s'", email.To)
+	}
+}
+
+func TestClientSendNilEmail(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		t.Error("Expected no request for a nil email")
+		return acceptedResponse(), nil
+	})
+
+	_, err := client.Send(nil)
+	validationErr, ok := err.(*ValidationError)
+	if !ok || validationErr.Message != "email must not be nil" || len(validationErr.Errors["email"]) != 1 {
+		t.Errorf("Expected a ValidationError for the nil email, got %v", err)
+	}
+	if _, err := client.SendDetailed(context.Background(), nil); err == nil {
+		t.Error("Expected SendDetailed to reject the nil email")
+	}
+	if results, err := client.SendAll(context.Background(), []*Email{nil}); err == nil || results[0].Err == nil {
+		t.Errorf("Expected SendAll to reject the nil email, got %v", results)
+	}
+	if err := (*Email)(nil).Validate(); err == nil {
+		t.Error("Expected Validate to reject the nil email")
+	}
+}
+
+func TestClientSendSnapshotsEmail(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.FallbackToText = true
+	client := NewClientWithConfig(config)
+
+	// The first request is held until the caller has changed the email and
+	// rejected, so that the fallback builds a second payload afterwards
+	started := make(chan struct{})
+	mutated := make(chan struct{})
+	var bodies []string
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) == 1 {
+			close(started)
+			<-mutated
+			return jsonResponse(http.StatusUnprocessableEntity, `{"message":"HTML content rejected by filter"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	email := NewEmailWithBoth("from@example.com", "to@example.com", "Original", "<p>Hi</p>", "Hi")
+	email.SetHeader("X-Tenant", "acme")
+	go func() {
+		<-started
+		email.Subject = "Changed"
+		email.Text = "Changed"
+		email.To = "someone-else@example.com"
+		email.SetHeader("X-Tenant", "changed")
+		email.ToAddress = &Address{Email: "changed@example.com"}
+		close(mutated)
+	}()
+
+	response, err := client.Send(email)
+	if err != nil || !response.FallbackToText {
+		t.Fatalf("Expected the text fallback to be sent, got %+v, %v", response, err)
+	}
+
+	var sent Email
+	if len(bodies) != 2 || json.Unmarshal([]byte(bodies[1]), &sent) != nil {
+		t.Fatalf("Expected 2 requests, got %v", bodies)
+	}
+	if sent.Subject != "Original" || sent.Text != "Hi" || sent.To != "to@example.com" || sent.Headers["X-Tenant"] != "acme" {
+		t.Errorf("Expected the payload to match the email as it was sent, got %+v", sent)
+	}
+}
+
+func TestClientAuthenticationReasons(t *testing.T) {
+	tests := []struct {
+		body   string
+		reason AuthReason
+	}{
+		{`{"message": "Missing Authorization header", "error": "missing_api_key"}`, AuthReasonMissingKey},
+		{`{"message": "Malformed API key", "code": "invalid_api_key"}`, AuthReasonInvalidKey},
+		{`{"message": "API key revoked", "error": "revoked_api_key"}`, AuthReasonExpiredKey},
+		{`{"message": "API key expired", "error": "expired_api_key"}`, AuthReasonExpiredKey},
+		{`{"message": "Invalid API Key"}`, AuthReasonUnknown},
+		{`not json`, AuthReasonUnknown},
+	}
+
+	for _, tt := range tests {
+		client := NewClient("test_api_key")
+		client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}, nil
+		})
+
+		_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+		authErr, ok := err.(*AuthenticationError)
+		if !ok {
+			t.Fatalf("Expected AuthenticationError, got %T", err)
+		}
+		if authErr.Reason != tt.reason {
+			t.Errorf("%s: expected reason %s, got %s", tt.body, tt.reason, authErr.Reason)
+		}
+		if authErr.Context()["reason"] != string(tt.reason) || authErr.Context()["suggestion"] != tt.reason.Suggestion() {
+			t.Errorf("Expected reason and suggestion in context, got %v", authErr.Context())
+		}
+		if !strings.Contains(authErr.Error(), tt.reason.Suggestion()) {
+			t.Errorf("Expected the suggestion in %q", authErr.Error())
+		}
+	}
+}
+
+func TestClientSuspensionReasons(t *testing.T) {
+	tests := []struct {
+		body      string
+		raw       string
+		kind      SuspensionReason
+		permanent bool
+	}{
+		{`{"message": "Account suspended", "error": "abuse_report"}`, "abuse_report", SuspensionReasonAbuseReport, true},
+		{`{"message": "Account suspended", "error": "payment_failure"}`, "payment_failure", SuspensionReasonPaymentFailure, false},
+		{`{"message": "Account suspended", "error": "Manual_Review"}`, "Manual_Review", SuspensionReasonManualReview, false},
+		{`{"message": "Account suspended", "error": "tos_violation_v2"}`, "tos_violation_v2", SuspensionReasonUnknown, false},
+		{`{"message": "Account suspended"}`, "", SuspensionReasonUnknown, false},
+		{`not json`, "unknown", SuspensionReasonUnknown, false},
+	}
+
+	for _, tt := range tests {
+		client := NewClient("test_api_key")
+		client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusForbidden,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}, nil
+		})
+
+		_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+		suspendedErr, ok := err.(*AccountSuspendedError)
+		if !ok {
+			t.Fatalf("Expected AccountSuspendedError, got %T", err)
+		}
+		if suspendedErr.Reason != tt.raw || suspendedErr.Kind != tt.kind || suspendedErr.IsPermanent() != tt.permanent {
+			t.Errorf("%s: expected %q, %s and permanent %t, got %q, %s and %t", tt.body,
+				tt.raw, tt.kind, tt.permanent, suspendedErr.Reason, suspendedErr.Kind, suspendedErr.IsPermanent())
+		}
+		if suspendedErr.Context()["suspension_reason"] != string(tt.kind) {
+			t.Errorf("Expected the suspension reason in context, got %v", suspendedErr.Context())
+		}
+	}
+}
+
+func TestUnprocessableEntityErrors(t *testing.T) {
+	tests := []struct {
+		body      string
+		queue     bool
+		reason    string
+		retryable bool
+	}{
+		{`{"message":"Mail queue unavailable, retry later"}`, true, "", true},
+		{`{"message":"Could not queue email","reason":"queue_overloaded"}`, true, "queue_overloaded", true},
+		{`{"message":"Could not queue email","error":"message_too_large"}`, true, "message_too_large", false},
+		{`{"message":"Could not queue email","code":"queue_paused","retryable":true}`, true, "queue_paused", true},
+		{`{"message":"Try again later","retryable":false}`, true, "", false},
+		{`not json`, true, "", false},
+		{`{"message":"Invalid fields","errors":{"to":["Recipient is blocked"]}}`, false, "", false},
+		{`{"message":"Invalid fields","errors":{"subject":"Subject is too long"}}`, false, "", false},
+	}
+
+	for _, tt := range tests {
+		client := NewClient("test_api_key")
+		client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusUnprocessableEntity, tt.body), nil
+		})
+
+		_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+		if !tt.queue {
+			validationErr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("%s: expected ValidationError, got %T", tt.body, err)
+			}
+			if validationErr.StatusCode() != http.StatusUnprocessableEntity || len(validationErr.Errors) != 1 {
+				t.Errorf("%s: expected the field errors with status 422, got %d and %v", tt.body, validationErr.StatusCode(), validationErr.Errors)
+			}
+			continue
+		}
+
+		queueErr, ok := err.(*QueueError)
+		if !ok {
+			t.Fatalf("%s: expected QueueError, got %T", tt.body, err)
+		}
+		if queueErr.Reason != tt.reason || queueErr.Retryable != tt.retryable || IsRetryable(err) != tt.retryable {
+			t.Errorf("%s: expected reason %q and retryable %t, got %q and %t", tt.body, tt.reason, tt.retryable, queueErr.Reason, queueErr.Retryable)
+		}
+		if queueErr.Context()["error_type"] != "queue_error" {
+			t.Errorf("Expected error_type queue_error, got %v", queueErr.Context())
+		}
+	}
+}
+
+func TestPayloadTooLargeError(t *testing.T) {
+	tests := []struct {
+		name  string
+		resp  func() *http.Response
+		limit int
+	}{
+		{"json with limit", func() *http.Response {
+			return jsonResponse(http.StatusRequestEntityTooLarge, `{"message":"Request too large","limit":1048576}`)
+		}, 1048576},
+		{"json with max_size", func() *http.Response {
+			return jsonResponse(http.StatusRequestEntityTooLarge, `{"max_size":2097152}`)
+		}, 2097152},
+		{"empty body", func() *http.Response {
+			return &http.Response{StatusCode: http.StatusRequestEntityTooLarge, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}
+		}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent, requests int
+			config := NewConfig()
+			config.APIKey = "test_api_key"
+			config.MaxRetries = 2
+			client := NewClientWithConfig(config, WithClock(newTestClock()), WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(req.Body)
+				sent = len(body)
+				requests++
+				return tt.resp(), nil
+			})))
+
+			_, err := client.SendHTML("from@example.com", "to@example.com", "Subject", strings.Repeat("<p>Hello</p>", 100))
+			var tooLarge *PayloadTooLargeError
+			if !errors.As(err, &tooLarge) {
+				t.Fatalf("Expected PayloadTooLargeError, got %T: %v", err, err)
+			}
+			if tooLarge.PayloadSize != sent || tooLarge.Limit != tt.limit || tooLarge.StatusCode() != http.StatusRequestEntityTooLarge {
+				t.Errorf("Expected payload size %d and limit %d, got %+v", sent, tt.limit, tooLarge)
+			}
+			if !strings.Contains(tooLarge.Error(), "Email.SizeReport()") {
+				t.Errorf("Expected the message to point at the size report, got %q", tooLarge.Error())
+			}
+			if IsRetryable(err) || requests != 1 {
+				t.Errorf("Expected the error not to be retried, got %d requests", requests)
+			}
+		})
+	}
+}
+
+func TestClientMissingAPIKey(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "   "
+
+	client := NewClientWithConfig(config)
+	requests := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return acceptedResponse(), nil
+	})
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	authErr, ok := err.(*AuthenticationError)
+	if !ok {
+		t.Fatalf("Expected AuthenticationError, got %T", err)
+	}
+	if authErr.Reason != AuthReasonMissingKey {
+		t.Errorf("Expected reason %s, got %s", AuthReasonMissingKey, authErr.Reason)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request without an API key, got %d", requests)
+	}
+}
+
+func TestClientBuildRequest(t *testing.T) {
+	type tenantKey struct{}
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.Locale = "de-DE"
+	config.DefaultFrom = "Team <team@example.com>"
+	config.AutoNormalize = true
+	config.ContextHeaderMappers = []ContextHeaderMapper{
+		func(ctx context.Context) (string, string, bool) {
+			tenant, ok := ctx.Value(tenantKey{}).(string)
+			return "X-Tenant", tenant, ok
+		},
+	}
+
+	var sent *http.Request
+	var sentBody []byte
+	client := NewClientWithConfig(config, WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		sentBody, _ = io.ReadAll(req.Body)
+		return acceptedResponse(), nil
+	})))
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	email := NewTextEmail("", " To@Example.com ", "Subject", "Hello")
+	built, err := client.BuildRequest(ctx, email)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := client.SendContext(ctx, email); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if built.Method != sent.Method || built.URL.String() != sent.URL.String() {
+		t.Errorf("Expected %s %s, got %s %s", sent.Method, sent.URL, built.Method, built.URL)
+	}
+	if !reflect.DeepEqual(built.Header, sent.Header) {
+		t.Errorf("Expected headers %v, got %v", sent.Header, built.Header)
+	}
+	if built.Header.Get("X-Tenant") != "acme" || built.Header.Get("Accept-Language") == "" {
+		t.Errorf("Expected context and locale headers, got %v", built.Header)
+	}
+	if built.ContentLength != int64(len(sentBody)) {
+		t.Errorf("Expected content length %d, got %d", len(sentBody), built.ContentLength)
+	}
+
+	// The body can be read again through GetBody
+	for i := 0; i < 2; i++ {
+		body, err := built.GetBody()
+		if err != nil {
+			t.Fatalf("Expected GetBody to succeed, got %v", err)
+		}
+		if data, _ := io.ReadAll(body); string(data) != string(sentBody) {
+			t.Errorf("Expected body %s, got %s", sentBody, data)
+		}
+	}
+	if !strings.Contains(string(sentBody), "team@example.com") || !strings.Contains(string(sentBody), `"to":"To@example.com"`) {
+		t.Errorf("Expected the defaults and normalization to be applied, got %s", sentBody)
+	}
+}
+
+func TestClientBuildRequestValidates(t *testing.T) {
+	client := NewClient("test_api_key")
+
+	var validationErr *ValidationError
+	if _, err := client.BuildRequest(context.Background(), NewTextEmail("from@example.com", "", "Subject", "Hello")); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ValidationError, got %v", err)
+	}
+	if _, err := client.BuildRequest(context.Background(), nil); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ValidationError for a nil email, got %v", err)
+	}
+	if _, err := client.httpClient.NewSendRequest(context.Background(), nil); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ValidationError for a nil email, got %v", err)
+	}
+}
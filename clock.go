@@ -0,0 +1,66 @@
+package poodle
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the time source used for retries, rate-limit waits and pacing,
+// and for the timestamps and backoff of the queues, outboxes and progress
+// reports of a client. Tests can supply a fake implementation via
+// WithClock to avoid real sleeps. Idle queue polling and progress
+// intervals use real timers, so that a fake clock whose Sleep returns at
+// once does not make them spin. Helpers built without a client, such as
+// the HTTPDoer decorators and the file sinks, use the time package.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Sleep waits for the given duration or until the context is done,
+	// returning the context's error in the latter case
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleepContext(ctx, d)
+}
+
+// waitClock waits on the clock for d or until stop is closed, and reports
+// whether d passed
+func waitClock(clock Clock, stop <-chan struct{}, d time.Duration) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return clock.Sleep(ctx, d) == nil
+}
+
+// WithClock replaces the time source used by the client, including the
+// waits of the RateLimitErrors it returns. Context deadlines remain on the
+// wall clock: SendWithWait reads the time left before the deadline with
+// the time package and then measures its waits on the clock. The HTTPDoer
+// decorators, such as DoerWithLogging and DoerWithRetry, are built without
+// a client and time requests with the time package, and stores such as
+// poodlesql take their own clock.
+func WithClock(clock Clock) Option {
+	return func(c *Client) {
+		c.httpClient.clock = clock
+		if c.httpClient.limiter != nil {
+			c.httpClient.limiter.clock = clock
+		}
+		if c.httpClient.pacer != nil && !c.httpClient.pacer.shared {
+			c.httpClient.pacer.clock = clock
+		}
+	}
+}
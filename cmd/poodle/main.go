@@ -0,0 +1,217 @@
+// Command poodle sends emails through the Poodle API from the command line.
+//
+// Usage:
+//
+//	poodle send --from sender@yourdomain.com --to recipient@example.com \
+//	    --subject "Hello" --html-file body.html --text-file body.txt
+//
+// The API key is read from --api-key or the POODLE_API_KEY environment
+// variable. All other POODLE_* environment variables are honored as well.
+//
+// Exit codes:
+//
+//	0  email accepted
+//	1  usage or unexpected error
+//	2  validation error
+//	3  authentication error
+//	4  rate limit exceeded
+//	5  network error
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+// Exit codes mapped to error classes
+const (
+	exitOK         = 0
+	exitError      = 1
+	exitValidation = 2
+	exitAuth       = 3
+	exitRateLimit  = 4
+	exitNetwork    = 5
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitError
+	}
+
+	switch args[0] {
+	case "send":
+		return runSend(args[1:], stdout, stderr)
+	case "version":
+		fmt.Fprintf(stdout, "poodle %s\n", poodle.Version())
+		return exitOK
+	case "help", "-h", "--help":
+		printUsage(stdout)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "poodle: unknown command %q\n", args[0])
+		printUsage(stderr)
+		return exitError
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: poodle <command> [flags]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  send     Send an email")
+	fmt.Fprintln(w, "  version  Print the SDK version")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Run 'poodle send -h' for the flags of the send command.")
+}
+
+// runSend implements the send command
+func runSend(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	from := flags.String("from", "", "sender email address")
+	to := flags.String("to", "", "recipient email address")
+	subject := flags.String("subject", "", "email subject")
+	html := flags.String("html", "", "HTML content")
+	htmlFile := flags.String("html-file", "", "read HTML content from `file`")
+	text := flags.String("text", "", "plain text content")
+	textFile := flags.String("text-file", "", "read plain text content from `file`")
+	apiKey := flags.String("api-key", "", "Poodle API key (defaults to $POODLE_API_KEY)")
+	baseURL := flags.String("base-url", "", "API base URL (defaults to $POODLE_BASE_URL)")
+	jsonOutput := flags.Bool("json", false, "print the API response as JSON")
+	dryRun := flags.Bool("dry-run", false, "validate and print the request without sending it")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "poodle: unexpected arguments: %v\n", flags.Args())
+		return exitError
+	}
+
+	email := poodle.NewEmail(*from, *to, *subject)
+
+	content, err := readContent(*html, *htmlFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "poodle: %s\n", err)
+		return exitError
+	}
+	email.HTML = content
+
+	content, err = readContent(*text, *textFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "poodle: %s\n", err)
+		return exitError
+	}
+	email.Text = content
+
+	if *dryRun {
+		if err := email.Validate(); err != nil {
+			return reportError(stderr, err)
+		}
+		return printJSON(stdout, stderr, email)
+	}
+
+	config := poodle.NewConfigFromEnv()
+	if *apiKey != "" {
+		config.APIKey = *apiKey
+	}
+	if *baseURL != "" {
+		config.BaseURL = *baseURL
+	}
+	if err := config.Validate(); err != nil {
+		return reportError(stderr, err)
+	}
+
+	client := poodle.NewClientWithConfig(config)
+	response, err := client.Send(email)
+	if err != nil {
+		return reportError(stderr, err)
+	}
+
+	if *jsonOutput {
+		return printJSON(stdout, stderr, response)
+	}
+
+	fmt.Fprintf(stdout, "Email sent: %s\n", response.Message)
+	return exitOK
+}
+
+// readContent returns the inline value or the contents of the file, which
+// are mutually exclusive
+func readContent(value, path string) (string, error) {
+	if path == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("content and content file cannot both be set (%s)", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// printJSON writes v as indented JSON
+func printJSON(stdout, stderr io.Writer, v interface{}) int {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintf(stderr, "poodle: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// reportError prints err and returns the exit code for its error class
+func reportError(stderr io.Writer, err error) int {
+	var validationErr *poodle.ValidationError
+	var authErr *poodle.AuthenticationError
+	var rateLimitErr *poodle.RateLimitError
+	var networkErr *poodle.NetworkError
+
+	switch {
+	case errors.As(err, &validationErr):
+		fmt.Fprintf(stderr, "poodle: validation error: %s\n", validationErr.Error())
+		for field, messages := range validationErr.Errors {
+			for _, message := range messages {
+				fmt.Fprintf(stderr, "  %s: %s\n", field, message)
+			}
+		}
+		return exitValidation
+
+	case errors.As(err, &authErr):
+		fmt.Fprintf(stderr, "poodle: authentication error: %s\n", authErr.Error())
+		return exitAuth
+
+	case errors.As(err, &rateLimitErr):
+		fmt.Fprintf(stderr, "poodle: rate limit exceeded: %s\n", rateLimitErr.Error())
+		return exitRateLimit
+
+	case errors.As(err, &networkErr):
+		fmt.Fprintf(stderr, "poodle: network error: %s\n", networkErr.Error())
+		return exitNetwork
+
+	default:
+		fmt.Fprintf(stderr, "poodle: %s\n", err.Error())
+		return exitError
+	}
+}
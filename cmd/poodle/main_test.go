@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+	"github.com/usepoodle/poodle-go/poodletest"
+)
+
+// runCLI runs the command line and returns the exit code and outputs
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestSendWithFiles(t *testing.T) {
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	dir := t.TempDir()
+	htmlFile := filepath.Join(dir, "body.html")
+	textFile := filepath.Join(dir, "body.txt")
+	if err := os.WriteFile(htmlFile, []byte("<h1>Hi</h1>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(textFile, []byte("Hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI("send",
+		"--api-key", server.APIKey,
+		"--base-url", server.URL,
+		"--from", "from@example.com",
+		"--to", "to@example.com",
+		"--subject", "Hello",
+		"--html-file", htmlFile,
+		"--text-file", textFile,
+	)
+
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr)
+	}
+	if !strings.Contains(stdout, "Email sent") {
+		t.Errorf("Unexpected output: %s", stdout)
+	}
+
+	sent := server.Sent()
+	if len(sent) != 1 || sent[0].HTML != "<h1>Hi</h1>" || sent[0].Text != "Hi" {
+		t.Errorf("Unexpected sent emails: %+v", sent)
+	}
+}
+
+func TestSendJSONOutput(t *testing.T) {
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	t.Setenv("POODLE_API_KEY", server.APIKey)
+	t.Setenv("POODLE_BASE_URL", server.URL)
+
+	code, stdout, stderr := runCLI("send", "--json",
+		"--from", "from@example.com", "--to", "to@example.com", "--subject", "Hello", "--text", "Hi")
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr)
+	}
+
+	var response poodle.EmailResponse
+	if err := json.Unmarshal([]byte(stdout), &response); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", stdout, err)
+	}
+	if !response.Success {
+		t.Error("Expected successful response")
+	}
+}
+
+func TestSendDryRun(t *testing.T) {
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	code, stdout, stderr := runCLI("send", "--dry-run", "--base-url", server.URL,
+		"--from", "from@example.com", "--to", "to@example.com", "--subject", "Hello", "--text", "Hi")
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr)
+	}
+	if !strings.Contains(stdout, `"to": "to@example.com"`) {
+		t.Errorf("Expected the request payload to be printed, got %s", stdout)
+	}
+	if server.Requests() != 0 {
+		t.Error("Expected dry run not to contact the API")
+	}
+}
+
+func TestSendExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *poodletest.Response
+		apiKey   string
+		from     string
+		expected int
+	}{
+		{"Validation", nil, "", "invalid", exitValidation},
+		{"Authentication", nil, "wrong_key", "from@example.com", exitAuth},
+		{"Rate limit", responsePtr(poodletest.RateLimited(time.Minute, 10)), "", "from@example.com", exitRateLimit},
+		{"Server error", responsePtr(poodletest.ServerError()), "", "from@example.com", exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := poodletest.NewServer()
+			defer server.Close()
+
+			if tt.response != nil {
+				server.Enqueue(*tt.response)
+			}
+
+			apiKey := server.APIKey
+			if tt.apiKey != "" {
+				apiKey = tt.apiKey
+			}
+
+			code, _, stderr := runCLI("send", "--api-key", apiKey, "--base-url", server.URL,
+				"--from", tt.from, "--to", "to@example.com", "--subject", "Hello", "--text", "Hi")
+			if code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d (stderr: %s)", tt.expected, code, stderr)
+			}
+		})
+	}
+}
+
+func TestSendNetworkError(t *testing.T) {
+	server := poodletest.NewServer()
+	url := server.URL
+	server.Close()
+
+	code, _, stderr := runCLI("send", "--api-key", "key", "--base-url", url,
+		"--from", "from@example.com", "--to", "to@example.com", "--subject", "Hello", "--text", "Hi")
+	if code != exitNetwork {
+		t.Errorf("Expected exit code %d, got %d (stderr: %s)", exitNetwork, code, stderr)
+	}
+}
+
+func TestSendMissingAPIKey(t *testing.T) {
+	t.Setenv("POODLE_API_KEY", "")
+
+	code, _, stderr := runCLI("send",
+		"--from", "from@example.com", "--to", "to@example.com", "--subject", "Hello", "--text", "Hi")
+	if code != exitValidation {
+		t.Errorf("Expected exit code %d, got %d (stderr: %s)", exitValidation, code, stderr)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	if code, _, _ := runCLI("bounce"); code != exitError {
+		t.Errorf("Expected exit code %d, got %d", exitError, code)
+	}
+	if code, _, _ := runCLI(); code != exitError {
+		t.Errorf("Expected exit code %d, got %d", exitError, code)
+	}
+}
+
+func responsePtr(response poodletest.Response) *poodletest.Response {
+	return &response
+}
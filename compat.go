@@ -0,0 +1,310 @@
+package poodle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIVersion is the API version this SDK release was built against. The
+// API is backwards compatible within a major version, so a newer minor
+// version is compatible and an older or newer major version is not.
+const APIVersion = "1.0"
+
+// Headers with which the API advertises its version and the oldest SDK
+// version it supports
+const (
+	APIVersionHeader    = "Poodle-API-Version"
+	MinSDKVersionHeader = "Poodle-Min-SDK-Version"
+)
+
+// Deprecation describes an API endpoint announced as deprecated by the
+// Deprecation header (RFC 9745) or scheduled for removal by the Sunset
+// header (RFC 8594)
+type Deprecation struct {
+	// Endpoint is the path of the deprecated endpoint, e.g. /v1/send-email
+	Endpoint string
+	// Date is when the endpoint was or will be deprecated. It is zero if
+	// the Deprecation header did not give a date or was missing.
+	Date time.Time
+	// Sunset is when the endpoint will stop working. It is zero if the
+	// response had no Sunset header.
+	Sunset time.Time
+	// Link points to documentation of the deprecation or sunset, from the
+	// Link header
+	Link string
+}
+
+// String describes the deprecation for a log or warning message
+func (d Deprecation) String() string {
+	s := d.Endpoint + " is deprecated"
+	if !d.Date.IsZero() {
+		s += " since " + d.Date.Format("2006-01-02")
+	}
+	if !d.Sunset.IsZero() {
+		s += " and will be removed on " + d.Sunset.Format("2006-01-02")
+	}
+	if d.Link != "" {
+		s += " (see " + d.Link + ")"
+	}
+	return s
+}
+
+// CompatibilityReport is the result of Client.CheckCompatibility
+type CompatibilityReport struct {
+	// SDKVersion is the version of this SDK, SDKVersion
+	SDKVersion string
+	// APIVersion is the version advertised by the API, or empty if it did
+	// not advertise one
+	APIVersion string
+	// MinSDKVersion is the oldest SDK version the API supports, or empty if
+	// it did not say
+	MinSDKVersion string
+	// Compatible is false if the API's major version differs from
+	// APIVersion, its version is older than APIVersion, or it requires a
+	// newer SDK
+	Compatible bool
+	// Deprecation is set if the send endpoint is deprecated
+	Deprecation *Deprecation
+	// Warnings describe every problem found, including those that do not
+	// make the SDK incompatible
+	Warnings []string
+}
+
+// CheckCompatibility asks the API which version it runs and whether the
+// send endpoint is deprecated, so that an incompatible SDK can be detected
+// at startup rather than by failing sends. It sends an OPTIONS request to
+// the send endpoint, which sends no email. An API that does not answer
+// OPTIONS yields a report with a warning rather than an error; other
+// failures, such as an invalid API key, are returned as errors.
+func (c *Client) CheckCompatibility(ctx context.Context) (*CompatibilityReport, error) {
+	config := c.snapshotConfig()
+	path := "/v1/send-email"
+	url := c.httpClient.failover.active(config) + path
+
+	if err := c.httpClient.throttle(ctx); err != nil {
+		return nil, NewNetworkError("Request cancelled: "+err.Error(), url)
+	}
+	resp, body, err := c.httpClient.do(ctx, config, http.MethodOptions, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CompatibilityReport{SDKVersion: SDKVersion, Compatible: true}
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented:
+		report.Warnings = append(report.Warnings, "The API does not advertise its version")
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, config.PIIMode.scrubError(c.httpClient.parseErrorResponse(config, resp, body, url, 0))
+	default:
+		report.APIVersion = strings.TrimSpace(resp.Header.Get(APIVersionHeader))
+		report.MinSDKVersion = strings.TrimSpace(resp.Header.Get(MinSDKVersionHeader))
+		report.checkVersions()
+	}
+
+	if deprecation := parseDeprecation(path, resp.Header); deprecation != nil {
+		report.Deprecation = deprecation
+		report.Warnings = append(report.Warnings, deprecation.String())
+		if !deprecation.Sunset.IsZero() && !c.httpClient.clock.Now().Before(deprecation.Sunset) {
+			report.Compatible = false
+		}
+	}
+
+	return report, nil
+}
+
+// checkVersions compares the advertised versions with the SDK's
+func (r *CompatibilityReport) checkVersions() {
+	if r.APIVersion == "" {
+		r.Warnings = append(r.Warnings, "The API does not advertise its version")
+	} else if api, ok := parseVersion(r.APIVersion); !ok {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("The API version %q cannot be parsed", r.APIVersion))
+	} else {
+		supported, _ := parseVersion(APIVersion)
+		switch {
+		case api[0] != supported[0]:
+			r.Compatible = false
+			r.Warnings = append(r.Warnings, fmt.Sprintf("The API version %s is not compatible with this SDK, which supports %d.x", r.APIVersion, supported[0]))
+		case compareVersions(api, supported) < 0:
+			r.Compatible = false
+			r.Warnings = append(r.Warnings, fmt.Sprintf("The API version %s is older than %s, which this SDK requires", r.APIVersion, APIVersion))
+		}
+	}
+
+	if r.MinSDKVersion == "" {
+		return
+	}
+	minSDK, ok := parseVersion(r.MinSDKVersion)
+	if !ok {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("The minimum SDK version %q cannot be parsed", r.MinSDKVersion))
+		return
+	}
+	sdk, _ := parseVersion(SDKVersion)
+	if compareVersions(sdk, minSDK) < 0 {
+		r.Compatible = false
+		r.Warnings = append(r.Warnings, fmt.Sprintf("The API requires SDK version %s or later, this is %s", r.MinSDKVersion, SDKVersion))
+	}
+}
+
+// parseVersion parses a version such as "1.2", "v1.2.3" or "1.2.3-beta"
+// into its major, minor and patch numbers. Missing parts are zero.
+func parseVersion(s string) ([3]int, bool) {
+	var version [3]int
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > len(version) {
+		return version, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+// compareVersions returns -1, 0 or 1 as a is older than, equal to or
+// newer than b
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseDeprecation reads the Deprecation, Sunset and Link headers of a
+// response. It returns nil if the endpoint is neither deprecated nor
+// scheduled for removal.
+func parseDeprecation(endpoint string, header http.Header) *Deprecation {
+	deprecation := header.Get("Deprecation")
+	sunset := header.Get("Sunset")
+	if deprecation == "" && sunset == "" || strings.EqualFold(deprecation, "false") {
+		return nil
+	}
+
+	d := &Deprecation{Endpoint: endpoint}
+	d.Date = parseDeprecationDate(deprecation)
+	if t, err := http.ParseTime(sunset); err == nil {
+		d.Sunset = t.UTC()
+	}
+	d.Link = headerLink(header, "deprecation")
+	if d.Link == "" {
+		d.Link = headerLink(header, "sunset")
+	}
+	return d
+}
+
+// parseDeprecationDate parses a Deprecation header, which is a structured
+// date such as "@1688169599" (RFC 9745), an HTTP date as in earlier drafts,
+// or "true"
+func parseDeprecationDate(s string) time.Time {
+	if strings.HasPrefix(s, "@") {
+		if seconds, err := strconv.ParseInt(s[1:], 10, 64); err == nil {
+			return time.Unix(seconds, 0).UTC()
+		}
+		return time.Time{}
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return t.UTC()
+	}
+	return time.Time{}
+}
+
+// headerLink returns the target of the first link with the relation in
+// the Link headers
+func headerLink(header http.Header, rel string) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			params := strings.Split(link, ";")
+			target := strings.TrimSpace(params[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range params[1:] {
+				name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+				if !found || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, r := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+					if strings.EqualFold(r, rel) {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// deprecationLog remembers the endpoints already reported as deprecated,
+// so that OnDeprecation is called once per endpoint
+type deprecationLog struct {
+	mutex    sync.Mutex
+	reported map[string]bool
+}
+
+// observeDeprecation reports a deprecated endpoint to OnDeprecation, or
+// logs it, the first time a response announces it
+func (c *HTTPClient) observeDeprecation(config *Config, requestURL string, header http.Header) {
+	if header.Get("Deprecation") == "" && header.Get("Sunset") == "" {
+		return
+	}
+
+	endpoint := requestURL
+	if u, err := url.Parse(requestURL); err == nil {
+		endpoint = u.Path
+	}
+	deprecation := parseDeprecation(endpoint, header)
+	if deprecation == nil {
+		return
+	}
+
+	c.deprecations.mutex.Lock()
+	reported := c.deprecations.reported[endpoint]
+	if !reported {
+		if c.deprecations.reported == nil {
+			c.deprecations.reported = make(map[string]bool)
+		}
+		c.deprecations.reported[endpoint] = true
+	}
+	c.deprecations.mutex.Unlock()
+	if reported {
+		return
+	}
+
+	if config.OnDeprecation == nil {
+		if config.logs(LogLevelError) {
+			log.Printf("Poodle API Deprecation: %s", deprecation)
+		}
+		return
+	}
+	callOnDeprecation(config, *deprecation)
+}
+
+// callOnDeprecation calls the OnDeprecation callback, recovering and
+// logging a panic so that it does not fail the request
+func callOnDeprecation(config *Config, deprecation Deprecation) {
+	defer func() {
+		if r := recover(); r != nil && config.logs(LogLevelError) {
+			log.Printf("Poodle OnDeprecation: callback panicked: %v", r)
+		}
+	}()
+
+	config.OnDeprecation(deprecation)
+}
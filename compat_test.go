@@ -0,0 +1,233 @@
+package poodle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func headerResponse(status int, header http.Header) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		compatible bool
+		warnings   int
+	}{
+		{"SameVersion", http.StatusNoContent, http.Header{APIVersionHeader: {"1.0"}}, true, 0},
+		{"NewerMinor", http.StatusNoContent, http.Header{APIVersionHeader: {"1.7"}}, true, 0},
+		{"NewerMajor", http.StatusNoContent, http.Header{APIVersionHeader: {"2.0"}}, false, 1},
+		{"NewerSDKRequired", http.StatusNoContent, http.Header{APIVersionHeader: {"1.3"}, MinSDKVersionHeader: {"1.2.0"}}, false, 1},
+		{"OlderSDKAllowed", http.StatusNoContent, http.Header{APIVersionHeader: {"1.3"}, MinSDKVersionHeader: {"0.9.0"}}, true, 0},
+		{"NoVersion", http.StatusOK, http.Header{}, true, 1},
+		{"UnparsableVersion", http.StatusOK, http.Header{APIVersionHeader: {"latest"}}, true, 1},
+		{"OptionsNotAllowed", http.StatusMethodNotAllowed, http.Header{}, true, 1},
+		{"Deprecated", http.StatusNoContent, http.Header{APIVersionHeader: {"1.0"}, "Deprecation": {"@1767225600"}}, true, 1},
+		{"PastSunset", http.StatusNoContent, http.Header{APIVersionHeader: {"1.0"}, "Sunset": {"Thu, 01 Jan 2015 00:00:00 GMT"}}, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, values := range tt.header {
+				header[http.CanonicalHeaderKey(key)] = values
+			}
+
+			client := NewClient("test_api_key")
+			client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Method != http.MethodOptions || req.URL.Path != "/v1/send-email" {
+					t.Errorf("Expected OPTIONS /v1/send-email, got %s %s", req.Method, req.URL.Path)
+				}
+				return headerResponse(tt.status, header), nil
+			})
+
+			report, err := client.CheckCompatibility(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if report.Compatible != tt.compatible {
+				t.Errorf("Expected compatible %v, got %v (%v)", tt.compatible, report.Compatible, report.Warnings)
+			}
+			if len(report.Warnings) != tt.warnings {
+				t.Errorf("Expected %d warnings, got %v", tt.warnings, report.Warnings)
+			}
+			if report.SDKVersion != SDKVersion || report.APIVersion != header.Get(APIVersionHeader) {
+				t.Errorf("Expected the versions to be reported, got %+v", report)
+			}
+		})
+	}
+}
+
+func TestCheckCompatibilityError(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusUnauthorized, `{"message":"Invalid API key"}`), nil
+	})
+
+	if _, err := client.CheckCompatibility(context.Background()); err == nil {
+		t.Fatal("Expected an error")
+	} else if _, ok := err.(*AuthenticationError); !ok {
+		t.Errorf("Expected AuthenticationError, got %T", err)
+	}
+}
+
+func TestParseDeprecation(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   *Deprecation
+	}{
+		{"None", http.Header{}, nil},
+		{"False", http.Header{"Deprecation": {"false"}}, nil},
+		{"True", http.Header{"Deprecation": {"true"}}, &Deprecation{Endpoint: "/v1/send-email"}},
+		{
+			"StructuredDate",
+			http.Header{"Deprecation": {"@1688169599"}},
+			&Deprecation{Endpoint: "/v1/send-email", Date: time.Date(2023, 6, 30, 23, 59, 59, 0, time.UTC)},
+		},
+		{
+			"HTTPDate",
+			http.Header{"Deprecation": {"Sun, 11 Nov 2018 23:59:59 GMT"}},
+			&Deprecation{Endpoint: "/v1/send-email", Date: time.Date(2018, 11, 11, 23, 59, 59, 0, time.UTC)},
+		},
+		{
+			"SunsetOnly",
+			http.Header{"Sunset": {"Sat, 31 Dec 2018 23:59:59 GMT"}},
+			&Deprecation{Endpoint: "/v1/send-email", Sunset: time.Date(2018, 12, 31, 23, 59, 59, 0, time.UTC)},
+		},
+		{
+			"DeprecationLink",
+			http.Header{
+				"Deprecation": {"true"},
+				"Link":        {`<https://example.com/next>; rel="next", <https://docs.usepoodle.com/v2>; rel="deprecation"; type="text/html"`},
+			},
+			&Deprecation{Endpoint: "/v1/send-email", Link: "https://docs.usepoodle.com/v2"},
+		},
+		{
+			"SunsetLink",
+			http.Header{
+				"Sunset": {"Sat, 31 Dec 2018 23:59:59 GMT"},
+				"Link":   {`<https://docs.usepoodle.com/sunset>;rel="sunset alternate"`},
+			},
+			&Deprecation{
+				Endpoint: "/v1/send-email",
+				Sunset:   time.Date(2018, 12, 31, 23, 59, 59, 0, time.UTC),
+				Link:     "https://docs.usepoodle.com/sunset",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseDeprecation("/v1/send-email", tt.header)
+			if tt.want == nil || got == nil {
+				if tt.want != got {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+				}
+				return
+			}
+			if got.Endpoint != tt.want.Endpoint || !got.Date.Equal(tt.want.Date) || !got.Sunset.Equal(tt.want.Sunset) || got.Link != tt.want.Link {
+				t.Errorf("Expected %+v, got %+v", *tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestOnDeprecationCalledOnce(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+
+	var mutex sync.Mutex
+	var deprecations []Deprecation
+	config.OnDeprecation = func(d Deprecation) {
+		mutex.Lock()
+		deprecations = append(deprecations, d)
+		mutex.Unlock()
+	}
+
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		resp := acceptedResponse()
+		resp.Header = http.Header{
+			"Deprecation": {"@1688169599"},
+			"Sunset":      {"Wed, 01 Jan 2031 00:00:00 GMT"},
+		}
+		return resp, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if len(deprecations) != 1 {
+		t.Fatalf("Expected 1 deprecation, got %d", len(deprecations))
+	}
+	d := deprecations[0]
+	if d.Endpoint != "/v1/send-email" || d.Sunset.Year() != 2031 || d.Date.Year() != 2023 {
+		t.Errorf("Expected the parsed deprecation, got %+v", d)
+	}
+	if want := "/v1/send-email is deprecated since 2023-06-30 and will be removed on 2031-01-01"; d.String() != want {
+		t.Errorf("Expected %q, got %q", want, d.String())
+	}
+}
+
+func TestDeprecationLoggedWithoutCallback(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.LogLevel = LogLevelError
+	output := captureLog(t)
+
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		resp := acceptedResponse()
+		resp.Header = http.Header{"Deprecation": {"true"}}
+		return resp, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if n := strings.Count(output.String(), "Poodle API Deprecation: /v1/send-email is deprecated"); n != 1 {
+		t.Errorf("Expected the deprecation to be logged once, got %d in %q", n, output.String())
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [3]int
+		ok   bool
+	}{
+		{"1", [3]int{1, 0, 0}, true},
+		{"1.2", [3]int{1, 2, 0}, true},
+		{"v1.2.3", [3]int{1, 2, 3}, true},
+		{"1.2.3-beta.1", [3]int{1, 2, 3}, true},
+		{"1.2.3.4", [3]int{}, false},
+		{"one", [3]int{}, false},
+		{"", [3]int{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseVersion(tt.in)
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("parseVersion(%q): expected %v, %v, got %v, %v", tt.in, tt.want, tt.ok, got, ok)
+		}
+	}
+}
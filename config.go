@@ -2,9 +2,13 @@ package poodle
 
 import (
 	"fmt"
-	"os"
-	"strconv"
+	"math"
+	"net/url"
+	"reflect"
+	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 )
 
 // Default configuration values
@@ -22,6 +26,267 @@ type Config struct {
 	Timeout        time.Duration
 	ConnectTimeout time.Duration
 	Debug          bool
+
+	// Serverless tunes the transport for environments such as AWS Lambda
+	// that freeze between invocations: idle connections are closed after
+	// ServerlessIdleConnTimeout and at most one is kept. As in every mode,
+	// a request whose reused connection was reset is repeated once, which
+	// can send an email twice if the API received the first request before
+	// the reset.
+	Serverless bool
+	// DisableKeepAlives opens a new connection for every request, which
+	// avoids stale connections at the cost of a TLS handshake per request
+	DisableKeepAlives bool
+	// LocalAddr binds outgoing connections to a local IP address, such as
+	// an egress address allowlisted by a firewall. It may include a port.
+	// Validate checks that the address is assigned to the host.
+	LocalAddr string
+	// DialerControl is called with the raw socket of every connection
+	// before it is dialed, to set socket options
+	DialerControl func(network, address string, c syscall.RawConn) error
+	// ForceIPv4 connects to the API over IPv4 only, for networks where
+	// IPv6 is broken
+	ForceIPv4 bool
+	// FallbackDelay is how long a connection to a dual-stack host waits
+	// for IPv6 before also trying IPv4. Zero uses the net.Dialer default
+	// of 300ms and a negative value disables the fallback.
+	FallbackDelay time.Duration
+	// HardenedTransport enforces TLS 1.2 or later, certificate
+	// verification and https for the base URLs and redirects, and refuses
+	// to start while SSLKEYLOGFILE is set. A custom HTTPDoer is wrapped to
+	// check every request URL and, for an *http.Client, its TLS settings.
+	// Violations fail with a SecurityPolicyError.
+	HardenedTransport bool
+	// WarmupOnCreate calls Client.Warmup in the background when the client
+	// is created, logging a failure at LogLevelError
+	WarmupOnCreate bool
+
+	// LogLevel controls what the client logs. Debug is equivalent to
+	// LogLevelTrace.
+	LogLevel LogLevel
+	// DebugMaxBodyBytes is the number of bytes of a request or response
+	// body logged at LogLevelTrace; longer bodies are truncated. Zero uses
+	// DefaultDebugMaxBodyBytes. Bodies that are not JSON are never logged.
+	DebugMaxBodyBytes int
+	// DebugFullBodies logs request and response bodies in full and
+	// whatever their content type, ignoring DebugMaxBodyBytes
+	DebugFullBodies bool
+
+	// PIIMode controls whether recipient addresses appear in errors, their
+	// context maps, trace logs and captured exchanges as they are, hashed
+	// or omitted. The zero value is PIIModeFull.
+	PIIMode PIIMode
+
+	// CaptureExchanges keeps the most recent requests and responses, with
+	// the API key redacted, for Client.LastExchange and RecentExchanges
+	CaptureExchanges bool
+
+	// MaxRetries is the number of times a request failing with a retryable
+	// error is repeated (0-10). Zero disables retries.
+	MaxRetries int
+	// RetryBackoff is the initial delay between retries, doubled on every
+	// attempt. Zero uses DefaultRetryBackoff.
+	RetryBackoff time.Duration
+	// RetryPolicy decides the delay before each retry and may stop
+	// retrying early. Nil uses ExponentialBackoff from RetryBackoff.
+	RetryPolicy RetryPolicy
+	// RetryMaxElapsed caps the total time spent on a send including retries.
+	// Zero means no cap beyond MaxRetries.
+	RetryMaxElapsed time.Duration
+	// OverallDeadline caps the total time of a single send, including
+	// validation, retries and rate-limit waits. When it expires the send
+	// fails with a DeadlineExceededError. A context deadline that is
+	// earlier still applies. Zero means no overall deadline.
+	OverallDeadline time.Duration
+	// BestEffortTimeout bounds a send made with the BestEffort option.
+	// Zero uses DefaultBestEffortTimeout.
+	BestEffortTimeout time.Duration
+	// OnDrop is called with the emails dropped by BestEffort sends and
+	// the error they failed with. Without it, drops are logged at
+	// LogLevelError.
+	OnDrop func(*Email, error)
+	// WaitOnRateLimit retries rate-limited requests after the Retry-After
+	// delay advertised by the API, within the MaxRetries budget.
+	WaitOnRateLimit bool
+	// MaxRateLimitWait caps the waits taken from the Retry-After and
+	// ratelimit-reset headers, for retries, RateLimitError.Wait and
+	// adaptive pacing. Zero uses DefaultMaxRateLimitWait.
+	MaxRateLimitWait time.Duration
+	// MaxRequestsPerSecond limits how many requests the client starts per
+	// second. Zero means unlimited.
+	MaxRequestsPerSecond float64
+
+	// AdaptivePacing spaces out requests when the ratelimit-remaining
+	// response header falls below PacingThreshold, spreading the remaining
+	// budget over the time until ratelimit-reset.
+	AdaptivePacing bool
+	// OnPacing is called whenever adaptive pacing starts, changes its
+	// interval or stops
+	OnPacing func(PacingEvent)
+	// OnRateLimit is called when the API answers 429 Too Many Requests,
+	// with the parsed error, and when adaptive pacing engages, with a nil
+	// error. RateLimitInfo.Entered is true for the first event of an
+	// episode, which lasts until a response other than 429 arrives while
+	// pacing is inactive. Panics in the callback are recovered and logged.
+	OnRateLimit func(info RateLimitInfo, err *RateLimitError)
+	// OnDeprecation is called the first time a response announces that its
+	// endpoint is deprecated or will be removed, with the Deprecation and
+	// Sunset headers parsed. Without a callback the deprecation is logged
+	// at LogLevelError. Panics in the callback are recovered and logged.
+	OnDeprecation func(Deprecation)
+
+	// FallbackBaseURLs are tried in order when a request to the active base
+	// URL fails with a network error, timeout or 5xx response. The last
+	// healthy URL is used for subsequent requests.
+	FallbackBaseURLs []string
+	// FailoverProbeInterval is how long the client stays on a fallback URL
+	// before trying BaseURL again. Zero uses DefaultFailoverProbeInterval.
+	FailoverProbeInterval time.Duration
+
+	// DefaultFrom is the sender of emails without From or FromAddress.
+	// Defaults attached with ContextWithDefaults take precedence.
+	DefaultFrom string
+
+	// AutoNormalize normalizes a copy of every email with Email.Normalize
+	// before it is validated and sent
+	AutoNormalize bool
+
+	// AutoRender renders every email that has Variables with Email.Render
+	// before it is validated and sent. Emails with unresolved variables
+	// fail with a ValidationError.
+	AutoRender bool
+
+	// EncodeSubjects sends subjects containing non-ASCII characters as RFC
+	// 2047 encoded words (see EncodeSubject)
+	EncodeSubjects bool
+
+	// CanonicalPayloads encodes request bodies with Email.MarshalCanonical,
+	// so that the same email is always sent as the same bytes
+	CanonicalPayloads bool
+
+	// SanitizeHTML removes scripts, event handlers and other unsafe markup
+	// from a copy of the HTML body before sending, using
+	// DefaultSanitizePolicy (see Email.SanitizeHTML)
+	SanitizeHTML bool
+
+	// AutoMinifyHTML minifies a copy of an HTML body over MaxContentSize
+	// before validating it, so that emails a little over the limit are
+	// still sent (see Email.MinifyHTML). Bodies within the limit are sent
+	// as they are.
+	AutoMinifyHTML bool
+
+	// FallbackToText sends an email again with only its text part, generated
+	// from the HTML if there is none, when the API rejects its content with
+	// a 422 response. The response is marked with FallbackToText. Other
+	// errors, such as authentication, rate-limit and 5xx errors, never fall
+	// back.
+	FallbackToText bool
+
+	// OnLintWarning is called with the warnings of Email.Lint for every
+	// email sent. The warnings do not fail the send.
+	OnLintWarning func(LintWarning)
+	// DisabledLints lists lint codes, such as LintAllCapsSubject, that are
+	// not passed to OnLintWarning
+	DisabledLints []string
+	// WarnUnverifiedFrom reports LintUnverifiedFrom through OnLintWarning,
+	// or logs it without a callback, the first time an email is sent from
+	// a domain that is not a verified sender domain of the account. The
+	// domain list is fetched in the background and cached for
+	// DomainCacheTTL, so the check never delays or fails a send, and the
+	// callback may be called from another goroutine.
+	WarnUnverifiedFrom bool
+	// StrictContentChecks fails the send with a ValidationError when the
+	// HTML does not look like markup or the text looks like HTML, instead
+	// of only reporting LintHTMLNotMarkup and LintTextIsHTML. Codes listed
+	// in DisabledLints are not checked.
+	StrictContentChecks bool
+
+	// AllowedRecipientDomains, if not empty, restricts recipients to these
+	// domains. Rules are exact domains or wildcards such as *.example.com,
+	// which match subdomains only.
+	AllowedRecipientDomains []string
+	// BlockedRecipientDomains rejects recipients in these domains, using
+	// the same rules as AllowedRecipientDomains
+	BlockedRecipientDomains []string
+	// RejectDisposable fails sends to known disposable email domains (see
+	// IsDisposableAddress) with a ValidationError on the to field
+	RejectDisposable bool
+
+	// VerifyRecipientDNS checks that recipient domains have MX or address
+	// records before sending (see Email.ValidateDeliverability). Domains
+	// that do not exist fail the send; lookup timeouts are only logged.
+	VerifyRecipientDNS bool
+	// DNSResolver is used by VerifyRecipientDNS. Nil uses the system
+	// resolver with cached results; wrap custom resolvers with
+	// NewCachingResolver to cache their answers.
+	DNSResolver Resolver
+	// DNSTimeout bounds the DNS lookups of a send. Zero uses
+	// DefaultDNSTimeout.
+	DNSTimeout time.Duration
+
+	// DedupeWindow suppresses sending an identical email (see
+	// Email.Fingerprint) again within the window. Zero disables the guard.
+	DedupeWindow time.Duration
+	// DedupeStore records recently sent fingerprints. Nil uses an in-memory
+	// store holding DefaultDedupeCapacity entries.
+	DedupeStore DedupeStore
+	// DedupeReturnCached returns the response of the original send for a
+	// suppressed duplicate instead of a DuplicateEmailError, marked with
+	// FromCache. Identical emails sent concurrently through the client
+	// wait for the first send and share its outcome, so only one request
+	// is made.
+	DedupeReturnCached bool
+
+	// AddressValidator checks the from and to addresses of the emails the
+	// client sends. Nil uses DefaultValidator.
+	AddressValidator *Validator
+
+	// PreSend hooks run in order after an email is validated and before it
+	// is encoded. They receive a copy of the email which they may modify,
+	// e.g. to append a footer. An error aborts the send and is returned as
+	// a ValidationError.
+	PreSend []func(*Email) error
+	// PostSend hooks run in order after every attempt to send an email,
+	// including failed attempts and retries, with the email as modified by
+	// the PreSend hooks. Hooks must not modify the email.
+	PostSend []func(*Email, *EmailResponse, error)
+
+	// Archive receives a copy of every successfully sent email. Emails are
+	// archived asynchronously; call Client.Close to flush them on shutdown.
+	Archive Archiver
+	// ArchiveQueueSize is the number of emails buffered for the archiver.
+	// When the buffer is full further emails are not archived. Zero uses
+	// DefaultArchiveQueueSize.
+	ArchiveQueueSize int
+	// OnArchiveError is called with emails that could not be archived
+	OnArchiveError func(*Email, error)
+
+	// DeadLetter keeps the emails a Queue gave up on, after their last
+	// attempt failed, for Queue.ReplayDeadLetters
+	DeadLetter DeadLetterSink
+
+	// ContextHeaderMappers add headers derived from the request context to
+	// every API request, e.g. HeaderFromContext for a correlation ID or
+	// TraceparentMapper. Headers with line breaks are skipped.
+	ContextHeaderMappers []ContextHeaderMapper
+
+	// Locale is a BCP 47 language tag such as "de-DE", sent as the
+	// Accept-Language header of every request so that API error messages
+	// are localized. WithLocale overrides it per request. Empty sends no
+	// Accept-Language header.
+	Locale string
+
+	// Metrics receives request and send metrics, e.g. the collectors of
+	// the poodleprom package. Nil disables metrics.
+	Metrics MetricsHook
+}
+
+// addressValidator returns the validator of the email addresses
+func (c *Config) addressValidator() *Validator {
+	if c.AddressValidator != nil {
+		return c.AddressValidator
+	}
+	return DefaultValidator
 }
 
 // NewConfig creates a new configuration with default values
@@ -34,59 +299,113 @@ func NewConfig() *Config {
 	}
 }
 
-// NewConfigFromEnv creates a new configuration from environment variables
+// NewConfigFromEnv creates a new configuration from environment variables.
+// Variables with invalid values are ignored and the defaults are kept.
 func NewConfigFromEnv() *Config {
-	config := NewConfig()
+	config, _ := loadConfigFromEnv()
+	return config
+}
 
-	if apiKey := os.Getenv("POODLE_API_KEY"); apiKey != "" {
-		config.APIKey = apiKey
+// NewConfigFromEnvStrict creates a new configuration from environment
+// variables and returns a ValidationError listing every variable with an
+// invalid value.
+func NewConfigFromEnvStrict() (*Config, error) {
+	config, errors := loadConfigFromEnv()
+	if len(errors) > 0 {
+		return nil, NewValidationError("Invalid environment configuration", errors)
 	}
+	return config, nil
+}
 
-	if baseURL := os.Getenv("POODLE_BASE_URL"); baseURL != "" {
-		config.BaseURL = baseURL
+// Validate validates the configuration
+func (c *Config) Validate() error {
+	if c.APIKey == "" {
+		return &ValidationError{
+			BaseError: BaseError{Message: "API key is required"},
+			Errors: map[string][]string{
+				"api_key": {"API key is required"},
+			},
+		}
 	}
 
-	if timeoutStr := os.Getenv("POODLE_TIMEOUT"); timeoutStr != "" {
-		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
-			config.Timeout = timeout
+	if c.BaseURL == "" {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Base URL is required"},
+			Errors: map[string][]string{
+				"base_url": {"Base URL is required"},
+			},
 		}
 	}
 
-	if connectTimeoutStr := os.Getenv("POODLE_CONNECT_TIMEOUT"); connectTimeoutStr != "" {
-		if connectTimeout, err := time.ParseDuration(connectTimeoutStr); err == nil {
-			config.ConnectTimeout = connectTimeout
+	if err := validateBaseURL(c.BaseURL); err != nil {
+		return err
+	}
+	c.BaseURL = strings.TrimRight(c.BaseURL, "/")
+
+	// Normalise a copy so the caller's slice is left untouched.
+	fallbacks := make([]string, len(c.FallbackBaseURLs))
+	for i, fallback := range c.FallbackBaseURLs {
+		if err := validateBaseURL(fallback); err != nil {
+			validationErr := err.(*ValidationError)
+			return &ValidationError{
+				BaseError: BaseError{Message: "Fallback base URL is invalid: " + validationErr.Errors["base_url"][0]},
+				Errors: map[string][]string{
+					"fallback_base_urls": validationErr.Errors["base_url"],
+				},
+			}
 		}
+		fallbacks[i] = strings.TrimRight(fallback, "/")
+	}
+	if c.FallbackBaseURLs != nil {
+		c.FallbackBaseURLs = fallbacks
 	}
 
-	if debugStr := os.Getenv("POODLE_DEBUG"); debugStr != "" {
-		if debug, err := strconv.ParseBool(debugStr); err == nil {
-			config.Debug = debug
+	if c.HardenedTransport {
+		if err := c.checkHardened(); err != nil {
+			return err
 		}
 	}
 
-	return config
-}
+	if c.FailoverProbeInterval < 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Failover probe interval must not be negative"},
+			Errors: map[string][]string{
+				"failover_probe_interval": {"Failover probe interval must not be negative"},
+			},
+		}
+	}
 
-// Validate validates the configuration
-func (c *Config) Validate() error {
-	if c.APIKey == "" {
+	if c.LogLevel < LogLevelOff || c.LogLevel > LogLevelTrace {
 		return &ValidationError{
-			BaseError: BaseError{Message: "API key is required"},
+			BaseError: BaseError{Message: "Log level is invalid"},
 			Errors: map[string][]string{
-				"api_key": {"API key is required"},
+				"log_level": {"Log level must be one of LogLevelOff, LogLevelError, LogLevelInfo or LogLevelTrace"},
 			},
 		}
 	}
 
-	if c.BaseURL == "" {
+	if !c.PIIMode.valid() {
 		return &ValidationError{
-			BaseError: BaseError{Message: "Base URL is required"},
+			BaseError: BaseError{Message: "PII mode is invalid"},
 			Errors: map[string][]string{
-				"base_url": {"Base URL is required"},
+				"pii_mode": {"PII mode must be one of PIIModeFull, PIIModeHashed or PIIModeOmit"},
 			},
 		}
 	}
 
+	if c.Locale != "" {
+		locale, ok := normalizeLocale(c.Locale)
+		if !ok {
+			return &ValidationError{
+				BaseError: BaseError{Message: "Locale is invalid"},
+				Errors: map[string][]string{
+					"locale": {"Locale must be a BCP 47 language tag such as de-DE"},
+				},
+			}
+		}
+		c.Locale = locale
+	}
+
 	if c.Timeout <= 0 {
 		return &ValidationError{
 			BaseError: BaseError{Message: "Timeout must be greater than 0"},
@@ -105,10 +424,294 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.LocalAddr != "" {
+		if _, problem := parseLocalAddr(c.LocalAddr); problem != "" {
+			return &ValidationError{
+				BaseError: BaseError{Message: "Local address is invalid"},
+				Errors: map[string][]string{
+					"local_addr": {problem},
+				},
+			}
+		}
+		if localAddr, _ := parseLocalAddr(c.LocalAddr); c.ForceIPv4 && localAddr.IP.To4() == nil {
+			return &ValidationError{
+				BaseError: BaseError{Message: "Local address is invalid"},
+				Errors: map[string][]string{
+					"local_addr": {"Local address must be an IPv4 address when ForceIPv4 is set"},
+				},
+			}
+		}
+	}
+
+	if c.MaxRetries < 0 || c.MaxRetries > MaxRetriesLimit {
+		message := fmt.Sprintf("Max retries must be between 0 and %d", MaxRetriesLimit)
+		return &ValidationError{
+			BaseError: BaseError{Message: message},
+			Errors: map[string][]string{
+				"max_retries": {message},
+			},
+		}
+	}
+
+	if c.RetryBackoff < 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Retry backoff must not be negative"},
+			Errors: map[string][]string{
+				"retry_backoff": {"Retry backoff must not be negative"},
+			},
+		}
+	}
+
+	if c.RetryMaxElapsed < 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Retry max elapsed must not be negative"},
+			Errors: map[string][]string{
+				"retry_max_elapsed": {"Retry max elapsed must not be negative"},
+			},
+		}
+	}
+
+	if c.OverallDeadline < 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Overall deadline must not be negative"},
+			Errors: map[string][]string{
+				"overall_deadline": {"Overall deadline must not be negative"},
+			},
+		}
+	}
+
+	if c.MaxRateLimitWait < 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Max rate limit wait must not be negative"},
+			Errors: map[string][]string{
+				"max_rate_limit_wait": {"Max rate limit wait must not be negative"},
+			},
+		}
+	}
+
+	if c.DebugMaxBodyBytes < 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Debug max body bytes must not be negative"},
+			Errors: map[string][]string{
+				"debug_max_body_bytes": {"Debug max body bytes must not be negative"},
+			},
+		}
+	}
+
+	if c.BestEffortTimeout < 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Best-effort timeout must not be negative"},
+			Errors: map[string][]string{
+				"best_effort_timeout": {"Best-effort timeout must not be negative"},
+			},
+		}
+	}
+
+	if err := validateDomainRules("allowed_recipient_domains", c.AllowedRecipientDomains); err != nil {
+		return err
+	}
+
+	if err := validateDomainRules("blocked_recipient_domains", c.BlockedRecipientDomains); err != nil {
+		return err
+	}
+
+	if c.DNSTimeout < 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "DNS timeout must not be negative"},
+			Errors: map[string][]string{
+				"dns_timeout": {"DNS timeout must not be negative"},
+			},
+		}
+	}
+
+	if c.DedupeWindow < 0 {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Dedupe window must not be negative"},
+			Errors: map[string][]string{
+				"dedupe_window": {"Dedupe window must not be negative"},
+			},
+		}
+	}
+
+	if c.MaxRequestsPerSecond < 0 || math.IsNaN(c.MaxRequestsPerSecond) || math.IsInf(c.MaxRequestsPerSecond, 0) {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Max requests per second must be a non-negative number"},
+			Errors: map[string][]string{
+				"max_requests_per_second": {"Max requests per second must be a non-negative number"},
+			},
+		}
+	}
+
+	return nil
+}
+
+// Warnings returns non-fatal problems with the configuration, such as
+// settings that have no effect in combination with others
+func (c *Config) Warnings() []string {
+	var warnings []string
+
+	if c.MaxRetries == 0 && c.RetryBackoff > 0 {
+		warnings = append(warnings, "RetryBackoff is set but MaxRetries is 0, so no retries will be made")
+	}
+
+	if c.MaxRetries == 0 && c.RetryMaxElapsed > 0 {
+		warnings = append(warnings, "RetryMaxElapsed is set but MaxRetries is 0, so no retries will be made")
+	}
+
+	if c.MaxRetries == 0 && c.WaitOnRateLimit {
+		warnings = append(warnings, "WaitOnRateLimit is set but MaxRetries is 0, so rate-limited requests will not be retried")
+	}
+
+	if c.RetryMaxElapsed > 0 && c.RetryMaxElapsed < c.Timeout {
+		warnings = append(warnings, "RetryMaxElapsed is shorter than Timeout, so a timed-out request will not be retried")
+	}
+
+	if c.OverallDeadline > 0 && c.OverallDeadline < c.Timeout {
+		warnings = append(warnings, "OverallDeadline is shorter than Timeout, so requests are cut off before their timeout")
+	}
+
+	for _, code := range c.DisabledLints {
+		if !lintCodes[strings.ToLower(strings.TrimSpace(code))] {
+			warnings = append(warnings, fmt.Sprintf("DisabledLints contains unknown lint code %q", code))
+		}
+	}
+
+	return warnings
+}
+
+// validateBaseURL checks that the base URL is an absolute http(s) URL
+// without credentials or a fragment.
+func validateBaseURL(baseURL string) error {
+	invalid := func(reason string) error {
+		return &ValidationError{
+			BaseError: BaseError{Message: "Base URL is invalid: " + reason},
+			Errors: map[string][]string{
+				"base_url": {reason},
+			},
+		}
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return invalid("Base URL could not be parsed")
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return invalid("Base URL must use the http or https scheme")
+	}
+
+	if parsed.Host == "" || parsed.Hostname() == "" {
+		return invalid("Base URL must include a host")
+	}
+
+	if parsed.User != nil {
+		return invalid("Base URL must not contain credentials")
+	}
+
+	if parsed.Fragment != "" || strings.Contains(baseURL, "#") {
+		return invalid("Base URL must not contain a fragment")
+	}
+
+	if parsed.RawQuery != "" {
+		return invalid("Base URL must not contain a query string")
+	}
+
 	return nil
 }
 
 // GetUserAgent returns the User-Agent string for HTTP requests
 func (c *Config) GetUserAgent() string {
-	return fmt.Sprintf("poodle-go/%s", SDKVersion)
+	return UserAgent()
+}
+
+// String returns a human-readable representation of every field of the
+// configuration with the API key masked, so that it is safe to print with
+// %v or %+v. Hooks, resolvers, stores and other values that may hold state
+// are shown by their type only.
+func (c Config) String() string {
+	return "Config{" + c.formatFields(false) + "}"
+}
+
+// GoString returns a Go-syntax representation of the configuration with the
+// API key masked, so that it is safe to print with %#v. Hooks, resolvers,
+// stores and other values that may hold state are shown by their type only.
+func (c Config) GoString() string {
+	return "&poodle.Config{" + c.formatFields(true) + "}"
+}
+
+// formatFields formats every field of the configuration, in declaration
+// order, for String or, with goSyntax, GoString
+func (c Config) formatFields(goSyntax bool) string {
+	value := reflect.ValueOf(c)
+	fields := make([]string, value.NumField())
+	for i := range fields {
+		name := value.Type().Field(i).Name
+		field := value.Field(i)
+		if name == "APIKey" {
+			field = reflect.ValueOf(maskAPIKey(c.APIKey))
+		}
+
+		if goSyntax {
+			fields[i] = name + ":" + formatConfigValue(field, "%#v")
+		} else {
+			fields[i] = name + ": " + formatConfigValue(field, "%v")
+		}
+	}
+	return strings.Join(fields, ", ")
+}
+
+// formatConfigValue formats a field of Config with the verb, showing
+// values that may hold state or secrets, such as hooks and stores, by
+// their type
+func formatConfigValue(field reflect.Value, verb string) string {
+	opaque := false
+	switch field.Kind() {
+	case reflect.Func, reflect.Interface, reflect.Ptr, reflect.Chan, reflect.Map:
+		opaque = true
+	case reflect.Slice:
+		opaque = field.Type().Elem().Kind() == reflect.Func
+	}
+	if !opaque {
+		return fmt.Sprintf(verb, field.Interface())
+	}
+
+	typeName := fmt.Sprintf("%v", field.Type())
+	if field.Kind() == reflect.Interface && !field.IsNil() {
+		typeName = fmt.Sprintf("%T", field.Interface())
+	}
+	switch {
+	case field.IsNil() && verb == "%#v":
+		return "(" + typeName + ")(nil)"
+	case field.IsNil():
+		return "<nil>"
+	case verb == "%#v":
+		return "(" + typeName + ")(…)"
+	case field.Kind() == reflect.Slice:
+		return fmt.Sprintf("[%d %s]", field.Len(), field.Type().Elem())
+	default:
+		return typeName
+	}
+}
+
+// Masked returns a copy of the configuration with the API key masked.
+// The copy is safe to log but must not be used to create a client.
+func (c *Config) Masked() *Config {
+	masked := *c
+	masked.APIKey = maskAPIKey(c.APIKey)
+	return &masked
+}
+
+// maskAPIKey hides all but the first and last 4 characters of an API key.
+// Keys too short to be partially revealed are masked entirely.
+func maskAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+
+	runes := []rune(apiKey)
+	if len(runes) < 12 || !utf8.ValidString(apiKey) {
+		return "****"
+	}
+
+	return string(runes[:4]) + "…" + string(runes[len(runes)-4:])
 }
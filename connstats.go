@@ -0,0 +1,85 @@
+package poodle
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// TransportStats is a snapshot of how a client's requests obtained their
+// connections, e.g. to confirm that tuned transport settings let
+// connections be reused
+type TransportStats struct {
+	// NewConnections is the number of requests that opened a connection
+	NewConnections int64
+	// ReusedConnections is the number of requests that reused a
+	// connection, including ones that had been idle in the pool
+	ReusedConnections int64
+	// IdleReused is the number of reused connections taken from the idle
+	// pool, and IdleTime the total time they had been idle
+	IdleReused int64
+	IdleTime   time.Duration
+	// TLSHandshakes is the number of completed TLS handshakes, and
+	// TLSResumptions the number of those that resumed a previous session
+	TLSHandshakes  int64
+	TLSResumptions int64
+}
+
+// ReuseRatio returns the fraction of requests that reused a connection,
+// or 0 if no connection was obtained yet
+func (s TransportStats) ReuseRatio() float64 {
+	total := s.NewConnections + s.ReusedConnections
+	if total == 0 {
+		return 0
+	}
+	return float64(s.ReusedConnections) / float64(total)
+}
+
+// transportStats maintains the counters behind TransportStats
+type transportStats struct {
+	newConns       atomic.Int64
+	reusedConns    atomic.Int64
+	idleReused     atomic.Int64
+	idleTime       atomic.Int64
+	tlsHandshakes  atomic.Int64
+	tlsResumptions atomic.Int64
+}
+
+func (s *transportStats) recordConn(info httptrace.GotConnInfo) {
+	if !info.Reused {
+		s.newConns.Add(1)
+		return
+	}
+
+	s.reusedConns.Add(1)
+	if info.WasIdle {
+		s.idleReused.Add(1)
+		s.idleTime.Add(int64(info.IdleTime))
+	}
+}
+
+func (s *transportStats) recordTLS(state tls.ConnectionState) {
+	s.tlsHandshakes.Add(1)
+	if state.DidResume {
+		s.tlsResumptions.Add(1)
+	}
+}
+
+func (s *transportStats) snapshot() TransportStats {
+	return TransportStats{
+		NewConnections:    s.newConns.Load(),
+		ReusedConnections: s.reusedConns.Load(),
+		IdleReused:        s.idleReused.Load(),
+		IdleTime:          time.Duration(s.idleTime.Load()),
+		TLSHandshakes:     s.tlsHandshakes.Load(),
+		TLSResumptions:    s.tlsResumptions.Load(),
+	}
+}
+
+// TransportStats returns a snapshot of the client's connection reuse
+// statistics. Requests made by a custom HTTPDoer that does not support
+// net/http/httptrace are not counted.
+func (c *Client) TransportStats() TransportStats {
+	return c.httpClient.transport.snapshot()
+}
@@ -0,0 +1,93 @@
+package poodle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func connStatsServer(tls bool) *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"success": true, "message": "Email queued"}`))
+	})
+	if tls {
+		return httptest.NewTLSServer(handler)
+	}
+	return httptest.NewServer(handler)
+}
+
+func TestTransportStatsReuse(t *testing.T) {
+	server := connStatsServer(false)
+	defer server.Close()
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = server.URL
+	config.CaptureExchanges = true
+	config.LogLevel = LogLevelInfo
+
+	client := NewClientWithConfig(config)
+	logs := captureLog(t)
+	for i := 0; i < 3; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	stats := client.TransportStats()
+	if stats.NewConnections != 1 || stats.ReusedConnections != 2 {
+		t.Errorf("Expected 1 new and 2 reused connections, got %+v", stats)
+	}
+	if stats.IdleReused != 2 || stats.IdleTime <= 0 {
+		t.Errorf("Expected the reused connections to come from the idle pool, got %+v", stats)
+	}
+	if ratio := stats.ReuseRatio(); ratio < 0.66 || ratio > 0.67 {
+		t.Errorf("Expected a reuse ratio of 2/3, got %v", ratio)
+	}
+
+	exchanges := client.RecentExchanges(3)
+	if exchanges[0].ConnReused || !exchanges[1].ConnReused || !exchanges[2].ConnReused {
+		t.Errorf("Expected the second and third requests to reuse the connection, got %v, %v and %v",
+			exchanges[0].ConnReused, exchanges[1].ConnReused, exchanges[2].ConnReused)
+	}
+
+	if lines := strings.Count(logs.String(), "conn_reused=true"); lines != 2 {
+		t.Errorf("Expected 2 log lines with a reused connection, got %d in %q", lines, logs.String())
+	}
+	if !strings.Contains(logs.String(), "conn_reused=false") {
+		t.Errorf("Expected the first request to log a new connection, got %q", logs.String())
+	}
+}
+
+func TestTransportStatsTLS(t *testing.T) {
+	server := connStatsServer(true)
+	defer server.Close()
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = server.URL
+
+	client := NewClientWithConfig(config, WithHTTPDoer(server.Client()))
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	stats := client.TransportStats()
+	if stats.TLSHandshakes != 1 || stats.TLSResumptions != 0 {
+		t.Errorf("Expected 1 full TLS handshake, got %+v", stats)
+	}
+	if stats.NewConnections != 1 || stats.ReusedConnections != 1 {
+		t.Errorf("Expected the TLS connection to be reused, got %+v", stats)
+	}
+}
+
+func TestTransportStatsEmpty(t *testing.T) {
+	client := NewClient("test_api_key")
+	if stats := client.TransportStats(); stats != (TransportStats{}) || stats.ReuseRatio() != 0 {
+		t.Errorf("Expected empty statistics, got %+v", stats)
+	}
+}
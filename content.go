@@ -0,0 +1,79 @@
+package poodle
+
+import "fmt"
+
+// contentKind is the body of an email a ContentPart sets
+type contentKind int
+
+const (
+	contentHTML contentKind = iota + 1
+	contentText
+)
+
+func (k contentKind) String() string {
+	switch k {
+	case contentHTML:
+		return "html"
+	case contentText:
+		return "text"
+	default:
+		return "unknown"
+	}
+}
+
+// ContentPart is one body of an email for SendContent and
+// NewContentEmail. Build parts with HTML and Text; the zero value is not a
+// valid part.
+type ContentPart struct {
+	kind    contentKind
+	content string
+}
+
+// HTML returns the HTML body of an email
+func HTML(html string) ContentPart {
+	return ContentPart{kind: contentHTML, content: html}
+}
+
+// Text returns the plain text body of an email
+func Text(text string) ContentPart {
+	return ContentPart{kind: contentText, content: text}
+}
+
+// NewContentEmail creates a new Email instance with the content parts, in
+// any order. It returns a ValidationError if a part is given twice or was
+// not built with HTML or Text.
+func NewContentEmail(from, to, subject string, parts ...ContentPart) (*Email, error) {
+	email := NewEmail(from, to, subject)
+	seen := make(map[contentKind]bool, len(parts))
+	for _, part := range parts {
+		if seen[part.kind] {
+			return nil, NewValidationError("Invalid content", map[string][]string{
+				"content": {fmt.Sprintf("Content part %s is given more than once", part.kind)},
+			})
+		}
+		seen[part.kind] = true
+
+		switch part.kind {
+		case contentHTML:
+			email.HTML = part.content
+		case contentText:
+			email.Text = part.content
+		default:
+			return nil, NewValidationError("Invalid content", map[string][]string{
+				"content": {"Content part must be built with HTML or Text"},
+			})
+		}
+	}
+	return email, nil
+}
+
+// SendContent sends an email with the content parts, e.g.
+// SendContent(from, to, subject, HTML(html), Text(text)). The parts can be
+// given in any order, but each at most once.
+func (c *Client) SendContent(from, to, subject string, parts ...ContentPart) (*EmailResponse, error) {
+	email, err := NewContentEmail(from, to, subject, parts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Send(email)
+}
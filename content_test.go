@@ -0,0 +1,87 @@
+package poodle
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestNewContentEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []ContentPart
+		html  string
+		text  string
+	}{
+		{"html", []ContentPart{HTML("<p>Hi</p>")}, "<p>Hi</p>", ""},
+		{"text", []ContentPart{Text("Hi")}, "", "Hi"},
+		{"html then text", []ContentPart{HTML("<p>Hi</p>"), Text("Hi")}, "<p>Hi</p>", "Hi"},
+		{"text then html", []ContentPart{Text("Hi"), HTML("<p>Hi</p>")}, "<p>Hi</p>", "Hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := NewContentEmail("from@example.com", "to@example.com", "Subject", tt.parts...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if email.HTML != tt.html || email.Text != tt.text {
+				t.Errorf("Expected HTML %q and text %q, got %q and %q", tt.html, tt.text, email.HTML, email.Text)
+			}
+		})
+	}
+}
+
+func TestNewContentEmailInvalidParts(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []ContentPart
+	}{
+		{"duplicate html", []ContentPart{HTML("<p>One</p>"), Text("Hi"), HTML("<p>Two</p>")}},
+		{"duplicate text", []ContentPart{Text("One"), Text("Two")}},
+		{"zero value", []ContentPart{{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewContentEmail("from@example.com", "to@example.com", "Subject", tt.parts...)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected ValidationError, got %v", err)
+			}
+			if len(validationErr.Errors["content"]) != 1 {
+				t.Errorf("Expected a content error, got %v", validationErr.Errors)
+			}
+		})
+	}
+}
+
+func TestSendContent(t *testing.T) {
+	var sent []map[string]string
+	client := NewClient("test_api_key", WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		var payload map[string]string
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("Expected a JSON body, got %s", body)
+		}
+		sent = append(sent, payload)
+		return acceptedResponse(), nil
+	})))
+
+	if _, err := client.SendContent("from@example.com", "to@example.com", "Subject", Text("Hi"), HTML("<p>Hi</p>")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := client.SendWithBoth("from@example.com", "to@example.com", "Subject", "<p>Hi</p>", "Hi"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sent) != 2 || sent[0]["html"] != "<p>Hi</p>" || sent[0]["text"] != "Hi" || sent[0]["html"] != sent[1]["html"] || sent[0]["text"] != sent[1]["text"] {
+		t.Errorf("Expected SendContent to send what SendWithBoth sends, got %v", sent)
+	}
+
+	if _, err := client.SendContent("from@example.com", "to@example.com", "Subject", Text("One"), Text("Two")); err == nil {
+		t.Error("Expected an error for a repeated part")
+	}
+	if len(sent) != 2 {
+		t.Errorf("Expected a repeated part not to be sent, got %d requests", len(sent))
+	}
+}
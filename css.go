@@ -0,0 +1,520 @@
+package poodle
+
+import (
+	"sort"
+	"strings"
+)
+
+// cssRule is a style rule whose selectors can be matched against elements
+// to inline its declarations
+type cssRule struct {
+	selectors    []cssSelector
+	declarations []cssDeclaration
+	order        int
+}
+
+// cssDeclaration is a property and its value, without !important
+type cssDeclaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+// cssSelector is a chain of compound selectors joined by descendant (' ')
+// or child ('>') combinators, e.g. "table.main > td p"
+type cssSelector struct {
+	compounds   []cssCompound
+	combinators []byte // combinators[i] joins compounds[i] and compounds[i+1]
+	specificity int
+}
+
+// cssCompound is a type or universal selector with the ID, class and
+// attribute selectors that follow it, e.g. "a.button[target]"
+type cssCompound struct {
+	tag     string // empty or "*" for any element
+	id      string
+	classes []string
+	attrs   []cssAttrSelector
+}
+
+// cssAttrSelector is an attribute presence ([name]) or equality
+// ([name=value]) selector
+type cssAttrSelector struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// parseStylesheet splits a stylesheet into the rules that can be inlined
+// and the CSS that cannot, such as at-rules and selectors with
+// pseudo-classes, which is returned as text to keep in a style block. The
+// kept text is formatted so that parsing it again keeps it unchanged.
+// order is the order of the first rule, to order rules across stylesheets.
+func parseStylesheet(css string, order int) (rules []cssRule, kept []string) {
+	css = stripCSSComments(css)
+
+	for i := 0; i < len(css); {
+		for i < len(css) && (isTagSpace(css[i]) || css[i] == '}' || css[i] == ';') {
+			i++
+		}
+		if i >= len(css) {
+			break
+		}
+
+		if css[i] == '@' {
+			end := cssStatementEnd(css, i)
+			kept = append(kept, strings.TrimSpace(css[i:end]))
+			i = end
+			continue
+		}
+
+		open := cssIndexOutsideStrings(css, i, '{')
+		if open < 0 {
+			break
+		}
+		close := cssBlockEnd(css, open)
+		prelude := strings.TrimSpace(css[i:open])
+		body := strings.TrimSpace(css[open+1 : close])
+		i = close + 1
+		if prelude == "" {
+			continue
+		}
+
+		rule := cssRule{declarations: parseDeclarations(body), order: order + len(rules)}
+		var unsupported []string
+		for _, text := range splitCSS(prelude, ',') {
+			text = strings.TrimSpace(text)
+			if selector, ok := parseSelector(text); ok {
+				rule.selectors = append(rule.selectors, selector)
+			} else if text != "" {
+				unsupported = append(unsupported, text)
+			}
+		}
+		if len(rule.selectors) > 0 && len(rule.declarations) > 0 {
+			rules = append(rules, rule)
+		}
+		if len(unsupported) > 0 {
+			kept = append(kept, strings.Join(unsupported, ", ")+" { "+body+" }")
+		}
+	}
+	return rules, kept
+}
+
+// stripCSSComments removes /* */ comments outside strings
+func stripCSSComments(css string) string {
+	if !strings.Contains(css, "/*") {
+		return css
+	}
+
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(css) {
+				b.WriteByte(c)
+				i++
+				c = css[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && i+1 < len(css) && css[i+1] == '*':
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += 2 + end + 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// cssStatementEnd returns the index after the at-rule starting at i, which
+// ends with a semicolon or a block
+func cssStatementEnd(css string, i int) int {
+	semicolon := cssIndexOutsideStrings(css, i, ';')
+	open := cssIndexOutsideStrings(css, i, '{')
+	if open >= 0 && (semicolon < 0 || open < semicolon) {
+		return cssBlockEnd(css, open) + 1
+	}
+	if semicolon >= 0 {
+		return semicolon + 1
+	}
+	return len(css)
+}
+
+// cssIndexOutsideStrings returns the index of the first c at or after i
+// that is not inside a string, or -1
+func cssIndexOutsideStrings(css string, i int, c byte) int {
+	var quote byte
+	for ; i < len(css); i++ {
+		switch {
+		case quote != 0:
+			if css[i] == '\\' {
+				i++
+			} else if css[i] == quote {
+				quote = 0
+			}
+		case css[i] == '"' || css[i] == '\'':
+			quote = css[i]
+		case css[i] == c:
+			return i
+		}
+	}
+	return -1
+}
+
+// cssBlockEnd returns the index of the brace closing the block opened at
+// open, or the last index if the block is not closed
+func cssBlockEnd(css string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(css); i++ {
+		switch {
+		case quote != 0:
+			if css[i] == '\\' {
+				i++
+			} else if css[i] == quote {
+				quote = 0
+			}
+		case css[i] == '"' || css[i] == '\'':
+			quote = css[i]
+		case css[i] == '{':
+			depth++
+		case css[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(css) - 1
+}
+
+// splitCSS splits s on sep outside strings, parentheses and brackets, so
+// that e.g. url(data:...;base64,...) stays in one piece
+func splitCSS(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case (c == ')' || c == ']') && depth > 0:
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseDeclarations parses a declaration block or style attribute,
+// skipping declarations without a property or value
+func parseDeclarations(block string) []cssDeclaration {
+	var declarations []cssDeclaration
+	for _, part := range splitCSS(block, ';') {
+		property, value, ok := strings.Cut(part, ":")
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.TrimSpace(value)
+		if !ok || property == "" || value == "" {
+			continue
+		}
+
+		declaration := cssDeclaration{property: property, value: value}
+		if bang := strings.LastIndexByte(value, '!'); bang >= 0 && strings.EqualFold(strings.TrimSpace(value[bang+1:]), "important") {
+			declaration.value = strings.TrimSpace(value[:bang])
+			declaration.important = true
+		}
+		if declaration.value != "" {
+			declarations = append(declarations, declaration)
+		}
+	}
+	return declarations
+}
+
+// formatDeclarations writes declarations as a style attribute value
+func formatDeclarations(declarations []cssDeclaration) string {
+	parts := make([]string, len(declarations))
+	for i, d := range declarations {
+		parts[i] = d.property + ": " + d.value
+		if d.important {
+			parts[i] += " !important"
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseSelector parses a selector made of type, universal, class, ID and
+// attribute selectors joined by descendant and child combinators. ok is
+// false for anything else, such as pseudo-classes, which cannot be
+// inlined.
+func parseSelector(s string) (selector cssSelector, ok bool) {
+	ids, classes, types := 0, 0, 0
+	combinator := byte(0)
+
+	for i := 0; i < len(s); {
+		if isTagSpace(s[i]) || s[i] == '>' {
+			if s[i] == '>' || combinator == 0 {
+				combinator = ' '
+				if s[i] == '>' {
+					combinator = '>'
+				}
+			}
+			i++
+			continue
+		}
+		if len(selector.compounds) > 0 {
+			if combinator == 0 {
+				return selector, false
+			}
+			selector.combinators = append(selector.combinators, combinator)
+		} else if combinator == '>' {
+			return selector, false
+		}
+		combinator = 0
+
+		var compound cssCompound
+		start := i
+		for i < len(s) && !isTagSpace(s[i]) && s[i] != '>' {
+			switch c := s[i]; {
+			case c == '*' && i == start:
+				compound.tag = "*"
+				i++
+			case isCSSIdentByte(c) && i == start:
+				end := cssIdentEnd(s, i)
+				compound.tag = strings.ToLower(s[i:end])
+				types++
+				i = end
+			case c == '.' || c == '#':
+				end := cssIdentEnd(s, i+1)
+				if end == i+1 {
+					return selector, false
+				}
+				if c == '.' {
+					compound.classes = append(compound.classes, s[i+1:end])
+					classes++
+				} else {
+					compound.id = s[i+1 : end]
+					ids++
+				}
+				i = end
+			case c == '[':
+				end := strings.IndexByte(s[i:], ']')
+				if end < 0 {
+					return selector, false
+				}
+				attr, valid := parseAttrSelector(s[i+1 : i+end])
+				if !valid {
+					return selector, false
+				}
+				compound.attrs = append(compound.attrs, attr)
+				classes++
+				i += end + 1
+			default:
+				// Pseudo-classes, sibling combinators and escapes
+				return selector, false
+			}
+		}
+		selector.compounds = append(selector.compounds, compound)
+	}
+
+	if len(selector.compounds) == 0 || combinator == '>' {
+		return selector, false
+	}
+	selector.specificity = ids*10000 + classes*100 + types
+	return selector, true
+}
+
+// parseAttrSelector parses the inside of [name] or [name=value]
+func parseAttrSelector(s string) (cssAttrSelector, bool) {
+	name, value, hasValue := strings.Cut(s, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || cssIdentEnd(name, 0) != len(name) {
+		return cssAttrSelector{}, false
+	}
+
+	attr := cssAttrSelector{name: name, hasValue: hasValue}
+	if hasValue {
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if cssIdentEnd(value, 0) != len(value) {
+			return cssAttrSelector{}, false
+		}
+		attr.value = value
+	}
+	return attr, true
+}
+
+func isCSSIdentByte(c byte) bool {
+	return isASCIILetter(c) || (c >= '0' && c <= '9') || c == '-' || c == '_' || c >= 0x80
+}
+
+// cssIdentEnd returns the index after the identifier starting at i
+func cssIdentEnd(s string, i int) int {
+	for i < len(s) && isCSSIdentByte(s[i]) {
+		i++
+	}
+	return i
+}
+
+// cssElement is an element as seen by selectors
+type cssElement struct {
+	name    string
+	id      string
+	classes []string
+	attrs   map[string]string
+}
+
+// newCSSElement returns the element of a start tag
+func newCSSElement(tag htmlTag) cssElement {
+	element := cssElement{name: tag.name, attrs: make(map[string]string, len(tag.attrs))}
+	for _, attr := range tag.attrs {
+		if _, ok := element.attrs[attr.name]; ok {
+			continue
+		}
+		element.attrs[attr.name] = attr.value
+		switch attr.name {
+		case "id":
+			element.id = attr.value
+		case "class":
+			element.classes = strings.Fields(attr.value)
+		}
+	}
+	return element
+}
+
+// matches reports whether the compound selector matches the element
+func (c cssCompound) matches(e cssElement) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != e.name {
+		return false
+	}
+	if c.id != "" && c.id != e.id {
+		return false
+	}
+	for _, class := range c.classes {
+		found := false
+		for _, has := range e.classes {
+			if has == class {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, attr := range c.attrs {
+		value, ok := e.attrs[attr.name]
+		if !ok || (attr.hasValue && value != attr.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the selector matches the last element of the
+// path, whose other elements are its ancestors from the root
+func (s cssSelector) matches(path []cssElement) bool {
+	return s.matchAt(len(s.compounds)-1, path)
+}
+
+func (s cssSelector) matchAt(compound int, path []cssElement) bool {
+	if len(path) == 0 || !s.compounds[compound].matches(path[len(path)-1]) {
+		return false
+	}
+	if compound == 0 {
+		return true
+	}
+
+	ancestors := path[:len(path)-1]
+	if s.combinators[compound-1] == '>' {
+		return s.matchAt(compound-1, ancestors)
+	}
+	for i := len(ancestors); i > 0; i-- {
+		if s.matchAt(compound-1, ancestors[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// cascadedDeclaration is a declaration with the precedence of its source
+type cascadedDeclaration struct {
+	cssDeclaration
+	specificity int
+	order       int
+}
+
+// inlineSpecificity ranks the style attribute above every selector
+const inlineSpecificity = 1 << 30
+
+// cascade returns the declarations of the rules matching the element at
+// the end of the path followed by its style attribute, in the order of
+// precedence: later declarations override earlier ones of the same
+// property, and each property is listed once.
+func cascade(rules []cssRule, path []cssElement, style string) []cssDeclaration {
+	var matched []cascadedDeclaration
+	for _, rule := range rules {
+		specificity := -1
+		for _, selector := range rule.selectors {
+			if selector.specificity > specificity && selector.matches(path) {
+				specificity = selector.specificity
+			}
+		}
+		if specificity < 0 {
+			continue
+		}
+		for _, declaration := range rule.declarations {
+			matched = append(matched, cascadedDeclaration{declaration, specificity, rule.order})
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	for _, declaration := range parseDeclarations(style) {
+		matched = append(matched, cascadedDeclaration{declaration, inlineSpecificity, 0})
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if a.important != b.important {
+			return !a.important
+		}
+		if a.specificity != b.specificity {
+			return a.specificity < b.specificity
+		}
+		return a.order < b.order
+	})
+
+	// A property set again moves to the end, so that shorthands and
+	// longhands keep their relative order
+	var result []cssDeclaration
+	for _, declaration := range matched {
+		for i, existing := range result {
+			if existing.property == declaration.property {
+				result = append(result[:i], result[i+1:]...)
+				break
+			}
+		}
+		result = append(result, declaration.cssDeclaration)
+	}
+	return result
+}
@@ -0,0 +1,283 @@
+package poodle
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrDeadLettersNotReplayable is returned by Queue.ReplayDeadLetters when
+// Config.DeadLetter is not set or does not implement DeadLetterReplayer
+var ErrDeadLettersNotReplayable = errors.New("poodle: dead-letter sink cannot replay its entries")
+
+// DeadLetterSink keeps the emails a Queue gave up on, so that they can be
+// triaged and replayed after an incident. Store is called from the queue's
+// workers, concurrently, with the email, a *DeadLetterError recording its
+// attempts and the number of attempts made.
+type DeadLetterSink interface {
+	Store(ctx context.Context, email *Email, err error, attempts int) error
+}
+
+// DeadLetterReplayer is implemented by dead-letter sinks whose entries
+// Queue.ReplayDeadLetters can re-enqueue
+type DeadLetterReplayer interface {
+	// ReplayDeadLetters calls fn with the stored entries in the order they
+	// were stored, and removes the entries for which fn returns nil. It
+	// stops at the first error and returns it.
+	ReplayDeadLetters(ctx context.Context, fn func(DeadLetter) error) error
+}
+
+// AttemptError records a failed attempt to send an email from a Queue
+type AttemptError struct {
+	At        time.Time `json:"at"`
+	ErrorType string    `json:"error_type"`
+	// Code is the HTTP status of the error, or zero for network errors
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// DeadLetterError is passed to a DeadLetterSink with the history of the
+// attempts to send an email. It unwraps to the error of the last attempt.
+// The history covers the attempts made by this process; attempts made
+// before by another process sharing the QueueStore are only counted in
+// Attempts.
+type DeadLetterError struct {
+	BaseError
+	Attempts       int
+	FirstAttemptAt time.Time
+	LastAttemptAt  time.Time
+	History        []AttemptError
+	LastError      error
+}
+
+func NewDeadLetterError(attempts int, history []AttemptError, lastErr error) *DeadLetterError {
+	err := &DeadLetterError{
+		BaseError: BaseError{
+			Message: fmt.Sprintf("Email was not sent after %d attempts: %s", attempts, lastErr.Error()),
+			Code:    0, // The status of each attempt is in History
+			ContextMap: map[string]interface{}{
+				"error_type": "dead_letter",
+				"attempts":   attempts,
+			},
+		},
+		Attempts:  attempts,
+		History:   history,
+		LastError: lastErr,
+	}
+	if len(history) > 0 {
+		err.FirstAttemptAt = history[0].At
+		err.LastAttemptAt = history[len(history)-1].At
+	}
+	return err
+}
+
+// Unwrap returns the error of the last attempt
+func (e *DeadLetterError) Unwrap() error {
+	return e.LastError
+}
+
+// newAttemptError records err as the failure of an attempt made at the
+// given time
+func newAttemptError(at time.Time, err error) AttemptError {
+	attempt := AttemptError{At: at.UTC(), ErrorType: errorTypeOf(err), Message: err.Error()}
+	var poodleErr PoodleError
+	if errors.As(err, &poodleErr) {
+		attempt.Code = poodleErr.StatusCode()
+	}
+	return attempt
+}
+
+// DeadLetter is a line of a FileDeadLetterSink
+type DeadLetter struct {
+	DeadAt time.Time `json:"dead_at"`
+	Email  *Email    `json:"email"`
+	// Priority and Variables are not part of the encoded Email
+	Priority       Priority          `json:"priority"`
+	Variables      map[string]string `json:"variables,omitempty"`
+	Attempts       int               `json:"attempts"`
+	FirstAttemptAt time.Time         `json:"first_attempt_at"`
+	LastAttemptAt  time.Time         `json:"last_attempt_at"`
+	Error          string            `json:"error"`
+	ErrorType      string            `json:"error_type"`
+	History        []AttemptError    `json:"history,omitempty"`
+}
+
+// newDeadLetter builds the record of an email stored in a dead-letter sink
+func newDeadLetter(email *Email, err error, attempts int) DeadLetter {
+	letter := DeadLetter{
+		DeadAt:    time.Now().UTC(),
+		Email:     email,
+		Priority:  email.Priority,
+		Variables: email.Variables,
+		Attempts:  attempts,
+		Error:     err.Error(),
+		ErrorType: errorTypeOf(err),
+	}
+
+	var deadLetterErr *DeadLetterError
+	if errors.As(err, &deadLetterErr) {
+		letter.FirstAttemptAt = deadLetterErr.FirstAttemptAt
+		letter.LastAttemptAt = deadLetterErr.LastAttemptAt
+		letter.History = deadLetterErr.History
+		if deadLetterErr.LastError != nil {
+			letter.ErrorType = errorTypeOf(deadLetterErr.LastError)
+		}
+	}
+	return letter
+}
+
+// FileDeadLetterSink appends dead letters to a JSON-lines file, one
+// DeadLetter per line. The file holds full emails and is created readable
+// by its owner only.
+type FileDeadLetterSink struct {
+	path  string
+	mutex sync.Mutex
+}
+
+var (
+	_ DeadLetterSink     = (*FileDeadLetterSink)(nil)
+	_ DeadLetterReplayer = (*FileDeadLetterSink)(nil)
+)
+
+// NewFileDeadLetterSink creates the dead-letter file at path if it does not
+// exist yet
+func NewFileDeadLetterSink(path string) (*FileDeadLetterSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	return &FileDeadLetterSink{path: path}, nil
+}
+
+// Store implements DeadLetterSink
+func (s *FileDeadLetterSink) Store(ctx context.Context, email *Email, err error, attempts int) error {
+	line, encodeErr := json.Marshal(newDeadLetter(email, err, attempts))
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, openErr := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if openErr != nil {
+		return openErr
+	}
+	if _, writeErr := file.Write(append(line, '\n')); writeErr != nil {
+		file.Close()
+		return writeErr
+	}
+	if syncErr := file.Sync(); syncErr != nil {
+		file.Close()
+		return syncErr
+	}
+	return file.Close()
+}
+
+// DeadLetters returns the stored entries in the order they were stored
+func (s *FileDeadLetterSink) DeadLetters() ([]DeadLetter, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	letters, _, err := s.read()
+	return letters, err
+}
+
+// ReplayDeadLetters implements DeadLetterReplayer. The entries that remain
+// are written to a temporary file that replaces the dead-letter file, so a
+// crash never loses them. Stores wait until the replay is done.
+func (s *FileDeadLetterSink) ReplayDeadLetters(ctx context.Context, fn func(DeadLetter) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	letters, lines, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	replayed := 0
+	for _, letter := range letters {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if err = fn(letter); err != nil {
+			break
+		}
+		replayed++
+	}
+	if replayed == 0 {
+		return err
+	}
+
+	if writeErr := s.rewrite(lines[replayed:]); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// read decodes the dead-letter file, returning the entries and their
+// lines
+func (s *FileDeadLetterSink) read() ([]DeadLetter, [][]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var letters []DeadLetter
+	var lines [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(line, &letter); err != nil {
+			return nil, nil, fmt.Errorf("poodle: dead-letter file %s line %d: %w", s.path, n, err)
+		}
+		letters = append(letters, letter)
+		lines = append(lines, append([]byte(nil), line...))
+	}
+	return letters, lines, scanner.Err()
+}
+
+// rewrite replaces the dead-letter file with the given lines
+func (s *FileDeadLetterSink) rewrite(lines [][]byte) error {
+	dir := filepath.Dir(s.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	for _, line := range lines {
+		if _, err := tmp.Write(append(line, '\n')); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
@@ -0,0 +1,172 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueueDeadLetters(t *testing.T) {
+	sink, err := NewFileDeadLetterSink(filepath.Join(t.TempDir(), "dead.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 0
+	config.DeadLetter = sink
+	client := NewClientWithConfig(config)
+
+	var mutex sync.Mutex
+	status := http.StatusServiceUnavailable
+	var sent []string
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		body, _ := io.ReadAll(req.Body)
+		sent = append(sent, string(body))
+		if status != http.StatusAccepted {
+			return jsonResponse(status, `{"message": "Unavailable"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	email := priorityEmail("Hello {{name}}", PriorityHigh)
+	email.Variables = map[string]string{"name": "Jane"}
+	email.SetHeader("X-Tenant", "acme")
+
+	queue := NewQueue(client, WithQueueMaxAttempts(3), WithQueueBackoff(time.Millisecond, time.Millisecond), WithQueuePollInterval(time.Millisecond))
+	if err := queue.Enqueue(email); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	queue.Close()
+
+	letters, err := sink.DeadLetters()
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("Expected one dead letter, got %v and %v", letters, err)
+	}
+	letter := letters[0]
+	if letter.Attempts != 3 || len(letter.History) != 3 || letter.ErrorType != "http_error" {
+		t.Errorf("Expected 3 recorded attempts, got %+v", letter)
+	}
+	for _, attempt := range letter.History {
+		if attempt.Code != http.StatusServiceUnavailable || attempt.ErrorType != "http_error" || attempt.Message == "" {
+			t.Errorf("Expected the 503 of each attempt, got %+v", attempt)
+		}
+	}
+	if letter.FirstAttemptAt.IsZero() || letter.LastAttemptAt.Before(letter.FirstAttemptAt) {
+		t.Errorf("Expected the first and last attempt times, got %v and %v", letter.FirstAttemptAt, letter.LastAttemptAt)
+	}
+	if letter.Priority != PriorityHigh || letter.Variables["name"] != "Jane" || letter.Email.Headers["X-Tenant"] != "acme" {
+		t.Errorf("Expected the full email, got %+v", letter)
+	}
+
+	// Replay once the API is back
+	mutex.Lock()
+	status = http.StatusAccepted
+	sent = nil
+	mutex.Unlock()
+
+	var results []SendResult
+	queue = NewQueue(client, WithResultHandler(func(result SendResult) {
+		results = append(results, result)
+	}))
+	replayed, err := queue.ReplayDeadLetters(context.Background())
+	if err != nil || replayed != 1 {
+		t.Fatalf("Expected one replayed email, got %d and %v", replayed, err)
+	}
+	queue.Close()
+
+	if len(results) != 1 || results[0].Err != nil || results[0].Email.Subject != "Hello {{name}}" || results[0].Email.Priority != PriorityHigh {
+		t.Errorf("Expected the replayed email to be sent, got %+v", results)
+	}
+	if len(sent) != 1 {
+		t.Errorf("Expected one request, got %d", len(sent))
+	}
+	if letters, _ := sink.DeadLetters(); len(letters) != 0 {
+		t.Errorf("Expected the sink to be empty after the replay, got %d entries", len(letters))
+	}
+}
+
+func TestQueueDeadLettersNonRetryable(t *testing.T) {
+	var stored []*DeadLetterError
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DeadLetter = deadLetterFunc(func(ctx context.Context, email *Email, err error, attempts int) error {
+		var deadLetterErr *DeadLetterError
+		if errors.As(err, &deadLetterErr) && attempts == deadLetterErr.Attempts {
+			stored = append(stored, deadLetterErr)
+		}
+		return nil
+	})
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusBadRequest, `{"message": "Bad sender"}`), nil
+	})
+
+	queue := NewQueue(client, WithQueueMaxAttempts(3))
+	queue.Enqueue(priorityEmail("invalid", PriorityNormal))
+	queue.Enqueue(priorityEmail("invalid", PriorityNormal))
+	queue.Close()
+
+	if len(stored) != 2 || stored[0].Attempts != 1 || len(stored[0].History) != 1 {
+		t.Fatalf("Expected both emails to be dead-lettered after one attempt, got %+v", stored)
+	}
+	var validationErr *ValidationError
+	if !errors.As(stored[0], &validationErr) {
+		t.Errorf("Expected the dead-letter error to unwrap to the ValidationError, got %v", stored[0].LastError)
+	}
+
+	if _, err := queue.ReplayDeadLetters(context.Background()); !errors.Is(err, ErrDeadLettersNotReplayable) {
+		t.Errorf("Expected ErrDeadLettersNotReplayable, got %v", err)
+	}
+}
+
+func TestFileDeadLetterSinkReplayStops(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	sink, err := NewFileDeadLetterSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subject := range []string{"one", "two", "three"} {
+		email := NewTextEmail("from@example.com", "to@example.com", subject, "Hi")
+		if err := sink.Store(context.Background(), email, NewNetworkError("down", ""), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	failed := errors.New("queue closed")
+	var seen []string
+	err = sink.ReplayDeadLetters(context.Background(), func(letter DeadLetter) error {
+		seen = append(seen, letter.Email.Subject)
+		if letter.Email.Subject == "two" {
+			return failed
+		}
+		return nil
+	})
+	if !errors.Is(err, failed) || len(seen) != 2 {
+		t.Fatalf("Expected the replay to stop at the error, got %v after %v", err, seen)
+	}
+
+	letters, err := sink.DeadLetters()
+	if err != nil || len(letters) != 2 || letters[0].Email.Subject != "two" || letters[1].Email.Subject != "three" {
+		t.Errorf("Expected the unreplayed entries to remain in order, got %+v and %v", letters, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the file to be private, got %v and %v", info, err)
+	}
+}
+
+// deadLetterFunc adapts a function to DeadLetterSink
+type deadLetterFunc func(ctx context.Context, email *Email, err error, attempts int) error
+
+func (f deadLetterFunc) Store(ctx context.Context, email *Email, err error, attempts int) error {
+	return f(ctx, email, err, attempts)
+}
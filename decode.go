@@ -0,0 +1,37 @@
+package poodle
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// utf8BOM is the byte order mark some CDNs prepend to response bodies
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// decodeResponseBody decodes the first JSON value of a response body into
+// v. A leading byte order mark is skipped, and the value may be followed
+// by whitespace or further JSON values, as appended by some middleware,
+// which are ignored. Anything else is an error.
+func decodeResponseBody(body []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(body, utf8BOM)))
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	for {
+		var trailing json.RawMessage
+		if err := decoder.Decode(&trailing); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// looksLikeJSON reports whether a body starts like a JSON object or array,
+// so that failing to decode it means it is malformed rather than another
+// format
+func looksLikeJSON(body []byte) bool {
+	body = bytes.TrimSpace(bytes.TrimPrefix(body, utf8BOM))
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
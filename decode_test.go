@@ -0,0 +1,89 @@
+package poodle
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixtureResponse returns a response with a body from testdata/responses
+func fixtureResponse(t *testing.T, status int, name string) *http.Response {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "responses", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+}
+
+func TestSendDecodesLenientResponseBodies(t *testing.T) {
+	for _, name := range []string{"bom.json", "trailing_newline.json", "concatenated.json"} {
+		t.Run(name, func(t *testing.T) {
+			client := NewClient("test_api_key", WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+				return fixtureResponse(t, http.StatusAccepted, name), nil
+			})))
+
+			response, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if !response.Success || response.Message != "Email queued" || response.RawBody != "" {
+				t.Errorf("Expected the first JSON value to be decoded, got %+v", response)
+			}
+		})
+	}
+}
+
+func TestSendMalformedResponseBody(t *testing.T) {
+	for _, name := range []string{"truncated.json", "trailing_garbage.json"} {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			config := NewConfig()
+			config.APIKey = "test_api_key"
+			config.MaxRetries = 2
+			client := NewClientWithConfig(config, WithClock(newTestClock()), WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+				calls++
+				return fixtureResponse(t, http.StatusAccepted, name), nil
+			})))
+
+			_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+			var parseErr *ResponseParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected ResponseParseError, got %T: %v", err, err)
+			}
+			want, _ := os.ReadFile(filepath.Join("testdata", "responses", name))
+			if !bytes.Equal(parseErr.Body, want) || parseErr.StatusCode() != http.StatusAccepted {
+				t.Errorf("Expected the raw body and status 202, got %q, %d", parseErr.Body, parseErr.StatusCode())
+			}
+			if calls != 1 {
+				t.Errorf("Expected an accepted email not to be sent again, got %d requests", calls)
+			}
+		})
+	}
+}
+
+func TestErrorResponseWithBOMAndTrailingValue(t *testing.T) {
+	client := NewClient("test_api_key", WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return fixtureResponse(t, http.StatusUnauthorized, "bom_concatenated_401.json"), nil
+	})))
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	var authErr *AuthenticationError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("Expected AuthenticationError, got %T", err)
+	}
+	if authErr.Reason != AuthReasonExpiredKey || authErr.Message != "Invalid API key" {
+		t.Errorf("Expected the decoded reason and message, got %s, %q", authErr.Reason, authErr.Message)
+	}
+	if _, ok := authErr.ContextMap["response_body"]; ok {
+		t.Error("Expected the body to be treated as JSON rather than quoted")
+	}
+}
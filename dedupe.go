@@ -0,0 +1,316 @@
+package poodle
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultDedupeCapacity is the number of fingerprints kept by the default
+// in-memory dedupe store
+const DefaultDedupeCapacity = 10000
+
+// DedupeStore records the fingerprints of recently sent emails. It must be
+// safe for concurrent use; Claim in particular must be atomic so that two
+// concurrent sends of the same email cannot both claim it. A Redis store
+// can implement Claim with SET NX and an expiry.
+type DedupeStore interface {
+	// Claim records the fingerprint for the window. It returns true if the
+	// fingerprint was not already recorded; otherwise it returns false and
+	// the response saved by Complete, which is nil while the original send
+	// is still in flight.
+	Claim(ctx context.Context, fingerprint string, window time.Duration) (bool, *EmailResponse, error)
+	// Complete saves the response of a successful send for the fingerprint
+	Complete(ctx context.Context, fingerprint string, response *EmailResponse) error
+	// Release forgets a fingerprint whose send failed so it can be retried
+	Release(ctx context.Context, fingerprint string) error
+}
+
+// MemoryDedupeStore is an in-memory DedupeStore that evicts the least
+// recently claimed fingerprints once its capacity is reached
+type MemoryDedupeStore struct {
+	capacity int
+	clock    Clock
+	mutex    sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+// dedupeEntry is a fingerprint held by a MemoryDedupeStore
+type dedupeEntry struct {
+	fingerprint string
+	expires     time.Time
+	response    *EmailResponse
+}
+
+// NewMemoryDedupeStore creates an in-memory dedupe store holding up to
+// capacity fingerprints. A capacity of zero or less uses
+// DefaultDedupeCapacity.
+func NewMemoryDedupeStore(capacity int) *MemoryDedupeStore {
+	return newMemoryDedupeStore(capacity, realClock{})
+}
+
+func newMemoryDedupeStore(capacity int, clock Clock) *MemoryDedupeStore {
+	if capacity <= 0 {
+		capacity = DefaultDedupeCapacity
+	}
+	return &MemoryDedupeStore{
+		capacity: capacity,
+		clock:    clock,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Claim implements DedupeStore
+func (s *MemoryDedupeStore) Claim(ctx context.Context, fingerprint string, window time.Duration) (bool, *EmailResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.clock.Now()
+	if element, ok := s.entries[fingerprint]; ok {
+		entry := element.Value.(*dedupeEntry)
+		if now.Before(entry.expires) {
+			return false, entry.response, nil
+		}
+		s.remove(element)
+	}
+
+	s.entries[fingerprint] = s.order.PushFront(&dedupeEntry{
+		fingerprint: fingerprint,
+		expires:     now.Add(window),
+	})
+	for s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+
+	return true, nil, nil
+}
+
+// Complete implements DedupeStore
+func (s *MemoryDedupeStore) Complete(ctx context.Context, fingerprint string, response *EmailResponse) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.entries[fingerprint]; ok {
+		element.Value.(*dedupeEntry).response = response
+	}
+	return nil
+}
+
+// Release implements DedupeStore
+func (s *MemoryDedupeStore) Release(ctx context.Context, fingerprint string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.entries[fingerprint]; ok {
+		s.remove(element)
+	}
+	return nil
+}
+
+// Len returns the number of fingerprints held, including expired ones not
+// yet evicted
+func (s *MemoryDedupeStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *MemoryDedupeStore) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.entries, element.Value.(*dedupeEntry).fingerprint)
+}
+
+// sendDeduplicated sends the email unless an identical email was sent
+// within the dedupe window. Store failures are logged in debug mode and do
+// not prevent the send.
+func (c *Client) sendDeduplicated(ctx context.Context, config *Config, email *Email) (*EmailResponse, error) {
+	// Invalid emails are rejected and recorded by sendEmail
+	if email.validate(config.addressValidator()) != nil {
+		return c.httpClient.sendEmail(ctx, config, email)
+	}
+
+	fingerprint := email.Fingerprint()
+	if !config.DedupeReturnCached {
+		return c.sendClaimed(ctx, config, email, fingerprint)
+	}
+
+	// Identical sends wait for the one in flight and share its outcome
+	flight, first := c.flights.join(fingerprint)
+	if !first {
+		select {
+		case <-flight.done:
+		case <-ctx.Done():
+			err := NewNetworkError("Request cancelled: "+ctx.Err().Error(), "")
+			c.httpClient.observeSend(config, err)
+			return nil, err
+		}
+		if flight.err != nil {
+			// Callers change errors in place, e.g. to scrub addresses,
+			// so every waiter gets its own copy
+			err := copyError(flight.err)
+			c.httpClient.observeSend(config, err)
+			return nil, err
+		}
+		c.httpClient.observeSend(config, nil)
+		return cachedResponse(flight.response), nil
+	}
+
+	finished := false
+	defer func() {
+		if finished {
+			return
+		}
+		// sendClaimed panicked, e.g. in a PreSend hook, or called
+		// runtime.Goexit: release the waiters with an error before the
+		// panic or exit continues
+		recovered := recover()
+		if recovered == nil {
+			c.flights.finish(fingerprint, flight, nil, errors.New("poodle: identical send exited"))
+			return
+		}
+		c.flights.finish(fingerprint, flight, nil, fmt.Errorf("poodle: identical send panicked: %v", recovered))
+		panic(recovered)
+	}()
+	response, err := c.sendClaimed(ctx, config, email, fingerprint)
+	finished = true
+	c.flights.finish(fingerprint, flight, response, err)
+	return response, err
+}
+
+// sendClaimed claims the fingerprint in the dedupe store and sends the
+// email if it was not claimed already
+func (c *Client) sendClaimed(ctx context.Context, config *Config, email *Email, fingerprint string) (*EmailResponse, error) {
+	claimed, cached, err := c.dedupe.Claim(ctx, fingerprint, config.DedupeWindow)
+	if err != nil {
+		if config.logs(LogLevelError) {
+			log.Printf("Poodle Dedupe: claim failed, sending anyway: %s", err.Error())
+		}
+		return c.httpClient.sendEmail(ctx, config, email)
+	}
+
+	if !claimed {
+		if config.DedupeReturnCached && cached != nil {
+			c.httpClient.observeSend(config, nil)
+			return cachedResponse(cached), nil
+		}
+		err := NewDuplicateEmailError(fingerprint)
+		c.httpClient.observeSend(config, err)
+		return nil, err
+	}
+
+	sent := false
+	defer func() {
+		if sent {
+			return
+		}
+		// The send failed or panicked, so the email may be sent again. The
+		// send context may be cancelled already.
+		if releaseErr := c.dedupe.Release(context.Background(), fingerprint); releaseErr != nil && config.logs(LogLevelError) {
+			log.Printf("Poodle Dedupe: release failed: %s", releaseErr.Error())
+		}
+	}()
+	response, err := c.httpClient.sendEmail(ctx, config, email)
+	if err != nil {
+		return nil, err
+	}
+	sent = true
+
+	if err := c.dedupe.Complete(ctx, fingerprint, response); err != nil && config.logs(LogLevelError) {
+		log.Printf("Poodle Dedupe: complete failed: %s", err.Error())
+	}
+
+	return response, nil
+}
+
+// cachedResponse returns a copy of the response of an original send,
+// marked as served from the cache
+func cachedResponse(original *EmailResponse) *EmailResponse {
+	response := *original
+	response.FromCache = true
+	return &response
+}
+
+// dedupeFlights tracks the sends in flight by fingerprint, so that
+// identical sends wait for the first one instead of claiming the
+// fingerprint while it is in flight
+type dedupeFlights struct {
+	mutex sync.Mutex
+	calls map[string]*dedupeFlight
+}
+
+// dedupeFlight is a send in flight. response and err are set before done
+// is closed.
+type dedupeFlight struct {
+	done     chan struct{}
+	response *EmailResponse
+	err      error
+}
+
+// join returns the flight of the fingerprint, and true if the caller
+// started it and must finish it
+func (f *dedupeFlights) join(fingerprint string) (*dedupeFlight, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if flight, ok := f.calls[fingerprint]; ok {
+		return flight, false
+	}
+	if f.calls == nil {
+		f.calls = make(map[string]*dedupeFlight)
+	}
+	flight := &dedupeFlight{done: make(chan struct{})}
+	f.calls[fingerprint] = flight
+	return flight, true
+}
+
+// finish records the outcome of a flight and releases its waiters
+func (f *dedupeFlights) finish(fingerprint string, flight *dedupeFlight, response *EmailResponse, err error) {
+	f.mutex.Lock()
+	delete(f.calls, fingerprint)
+	f.mutex.Unlock()
+
+	flight.response, flight.err = response, err
+	close(flight.done)
+}
+
+// copyError returns a shallow copy of a Poodle error with its own context
+// and field errors, so that changing the copy leaves the original alone.
+// Other errors are returned as they are.
+func copyError(err error) error {
+	original := reflect.ValueOf(err)
+	if original.Kind() != reflect.Pointer || original.IsNil() || original.Elem().Kind() != reflect.Struct {
+		return err
+	}
+	if _, ok := err.(interface{ base() *BaseError }); !ok {
+		return err
+	}
+
+	copied := reflect.New(original.Elem().Type())
+	copied.Elem().Set(original.Elem())
+	copiedErr := copied.Interface().(error)
+
+	base := copiedErr.(interface{ base() *BaseError }).base()
+	if base.ContextMap != nil {
+		contextMap := make(map[string]interface{}, len(base.ContextMap))
+		for key, value := range base.ContextMap {
+			contextMap[key] = value
+		}
+		base.ContextMap = contextMap
+	}
+	if validationErr, ok := copiedErr.(*ValidationError); ok && validationErr.Errors != nil {
+		fieldErrors := make(map[string][]string, len(validationErr.Errors))
+		for field, messages := range validationErr.Errors {
+			fieldErrors[field] = messages
+		}
+		validationErr.Errors = fieldErrors
+	}
+	return copiedErr
+}
@@ -0,0 +1,385 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newDedupeTestClient(config *Config, status int) (*Client, *int32, *testClock) {
+	clock := newTestClock()
+	client := NewClientWithConfig(config, WithClock(clock))
+
+	var calls int32
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		if status == http.StatusAccepted {
+			return acceptedResponse(), nil
+		}
+		return jsonResponse(status, `{"message":"Server error"}`), nil
+	})
+
+	return client, &calls, clock
+}
+
+func TestDedupeSuppressesDuplicates(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DedupeWindow = time.Minute
+
+	client, calls, clock := newDedupeTestClient(config, http.StatusAccepted)
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+	duplicateErr, ok := err.(*DuplicateEmailError)
+	if !ok {
+		t.Fatalf("Expected DuplicateEmailError, got %T", err)
+	}
+	if duplicateErr.Fingerprint != NewTextEmail("from@example.com", "to@example.com", "Receipt", "Thanks").Fingerprint() {
+		t.Errorf("Expected the error to carry the email fingerprint")
+	}
+
+	if _, err := client.SendText("from@example.com", "other@example.com", "Receipt", "Thanks"); err != nil {
+		t.Errorf("Expected a different recipient to be sent, got: %v", err)
+	}
+
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("Expected 2 requests, got %d", atomic.LoadInt32(calls))
+	}
+
+	clock.Sleep(context.Background(), time.Minute)
+	if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err != nil {
+		t.Errorf("Expected the email to be sent again after the window, got: %v", err)
+	}
+	if atomic.LoadInt32(calls) != 3 {
+		t.Errorf("Expected 3 requests, got %d", atomic.LoadInt32(calls))
+	}
+}
+
+func TestDedupeReturnCached(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DedupeWindow = time.Minute
+	config.DedupeReturnCached = true
+
+	client, calls, _ := newDedupeTestClient(config, http.StatusAccepted)
+
+	first, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	second, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+	if err != nil {
+		t.Fatalf("Expected the cached response, got: %v", err)
+	}
+	if second.Message != first.Message || second == first || !second.FromCache || first.FromCache {
+		t.Errorf("Expected a copy of the original response, got %+v", second)
+	}
+	if atomic.LoadInt32(calls) != 1 {
+		t.Errorf("Expected 1 request, got %d", atomic.LoadInt32(calls))
+	}
+}
+
+func TestDedupeFailedSendIsNotRemembered(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DedupeWindow = time.Minute
+
+	client, calls, _ := newDedupeTestClient(config, http.StatusInternalServerError)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+		if _, ok := err.(*HTTPError); !ok {
+			t.Fatalf("Expected HTTPError, got %T", err)
+		}
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("Expected failed sends to be retryable, got %d requests", atomic.LoadInt32(calls))
+	}
+}
+
+func TestDedupeConcurrentSends(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DedupeWindow = time.Minute
+
+	client, calls, _ := newDedupeTestClient(config, http.StatusAccepted)
+
+	var wg sync.WaitGroup
+	var sent, duplicates int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+			switch err.(type) {
+			case nil:
+				atomic.AddInt32(&sent, 1)
+			case *DuplicateEmailError:
+				atomic.AddInt32(&duplicates, 1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sent != 1 || duplicates != 49 || atomic.LoadInt32(calls) != 1 {
+		t.Errorf("Expected exactly one send, got %d sent, %d duplicates, %d requests", sent, duplicates, atomic.LoadInt32(calls))
+	}
+}
+
+func TestDedupeReturnCachedConcurrentSends(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DedupeWindow = time.Minute
+	config.DedupeReturnCached = true
+
+	client := NewClientWithConfig(config)
+	var calls int32
+	release := make(chan struct{})
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return jsonResponse(http.StatusAccepted, `{"success": true, "message": "Email queued", "message_id": "msg_1"}`), nil
+	})
+
+	var wg sync.WaitGroup
+	var fresh, cached int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+			if err != nil {
+				t.Errorf("Expected the shared response, got %v", err)
+				return
+			}
+			if id, _ := response.GetString("message_id"); id != "msg_1" {
+				t.Errorf("Expected the message ID of the original send, got %q", id)
+			}
+			if response.FromCache {
+				atomic.AddInt32(&cached, 1)
+			} else {
+				atomic.AddInt32(&fresh, 1)
+			}
+		}()
+	}
+	// Let the sends pile up behind the first request
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 1 || fresh != 1 || cached != 49 {
+		t.Errorf("Expected exactly one request, got %d requests, %d fresh and %d cached responses", atomic.LoadInt32(&calls), fresh, cached)
+	}
+}
+
+func TestDedupeReturnCachedFailures(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DedupeWindow = time.Minute
+	config.DedupeReturnCached = true
+	config.MaxRetries = 0
+
+	client, calls, clock := newDedupeTestClient(config, http.StatusAccepted)
+	failing := true
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(calls, 1)
+		if failing {
+			return jsonResponse(http.StatusInternalServerError, `{"message":"Server error"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	// A failed send is not cached
+	if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err == nil {
+		t.Fatal("Expected the send to fail")
+	}
+	failing = false
+	response, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+	if err != nil || response.FromCache {
+		t.Fatalf("Expected a fresh send after the failure, got %+v, %v", response, err)
+	}
+
+	// The cached response expires with the window
+	if response, _ := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); !response.FromCache {
+		t.Error("Expected the cached response within the window")
+	}
+	clock.Sleep(context.Background(), time.Minute)
+	if response, _ := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); response.FromCache {
+		t.Error("Expected a fresh send after the window")
+	}
+	if atomic.LoadInt32(calls) != 3 {
+		t.Errorf("Expected 3 requests, got %d", atomic.LoadInt32(calls))
+	}
+}
+
+func TestDedupeReturnCachedSharedFailure(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DedupeWindow = time.Minute
+	config.DedupeReturnCached = true
+	config.MaxRetries = 0
+
+	release := make(chan struct{})
+	client := NewClientWithConfig(config, WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		<-release
+		return jsonResponse(http.StatusInternalServerError, `{"message":"Server error"}`), nil
+	})))
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+			errs <- err
+		}()
+		// Let the first send start before the second joins it
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(release)
+
+	var first, second *HTTPError
+	if !errors.As(<-errs, &first) || !errors.As(<-errs, &second) {
+		t.Fatal("Expected both sends to fail with an HTTPError")
+	}
+	if first == second {
+		t.Error("Expected the waiter to get its own copy of the error")
+	}
+	first.ContextMap["waited"] = time.Second
+	if _, ok := second.ContextMap["waited"]; ok {
+		t.Error("Expected the copies not to share their context")
+	}
+	if stats := client.Stats(); stats.Attempted != 2 || stats.Failed != 2 {
+		t.Errorf("Expected both failures to be recorded, got %+v", stats)
+	}
+}
+
+func TestMemoryDedupeStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryDedupeStore(2, newTestClock())
+
+	for _, fingerprint := range []string{"a", "b", "c"} {
+		if claimed, _, _ := store.Claim(ctx, fingerprint, time.Minute); !claimed {
+			t.Errorf("Expected '%s' to be claimed", fingerprint)
+		}
+	}
+
+	if store.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", store.Len())
+	}
+	if claimed, _, _ := store.Claim(ctx, "a", time.Minute); !claimed {
+		t.Error("Expected the least recently claimed fingerprint to be evicted")
+	}
+	if claimed, _, _ := store.Claim(ctx, "c", time.Minute); claimed {
+		t.Error("Expected a recent fingerprint to be kept")
+	}
+}
+
+func TestDedupeReturnCachedPanickingSend(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DedupeWindow = time.Minute
+	config.DedupeReturnCached = true
+
+	var hookCalls int32
+	release := make(chan struct{})
+	config.PreSend = []func(*Email) error{func(*Email) error {
+		if atomic.AddInt32(&hookCalls, 1) == 1 {
+			<-release
+			panic("hook failed")
+		}
+		return nil
+	}}
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+	}()
+	waited := make(chan error)
+	go func() {
+		// Let the first send start before joining it
+		time.Sleep(20 * time.Millisecond)
+		_, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+		waited <- err
+	}()
+	time.Sleep(40 * time.Millisecond)
+	close(release)
+
+	if recovered := <-panicked; recovered != "hook failed" {
+		t.Errorf("Expected the panic to reach the caller, got %v", recovered)
+	}
+	select {
+	case err := <-waited:
+		if err == nil || !strings.Contains(err.Error(), "hook failed") {
+			t.Errorf("Expected the waiter to get the panic as an error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the waiter to be released when the send panicked")
+	}
+
+	// The fingerprint was released, so the email can be sent again
+	if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err != nil {
+		t.Errorf("Expected a later send to go out, got %v", err)
+	}
+}
+
+func TestDedupeReturnCachedExitingSend(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DedupeWindow = time.Minute
+	config.DedupeReturnCached = true
+
+	var hookCalls int32
+	release := make(chan struct{})
+	config.PreSend = []func(*Email) error{func(*Email) error {
+		if atomic.AddInt32(&hookCalls, 1) == 1 {
+			<-release
+			runtime.Goexit()
+		}
+		return nil
+	}}
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+	}()
+	waited := make(chan error)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+		waited <- err
+	}()
+	time.Sleep(40 * time.Millisecond)
+	close(release)
+
+	<-exited
+	select {
+	case err := <-waited:
+		if err == nil || !strings.Contains(err.Error(), "exited") {
+			t.Errorf("Expected the waiter to get an error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the waiter to be released when the send exited")
+	}
+}
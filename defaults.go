@@ -0,0 +1,111 @@
+package poodle
+
+import (
+	"context"
+	"net/http"
+)
+
+// Defaults are email fields applied to every email sent with a context,
+// e.g. the From address and an X-Tenant header of the tenant a request is
+// handled for. Attach them with ContextWithDefaults.
+type Defaults struct {
+	// From is used for emails without From or FromAddress. It takes
+	// precedence over Config.DefaultFrom.
+	From string
+	// Headers are added to every email that does not set a header of the
+	// same name. Names are compared case-insensitively.
+	Headers map[string]string
+}
+
+// defaultsKey is the context key used by ContextWithDefaults
+type defaultsKey struct{}
+
+// ContextWithDefaults returns a context whose sends apply the defaults.
+// Fields set on an email always win over the defaults. The defaults are
+// copied, so changing d or its Headers afterwards has no effect.
+//
+// Defaults attached to a context that already carries defaults are merged
+// with them: a non-empty From replaces the outer From, and headers replace
+// outer headers of the same name while the other outer headers still
+// apply.
+func ContextWithDefaults(ctx context.Context, d Defaults) context.Context {
+	merged := Defaults{From: d.From}
+	outer, ok := ctx.Value(defaultsKey{}).(*Defaults)
+	if ok && merged.From == "" {
+		merged.From = outer.From
+	}
+
+	if len(d.Headers) > 0 || ok && len(outer.Headers) > 0 {
+		merged.Headers = make(map[string]string)
+		if ok {
+			for key, value := range outer.Headers {
+				merged.Headers[key] = value
+			}
+		}
+		for key, value := range d.Headers {
+			merged.Headers[http.CanonicalHeaderKey(key)] = value
+		}
+	}
+
+	return context.WithValue(ctx, defaultsKey{}, &merged)
+}
+
+// DefaultsFromContext returns a copy of the defaults attached to the
+// context, merged as described by ContextWithDefaults. ok is false if the
+// context carries no defaults.
+func DefaultsFromContext(ctx context.Context) (d Defaults, ok bool) {
+	attached, ok := ctx.Value(defaultsKey{}).(*Defaults)
+	if !ok {
+		return Defaults{}, false
+	}
+
+	d.From = attached.From
+	if attached.Headers != nil {
+		d.Headers = make(map[string]string, len(attached.Headers))
+		for key, value := range attached.Headers {
+			d.Headers[key] = value
+		}
+	}
+	return d, true
+}
+
+// applyDefaults returns the email with the context's defaults and
+// Config.DefaultFrom filled in. The email is copied if anything changes.
+func applyDefaults(ctx context.Context, config *Config, email *Email) *Email {
+	defaults, _ := ctx.Value(defaultsKey{}).(*Defaults)
+
+	from := config.DefaultFrom
+	if defaults != nil && defaults.From != "" {
+		from = defaults.From
+	}
+	needsFrom := from != "" && email.From == "" && email.FromAddress == nil
+
+	var headers map[string]string
+	if defaults != nil && len(defaults.Headers) > 0 {
+		set := make(map[string]bool, len(email.Headers))
+		for key := range email.Headers {
+			set[http.CanonicalHeaderKey(key)] = true
+		}
+		for key, value := range defaults.Headers {
+			if !set[key] {
+				if headers == nil {
+					headers = make(map[string]string)
+				}
+				headers[key] = value
+			}
+		}
+	}
+
+	if !needsFrom && headers == nil {
+		return email
+	}
+
+	email = email.clone()
+	if needsFrom {
+		email.From = from
+	}
+	for key, value := range headers {
+		email.SetHeader(key, value)
+	}
+	return email
+}
@@ -0,0 +1,169 @@
+package poodle
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// sentEmails records the from address and headers of every request
+type sentEmails struct {
+	mutex  sync.Mutex
+	emails []Email
+}
+
+func (s *sentEmails) doer() mockDoerFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		var email Email
+		json.Unmarshal(body, &email)
+		s.mutex.Lock()
+		s.emails = append(s.emails, email)
+		s.mutex.Unlock()
+		return acceptedResponse(), nil
+	}
+}
+
+func defaultsTestClient(defaultFrom string) (*Client, *sentEmails) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DefaultFrom = defaultFrom
+
+	sent := &sentEmails{}
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = sent.doer()
+	return client, sent
+}
+
+func TestContextDefaultsPrecedence(t *testing.T) {
+	client, sent := defaultsTestClient("config@example.com")
+
+	// Config only
+	if _, err := client.SendContext(context.Background(), NewTextEmail("", "to@example.com", "Hello", "Hi")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Context over config
+	ctx := ContextWithDefaults(context.Background(), Defaults{From: "tenant@example.com"})
+	if _, err := client.SendContext(ctx, NewTextEmail("", "to@example.com", "Hello", "Hi")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Email over context
+	if _, err := client.SendContext(ctx, NewTextEmail("explicit@example.com", "to@example.com", "Hello", "Hi")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	email := NewTextEmail("", "to@example.com", "Hello", "Hi")
+	email.FromAddress = &Address{Name: "Explicit", Email: "address@example.com"}
+	if _, err := client.SendContext(ctx, email); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if email.From != "" {
+		t.Errorf("Expected the caller's email to be unchanged, got From %q", email.From)
+	}
+
+	expected := []string{"config@example.com", "tenant@example.com", "explicit@example.com", "Explicit <address@example.com>"}
+	for i, from := range expected {
+		if got := sent.emails[i].From; got != from {
+			t.Errorf("Email %d: expected From %q, got %q", i, from, got)
+		}
+	}
+}
+
+func TestContextDefaultsHeaders(t *testing.T) {
+	client, sent := defaultsTestClient("")
+
+	headers := map[string]string{"x-tenant": "acme", "X-Region": "eu"}
+	ctx := ContextWithDefaults(context.Background(), Defaults{From: "tenant@example.com", Headers: headers})
+	headers["X-Tenant"] = "changed"
+	headers["X-Extra"] = "added"
+
+	email := NewTextEmail("", "to@example.com", "Hello", "Hi")
+	email.Headers = map[string]string{"x-region": "us"}
+	if _, err := client.SendContext(ctx, email); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	got := sent.emails[0].Headers
+	if got["X-Tenant"] != "acme" {
+		t.Errorf("Expected the defaults to be copied when attached, got %v", got)
+	}
+	if _, ok := got["X-Extra"]; ok {
+		t.Errorf("Expected headers added after attaching to be ignored, got %v", got)
+	}
+	if got["x-region"] != "us" || got["X-Region"] != "" {
+		t.Errorf("Expected the email's header to win regardless of case, got %v", got)
+	}
+	if len(email.Headers) != 1 {
+		t.Errorf("Expected the caller's headers to be unchanged, got %v", email.Headers)
+	}
+}
+
+func TestContextDefaultsNesting(t *testing.T) {
+	outer := ContextWithDefaults(context.Background(), Defaults{
+		From:    "outer@example.com",
+		Headers: map[string]string{"X-Tenant": "acme", "X-Region": "eu"},
+	})
+	inner := ContextWithDefaults(outer, Defaults{Headers: map[string]string{"x-region": "us"}})
+	innermost := ContextWithDefaults(inner, Defaults{From: "inner@example.com"})
+
+	d, ok := DefaultsFromContext(innermost)
+	if !ok {
+		t.Fatal("Expected defaults in the context")
+	}
+	if d.From != "inner@example.com" || d.Headers["X-Tenant"] != "acme" || d.Headers["X-Region"] != "us" {
+		t.Errorf("Expected the merged defaults, got %+v", d)
+	}
+
+	d, _ = DefaultsFromContext(inner)
+	if d.From != "outer@example.com" {
+		t.Errorf("Expected the outer From to apply, got %q", d.From)
+	}
+
+	// The outer context is unaffected, and returned copies can't change it
+	d, _ = DefaultsFromContext(outer)
+	if d.Headers["X-Region"] != "eu" {
+		t.Errorf("Expected the outer defaults to be unchanged, got %+v", d)
+	}
+	d.Headers["X-Region"] = "changed"
+	if d, _ = DefaultsFromContext(outer); d.Headers["X-Region"] != "eu" {
+		t.Errorf("Expected the returned defaults to be a copy, got %+v", d)
+	}
+
+	if _, ok := DefaultsFromContext(context.Background()); ok {
+		t.Error("Expected no defaults in a plain context")
+	}
+}
+
+func TestContextDefaultsSendAll(t *testing.T) {
+	client, sent := defaultsTestClient("config@example.com")
+	ctx := ContextWithDefaults(context.Background(), Defaults{
+		From:    "tenant@example.com",
+		Headers: map[string]string{"X-Tenant": "acme"},
+	})
+
+	emails := []*Email{
+		NewTextEmail("", "a@example.com", "Hello", "Hi"),
+		NewTextEmail("", "b@example.com", "Hello", "Hi"),
+	}
+	if _, err := client.SendAll(ctx, emails); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, email := range sent.emails {
+		if email.From != "tenant@example.com" || email.Headers["X-Tenant"] != "acme" {
+			t.Errorf("Expected the context defaults, got %+v", email)
+		}
+	}
+
+	outcome, err := client.SendDetailed(ctx, NewTextEmail("", "c@example.com", "Hello", "Hi"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if outcome.Email.From != "tenant@example.com" {
+		t.Errorf("Expected the outcome to hold the defaulted email, got %+v", outcome.Email)
+	}
+}
@@ -0,0 +1,222 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DNS verification defaults
+const (
+	DefaultDNSTimeout     = 5 * time.Second
+	DefaultDNSPositiveTTL = time.Hour
+	DefaultDNSNegativeTTL = 5 * time.Minute
+)
+
+// Resolver looks up the DNS records used to check that a recipient domain
+// can receive email. *net.Resolver implements it.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// defaultResolver is used when no resolver is given
+var defaultResolver = NewCachingResolver(net.DefaultResolver, DefaultDNSPositiveTTL, DefaultDNSNegativeTTL)
+
+// ValidateDeliverability checks that the domains of the recipients have MX
+// records, falling back to A/AAAA records. It returns a ValidationError for
+// domains that do not exist and a DNSLookupWarning if a lookup timed out.
+// A nil resolver uses the system resolver with cached results. Lookups
+// take at most DefaultDNSTimeout unless ctx has an earlier deadline.
+func (e *Email) ValidateDeliverability(ctx context.Context, resolver Resolver) error {
+	if resolver == nil {
+		resolver = defaultResolver
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultDNSTimeout)
+		defer cancel()
+	}
+
+	errors := make(map[string][]string)
+	var warning error
+	for _, r := range e.recipients() {
+		domain := strings.ToLower(r.address[strings.LastIndex(r.address, "@")+1:])
+
+		exists, err := domainAcceptsMail(ctx, resolver, domain)
+		if err != nil {
+			if warning == nil {
+				warning = NewDNSLookupWarning(domain, err)
+			}
+			continue
+		}
+		if !exists {
+			errors[r.field] = append(errors[r.field], fmt.Sprintf("Recipient domain %s does not exist or cannot receive email", domain))
+		}
+	}
+
+	if len(errors) > 0 {
+		return NewValidationError("Recipient domain is not deliverable", errors)
+	}
+	return warning
+}
+
+// domainAcceptsMail reports whether the domain has MX records or, failing
+// that, an address record. An error is returned if DNS could not answer.
+func domainAcceptsMail(ctx context.Context, resolver Resolver, domain string) (bool, error) {
+	records, err := resolver.LookupMX(ctx, domain)
+	if err == nil {
+		for _, record := range records {
+			// A null MX (RFC 7505) declares that the domain accepts no email
+			if record.Host != "." && record.Host != "" {
+				return true, nil
+			}
+		}
+		if len(records) > 0 {
+			return false, nil
+		}
+	} else if !isNotFound(err) {
+		return false, err
+	}
+
+	addresses, err := resolver.LookupHost(ctx, domain)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(addresses) > 0, nil
+}
+
+// isNotFound reports whether a DNS error means the name has no records
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// verifyRecipientDNS runs the deliverability check for a send. Lookup
+// warnings are logged and do not fail the send.
+func verifyRecipientDNS(ctx context.Context, config *Config, email *Email) error {
+	timeout := config.DNSTimeout
+	if timeout == 0 {
+		timeout = DefaultDNSTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := email.ValidateDeliverability(ctx, config.DNSResolver)
+
+	var warning *DNSLookupWarning
+	if errors.As(err, &warning) {
+		if config.logs(LogLevelError) {
+			log.Printf("Poodle DNS Warning: %s", warning.Error())
+		}
+		return nil
+	}
+	return err
+}
+
+// cachingResolver caches the answers of another resolver
+type cachingResolver struct {
+	next        Resolver
+	positiveTTL time.Duration
+	negativeTTL time.Duration
+	clock       Clock
+
+	mutex   sync.Mutex
+	entries map[string]dnsCacheEntry
+}
+
+// dnsCacheEntry is a cached lookup result
+type dnsCacheEntry struct {
+	mx      []*net.MX
+	hosts   []string
+	err     error
+	expires time.Time
+}
+
+// CachingResolverOption configures a resolver returned by
+// NewCachingResolver
+type CachingResolverOption func(*cachingResolver)
+
+// WithResolverClock sets the clock that expires cached answers, e.g. the
+// Clock given to the client with WithClock. The default is the system
+// clock.
+func WithResolverClock(clock Clock) CachingResolverOption {
+	return func(r *cachingResolver) {
+		r.clock = clock
+	}
+}
+
+// NewCachingResolver wraps a resolver so that answers are cached for
+// positiveTTL and "not found" answers for negativeTTL. Timeouts and other
+// failures are not cached.
+func NewCachingResolver(next Resolver, positiveTTL, negativeTTL time.Duration, opts ...CachingResolverOption) Resolver {
+	r := &cachingResolver{
+		next:        next,
+		positiveTTL: positiveTTL,
+		negativeTTL: negativeTTL,
+		clock:       realClock{},
+		entries:     make(map[string]dnsCacheEntry),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// LookupMX implements Resolver
+func (r *cachingResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if entry, ok := r.get("mx:" + name); ok {
+		return entry.mx, entry.err
+	}
+
+	records, err := r.next.LookupMX(ctx, name)
+	r.put("mx:"+name, dnsCacheEntry{mx: records, err: err})
+	return records, err
+}
+
+// LookupHost implements Resolver
+func (r *cachingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if entry, ok := r.get("host:" + host); ok {
+		return entry.hosts, entry.err
+	}
+
+	addresses, err := r.next.LookupHost(ctx, host)
+	r.put("host:"+host, dnsCacheEntry{hosts: addresses, err: err})
+	return addresses, err
+}
+
+func (r *cachingResolver) get(key string) (dnsCacheEntry, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry, ok := r.entries[key]
+	if !ok || !r.clock.Now().Before(entry.expires) {
+		return dnsCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (r *cachingResolver) put(key string, entry dnsCacheEntry) {
+	ttl := r.positiveTTL
+	if entry.err != nil {
+		if !isNotFound(entry.err) {
+			return
+		}
+		ttl = r.negativeTTL
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry.expires = r.clock.Now().Add(ttl)
+	r.entries[key] = entry
+}
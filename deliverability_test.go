@@ -0,0 +1,177 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubResolver answers DNS lookups from maps and counts them
+type stubResolver struct {
+	mutex   sync.Mutex
+	mx      map[string][]*net.MX
+	hosts   map[string][]string
+	timeout map[string]bool
+	lookups int
+}
+
+func (r *stubResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.lookups++
+	if r.timeout[name] {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+	if records, ok := r.mx[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.lookups++
+	if addresses, ok := r.hosts[host]; ok {
+		return addresses, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func newStubResolver() *stubResolver {
+	return &stubResolver{
+		mx: map[string][]*net.MX{
+			"gmail.com":   {{Host: "gmail-smtp-in.l.google.com.", Pref: 5}},
+			"nomail.com":  {{Host: ".", Pref: 0}},
+			"example.com": {},
+		},
+		hosts:   map[string][]string{"example.com": {"93.184.216.34"}},
+		timeout: map[string]bool{"slow.com": true},
+	}
+}
+
+func TestValidateDeliverability(t *testing.T) {
+	resolver := newStubResolver()
+
+	tests := []struct {
+		to      string
+		invalid bool
+		warning bool
+	}{
+		{"user@gmail.com", false, false},
+		{"user@example.com", false, false},
+		{"user@gamil.com", true, false},
+		{"user@nomail.com", true, false},
+		{"user@slow.com", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			email := NewTextEmail("from@example.com", tt.to, "Subject", "Hello")
+			err := email.ValidateDeliverability(context.Background(), resolver)
+
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) != tt.invalid {
+				t.Errorf("Expected ValidationError = %t, got %v", tt.invalid, err)
+			}
+			if tt.invalid && len(validationErr.Errors["to"]) != 1 {
+				t.Errorf("Expected a 'to' field error, got %v", validationErr.Errors)
+			}
+
+			var warning *DNSLookupWarning
+			if errors.As(err, &warning) != tt.warning {
+				t.Errorf("Expected DNSLookupWarning = %t, got %v", tt.warning, err)
+			}
+			if err == nil && (tt.invalid || tt.warning) {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestCachingResolver(t *testing.T) {
+	stub := newStubResolver()
+	resolver := NewCachingResolver(stub, DefaultDNSPositiveTTL, DefaultDNSNegativeTTL)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		for _, to := range []string{"user@gmail.com", "user@gamil.com", "user@slow.com"} {
+			NewTextEmail("from@example.com", to, "Subject", "Hello").ValidateDeliverability(ctx, resolver)
+		}
+	}
+
+	// gmail.com: 1 MX lookup; gamil.com: MX + host lookups cached as not
+	// found; slow.com: timeouts are never cached
+	if stub.lookups != 1+2+3 {
+		t.Errorf("Expected 6 lookups, got %d", stub.lookups)
+	}
+}
+
+func TestCachingResolverClock(t *testing.T) {
+	clock := newTestClock()
+	stub := newStubResolver()
+	resolver := NewCachingResolver(stub, time.Minute, time.Minute, WithResolverClock(clock))
+	ctx := context.Background()
+
+	email := NewTextEmail("from@example.com", "user@gmail.com", "Subject", "Hello")
+	email.ValidateDeliverability(ctx, resolver)
+	clock.Sleep(ctx, 59*time.Second)
+	email.ValidateDeliverability(ctx, resolver)
+	if stub.lookups != 1 {
+		t.Errorf("Expected the answer to be cached, got %d lookups", stub.lookups)
+	}
+
+	clock.Sleep(ctx, time.Second)
+	email.ValidateDeliverability(ctx, resolver)
+	if stub.lookups != 2 {
+		t.Errorf("Expected the answer to expire on the clock, got %d lookups", stub.lookups)
+	}
+}
+
+func TestClientVerifyRecipientDNS(t *testing.T) {
+	resolver := newStubResolver()
+
+	requests := 0
+	newClient := func(verify bool) *Client {
+		config := NewConfig()
+		config.APIKey = "test_api_key"
+		config.DNSResolver = resolver
+		config.VerifyRecipientDNS = verify
+
+		client := NewClientWithConfig(config)
+		client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return acceptedResponse(), nil
+		})
+		return client
+	}
+
+	// Without the flag no lookups are made
+	if _, err := newClient(false).SendText("from@example.com", "user@gamil.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected no DNS check by default, got: %v", err)
+	}
+	if resolver.lookups != 0 {
+		t.Errorf("Expected no lookups without VerifyRecipientDNS, got %d", resolver.lookups)
+	}
+
+	client := newClient(true)
+	if _, err := client.SendText("from@example.com", "user@gamil.com", "Subject", "Hello"); err == nil {
+		t.Error("Expected an undeliverable domain to fail the send")
+	} else if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	}
+
+	if _, err := client.SendText("from@example.com", "user@slow.com", "Subject", "Hello"); err != nil {
+		t.Errorf("Expected a lookup timeout not to fail the send, got: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
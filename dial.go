@@ -0,0 +1,49 @@
+package poodle
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// dialFunc is the signature of net.Dialer.DialContext
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// forceIPv4 returns dial restricted to IPv4 if config.ForceIPv4 is set
+func forceIPv4(config *Config, dial dialFunc) dialFunc {
+	if !config.ForceIPv4 {
+		return dial
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if network == "tcp" {
+			network = "tcp4"
+		}
+		return dial(ctx, network, address)
+	}
+}
+
+// networkErrorKind classifies the error of a failed request, or returns
+// the empty kind if it is not one of the known kinds
+func networkErrorKind(err error) NetworkErrorKind {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &dnsErr):
+		return NetworkErrorKindDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return NetworkErrorKindTLS
+	case errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH):
+		return NetworkErrorKindUnreachable
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return NetworkErrorKindRefused
+	}
+	return ""
+}
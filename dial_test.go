@@ -0,0 +1,125 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestForceIPv4(t *testing.T) {
+	for _, force := range []bool{true, false} {
+		config := NewConfig()
+		config.ForceIPv4 = force
+
+		var networks []string
+		dial := forceIPv4(config, func(ctx context.Context, network, address string) (net.Conn, error) {
+			networks = append(networks, network)
+			return nil, errors.New("stub")
+		})
+		dial(context.Background(), "tcp", "api.usepoodle.com:443")
+		dial(context.Background(), "tcp6", "[::1]:443")
+
+		want := "tcp"
+		if force {
+			want = "tcp4"
+		}
+		if networks[0] != want || networks[1] != "tcp6" {
+			t.Errorf("Expected networks [%s tcp6] with ForceIPv4=%t, got %v", want, force, networks)
+		}
+	}
+}
+
+func TestForceIPv4RejectsIPv6LocalAddr(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.ForceIPv4 = true
+	config.LocalAddr = "::1"
+
+	err := config.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Errors["local_addr"]) == 0 {
+		t.Errorf("Expected a local_addr ValidationError, got %v", err)
+	}
+}
+
+func TestNetworkErrorKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind NetworkErrorKind
+	}{
+		{"No such host", &net.DNSError{Err: "no such host", Name: "api.usepoodle.com", IsNotFound: true}, NetworkErrorKindDNS},
+		{"Lookup timeout", &net.DNSError{Err: "i/o timeout", Name: "api.usepoodle.com", IsTimeout: true}, NetworkErrorKindDNS},
+		{"Network unreachable", os.NewSyscallError("connect", syscall.ENETUNREACH), NetworkErrorKindUnreachable},
+		{"Host unreachable", os.NewSyscallError("connect", syscall.EHOSTUNREACH), NetworkErrorKindUnreachable},
+		{"Refused", os.NewSyscallError("connect", syscall.ECONNREFUSED), NetworkErrorKindRefused},
+		{"Other", errors.New("something broke"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := timeoutTestClient(t, "http://api.example.com", 5*time.Second)
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: tt.err}
+			}
+
+			_, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+			var timeoutErr *TimeoutError
+			if errors.As(err, &timeoutErr) {
+				t.Fatalf("Expected a NetworkError rather than a timeout, got: %v", err)
+			}
+			networkErr, ok := err.(*NetworkError)
+			if !ok {
+				t.Fatalf("Expected NetworkError, got %T: %v", err, err)
+			}
+			if networkErr.Kind != tt.kind {
+				t.Errorf("Expected kind %q, got %q", tt.kind, networkErr.Kind)
+			}
+			if kind, _ := networkErr.Context()["kind"].(string); kind != string(tt.kind) {
+				t.Errorf("Expected kind %q in the context, got %v", tt.kind, networkErr.Context()["kind"])
+			}
+		})
+	}
+}
+
+func TestNewConfigFromEnvDialSettings(t *testing.T) {
+	t.Setenv("POODLE_FORCE_IPV4", "true")
+	t.Setenv("POODLE_FALLBACK_DELAY", "50ms")
+	t.Setenv("POODLE_LOCAL_ADDR", "127.0.0.1")
+
+	config, err := NewConfigFromEnvStrict()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !config.ForceIPv4 {
+		t.Error("Expected ForceIPv4 to be true")
+	}
+	if config.FallbackDelay != 50*time.Millisecond {
+		t.Errorf("Expected FallbackDelay to be 50ms, got %v", config.FallbackDelay)
+	}
+	if config.LocalAddr != "127.0.0.1" {
+		t.Errorf("Expected LocalAddr to be 127.0.0.1, got %q", config.LocalAddr)
+	}
+}
+
+func TestNewConfigFromEnvDisablesFallback(t *testing.T) {
+	t.Setenv("POODLE_FALLBACK_DELAY", "-1ns")
+
+	config, err := NewConfigFromEnvStrict()
+	if err != nil {
+		t.Fatalf("Expected a negative delay to be accepted, got: %v", err)
+	}
+	if config.FallbackDelay != -1 {
+		t.Errorf("Expected FallbackDelay to be -1ns, got %v", config.FallbackDelay)
+	}
+
+	t.Setenv("POODLE_FALLBACK_DELAY", "soon")
+	if _, err := NewConfigFromEnvStrict(); err == nil {
+		t.Error("Expected an invalid delay to be rejected")
+	}
+}
@@ -0,0 +1,122 @@
+package poodle
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DisposableAddressCode is recorded as the "validation_code" context value
+// of the ValidationError returned for a disposable recipient
+const DisposableAddressCode = "disposable_address"
+
+//go:embed disposable_domains.txt
+var disposableDomainsList string
+
+// disposableDomains is the sorted list of known disposable domains
+var disposableDomains = struct {
+	sync.RWMutex
+	sorted []string
+}{sorted: parseDisposableDomains(disposableDomainsList)}
+
+// parseDisposableDomains parses the embedded domain list into a sorted slice
+func parseDisposableDomains(list string) []string {
+	var domains []string
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, strings.ToLower(line))
+	}
+	return sortDomains(domains)
+}
+
+// sortDomains sorts the domains and removes duplicates
+func sortDomains(domains []string) []string {
+	sort.Strings(domains)
+	unique := domains[:0]
+	for i, domain := range domains {
+		if i == 0 || domain != domains[i-1] {
+			unique = append(unique, domain)
+		}
+	}
+	return unique
+}
+
+// AddDisposableDomains extends the list of disposable domains used by
+// IsDisposableAddress and Config.RejectDisposable. Subdomains of an added
+// domain are matched as well.
+func AddDisposableDomains(domains ...string) {
+	disposableDomains.Lock()
+	defer disposableDomains.Unlock()
+
+	merged := make([]string, 0, len(disposableDomains.sorted)+len(domains))
+	merged = append(merged, disposableDomains.sorted...)
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			merged = append(merged, domain)
+		}
+	}
+	disposableDomains.sorted = sortDomains(merged)
+}
+
+// SetDisposableDomains replaces the list of disposable domains, including
+// the built-in list. Passing no domains disables detection.
+func SetDisposableDomains(domains ...string) {
+	cleaned := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			cleaned = append(cleaned, domain)
+		}
+	}
+
+	disposableDomains.Lock()
+	defer disposableDomains.Unlock()
+
+	disposableDomains.sorted = sortDomains(cleaned)
+}
+
+// IsDisposableAddress reports whether the address belongs to a known
+// disposable email domain or one of its subdomains, such as
+// foo.mailinator.com
+func IsDisposableAddress(addr string) bool {
+	domain := strings.ToLower(strings.TrimSpace(addr[strings.LastIndex(addr, "@")+1:]))
+	domain = strings.TrimSuffix(domain, ">")
+
+	disposableDomains.RLock()
+	defer disposableDomains.RUnlock()
+
+	for domain != "" {
+		i := sort.SearchStrings(disposableDomains.sorted, domain)
+		if i < len(disposableDomains.sorted) && disposableDomains.sorted[i] == domain {
+			return true
+		}
+
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
+
+// checkDisposable rejects recipients with disposable addresses
+func checkDisposable(email *Email) error {
+	errors := make(map[string][]string)
+	for _, r := range email.recipients() {
+		if IsDisposableAddress(r.address) {
+			errors[r.field] = append(errors[r.field], fmt.Sprintf("Recipient %s uses a disposable email domain", r.address))
+		}
+	}
+
+	if len(errors) > 0 {
+		err := NewValidationError("Disposable recipient address", errors)
+		err.ContextMap["validation_code"] = DisposableAddressCode
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package poodle
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsDisposableAddress(t *testing.T) {
+	tests := []struct {
+		addr       string
+		disposable bool
+	}{
+		{"user@mailinator.com", true},
+		{"user@MAILINATOR.com", true},
+		{"user@foo.mailinator.com", true},
+		{"user@a.b.yopmail.com", true},
+		{"user@example.com", false},
+		{"user@notmailinator.com", false},
+		{"user@mailinator.com.example.org", false},
+		{"mailinator.com", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsDisposableAddress(tt.addr); got != tt.disposable {
+			t.Errorf("IsDisposableAddress(%s) = %t, want %t", tt.addr, got, tt.disposable)
+		}
+	}
+}
+
+func TestDisposableDomainsSorted(t *testing.T) {
+	sorted := disposableDomains.sorted
+	if len(sorted) == 0 {
+		t.Fatal("Expected the embedded list to contain domains")
+	}
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1] >= sorted[i] {
+			t.Fatalf("Expected sorted unique domains, got %s before %s", sorted[i-1], sorted[i])
+		}
+	}
+}
+
+func TestAddAndSetDisposableDomains(t *testing.T) {
+	disposableDomains.RLock()
+	original := append([]string(nil), disposableDomains.sorted...)
+	disposableDomains.RUnlock()
+	t.Cleanup(func() { SetDisposableDomains(original...) })
+
+	if IsDisposableAddress("user@throwaway.example") {
+		t.Fatal("Expected throwaway.example not to be disposable before it is added")
+	}
+
+	AddDisposableDomains(" Throwaway.Example ", "mailinator.com")
+	if !IsDisposableAddress("user@sub.throwaway.example") {
+		t.Error("Expected added domain and its subdomains to be disposable")
+	}
+	if !IsDisposableAddress("user@mailinator.com") {
+		t.Error("Expected built-in domains to be kept when adding")
+	}
+
+	SetDisposableDomains("only.example")
+	if IsDisposableAddress("user@mailinator.com") {
+		t.Error("Expected SetDisposableDomains to replace the built-in list")
+	}
+	if !IsDisposableAddress("user@only.example") {
+		t.Error("Expected replaced list to be used")
+	}
+}
+
+func TestClientRejectDisposable(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.RejectDisposable = true
+
+	client := NewClientWithConfig(config)
+	requests := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return acceptedResponse(), nil
+	})
+
+	_, err := client.SendText("from@example.com", "signup@foo.mailinator.com", "Subject", "Hello")
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %T", err)
+	}
+	if len(validationErr.Errors["to"]) != 1 {
+		t.Errorf("Expected an error on the to field, got %v", validationErr.Errors)
+	}
+	if code := validationErr.Context()["validation_code"]; code != DisposableAddressCode {
+		t.Errorf("Expected validation_code %s, got %v", DisposableAddressCode, code)
+	}
+
+	if _, err := client.SendText("from@example.com", "user@example.com", "Subject", "Hello"); err != nil {
+		t.Errorf("Expected regular address to be sent, got: %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
@@ -0,0 +1,196 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DoerFunc adapts a function to the HTTPDoer interface
+type DoerFunc func(*http.Request) (*http.Response, error)
+
+// Do calls f(req)
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// decoratedDoer is an HTTPDoer returned by the decorators. It forwards
+// CloseIdleConnections to the doer it wraps, so that Client.Close still
+// releases the connections of a decorated *http.Client.
+type decoratedDoer struct {
+	next HTTPDoer
+	do   DoerFunc
+}
+
+func (d *decoratedDoer) Do(req *http.Request) (*http.Response, error) {
+	return d.do(req)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped doer if
+// it supports it
+func (d *decoratedDoer) CloseIdleConnections() {
+	if closer, ok := d.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// DoerWithLogging returns an HTTPDoer that logs every request made through
+// next with its method, URL, status and duration, or the error it failed
+// with. Headers and bodies are not logged, so credentials are never
+// written. A nil logger uses the standard logger. Durations are measured
+// with the time package, as the decorators are built without a client and
+// its Clock. The client does not use it for its own logging, which writes
+// the same line at LogLevelInfo once the response body is read, with
+// whether the connection was reused.
+func DoerWithLogging(next HTTPDoer, logger *log.Logger) HTTPDoer {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &decoratedDoer{next: next, do: func(req *http.Request) (*http.Response, error) {
+		started := time.Now()
+		resp, err := next.Do(req)
+		if err != nil {
+			logger.Printf("Poodle API Request: %s %s failed after %s: %s", req.Method, req.URL.Redacted(), time.Since(started), err.Error())
+			return resp, err
+		}
+		logger.Print(requestLogLine(req, resp.StatusCode, time.Since(started)))
+		return resp, err
+	}}
+}
+
+// requestLogLine formats the summary of a request, as logged by
+// DoerWithLogging and at LogLevelInfo
+func requestLogLine(req *http.Request, status int, duration time.Duration) string {
+	return fmt.Sprintf("Poodle API Request: %s %s -> %d in %s", req.Method, req.URL.Redacted(), status, duration)
+}
+
+// DoerRetryPolicy configures DoerWithRetry
+type DoerRetryPolicy struct {
+	// MaxRetries is the number of times a request is repeated
+	MaxRetries int
+	// Backoff decides the delay before each retry, as for the client's
+	// own retries, and can stop retrying early. It receives the transport
+	// error of the failed attempt, or nil for a response. Nil retries
+	// immediately. The delay is a real timer, not the client's Clock.
+	Backoff RetryPolicy
+	// ShouldRetry decides whether a request is repeated given its response
+	// or error. Nil uses RetryTransient.
+	ShouldRetry func(resp *http.Response, err error) bool
+	// OnRetry, if set, is called before each retry with the number of the
+	// failed attempt, starting at 1
+	OnRetry func(req *http.Request, attempt int, resp *http.Response, err error)
+}
+
+// RetryTransient reports whether a request failed with a transport error,
+// other than a cancelled or expired context, or a 429 or 5xx response
+func RetryTransient(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// DoerWithRetry returns an HTTPDoer that repeats requests through next as
+// the policy says. Requests are only repeated if their body can be
+// replayed through Request.GetBody, as for requests built from a byte
+// slice, and not once their context is done. The response bodies of
+// repeated attempts are drained and closed.
+//
+// The client's own retries (Config.MaxRetries) work on API errors and
+// take rate limits, failover and hooks into account; use this decorator
+// for transports shared with other code or for retries below the client.
+func DoerWithRetry(next HTTPDoer, policy DoerRetryPolicy) HTTPDoer {
+	shouldRetry := policy.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = RetryTransient
+	}
+
+	return &decoratedDoer{next: next, do: func(req *http.Request) (*http.Response, error) {
+		attemptReq := req
+		for attempt := 1; ; attempt++ {
+			resp, err := next.Do(attemptReq)
+			if attempt > policy.MaxRetries || req.Context().Err() != nil || !shouldRetry(resp, err) {
+				return resp, err
+			}
+
+			retry, ok := replayRequest(req)
+			if !ok {
+				return resp, err
+			}
+			var delay time.Duration
+			if policy.Backoff != nil {
+				if delay, ok = policy.Backoff.NextDelay(attempt, err); !ok {
+					return resp, err
+				}
+			}
+			if resp != nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			if policy.OnRetry != nil {
+				policy.OnRetry(req, attempt, resp, err)
+			}
+
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, req.Context().Err()
+				case <-timer.C:
+				}
+			}
+			attemptReq = retry
+		}
+	}}
+}
+
+// replayRequest returns a copy of the request with a fresh body, or false
+// if the body cannot be replayed
+func replayRequest(req *http.Request) (*http.Request, bool) {
+	retry := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return retry, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retry.Body = body
+	return retry, true
+}
+
+// DoerWithHeaders returns an HTTPDoer that sets the headers on every
+// request made through next, replacing values the request already has.
+// Like Config.ContextHeaderMappers, it skips headers that the client sets
+// itself, such as Authorization, and headers with invalid names or line
+// breaks in their values. The map is copied, and requests are cloned
+// rather than modified. The client sets its context and locale headers
+// when it builds a request instead, with the same checks.
+func DoerWithHeaders(next HTTPDoer, headers map[string]string) HTTPDoer {
+	valid := make(http.Header, len(headers))
+	for key, value := range headers {
+		if checkHeader(key, value) == "" {
+			valid.Set(key, value)
+		}
+	}
+
+	return &decoratedDoer{next: next, do: func(req *http.Request) (*http.Response, error) {
+		if len(valid) == 0 {
+			return next.Do(req)
+		}
+		req = req.Clone(req.Context())
+		for key, values := range valid {
+			req.Header[key] = append([]string(nil), values...)
+		}
+		return next.Do(req)
+	}}
+}
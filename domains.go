@@ -0,0 +1,318 @@
+package poodle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Domain verification states
+const (
+	DomainStatusPending  = "pending"
+	DomainStatusVerified = "verified"
+	DomainStatusFailed   = "failed"
+)
+
+// DomainCacheTTL is how long IsDomainVerified caches the verified domains
+const DomainCacheTTL = 5 * time.Minute
+
+// Domain is a sender domain registered with Poodle
+type Domain struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Status     string      `json:"status"`
+	Records    []DNSRecord `json:"records"`
+	CreatedAt  time.Time   `json:"created_at"`
+	VerifiedAt *time.Time  `json:"verified_at,omitempty"`
+}
+
+// DNSRecord is a DNS record that must be installed for a domain to pass
+// verification
+type DNSRecord struct {
+	// Purpose is what the record is used for: "spf", "dkim" or "dmarc"
+	Purpose  string `json:"purpose"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Verified bool   `json:"verified"`
+}
+
+// IsVerified returns true if the domain may be used as a From domain
+func (d *Domain) IsVerified() bool {
+	return d.Status == DomainStatusVerified
+}
+
+// PendingRecords returns the DNS records that have not been verified yet
+func (d *Domain) PendingRecords() []DNSRecord {
+	var pending []DNSRecord
+	for _, record := range d.Records {
+		if !record.Verified {
+			pending = append(pending, record)
+		}
+	}
+	return pending
+}
+
+// domainResponse is the API envelope for a single domain
+type domainResponse struct {
+	Data Domain `json:"data"`
+}
+
+// domainListResponse is the API envelope for a list of domains
+type domainListResponse struct {
+	Data []Domain `json:"data"`
+}
+
+// ListDomains returns the sender domains registered for the account
+func (c *Client) ListDomains(ctx context.Context) ([]Domain, error) {
+	var response domainListResponse
+	if err := c.httpClient.apiRequest(ctx, c.snapshotConfig(), http.MethodGet, "/v1/domains", nil, &response); err != nil {
+		return nil, err
+	}
+
+	c.domains.store(response.Data, c.httpClient.clock.Now())
+	return response.Data, nil
+}
+
+// GetDomain returns a sender domain by name
+func (c *Client) GetDomain(ctx context.Context, name string) (*Domain, error) {
+	return c.domainRequest(ctx, http.MethodGet, name, "")
+}
+
+// CreateDomain registers a sender domain. The returned domain lists the DNS
+// records to install before calling VerifyDomain.
+func (c *Client) CreateDomain(ctx context.Context, name string) (*Domain, error) {
+	if err := validateDomainName(name); err != nil {
+		return nil, err
+	}
+
+	var response domainResponse
+	body := map[string]string{"name": name}
+	if err := c.httpClient.apiRequest(ctx, c.snapshotConfig(), http.MethodPost, "/v1/domains", body, &response); err != nil {
+		return nil, err
+	}
+
+	return &response.Data, nil
+}
+
+// VerifyDomain asks Poodle to check the domain's DNS records and returns the
+// updated verification state
+func (c *Client) VerifyDomain(ctx context.Context, name string) (*Domain, error) {
+	domain, err := c.domainRequest(ctx, http.MethodPost, name, "/verify")
+	if err != nil {
+		return nil, err
+	}
+
+	c.domains.invalidate()
+	return domain, nil
+}
+
+// InvalidateDomainCache drops the cached verification state of the
+// account's domains, e.g. after a domain was verified elsewhere, so that
+// IsDomainVerified and WarnUnverifiedFrom fetch the domain list again
+func (c *Client) InvalidateDomainCache() {
+	c.domains.invalidate()
+}
+
+// IsDomainVerified reports whether the domain is a verified sender domain.
+// The list of verified domains is cached for DomainCacheTTL.
+func (c *Client) IsDomainVerified(ctx context.Context, name string) (bool, error) {
+	name = strings.ToLower(name)
+	if verified, ok := c.domains.lookup(name, c.httpClient.clock.Now()); ok {
+		return verified, nil
+	}
+
+	if _, err := c.ListDomains(ctx); err != nil {
+		return false, err
+	}
+
+	verified, _ := c.domains.lookup(name, c.httpClient.clock.Now())
+	return verified, nil
+}
+
+// domainRequest performs a request against a single domain resource
+func (c *Client) domainRequest(ctx context.Context, method, name, suffix string) (*Domain, error) {
+	if err := validateDomainName(name); err != nil {
+		return nil, err
+	}
+
+	var response domainResponse
+	path := "/v1/domains/" + url.PathEscape(name) + suffix
+	if err := c.httpClient.apiRequest(ctx, c.snapshotConfig(), method, path, nil, &response); err != nil {
+		return nil, err
+	}
+
+	return &response.Data, nil
+}
+
+// validateDomainName checks that a domain name is present and plausible
+func validateDomainName(name string) error {
+	if name == "" {
+		return NewValidationError("Domain name is required", map[string][]string{
+			"name": {"Domain name is required"},
+		})
+	}
+
+	if !strings.Contains(name, ".") || strings.ContainsAny(name, "/@ ") {
+		return NewValidationError("Domain name is invalid", map[string][]string{
+			"name": {"Domain name must be a host name such as example.com"},
+		})
+	}
+
+	return nil
+}
+
+// domainCache caches the verification state of the account's domains
+type domainCache struct {
+	mutex    sync.Mutex
+	verified map[string]bool
+	expires  time.Time
+
+	// warned holds the From domains reported as unverified since the list
+	// was stored, pending the From domains seen while it is being fetched
+	// for WarnUnverifiedFrom
+	warned     map[string]bool
+	pending    map[string]bool
+	refreshing bool
+}
+
+func (d *domainCache) store(domains []Domain, now time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.verified = make(map[string]bool, len(domains))
+	for i := range domains {
+		d.verified[strings.ToLower(domains[i].Name)] = domains[i].IsVerified()
+	}
+	d.expires = now.Add(DomainCacheTTL)
+	d.warned = nil
+}
+
+// lookup returns the cached state of a domain and whether the cache is fresh
+func (d *domainCache) lookup(name string, now time.Time) (bool, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.verified == nil || !now.Before(d.expires) {
+		return false, false
+	}
+	return d.verified[name], true
+}
+
+func (d *domainCache) invalidate() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.verified = nil
+	d.warned = nil
+}
+
+// observeFrom records a From domain. warn is true if the domain is
+// unverified and was not reported yet, and refresh is true if the caller
+// must fetch the domain list and then call refreshed.
+func (d *domainCache) observeFrom(name string, now time.Time) (warn, refresh bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.verified != nil && now.Before(d.expires) {
+		return d.warn(name), false
+	}
+
+	if d.pending == nil {
+		d.pending = make(map[string]bool)
+	}
+	d.pending[name] = true
+	if d.refreshing {
+		return false, false
+	}
+	d.refreshing = true
+	return false, true
+}
+
+// refreshed ends a fetch started by observeFrom and returns the pending
+// domains to report as unverified. After a failed fetch, the next email
+// starts another.
+func (d *domainCache) refreshed(ok bool) []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var unverified []string
+	for name := range d.pending {
+		if ok && d.verified != nil && d.warn(name) {
+			unverified = append(unverified, name)
+		}
+	}
+	sort.Strings(unverified)
+	d.pending = nil
+	d.refreshing = false
+	return unverified
+}
+
+// warn reports whether a domain is unverified and not reported yet, and
+// marks it reported. The mutex must be held.
+func (d *domainCache) warn(name string) bool {
+	if d.verified[name] || d.warned[name] {
+		return false
+	}
+	if d.warned == nil {
+		d.warned = make(map[string]bool)
+	}
+	d.warned[name] = true
+	return true
+}
+
+// checkFromDomain reports the From domain of the email as
+// LintUnverifiedFrom if it is not a verified sender domain. When the
+// cached domain list is stale, it is fetched in the background and the
+// domain is checked once it arrives.
+func (c *Client) checkFromDomain(config *Config, email *Email) {
+	if containsFold(config.DisabledLints, LintUnverifiedFrom) {
+		return
+	}
+	address := email.fromAddress().Email
+	at := strings.LastIndex(address, "@")
+	if at < 0 || at == len(address)-1 {
+		return
+	}
+	name := strings.ToLower(address[at+1:])
+
+	warn, refresh := c.domains.observeFrom(name, c.httpClient.clock.Now())
+	if warn {
+		reportUnverifiedFrom(config, name)
+	}
+	if !refresh {
+		return
+	}
+
+	go func() {
+		_, err := c.ListDomains(context.Background())
+		if err != nil && config.logs(LogLevelError) {
+			log.Printf("Poodle API Warning: could not check the From domain %s: %s", name, err.Error())
+		}
+		for _, unverified := range c.domains.refreshed(err == nil) {
+			reportUnverifiedFrom(config, unverified)
+		}
+	}()
+}
+
+// reportUnverifiedFrom passes a LintUnverifiedFrom warning to
+// OnLintWarning, or logs it without a callback
+func reportUnverifiedFrom(config *Config, name string) {
+	warning := LintWarning{
+		Code:     LintUnverifiedFrom,
+		Field:    "from",
+		Message:  fmt.Sprintf("From domain %s is not a verified sender domain of the account, so emails from it may be rejected or marked as spam", name),
+		Severity: LintSeverityWarning,
+	}
+	if config.OnLintWarning != nil {
+		config.OnLintWarning(warning)
+	} else if config.logs(LogLevelError) {
+		log.Printf("Poodle Lint Warning: %s", warning.Message)
+	}
+}
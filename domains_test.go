@@ -0,0 +1,272 @@
+package poodle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testDomainJSON = `{"id":"dom_1","name":"example.com","status":"pending","created_at":"2024-03-01T12:00:00Z","records":[` +
+	`{"purpose":"spf","type":"TXT","name":"example.com","value":"v=spf1 include:usepoodle.com ~all","verified":true},` +
+	`{"purpose":"dkim","type":"CNAME","name":"poodle._domainkey.example.com","value":"dkim.usepoodle.com","verified":false}]}`
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestClientDomains(t *testing.T) {
+	client := NewClient("test_api_key")
+
+	var requests []string
+	var bodies []string
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		body := ""
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+		}
+		bodies = append(bodies, body)
+
+		switch req.URL.Path {
+		case "/v1/domains":
+			if req.Method == http.MethodPost {
+				return jsonResponse(http.StatusCreated, `{"data":`+testDomainJSON+`}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{"data":[`+testDomainJSON+`]}`), nil
+		case "/v1/domains/example.com":
+			return jsonResponse(http.StatusOK, `{"data":`+testDomainJSON+`}`), nil
+		case "/v1/domains/example.com/verify":
+			verified := strings.Replace(testDomainJSON, `"status":"pending"`, `"status":"verified"`, 1)
+			return jsonResponse(http.StatusOK, `{"data":`+verified+`}`), nil
+		}
+		return jsonResponse(http.StatusNotFound, `{"message":"Domain not found"}`), nil
+	})
+
+	ctx := context.Background()
+
+	created, err := client.CreateDomain(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if created.Name != "example.com" || created.IsVerified() {
+		t.Errorf("Unexpected created domain: %+v", created)
+	}
+	if bodies[0] != `{"name":"example.com"}` {
+		t.Errorf("Expected create body with the domain name, got '%s'", bodies[0])
+	}
+	if pending := created.PendingRecords(); len(pending) != 1 || pending[0].Purpose != "dkim" {
+		t.Errorf("Expected the DKIM record to be pending, got %+v", pending)
+	}
+
+	domains, err := client.ListDomains(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(domains) != 1 || len(domains[0].Records) != 2 {
+		t.Errorf("Unexpected domain list: %+v", domains)
+	}
+	if !domains[0].CreatedAt.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected created_at: %v", domains[0].CreatedAt)
+	}
+
+	if _, err := client.GetDomain(ctx, "example.com"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	verified, err := client.VerifyDomain(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !verified.IsVerified() {
+		t.Errorf("Expected domain to be verified, got status '%s'", verified.Status)
+	}
+
+	expected := []string{
+		"POST /v1/domains",
+		"GET /v1/domains",
+		"GET /v1/domains/example.com",
+		"POST /v1/domains/example.com/verify",
+	}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected requests %v, got %v", expected, requests)
+	}
+
+	_, err = client.GetDomain(ctx, "missing.com")
+	httpErr, ok := err.(*HTTPError)
+	if !ok {
+		t.Fatalf("Expected HTTPError, got %T", err)
+	}
+	if httpErr.StatusCode() != http.StatusNotFound || httpErr.Error() != "Domain not found" {
+		t.Errorf("Unexpected error: %v (%d)", httpErr, httpErr.StatusCode())
+	}
+}
+
+func TestClientDomainErrors(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusUnauthorized, `{"message":"Invalid API key"}`), nil
+	})
+
+	if _, err := client.ListDomains(context.Background()); err == nil {
+		t.Fatal("Expected error, got nil")
+	} else if _, ok := err.(*AuthenticationError); !ok {
+		t.Errorf("Expected AuthenticationError, got %T", err)
+	}
+
+	for _, name := range []string{"", "localhost", "user@example.com", "example.com/verify"} {
+		if _, err := client.GetDomain(context.Background(), name); err == nil {
+			t.Errorf("Expected validation error for domain name '%s'", name)
+		} else if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("Expected ValidationError for '%s', got %T", name, err)
+		}
+	}
+}
+
+func TestClientIsDomainVerified(t *testing.T) {
+	clock := newTestClock()
+	client := NewClient("test_api_key", WithClock(clock))
+
+	calls := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(http.StatusOK, `{"data":[{"name":"Verified.com","status":"verified"},{"name":"pending.com","status":"pending"}]}`), nil
+	})
+
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name     string
+		verified bool
+	}{
+		{"verified.com", true},
+		{"pending.com", false},
+		{"unknown.com", false},
+	} {
+		verified, err := client.IsDomainVerified(ctx, tt.name)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if verified != tt.verified {
+			t.Errorf("Expected %s verified=%t, got %t", tt.name, tt.verified, verified)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the domain list to be cached, got %d requests", calls)
+	}
+
+	clock.Sleep(ctx, DomainCacheTTL)
+	if _, err := client.IsDomainVerified(ctx, "verified.com"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the cache to expire after %s, got %d requests", DomainCacheTTL, calls)
+	}
+}
+
+func TestWarnUnverifiedFrom(t *testing.T) {
+	var lists int32
+	release := make(chan struct{})
+	failList := false
+	var mutex sync.Mutex
+
+	warnings := make(chan LintWarning, 10)
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.WarnUnverifiedFrom = true
+	config.OnLintWarning = func(w LintWarning) { warnings <- w }
+
+	clock := newTestClock()
+	client := NewClientWithConfig(config, WithClock(clock), WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/v1/domains" {
+			return acceptedResponse(), nil
+		}
+		atomic.AddInt32(&lists, 1)
+		<-release
+		mutex.Lock()
+		defer mutex.Unlock()
+		if failList {
+			return jsonResponse(http.StatusServiceUnavailable, `{"message":"Unavailable"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":[{"name":"verified.com","status":"verified"},{"name":"pending.com","status":"pending"}]}`), nil
+	})))
+
+	send := func(from string) {
+		t.Helper()
+		if _, err := client.SendText(from, "to@example.com", "Subject", "Hello"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	expectWarning := func(domain string) {
+		t.Helper()
+		select {
+		case w := <-warnings:
+			if w.Code != LintUnverifiedFrom || w.Field != "from" || !strings.Contains(w.Message, domain) {
+				t.Errorf("Expected an unverified_from warning for %s, got %+v", domain, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected a warning for %s", domain)
+		}
+	}
+	expectNoWarning := func() {
+		t.Helper()
+		select {
+		case w := <-warnings:
+			t.Errorf("Expected no warning, got %+v", w)
+		default:
+		}
+	}
+
+	// The sends complete while the domain list is still being fetched
+	send("a@Pending.com")
+	send("a@verified.com")
+	close(release)
+	expectWarning("pending.com")
+
+	send("b@pending.com")
+	send("b@verified.com")
+	expectNoWarning()
+	send("a@other.com")
+	expectWarning("other.com")
+	if n := atomic.LoadInt32(&lists); n != 1 {
+		t.Errorf("Expected the domain list to be fetched once, got %d", n)
+	}
+
+	// Invalidating the cache checks the domains again
+	client.InvalidateDomainCache()
+	send("c@pending.com")
+	expectWarning("pending.com")
+	if n := atomic.LoadInt32(&lists); n != 2 {
+		t.Errorf("Expected the domain list to be fetched again, got %d", n)
+	}
+
+	// A failed fetch does not fail the send and is retried by the next one
+	mutex.Lock()
+	failList = true
+	mutex.Unlock()
+	clock.Sleep(context.Background(), DomainCacheTTL)
+	send("d@pending.com")
+	refreshing := func() bool {
+		client.domains.mutex.Lock()
+		defer client.domains.mutex.Unlock()
+		return client.domains.refreshing
+	}
+	for atomic.LoadInt32(&lists) != 3 || refreshing() {
+		time.Sleep(time.Millisecond)
+	}
+	expectNoWarning()
+	mutex.Lock()
+	failList = false
+	mutex.Unlock()
+	send("e@pending.com")
+	expectWarning("pending.com")
+}
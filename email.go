@@ -1,7 +1,9 @@
 package poodle
 
 import (
-	"regexp"
+	"encoding/json"
+	"net/http"
+	"sort"
 	"strings"
 )
 
@@ -12,6 +14,23 @@ type Email struct {
 	Subject string `json:"subject"`
 	HTML    string `json:"html,omitempty"`
 	Text    string `json:"text,omitempty"`
+
+	// FromAddress and ToAddress take precedence over From and To when set,
+	// and are sent in their place
+	FromAddress *Address `json:"-"`
+	ToAddress   *Address `json:"-"`
+
+	// Headers are additional headers of the email, such as
+	// List-Unsubscribe. Use SetHeader to set them.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Priority orders the email in Queue, SendAll and Outbox. It is not
+	// sent to the API.
+	Priority Priority `json:"-"`
+
+	// Variables are the values of the {{name}} tokens in the subject and
+	// bodies, substituted by Render. They are not sent to the API.
+	Variables map[string]string `json:"-"`
 }
 
 // Email validation constants
@@ -19,9 +38,6 @@ const (
 	MaxContentSize = 10 * 1024 * 1024 // 10MB
 )
 
-// Email address validation regex (RFC 5322 compliant)
-var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-
 // NewEmail creates a new Email instance
 func NewEmail(from, to, subject string) *Email {
 	return &Email{
@@ -62,25 +78,39 @@ func NewEmailWithBoth(from, to, subject, html, text string) *Email {
 	}
 }
 
-// Validate validates the email data
+// Validate validates the email data. Addresses are checked with
+// DefaultValidator. A subject with a line break that would start a new
+// header is rejected; folded lines, as EncodeSubject produces, are not.
 func (e *Email) Validate() error {
+	return e.validate(DefaultValidator)
+}
+
+// validate is Validate with the given address validator
+func (e *Email) validate(validator *Validator) error {
+	if e == nil {
+		return newNilEmailError()
+	}
 	errors := make(map[string][]string)
 
 	// Validate required fields
-	if strings.TrimSpace(e.From) == "" {
+	from := e.fromAddress()
+	if strings.TrimSpace(from.Email) == "" {
 		errors["from"] = append(errors["from"], "From address is required")
-	} else if !isValidEmail(e.From) {
+	} else if from.validate(validator) != nil {
 		errors["from"] = append(errors["from"], "From address is not a valid email")
 	}
 
-	if strings.TrimSpace(e.To) == "" {
+	to := e.toAddress()
+	if strings.TrimSpace(to.Email) == "" {
 		errors["to"] = append(errors["to"], "To address is required")
-	} else if !isValidEmail(e.To) {
+	} else if to.validate(validator) != nil {
 		errors["to"] = append(errors["to"], "To address is not a valid email")
 	}
 
 	if strings.TrimSpace(e.Subject) == "" {
 		errors["subject"] = append(errors["subject"], "Subject is required")
+	} else if hasHeaderBreak(e.Subject) {
+		errors["subject"] = append(errors["subject"], "Subject contains a line break")
 	}
 
 	// Validate content - at least one of HTML or Text must be provided
@@ -90,15 +120,25 @@ func (e *Email) Validate() error {
 
 	// Validate content size
 	if len(e.HTML) > MaxContentSize {
-		errors["html"] = append(errors["html"], "HTML content exceeds maximum size limit")
+		errors["html"] = append(errors["html"], contentSizeMessage("HTML", len(e.HTML), ""))
 	}
 
 	if len(e.Text) > MaxContentSize {
-		errors["text"] = append(errors["text"], "Text content exceeds maximum size limit")
+		errors["text"] = append(errors["text"], contentSizeMessage("Text", len(e.Text), ""))
+	}
+
+	for _, key := range e.headerKeys() {
+		if reason := checkEmailHeader(key, e.Headers[key]); reason != "" {
+			errors["headers"] = append(errors["headers"], reason)
+		}
 	}
 
 	if len(errors) > 0 {
-		return NewValidationError("Email validation failed", errors)
+		err := NewValidationError("Email validation failed", errors)
+		if len(e.HTML) > MaxContentSize || len(e.Text) > MaxContentSize {
+			withSizeReport(err, e)
+		}
+		return err
 	}
 
 	return nil
@@ -123,6 +163,27 @@ func (e *Email) SetBoth(html, text string) *Email {
 	return e
 }
 
+// SetHeader sets an additional header of the email. The name is
+// canonicalized, so List-Unsubscribe and list-unsubscribe are the same
+// header.
+func (e *Email) SetHeader(key, value string) *Email {
+	if e.Headers == nil {
+		e.Headers = make(map[string]string)
+	}
+	e.Headers[http.CanonicalHeaderKey(key)] = value
+	return e
+}
+
+// headerKeys returns the names of the email's headers in sorted order
+func (e *Email) headerKeys() []string {
+	keys := make([]string, 0, len(e.Headers))
+	for key := range e.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // HasHTML returns true if the email has HTML content
 func (e *Email) HasHTML() bool {
 	return strings.TrimSpace(e.HTML) != ""
@@ -133,42 +194,105 @@ func (e *Email) HasText() bool {
 	return strings.TrimSpace(e.Text) != ""
 }
 
-// isValidEmail validates email address format
-func isValidEmail(email string) bool {
-	email = strings.TrimSpace(email)
-	if len(email) == 0 || len(email) > 254 {
-		return false
+// Normalize trims surrounding whitespace from the addresses and subject,
+// lowercases the domain part of the addresses and collapses runs of
+// whitespace in the subject. The local part of addresses and the HTML and
+// text bodies are left unchanged.
+func (e *Email) Normalize() *Email {
+	e.From = normalizeAddress(e.From)
+	e.To = normalizeAddress(e.To)
+	if e.FromAddress != nil {
+		e.FromAddress.Email = normalizeAddress(e.FromAddress.Email)
 	}
+	if e.ToAddress != nil {
+		e.ToAddress.Email = normalizeAddress(e.ToAddress.Email)
+	}
+	e.Subject = strings.Join(strings.Fields(e.Subject), " ")
+	return e
+}
 
-	if !emailRegex.MatchString(email) {
-		return false
+// normalizeAddress trims an address and lowercases its domain
+func normalizeAddress(address string) string {
+	address = strings.TrimSpace(address)
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		address = address[:at+1] + strings.ToLower(address[at+1:])
 	}
+	return address
+}
 
-	parts := strings.Split(email, "@")
-	if len(parts) != 2 {
-		return false
+// fromAddress returns the sender: FromAddress if set, otherwise From
+// parsed as an address
+func (e *Email) fromAddress() Address {
+	if e.FromAddress != nil {
+		return *e.FromAddress
 	}
-	localPart := parts[0]
-	domainPart := parts[1]
+	return addressOf(e.From)
+}
 
-	if strings.HasPrefix(localPart, ".") || strings.HasSuffix(localPart, ".") || strings.Contains(localPart, "..") {
-		return false
+// toAddress returns the recipient: ToAddress if set, otherwise To parsed
+// as an address
+func (e *Email) toAddress() Address {
+	if e.ToAddress != nil {
+		return *e.ToAddress
 	}
+	return addressOf(e.To)
+}
 
-	if strings.HasPrefix(domainPart, "-") || strings.HasSuffix(domainPart, "-") ||
-		strings.HasPrefix(domainPart, ".") || strings.HasSuffix(domainPart, ".") || // Technically suffix dot might be for FQDN but generally not for email context
-		strings.Contains(domainPart, "..") { // Prevent domain..com
-		return false
+// wireFrom returns the from field as sent to the API
+func (e *Email) wireFrom() string {
+	if e.FromAddress != nil {
+		return e.FromAddress.String()
 	}
-	domainLabels := strings.Split(domainPart, ".")
-	if len(domainLabels) < 2 { // e.g. @localhost
-		return false
+	return e.From
+}
+
+// wireTo returns the to field as sent to the API
+func (e *Email) wireTo() string {
+	if e.ToAddress != nil {
+		return e.ToAddress.String()
 	}
-	for _, label := range domainLabels {
-		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") || len(label) == 0 {
-			return false // Each label cannot start or end with a hyphen or be empty
+	return e.To
+}
+
+// MarshalJSON encodes the email as sent to the API, with FromAddress and
+// ToAddress in place of From and To when set
+func (e Email) MarshalJSON() ([]byte, error) {
+	type plain Email
+	p := plain(e)
+	p.From = e.wireFrom()
+	p.To = e.wireTo()
+	return json.Marshal(p)
+}
+
+// newNilEmailError returns the ValidationError of a nil *Email
+func newNilEmailError() *ValidationError {
+	return NewValidationError("email must not be nil", map[string][]string{
+		"email": {"Email must not be nil"},
+	})
+}
+
+// clone returns a copy of the email that can be modified independently
+func (e *Email) clone() *Email {
+	clone := *e
+	if e.FromAddress != nil {
+		from := *e.FromAddress
+		clone.FromAddress = &from
+	}
+	if e.ToAddress != nil {
+		to := *e.ToAddress
+		clone.ToAddress = &to
+	}
+	if e.Headers != nil {
+		clone.Headers = make(map[string]string, len(e.Headers))
+		for key, value := range e.Headers {
+			clone.Headers[key] = value
 		}
 	}
-
-	return true
+	if e.Variables != nil {
+		clone.Variables = make(map[string]string, len(e.Variables))
+		for key, value := range e.Variables {
+			clone.Variables[key] = value
+		}
+	}
+	return &clone
 }
@@ -1,6 +1,7 @@
 package poodle
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -266,3 +267,35 @@ func TestIsValidEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestEmailNormalize(t *testing.T) {
+	email := &Email{
+		From:    " Sender@Example.COM\t",
+		To:      "Bob@EXAMPLE.COM ",
+		Subject: "  Your   order has\n shipped  ",
+		HTML:    "  <p>Hello   World</p>  ",
+		Text:    "  Hello   World  ",
+	}
+
+	email.Normalize()
+
+	if email.From != "Sender@example.com" {
+		t.Errorf("Expected From to be 'Sender@example.com', got '%s'", email.From)
+	}
+	if email.To != "Bob@example.com" {
+		t.Errorf("Expected To to be 'Bob@example.com', got '%s'", email.To)
+	}
+	if email.Subject != "Your order has shipped" {
+		t.Errorf("Expected Subject to be 'Your order has shipped', got '%s'", email.Subject)
+	}
+	if email.HTML != "  <p>Hello   World</p>  " || email.Text != "  Hello   World  " {
+		t.Errorf("Expected bodies to be untouched, got %q and %q", email.HTML, email.Text)
+	}
+
+	clean := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	normalized := *clean
+	normalized.Normalize()
+	if !reflect.DeepEqual(normalized, *clean) {
+		t.Errorf("Expected a clean email to be unchanged, got %+v", normalized)
+	}
+}
@@ -0,0 +1,361 @@
+package poodle
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by NewConfigFromEnv
+const (
+	EnvAPIKey               = "POODLE_API_KEY"
+	EnvBaseURL              = "POODLE_BASE_URL"
+	EnvTimeout              = "POODLE_TIMEOUT"
+	EnvConnectTimeout       = "POODLE_CONNECT_TIMEOUT"
+	EnvDebug                = "POODLE_DEBUG"
+	EnvServerless           = "POODLE_SERVERLESS"
+	EnvDisableKeepAlives    = "POODLE_DISABLE_KEEP_ALIVES"
+	EnvLocalAddr            = "POODLE_LOCAL_ADDR"
+	EnvForceIPv4            = "POODLE_FORCE_IPV4"
+	EnvFallbackDelay        = "POODLE_FALLBACK_DELAY"
+	EnvHardenedTransport    = "POODLE_HARDENED_TRANSPORT"
+	EnvWarmupOnCreate       = "POODLE_WARMUP_ON_CREATE"
+	EnvLogLevel             = "POODLE_LOG_LEVEL"
+	EnvPIIMode              = "POODLE_PII_MODE"
+	EnvLocale               = "POODLE_LOCALE"
+	EnvCaptureExchanges     = "POODLE_CAPTURE_EXCHANGES"
+	EnvMaxRetries           = "POODLE_MAX_RETRIES"
+	EnvRetryBackoff         = "POODLE_RETRY_BACKOFF"
+	EnvRetryMaxElapsed      = "POODLE_RETRY_MAX_ELAPSED"
+	EnvOverallDeadline      = "POODLE_OVERALL_DEADLINE"
+	EnvBestEffortTimeout    = "POODLE_BEST_EFFORT_TIMEOUT"
+	EnvWaitOnRateLimit      = "POODLE_WAIT_ON_RATE_LIMIT"
+	EnvMaxRateLimitWait     = "POODLE_MAX_RATE_LIMIT_WAIT"
+	EnvMaxRequestsPerSecond = "POODLE_MAX_REQUESTS_PER_SECOND"
+	EnvAdaptivePacing       = "POODLE_ADAPTIVE_PACING"
+	EnvDefaultFrom          = "POODLE_DEFAULT_FROM"
+	EnvAutoNormalize        = "POODLE_AUTO_NORMALIZE"
+	EnvAutoRender           = "POODLE_AUTO_RENDER"
+	EnvEncodeSubjects       = "POODLE_ENCODE_SUBJECTS"
+	EnvCanonicalPayloads    = "POODLE_CANONICAL_PAYLOADS"
+	EnvSanitizeHTML         = "POODLE_SANITIZE_HTML"
+	EnvAutoMinifyHTML       = "POODLE_AUTO_MINIFY_HTML"
+	EnvFallbackToText       = "POODLE_FALLBACK_TO_TEXT"
+	EnvDisabledLints        = "POODLE_DISABLED_LINTS"
+	EnvStrictContentChecks  = "POODLE_STRICT_CONTENT_CHECKS"
+	EnvWarnUnverifiedFrom   = "POODLE_WARN_UNVERIFIED_FROM"
+	EnvAllowedDomains       = "POODLE_ALLOWED_DOMAINS"
+	EnvBlockedDomains       = "POODLE_BLOCKED_DOMAINS"
+	EnvRejectDisposable     = "POODLE_REJECT_DISPOSABLE"
+)
+
+// envPrefix starts the names of the variables read by NewConfigFromEnv
+const envPrefix = "POODLE_"
+
+// Types of environment variables, as in EnvVarSpec.Type
+const (
+	EnvTypeString   = "string"
+	EnvTypeBool     = "bool"
+	EnvTypeInteger  = "integer"
+	EnvTypeNumber   = "number"
+	EnvTypeDuration = "duration"
+	EnvTypeList     = "list"
+	EnvTypeEnum     = "enum"
+)
+
+// EnvVarSpec describes an environment variable read by NewConfigFromEnv,
+// e.g. to generate documentation or deployment manifests
+type EnvVarSpec struct {
+	Name string
+	// Type is one of the EnvType constants: a duration is written like
+	// 30s, a bool like true or 0, and a list is comma-separated
+	Type string
+	// Default is the value used when the variable is unset, written as
+	// the variable would be, or empty if there is none
+	Default     string
+	Description string
+	// Values lists the values of an enum
+	Values []string
+}
+
+// boundedInt is an integer field with its accepted range
+type boundedInt struct {
+	target   *int
+	min, max int
+}
+
+// signedDuration is a duration field that also accepts zero and negative
+// values, such as FallbackDelay, where a negative value has a meaning
+type signedDuration struct {
+	target *time.Duration
+}
+
+// envVar is a variable read by NewConfigFromEnv and the Config field it
+// sets. The type of the field, returned by field as a pointer, decides how
+// the value is parsed and the type reported by EnvVars.
+type envVar struct {
+	name         string
+	defaultValue string
+	description  string
+	field        func(*Config) interface{}
+}
+
+// envVars lists the variables read by NewConfigFromEnv, in the order they
+// are documented
+var envVars = []envVar{
+	{EnvAPIKey, "", "Your Poodle API key", func(c *Config) interface{} { return &c.APIKey }},
+	{EnvBaseURL, DefaultBaseURL, "API base URL", func(c *Config) interface{} { return &c.BaseURL }},
+	{EnvTimeout, DefaultTimeout.String(), "Request timeout", func(c *Config) interface{} { return &c.Timeout }},
+	{EnvConnectTimeout, DefaultConnectTimeout.String(), "Connection timeout", func(c *Config) interface{} { return &c.ConnectTimeout }},
+	{EnvDebug, "false", "Enable debug logging", func(c *Config) interface{} { return &c.Debug }},
+	{EnvServerless, "false", "Tune connections for serverless platforms", func(c *Config) interface{} { return &c.Serverless }},
+	{EnvDisableKeepAlives, "false", "Open a new connection per request", func(c *Config) interface{} { return &c.DisableKeepAlives }},
+	{EnvLocalAddr, "", "Local IP address to bind connections to", func(c *Config) interface{} { return &c.LocalAddr }},
+	{EnvForceIPv4, "false", "Connect over IPv4 only", func(c *Config) interface{} { return &c.ForceIPv4 }},
+	{EnvFallbackDelay, "300ms", "Wait for IPv6 before also trying IPv4; negative disables the fallback", func(c *Config) interface{} { return signedDuration{&c.FallbackDelay} }},
+	{EnvHardenedTransport, "false", "Enforce https, TLS 1.2+ and certificate verification", func(c *Config) interface{} { return &c.HardenedTransport }},
+	{EnvWarmupOnCreate, "false", "Open a connection to the API when the client is created", func(c *Config) interface{} { return &c.WarmupOnCreate }},
+	{EnvLogLevel, LogLevelOff.String(), "Log level", func(c *Config) interface{} { return &c.LogLevel }},
+	{EnvPIIMode, string(PIIModeFull), "How recipient addresses appear in errors and logs", func(c *Config) interface{} { return &c.PIIMode }},
+	{EnvLocale, "", "Language of API error messages, e.g. de-DE", func(c *Config) interface{} { return &c.Locale }},
+	{EnvCaptureExchanges, "false", "Keep recent requests and responses for support", func(c *Config) interface{} { return &c.CaptureExchanges }},
+	{EnvMaxRetries, "0", "Retries for transient failures", func(c *Config) interface{} { return boundedInt{&c.MaxRetries, 0, MaxRetriesLimit} }},
+	{EnvRetryBackoff, DefaultRetryBackoff.String(), "Initial delay between retries", func(c *Config) interface{} { return &c.RetryBackoff }},
+	{EnvRetryMaxElapsed, "", "Maximum total time spent retrying", func(c *Config) interface{} { return &c.RetryMaxElapsed }},
+	{EnvOverallDeadline, "", "Maximum total time of a send, including retries", func(c *Config) interface{} { return &c.OverallDeadline }},
+	{EnvBestEffortTimeout, DefaultBestEffortTimeout.String(), "Maximum time of a BestEffort send", func(c *Config) interface{} { return &c.BestEffortTimeout }},
+	{EnvWaitOnRateLimit, "false", "Retry rate-limited requests after Retry-After", func(c *Config) interface{} { return &c.WaitOnRateLimit }},
+	{EnvMaxRateLimitWait, DefaultMaxRateLimitWait.String(), "Maximum wait taken from rate-limit headers", func(c *Config) interface{} { return &c.MaxRateLimitWait }},
+	{EnvMaxRequestsPerSecond, "", "Client-side request rate limit", func(c *Config) interface{} { return &c.MaxRequestsPerSecond }},
+	{EnvAdaptivePacing, "false", "Pace requests from rate-limit headers", func(c *Config) interface{} { return &c.AdaptivePacing }},
+	{EnvDefaultFrom, "", "Sender of emails without a From address", func(c *Config) interface{} { return &c.DefaultFrom }},
+	{EnvAutoNormalize, "false", "Normalize addresses and subject before sending", func(c *Config) interface{} { return &c.AutoNormalize }},
+	{EnvAutoRender, "false", "Render template variables before sending", func(c *Config) interface{} { return &c.AutoRender }},
+	{EnvEncodeSubjects, "false", "RFC 2047-encode non-ASCII subjects", func(c *Config) interface{} { return &c.EncodeSubjects }},
+	{EnvCanonicalPayloads, "false", "Send byte-stable canonical JSON request bodies", func(c *Config) interface{} { return &c.CanonicalPayloads }},
+	{EnvSanitizeHTML, "false", "Remove scripts and unsafe markup from HTML", func(c *Config) interface{} { return &c.SanitizeHTML }},
+	{EnvAutoMinifyHTML, "false", "Minify HTML bodies over the size limit before sending", func(c *Config) interface{} { return &c.AutoMinifyHTML }},
+	{EnvFallbackToText, "false", "Resend as text when HTML content is rejected", func(c *Config) interface{} { return &c.FallbackToText }},
+	{EnvDisabledLints, "", "Lint codes not reported", func(c *Config) interface{} { return &c.DisabledLints }},
+	{EnvStrictContentChecks, "false", "Fail sends whose HTML or text is in the wrong field", func(c *Config) interface{} { return &c.StrictContentChecks }},
+	{EnvWarnUnverifiedFrom, "false", "Warn when the From domain is not verified", func(c *Config) interface{} { return &c.WarnUnverifiedFrom }},
+	{EnvAllowedDomains, "", "Recipient domain allow-list", func(c *Config) interface{} { return &c.AllowedRecipientDomains }},
+	{EnvBlockedDomains, "", "Recipient domain deny-list", func(c *Config) interface{} { return &c.BlockedRecipientDomains }},
+	{EnvRejectDisposable, "false", "Reject disposable recipient addresses", func(c *Config) interface{} { return &c.RejectDisposable }},
+}
+
+// EnvVars describes the environment variables read by NewConfigFromEnv,
+// in the order they are documented
+func EnvVars() []EnvVarSpec {
+	var scratch Config
+	specs := make([]EnvVarSpec, len(envVars))
+	for i, v := range envVars {
+		spec := EnvVarSpec{Name: v.name, Default: v.defaultValue, Description: v.description}
+		switch field := v.field(&scratch).(type) {
+		case *string:
+			spec.Type = EnvTypeString
+		case *bool:
+			spec.Type = EnvTypeBool
+		case boundedInt:
+			spec.Type = EnvTypeInteger
+			spec.Description += fmt.Sprintf(" (%d-%d)", field.min, field.max)
+		case *float64:
+			spec.Type = EnvTypeNumber
+		case *time.Duration, signedDuration:
+			spec.Type = EnvTypeDuration
+		case *[]string:
+			spec.Type = EnvTypeList
+		case *LogLevel:
+			spec.Type = EnvTypeEnum
+			spec.Values = []string{"off", "error", "info", "trace"}
+		case *PIIMode:
+			spec.Type = EnvTypeEnum
+			spec.Values = []string{string(PIIModeFull), string(PIIModeHashed), string(PIIModeOmit)}
+		}
+		specs[i] = spec
+	}
+	return specs
+}
+
+// ValidateEnv checks the variables of an environment, e.g. a deployment
+// manifest, as NewConfigFromEnvStrict would read them. It returns a
+// ValidationError listing each invalid value and each POODLE_ variable
+// that NewConfigFromEnv does not read, such as a misspelled name.
+// Variables without the prefix are ignored.
+func ValidateEnv(vars map[string]string) error {
+	_, errors := configFromEnv(func(name string) string { return vars[name] })
+
+	known := make(map[string]bool, len(envVars))
+	for _, v := range envVars {
+		known[v.name] = true
+	}
+	var unknown []string
+	for name := range vars {
+		if strings.HasPrefix(name, envPrefix) && !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errors[name] = append(errors[name], name+" is not a Poodle environment variable")
+	}
+
+	if len(errors) > 0 {
+		return NewValidationError("Invalid environment configuration", errors)
+	}
+	return nil
+}
+
+// loadConfigFromEnv builds a configuration from environment variables and
+// returns the parse errors keyed by variable name
+func loadConfigFromEnv() (*Config, map[string][]string) {
+	return configFromEnv(os.Getenv)
+}
+
+// configFromEnv builds a configuration from the variables returned by
+// getenv, which returns an empty string for unset variables
+func configFromEnv(getenv func(string) string) (*Config, map[string][]string) {
+	config := NewConfig()
+	env := &envParser{errors: make(map[string][]string)}
+	for _, v := range envVars {
+		if value := getenv(v.name); value != "" {
+			env.parse(v.name, value, v.field(config))
+		}
+	}
+	return config, env.errors
+}
+
+// envParser reads typed environment variables, recording invalid values
+// instead of applying them
+type envParser struct {
+	errors map[string][]string
+}
+
+func (p *envParser) invalid(name, reason string) {
+	p.errors[name] = append(p.errors[name], fmt.Sprintf("%s %s", name, reason))
+}
+
+// parse sets the field to the value of the variable
+func (p *envParser) parse(name, value string, field interface{}) {
+	switch target := field.(type) {
+	case *string:
+		*target = value
+	case *bool:
+		p.boolean(name, value, target)
+	case boundedInt:
+		p.integer(name, value, target)
+	case *float64:
+		p.float(name, value, target)
+	case *time.Duration:
+		p.duration(name, value, target)
+	case signedDuration:
+		p.signedDuration(name, value, target)
+	case *[]string:
+		p.list(value, target)
+	case *LogLevel:
+		p.logLevel(name, value, target)
+	case *PIIMode:
+		p.piiMode(name, value, target)
+	default:
+		panic(fmt.Sprintf("poodle: unsupported field type %T for %s", field, name))
+	}
+}
+
+func (p *envParser) duration(name, value string, target *time.Duration) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		p.invalid(name, "must be a duration such as 30s")
+		return
+	}
+	if duration <= 0 {
+		p.invalid(name, "must be positive")
+		return
+	}
+
+	*target = duration
+}
+
+func (p *envParser) signedDuration(name, value string, target signedDuration) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		p.invalid(name, "must be a duration such as 30s")
+		return
+	}
+
+	*target.target = duration
+}
+
+func (p *envParser) boolean(name, value string, target *bool) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.invalid(name, "must be a boolean")
+		return
+	}
+
+	*target = b
+}
+
+func (p *envParser) integer(name, value string, target boundedInt) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		p.invalid(name, "must be an integer")
+		return
+	}
+	if n < target.min || n > target.max {
+		p.invalid(name, fmt.Sprintf("must be between %d and %d", target.min, target.max))
+		return
+	}
+
+	*target.target = n
+}
+
+func (p *envParser) float(name, value string, target *float64) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		p.invalid(name, "must be a number")
+		return
+	}
+	if f < 0 {
+		p.invalid(name, "must not be negative")
+		return
+	}
+
+	*target = f
+}
+
+func (p *envParser) logLevel(name, value string, target *LogLevel) {
+	level, err := ParseLogLevel(value)
+	if err != nil {
+		p.invalid(name, "must be one of off, error, info or trace")
+		return
+	}
+
+	*target = level
+}
+
+// piiMode reads a PII mode name
+func (p *envParser) piiMode(name, value string, target *PIIMode) {
+	mode, err := ParsePIIMode(value)
+	if err != nil {
+		p.invalid(name, "must be one of full, hashed or omit")
+		return
+	}
+
+	*target = mode
+}
+
+// list reads a comma-separated list, ignoring empty items
+func (p *envParser) list(value string, target *[]string) {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	*target = items
+}
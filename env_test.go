@@ -0,0 +1,132 @@
+package poodle
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleEnvValue returns a valid value of the variable's type that differs
+// from its default
+func sampleEnvValue(spec EnvVarSpec) string {
+	switch spec.Type {
+	case EnvTypeBool:
+		return "true"
+	case EnvTypeInteger:
+		return "1"
+	case EnvTypeNumber:
+		return "2.5"
+	case EnvTypeDuration:
+		return "42s"
+	case EnvTypeList:
+		return "a, b"
+	case EnvTypeEnum:
+		return spec.Values[len(spec.Values)-1]
+	default:
+		return "https://sample.example.com"
+	}
+}
+
+func TestEnvVarsAreReadByNewConfigFromEnv(t *testing.T) {
+	specs := EnvVars()
+	if len(specs) != len(envVars) {
+		t.Fatalf("Expected %d specs, got %d", len(envVars), len(specs))
+	}
+
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		if !strings.HasPrefix(spec.Name, envPrefix) || seen[spec.Name] {
+			t.Errorf("Expected a unique POODLE_ name, got %q", spec.Name)
+		}
+		seen[spec.Name] = true
+		if spec.Type == "" || spec.Description == "" {
+			t.Errorf("Expected %s to have a type and description, got %+v", spec.Name, spec)
+		}
+		if (spec.Type == EnvTypeEnum) != (len(spec.Values) > 0) {
+			t.Errorf("Expected values for enums only, got %+v", spec)
+		}
+
+		t.Run(spec.Name, func(t *testing.T) {
+			t.Setenv(spec.Name, sampleEnvValue(spec))
+			config, err := NewConfigFromEnvStrict()
+			if err != nil {
+				t.Fatalf("Expected %s=%s to be accepted, got %v", spec.Name, sampleEnvValue(spec), err)
+			}
+			if reflect.DeepEqual(config, NewConfig()) {
+				t.Errorf("Expected %s to change the configuration", spec.Name)
+			}
+		})
+	}
+
+	defaults := make(map[string]string)
+	for _, spec := range specs {
+		if spec.Default != "" {
+			defaults[spec.Name] = spec.Default
+		}
+	}
+	if err := ValidateEnv(defaults); err != nil {
+		t.Errorf("Expected the defaults to be valid, got %v", err)
+	}
+}
+
+func TestEnvVarsMatchREADME(t *testing.T) {
+	readme, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows [][]string
+	for _, line := range strings.Split(string(readme), "\n") {
+		if !strings.HasPrefix(line, "| `POODLE_") {
+			continue
+		}
+		var cells []string
+		for _, cell := range strings.Split(strings.Trim(line, "|"), "|") {
+			cells = append(cells, strings.Trim(strings.TrimSpace(cell), "`"))
+		}
+		rows = append(rows, cells)
+	}
+
+	specs := EnvVars()
+	if len(rows) != len(specs) {
+		t.Fatalf("Expected %d documented variables, got %d", len(specs), len(rows))
+	}
+	for i, spec := range specs {
+		want := spec.Default
+		if want == "" {
+			want = "-"
+		}
+		if rows[i][0] != spec.Name || rows[i][1] != want {
+			t.Errorf("Expected row %d to document %s with default %s, got %v", i, spec.Name, want, rows[i][:2])
+		}
+	}
+}
+
+func TestValidateEnv(t *testing.T) {
+	if err := ValidateEnv(map[string]string{EnvAPIKey: "key", EnvMaxRetries: "3", "HOME": "/root"}); err != nil {
+		t.Errorf("Expected a valid environment, got %v", err)
+	}
+
+	err := ValidateEnv(map[string]string{
+		"POODLE_BASEURL":  "https://api.example.com",
+		EnvMaxRetries:     "11",
+		EnvTimeout:        "soon",
+		"POODLE_API_KEY":  "key",
+		"OTHER_SERVICE_X": "1",
+	})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	if len(validationErr.Errors) != 3 {
+		t.Errorf("Expected 3 invalid variables, got %v", validationErr.Errors)
+	}
+	if got := validationErr.Errors["POODLE_BASEURL"]; len(got) != 1 || got[0] != "POODLE_BASEURL is not a Poodle environment variable" {
+		t.Errorf("Expected the misspelled name to be reported, got %v", got)
+	}
+	if got := validationErr.Errors[EnvMaxRetries]; len(got) != 1 || got[0] != "POODLE_MAX_RETRIES must be between 0 and 10" {
+		t.Errorf("Expected the out-of-range value to be reported, got %v", got)
+	}
+}
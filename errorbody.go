@@ -0,0 +1,101 @@
+package poodle
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// errorExcerptLength is the number of characters of a non-JSON error
+	// body quoted in the error message
+	errorExcerptLength = 200
+	// errorBodyLimit is the number of bytes of a non-JSON error body kept
+	// in the error context
+	errorBodyLimit = 4096
+)
+
+// proxyServers are substrings of the Server header of intermediaries that
+// answer with their own error pages rather than the API's
+var proxyServers = []string{
+	"cloudflare", "nginx", "envoy", "squid", "varnish", "haproxy",
+	"akamai", "cloudfront", "awselb", "bigip", "zscaler", "traefik",
+}
+
+// annotateNonJSONError adds the body of an error response that is not JSON,
+// such as the HTML page of a load balancer, to its error. The message gets
+// an excerpt of the body text, and the context gets the content type, the
+// body up to errorBodyLimit bytes and proxy_generated if the headers name
+// an intermediary. JSON bodies leave the error unchanged.
+func annotateNonJSONError(err error, resp *http.Response, body []byte) error {
+	contentType := resp.Header.Get("Content-Type")
+	if isJSONBody(contentType, body) {
+		return err
+	}
+
+	withBase, ok := err.(interface{ base() *BaseError })
+	if !ok {
+		return err
+	}
+	base := withBase.base()
+	if base.ContextMap == nil {
+		base.ContextMap = make(map[string]interface{})
+	}
+
+	if excerpt := bodyExcerpt(contentType, body); excerpt != "" {
+		base.Message += ": " + excerpt
+		base.ContextMap["content_type"] = contentType
+		base.ContextMap["response_body"] = truncate(string(body), errorBodyLimit)
+	}
+	if isProxyResponse(resp.Header) {
+		base.ContextMap["proxy_generated"] = true
+	}
+	return err
+}
+
+// isJSONBody reports whether a request or response body can be parsed as
+// JSON. A body declared as another media type is not, even if it happens
+// to be valid JSON, and a body without a content type is JSON if it parses.
+func isJSONBody(contentType string, body []byte) bool {
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			return false
+		}
+	}
+	var value json.RawMessage
+	return decodeResponseBody(body, &value) == nil
+}
+
+// bodyExcerpt returns the start of the text of a body, with HTML markup
+// removed and whitespace collapsed, for use in an error message
+func bodyExcerpt(contentType string, body []byte) string {
+	text := string(body)
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "text/html" || (contentType == "" && strings.HasPrefix(strings.TrimSpace(text), "<")) {
+		text = htmlToText(text)
+	}
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) > errorExcerptLength {
+		return string(runes[:errorExcerptLength]) + "..."
+	}
+	return text
+}
+
+// isProxyResponse reports whether the headers of a response suggest that
+// it was generated by an intermediary, such as a load balancer or a
+// corporate proxy, rather than the API
+func isProxyResponse(header http.Header) bool {
+	if header.Get("Via") != "" {
+		return true
+	}
+	server := strings.ToLower(header.Get("Server"))
+	for _, proxy := range proxyServers {
+		if strings.Contains(server, proxy) {
+			return true
+		}
+	}
+	return false
+}
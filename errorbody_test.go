@@ -0,0 +1,129 @@
+package poodle
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func nonJSONTestClient(t *testing.T, resp func() *http.Response) *Client {
+	t.Helper()
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 0
+
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return resp(), nil
+	})
+	return client
+}
+
+func TestProxyErrorPage(t *testing.T) {
+	page, err := os.ReadFile(filepath.Join("testdata", "errors", "proxy_502.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := nonJSONTestClient(t, func() *http.Response {
+		header := http.Header{}
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		header.Set("Server", "nginx/1.25.3")
+		return &http.Response{StatusCode: http.StatusBadGateway, Header: header, Body: io.NopCloser(strings.NewReader(string(page)))}
+	})
+
+	_, err = client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected an HTTPError, got %T: %v", err, err)
+	}
+	if !strings.Contains(err.Error(), "HTTP 502 error: 502 Bad Gateway") || strings.Contains(err.Error(), "<") || strings.Contains(err.Error(), "font-family") {
+		t.Errorf("Expected an excerpt of the page text in the error, got %q", err.Error())
+	}
+	if httpErr.Context()["proxy_generated"] != true {
+		t.Errorf("Expected the nginx page to be marked as proxy generated, got %v", httpErr.Context())
+	}
+	if httpErr.Context()["response_body"] != string(page) || httpErr.Context()["content_type"] != "text/html; charset=utf-8" {
+		t.Errorf("Expected the page in the context, got %v", httpErr.Context())
+	}
+}
+
+func TestNonJSONErrorBodies(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		header      string
+		message     string
+		proxy       bool
+	}{
+		{"text 400", 400, "text/plain", "bad request\n", "", "bad request", false},
+		{"text 401", 401, "text/plain", "Unauthorized", "", "Unauthorized", false},
+		{"text 429", 429, "text/plain", "Too many requests", "", "Too many requests", false},
+		{"text 503 via", 503, "text/plain", "upstream connect error", "1.1 varnish", "upstream connect error", true},
+		{"json mislabeled", 500, "text/plain", `{"message":"oops"}`, "", `{"message":"oops"}`, false},
+		{"no content type", 500, "", "<html><body><p>Internal error</p></body></html>", "", "Internal error", false},
+		{"long", 500, "text/plain", strings.Repeat("é", 300), "", strings.Repeat("é", errorExcerptLength) + "...", false},
+	}
+
+	for _, tt := range tests {
+		client := nonJSONTestClient(t, func() *http.Response {
+			header := http.Header{}
+			if tt.contentType != "" {
+				header.Set("Content-Type", tt.contentType)
+			}
+			if tt.header != "" {
+				header.Set("Via", tt.header)
+			}
+			header.Set("Retry-After", "1")
+			return &http.Response{StatusCode: tt.status, Header: header, Body: io.NopCloser(strings.NewReader(tt.body))}
+		})
+
+		_, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected %q in the error, got %v", tt.name, tt.message, err)
+			continue
+		}
+		context := err.(interface{ Context() map[string]interface{} }).Context()
+		if (context["proxy_generated"] == true) != tt.proxy {
+			t.Errorf("%s: expected proxy_generated %t, got %v", tt.name, tt.proxy, context)
+		}
+	}
+}
+
+func TestNonJSONRateLimit(t *testing.T) {
+	client := nonJSONTestClient(t, func() *http.Response {
+		header := http.Header{}
+		header.Set("Content-Type", "text/plain")
+		header.Set("Retry-After", "30")
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: header, Body: io.NopCloser(strings.NewReader("slow down"))}
+	})
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != 30 {
+		t.Errorf("Expected a RateLimitError from the headers, got %T: %v", err, err)
+	}
+}
+
+func TestJSONErrorBodyUnchanged(t *testing.T) {
+	client := nonJSONTestClient(t, func() *http.Response {
+		resp := jsonResponse(http.StatusBadGateway, `{"message":"Upstream failed"}`)
+		resp.Header.Set("Server", "nginx")
+		return resp
+	})
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Message != "Upstream failed" {
+		t.Fatalf("Expected the JSON message, got %v", err)
+	}
+	if _, ok := httpErr.Context()["proxy_generated"]; ok {
+		t.Errorf("Expected no annotation of a JSON body, got %v", httpErr.Context())
+	}
+}
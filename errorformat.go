@@ -0,0 +1,245 @@
+package poodle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The errors of this package implement fmt.Formatter. %v and %s print a
+// one-line summary in a semi-stable format, meant for logs and for
+// searching them:
+//
+//	poodle: <error_type>: <message> (<status>, <key>=<value>, ...)
+//
+// for example
+//
+//	poodle: rate_limit_exceeded: Rate limit exceeded (429, retry_after=60s)
+//
+// The status is omitted when the error has none, and the parentheses when
+// there is nothing to put in them. The prefix, the error type and the
+// order of the parts are kept across minor versions; the messages and the
+// key=value details may change. %+v adds the error's context, one
+// "key: value" line per entry sorted by key, and the wrapped cause in
+// its own %+v format, each indented below the summary. %q quotes the
+// summary. Error() is unchanged.
+
+// formatDetailer is implemented by errors that add key=value details to
+// their summary
+type formatDetailer interface {
+	formatDetails() []string
+}
+
+// formatError implements fmt.Formatter for a Poodle error
+func formatError(s fmt.State, verb rune, err PoodleError) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, verboseError(err))
+			return
+		}
+		io.WriteString(s, summarizeError(err))
+	case 's':
+		io.WriteString(s, summarizeError(err))
+	case 'q':
+		fmt.Fprintf(s, "%q", summarizeError(err))
+	default:
+		fmt.Fprintf(s, "%%!%c(%T=%s)", verb, err, summarizeError(err))
+	}
+}
+
+// summarizeError returns the one-line summary of a Poodle error
+func summarizeError(err PoodleError) string {
+	message := err.Error()
+	if base := baseErrorOf(err); base != nil && base.Message != "" {
+		message = base.Message
+	}
+
+	var details []string
+	if code := err.StatusCode(); code != 0 {
+		details = append(details, strconv.Itoa(code))
+	}
+	if detailer, ok := err.(formatDetailer); ok {
+		details = append(details, detailer.formatDetails()...)
+	}
+
+	errorType := errorTypeOf(err)
+	if _, ok := err.(*ValidationError); ok && errorType == "unknown" {
+		// Configuration errors are built without a context
+		errorType = "validation_error"
+	}
+
+	summary := "poodle: " + errorType + ": " + message
+	if len(details) > 0 {
+		summary += " (" + strings.Join(details, ", ") + ")"
+	}
+	return summary
+}
+
+// verboseError returns the summary of a Poodle error followed by its
+// context and its cause
+func verboseError(err PoodleError) string {
+	var b strings.Builder
+	b.WriteString(summarizeError(err))
+
+	var context map[string]interface{}
+	if base := baseErrorOf(err); base != nil {
+		context = base.ContextMap
+	}
+	keys := make([]string, 0, len(context))
+	for key := range context {
+		if key != "error_type" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\n    %s: %v", key, context[key])
+	}
+
+	if cause := errors.Unwrap(err); cause != nil {
+		b.WriteString("\n    cause: ")
+		b.WriteString(strings.ReplaceAll(fmt.Sprintf("%+v", cause), "\n", "\n    "))
+	}
+	return b.String()
+}
+
+// baseErrorOf returns the BaseError embedded in a Poodle error
+func baseErrorOf(err PoodleError) *BaseError {
+	switch e := err.(type) {
+	case *BaseError:
+		return e
+	case interface{ base() *BaseError }:
+		return e.base()
+	}
+	return nil
+}
+
+// Format implements fmt.Formatter
+func (e *BaseError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *ValidationError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *AuthenticationError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *AccountSuspendedError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *SubscriptionError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *RateLimitError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *PayloadTooLargeError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *NetworkError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *TimeoutError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *HTTPError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *QueueError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *DuplicateEmailError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *DeadlineExceededError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *SecurityPolicyError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *DNSLookupWarning) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *NoRouteError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *ResponseParseError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *ResponseReadError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *EncodeError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *DeadLetterError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+// Format implements fmt.Formatter
+func (e *UnresolvedAssetsError) Format(s fmt.State, verb rune) { formatError(s, verb, e) }
+
+func (e *ValidationError) formatDetails() []string {
+	fields := make([]string, 0, len(e.Errors))
+	for field := range e.Errors {
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	sort.Strings(fields)
+	return []string{"fields=" + strings.Join(fields, ",")}
+}
+
+func (e *AuthenticationError) formatDetails() []string {
+	return []string{"reason=" + string(e.Reason)}
+}
+
+func (e *AccountSuspendedError) formatDetails() []string {
+	return []string{"reason=" + string(e.Kind)}
+}
+
+func (e *RateLimitError) formatDetails() []string {
+	return []string{"retry_after=" + strconv.Itoa(e.RetryAfter) + "s"}
+}
+
+func (e *PayloadTooLargeError) formatDetails() []string {
+	details := []string{"payload_size=" + strconv.Itoa(e.PayloadSize)}
+	if e.Limit > 0 {
+		details = append(details, "limit="+strconv.Itoa(e.Limit))
+	}
+	return details
+}
+
+func (e *NetworkError) formatDetails() []string {
+	var details []string
+	if e.Kind != "" {
+		details = append(details, "kind="+string(e.Kind))
+	}
+	if e.RequestSent {
+		details = append(details, "request_sent=true")
+	}
+	return details
+}
+
+func (e *TimeoutError) formatDetails() []string {
+	return []string{"phase=" + string(e.Phase), "timeout=" + e.Timeout.String()}
+}
+
+func (e *QueueError) formatDetails() []string {
+	return []string{"retryable=" + strconv.FormatBool(e.Retryable)}
+}
+
+func (e *DeadlineExceededError) formatDetails() []string {
+	return []string{"attempts=" + strconv.Itoa(e.Attempts)}
+}
+
+func (e *SecurityPolicyError) formatDetails() []string {
+	return []string{"policy=" + string(e.Policy)}
+}
+
+func (e *DeadLetterError) formatDetails() []string {
+	return []string{"attempts=" + strconv.Itoa(e.Attempts)}
+}
@@ -0,0 +1,124 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// formattedErrors are the errors whose formatting is pinned by the golden
+// files in testdata/errors/format
+var formattedErrors = map[string]func() PoodleError{
+	"base": func() PoodleError {
+		return &BaseError{Message: "Something went wrong"}
+	},
+	"validation": func() PoodleError {
+		return NewValidationError("Invalid email", map[string][]string{
+			"to":      {"To is required"},
+			"subject": {"Subject is required"},
+		})
+	},
+	"config_validation": func() PoodleError {
+		return &ValidationError{BaseError: BaseError{Message: "API key is required"}}
+	},
+	"authentication": func() PoodleError {
+		return NewAuthenticationErrorWithReason("Invalid API key", AuthReasonInvalidKey)
+	},
+	"account_suspended": func() PoodleError {
+		return NewAccountSuspendedError("Account suspended", "payment_failed")
+	},
+	"subscription": func() PoodleError {
+		return NewSubscriptionError("Monthly limit reached", "limit_reached")
+	},
+	"rate_limit": func() PoodleError {
+		return NewRateLimitError("Rate limit exceeded", 60, 100, 0, 60)
+	},
+	"payload_too_large": func() PoodleError {
+		return NewPayloadTooLargeError("", 12582912, 10485760)
+	},
+	"network": func() PoodleError {
+		return newNetworkErrorKind(NetworkErrorKindRefused, "connection refused", "https://api.usepoodle.com/v1/send-email")
+	},
+	"connection_timeout": func() PoodleError {
+		return NewConnectionTimeoutError(30, "https://api.usepoodle.com/v1/send-email")
+	},
+	"timeout": func() PoodleError {
+		return NewTimeoutError(TimeoutPhaseTotal, TimeoutPhaseBody, 10*time.Second, "https://api.usepoodle.com/v1/send-email", context.DeadlineExceeded)
+	},
+	"http": func() PoodleError {
+		return NewHTTPError(502, "Bad gateway", "https://api.usepoodle.com/v1/send-email", "<html>Bad gateway</html>")
+	},
+	"queue": func() PoodleError {
+		return NewQueueError("Queue unavailable", "queue_unavailable", true, 5)
+	},
+	"duplicate_email": func() PoodleError {
+		return NewDuplicateEmailError("v1:abc123")
+	},
+	"deadline_exceeded": func() PoodleError {
+		return NewDeadlineExceededError(30*time.Second, 3, NewRateLimitError("Rate limit exceeded", 60, 100, 0, 60))
+	},
+	"security_policy": func() PoodleError {
+		return NewSecurityPolicyError(SecurityPolicyCleartextURL, "the base URL must use https")
+	},
+	"dns_lookup_warning": func() PoodleError {
+		return NewDNSLookupWarning("example.com", errors.New("i/o timeout"))
+	},
+	"no_route": func() PoodleError {
+		return NewNoRouteError("example.org")
+	},
+	"response_parse": func() PoodleError {
+		return NewResponseParseError(202, "https://api.usepoodle.com/v1/send-email", []byte(`{"success": tr`), errors.New("unexpected EOF"))
+	},
+	"dead_letter": func() PoodleError {
+		return NewDeadLetterError(2, nil, NewNetworkError("connection reset", "https://api.usepoodle.com/v1/send-email"))
+	},
+	"unresolved_assets": func() PoodleError {
+		return NewUnresolvedAssetsError([]string{"images/hero.png: file does not exist"})
+	},
+}
+
+func TestErrorFormatGolden(t *testing.T) {
+	for name, newErr := range formattedErrors {
+		t.Run(name, func(t *testing.T) {
+			err := newErr()
+			got := fmt.Sprintf("%v\n%s\n%+v\n", err, err, err)
+
+			golden := filepath.Join("testdata", "errors", "format", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, readErr := os.ReadFile(golden)
+			if readErr != nil {
+				t.Fatalf("Failed to read %s, run go test -run ErrorFormat -update: %v", golden, readErr)
+			}
+			if got != string(want) {
+				t.Errorf("Formatting differs from %s\nwant: %s\ngot:  %s", golden, want, got)
+			}
+		})
+	}
+}
+
+func TestErrorFormatKeepsErrorsAs(t *testing.T) {
+	err := fmt.Errorf("send welcome email: %w", NewDeadlineExceededError(30*time.Second, 3, NewRateLimitError("Rate limit exceeded", 60, 100, 0, 60)))
+
+	if want := "send welcome email: poodle: deadline_exceeded: Send did not complete within 30s after 3 attempts: Rate limit exceeded (408, attempts=3)"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != 60 {
+		t.Errorf("Expected errors.As to find the RateLimitError, got %v", rateLimitErr)
+	}
+	var networkErr *NetworkError
+	if !errors.As(fmt.Errorf("%w", NewTimeoutError(TimeoutPhaseDial, TimeoutPhaseDial, time.Second, "", nil)), &networkErr) {
+		t.Error("Expected errors.As to find a TimeoutError as a NetworkError")
+	}
+	if got := NewRateLimitError("Rate limit exceeded", 60, 100, 0, 60).Error(); got != "Rate limit exceeded" {
+		t.Errorf("Expected Error() to be unchanged, got %q", got)
+	}
+}
@@ -1,8 +1,12 @@
 package poodle
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // PoodleError is the base interface for all Poodle SDK errors
@@ -10,6 +14,8 @@ type PoodleError interface {
 	error
 	StatusCode() int
 	Context() map[string]interface{}
+	// Hint returns what to do about the error (see Remediation)
+	Hint() string
 }
 
 // BaseError provides common functionality for all error types
@@ -27,11 +33,15 @@ func (e *BaseError) StatusCode() int {
 	return e.Code
 }
 
+// Context returns a copy of the error's context with the SDK version
+// added as sdk_version
 func (e *BaseError) Context() map[string]interface{} {
-	if e.ContextMap == nil {
-		return make(map[string]interface{})
+	context := make(map[string]interface{}, len(e.ContextMap)+1)
+	for key, value := range e.ContextMap {
+		context[key] = value
 	}
-	return e.ContextMap
+	context["sdk_version"] = SDKVersion
+	return context
 }
 
 // ValidationError represents validation errors (400 Bad Request)
@@ -61,49 +71,140 @@ func (e *ValidationError) Error() string {
 	return "Validation failed"
 }
 
+// AuthReason is the cause of an AuthenticationError
+type AuthReason string
+
+// Authentication failure reasons
+const (
+	AuthReasonMissingKey AuthReason = "missing_key"
+	AuthReasonInvalidKey AuthReason = "invalid_key"
+	AuthReasonExpiredKey AuthReason = "expired_key"
+	AuthReasonUnknown    AuthReason = "unknown"
+)
+
+// Suggestion returns how to resolve an authentication failure with this
+// reason
+func (r AuthReason) Suggestion() string {
+	switch r {
+	case AuthReasonMissingKey:
+		return "set Config.APIKey or the POODLE_API_KEY environment variable"
+	case AuthReasonInvalidKey:
+		return "check that the API key was copied completely from the dashboard"
+	case AuthReasonExpiredKey:
+		return "the API key was revoked or has expired; create a new key in the dashboard"
+	default:
+		return "check that the API key is correct and active"
+	}
+}
+
 // AuthenticationError represents authentication errors (401 Unauthorized)
 type AuthenticationError struct {
 	BaseError
+	Reason AuthReason
 }
 
 func NewAuthenticationError(message string) *AuthenticationError {
+	return NewAuthenticationErrorWithReason(message, AuthReasonUnknown)
+}
+
+// NewAuthenticationErrorWithReason creates an AuthenticationError for the
+// given reason
+func NewAuthenticationErrorWithReason(message string, reason AuthReason) *AuthenticationError {
 	if message == "" {
 		message = "Invalid or missing API key"
 	}
+	if reason == "" {
+		reason = AuthReasonUnknown
+	}
 	return &AuthenticationError{
 		BaseError: BaseError{
 			Message: message,
 			Code:    http.StatusUnauthorized,
 			ContextMap: map[string]interface{}{
 				"error_type": "authentication_error",
+				"reason":     string(reason),
+				"suggestion": reason.Suggestion(),
 			},
 		},
+		Reason: reason,
 	}
 }
 
+func (e *AuthenticationError) Error() string {
+	return fmt.Sprintf("%s (%s)", e.Message, e.Reason.Suggestion())
+}
+
+// SuspensionReason is the documented cause of an account suspension
+type SuspensionReason string
+
+// Account suspension reasons
+const (
+	SuspensionReasonAbuseReport    SuspensionReason = "abuse_report"
+	SuspensionReasonPaymentFailure SuspensionReason = "payment_failure"
+	SuspensionReasonManualReview   SuspensionReason = "manual_review"
+	SuspensionReasonUnknown        SuspensionReason = "unknown"
+)
+
+// ParseSuspensionReason maps the reason code of a 403 response to a
+// SuspensionReason. Codes it does not recognize, including codes added to
+// the API later, yield SuspensionReasonUnknown.
+func ParseSuspensionReason(code string) SuspensionReason {
+	switch strings.ToLower(strings.TrimSpace(code)) {
+	case "abuse_report", "abuse", "spam_complaints":
+		return SuspensionReasonAbuseReport
+	case "payment_failure", "payment_failed", "billing":
+		return SuspensionReasonPaymentFailure
+	case "manual_review", "review", "under_review":
+		return SuspensionReasonManualReview
+	default:
+		return SuspensionReasonUnknown
+	}
+}
+
+// IsPermanent reports whether a suspension for this reason is not expected
+// to be lifted, so that emails should be dropped rather than kept for
+// later. Only abuse reports are permanent; a failed payment or a review
+// can be resolved, and unknown reasons are assumed to be resolvable.
+func (r SuspensionReason) IsPermanent() bool {
+	return r == SuspensionReasonAbuseReport
+}
+
 // AccountSuspendedError represents account suspension errors (403 Forbidden)
 type AccountSuspendedError struct {
 	BaseError
+	// Reason is the reason code as returned by the API
 	Reason string
+	// Kind is the reason mapped to a SuspensionReason
+	Kind SuspensionReason
 }
 
 func NewAccountSuspendedError(message, reason string) *AccountSuspendedError {
 	if message == "" {
 		message = "Account suspended"
 	}
+	kind := ParseSuspensionReason(reason)
 	return &AccountSuspendedError{
 		BaseError: BaseError{
 			Message: message,
 			Code:    http.StatusForbidden,
 			ContextMap: map[string]interface{}{
-				"error_type": "account_suspended",
-				"reason":     reason,
+				"error_type":        "account_suspended",
+				"reason":            reason,
+				"suspension_reason": string(kind),
+				"permanent":         kind.IsPermanent(),
 			},
 		},
 		Reason: reason,
+		Kind:   kind,
 	}
 }
 
+// IsPermanent reports whether the suspension is not expected to be lifted
+// (see SuspensionReason.IsPermanent)
+func (e *AccountSuspendedError) IsPermanent() bool {
+	return e.Kind.IsPermanent()
+}
+
 // SubscriptionError represents subscription-related errors (402 Payment Required)
 type SubscriptionError struct {
 	BaseError
@@ -130,10 +231,23 @@ func NewSubscriptionError(message, errorType string) *SubscriptionError {
 // RateLimitError represents rate limiting errors (429 Too Many Requests)
 type RateLimitError struct {
 	BaseError
+	// RetryAfter is the Retry-After delay in seconds, corrected for clock
+	// skew if the header is a date and capped at Config.MaxRateLimitWait
 	RetryAfter int
 	Limit      int
 	Remaining  int
-	Reset      int64
+	// Reset is the ratelimit-reset header as sent: seconds until the
+	// window resets, or a Unix timestamp on the server's clock
+	Reset int64
+	// ResetIn is the time until the window resets, corrected for clock
+	// skew and capped at Config.MaxRateLimitWait, or zero if unknown
+	ResetIn time.Duration
+	// Skew is how far the server's clock, going by the Date header of the
+	// response, is ahead of the local clock. It is negative if the server
+	// is behind, and zero if the difference is under a second.
+	Skew time.Duration
+
+	clock Clock // waited on by Wait; nil uses the time package
 }
 
 func NewRateLimitError(message string, retryAfter, limit, remaining int, reset int64) *RateLimitError {
@@ -159,10 +273,86 @@ func NewRateLimitError(message string, retryAfter, limit, remaining int, reset i
 	}
 }
 
+// PayloadTooLargeError represents a request the API rejected as too large
+// (413 Payload Too Large). The API's limit on the request body may differ
+// from MaxContentSize. It is never retried.
+type PayloadTooLargeError struct {
+	BaseError
+	// PayloadSize is the size in bytes of the request body that was sent
+	PayloadSize int
+	// Limit is the largest request body the API accepts in bytes, or zero
+	// if the response does not say
+	Limit int
+}
+
+func NewPayloadTooLargeError(message string, payloadSize, limit int) *PayloadTooLargeError {
+	if message == "" {
+		message = fmt.Sprintf("Request of %d bytes is too large for the API", payloadSize)
+		if limit > 0 {
+			message += fmt.Sprintf(" (limit %d bytes)", limit)
+		}
+	}
+	message += "; Email.SizeReport() shows which parts to shrink"
+	return &PayloadTooLargeError{
+		BaseError: BaseError{
+			Message: message,
+			Code:    http.StatusRequestEntityTooLarge,
+			ContextMap: map[string]interface{}{
+				"error_type":   "payload_too_large",
+				"payload_size": payloadSize,
+				"limit":        limit,
+			},
+		},
+		PayloadSize: payloadSize,
+		Limit:       limit,
+	}
+}
+
+// NetworkErrorKind says why a request could not reach the API
+type NetworkErrorKind string
+
+// Network error kinds. Errors that fit none of them have the empty kind.
+const (
+	// NetworkErrorKindDNS is a failed lookup of the API host, including a
+	// lookup that timed out
+	NetworkErrorKindDNS NetworkErrorKind = "dns"
+	// NetworkErrorKindUnreachable is a missing route to the network or
+	// host, as for IPv6 addresses on a network without IPv6
+	NetworkErrorKindUnreachable NetworkErrorKind = "unreachable"
+	// NetworkErrorKindRefused is a connection refused by the host
+	NetworkErrorKindRefused NetworkErrorKind = "refused"
+	// NetworkErrorKindTimeout is a TimeoutError
+	NetworkErrorKindTimeout NetworkErrorKind = "timeout"
+	// NetworkErrorKindTLS is a rejected server certificate or a failed
+	// TLS handshake
+	NetworkErrorKindTLS NetworkErrorKind = "tls"
+)
+
 // NetworkError represents network connectivity errors
 type NetworkError struct {
 	BaseError
-	URL string
+	URL  string
+	Kind NetworkErrorKind
+	// RequestSent is set when the connection failed after the request was
+	// written to it, so the API may have processed the request. Such errors
+	// are not retried, as repeating a send could deliver the email twice.
+	RequestSent bool
+}
+
+// markRequestSent sets RequestSent and records it in the context
+func (e *NetworkError) markRequestSent() {
+	e.RequestSent = true
+	e.ContextMap["request_sent"] = true
+}
+
+// newNetworkErrorKind creates a NetworkError of the given kind
+func newNetworkErrorKind(kind NetworkErrorKind, message, url string) *NetworkError {
+	err := NewNetworkError(message, url)
+	err.Kind = kind
+	if kind != "" {
+		err.ContextMap["kind"] = string(kind)
+	}
+	return err
 }
 
 func NewNetworkError(message, url string) *NetworkError {
@@ -182,6 +372,8 @@ func NewNetworkError(message, url string) *NetworkError {
 	}
 }
 
+// NewConnectionTimeoutError creates a NetworkError for a connection that
+// could not be opened in time. Requests report timeouts as TimeoutError.
 func NewConnectionTimeoutError(timeout int, url string) *NetworkError {
 	message := fmt.Sprintf("Connection timeout after %d seconds", timeout)
 	return &NetworkError{
@@ -198,6 +390,100 @@ func NewConnectionTimeoutError(timeout int, url string) *NetworkError {
 	}
 }
 
+// TimeoutPhase is the part of a request that took too long
+type TimeoutPhase string
+
+// Timeout phases
+const (
+	// TimeoutPhaseDial is resolving the host and opening the connection
+	TimeoutPhaseDial TimeoutPhase = "dial"
+	// TimeoutPhaseTLS is the TLS handshake
+	TimeoutPhaseTLS TimeoutPhase = "tls"
+	// TimeoutPhaseResponseHeader is waiting for the response after the
+	// connection was established
+	TimeoutPhaseResponseHeader TimeoutPhase = "response_header"
+	// TimeoutPhaseBody is reading the response body
+	TimeoutPhaseBody TimeoutPhase = "body"
+	// TimeoutPhaseTotal is the request deadline of Config.Timeout or the
+	// context expiring
+	TimeoutPhaseTotal TimeoutPhase = "total"
+)
+
+// TimeoutError is returned when a request times out. Phase tells whether
+// the connection could not be opened (TimeoutPhaseDial), the handshake or
+// the server stalled, or the request deadline expired (TimeoutPhaseTotal),
+// in which case During is the phase the request was in and the error
+// unwraps to context.DeadlineExceeded. errors.As finds a TimeoutError as
+// a *NetworkError too.
+type TimeoutError struct {
+	NetworkError
+	Phase   TimeoutPhase
+	During  TimeoutPhase
+	Timeout time.Duration
+	Err     error
+}
+
+// NewTimeoutError creates a TimeoutError. during is the phase the request
+// was in; it differs from phase only for TimeoutPhaseTotal.
+func NewTimeoutError(phase, during TimeoutPhase, timeout time.Duration, url string, err error) *TimeoutError {
+	var message string
+	errorType := "timeout"
+	switch phase {
+	case TimeoutPhaseDial:
+		message = fmt.Sprintf("Connection timeout after %s", timeout)
+		errorType = "connection_timeout"
+	case TimeoutPhaseTLS:
+		message = fmt.Sprintf("TLS handshake timeout after %s", timeout)
+	case TimeoutPhaseResponseHeader:
+		message = "Timed out waiting for the response"
+	case TimeoutPhaseBody:
+		message = "Timed out reading the response body"
+	default:
+		message = fmt.Sprintf("Request did not complete within %s", timeout)
+		if during != "" {
+			message += fmt.Sprintf(" (timed out in phase %s)", during)
+		}
+	}
+
+	return &TimeoutError{
+		NetworkError: NetworkError{
+			BaseError: BaseError{
+				Message: message,
+				Code:    http.StatusRequestTimeout,
+				ContextMap: map[string]interface{}{
+					"error_type": errorType,
+					"phase":      string(phase),
+					"during":     string(during),
+					"timeout":    timeout.String(),
+					"url":        url,
+					"kind":       string(NetworkErrorKindTimeout),
+				},
+			},
+			URL:  url,
+			Kind: NetworkErrorKindTimeout,
+		},
+		Phase:   phase,
+		During:  during,
+		Timeout: timeout,
+		Err:     err,
+	}
+}
+
+// Unwrap returns the underlying error
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// As lets errors.As find the error as a *NetworkError, as timeouts were
+// reported before TimeoutError existed
+func (e *TimeoutError) As(target interface{}) bool {
+	if networkErr, ok := target.(**NetworkError); ok {
+		*networkErr = &e.NetworkError
+		return true
+	}
+	return false
+}
+
 // HTTPError represents generic HTTP errors
 type HTTPError struct {
 	BaseError
@@ -223,3 +509,273 @@ func NewHTTPError(statusCode int, message, url, responseBody string) *HTTPError
 		ResponseBody: responseBody,
 	}
 }
+
+// QueueError represents a 422 Unprocessable Entity response without field
+// errors: the API accepted the request but could not queue the email.
+// Retryable is set when the failure is transient, such as an unavailable
+// queue, as the API indicates with a retryable flag or its message.
+type QueueError struct {
+	BaseError
+	// Reason is the reason code returned by the API, if any
+	Reason    string
+	Retryable bool
+	// RetryAfter is the Retry-After header in seconds, or -1 if absent
+	RetryAfter int
+}
+
+func NewQueueError(message, reason string, retryable bool, retryAfter int) *QueueError {
+	if message == "" {
+		message = "Email could not be queued"
+	}
+	return &QueueError{
+		BaseError: BaseError{
+			Message: message,
+			Code:    http.StatusUnprocessableEntity,
+			ContextMap: map[string]interface{}{
+				"error_type":  "queue_error",
+				"reason":      reason,
+				"retryable":   retryable,
+				"retry_after": retryAfter,
+			},
+		},
+		Reason:     reason,
+		Retryable:  retryable,
+		RetryAfter: retryAfter,
+	}
+}
+
+// ResponseReadError is returned when the body of an API response could
+// not be read after the API answered with a status. For a send, the status
+// tells whether the API accepted the email, so a ResponseReadError with
+// status 202 means the email was queued. It is never retried.
+type ResponseReadError struct {
+	BaseError
+	URL string
+	Err error
+}
+
+func NewResponseReadError(statusCode int, url string, cause error) *ResponseReadError {
+	return &ResponseReadError{
+		BaseError: BaseError{
+			Message: fmt.Sprintf("Failed to read response body: %s", cause),
+			Code:    statusCode,
+			ContextMap: map[string]interface{}{
+				"error_type": "response_read_error",
+				"url":        url,
+			},
+		},
+		URL: url,
+		Err: cause,
+	}
+}
+
+// Unwrap returns the error that stopped reading the body
+func (e *ResponseReadError) Unwrap() error {
+	return e.Err
+}
+
+// EncodeError is returned when the request body could not be encoded as
+// JSON, as for values encoding/json does not support. No request was
+// made, and it is never retried, since encoding the same values again fails
+// the same way.
+type EncodeError struct {
+	BaseError
+	Err error
+}
+
+func NewEncodeError(cause error) *EncodeError {
+	return &EncodeError{
+		BaseError: BaseError{
+			Message: fmt.Sprintf("Failed to encode request body: %s", cause),
+			Code:    0, // The request was not made
+			ContextMap: map[string]interface{}{
+				"error_type": "encode_error",
+			},
+		},
+		Err: cause,
+	}
+}
+
+// Unwrap returns the encoding error
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateEmailError is returned when an identical email was already sent
+// within the configured dedupe window
+type DuplicateEmailError struct {
+	BaseError
+	Fingerprint string
+}
+
+func NewDuplicateEmailError(fingerprint string) *DuplicateEmailError {
+	return &DuplicateEmailError{
+		BaseError: BaseError{
+			Message: "Duplicate email suppressed: an identical email was sent recently",
+			Code:    http.StatusConflict,
+			ContextMap: map[string]interface{}{
+				"error_type":  "duplicate_email",
+				"fingerprint": fingerprint,
+			},
+		},
+		Fingerprint: fingerprint,
+	}
+}
+
+// DeadlineExceededError is returned when a send did not complete within
+// Config.OverallDeadline. It wraps the error of the last attempt, or the
+// error that stopped the send before an attempt was made.
+type DeadlineExceededError struct {
+	BaseError
+	Deadline  time.Duration
+	Attempts  int
+	LastError error
+}
+
+func NewDeadlineExceededError(deadline time.Duration, attempts int, lastErr error) *DeadlineExceededError {
+	message := fmt.Sprintf("Send did not complete within %s after %d attempts", deadline, attempts)
+	if lastErr != nil {
+		message += ": " + lastErr.Error()
+	}
+	return &DeadlineExceededError{
+		BaseError: BaseError{
+			Message: message,
+			Code:    http.StatusRequestTimeout,
+			ContextMap: map[string]interface{}{
+				"error_type": "deadline_exceeded",
+				"deadline":   deadline.String(),
+				"attempts":   attempts,
+			},
+		},
+		Deadline:  deadline,
+		Attempts:  attempts,
+		LastError: lastErr,
+	}
+}
+
+// Unwrap returns the error of the last attempt
+func (e *DeadlineExceededError) Unwrap() error {
+	return e.LastError
+}
+
+// Is reports whether target is context.DeadlineExceeded, so that the error
+// can be handled like an expired context
+func (e *DeadlineExceededError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// SecurityPolicy names a rule enforced by Config.HardenedTransport
+type SecurityPolicy string
+
+// Rules enforced by Config.HardenedTransport
+const (
+	// SecurityPolicyCleartextURL requires https request URLs
+	SecurityPolicyCleartextURL SecurityPolicy = "cleartext_url"
+	// SecurityPolicyTLSVersion requires TLS 1.2 or later
+	SecurityPolicyTLSVersion SecurityPolicy = "tls_version"
+	// SecurityPolicyCertificateVerification requires server certificates
+	// to be verified
+	SecurityPolicyCertificateVerification SecurityPolicy = "certificate_verification"
+	// SecurityPolicyKeyLog forbids logging TLS session keys, e.g. to the
+	// file named by SSLKEYLOGFILE
+	SecurityPolicyKeyLog SecurityPolicy = "key_log"
+)
+
+// SecurityPolicyError is returned when a configuration or request violates
+// a rule enforced by Config.HardenedTransport. It is never retried.
+type SecurityPolicyError struct {
+	BaseError
+	Policy SecurityPolicy
+}
+
+func NewSecurityPolicyError(policy SecurityPolicy, message string) *SecurityPolicyError {
+	return &SecurityPolicyError{
+		BaseError: BaseError{
+			Message: "Security policy violated: " + message,
+			ContextMap: map[string]interface{}{
+				"error_type": "security_policy",
+				"policy":     string(policy),
+			},
+		},
+		Policy: policy,
+	}
+}
+
+// DNSLookupWarning is returned when a recipient domain could not be checked
+// because the DNS lookup timed out or failed temporarily. Unlike a
+// ValidationError it does not mean the address is undeliverable.
+type DNSLookupWarning struct {
+	BaseError
+	Domain string
+}
+
+func NewDNSLookupWarning(domain string, cause error) *DNSLookupWarning {
+	return &DNSLookupWarning{
+		BaseError: BaseError{
+			Message: fmt.Sprintf("Could not verify recipient domain %s: %v", domain, cause),
+			ContextMap: map[string]interface{}{
+				"error_type": "dns_lookup_warning",
+				"domain":     domain,
+			},
+		},
+		Domain: domain,
+	}
+}
+
+// errorTypeOf returns the error_type recorded in a Poodle error's context,
+// or "unknown" for other errors
+func errorTypeOf(err error) string {
+	var poodleErr PoodleError
+	if errors.As(err, &poodleErr) {
+		if errorType, ok := poodleErr.Context()["error_type"].(string); ok {
+			return errorType
+		}
+	}
+	return "unknown"
+}
+
+// NoRouteError is returned by Router when no route matches the domain of
+// the email's From address and there is no default route
+type NoRouteError struct {
+	BaseError
+	Domain string
+}
+
+func NewNoRouteError(domain string) *NoRouteError {
+	return &NoRouteError{
+		BaseError: BaseError{
+			Message: fmt.Sprintf("No route for From domain %q", domain),
+			Code:    0, // The email was not sent
+			ContextMap: map[string]interface{}{
+				"error_type": "no_route_error",
+				"domain":     domain,
+			},
+		},
+		Domain: domain,
+	}
+}
+
+// ResponseParseError is returned when the body of an API response is
+// malformed JSON. For a send, the status code tells whether the API
+// accepted the email, so a ResponseParseError with status 202 means the
+// email was queued and should not be sent again.
+type ResponseParseError struct {
+	BaseError
+	// Body is the raw response body
+	Body []byte
+}
+
+func NewResponseParseError(statusCode int, url string, body []byte, cause error) *ResponseParseError {
+	return &ResponseParseError{
+		BaseError: BaseError{
+			Message: fmt.Sprintf("Failed to parse response: %s", cause),
+			Code:    statusCode,
+			ContextMap: map[string]interface{}{
+				"error_type":    "response_parse_error",
+				"url":           url,
+				"response_body": truncate(string(body), errorBodyLimit),
+			},
+		},
+		Body: body,
+	}
+}
@@ -0,0 +1,19 @@
+package poodle
+
+import (
+	"context"
+	"time"
+)
+
+// eventSink receives structured diagnostic events from the HTTP layer.
+// Arguments are concrete types so that calls do not allocate when the sink
+// discards the event.
+type eventSink interface {
+	// request is emitted before a request is sent
+	request(ctx context.Context, method, url string, bodyBytes int)
+	// response is emitted when response headers are received.
+	// rateLimitRemaining is -1 when the API did not report it.
+	response(ctx context.Context, status int, duration time.Duration, requestID string, rateLimitRemaining int)
+	// error is emitted when an attempt fails
+	error(ctx context.Context, err error, attempt int)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+func main() {
+	// Get API key from environment variable
+	apiKey := os.Getenv("POODLE_API_KEY")
+	if apiKey == "" {
+		log.Fatal("POODLE_API_KEY environment variable is required")
+	}
+
+	// Initialize the Poodle client
+	client := poodle.NewClient(apiKey)
+	defer client.Close()
+
+	recipients := []string{"alice@example.com", "bob@example.com"}
+
+	for _, recipient := range recipients {
+		email := poodle.NewEmailWithBoth(
+			"news@yourdomain.com",
+			recipient,
+			"Our spring collection is here",
+			"<html><body><h1>Spring collection</h1><p>Fresh styles for the new season.</p></body></html>",
+			"Spring collection\n\nFresh styles for the new season.",
+		)
+
+		// Bulk senders must offer one-click unsubscribe: an https URL in
+		// List-Unsubscribe plus List-Unsubscribe-Post. The footer adds a
+		// visible link to both bodies.
+		unsubscribeURL := "https://yourdomain.com/unsubscribe?email=" + url.QueryEscape(recipient)
+		if err := email.SetUnsubscribe(unsubscribeURL, "unsubscribe@yourdomain.com", poodle.DefaultUnsubscribeFooter); err != nil {
+			log.Fatalf("Invalid unsubscribe options: %v", err)
+		}
+
+		response, err := client.Send(email)
+		if err != nil {
+			log.Printf("Failed to send to %s: %v", recipient, err)
+			continue
+		}
+		fmt.Printf("Sent to %s: %s\n", recipient, response.Message)
+	}
+}
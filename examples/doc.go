@@ -4,4 +4,9 @@
 // Available examples:
 //   - basic_usage: Shows basic email sending functionality
 //   - error_handling: Demonstrates comprehensive error handling
+//   - campaign: Sends a campaign email with one-click unsubscribe
+//   - rate_limit_wait: Waits out rate limits with SendWithWait
+//   - stream_csv: Streams recipients from a CSV file through SendStream
+//   - send_individually: Sends each recipient of a list their own copy of an email
+//   - prometheus_metrics: Exposes client metrics to Prometheus
 package examples
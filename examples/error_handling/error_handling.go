@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -66,6 +68,21 @@ func main() {
 		fmt.Printf("Email sent successfully: %s\n", response.Message)
 	}
 
+	// Example 4: Handle partial failures of a batch
+	fmt.Println("\nExample 4: Batch Errors")
+	emails := []*poodle.Email{
+		poodle.NewTextEmail("sender@yourdomain.com", "first@example.com", "Batch Email", "Hello!"),
+		poodle.NewTextEmail("sender@yourdomain.com", "invalid-email", "Batch Email", "Hello!"),
+		poodle.NewTextEmail("sender@yourdomain.com", "second@example.com", "Batch Email", "Hello!"),
+	}
+
+	_, err = client.SendAll(context.Background(), emails)
+	if err != nil {
+		handleBatchError(err)
+	} else {
+		fmt.Println("All emails sent successfully")
+	}
+
 	fmt.Println("\nError handling examples completed!")
 }
 
@@ -87,7 +104,19 @@ func handleError(err error) {
 	case *poodle.AuthenticationError:
 		fmt.Println("  Type: Authentication Error")
 		fmt.Printf("  Status Code: %d\n", e.StatusCode())
-		fmt.Println("  Suggestion: Check your API key and ensure it's valid")
+		fmt.Println("  Reason:", e.Reason)
+		fmt.Println("  Suggestion:", e.Reason.Suggestion())
+		fmt.Println("  Context:", e.Context())
+
+	case *poodle.QueueError:
+		fmt.Println("  Type: Queue Error")
+		fmt.Printf("  Status Code: %d\n", e.StatusCode())
+		fmt.Printf("  Reason: %s\n", e.Reason)
+		if e.Retryable {
+			fmt.Println("  Suggestion: The mail queue is temporarily unavailable; retry later")
+		} else {
+			fmt.Println("  Suggestion: The email cannot be queued; check the reason before sending again")
+		}
 		fmt.Println("  Context:", e.Context())
 
 	case *poodle.RateLimitError:
@@ -110,8 +139,22 @@ func handleError(err error) {
 	case *poodle.AccountSuspendedError:
 		fmt.Println("  Type: Account Suspended Error")
 		fmt.Printf("  Status Code: %d\n", e.StatusCode())
-		fmt.Printf("  Reason: %s\n", e.Reason)
-		fmt.Println("  Suggestion: Contact support")
+		fmt.Printf("  Reason: %s (%s)\n", e.Kind, e.Reason)
+		if e.IsPermanent() {
+			fmt.Println("  Suggestion: Contact support; queued emails should be dropped")
+		} else {
+			fmt.Println("  Suggestion: Resolve the suspension; queued emails can be sent afterwards")
+		}
+		fmt.Println("  Context:", e.Context())
+
+	case *poodle.TimeoutError:
+		fmt.Println("  Type: Timeout Error")
+		fmt.Printf("  Phase: %s\n", e.Phase)
+		if e.Phase == poodle.TimeoutPhaseTotal {
+			fmt.Printf("  During: %s\n", e.During)
+		}
+		fmt.Printf("  URL: %s\n", e.URL)
+		fmt.Println("  Suggestion: Check your connection, or raise Timeout if the API is slow to respond")
 		fmt.Println("  Context:", e.Context())
 
 	case *poodle.NetworkError:
@@ -135,3 +178,31 @@ func handleError(err error) {
 
 	fmt.Println()
 }
+
+// handleBatchError demonstrates how to inspect the failures of a batch
+func handleBatchError(err error) {
+	fmt.Printf("Error occurred: %s\n", err.Error())
+
+	var multiErr *poodle.MultiError
+	if !errors.As(err, &multiErr) {
+		handleError(err)
+		return
+	}
+
+	// errors.As searches every failure, so a rate limit anywhere in the
+	// batch can be detected without iterating
+	var rateLimitErr *poodle.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		fmt.Printf("  At least one email was rate limited, retry after %d seconds\n", rateLimitErr.RetryAfter)
+	}
+
+	fmt.Printf("  Failed indices: %v\n", multiErr.Failed())
+	for errorType, failures := range multiErr.ByType() {
+		fmt.Printf("  %s: %d email(s)\n", errorType, len(failures))
+	}
+
+	for _, failure := range multiErr.Failures {
+		fmt.Printf("  Email %d to %s:\n", failure.Index, failure.Email.To)
+		handleError(failure.Err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/usepoodle/poodle-go"
+	"github.com/usepoodle/poodle-go/poodleprom"
+)
+
+func main() {
+	// Get API key from environment variable
+	if os.Getenv("POODLE_API_KEY") == "" {
+		log.Fatal("POODLE_API_KEY environment variable is required")
+	}
+
+	// Register the Poodle collectors on a dedicated registry
+	registry := prometheus.NewRegistry()
+	metrics, err := poodleprom.New(registry)
+	if err != nil {
+		log.Fatalf("Failed to register metrics: %v", err)
+	}
+
+	// Attach the metrics to the client
+	config := poodle.NewConfigFromEnv()
+	config.Metrics = metrics
+	config.MaxRetries = 2
+	client := poodle.NewClientWithConfig(config)
+
+	// Expose /metrics
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	go func() {
+		log.Println("Serving metrics on http://localhost:2112/metrics")
+		log.Fatal(http.ListenAndServe(":2112", nil))
+	}()
+
+	// Send an email every ten seconds
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for i := 1; ; i++ {
+		response, err := client.SendText(
+			"sender@yourdomain.com",
+			"recipient@example.com",
+			fmt.Sprintf("Heartbeat #%d", i),
+			"This email was sent by the Prometheus metrics example.",
+		)
+		if err != nil {
+			log.Printf("Send failed: %v", err)
+		} else {
+			log.Printf("Send succeeded: %s", response.Message)
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,7 @@
+module rate_limit_wait
+
+go 1.20
+
+require github.com/usepoodle/poodle-go v0.0.0
+
+replace github.com/usepoodle/poodle-go => ../..
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+func main() {
+	// Get API key from environment variable
+	apiKey := os.Getenv("POODLE_API_KEY")
+	if apiKey == "" {
+		log.Fatal("POODLE_API_KEY environment variable is required")
+	}
+
+	// Initialize the Poodle client
+	client := poodle.NewClient(apiKey)
+	defer client.Close()
+
+	// Give every email up to two minutes, including time spent waiting for
+	// the rate limit to reset
+	recipients := []string{"alice@example.com", "bob@example.com", "carol@example.com"}
+	for _, recipient := range recipients {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		response, err := client.SendWithWait(ctx, poodle.NewTextEmail(
+			"notifications@yourdomain.com",
+			recipient,
+			"Your weekly summary",
+			"Here is what happened this week.",
+		))
+		cancel()
+
+		var rateLimitErr *poodle.RateLimitError
+		switch {
+		case errors.As(err, &rateLimitErr):
+			// Still rate limited when the deadline came; try again later
+			fmt.Printf("%s: still rate limited after waiting %v\n", recipient, rateLimitErr.Context()["waited"])
+		case err != nil:
+			// Validation, authentication and other errors are not retried
+			fmt.Printf("%s: %v\n", recipient, err)
+		default:
+			fmt.Printf("%s: %s\n", recipient, response.Message)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+func main() {
+	// Get API key from environment variable
+	apiKey := os.Getenv("POODLE_API_KEY")
+	if apiKey == "" {
+		log.Fatal("POODLE_API_KEY environment variable is required")
+	}
+
+	// Initialize the Poodle client
+	client := poodle.NewClient(apiKey)
+	defer client.Close()
+
+	// One email, sent to each recipient separately so that nobody sees the
+	// other addresses. Nobody in the list gets it twice.
+	base := poodle.NewTextEmail(
+		"notifications@yourdomain.com",
+		"",
+		"Your invitation, {{first_name}}",
+		"This invitation was sent to {{email}}.",
+	)
+	base.Variables = map[string]string{"first_name": "there"}
+	recipients := []string{
+		"Alice <alice@example.com>",
+		"bob@example.com",
+		"carol@example.com",
+		"ALICE@example.com",
+	}
+
+	results, err := client.SendIndividually(context.Background(), base, recipients,
+		poodle.WithConcurrency(2),
+		poodle.WithRecipientVariables(map[string]map[string]string{
+			"alice@example.com": {"first_name": "Alice"},
+		}),
+	)
+	for _, result := range results {
+		if result.OK() {
+			fmt.Printf("%s: %s\n", result.Recipient, result.Response.Message)
+		} else {
+			fmt.Printf("%s: %v\n", result.Recipient, result.Err)
+		}
+	}
+
+	// A failed recipient does not stop the others
+	var multiErr *poodle.MultiError
+	if errors.As(err, &multiErr) {
+		fmt.Printf("%d of %d recipients failed\n", len(multiErr.Failures), multiErr.Total)
+	}
+}
@@ -0,0 +1,7 @@
+module stream_csv
+
+go 1.20
+
+require github.com/usepoodle/poodle-go v0.0.0
+
+replace github.com/usepoodle/poodle-go => ../..
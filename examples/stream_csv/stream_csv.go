@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+func main() {
+	// Get API key from environment variable
+	apiKey := os.Getenv("POODLE_API_KEY")
+	if apiKey == "" {
+		log.Fatal("POODLE_API_KEY environment variable is required")
+	}
+	if len(os.Args) != 2 {
+		log.Fatal("usage: stream_csv recipients.csv (columns: email,name,first_name,plan)")
+	}
+
+	file, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	// Initialize the Poodle client
+	client := poodle.NewClient(apiKey)
+	defer client.Close()
+
+	// Stop taking new rows on Ctrl-C; sends already in flight still report
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Read the file one row at a time, so memory use does not grow with
+	// the number of recipients. Extra columns fill {{placeholders}}.
+	base := poodle.NewTextEmail(
+		"newsletter@yourdomain.com",
+		"",
+		"Our monthly update",
+		"Hi {{first_name}},\n\nHere is what is new on your {{plan}} plan this month.",
+	)
+	emails := make(chan *poodle.Email)
+	go func() {
+		defer close(emails)
+		for row := range poodle.StreamRecipientsCSV(ctx, file, poodle.CSVOptions{}) {
+			if row.Err != nil {
+				// Bad rows are reported and skipped
+				log.Printf("skipping %v", row.Err)
+				continue
+			}
+			select {
+			case emails <- row.Recipient.Apply(base):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Handle each result as soon as its send completes
+	sent, failed := 0, 0
+	for result := range client.SendStream(ctx, emails, 8) {
+		if !result.OK() {
+			failed++
+			fmt.Printf("row %d (%s): %v\n", result.Index+1, result.Email.ToAddress.Email, result.Err)
+			continue
+		}
+		sent++
+	}
+	fmt.Printf("sent %d, failed %d\n", sent, failed)
+}
@@ -0,0 +1,214 @@
+package poodle
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exchange capture limits
+const (
+	// MaxExchangeBodySize is the number of bytes of a request or response
+	// body kept in a captured Exchange
+	MaxExchangeBodySize = 16 * 1024
+	// ExchangeBufferSize is the number of exchanges the client keeps
+	ExchangeBufferSize = 32
+)
+
+// redacted replaces the API key in captured exchanges
+const redacted = "[REDACTED]"
+
+// Exchange is a captured request to the API and its response, for
+// attaching to support requests. The API key is redacted, addresses are
+// handled according to Config.PIIMode and bodies longer than
+// MaxExchangeBodySize are truncated.
+type Exchange struct {
+	Method         string
+	URL            string
+	RequestHeader  http.Header
+	RequestBody    string
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   string
+	// Error is the transport error if no response was received
+	Error     string
+	StartedAt time.Time
+	EndedAt   time.Time
+	Duration  time.Duration
+	// ConnReused reports whether the request reused a connection
+	ConnReused bool
+}
+
+// WriteTo writes a readable dump of the exchange to w
+func (e *Exchange) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "--- %s %s at %s (%s)\n", e.Method, e.URL, e.StartedAt.UTC().Format(time.RFC3339Nano), e.Duration)
+	writeExchangeHeaders(&b, "> ", e.RequestHeader)
+	if e.RequestBody != "" {
+		fmt.Fprintf(&b, ">\n> %s\n", e.RequestBody)
+	}
+
+	if e.Error != "" {
+		fmt.Fprintf(&b, "! %s\n", e.Error)
+	} else {
+		fmt.Fprintf(&b, "< %d %s\n", e.StatusCode, http.StatusText(e.StatusCode))
+		writeExchangeHeaders(&b, "< ", e.ResponseHeader)
+		if e.ResponseBody != "" {
+			fmt.Fprintf(&b, "<\n< %s\n", e.ResponseBody)
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// writeExchangeHeaders writes the headers sorted by name, one per line
+func writeExchangeHeaders(b *strings.Builder, prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range header[key] {
+			fmt.Fprintf(b, "%s%s: %s\n", prefix, key, value)
+		}
+	}
+}
+
+// clone returns a copy of the exchange whose headers can be modified
+// independently
+func (e Exchange) clone() Exchange {
+	e.RequestHeader = e.RequestHeader.Clone()
+	e.ResponseHeader = e.ResponseHeader.Clone()
+	return e
+}
+
+// exchangeLog is a fixed-size ring of the most recent exchanges
+type exchangeLog struct {
+	mutex   sync.Mutex
+	entries []Exchange
+	next    int
+	count   int
+}
+
+// add stores the exchange, replacing the oldest one when the ring is full
+func (l *exchangeLog) add(exchange Exchange) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.entries == nil {
+		l.entries = make([]Exchange, ExchangeBufferSize)
+	}
+	l.entries[l.next] = exchange
+	l.next = (l.next + 1) % len(l.entries)
+	if l.count < len(l.entries) {
+		l.count++
+	}
+}
+
+// recent returns up to n of the most recent exchanges, oldest first
+func (l *exchangeLog) recent(n int) []Exchange {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if n > l.count {
+		n = l.count
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	exchanges := make([]Exchange, n)
+	for i := range exchanges {
+		index := (l.next - n + i + len(l.entries)) % len(l.entries)
+		exchanges[i] = l.entries[index].clone()
+	}
+	return exchanges
+}
+
+// captureExchange records a request and its response, or the transport
+// error, with the API key redacted. resp is nil if no response was
+// received.
+func (c *HTTPClient) captureExchange(config *Config, req *http.Request, requestBody []byte, resp *http.Response, responseBody []byte, err error, started time.Time, connReused bool) {
+	ended := c.clock.Now()
+	exchange := Exchange{
+		Method:        req.Method,
+		URL:           config.PIIMode.scrub(req.URL.String()),
+		RequestHeader: redactHeader(req.Header, config.APIKey),
+		RequestBody:   config.PIIMode.scrub(captureBody(requestBody, config.APIKey)),
+		StartedAt:     started,
+		EndedAt:       ended,
+		Duration:      ended.Sub(started),
+		ConnReused:    connReused,
+	}
+
+	if resp != nil {
+		exchange.StatusCode = resp.StatusCode
+		exchange.ResponseHeader = redactHeader(resp.Header, config.APIKey)
+		exchange.ResponseBody = config.PIIMode.scrub(captureBody(responseBody, config.APIKey))
+	}
+	if err != nil {
+		exchange.Error = config.PIIMode.scrub(redactKey(err.Error(), config.APIKey))
+	}
+
+	c.exchanges.add(exchange)
+}
+
+// redactHeader returns a copy of the header with the Authorization header
+// and any occurrence of the API key redacted
+func redactHeader(header http.Header, apiKey string) http.Header {
+	redactedHeader := make(http.Header, len(header))
+	for key, values := range header {
+		copied := make([]string, len(values))
+		for i, value := range values {
+			if strings.EqualFold(key, "Authorization") {
+				value = "Bearer " + redacted
+			}
+			copied[i] = redactKey(value, apiKey)
+		}
+		redactedHeader[key] = copied
+	}
+	return redactedHeader
+}
+
+// captureBody returns the body with the API key redacted, truncated to
+// MaxExchangeBodySize bytes
+func captureBody(body []byte, apiKey string) string {
+	s := string(body)
+	if len(s) > MaxExchangeBodySize {
+		cut := runeBoundary(s, MaxExchangeBodySize)
+		s = fmt.Sprintf("%s... [truncated %d bytes]", s[:cut], len(s)-cut)
+	}
+	return redactKey(s, apiKey)
+}
+
+// redactKey replaces every occurrence of the API key in s
+func redactKey(s, apiKey string) string {
+	if apiKey == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, apiKey, redacted)
+}
+
+// LastExchange returns the most recent captured exchange, or nil if none
+// was captured. Exchanges are only captured with Config.CaptureExchanges.
+func (c *Client) LastExchange() *Exchange {
+	exchanges := c.httpClient.exchanges.recent(1)
+	if len(exchanges) == 0 {
+		return nil
+	}
+	return &exchanges[0]
+}
+
+// RecentExchanges returns up to n of the most recent captured exchanges,
+// oldest first. At most ExchangeBufferSize exchanges are kept.
+func (c *Client) RecentExchanges(n int) []Exchange {
+	return c.httpClient.exchanges.recent(n)
+}
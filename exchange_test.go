@@ -0,0 +1,138 @@
+package poodle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const exchangeTestKey = "poodle_live_1234567890abcdef"
+
+func newExchangeTestClient(capture bool, doer mockDoerFunc) *Client {
+	config := NewConfig()
+	config.APIKey = exchangeTestKey
+	config.CaptureExchanges = capture
+
+	client := NewClientWithConfig(config, WithClock(newTestClock()))
+	client.httpClient.httpClient = doer
+	return client
+}
+
+func TestCaptureExchanges(t *testing.T) {
+	client := newExchangeTestClient(true, func(req *http.Request) (*http.Response, error) {
+		response := acceptedResponse()
+		response.Header = http.Header{"X-Request-Id": {"req_1"}, "X-Echo": {"key " + exchangeTestKey}}
+		return response, nil
+	})
+
+	if client.LastExchange() != nil {
+		t.Fatal("Expected no exchange before the first request")
+	}
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello "+exchangeTestKey); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exchange := client.LastExchange()
+	if exchange == nil {
+		t.Fatal("Expected the exchange to be captured")
+	}
+	if exchange.Method != http.MethodPost || !strings.HasSuffix(exchange.URL, "/v1/send-email") || exchange.StatusCode != http.StatusAccepted {
+		t.Errorf("Unexpected exchange %+v", exchange)
+	}
+	if exchange.RequestHeader.Get("Authorization") != "Bearer [REDACTED]" {
+		t.Errorf("Expected the Authorization header to be redacted, got %q", exchange.RequestHeader.Get("Authorization"))
+	}
+	if !strings.Contains(exchange.ResponseBody, "Email queued") || exchange.ResponseHeader.Get("X-Request-Id") != "req_1" {
+		t.Errorf("Expected the response to be captured, got %+v", exchange)
+	}
+
+	var dump strings.Builder
+	if _, err := exchange.WriteTo(&dump); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(dump.String(), exchangeTestKey) {
+		t.Errorf("Expected the API key to be redacted everywhere, got:\n%s", dump.String())
+	}
+	for _, want := range []string{"POST ", "> Authorization: Bearer [REDACTED]", "< 202 Accepted", "< X-Request-Id: req_1", `"subject":"Subject"`} {
+		if !strings.Contains(dump.String(), want) {
+			t.Errorf("Expected dump to contain %q, got:\n%s", want, dump.String())
+		}
+	}
+}
+
+func TestCaptureExchangesOffByDefault(t *testing.T) {
+	client := newExchangeTestClient(false, func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	if client.LastExchange() != nil || len(client.RecentExchanges(10)) != 0 {
+		t.Error("Expected no exchanges to be captured by default")
+	}
+}
+
+func TestCaptureExchangesTransportError(t *testing.T) {
+	client := newExchangeTestClient(true, func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("dial tcp: connection refused")
+	})
+
+	client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	exchange := client.LastExchange()
+	if exchange == nil || exchange.StatusCode != 0 || !strings.Contains(exchange.Error, "connection refused") {
+		t.Errorf("Expected the transport error to be captured, got %+v", exchange)
+	}
+}
+
+func TestCaptureExchangesTruncatesBodies(t *testing.T) {
+	large := strings.Repeat("x", MaxExchangeBodySize+100)
+	client := newExchangeTestClient(true, func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusAccepted,
+			Body:       io.NopCloser(strings.NewReader(large)),
+		}, nil
+	})
+
+	client.SendText("from@example.com", "to@example.com", "Subject", large)
+	exchange := client.LastExchange()
+	for _, body := range []string{exchange.RequestBody, exchange.ResponseBody} {
+		if len(body) > MaxExchangeBodySize+64 || !strings.Contains(body, "[truncated") {
+			t.Errorf("Expected body to be truncated, got %d bytes", len(body))
+		}
+	}
+}
+
+func TestRecentExchangesBounded(t *testing.T) {
+	client := newExchangeTestClient(true, func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < ExchangeBufferSize+10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client.SendText("from@example.com", "to@example.com", fmt.Sprintf("Subject %d", i), "Hello")
+		}(i)
+	}
+	wg.Wait()
+
+	if exchanges := client.RecentExchanges(1000); len(exchanges) != ExchangeBufferSize {
+		t.Errorf("Expected %d exchanges to be kept, got %d", ExchangeBufferSize, len(exchanges))
+	}
+
+	client.SendText("from@example.com", "to@example.com", "Last", "Hello")
+	exchanges := client.RecentExchanges(2)
+	if len(exchanges) != 2 || !strings.Contains(exchanges[1].RequestBody, `"subject":"Last"`) {
+		t.Errorf("Expected the latest exchange last, got %+v", exchanges)
+	}
+
+	exchanges[1].RequestHeader.Set("Authorization", "changed")
+	if client.LastExchange().RequestHeader.Get("Authorization") == "changed" {
+		t.Error("Expected returned exchanges to be copies")
+	}
+}
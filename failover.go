@@ -0,0 +1,109 @@
+package poodle
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultFailoverProbeInterval is how long the client stays on a fallback
+// base URL before trying the primary again
+const DefaultFailoverProbeInterval = 5 * time.Minute
+
+// failover tracks which of the configured base URLs is currently healthy.
+// The zero value uses the primary base URL.
+type failover struct {
+	mutex sync.Mutex
+	// current is the fallback base URL in use, or empty for the primary
+	current string
+	// since is when current was last selected or probed
+	since time.Time
+}
+
+// order returns the base URLs to try for a request, starting with the
+// active one, and whether the primary is being probed after a failover
+func (f *failover) order(config *Config, now time.Time) ([]string, bool) {
+	baseURLs := append([]string{config.BaseURL}, config.FallbackBaseURLs...)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.current == "" {
+		return baseURLs, false
+	}
+
+	interval := config.FailoverProbeInterval
+	if interval == 0 {
+		interval = DefaultFailoverProbeInterval
+	}
+	if now.Sub(f.since) >= interval {
+		return baseURLs, true
+	}
+
+	for i, baseURL := range baseURLs {
+		if baseURL == f.current {
+			return append(baseURLs[i:], baseURLs[:i]...), false
+		}
+	}
+
+	// The fallback is no longer configured
+	f.current = ""
+	return baseURLs, false
+}
+
+// healthy records that a request to baseURL succeeded
+func (f *failover) healthy(config *Config, baseURL string, probing bool, now time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if baseURL == config.BaseURL {
+		f.current = ""
+		return
+	}
+
+	if baseURL != f.current || probing {
+		f.current = baseURL
+		f.since = now
+	}
+}
+
+// active returns the base URL requests are currently sent to
+func (f *failover) active(config *Config) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.current != "" {
+		for _, baseURL := range config.FallbackBaseURLs {
+			if baseURL == f.current {
+				return f.current
+			}
+		}
+	}
+	return config.BaseURL
+}
+
+// isFailoverError reports whether err indicates that the endpoint rather
+// than the request is at fault: network errors, timeouts and 5xx responses.
+// A network error after the request was sent is not, as the endpoint may
+// have processed it.
+func isFailoverError(err error) bool {
+	var networkErr *NetworkError
+	if errors.As(err, &networkErr) {
+		return !networkErr.RequestSent
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode() >= http.StatusInternalServerError
+	}
+
+	return false
+}
+
+// ActiveBaseURL returns the base URL requests are currently sent to. It
+// differs from the configured BaseURL after failing over to one of the
+// FallbackBaseURLs.
+func (c *Client) ActiveBaseURL() string {
+	return c.httpClient.failover.active(c.snapshotConfig())
+}
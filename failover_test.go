@@ -0,0 +1,195 @@
+package poodle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failoverServer is a mock API endpoint whose status can be changed
+type failoverServer struct {
+	*httptest.Server
+	status   int32
+	requests int32
+}
+
+func newFailoverServer(t *testing.T) *failoverServer {
+	s := &failoverServer{status: http.StatusAccepted}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		status := int(atomic.LoadInt32(&s.status))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status == http.StatusAccepted {
+			w.Write([]byte(`{"success":true,"message":"Email queued"}`))
+		} else {
+			w.Write([]byte(`{"success":false,"message":"Unavailable"}`))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *failoverServer) setStatus(status int) {
+	atomic.StoreInt32(&s.status, int32(status))
+}
+
+func (s *failoverServer) take() int {
+	return int(atomic.SwapInt32(&s.requests, 0))
+}
+
+func TestFailoverSequence(t *testing.T) {
+	primary := newFailoverServer(t)
+	fallback := newFailoverServer(t)
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = primary.URL
+	config.FallbackBaseURLs = []string{fallback.URL + "/"}
+	config.FailoverProbeInterval = time.Minute
+
+	clock := newTestClock()
+	client := NewClientWithConfig(config, WithClock(clock))
+	send := func() error {
+		_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+		return err
+	}
+
+	// Healthy primary
+	if err := send(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if primary.take() != 1 || fallback.take() != 0 || client.ActiveBaseURL() != primary.URL {
+		t.Fatal("Expected the primary to be used while healthy")
+	}
+
+	// Primary outage fails over within the same send
+	primary.setStatus(http.StatusServiceUnavailable)
+	if err := send(); err != nil {
+		t.Fatalf("Expected failover to succeed, got: %v", err)
+	}
+	if primary.take() != 1 || fallback.take() != 1 {
+		t.Error("Expected one request to each endpoint")
+	}
+	if client.ActiveBaseURL() != fallback.URL {
+		t.Errorf("Expected active base URL '%s', got '%s'", fallback.URL, client.ActiveBaseURL())
+	}
+
+	// Subsequent sends stay on the fallback
+	if err := send(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if primary.take() != 0 || fallback.take() != 1 {
+		t.Error("Expected the fallback to be remembered")
+	}
+
+	// The primary is probed after the interval but is still down
+	clock.Sleep(context.Background(), time.Minute)
+	if err := send(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if primary.take() != 1 || fallback.take() != 1 {
+		t.Error("Expected the primary to be probed before falling back")
+	}
+	if err := send(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if primary.take() != 0 {
+		t.Error("Expected the primary not to be probed again before the interval")
+	}
+	fallback.take()
+
+	// The primary recovers and is used again after the next probe
+	primary.setStatus(http.StatusAccepted)
+	clock.Sleep(context.Background(), time.Minute)
+	if err := send(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if primary.take() != 1 || fallback.take() != 0 || client.ActiveBaseURL() != primary.URL {
+		t.Error("Expected the client to return to the recovered primary")
+	}
+}
+
+func TestFailoverIgnoresClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden} {
+		primary := newFailoverServer(t)
+		fallback := newFailoverServer(t)
+		primary.setStatus(status)
+
+		config := NewConfig()
+		config.APIKey = "test_api_key"
+		config.BaseURL = primary.URL
+		config.FallbackBaseURLs = []string{fallback.URL}
+		client := NewClientWithConfig(config)
+
+		if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err == nil {
+			t.Errorf("Expected status %d to fail the send", status)
+		}
+		if fallback.take() != 0 {
+			t.Errorf("Expected status %d not to trigger failover", status)
+		}
+		if client.ActiveBaseURL() != primary.URL {
+			t.Errorf("Expected status %d to keep the primary active", status)
+		}
+	}
+}
+
+func TestFailoverAllEndpointsDown(t *testing.T) {
+	primary := newFailoverServer(t)
+	fallback := newFailoverServer(t)
+	primary.setStatus(http.StatusBadGateway)
+	fallback.setStatus(http.StatusServiceUnavailable)
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = primary.URL
+	config.FallbackBaseURLs = []string{fallback.URL}
+	client := NewClientWithConfig(config)
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	httpErr, ok := err.(*HTTPError)
+	if !ok {
+		t.Fatalf("Expected HTTPError, got %T", err)
+	}
+	if httpErr.StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("Expected the last endpoint's error, got %d", httpErr.StatusCode())
+	}
+	if client.ActiveBaseURL() != primary.URL {
+		t.Errorf("Expected the primary to stay active, got '%s'", client.ActiveBaseURL())
+	}
+}
+
+func TestConfigValidateFallbackBaseURLs(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.FallbackBaseURLs = []string{"ftp://api.eu.usepoodle.com"}
+
+	err := config.Validate()
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %T", err)
+	}
+	if _, exists := validationErr.Errors["fallback_base_urls"]; !exists {
+		t.Errorf("Expected fallback_base_urls error, got %v", validationErr.Errors)
+	}
+}
+
+func TestConfigValidateKeepsCallerFallbacks(t *testing.T) {
+	fallbacks := []string{"https://api.eu.usepoodle.com/"}
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.FallbackBaseURLs = fallbacks
+
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if config.FallbackBaseURLs[0] != "https://api.eu.usepoodle.com" {
+		t.Errorf("Expected the fallback to be normalised, got '%s'", config.FallbackBaseURLs[0])
+	}
+	if fallbacks[0] != "https://api.eu.usepoodle.com/" {
+		t.Errorf("Expected the caller's slice to be left untouched, got '%s'", fallbacks[0])
+	}
+}
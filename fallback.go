@@ -0,0 +1,151 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// contentRejectionTerms identify a 422 response caused by the email content
+var contentRejectionTerms = []string{"content", "html", "body"}
+
+// isContentRejection reports whether the API rejected the email because
+// of its content: a 422 ValidationError, or a QueueError that is not
+// retryable, whose message, reason or details mention the content. Errors
+// found by client-side validation have status 400 and are not content
+// rejections.
+func isContentRejection(err error) bool {
+	var texts []string
+	var validationErr *ValidationError
+	var queueErr *QueueError
+	switch {
+	case errors.As(err, &validationErr) && validationErr.Code == http.StatusUnprocessableEntity:
+		texts = append(texts, validationErr.Message)
+		for _, messages := range validationErr.Errors {
+			texts = append(texts, messages...)
+		}
+	case errors.As(err, &queueErr) && !queueErr.Retryable:
+		texts = append(texts, queueErr.Message, queueErr.Reason)
+	default:
+		return false
+	}
+
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		for _, term := range contentRejectionTerms {
+			if strings.Contains(text, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sendTextFallback sends a text-only copy of an email whose HTML content
+// was rejected, generating the text from the HTML if the email has none.
+// The response is marked with FallbackToText and the original error.
+func (c *HTTPClient) sendTextFallback(ctx context.Context, config *Config, email *Email, rejection error, attempts *int) (*EmailResponse, error) {
+	rejection = config.PIIMode.scrubError(rejection)
+	if config.logs(LogLevelError) {
+		log.Printf("Poodle API Fallback: HTML content was rejected, sending as text: %s", rejection.Error())
+	}
+
+	text := email.clone()
+	if strings.TrimSpace(text.Text) == "" {
+		text.Text = htmlToText(text.HTML)
+	}
+	text.HTML = ""
+
+	fallbackAttempts := 0
+	response, err := c.send(ctx, config, text, &fallbackAttempts)
+	*attempts += fallbackAttempts
+	if err != nil {
+		return nil, err
+	}
+
+	response.FallbackToText = true
+	response.FallbackError = rejection
+	return response, nil
+}
+
+// blockElements start a new line when converting HTML to text
+var blockElements = map[string]bool{
+	"p": true, "div": true, "li": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "pre": true, "hr": true, "ul": true, "ol": true,
+}
+
+// htmlToText converts an HTML body to plain text: tags are removed, block
+// elements start new lines, the content of raw text elements such as scripts is
+// dropped, comments are skipped, entities are decoded and runs of spaces
+// are collapsed
+func htmlToText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			next := strings.IndexByte(s[i:], '<')
+			if next < 0 {
+				next = len(s) - i
+			}
+			// Line breaks in the markup are spaces in the rendered text
+			b.WriteString(strings.Map(func(r rune) rune {
+				if r == '\n' || r == '\r' || r == '\t' {
+					return ' '
+				}
+				return r
+			}, s[i:i+next]))
+			i += next
+			continue
+		}
+
+		// Skip comments and declarations such as <!DOCTYPE html>
+		if strings.HasPrefix(s[i:], "<!--") {
+			end := strings.Index(s[i+4:], "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+		if strings.HasPrefix(s[i:], "<!") {
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				break
+			}
+			i += end + 1
+			continue
+		}
+
+		tag, next, ok := parseTag(s, i)
+		if !ok {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		i = next
+
+		if !tag.end && rawTextElements[tag.name] {
+			_, _, i = rawText(s, i, tag.name)
+			continue
+		}
+		// A block element starts a new line, and each <br> adds one
+		if tag.name == "br" || blockElements[tag.name] && !strings.HasSuffix(strings.TrimRight(b.String(), " "), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+
+	lines := strings.Split(html.UnescapeString(b.String()), "\n")
+	text := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		// Keep at most one blank line between paragraphs
+		if line == "" && (len(text) == 0 || text[len(text)-1] == "") {
+			continue
+		}
+		text = append(text, line)
+	}
+	return strings.TrimSpace(strings.Join(text, "\n"))
+}
@@ -0,0 +1,112 @@
+package poodle
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFallbackToText(t *testing.T) {
+	logs := captureLog(t)
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.LogLevel = LogLevelError
+	config.FallbackToText = true
+
+	var bodies []map[string]interface{}
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		var body map[string]interface{}
+		data, _ := io.ReadAll(req.Body)
+		json.Unmarshal(data, &body)
+		bodies = append(bodies, body)
+		if len(bodies) == 1 {
+			return jsonResponse(http.StatusUnprocessableEntity, `{"message":"HTML content rejected by filter"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	response, err := client.SendHTML("from@example.com", "to@example.com", "Subject", "<h1>Sale</h1><p>50% &amp; more</p>")
+	if err != nil {
+		t.Fatalf("Expected the text fallback to succeed, got: %v", err)
+	}
+	if !response.FallbackToText {
+		t.Error("Expected the response to be marked as a text fallback")
+	}
+	var queueErr *QueueError
+	if !errors.As(response.FallbackError, &queueErr) || queueErr.StatusCode() != http.StatusUnprocessableEntity {
+		t.Errorf("Expected the original 422 error, got %v", response.FallbackError)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(bodies))
+	}
+	if _, ok := bodies[1]["html"]; ok || bodies[1]["text"] != "Sale\n50% & more" {
+		t.Errorf("Expected a text-only fallback, got %v", bodies[1])
+	}
+	if !strings.Contains(logs.String(), "HTML content rejected by filter") {
+		t.Errorf("Expected the original error to be logged, got %q", logs.String())
+	}
+}
+
+func TestFallbackToTextNotUsed(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		status   int
+		body     string
+		requests int
+	}{
+		{"disabled", false, http.StatusUnprocessableEntity, `{"message":"HTML content rejected"}`, 1},
+		{"unrelated 422", true, http.StatusUnprocessableEntity, `{"message":"Queue is full"}`, 1},
+		{"authentication", true, http.StatusUnauthorized, `{"message":"Invalid content key"}`, 1},
+		{"rate limit", true, http.StatusTooManyRequests, `{"message":"Too much content"}`, 1},
+		{"server error", true, http.StatusInternalServerError, `{"message":"Content store down"}`, 1},
+	}
+
+	for _, tt := range tests {
+		config := NewConfig()
+		config.APIKey = "test_api_key"
+		config.FallbackToText = tt.enabled
+
+		requests := 0
+		client := NewClientWithConfig(config)
+		client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return jsonResponse(tt.status, tt.body), nil
+		})
+
+		_, err := client.SendWithBoth("from@example.com", "to@example.com", "Subject", "<p>Hi</p>", "Hi")
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if requests != tt.requests {
+			t.Errorf("%s: expected %d requests, got %d", tt.name, tt.requests, requests)
+		}
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{"<p>Hello</p><p>World</p>", "Hello\nWorld"},
+		{"<!DOCTYPE html><html><head><title>Ignored</title><style>p{}</style></head><body>Hi<br>there</body></html>", "Hi\nthere"},
+		{"<ul><li>One</li><li>Two</li></ul>", "One\nTwo"},
+		{"<p>Tom &amp; Jerry   <b>rock</b></p><!-- note --><script>alert(1)</script>", "Tom & Jerry rock"},
+		{"<div>A</div>\n\n\n<div>B\nC</div>", "A\nB C"},
+		{"Line<br><br>Gap", "Line\n\nGap"},
+		{"1 < 2", "1 < 2"},
+	}
+
+	for _, tt := range tests {
+		if got := htmlToText(tt.html); got != tt.want {
+			t.Errorf("htmlToText(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package poodle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// FingerprintVersion prefixes every fingerprint. It changes only if the
+// canonical form below changes, so stored fingerprints remain comparable
+// across SDK versions.
+const FingerprintVersion = "v1"
+
+// Fingerprint returns a stable identity of the email's content, suitable
+// for dedupe, outbox persistence and idempotency keys. It has the form
+// "v1:" followed by the hex-encoded SHA-256 of the canonical form:
+//
+//	poodle-fingerprint-v1\n
+//	from <n>:<from>\n
+//	to <n>:<to>\n
+//	subject <n>:<subject>\n
+//	html <n>:<html>\n
+//	text <n>:<text>\n
+//	headers <count>\n
+//	<n>:<name> <n>:<value>\n    (one line per header, sorted by name)
+//
+// where <n> is the length in bytes of the value that follows. Addresses
+// are taken as sent, so FromAddress takes precedence over From, and have
+// surrounding whitespace removed and their domain lowercased; the
+// subject and bodies are used as-is. Header names are canonicalized, so the
+// order or case in which headers were set does not matter. Fields that do
+// not change the delivered message are not part of the fingerprint.
+func (e *Email) Fingerprint() string {
+	hash := sha256.New()
+	write := func(s string) {
+		hash.Write([]byte(strconv.Itoa(len(s)) + ":" + s))
+	}
+
+	hash.Write([]byte("poodle-fingerprint-" + FingerprintVersion + "\n"))
+	for _, field := range []struct{ name, value string }{
+		{"from", normalizeAddress(e.wireFrom())},
+		{"to", normalizeAddress(e.wireTo())},
+		{"subject", e.Subject},
+		{"html", e.HTML},
+		{"text", e.Text},
+	} {
+		hash.Write([]byte(field.name + " "))
+		write(field.value)
+		hash.Write([]byte("\n"))
+	}
+
+	headers := make([][2]string, 0, len(e.Headers))
+	for key, value := range e.Headers {
+		headers = append(headers, [2]string{http.CanonicalHeaderKey(key), value})
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		if headers[i][0] != headers[j][0] {
+			return headers[i][0] < headers[j][0]
+		}
+		return headers[i][1] < headers[j][1]
+	})
+
+	hash.Write([]byte("headers " + strconv.Itoa(len(headers)) + "\n"))
+	for _, header := range headers {
+		write(header[0])
+		hash.Write([]byte(" "))
+		write(header[1])
+		hash.Write([]byte("\n"))
+	}
+
+	return FingerprintVersion + ":" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// Equal reports whether the emails have the same content, i.e. the same
+// Fingerprint
+func (e *Email) Equal(other *Email) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	return e.Fingerprint() == other.Fingerprint()
+}
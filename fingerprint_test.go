@@ -0,0 +1,100 @@
+package poodle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEmailFingerprint(t *testing.T) {
+	email := NewEmailWithBoth("from@example.com", "to@example.com", "Subject", "<p>Hi</p>", "Hi")
+	same := NewEmailWithBoth("from@example.com", "to@example.com", "Subject", "<p>Hi</p>", "Hi")
+
+	if email.Fingerprint() != same.Fingerprint() {
+		t.Error("Expected identical emails to have the same fingerprint")
+	}
+	if !strings.HasPrefix(email.Fingerprint(), "v1:") || len(email.Fingerprint()) != 67 {
+		t.Errorf("Expected a versioned hex SHA-256 fingerprint, got '%s'", email.Fingerprint())
+	}
+
+	// Field boundaries must be part of the hash
+	shifted := NewEmailWithBoth("from@example.com", "to@example.com", "Subject<p>", "Hi</p>", "Hi")
+	if email.Fingerprint() == shifted.Fingerprint() {
+		t.Error("Expected emails with different fields to have different fingerprints")
+	}
+}
+
+func TestEmailFingerprintStable(t *testing.T) {
+	// The canonical form is part of the public contract: if this value
+	// changes, FingerprintVersion must change too
+	email := NewEmailWithBoth("from@example.com", "to@example.com", "Subject", "<p>Hi</p>", "Hi")
+	email.SetHeader("X-Campaign", "spring")
+
+	const want = "v1:00b96f465c64269a514b68df27f71d03787d77cecd49c388c704a9d760b2a02f"
+	if got := email.Fingerprint(); got != want {
+		t.Errorf("Expected fingerprint %s, got %s", want, got)
+	}
+}
+
+func TestEmailFingerprintCanonicalization(t *testing.T) {
+	base := NewEmailWithBoth("from@example.com", "to@example.com", "Subject", "<p>Hi</p>", "Hi")
+
+	// Whitespace and domain case of addresses are normalized
+	messy := NewEmailWithBoth(" from@EXAMPLE.com ", "\tto@example.COM", "Subject", "<p>Hi</p>", "Hi")
+	if !base.Equal(messy) {
+		t.Error("Expected address whitespace and domain case to be ignored")
+	}
+
+	// Bodies and the subject are used as-is
+	for _, changed := range []*Email{
+		NewEmailWithBoth("from@example.com", "to@example.com", "Subject ", "<p>Hi</p>", "Hi"),
+		NewEmailWithBoth("from@example.com", "to@example.com", "Subject", "<p>Hi</p> ", "Hi"),
+		NewEmailWithBoth("From@example.com", "to@example.com", "Subject", "<p>Hi</p>", "Hi"),
+	} {
+		if base.Equal(changed) {
+			t.Errorf("Expected %+v to differ from the base email", changed)
+		}
+	}
+}
+
+func TestEmailFingerprintHeaderOrder(t *testing.T) {
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("X-Header-%02d", i)
+	}
+
+	forward := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	for _, key := range keys {
+		forward.SetHeader(key, "value "+key)
+	}
+
+	backward := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	backward.Headers = make(map[string]string)
+	for i := len(keys) - 1; i >= 0; i-- {
+		backward.Headers[strings.ToLower(keys[i])] = "value " + keys[i]
+	}
+
+	want := forward.Fingerprint()
+	for i := 0; i < 10; i++ {
+		if got := backward.Fingerprint(); got != want {
+			t.Fatalf("Expected header order and case not to change the fingerprint, got %s and %s", got, want)
+		}
+	}
+
+	backward.Headers["x-header-00"] = "other"
+	if forward.Equal(backward) {
+		t.Error("Expected a different header value to change the fingerprint")
+	}
+}
+
+func TestEmailEqualNil(t *testing.T) {
+	var nilEmail *Email
+	email := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+
+	if !nilEmail.Equal(nil) {
+		t.Error("Expected nil emails to be equal")
+	}
+	if email.Equal(nil) || nilEmail.Equal(email) {
+		t.Error("Expected nil and non-nil emails to differ")
+	}
+}
@@ -0,0 +1,127 @@
+package poodle
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// checkHardened checks the parts of the configuration that
+// Config.HardenedTransport restricts: every base URL must use https, and
+// TLS keys must not be logged to SSLKEYLOGFILE
+func (c *Config) checkHardened() error {
+	for _, baseURL := range append([]string{c.BaseURL}, c.FallbackBaseURLs...) {
+		if err := checkHTTPS(baseURL); err != nil {
+			return err
+		}
+	}
+
+	if os.Getenv("SSLKEYLOGFILE") != "" {
+		return NewSecurityPolicyError(SecurityPolicyKeyLog, "SSLKEYLOGFILE is set, so TLS session keys may be logged")
+	}
+	return nil
+}
+
+// checkHTTPS returns a SecurityPolicyError unless the URL uses https
+func checkHTTPS(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" {
+		return NewSecurityPolicyError(SecurityPolicyCleartextURL, "URL must use https: "+rawURL)
+	}
+	return nil
+}
+
+// checkTLSConfig returns a SecurityPolicyError if the TLS configuration
+// skips certificate verification, allows versions before TLS 1.2 or logs
+// session keys. A nil configuration uses the crypto/tls defaults, which
+// are safe.
+func checkTLSConfig(config *tls.Config) error {
+	if config == nil {
+		return nil
+	}
+	if config.InsecureSkipVerify {
+		return NewSecurityPolicyError(SecurityPolicyCertificateVerification, "TLS certificate verification is disabled")
+	}
+	if config.MinVersion != 0 && config.MinVersion < tls.VersionTLS12 || config.MaxVersion != 0 && config.MaxVersion < tls.VersionTLS12 {
+		return NewSecurityPolicyError(SecurityPolicyTLSVersion, "TLS versions before 1.2 are allowed")
+	}
+	if config.KeyLogWriter != nil {
+		return NewSecurityPolicyError(SecurityPolicyKeyLog, "TLS session keys are logged")
+	}
+	return nil
+}
+
+// hardenTransport restricts the SDK's own transport and client to TLS 1.2
+// or later and to https redirects
+func hardenTransport(client *http.Client, transport *http.Transport) {
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	client.CheckRedirect = httpsRedirects(nil)
+}
+
+// httpsRedirects returns a CheckRedirect function that refuses redirects to
+// URLs that do not use https before applying next, or the default policy
+// if next is nil
+func httpsRedirects(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return NewSecurityPolicyError(SecurityPolicyCleartextURL, "Redirect must use https: "+req.URL.Redacted())
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
+
+// hardenedDoer wraps a custom HTTPDoer when Config.HardenedTransport is
+// set. It checks that every request uses https and, if the doer is an
+// *http.Client, that its transport verifies certificates, requires TLS 1.2
+// and does not log keys.
+type hardenedDoer struct {
+	next HTTPDoer
+}
+
+// newHardenedDoer wraps doer. An *http.Client is copied so that its
+// redirects can be restricted to https without changing the caller's
+// client.
+func newHardenedDoer(doer HTTPDoer) *hardenedDoer {
+	if client, ok := doer.(*http.Client); ok {
+		hardened := *client
+		hardened.CheckRedirect = httpsRedirects(client.CheckRedirect)
+		doer = &hardened
+	}
+	return &hardenedDoer{next: doer}
+}
+
+// Do implements HTTPDoer
+func (d *hardenedDoer) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return nil, NewSecurityPolicyError(SecurityPolicyCleartextURL, "Request URL must use https: "+req.URL.Redacted())
+	}
+
+	if client, ok := d.next.(*http.Client); ok {
+		roundTripper := client.Transport
+		if roundTripper == nil {
+			roundTripper = http.DefaultTransport
+		}
+		if transport, ok := roundTripper.(*http.Transport); ok {
+			if err := checkTLSConfig(transport.TLSClientConfig); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return d.next.Do(req)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped doer
+func (d *hardenedDoer) CloseIdleConnections() {
+	if closer, ok := d.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
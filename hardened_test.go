@@ -0,0 +1,184 @@
+package poodle
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func hardenedConfig(baseURL string) *Config {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = baseURL
+	config.HardenedTransport = true
+	return config
+}
+
+func expectPolicy(t *testing.T, err error, policy SecurityPolicy) {
+	t.Helper()
+	var policyErr *SecurityPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Expected SecurityPolicyError, got %T: %v", err, err)
+	}
+	if policyErr.Policy != policy {
+		t.Errorf("Expected policy %s, got %s", policy, policyErr.Policy)
+	}
+	if policyErr.Context()["error_type"] != "security_policy" {
+		t.Errorf("Expected error_type security_policy, got %v", policyErr.Context()["error_type"])
+	}
+	if IsRetryable(err) {
+		t.Error("Expected a security policy violation not to be retryable")
+	}
+}
+
+func TestHardenedTransportValidation(t *testing.T) {
+	config := hardenedConfig("http://api.usepoodle.com")
+	expectPolicy(t, config.Validate(), SecurityPolicyCleartextURL)
+
+	config = hardenedConfig("https://api.usepoodle.com")
+	config.FallbackBaseURLs = []string{"https://eu.api.usepoodle.com", "http://us.api.usepoodle.com"}
+	expectPolicy(t, config.Validate(), SecurityPolicyCleartextURL)
+
+	config = hardenedConfig("https://api.usepoodle.com")
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected an https configuration to be valid, got: %v", err)
+	}
+
+	config = hardenedConfig("http://localhost:8080")
+	config.HardenedTransport = false
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected http to be allowed without HardenedTransport, got: %v", err)
+	}
+}
+
+func TestHardenedTransportRefusesKeyLog(t *testing.T) {
+	t.Setenv("SSLKEYLOGFILE", "/tmp/keys.log")
+
+	expectPolicy(t, hardenedConfig("https://api.usepoodle.com").Validate(), SecurityPolicyKeyLog)
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected NewClientWithConfig to panic")
+		}
+	}()
+	NewClientWithConfig(hardenedConfig("https://api.usepoodle.com"))
+}
+
+func TestHardenedTransportSetBaseURL(t *testing.T) {
+	client := NewClientWithConfig(hardenedConfig("https://api.usepoodle.com"))
+
+	expectPolicy(t, client.SetBaseURL("http://api.usepoodle.com"), SecurityPolicyCleartextURL)
+	if client.GetConfig().BaseURL != "https://api.usepoodle.com" {
+		t.Errorf("Expected the base URL to be unchanged, got %s", client.GetConfig().BaseURL)
+	}
+	if err := client.SetBaseURL("https://eu.api.usepoodle.com"); err != nil {
+		t.Errorf("Expected an https base URL to be accepted, got: %v", err)
+	}
+}
+
+func TestHardenedTransportDefaults(t *testing.T) {
+	client := NewClientWithConfig(hardenedConfig("https://api.usepoodle.com"))
+
+	httpClient, ok := client.httpClient.httpClient.(*http.Client)
+	if !ok {
+		t.Fatalf("Expected the default client not to be wrapped, got %T", client.httpClient.httpClient)
+	}
+	transport := httpClient.Transport.(*http.Transport)
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("Expected TLS 1.2 to be required, got %+v", transport.TLSClientConfig)
+	}
+	if err := checkTLSConfig(transport.TLSClientConfig); err != nil {
+		t.Errorf("Expected the default TLS configuration to pass, got: %v", err)
+	}
+
+	redirect := httptest.NewRequest(http.MethodGet, "http://api.usepoodle.com/v1/send-email", nil)
+	expectPolicy(t, httpClient.CheckRedirect(redirect, nil), SecurityPolicyCleartextURL)
+}
+
+func TestHardenedTransportCustomClient(t *testing.T) {
+	tests := []struct {
+		name   string
+		tls    *tls.Config
+		policy SecurityPolicy
+	}{
+		{"InsecureSkipVerify", &tls.Config{InsecureSkipVerify: true}, SecurityPolicyCertificateVerification},
+		{"TLS10", &tls.Config{MinVersion: tls.VersionTLS10}, SecurityPolicyTLSVersion},
+		{"MaxTLS11", &tls.Config{MaxVersion: tls.VersionTLS11}, SecurityPolicyTLSVersion},
+		{"KeyLogWriter", &tls.Config{KeyLogWriter: io.Discard}, SecurityPolicyKeyLog},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := hardenedConfig("https://api.usepoodle.com")
+			config.MaxRetries = 2
+			custom := &http.Client{Transport: &http.Transport{TLSClientConfig: tt.tls}}
+			client := NewClientWithConfig(config, WithHTTPDoer(custom))
+
+			_, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+			expectPolicy(t, err, tt.policy)
+		})
+	}
+}
+
+func TestHardenedTransportCustomClientSends(t *testing.T) {
+	var redirect atomic.Bool
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if redirect.Load() {
+			http.Redirect(w, r, "http://api.usepoodle.com/v1/send-email", http.StatusTemporaryRedirect)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, `{"success": true, "message": "Email queued"}`)
+	}))
+	defer server.Close()
+
+	client := NewClientWithConfig(hardenedConfig(server.URL), WithHTTPDoer(server.Client()))
+	if _, ok := client.httpClient.httpClient.(*hardenedDoer); !ok {
+		t.Fatalf("Expected the custom client to be wrapped, got %T", client.httpClient.httpClient)
+	}
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err != nil {
+		t.Fatalf("Expected a verified TLS client to send, got: %v", err)
+	}
+
+	redirect.Store(true)
+	_, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+	expectPolicy(t, err, SecurityPolicyCleartextURL)
+	if server.Client().CheckRedirect != nil {
+		t.Error("Expected the caller's client not to be modified")
+	}
+}
+
+func TestHardenedDoerRejectsCleartext(t *testing.T) {
+	var calls int32
+	doer := newHardenedDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return acceptedResponse(), nil
+	}))
+
+	_, err := doer.Do(httptest.NewRequest(http.MethodPost, "http://api.usepoodle.com/v1/send-email", nil))
+	expectPolicy(t, err, SecurityPolicyCleartextURL)
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Errorf("Expected the request not to be delegated, got %d calls", atomic.LoadInt32(&calls))
+	}
+
+	if _, err := doer.Do(httptest.NewRequest(http.MethodPost, "https://api.usepoodle.com/v1/send-email", nil)); err != nil {
+		t.Errorf("Expected an https request to be delegated, got: %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 call, got %d", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestHardenedTransportFromEnv(t *testing.T) {
+	t.Setenv("POODLE_API_KEY", "test_api_key")
+	t.Setenv("POODLE_HARDENED_TRANSPORT", "true")
+
+	if config := NewConfigFromEnv(); !config.HardenedTransport {
+		t.Error("Expected HardenedTransport to be set from the environment")
+	}
+}
@@ -0,0 +1,131 @@
+package poodle
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ContextHeaderMapper derives a request header from the request context,
+// e.g. a correlation ID set by middleware. It returns false to add no
+// header.
+type ContextHeaderMapper func(ctx context.Context) (key, value string, ok bool)
+
+// traceparentKey is the context key used by WithTraceparent
+type traceparentKey struct{}
+
+// WithTraceparent returns a context carrying a W3C traceparent value for
+// TraceparentMapper to forward
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, traceparent)
+}
+
+// TraceparentMapper forwards the W3C traceparent stored with
+// WithTraceparent as the traceparent header. Malformed values are ignored.
+func TraceparentMapper(ctx context.Context) (string, string, bool) {
+	traceparent, _ := ctx.Value(traceparentKey{}).(string)
+	if !isValidTraceparent(traceparent) {
+		return "", "", false
+	}
+	return "traceparent", traceparent, true
+}
+
+// HeaderFromContext returns a mapper that sends the string stored in the
+// context under key as the given header, e.g. to forward a request ID:
+//
+//	HeaderFromContext("X-Correlation-Id", middleware.RequestIDKey)
+func HeaderFromContext(header string, key interface{}) ContextHeaderMapper {
+	return func(ctx context.Context) (string, string, bool) {
+		value, ok := ctx.Value(key).(string)
+		if !ok || value == "" {
+			return "", "", false
+		}
+		return header, value, true
+	}
+}
+
+// isValidTraceparent checks the version-traceid-parentid-flags format of a
+// W3C traceparent value
+func isValidTraceparent(value string) bool {
+	parts := strings.Split(value, "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return false
+	}
+	for _, part := range parts {
+		for _, r := range part {
+			if !strings.ContainsRune("0123456789abcdef", r) {
+				return false
+			}
+		}
+	}
+	return parts[1] != strings.Repeat("0", 32) && parts[2] != strings.Repeat("0", 16)
+}
+
+// reservedHeaders are set by the client and cannot be overridden
+var reservedHeaders = map[string]bool{
+	"Authorization":  true,
+	"Content-Type":   true,
+	"Content-Length": true,
+	"Host":           true,
+}
+
+// checkHeader returns a reason why the header may not be added to a
+// request, or an empty string if it may. Values containing CR or LF are
+// rejected to prevent header injection.
+func checkHeader(key, value string) string {
+	if key == "" {
+		return "header name is empty"
+	}
+	for _, r := range key {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return "header name " + key + " contains invalid characters"
+		}
+	}
+	if reservedHeaders[http.CanonicalHeaderKey(key)] {
+		return "header " + key + " is set by the client"
+	}
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return "header " + key + " value contains line breaks"
+	}
+	return ""
+}
+
+// reservedEmailHeaders are derived from the email's fields and cannot be
+// set as additional headers
+var reservedEmailHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Subject":                   true,
+	"Mime-Version":              true,
+	"Content-Transfer-Encoding": true,
+}
+
+// checkEmailHeader returns a reason why the header may not be added to an
+// email, or an empty string if it may
+func checkEmailHeader(key, value string) string {
+	if reservedEmailHeaders[http.CanonicalHeaderKey(key)] {
+		return "header " + key + " is set from the email fields"
+	}
+	return checkHeader(key, value)
+}
+
+// applyContextHeaders adds the headers produced by the configured mappers
+// to the request, skipping and logging invalid ones
+func applyContextHeaders(config *Config, req *http.Request) {
+	for _, mapper := range config.ContextHeaderMappers {
+		key, value, ok := mapper(req.Context())
+		if !ok {
+			continue
+		}
+
+		if reason := checkHeader(key, value); reason != "" {
+			if config.logs(LogLevelError) {
+				log.Printf("Poodle API Header: skipping context header: %s", reason)
+			}
+			continue
+		}
+
+		req.Header.Set(key, value)
+	}
+}
@@ -0,0 +1,103 @@
+package poodle
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+type requestIDKey struct{}
+
+func TestContextHeaderMappers(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.ContextHeaderMappers = []ContextHeaderMapper{
+		HeaderFromContext("X-Correlation-Id", requestIDKey{}),
+		TraceparentMapper,
+		func(ctx context.Context) (string, string, bool) {
+			return "X-Injected", "value\r\nBcc: victim@example.com", true
+		},
+		func(ctx context.Context) (string, string, bool) {
+			return "Authorization", "Bearer stolen", true
+		},
+	}
+
+	client := NewClientWithConfig(config)
+
+	var header http.Header
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		header = req.Header
+		return acceptedResponse(), nil
+	})
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-123")
+	ctx = WithTraceparent(ctx, traceparent)
+	email := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	if _, err := client.SendContext(ctx, email); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if header.Get("X-Correlation-Id") != "req-123" {
+		t.Errorf("Expected correlation ID to be forwarded, got '%s'", header.Get("X-Correlation-Id"))
+	}
+	if header.Get("Traceparent") != traceparent {
+		t.Errorf("Expected traceparent to be forwarded, got '%s'", header.Get("Traceparent"))
+	}
+	if _, exists := header["X-Injected"]; exists {
+		t.Error("Expected a header with line breaks to be skipped")
+	}
+	if header.Get("Authorization") != "Bearer test_api_key" {
+		t.Errorf("Expected the Authorization header not to be overridden, got '%s'", header.Get("Authorization"))
+	}
+
+	// Without values in the context no headers are added
+	if _, err := client.Send(email); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if header.Get("X-Correlation-Id") != "" || header.Get("Traceparent") != "" {
+		t.Errorf("Expected no context headers, got %v", header)
+	}
+}
+
+func TestTraceparentValidation(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		_, _, ok := TraceparentMapper(WithTraceparent(context.Background(), tt.value))
+		if ok != tt.valid {
+			t.Errorf("TraceparentMapper(%q) ok = %t, want %t", tt.value, ok, tt.valid)
+		}
+	}
+}
+
+func TestCheckHeader(t *testing.T) {
+	tests := []struct {
+		key, value string
+		valid      bool
+	}{
+		{"X-Correlation-Id", "abc", true},
+		{"X-Correlation-Id", "abc\r\ndef", false},
+		{"X-Correlation-Id", "abc\ndef", false},
+		{"Bad Header", "abc", false},
+		{"", "abc", false},
+		{"content-type", "text/plain", false},
+	}
+
+	for _, tt := range tests {
+		if reason := checkHeader(tt.key, tt.value); (reason == "") != tt.valid {
+			t.Errorf("checkHeader(%q, %q) = %q, want valid = %t", tt.key, tt.value, reason, tt.valid)
+		}
+	}
+}
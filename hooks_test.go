@@ -0,0 +1,141 @@
+package poodle
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPreSendHooks(t *testing.T) {
+	var order []string
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.PreSend = []func(*Email) error{
+		func(email *Email) error {
+			order = append(order, "footer")
+			if email.HasHTML() {
+				email.HTML += "<p>Poodle Inc.</p>"
+			}
+			return nil
+		},
+		func(email *Email) error {
+			order = append(order, "audit")
+			return nil
+		},
+	}
+
+	client := NewClientWithConfig(config)
+
+	var sent Email
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		json.Unmarshal(body, &sent)
+		return acceptedResponse(), nil
+	})
+
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Subject", "<p>Hello</p>")
+	if _, err := client.Send(email); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if sent.HTML != "<p>Hello</p><p>Poodle Inc.</p>" {
+		t.Errorf("Expected the footer to be sent, got '%s'", sent.HTML)
+	}
+	if email.HTML != "<p>Hello</p>" {
+		t.Errorf("Expected the caller's email to be unchanged, got '%s'", email.HTML)
+	}
+	if strings.Join(order, ",") != "footer,audit" {
+		t.Errorf("Expected hooks to run in registration order, got %v", order)
+	}
+}
+
+func TestPreSendHookRejectsEmail(t *testing.T) {
+	allowlist := map[string]bool{"example.com": true}
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.PreSend = []func(*Email) error{
+		func(email *Email) error {
+			domain := email.To[strings.LastIndex(email.To, "@")+1:]
+			if !allowlist[domain] {
+				return errors.New("recipient domain " + domain + " is not allowlisted")
+			}
+			return nil
+		},
+	}
+
+	client := NewClientWithConfig(config)
+
+	requests := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return acceptedResponse(), nil
+	})
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected allowlisted recipient to be sent, got: %v", err)
+	}
+
+	_, err := client.SendText("from@example.com", "to@gmail.com", "Subject", "Hello")
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %T", err)
+	}
+	if validationErr.Error() != "recipient domain gmail.com is not allowlisted" {
+		t.Errorf("Unexpected error message: %s", validationErr.Error())
+	}
+	if _, ok := validationErr.Errors["pre_send"]; !ok {
+		t.Errorf("Expected a pre_send field error, got %v", validationErr.Errors)
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected the rejected email not to be sent, got %d requests", requests)
+	}
+}
+
+func TestPostSendHooks(t *testing.T) {
+	type record struct {
+		to  string
+		ok  bool
+		err error
+	}
+	var records []record
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 1
+	config.PostSend = []func(*Email, *EmailResponse, error){
+		func(email *Email, response *EmailResponse, err error) {
+			records = append(records, record{to: email.To, ok: response != nil, err: err})
+		},
+	}
+
+	client := NewClientWithConfig(config, WithClock(newTestClock()))
+
+	statuses := []int{http.StatusInternalServerError, http.StatusAccepted}
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		status := statuses[0]
+		statuses = statuses[1:]
+		if status == http.StatusAccepted {
+			return acceptedResponse(), nil
+		}
+		return jsonResponse(status, `{"message":"Server error"}`), nil
+	})
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Expected a record for every attempt, got %d", len(records))
+	}
+	if records[0].ok || records[0].err == nil {
+		t.Errorf("Expected the first attempt to be recorded as failed, got %+v", records[0])
+	}
+	if !records[1].ok || records[1].err != nil || records[1].to != "to@example.com" {
+		t.Errorf("Expected the second attempt to be recorded as successful, got %+v", records[1])
+	}
+}
@@ -2,7 +2,9 @@ package poodle
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -21,8 +23,20 @@ type HTTPDoer interface {
 
 // HTTPClient handles HTTP communication with the Poodle API
 type HTTPClient struct {
-	config     *Config
-	httpClient HTTPDoer // Changed from *http.Client
+	config       *Config
+	httpClient   HTTPDoer // Changed from *http.Client
+	limiter      *rateLimiter
+	shared       *rateLimiter
+	clock        Clock
+	events       eventSink
+	archive      *archiveQueue
+	failover     failover
+	pacer        *rateLimiter
+	rateLimit    rateLimitEpisode
+	exchanges    exchangeLog
+	stats        clientStats
+	transport    transportStats
+	deprecations deprecationLog
 }
 
 // NewHTTPClient creates a new HTTP client
@@ -32,132 +46,558 @@ func NewHTTPClient(config *Config) *HTTPClient {
 		Timeout:   config.ConnectTimeout, // This is the connection timeout
 		KeepAlive: 30 * time.Second,      // Default keep-alive, can be configured if needed
 	}
+	if config.LocalAddr != "" {
+		if localAddr, problem := parseLocalAddr(config.LocalAddr); problem == "" {
+			dialer.LocalAddr = localAddr
+		}
+	}
+	dialer.Control = config.DialerControl
+	dialer.FallbackDelay = config.FallbackDelay
 
 	transport := &http.Transport{
-		// DialContext is preferred, but Dial is used for Go 1.20 compatibility.
-		// The timeout is handled by the net.Dialer.
-		Dial: func(network, addr string) (net.Conn, error) {
-			return dialer.Dial(network, addr)
-		},
-		MaxIdleConns:          100, // Default, can be configured
-		IdleConnTimeout:       90 * time.Second, // Default, can be configured
-		TLSHandshakeTimeout:   10 * time.Second, // Default, can be configured
-		ExpectContinueTimeout: 1 * time.Second, // Default, can be configured
+		DialContext:           forceIPv4(config, dialer.DialContext), // The timeout is handled by the net.Dialer
+		MaxIdleConns:          100,                                   // Default, can be configured
+		IdleConnTimeout:       90 * time.Second,                      // Default, can be configured
+		TLSHandshakeTimeout:   10 * time.Second,                      // Default, can be configured
+		ExpectContinueTimeout: 1 * time.Second,                       // Default, can be configured
+	}
+	configureTransport(transport, config)
+
+	var pacer *rateLimiter
+	if config.AdaptivePacing {
+		pacer = &rateLimiter{clock: realClock{}}
+	}
+
+	httpClient := &http.Client{
+		// The total request timeout is applied per request via the
+		// request context so that it can be changed at runtime.
+		Transport: transport,
+	}
+	if config.HardenedTransport {
+		hardenTransport(httpClient, transport)
 	}
 
 	return &HTTPClient{
-		config: config,
-		httpClient: &http.Client{
-			Timeout:   config.Timeout, // This is the total request timeout
-			Transport: transport,
-		},
+		config:     config,
+		limiter:    newRateLimiter(config.MaxRequestsPerSecond, realClock{}),
+		pacer:      pacer,
+		clock:      realClock{},
+		httpClient: httpClient,
 	}
 }
 
 // SendEmail sends an email via the API
 func (c *HTTPClient) SendEmail(email *Email) (*EmailResponse, error) {
-	// Validate email before sending
-	if err := email.Validate(); err != nil {
+	return c.SendEmailContext(context.Background(), email)
+}
+
+// SendEmailContext sends an email via the API using the provided context
+func (c *HTTPClient) SendEmailContext(ctx context.Context, email *Email) (*EmailResponse, error) {
+	return c.sendEmail(ctx, c.config, email)
+}
+
+// sendEmail sends an email using the given configuration snapshot.
+// The snapshot is read once per call, so concurrent changes to the client
+// configuration only affect subsequent requests.
+func (c *HTTPClient) sendEmail(ctx context.Context, config *Config, email *Email) (*EmailResponse, error) {
+	parent := ctx
+	if config.OverallDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.OverallDeadline)
+		defer cancel()
+	}
+
+	attempts := 0
+	response, err := c.send(ctx, config, email, &attempts)
+	if err != nil && config.FallbackToText && email.HasHTML() && isContentRejection(err) {
+		response, err = c.sendTextFallback(ctx, config, email, err, &attempts)
+	}
+	err = config.PIIMode.scrubError(err)
+	recordAttempts(ctx, attempts)
+
+	// Report the overall deadline only if it, rather than the caller's
+	// context, ended the send
+	if err != nil && config.OverallDeadline > 0 && ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+		err = NewDeadlineExceededError(config.OverallDeadline, attempts, err)
+	}
+
+	c.observeSend(config, err)
+	return response, err
+}
+
+// observeSend records the outcome of a send in the statistics and metrics
+func (c *HTTPClient) observeSend(config *Config, err error) {
+	c.stats.recordSend(err)
+
+	if config.Metrics != nil {
+		status := "success"
+		if err != nil {
+			status = errorTypeOf(err)
+		}
+		config.Metrics.ObserveSend(status)
+	}
+}
+
+// send validates the email and sends it, retrying transient failures.
+// The number of attempts made is stored in attempts.
+func (c *HTTPClient) send(ctx context.Context, config *Config, email *Email, attempts *int) (*EmailResponse, error) {
+	email, requestBody, err := c.prepareSend(ctx, config, email)
+	if err != nil {
 		return nil, err
 	}
 
+	start := c.clock.Now()
+	for attempt := 1; ; attempt++ {
+		*attempts = attempt
+		response, err := c.sendToEndpoints(ctx, config, email, requestBody)
+		if err == nil {
+			if c.archive != nil {
+				c.archive.enqueue(email, response)
+			}
+			return response, nil
+		}
+
+		if c.events != nil {
+			c.events.error(ctx, err, attempt)
+		}
+
+		delay, retry := retryDelay(config, attempt, err)
+		if !retry || (config.RetryMaxElapsed > 0 && c.clock.Now().Sub(start)+delay > config.RetryMaxElapsed) {
+			return nil, err
+		}
+
+		if config.logs(LogLevelError) {
+			log.Printf("Poodle API Retry: attempt %d of %d failed, retrying in %s: %s", attempt, config.MaxRetries+1, delay, err.Error())
+		}
+
+		c.stats.retries.Add(1)
+		if config.Metrics != nil {
+			config.Metrics.ObserveRetry()
+		}
+
+		if c.clock.Sleep(ctx, delay) != nil {
+			return nil, err
+		}
+	}
+}
+
+// prepareSend validates the email, applies the configured content
+// changes and checks, and returns the email as sent with its request body
+func (c *HTTPClient) prepareSend(ctx context.Context, config *Config, email *Email) (*Email, []byte, error) {
+	if config.AutoMinifyHTML && len(email.HTML) > MaxContentSize {
+		email = email.clone().MinifyHTML()
+		recordEmail(ctx, email)
+	}
+
+	// Validate email before sending
+	if err := email.validate(config.addressValidator()); err != nil {
+		return nil, nil, err
+	}
+
+	// Run pre-send hooks on a copy so that the caller's email is unchanged
+	if len(config.PreSend) > 0 {
+		email = email.clone()
+		if err := runPreSend(config.PreSend, email, config.addressValidator()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if config.SanitizeHTML && email.HasHTML() {
+		email = email.clone().SanitizeHTML(DefaultSanitizePolicy())
+	}
+	recordEmail(ctx, email)
+
+	if err := checkRecipientDomains(config, email); err != nil {
+		return nil, nil, err
+	}
+
+	if config.RejectDisposable {
+		if err := checkDisposable(email); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if config.VerifyRecipientDNS {
+		if err := verifyRecipientDNS(ctx, config, email); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if config.StrictContentChecks {
+		if err := checkContent(config, email); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	reportLintWarnings(config, email)
+
 	// Prepare request body
-	requestBody, err := json.Marshal(email)
+	requestBody, err := marshalEmail(config, email)
 	if err != nil {
-		return nil, NewNetworkError("Failed to encode request body", "")
+		return nil, nil, NewEncodeError(err)
+	}
+	return email, requestBody, nil
+}
+
+// sendToEndpoints makes one attempt to send the email, starting at the
+// active endpoint and failing over to the next configured base URL while
+// the failures are caused by the endpoint rather than the request
+func (c *HTTPClient) sendToEndpoints(ctx context.Context, config *Config, email *Email, requestBody []byte) (*EmailResponse, error) {
+	baseURLs, probing := c.failover.order(config, c.clock.Now())
+
+	var err error
+	for i, baseURL := range baseURLs {
+		url := baseURL + "/v1/send-email"
+
+		// Respect the client-side request rate limit and pacing
+		if waitErr := c.throttle(ctx); waitErr != nil {
+			return nil, NewNetworkError("Request cancelled: "+waitErr.Error(), url)
+		}
+
+		var response *EmailResponse
+		response, err = c.doAttempt(ctx, config, url, requestBody)
+		for _, hook := range config.PostSend {
+			hook(email, response, err)
+		}
+		if err == nil {
+			c.failover.healthy(config, baseURL, probing, c.clock.Now())
+			return response, nil
+		}
+
+		if !isFailoverError(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		if config.logs(LogLevelError) && i+1 < len(baseURLs) {
+			log.Printf("Poodle API Failover: %s failed, trying %s: %s", baseURL, baseURLs[i+1], err.Error())
+		}
 	}
 
-	// Build URL
-	url := strings.TrimRight(c.config.BaseURL, "/") + "/v1/send-email"
+	return nil, err
+}
 
-	// Create request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+// doAttempt performs a single HTTP request to the send-email endpoint
+func (c *HTTPClient) doAttempt(ctx context.Context, config *Config, url string, requestBody []byte) (*EmailResponse, error) {
+	resp, responseBody, err := c.do(ctx, config, http.MethodPost, url, requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusAccepted { // 202 - Success
+		return c.parseSuccessResponse(config, url, responseBody)
+	}
+
+	return nil, config.PIIMode.scrubError(c.parseErrorResponse(config, resp, responseBody, url, len(requestBody)))
+}
+
+// apiRequest performs a JSON request against an API endpoint other than
+// send-email. The request body is omitted when in is nil, and a successful
+// response is decoded into out unless out is nil.
+func (c *HTTPClient) apiRequest(ctx context.Context, config *Config, method, path string, in, out interface{}) error {
+	var requestBody []byte
+	if in != nil {
+		var err error
+		if requestBody, err = json.Marshal(in); err != nil {
+			return NewEncodeError(err)
+		}
+	}
+
+	url := c.failover.active(config) + path
+
+	// Respect the client-side request rate limit and pacing
+	if err := c.throttle(ctx); err != nil {
+		return NewNetworkError("Request cancelled: "+err.Error(), url)
+	}
+
+	resp, responseBody, err := c.do(ctx, config, method, url, requestBody)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return config.PIIMode.scrubError(c.parseErrorResponse(config, resp, responseBody, url, len(requestBody)))
+	}
+
+	if out != nil {
+		if err := decodeResponseBody(responseBody, out); err != nil {
+			return NewResponseParseError(resp.StatusCode, url, responseBody, err)
+		}
+	}
+
+	return nil
+}
+
+// NewSendRequest returns the request SendEmailContext would make for the
+// email, without making it: the email is validated and prepared and the
+// body, URL and headers are set exactly as for a send. GetBody is set, so
+// the request can be sent more than once. The request timeout is not
+// applied; it is up to whoever sends the request.
+func (c *HTTPClient) NewSendRequest(ctx context.Context, email *Email) (*http.Request, error) {
+	return c.newSendRequest(ctx, c.config, email)
+}
+
+// newSendRequest builds the send-email request using the given
+// configuration snapshot
+func (c *HTTPClient) newSendRequest(ctx context.Context, config *Config, email *Email) (*http.Request, error) {
+	if email == nil {
+		return nil, newNilEmailError()
+	}
+	if strings.TrimSpace(config.APIKey) == "" {
+		return nil, NewAuthenticationErrorWithReason("API key is required", AuthReasonMissingKey)
+	}
+
+	_, requestBody, err := c.prepareSend(ctx, config, email)
+	if err != nil {
+		return nil, err
+	}
+	return newAPIRequest(ctx, config, http.MethodPost, c.failover.active(config)+"/v1/send-email", requestBody)
+}
+
+// newAPIRequest creates a request against the API with the headers of
+// the configuration. The request body is omitted when requestBody is nil.
+func newAPIRequest(ctx context.Context, config *Config, method, url string, requestBody []byte) (*http.Request, error) {
+	var body io.Reader
+	if requestBody != nil {
+		body = bytes.NewReader(requestBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, NewNetworkError("Failed to create request", url)
 	}
 
 	// Set headers
-	req.Header.Set("Content-Type", "application/json")
+	if requestBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
-	req.Header.Set("User-Agent", c.config.GetUserAgent())
+	req.Header.Set("Authorization", "Bearer "+config.APIKey)
+	req.Header.Set("User-Agent", config.GetUserAgent())
+	applyLocale(config, req)
+	applyContextHeaders(config, req)
+	return req, nil
+}
+
+// do performs a single HTTP request against the API and returns the
+// response with its body already read. Transport failures are mapped to
+// NetworkError; the status code is left to the caller.
+func (c *HTTPClient) do(ctx context.Context, config *Config, method, url string, requestBody []byte) (*http.Response, []byte, error) {
+	// Without a key the request can only fail, so don't make it
+	if strings.TrimSpace(config.APIKey) == "" {
+		return nil, nil, NewAuthenticationErrorWithReason("API key is required", AuthReasonMissingKey)
+	}
+
+	// Apply the total request timeout as a per-request deadline
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	ctx, trace := withRequestTrace(ctx, &c.transport)
+
+	req, err := newAPIRequest(ctx, config, method, url, requestBody)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Debug logging
-	if c.config.Debug {
-		log.Printf("Poodle API Request: %s %s", req.Method, req.URL.String())
-		log.Printf("Request Body: %s", string(requestBody))
+	if config.logs(LogLevelTrace) {
+		log.Printf("Poodle API Request Headers:%s", formatHeaders(req.Header))
+		if requestBody != nil {
+			log.Printf("Request Body: %s", config.debugBody(req.Header.Get("Content-Type"), requestBody))
+		}
+	}
+
+	if c.events != nil {
+		c.events.request(ctx, req.Method, url, len(requestBody))
 	}
 
 	// Send request
-	resp, err := c.httpClient.Do(req)
+	started := c.clock.Now()
+	resp, err := c.doRetryingReset(config, req, trace)
+	c.stats.recordRequest(len(requestBody), c.clock.Now().Sub(started))
+	if config.Metrics != nil {
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+			if remaining := headerInt(resp.Header, "ratelimit-remaining"); remaining >= 0 {
+				config.Metrics.ObserveRateLimitRemaining(remaining)
+			}
+		}
+		config.Metrics.ObserveRequest(status, c.clock.Now().Sub(started))
+	}
 	if err != nil {
-		// Handle timeout errors
-		if strings.Contains(err.Error(), "timeout") {
-			timeout := int(c.config.Timeout.Seconds())
-			return nil, NewConnectionTimeoutError(timeout, url)
+		if config.CaptureExchanges {
+			c.captureExchange(config, req, requestBody, nil, nil, err, started, trace.reused())
+		}
+
+		// A security policy violation is reported as it is, not as a
+		// network error, so that it is never retried
+		var policyErr *SecurityPolicyError
+		if errors.As(err, &policyErr) {
+			return nil, nil, policyErr
+		}
+
+		// Handle timeout errors. A failure after the request was written
+		// is marked, so that it is not retried.
+		if timeoutErr := c.timeoutError(config, ctx, trace, err, started, url); timeoutErr != nil {
+			if trace.sent() {
+				timeoutErr.markRequestSent()
+			}
+			return nil, nil, timeoutErr
 		}
-		return nil, NewNetworkError("Request failed: "+err.Error(), url)
+		networkErr := newNetworkErrorKind(networkErrorKind(err), "Request failed: "+err.Error(), url)
+		if trace.sent() {
+			networkErr.markRequestSent()
+		}
+		return nil, nil, networkErr
 	}
 	defer resp.Body.Close()
 
 	// Read response body
+	trace.set(&trace.readingBody)
 	responseBody, err := io.ReadAll(resp.Body)
+	if config.CaptureExchanges {
+		c.captureExchange(config, req, requestBody, resp, responseBody, err, started, trace.reused())
+	}
 	if err != nil {
-		return nil, NewNetworkError("Failed to read response body", url)
+		if timeoutErr := c.timeoutError(config, ctx, trace, err, started, url); timeoutErr != nil {
+			timeoutErr.markRequestSent()
+			return nil, nil, timeoutErr
+		}
+		return nil, nil, NewResponseReadError(resp.StatusCode, url, err)
+	}
+
+	engaged, paced := false, false
+	if c.pacer != nil {
+		engaged, paced = c.observePacing(config, resp.Header)
+	}
+	c.observeRateLimit(config, resp, responseBody, engaged, paced)
+	c.observeDeprecation(config, url, resp.Header)
+	recordRequestID(ctx, resp.Header.Get("X-Request-Id"))
+
+	if c.events != nil {
+		c.events.response(ctx, resp.StatusCode, c.clock.Now().Sub(started), resp.Header.Get("X-Request-Id"), headerInt(resp.Header, "ratelimit-remaining"))
 	}
 
 	// Debug logging
-	if c.config.Debug {
-		log.Printf("Poodle API Response: %d %s", resp.StatusCode, string(responseBody))
+	if config.logs(LogLevelInfo) {
+		log.Printf("%s (conn_reused=%t)", requestLogLine(req, resp.StatusCode, c.clock.Now().Sub(started)), trace.reused())
 	}
+	if config.logs(LogLevelTrace) {
+		log.Printf("Poodle API Response Headers:%s", formatHeaders(resp.Header))
+		log.Printf("Poodle API Response: %d %s", resp.StatusCode, config.debugBody(resp.Header.Get("Content-Type"), responseBody))
+	}
+
+	return resp, responseBody, nil
+}
 
+// parseErrorResponse maps an unsuccessful API response to the matching
+// error type, recording the request ID, the language of the message and
+// the body of responses that are not JSON
+func (c *HTTPClient) parseErrorResponse(config *Config, resp *http.Response, responseBody []byte, url string, requestSize int) error {
+	err := annotateNonJSONError(c.errorForStatus(config, resp, responseBody, url, requestSize), resp, responseBody)
+	return recordContentLanguage(recordErrorRequestID(err, resp.Header), resp.Header)
+}
+
+// errorForStatus maps an unsuccessful API response to an error by its
+// status code. requestSize is the size of the request body.
+func (c *HTTPClient) errorForStatus(config *Config, resp *http.Response, responseBody []byte, url string, requestSize int) error {
 	// Handle different status codes
 	switch resp.StatusCode {
-	case http.StatusAccepted: // 202 - Success
-		return c.parseSuccessResponse(responseBody)
-
 	case http.StatusBadRequest: // 400 - Validation error
-		return nil, c.parseValidationError(responseBody)
+		return c.parseValidationError(responseBody)
 
 	case http.StatusUnauthorized: // 401 - Authentication error
-		return nil, c.parseAuthenticationError(responseBody)
+		return c.parseAuthenticationError(responseBody)
 
 	case http.StatusPaymentRequired: // 402 - Subscription error
-		return nil, c.parseSubscriptionError(responseBody)
+		return c.parseSubscriptionError(responseBody)
 
 	case http.StatusForbidden: // 403 - Account suspended
-		return nil, c.parseAccountSuspendedError(responseBody)
+		return c.parseAccountSuspendedError(responseBody)
 
-	case http.StatusUnprocessableEntity: // 422 - Job queue error
-		return nil, c.parseValidationError(responseBody)
+	case http.StatusRequestEntityTooLarge: // 413 - Request too large
+		return c.parsePayloadTooLargeError(responseBody, requestSize)
+
+	case http.StatusUnprocessableEntity: // 422 - Field errors or job queue error
+		return c.parseUnprocessableError(resp, responseBody)
 
 	case http.StatusTooManyRequests: // 429 - Rate limit
-		return nil, c.parseRateLimitError(resp, responseBody)
+		return c.parseRateLimitError(config, resp, responseBody)
 
 	default:
 		// Generic HTTP error
-		return nil, c.parseGenericError(resp.StatusCode, responseBody, url)
+		return c.parseGenericError(resp.StatusCode, responseBody, url)
+	}
+}
+
+// runPreSend runs the pre-send hooks in order, stopping at the first error.
+// Hook errors are returned as a ValidationError, and the email is validated
+// again since hooks may modify it.
+func runPreSend(hooks []func(*Email) error, email *Email, validator *Validator) error {
+	for _, hook := range hooks {
+		if err := hook(email); err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				return validationErr
+			}
+			return NewValidationError(err.Error(), map[string][]string{
+				"pre_send": {err.Error()},
+			})
+		}
+	}
+
+	return email.validate(validator)
+}
+
+// headerInt returns the integer value of a response header, or -1 if the
+// header is absent or not an integer
+func headerInt(header http.Header, key string) int {
+	value, err := strconv.Atoi(header.Get(key))
+	if err != nil {
+		return -1
 	}
+	return value
 }
 
-// parseSuccessResponse parses a successful API response
-func (c *HTTPClient) parseSuccessResponse(body []byte) (*EmailResponse, error) {
+// parseSuccessResponse parses a successful API response. The email is
+// queued once the API answers 202, so an empty or non-JSON body, as
+// returned by some proxies, is still a success. A body that starts as JSON
+// but is malformed is a ResponseParseError.
+func (c *HTTPClient) parseSuccessResponse(config *Config, url string, body []byte) (*EmailResponse, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return NewEmailResponse(true, "accepted"), nil
+	}
+
 	var response EmailResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, NewNetworkError("Failed to parse response", "")
+	if err := decodeResponseBody(body, &response); err != nil {
+		if looksLikeJSON(body) {
+			return nil, NewResponseParseError(http.StatusAccepted, url, body, err)
+		}
+		if config.logs(LogLevelError) {
+			log.Printf("Poodle API Warning: 202 response body is not JSON, treating the email as accepted: %s", truncate(string(body), 200))
+		}
+		response := NewEmailResponse(true, "accepted")
+		response.RawBody = string(body)
+		return response, nil
 	}
 	return &response, nil
 }
 
+// truncate shortens s to at most n bytes for logging
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
 // parseValidationError parses validation error responses
-func (c *HTTPClient) parseValidationError(body []byte) error {
+func (c *HTTPClient) parseValidationError(body []byte) *ValidationError {
 	var apiResponse struct {
 		Success bool   `json:"success"`
 		Message string `json:"message"`
 		Error   string `json:"error,omitempty"`
 	}
 
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
+	if err := decodeResponseBody(body, &apiResponse); err != nil {
 		return NewValidationError("Validation failed", map[string][]string{
 			"request": {"Invalid request format"},
 		})
@@ -175,17 +615,126 @@ func (c *HTTPClient) parseValidationError(body []byte) error {
 	return NewValidationError(apiResponse.Message, errors)
 }
 
-// parseAuthenticationError parses authentication error responses
+// parseUnprocessableError parses a 422 response. A body listing field
+// errors is a ValidationError; any other 422 means the email could not be
+// queued and is a QueueError.
+func (c *HTTPClient) parseUnprocessableError(resp *http.Response, body []byte) error {
+	var apiResponse struct {
+		Message   string          `json:"message"`
+		Error     string          `json:"error,omitempty"`
+		Reason    string          `json:"reason,omitempty"`
+		Code      string          `json:"code,omitempty"`
+		Retryable *bool           `json:"retryable,omitempty"`
+		Errors    json.RawMessage `json:"errors,omitempty"`
+	}
+
+	if err := decodeResponseBody(body, &apiResponse); err != nil {
+		return NewQueueError("Email could not be queued", "", false, headerInt(resp.Header, "Retry-After"))
+	}
+
+	if fields := parseFieldErrors(apiResponse.Errors); len(fields) > 0 {
+		err := NewValidationError(apiResponse.Message, fields)
+		err.Code = http.StatusUnprocessableEntity
+		return err
+	}
+
+	reason := apiResponse.Reason
+	if reason == "" {
+		reason = apiResponse.Code
+	}
+	if reason == "" {
+		reason = apiResponse.Error
+	}
+
+	retryable := isTransientQueueFailure(apiResponse.Message, reason)
+	if apiResponse.Retryable != nil {
+		retryable = *apiResponse.Retryable
+	}
+
+	return NewQueueError(apiResponse.Message, reason, retryable, headerInt(resp.Header, "Retry-After"))
+}
+
+// parseFieldErrors parses the errors object of an error response, whose
+// fields map to a message or a list of messages
+func parseFieldErrors(raw json.RawMessage) map[string][]string {
+	var fields map[string]json.RawMessage
+	if len(raw) == 0 || json.Unmarshal(raw, &fields) != nil {
+		return nil
+	}
+
+	errors := make(map[string][]string, len(fields))
+	for field, value := range fields {
+		var messages []string
+		var message string
+		switch {
+		case json.Unmarshal(value, &messages) == nil:
+		case json.Unmarshal(value, &message) == nil:
+			messages = []string{message}
+		default:
+			continue
+		}
+		if len(messages) > 0 {
+			errors[field] = messages
+		}
+	}
+	return errors
+}
+
+// transientQueueTerms identify a 422 response caused by a temporary
+// condition of the mail queue
+var transientQueueTerms = []string{
+	"retry", "try again", "unavailable", "temporar", "timed out", "timeout",
+	"overloaded", "busy", "queue is full", "queue full",
+}
+
+// isTransientQueueFailure reports whether the message or reason of a 422
+// response indicates that queueing may succeed later
+func isTransientQueueFailure(message, reason string) bool {
+	text := strings.ToLower(message + " " + strings.ReplaceAll(reason, "_", " "))
+	for _, term := range transientQueueTerms {
+		if strings.Contains(text, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAuthenticationError parses authentication error responses. The
+// reason is taken from the error code, which tells a missing key from an
+// invalid or expired one.
 func (c *HTTPClient) parseAuthenticationError(body []byte) error {
 	var apiResponse struct {
 		Message string `json:"message"`
+		Error   string `json:"error,omitempty"`
+		Code    string `json:"code,omitempty"`
 	}
 
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
+	if err := decodeResponseBody(body, &apiResponse); err != nil {
 		return NewAuthenticationError("Invalid or missing API key")
 	}
 
-	return NewAuthenticationError(apiResponse.Message)
+	code := apiResponse.Code
+	if code == "" {
+		code = apiResponse.Error
+	}
+
+	return NewAuthenticationErrorWithReason(apiResponse.Message, authReason(code))
+}
+
+// authReason maps an API error code such as missing_api_key,
+// invalid_api_key or revoked_api_key to an AuthReason
+func authReason(code string) AuthReason {
+	code = strings.ToLower(code)
+	switch {
+	case strings.Contains(code, "missing"):
+		return AuthReasonMissingKey
+	case strings.Contains(code, "expired"), strings.Contains(code, "revoked"):
+		return AuthReasonExpiredKey
+	case strings.Contains(code, "invalid"), strings.Contains(code, "malformed"):
+		return AuthReasonInvalidKey
+	default:
+		return AuthReasonUnknown
+	}
 }
 
 // parseSubscriptionError parses subscription error responses
@@ -195,7 +744,7 @@ func (c *HTTPClient) parseSubscriptionError(body []byte) error {
 		Error   string `json:"error,omitempty"`
 	}
 
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
+	if err := decodeResponseBody(body, &apiResponse); err != nil {
 		return NewSubscriptionError("Subscription error", "unknown")
 	}
 
@@ -220,32 +769,29 @@ func (c *HTTPClient) parseAccountSuspendedError(body []byte) error {
 		Error   string `json:"error,omitempty"`
 	}
 
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
+	if err := decodeResponseBody(body, &apiResponse); err != nil {
 		return NewAccountSuspendedError("Account suspended", "unknown")
 	}
 
 	return NewAccountSuspendedError(apiResponse.Message, apiResponse.Error)
 }
 
-// parseRateLimitError parses rate limit error responses
-func (c *HTTPClient) parseRateLimitError(resp *http.Response, body []byte) error {
+// parseRateLimitError parses rate limit error responses. The Retry-After
+// and ratelimit-reset headers are corrected for the skew between the local
+// and server clocks and capped at the configured maximum wait.
+func (c *HTTPClient) parseRateLimitError(config *Config, resp *http.Response, body []byte) error {
 	var apiResponse struct {
 		Message string `json:"message"`
 		Error   string `json:"error,omitempty"`
 	}
 
-	// Parse response body
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return NewNetworkError("Failed to parse rate limit response", "")
-	}
+	// The headers carry the limits, so a body that is not JSON, as sent
+	// by some proxies, still makes a RateLimitError
+	_ = decodeResponseBody(body, &apiResponse)
 
 	// Extract rate limit information from headers
-	retryAfter := 0
-	if retryAfterStr := resp.Header.Get("retry-after"); retryAfterStr != "" {
-		if val, err := strconv.Atoi(retryAfterStr); err == nil {
-			retryAfter = val
-		}
-	}
+	timing := readRateLimitTiming(config, resp.Header, c.clock.Now())
+	retryAfter := int((timing.retryAfter + time.Second - 1) / time.Second)
 
 	limit := 0
 	if limitStr := resp.Header.Get("ratelimit-limit"); limitStr != "" {
@@ -273,7 +819,37 @@ func (c *HTTPClient) parseRateLimitError(resp *http.Response, body []byte) error
 		message = fmt.Sprintf("Rate limit exceeded. Retry after %d seconds.", retryAfter)
 	}
 
-	return NewRateLimitError(message, retryAfter, limit, remaining, reset)
+	err := NewRateLimitError(message, retryAfter, limit, remaining, reset)
+	err.clock = c.clock
+	err.ResetIn = timing.reset
+	err.Skew = timing.skew
+	if timing.reset > 0 {
+		err.ContextMap["reset_in"] = timing.reset
+	}
+	if timing.skew != 0 {
+		err.ContextMap["clock_skew"] = timing.skew
+	}
+	return err
+}
+
+// parsePayloadTooLargeError parses 413 error responses, taking the API's
+// limit from a limit or max_size field if the body has one
+func (c *HTTPClient) parsePayloadTooLargeError(body []byte, requestSize int) error {
+	var apiResponse struct {
+		Message string `json:"message"`
+		Limit   int    `json:"limit"`
+		MaxSize int    `json:"max_size"`
+	}
+	decodeResponseBody(body, &apiResponse)
+
+	limit := apiResponse.Limit
+	if limit <= 0 {
+		limit = apiResponse.MaxSize
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	return NewPayloadTooLargeError(apiResponse.Message, requestSize, limit)
 }
 
 // parseGenericError parses generic HTTP error responses
@@ -284,7 +860,7 @@ func (c *HTTPClient) parseGenericError(statusCode int, body []byte, url string)
 	}
 
 	message := fmt.Sprintf("HTTP %d error", statusCode)
-	if err := json.Unmarshal(body, &apiResponse); err == nil && apiResponse.Message != "" {
+	if err := decodeResponseBody(body, &apiResponse); err == nil && apiResponse.Message != "" {
 		message = apiResponse.Message
 	}
 
@@ -0,0 +1,81 @@
+package poodle
+
+import (
+	"context"
+	"strings"
+)
+
+// WithDuplicateRecipients makes SendIndividually send one email per entry
+// of its recipient list, even if an address is listed more than once. By
+// default duplicates, compared case-insensitively, get a single email.
+func WithDuplicateRecipients() BatchOption {
+	return func(o *batchOptions) {
+		o.keepDuplicates = true
+	}
+}
+
+// WithRecipientVariables sets the template variables of each recipient of
+// SendIndividually, keyed by address. Addresses are matched
+// case-insensitively, as duplicates are, preferring a key of the same
+// case. They take precedence over the variables of the base email.
+func WithRecipientVariables(variables map[string]map[string]string) BatchOption {
+	return func(o *batchOptions) {
+		o.recipientVariables = variables
+	}
+}
+
+// SendIndividually sends a copy of base to each recipient, so that no
+// recipient sees the others, as SendAll does with the same options. The
+// To address of base is replaced by the recipient, which may have a
+// display name, and the {{variable}} tokens of each copy are filled as by
+// Recipient.Apply, from {{email}}, the recipient's address, the variables
+// set with WithRecipientVariables and those of base.
+//
+// It returns a result per recipient, in the order of the list, with
+// SendResult.Recipient set to the address. An address listed more than
+// once is sent to once unless WithDuplicateRecipients is set. A failed
+// recipient does not stop the others unless WithFailureMode says
+// otherwise; if any failed the error is a *MultiError.
+func (c *Client) SendIndividually(ctx context.Context, base *Email, recipients []string, opts ...BatchOption) ([]SendResult, error) {
+	if base == nil {
+		return nil, newNilEmailError()
+	}
+	options := newBatchOptions(opts)
+
+	var addresses []Address
+	seen := make(map[string]bool, len(recipients))
+	for _, recipient := range recipients {
+		address := addressOf(recipient)
+		key := strings.ToLower(address.Email)
+		if seen[key] && !options.keepDuplicates {
+			continue
+		}
+		seen[key] = true
+		addresses = append(addresses, address)
+	}
+
+	recipientVariables := make(map[string]map[string]string, len(options.recipientVariables))
+	for key, variables := range options.recipientVariables {
+		recipientVariables[strings.ToLower(addressOf(key).Email)] = variables
+	}
+
+	emails := make([]*Email, len(addresses))
+	for i, address := range addresses {
+		recipient, ok := options.recipientVariables[address.Email]
+		if !ok {
+			recipient = recipientVariables[strings.ToLower(address.Email)]
+		}
+
+		variables := map[string]string{"email": address.Email}
+		for name, value := range recipient {
+			variables[name] = value
+		}
+		emails[i] = Recipient{Email: address.Email, Name: address.Name, Variables: variables}.Apply(base)
+	}
+
+	results, _ := c.SendAll(ctx, emails, opts...)
+	for i := range results {
+		results[i].Recipient = addresses[i].Email
+	}
+	return results, newMultiError(results)
+}
@@ -0,0 +1,405 @@
+package poodle
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// DefaultMaxInlineImageSize is the size of the largest image InlineAssets
+// embeds unless InlineOptions.MaxImageSize says otherwise
+const DefaultMaxInlineImageSize = 64 * 1024
+
+// InlineOptions configures Email.InlineAssets
+type InlineOptions struct {
+	// FS resolves the relative paths of linked stylesheets and images,
+	// e.g. os.DirFS("templates") or an embed.FS. Without it, only style
+	// blocks are inlined.
+	FS fs.FS
+	// MaxImageSize is the size in bytes of the largest image embedded.
+	// Larger images are left as they are and reported. Zero uses
+	// DefaultMaxInlineImageSize.
+	MaxImageSize int
+}
+
+// UnresolvedAssetsError is returned by InlineAssets when stylesheets or
+// images could not be inlined. The rest of the HTML body is inlined.
+type UnresolvedAssetsError struct {
+	BaseError
+	// References lists each reference with the reason it was not
+	// inlined, e.g. "images/hero.png: file does not exist"
+	References []string
+}
+
+func NewUnresolvedAssetsError(references []string) *UnresolvedAssetsError {
+	return &UnresolvedAssetsError{
+		BaseError: BaseError{
+			Message: fmt.Sprintf("%d assets could not be inlined: %s", len(references), strings.Join(references, "; ")),
+			Code:    0, // Nothing was sent
+			ContextMap: map[string]interface{}{
+				"error_type": "unresolved_assets",
+				"references": references,
+			},
+		},
+		References: references,
+	}
+}
+
+// inlineSkippedElements never receive inlined styles
+var inlineSkippedElements = map[string]bool{
+	"html": true, "head": true, "title": true, "meta": true, "link": true,
+	"style": true, "script": true, "base": true, "noscript": true,
+}
+
+// voidElements have no end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// InlineAssets prepares the HTML body for email clients, which strip
+// stylesheets and block images:
+//
+//   - the rules of style blocks and of stylesheets linked with
+//     <link rel="stylesheet"> are copied into the style attributes of the
+//     elements they match, respecting specificity, source order and
+//     !important. Rules that cannot be inlined, such as media queries and
+//     selectors with pseudo-classes, are kept in a style block in place of
+//     the original.
+//   - images with a relative src are embedded as data: URIs
+//
+// Stylesheets and images are read from opts.FS. Remote stylesheets, files
+// that cannot be read and images over opts.MaxImageSize are left in place
+// and reported in an UnresolvedAssetsError, after the rest is inlined.
+// Inlining an inlined body changes nothing. If the inlined body exceeds
+// MaxContentSize, a ValidationError is returned and the email is
+// unchanged.
+func (e *Email) InlineAssets(opts InlineOptions) error {
+	if !e.HasHTML() {
+		return nil
+	}
+	if opts.MaxImageSize <= 0 {
+		opts.MaxImageSize = DefaultMaxInlineImageSize
+	}
+
+	inlined, unresolved := inlineAssets(e.HTML, opts)
+
+	if len(inlined) > MaxContentSize {
+		tagged := e.clone()
+		tagged.HTML = inlined
+		return withSizeReport(NewValidationError("Email validation failed", map[string][]string{
+			"html": {contentSizeMessage("HTML", len(inlined), "after inlining assets")},
+		}), tagged)
+	}
+
+	e.HTML = inlined
+	if len(unresolved) > 0 {
+		return NewUnresolvedAssetsError(unresolved)
+	}
+	return nil
+}
+
+// htmlToken is a piece of HTML markup: text, a comment or declaration, or
+// a tag. The content of raw text elements such as style follows their
+// start tag in content and closing.
+type htmlToken struct {
+	raw     string
+	tag     *htmlTag
+	content string
+	closing string
+}
+
+// tokenizeHTML splits markup into tokens, using the tag parser of
+// SanitizeHTML
+func tokenizeHTML(s string) []htmlToken {
+	var tokens []htmlToken
+	text := func(t string) {
+		if t != "" {
+			tokens = append(tokens, htmlToken{raw: t})
+		}
+	}
+
+	for i := 0; i < len(s); {
+		lt := strings.IndexByte(s[i:], '<')
+		if lt < 0 {
+			text(s[i:])
+			break
+		}
+		text(s[i : i+lt])
+		i += lt
+
+		if strings.HasPrefix(s[i:], "<!--") {
+			next := len(s)
+			if end := strings.Index(s[i+4:], "-->"); end >= 0 {
+				next = i + 4 + end + 3
+			}
+			text(s[i:next])
+			i = next
+			continue
+		}
+		if strings.HasPrefix(s[i:], "<!") || strings.HasPrefix(s[i:], "<?") {
+			next := len(s)
+			if end := strings.IndexByte(s[i:], '>'); end >= 0 {
+				next = i + end + 1
+			}
+			text(s[i:next])
+			i = next
+			continue
+		}
+
+		tag, next, ok := parseTag(s, i)
+		if !ok {
+			// A '<' that does not start a tag is text
+			text("<")
+			i++
+			continue
+		}
+		if tag.unterminated {
+			text(s[i:])
+			break
+		}
+		i = next
+
+		token := htmlToken{raw: tag.raw, tag: &tag}
+		if !tag.end && rawTextElements[tag.name] {
+			token.content, token.closing, i = rawText(s, i, tag.name)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// inlineAssets returns the markup with its assets inlined and the
+// references that could not be resolved
+func inlineAssets(s string, opts InlineOptions) (string, []string) {
+	tokens := tokenizeHTML(s)
+	var unresolved []string
+
+	// Collect the stylesheets in document order. replacements holds the
+	// markup that replaces each inlined style or link token.
+	var rules []cssRule
+	replacements := make(map[int]string)
+	for i, token := range tokens {
+		if token.tag == nil || token.tag.end {
+			continue
+		}
+
+		var css string
+		switch {
+		case token.tag.name == "style" && isInlinableMedia(token.tag):
+			css = token.content
+		case token.tag.name == "link" && isStylesheetLink(token.tag):
+			href := tagAttr(token.tag, "href")
+			data, err := readAsset(opts.FS, href)
+			if err != nil {
+				unresolved = append(unresolved, href+": "+err.Error())
+				continue
+			}
+			css = string(data)
+		default:
+			continue
+		}
+
+		sheetRules, kept := parseStylesheet(css, len(rules))
+		rules = append(rules, sheetRules...)
+		replacements[i] = ""
+		if len(kept) > 0 {
+			open := "<style>"
+			if token.tag.name == "style" {
+				open = token.tag.raw
+			}
+			replacements[i] = open + "\n" + strings.Join(kept, "\n") + "\n</style>"
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	var path []cssElement
+	for i, token := range tokens {
+		if replacement, ok := replacements[i]; ok {
+			b.WriteString(replacement)
+			continue
+		}
+		tag := token.tag
+		if tag == nil {
+			b.WriteString(token.raw)
+			continue
+		}
+		if tag.end {
+			for j := len(path) - 1; j >= 0; j-- {
+				if path[j].name == tag.name {
+					path = path[:j]
+					break
+				}
+			}
+			b.WriteString(token.raw)
+			continue
+		}
+
+		element := newCSSElement(*tag)
+		elementPath := append(path, element)
+		if !voidElements[tag.name] && !tag.selfClosing && token.closing == "" && !rawTextElements[tag.name] {
+			path = elementPath
+		}
+
+		attrs := tag.attrs
+		changed := false
+		if !inlineSkippedElements[tag.name] {
+			if declarations := cascade(rules, elementPath, element.attrs["style"]); declarations != nil {
+				attrs = setTagAttr(attrs, "style", formatDeclarations(declarations))
+				changed = true
+			}
+		}
+		if tag.name == "img" {
+			if src, ok := element.attrs["src"]; ok && isLocalAsset(src) {
+				if uri, err := dataURI(opts, src); err != nil {
+					unresolved = append(unresolved, src+": "+err.Error())
+				} else {
+					attrs = setTagAttr(attrs, "src", uri)
+					changed = true
+				}
+			}
+		}
+
+		if changed {
+			b.WriteString(tag.rebuild(attrs))
+		} else {
+			b.WriteString(token.raw)
+		}
+		b.WriteString(token.content)
+		b.WriteString(token.closing)
+	}
+
+	return b.String(), unresolved
+}
+
+// isInlinableMedia reports whether a style block applies to screens, so
+// that its rules may be inlined
+func isInlinableMedia(tag *htmlTag) bool {
+	media := strings.ToLower(strings.TrimSpace(tagAttr(tag, "media")))
+	return media == "" || media == "all" || media == "screen"
+}
+
+// isStylesheetLink reports whether a link tag links a stylesheet for
+// screens
+func isStylesheetLink(tag *htmlTag) bool {
+	for _, rel := range strings.Fields(strings.ToLower(tagAttr(tag, "rel"))) {
+		if rel == "stylesheet" {
+			return isInlinableMedia(tag)
+		}
+	}
+	return false
+}
+
+// tagAttr returns the decoded value of the first attribute with the name
+func tagAttr(tag *htmlTag, name string) string {
+	for _, attr := range tag.attrs {
+		if attr.name == name {
+			return attr.value
+		}
+	}
+	return ""
+}
+
+// setTagAttr returns the attributes with the value of the first attribute
+// with the name replaced, or with the attribute appended
+func setTagAttr(attrs []htmlAttr, name, value string) []htmlAttr {
+	attr := htmlAttr{
+		name:     name,
+		value:    value,
+		rawValue: attrEscaper.Replace(value),
+		quote:    '"',
+		hasValue: true,
+	}
+	attr.raw = " " + name + `="` + attr.rawValue + `"`
+
+	updated := make([]htmlAttr, 0, len(attrs)+1)
+	replaced := false
+	for _, existing := range attrs {
+		if existing.name == name && !replaced {
+			updated = append(updated, attr)
+			replaced = true
+		} else {
+			updated = append(updated, existing)
+		}
+	}
+	if !replaced {
+		updated = append(updated, attr)
+	}
+	return updated
+}
+
+// attrEscaper escapes a value for a double-quoted attribute
+var attrEscaper = strings.NewReplacer("&", "&amp;", `"`, "&quot;")
+
+// isLocalAsset reports whether a reference is a relative path rather than
+// a URL, data: URI or cid: reference
+func isLocalAsset(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "//") || strings.HasPrefix(ref, "#") || strings.Contains(ref, "{{") {
+		return false
+	}
+	if colon := strings.IndexByte(ref, ':'); colon >= 0 && !strings.ContainsAny(ref[:colon], "/?#") {
+		return false
+	}
+	return true
+}
+
+// readAsset reads a referenced file from the file system
+func readAsset(fsys fs.FS, ref string) ([]byte, error) {
+	if !isLocalAsset(ref) {
+		return nil, errors.New("not a local file")
+	}
+	if fsys == nil {
+		return nil, errors.New("no file system to read it from")
+	}
+
+	name := strings.TrimSpace(ref)
+	if cut := strings.IndexAny(name, "?#"); cut >= 0 {
+		name = name[:cut]
+	}
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if !fs.ValidPath(name) {
+		return nil, errors.New("path is outside the file system")
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fs.ErrNotExist
+		}
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return nil, pathErr.Err
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// dataURI returns the image as a data: URI
+func dataURI(opts InlineOptions, src string) (string, error) {
+	data, err := readAsset(opts.FS, src)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > opts.MaxImageSize {
+		return "", fmt.Errorf("image is larger than %s", formatSize(opts.MaxImageSize))
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(strings.SplitN(src, "?", 2)[0]))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	contentType, _, _ = mime.ParseMediaType(contentType)
+	if !strings.HasPrefix(contentType, "image/") || contentType == "image/svg+xml" {
+		return "", fmt.Errorf("%s is not an image that can be embedded", contentType)
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,180 @@
+package poodle
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestInlineAssetsGolden(t *testing.T) {
+	template, err := os.ReadFile(filepath.Join("testdata", "inline", "template.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Welcome", string(template))
+	err = email.InlineAssets(InlineOptions{FS: os.DirFS(filepath.Join("testdata", "inline"))})
+
+	var unresolved *UnresolvedAssetsError
+	if !errors.As(err, &unresolved) {
+		t.Fatalf("Expected an UnresolvedAssetsError, got %v", err)
+	}
+	expected := []string{
+		"https://fonts.example.com/css?family=Inter: not a local file",
+		"images/missing.png: file does not exist",
+	}
+	if !reflect.DeepEqual(unresolved.References, expected) {
+		t.Errorf("Expected unresolved references %v, got %v", expected, unresolved.References)
+	}
+
+	golden := filepath.Join("testdata", "inline", "template.golden.html")
+	if *update {
+		if err := os.WriteFile(golden, []byte(email.HTML), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email.HTML != string(want) {
+		t.Errorf("Inlined HTML differs from %s\nwant:\n%s\ngot:\n%s", golden, want, email.HTML)
+	}
+
+	// Inlining again changes nothing
+	again := email.clone()
+	again.InlineAssets(InlineOptions{FS: os.DirFS(filepath.Join("testdata", "inline"))})
+	if again.HTML != email.HTML {
+		t.Errorf("Expected inlining to be idempotent, got:\n%s", again.HTML)
+	}
+}
+
+func TestInlineAssetsCascade(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			"specificity over order",
+			`<style>#x { color: red } .a { color: blue } p { color: green }</style><p id="x" class="a">Hi</p>`,
+			`<p id="x" class="a" style="color: red">Hi</p>`,
+		},
+		{
+			"later rule wins",
+			`<style>p { color: red } p { color: blue }</style><p>Hi</p>`,
+			`<p style="color: blue">Hi</p>`,
+		},
+		{
+			"inline style wins",
+			`<style>p { color: red; margin: 0 }</style><p style="color:blue">Hi</p>`,
+			`<p style="margin: 0; color: blue">Hi</p>`,
+		},
+		{
+			"important beats inline",
+			`<style>p { color: red !important }</style><p style="color: blue">Hi</p>`,
+			`<p style="color: red !important">Hi</p>`,
+		},
+		{
+			"shorthand order",
+			`<style>.a { margin-top: 5px } p { margin: 0 }</style><p class="a">Hi</p>`,
+			`<p class="a" style="margin: 0; margin-top: 5px">Hi</p>`,
+		},
+		{
+			"child and descendant",
+			`<style>div > p { color: red } div span { color: blue }</style><div><section><p><span>a</span></p></section><p>b</p></div>`,
+			`<div><section><p><span style="color: blue">a</span></p></section><p style="color: red">b</p></div>`,
+		},
+		{
+			"attribute selectors",
+			`<style>[align=center] { text-align: center } a[target] { color: red }</style><td align="center"><a target="_blank" href="#">x</a><a href="#">y</a></td>`,
+			`<td align="center" style="text-align: center"><a target="_blank" href="#" style="color: red">x</a><a href="#">y</a></td>`,
+		},
+		{
+			"kept rules",
+			`<style media="screen">a:hover, a { color: red } @media (max-width: 600px) { a { color: blue } }</style><a>x</a>`,
+			"<style media=\"screen\">\na:hover { color: red }\n@media (max-width: 600px) { a { color: blue } }\n</style><a style=\"color: red\">x</a>",
+		},
+		{
+			"print styles",
+			`<style media="print">a { color: red }</style><a>x</a>`,
+			`<style media="print">a { color: red }</style><a>x</a>`,
+		},
+		{
+			"escaped values",
+			`<style>p { font-family: "Helvetica Neue", Arial }</style><p>x</p>`,
+			`<p style="font-family: &quot;Helvetica Neue&quot;, Arial">x</p>`,
+		},
+		{
+			"no rules",
+			`<p STYLE = 'color:red'>unchanged</p>`,
+			`<p STYLE = 'color:red'>unchanged</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		email := NewHTMLEmail("from@example.com", "to@example.com", "Hello", tt.html)
+		if err := email.InlineAssets(InlineOptions{}); err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+		}
+		if email.HTML != tt.expected {
+			t.Errorf("%s:\nexpected %s\ngot      %s", tt.name, tt.expected, email.HTML)
+		}
+
+		again := email.clone()
+		again.InlineAssets(InlineOptions{})
+		if again.HTML != email.HTML {
+			t.Errorf("%s: expected idempotence, got %s", tt.name, again.HTML)
+		}
+	}
+}
+
+func TestInlineAssetsImages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"small.gif":  {Data: []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")},
+		"large.png":  {Data: append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 200)...)},
+		"logo.svg":   {Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)},
+		"notes.txt":  {Data: []byte("hello")},
+		"styles.css": {Data: []byte("img { border: 0 }")},
+	}
+
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Hello",
+		`<img src="./small.gif"><img src="large.png"><img src="logo.svg"><img src="notes.txt"><img src="../secret.png"><img src="cid:logo"><img src="{{avatar}}">`)
+	err := email.InlineAssets(InlineOptions{FS: fsys, MaxImageSize: 100})
+
+	if !strings.Contains(email.HTML, `<img src="data:image/gif;base64,R0lGODlhAQABAAAAADs=">`) {
+		t.Errorf("Expected the small image to be embedded, got %s", email.HTML)
+	}
+	if !strings.Contains(email.HTML, `<img src="large.png">`) || !strings.Contains(email.HTML, `<img src="cid:logo"><img src="{{avatar}}">`) {
+		t.Errorf("Expected other images to be left alone, got %s", email.HTML)
+	}
+
+	var unresolved *UnresolvedAssetsError
+	if !errors.As(err, &unresolved) || len(unresolved.References) != 4 {
+		t.Fatalf("Expected 4 unresolved images, got %v", err)
+	}
+	for i, prefix := range []string{"large.png: image is larger than", "logo.svg: image/svg+xml is not", "notes.txt: text/plain is not", "../secret.png: path is outside"} {
+		if !strings.HasPrefix(unresolved.References[i], prefix) {
+			t.Errorf("Expected %q, got %q", prefix, unresolved.References[i])
+		}
+	}
+}
+
+func TestInlineAssetsSizeLimit(t *testing.T) {
+	fsys := fstest.MapFS{"logo.png": {Data: append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 1000)...)}}
+	html := `<img src="logo.png"><p>` + strings.Repeat("x", MaxContentSize-30) + `</p>`
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Hello", html)
+
+	err := email.InlineAssets(InlineOptions{FS: fsys})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Context()["size_report"] == nil {
+		t.Fatalf("Expected a ValidationError with a size report, got %v", err)
+	}
+	if email.HTML != html {
+		t.Error("Expected the email to be unchanged")
+	}
+}
@@ -0,0 +1,57 @@
+package poodle
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// doRetryingReset performs the request and repeats it once if it failed on
+// a stale keep-alive connection (see staleConnectionRetryPolicy), in
+// serverless mode as otherwise. The repeats are counted in
+// ClientStats.StaleConnectionRetries. Request bodies are byte slices, so
+// the body can be replayed.
+func (c *HTTPClient) doRetryingReset(config *Config, req *http.Request, trace *requestTrace) (*http.Response, error) {
+	return DoerWithRetry(c.httpClient, c.staleConnectionRetryPolicy(config, trace)).Do(req)
+}
+
+// staleConnectionRetryPolicy repeats a request once, immediately, if it
+// failed on a connection reused from the idle pool because the server had
+// closed it while it sat idle, which the transport only notices once the
+// request is written. The request is repeated whether or not retries are
+// enabled: a server that closed the connection while it was idle never
+// read the request. A reset can also come after the server read the
+// request, in which case the repeat sends it twice; this is rare, as the
+// API answers sends quickly, and DedupeWindow guards against it. A reset on
+// a new connection, or a response cut off with an EOF, may come after the
+// API processed the send, so it is not repeated. The other idle
+// connections are closed first, as they are likely stale too.
+func (c *HTTPClient) staleConnectionRetryPolicy(config *Config, trace *requestTrace) DoerRetryPolicy {
+	return DoerRetryPolicy{
+		MaxRetries: 1,
+		ShouldRetry: func(resp *http.Response, err error) bool {
+			return err != nil && trace.reused() && isStaleConnection(err)
+		},
+		OnRetry: func(req *http.Request, attempt int, resp *http.Response, err error) {
+			if closer, ok := c.httpClient.(interface{ CloseIdleConnections() }); ok {
+				closer.CloseIdleConnections()
+			}
+			c.stats.staleRetries.Add(1)
+			if config.logs(LogLevelInfo) {
+				log.Printf("Poodle API: idle connection was closed by the server, retrying %s %s on a new connection: %s", req.Method, req.URL.Redacted(), err.Error())
+			}
+		},
+	}
+}
+
+// isStaleConnection reports whether err means the server reset a
+// connection the client still considered open, as happens when a
+// keep-alive connection outlives a frozen serverless environment, or told
+// an HTTP/2 client to stop using it
+func isStaleConnection(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		strings.Contains(err.Error(), "connection reset by peer") ||
+		strings.Contains(err.Error(), "http2: server sent GOAWAY")
+}
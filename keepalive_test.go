@@ -0,0 +1,151 @@
+package poodle
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+// staleConnServer is an HTTP/1.1 server that answers the first request on
+// each connection, keeping the connection open, and resets the connection
+// when a later request arrives, as a server that closed an idle keep-alive
+// connection does. With resetFirst it resets every connection on the
+// first request instead.
+func staleConnServer(t *testing.T, resetFirst bool) (url string, accepted *int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	accepted = new(int32)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(accepted, 1)
+			go serveStaleConn(conn.(*net.TCPConn), resetFirst)
+		}
+	}()
+	return "http://" + listener.Addr().String(), accepted
+}
+
+func serveStaleConn(conn *net.TCPConn, resetFirst bool) {
+	reader := bufio.NewReader(conn)
+	for n := 1; ; n++ {
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		_, _ = io.Copy(io.Discard, req.Body)
+		if n > 1 || resetFirst {
+			// Close with an RST rather than a FIN
+			_ = conn.SetLinger(0)
+			conn.Close()
+			return
+		}
+		body := `{"success": true, "message": "Email queued"}`
+		fmt.Fprintf(conn, "HTTP/1.1 202 Accepted\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	}
+}
+
+func TestStaleConnectionRetry(t *testing.T) {
+	url, accepted := staleConnServer(t, false)
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = url
+	client := NewClientWithConfig(config)
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+			t.Fatalf("Expected send %d to succeed on a new connection, got %v", i+1, err)
+		}
+	}
+
+	stats := client.Stats()
+	if stats.StaleConnectionRetries != 2 || stats.Retries != 0 || stats.Requests != 3 {
+		t.Errorf("Expected 2 silent retries of 3 requests, got %+v", stats)
+	}
+	if atomic.LoadInt32(accepted) != 3 {
+		t.Errorf("Expected every send to end on a new connection, got %d connections", atomic.LoadInt32(accepted))
+	}
+}
+
+func TestStaleConnectionRetryOnlyForReusedConnections(t *testing.T) {
+	url, accepted := staleConnServer(t, true)
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = url
+	client := NewClientWithConfig(config)
+	defer client.Close()
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	var networkErr *NetworkError
+	if !errors.As(err, &networkErr) {
+		t.Fatalf("Expected NetworkError, got %v", err)
+	}
+	if stats := client.Stats(); stats.StaleConnectionRetries != 0 || atomic.LoadInt32(accepted) != 1 {
+		t.Errorf("Expected a reset on a new connection not to be retried, got %d retries and %d connections", stats.StaleConnectionRetries, atomic.LoadInt32(accepted))
+	}
+}
+
+func TestRetryStaleConnection(t *testing.T) {
+	var calls int32
+	var failWith error
+	client := NewClient("test_api_key", WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		if body, _ := io.ReadAll(req.Body); string(body) != "payload" {
+			t.Errorf("Expected the body to be replayed, got %q", body)
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			gotConn(req, true)
+			return nil, failWith
+		}
+		gotConn(req, false)
+		return acceptedResponse(), nil
+	})))
+	config := client.snapshotConfig()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		err   error
+		retry bool
+	}{
+		{"reset", context.Background(), &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"reset message", context.Background(), &url.Error{Op: "Post", Err: errors.New("read tcp: connection reset by peer")}, true},
+		{"GOAWAY", context.Background(), errors.New(`http2: server sent GOAWAY and closed the connection; LastStreamID=1, ErrCode=NO_ERROR, debug=""`), true},
+		{"EOF", context.Background(), &url.Error{Op: "Post", Err: io.EOF}, false},
+		{"other error", context.Background(), &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, false},
+		{"deadline passed", cancelled, syscall.ECONNRESET, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			failWith = tt.err
+			ctx, trace := withRequestTrace(tt.ctx, &client.httpClient.transport)
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.example.com/v1/send", strings.NewReader("payload"))
+
+			resp, err := client.httpClient.doRetryingReset(config, req, trace)
+			if tt.retry != (calls == 2) || tt.retry != (err == nil && resp != nil) {
+				t.Errorf("Expected retry=%v, got %d calls and %v", tt.retry, calls, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package poodle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces requests evenly so that no more than the configured
+// number of requests per second are started
+type rateLimiter struct {
+	interval time.Duration
+	clock    Clock
+	mutex    sync.Mutex
+	next     time.Time
+	// shared is true for the limiters of a SharedLimiter, whose clock is
+	// not replaced by WithClock
+	shared bool
+}
+
+// SharedLimiter is a client-side rate limit and adaptive pacing state
+// shared by several clients, e.g. one client per tenant of a single Poodle
+// account. It is safe for concurrent use.
+type SharedLimiter struct {
+	limiter *rateLimiter
+	pacer   *rateLimiter
+}
+
+// NewSharedLimiter creates a limiter that lets the clients using it start
+// at most rps requests per second between them. A non-positive rps applies
+// no request limit and only shares the adaptive pacing state.
+func NewSharedLimiter(rps float64) *SharedLimiter {
+	shared := &SharedLimiter{pacer: &rateLimiter{clock: realClock{}, shared: true}}
+	if limiter := newRateLimiter(rps, realClock{}); limiter != nil {
+		limiter.shared = true
+		shared.limiter = limiter
+	}
+	return shared
+}
+
+// WithSharedLimiter makes the client wait for the shared limiter before
+// each request, in addition to its own MaxRequestsPerSecond. With
+// AdaptivePacing enabled, the client paces its requests with the shared
+// state, which the rate-limit headers of every response to a client using
+// the limiter update. A nil limiter is ignored.
+func WithSharedLimiter(shared *SharedLimiter) Option {
+	return func(c *Client) {
+		if shared == nil {
+			return
+		}
+		c.httpClient.shared = shared.limiter
+		if c.httpClient.pacer != nil {
+			c.httpClient.pacer = shared.pacer
+		}
+	}
+}
+
+// newRateLimiter creates a rate limiter for the given requests per second.
+// It returns nil when rps is not positive, meaning no limit is applied.
+func newRateLimiter(rps float64, clock Clock) *rateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / rps),
+		clock:    clock,
+	}
+}
+
+// Wait blocks until the next request may be started or the context is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mutex.Lock()
+	now := l.clock.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mutex.Unlock()
+
+	if err := l.clock.Sleep(ctx, wait); err != nil {
+		// Give the reserved slot back so cancelled callers don't slow others down
+		l.mutex.Lock()
+		l.next = l.next.Add(-l.interval)
+		l.mutex.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// currentInterval returns the spacing between requests
+func (l *rateLimiter) currentInterval() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.interval
+}
+
+// setInterval changes the spacing between requests. The next request is
+// delayed by the new interval from now unless it is already later.
+func (l *rateLimiter) setInterval(interval time.Duration) (previous time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	previous = l.interval
+	l.interval = interval
+	if next := l.clock.Now().Add(interval); next.After(l.next) {
+		l.next = next
+	}
+	return previous
+}
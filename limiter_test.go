@@ -0,0 +1,118 @@
+package poodle
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSharedLimiterAcrossClients(t *testing.T) {
+	var mutex sync.Mutex
+	var starts []time.Time
+	doer := mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		starts = append(starts, time.Now())
+		mutex.Unlock()
+		return acceptedResponse(), nil
+	})
+
+	shared := NewSharedLimiter(100)
+	var clients []*Client
+	for i := 0; i < 3; i++ {
+		clients = append(clients, NewClient("test_api_key", WithHTTPDoer(doer), WithSharedLimiter(shared)))
+	}
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(client *Client) {
+				defer wg.Done()
+				if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+			}(client)
+		}
+	}
+	wg.Wait()
+
+	if len(starts) != 30 {
+		t.Fatalf("Expected 30 requests, got %d", len(starts))
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	// The nth request may start no earlier than n intervals after the
+	// first, allowing for scheduling delays of the first request
+	const interval, tolerance = 10 * time.Millisecond, 5 * time.Millisecond
+	for n := 1; n < len(starts); n++ {
+		if elapsed := starts[n].Sub(starts[0]); elapsed < time.Duration(n)*interval-tolerance {
+			t.Fatalf("Expected request %d to start at least %s after the first, got %s", n, time.Duration(n)*interval, elapsed)
+		}
+	}
+}
+
+func TestSharedLimiterPacing(t *testing.T) {
+	shared := NewSharedLimiter(0)
+	clock := newTestClock()
+	shared.pacer.clock = clock
+
+	newPacedClient := func(adaptive bool, remaining string) *Client {
+		config := NewConfig()
+		config.APIKey = "test_api_key"
+		config.AdaptivePacing = adaptive
+		return NewClientWithConfig(config, WithSharedLimiter(shared), WithClock(clock), WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusAccepted,
+				Header: http.Header{
+					"Ratelimit-Remaining": {remaining},
+					"Ratelimit-Reset":     {"10"},
+				},
+				Body: io.NopCloser(strings.NewReader(`{"success": true, "message": "Email queued"}`)),
+			}, nil
+		})))
+	}
+	low := newPacedClient(true, "2")
+	other := newPacedClient(true, "100")
+	unpaced := newPacedClient(false, "100")
+
+	if shared.pacer.clock != Clock(clock) {
+		t.Fatal("Expected WithClock to leave the shared clock alone")
+	}
+
+	// The headers of a response to one client pace the other
+	send := func(client *Client) {
+		t.Helper()
+		if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	send(low)
+	send(unpaced)
+	if sleeps := clock.Sleeps(); len(sleeps) != 0 {
+		t.Errorf("Expected a client without AdaptivePacing not to wait, got %v", sleeps)
+	}
+	send(other)
+	if sleeps := clock.Sleeps(); len(sleeps) != 1 || sleeps[0] != 5*time.Second {
+		t.Errorf("Expected the other client to wait 5s, got %v", sleeps)
+	}
+}
+
+func TestSharedLimiterNil(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AdaptivePacing = true
+	client := NewClientWithConfig(config, WithSharedLimiter(nil), WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})))
+
+	if client.httpClient.shared != nil || client.httpClient.pacer == nil {
+		t.Error("Expected a nil limiter to leave the client's own limits in place")
+	}
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Errorf("Expected the send to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package poodle
+
+import (
+	"html"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// AddLinkParams appends the query parameters, such as utm_source and
+// utm_campaign, to every http and https link in the HTML body. Parameters a
+// link already has are left unchanged, as are mailto:, cid: and anchor
+// links and hrefs that cannot be parsed. If the rewritten body exceeds
+// MaxContentSize, a ValidationError is returned and the email is unchanged.
+func (e *Email) AddLinkParams(params map[string]string) error {
+	if len(params) == 0 || !e.HasHTML() {
+		return nil
+	}
+
+	rewritten := rewriteLinks(e.HTML, func(href string) (string, bool) {
+		return addQueryParams(href, params)
+	})
+
+	if len(rewritten) > MaxContentSize {
+		tagged := e.clone()
+		tagged.HTML = rewritten
+		return withSizeReport(NewValidationError("Email validation failed", map[string][]string{
+			"html": {contentSizeMessage("HTML", len(rewritten), "after adding link parameters")},
+		}), tagged)
+	}
+
+	e.HTML = rewritten
+	return nil
+}
+
+// WithLinkParams returns a PreSend hook that adds the query parameters to
+// the links of every email (see Email.AddLinkParams)
+func WithLinkParams(params map[string]string) func(*Email) error {
+	return func(email *Email) error {
+		return email.AddLinkParams(params)
+	}
+}
+
+// addQueryParams returns the href with the parameters it does not have
+// yet. ok is false if the href is not an http or https URL.
+func addQueryParams(href string, params map[string]string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || (!strings.EqualFold(u.Scheme, "http") && !strings.EqualFold(u.Scheme, "https")) {
+		return "", false
+	}
+
+	query := u.Query()
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if _, ok := query[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return "", false
+	}
+	sort.Strings(keys)
+
+	missing := make([]string, len(keys))
+	for i, key := range keys {
+		missing[i] = url.QueryEscape(key) + "=" + url.QueryEscape(params[key])
+	}
+
+	// Append to the original text rather than re-encoding the URL, so that
+	// the rest of the link is unchanged
+	base, fragment := strings.TrimSpace(href), ""
+	if hash := strings.IndexByte(base, '#'); hash >= 0 {
+		base, fragment = base[:hash], base[hash:]
+	}
+	switch {
+	case !strings.Contains(base, "?"):
+		base += "?"
+	case !strings.HasSuffix(base, "?") && !strings.HasSuffix(base, "&"):
+		base += "&"
+	}
+	return base + strings.Join(missing, "&") + fragment, true
+}
+
+// rewriteLinks calls rewrite with the href of every a and area element and
+// replaces the href when rewrite returns true. All other markup is copied
+// unchanged.
+func rewriteLinks(s string, rewrite func(href string) (string, bool)) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); {
+		lt := strings.IndexByte(s[i:], '<')
+		if lt < 0 {
+			b.WriteString(s[i:])
+			break
+		}
+		b.WriteString(s[i : i+lt])
+		i += lt
+
+		if strings.HasPrefix(s[i:], "<!--") {
+			next := len(s)
+			if end := strings.Index(s[i+4:], "-->"); end >= 0 {
+				next = i + 4 + end + 3
+			}
+			b.WriteString(s[i:next])
+			i = next
+			continue
+		}
+
+		tag, next, ok := parseTag(s, i)
+		if !ok {
+			b.WriteByte('<')
+			i++
+			continue
+		}
+		i = next
+
+		if tag.end || tag.unterminated {
+			b.WriteString(tag.raw)
+			continue
+		}
+
+		if rawTextElements[tag.name] {
+			content, closing, next := rawText(s, i, tag.name)
+			b.WriteString(tag.raw)
+			b.WriteString(content)
+			b.WriteString(closing)
+			i = next
+			continue
+		}
+
+		if tag.name != "a" && tag.name != "area" {
+			b.WriteString(tag.raw)
+			continue
+		}
+
+		changed := false
+		attrs := append([]htmlAttr(nil), tag.attrs...)
+		for j, attr := range attrs {
+			if attr.name != "href" || !attr.hasValue {
+				continue
+			}
+			if href, ok := rewrite(attr.value); ok {
+				attrs[j] = attr.withValue(href)
+				changed = true
+			}
+		}
+
+		if changed {
+			b.WriteString(tag.rebuild(attrs))
+		} else {
+			b.WriteString(tag.raw)
+		}
+	}
+
+	return b.String()
+}
+
+// withValue returns the attribute with a new value, keeping its quote
+// style. Unquoted values are quoted.
+func (a htmlAttr) withValue(value string) htmlAttr {
+	prefix := a.raw[:len(a.raw)-len(a.rawValue)]
+	if a.quote != 0 {
+		prefix = a.raw[:len(a.raw)-len(a.rawValue)-2]
+	}
+
+	quote := a.quote
+	if quote == 0 {
+		quote = '"'
+	}
+
+	a.value = value
+	a.rawValue = html.EscapeString(value)
+	a.raw = prefix + string(quote) + a.rawValue + string(quote)
+	a.quote = quote
+	return a
+}
@@ -0,0 +1,114 @@
+package poodle
+
+import (
+	"strings"
+	"testing"
+)
+
+var utmParams = map[string]string{
+	"utm_source":   "newsletter",
+	"utm_medium":   "email",
+	"utm_campaign": "spring sale",
+}
+
+func TestAddLinkParams(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"plain link",
+			`<a href="https://example.com/shop">Shop</a>`,
+			`<a href="https://example.com/shop?utm_campaign=spring+sale&amp;utm_medium=email&amp;utm_source=newsletter">Shop</a>`,
+		},
+		{
+			"existing query and fragment",
+			`<a class="btn" href='http://example.com/p?id=1#top'>P</a>`,
+			`<a class="btn" href='http://example.com/p?id=1&amp;utm_campaign=spring+sale&amp;utm_medium=email&amp;utm_source=newsletter#top'>P</a>`,
+		},
+		{
+			"existing parameter kept",
+			`<a href="https://example.com/?utm_source=twitter&amp;x=1">X</a>`,
+			`<a href="https://example.com/?utm_source=twitter&amp;x=1&amp;utm_campaign=spring+sale&amp;utm_medium=email">X</a>`,
+		},
+		{
+			"unquoted href",
+			`<a href=https://example.com/ title=t>X</a>`,
+			`<a href="https://example.com/?utm_campaign=spring+sale&amp;utm_medium=email&amp;utm_source=newsletter" title=t>X</a>`,
+		},
+		{
+			"area element",
+			`<area shape="rect" HREF="HTTPS://example.com">`,
+			`<area shape="rect" HREF="HTTPS://example.com?utm_campaign=spring+sale&amp;utm_medium=email&amp;utm_source=newsletter">`,
+		},
+		{
+			"untouched links",
+			`<a href="mailto:hi@example.com">Mail</a><a href="#top">Top</a><img src="cid:logo"><a href="cid:part">C</a><a href="/relative">R</a><a>none</a>`,
+			`<a href="mailto:hi@example.com">Mail</a><a href="#top">Top</a><img src="cid:logo"><a href="cid:part">C</a><a href="/relative">R</a><a>none</a>`,
+		},
+		{
+			"unparsable href skipped",
+			`<a href="http://exa mple.com/%zz">Bad</a><a href="https://example.com">Good</a>`,
+			`<a href="http://exa mple.com/%zz">Bad</a><a href="https://example.com?utm_campaign=spring+sale&amp;utm_medium=email&amp;utm_source=newsletter">Good</a>`,
+		},
+		{
+			"comments and scripts untouched",
+			`<!-- <a href="https://example.com">x</a> --><style>a[href="https://example.com"]{}</style>`,
+			`<!-- <a href="https://example.com">x</a> --><style>a[href="https://example.com"]{}</style>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email := NewHTMLEmail("from@example.com", "to@example.com", "Subject", tt.in)
+			if err := email.AddLinkParams(utmParams); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if email.HTML != tt.want {
+				t.Errorf("want: %s\ngot:  %s", tt.want, email.HTML)
+			}
+		})
+	}
+}
+
+func TestAddLinkParamsIdempotent(t *testing.T) {
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Subject", `<a href="https://example.com">x</a>`)
+	email.AddLinkParams(utmParams)
+	first := email.HTML
+	email.AddLinkParams(utmParams)
+
+	if email.HTML != first {
+		t.Errorf("Expected parameters to be added once, got %s", email.HTML)
+	}
+}
+
+func TestAddLinkParamsContentSize(t *testing.T) {
+	link := `<a href="https://example.com">x</a>`
+	html := strings.Repeat(" ", MaxContentSize-len(link)) + link
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Subject", html)
+
+	err := email.AddLinkParams(utmParams)
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %T", err)
+	}
+	if len(validationErr.Errors["html"]) == 0 {
+		t.Errorf("Expected an error on the html field, got %v", validationErr.Errors)
+	}
+	if email.HTML != html {
+		t.Error("Expected the email to be unchanged")
+	}
+}
+
+func TestWithLinkParamsHook(t *testing.T) {
+	hook := WithLinkParams(map[string]string{"utm_source": "app"})
+
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Subject", `<a href="https://example.com/">x</a>`)
+	if err := hook(email); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(email.HTML, "https://example.com/?utm_source=app") {
+		t.Errorf("Expected hook to add parameters, got %s", email.HTML)
+	}
+}
@@ -0,0 +1,280 @@
+package poodle
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Lint thresholds
+const (
+	// MaxLintSubjectLength is the subject length in characters above which
+	// LintLongSubject is reported
+	MaxLintSubjectLength = 150
+	// MaxLintHTMLSize is the HTML size in bytes above which LintLargeHTML
+	// is reported. Gmail clips messages larger than about 100KB.
+	MaxLintHTMLSize = 100 * 1024
+)
+
+// Lint codes
+const (
+	LintMissingText    = "missing_text"
+	LintLongSubject    = "long_subject"
+	LintLargeHTML      = "large_html"
+	LintAllCapsSubject = "all_caps_subject"
+	LintHTMLNotMarkup  = "html_not_markup"
+	LintTextIsHTML     = "text_is_html"
+	// LintUnverifiedFrom is reported with Config.WarnUnverifiedFrom when
+	// the From domain is not a verified sender domain of the account
+	LintUnverifiedFrom = "unverified_from"
+)
+
+// lintCodes are the known lint codes
+var lintCodes = map[string]bool{
+	LintMissingText:    true,
+	LintLongSubject:    true,
+	LintLargeHTML:      true,
+	LintAllCapsSubject: true,
+	LintHTMLNotMarkup:  true,
+	LintTextIsHTML:     true,
+	LintUnverifiedFrom: true,
+}
+
+// LintSeverity indicates how likely a lint warning is to affect delivery
+type LintSeverity string
+
+// Lint severities
+const (
+	LintSeverityInfo    LintSeverity = "info"
+	LintSeverityWarning LintSeverity = "warning"
+)
+
+// LintWarning is an issue that does not prevent sending an email but may
+// affect how it is delivered or displayed
+type LintWarning struct {
+	Code     string
+	Field    string
+	Message  string
+	Severity LintSeverity
+}
+
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%s: %s (%s)", w.Severity, w.Message, w.Code)
+}
+
+// Lint returns warnings about the email that Validate does not treat as
+// errors: an HTML body without a text alternative, a subject longer than
+// MaxLintSubjectLength characters or written in capitals, HTML larger
+// than MaxLintHTMLSize, and bodies that look like they were passed in the
+// wrong field: HTML that is not markup (see looksLikeMarkup) and text that
+// is (see looksLikeHTML).
+func (e *Email) Lint() []LintWarning {
+	var warnings []LintWarning
+
+	if e.HTML != "" && e.Text == "" {
+		warnings = append(warnings, LintWarning{
+			Code:     LintMissingText,
+			Field:    "text",
+			Message:  "HTML email has no text alternative",
+			Severity: LintSeverityWarning,
+		})
+	}
+
+	if length := utf8.RuneCountInString(e.Subject); length > MaxLintSubjectLength {
+		warnings = append(warnings, LintWarning{
+			Code:     LintLongSubject,
+			Field:    "subject",
+			Message:  fmt.Sprintf("Subject is %d characters long; clients truncate subjects longer than %d", length, MaxLintSubjectLength),
+			Severity: LintSeverityWarning,
+		})
+	}
+
+	if isAllCaps(e.Subject) {
+		warnings = append(warnings, LintWarning{
+			Code:     LintAllCapsSubject,
+			Field:    "subject",
+			Message:  "Subject is written in capitals, which spam filters penalize",
+			Severity: LintSeverityInfo,
+		})
+	}
+
+	if len(e.HTML) > MaxLintHTMLSize {
+		warnings = append(warnings, LintWarning{
+			Code:     LintLargeHTML,
+			Field:    "html",
+			Message:  fmt.Sprintf("HTML is %d KB; Gmail clips messages larger than %d KB", len(e.HTML)/1024, MaxLintHTMLSize/1024),
+			Severity: LintSeverityWarning,
+		})
+	}
+
+	return append(warnings, e.contentWarnings()...)
+}
+
+// contentWarnings returns the LintHTMLNotMarkup and LintTextIsHTML warnings
+func (e *Email) contentWarnings() []LintWarning {
+	var warnings []LintWarning
+
+	if e.HTML != "" && !looksLikeMarkup(e.HTML) {
+		warnings = append(warnings, LintWarning{
+			Code:     LintHTMLNotMarkup,
+			Field:    "html",
+			Message:  "HTML contains no tags or entities; it may be a file path, JSON or plain text",
+			Severity: LintSeverityWarning,
+		})
+	}
+
+	if e.Text != "" && looksLikeHTML(e.Text) {
+		warnings = append(warnings, LintWarning{
+			Code:     LintTextIsHTML,
+			Field:    "text",
+			Message:  "Text contains HTML markup; it may be the HTML body",
+			Severity: LintSeverityWarning,
+		})
+	}
+
+	return warnings
+}
+
+// checkContent returns a ValidationError for the content warnings that
+// are not disabled, for Config.StrictContentChecks
+func checkContent(config *Config, email *Email) error {
+	errors := make(map[string][]string)
+	for _, warning := range email.contentWarnings() {
+		if !containsFold(config.DisabledLints, warning.Code) {
+			errors[warning.Field] = append(errors[warning.Field], warning.Message)
+		}
+	}
+	if len(errors) > 0 {
+		return NewValidationError("Email content does not match its fields", errors)
+	}
+	return nil
+}
+
+// looksLikeMarkup reports whether s contains a tag, such as <p or </p, or
+// an entity, such as &amp; or &#39;, and is not a JSON object or array. A
+// "<" not followed by a letter, "/" or "!", as in "I <3 you" or "a < b",
+// is not a tag, and a "&" not followed by a name or number and ";", as in
+// "AT&T", is not an entity. The check is a single pass over s.
+func looksLikeMarkup(s string) bool {
+	trimmed := strings.TrimSpace(s)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return false
+	}
+
+	for i := 0; i < len(s)-1; i++ {
+		switch s[i] {
+		case '<':
+			if c := s[i+1]; isASCIILetter(c) || c == '/' || c == '!' {
+				return true
+			}
+		case '&':
+			if entityLength(s[i+1:]) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// entityLength returns the length of the entity name or number and ";" at
+// the start of s, or 0 if there is none
+func entityLength(s string) int {
+	i := 0
+	if strings.HasPrefix(s, "#") {
+		i++
+		if i < len(s) && (s[i] == 'x' || s[i] == 'X') {
+			i++
+		}
+	}
+	start := i
+	for i < len(s) && i-start < 32 && (isASCIILetter(s[i]) || s[i] >= '0' && s[i] <= '9') {
+		i++
+	}
+	if i == start || i >= len(s) || s[i] != ';' {
+		return 0
+	}
+	return i + 1
+}
+
+// htmlDocumentTags are the tags whose presence in a text body marks it as
+// HTML. Inline tags such as <b> are left out, as plain text mentions them
+// more often.
+var htmlDocumentTags = []string{
+	"html", "head", "body", "div", "p", "table", "tr", "td", "span", "br", "style", "a",
+}
+
+// looksLikeHTML reports whether the text s starts with a doctype or <html>,
+// or contains a <br> or a closing tag of htmlDocumentTags, such as </p> or
+// </div>. Angle brackets in text such as "<3", "->" or "<jane@example.com>"
+// are not matched. The check is a single pass over s.
+func looksLikeHTML(s string) bool {
+	lower := strings.ToLower(strings.TrimSpace(s))
+	if strings.HasPrefix(lower, "<!doctype html") || strings.HasPrefix(lower, "<html") {
+		return true
+	}
+
+	for i := strings.IndexByte(lower, '<'); i >= 0; {
+		tag := lower[i+1:]
+		closing := strings.HasPrefix(tag, "/")
+		tag = strings.TrimPrefix(tag, "/")
+		for _, name := range htmlDocumentTags {
+			if !strings.HasPrefix(tag, name) || len(tag) == len(name) {
+				continue
+			}
+			switch next := tag[len(name)]; {
+			case closing && next == '>':
+				return true
+			case name == "br" && (next == '>' || next == '/' || next == ' '):
+				return true
+			}
+		}
+
+		next := strings.IndexByte(lower[i+1:], '<')
+		if next < 0 {
+			break
+		}
+		i += 1 + next
+	}
+	return false
+}
+
+// isAllCaps reports whether s has at least five letters and none of them
+// is lowercase
+func isAllCaps(s string) bool {
+	letters := 0
+	for _, r := range s {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters >= 5
+}
+
+// reportLintWarnings passes the email's lint warnings that are not
+// disabled to the configured callback
+func reportLintWarnings(config *Config, email *Email) {
+	if config.OnLintWarning == nil {
+		return
+	}
+
+	for _, warning := range email.Lint() {
+		if !containsFold(config.DisabledLints, warning.Code) {
+			config.OnLintWarning(warning)
+		}
+	}
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package poodle
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEmailLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		email *Email
+		codes []string
+	}{
+		{
+			name:  "clean email",
+			email: &Email{Subject: "Your receipt", HTML: "<p>Thanks</p>", Text: "Thanks"},
+		},
+		{
+			name:  "text only",
+			email: &Email{Subject: "Your receipt", Text: "Thanks"},
+		},
+		{
+			name:  "missing text alternative",
+			email: &Email{Subject: "Your receipt", HTML: "<p>Thanks</p>"},
+			codes: []string{LintMissingText},
+		},
+		{
+			name:  "long subject",
+			email: &Email{Subject: strings.Repeat("é", MaxLintSubjectLength+1), Text: "Thanks"},
+			codes: []string{LintLongSubject},
+		},
+		{
+			name:  "subject at the limit",
+			email: &Email{Subject: strings.Repeat("é", MaxLintSubjectLength), Text: "Thanks"},
+		},
+		{
+			name:  "all caps subject",
+			email: &Email{Subject: "ACT NOW: 50% OFF!", Text: "Thanks"},
+			codes: []string{LintAllCapsSubject},
+		},
+		{
+			name:  "short acronym subject",
+			email: &Email{Subject: "FYI", Text: "Thanks"},
+		},
+		{
+			name:  "large html",
+			email: &Email{Subject: "Newsletter", HTML: "<p>" + strings.Repeat("a", MaxLintHTMLSize), Text: "Thanks"},
+			codes: []string{LintLargeHTML},
+		},
+		{
+			name:  "file path as html",
+			email: &Email{Subject: "Newsletter", HTML: "templates/welcome.html", Text: "Welcome"},
+			codes: []string{LintHTMLNotMarkup},
+		},
+		{
+			name:  "html as text",
+			email: &Email{Subject: "Newsletter", HTML: "<p>Welcome</p>", Text: "<p>Welcome</p>"},
+			codes: []string{LintTextIsHTML},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := tt.email.Lint()
+			if len(warnings) != len(tt.codes) {
+				t.Fatalf("Expected %d warnings, got %v", len(tt.codes), warnings)
+			}
+			for i, warning := range warnings {
+				if warning.Code != tt.codes[i] {
+					t.Errorf("Expected code %s, got %s", tt.codes[i], warning.Code)
+				}
+				if warning.Field == "" || warning.Message == "" || warning.Severity == "" {
+					t.Errorf("Expected field, message and severity to be set, got %+v", warning)
+				}
+			}
+		})
+	}
+}
+
+func TestClientOnLintWarning(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DisabledLints = []string{" ALL_CAPS_SUBJECT "}
+
+	var warnings []LintWarning
+	config.OnLintWarning = func(w LintWarning) {
+		warnings = append(warnings, w)
+	}
+
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	if _, err := client.SendHTML("from@example.com", "to@example.com", "BIG NEWS TODAY", "<p>Hello</p>"); err != nil {
+		t.Fatalf("Expected lint warnings not to fail the send, got: %v", err)
+	}
+
+	if len(warnings) != 1 || warnings[0].Code != LintMissingText {
+		t.Errorf("Expected only the %s warning, got %v", LintMissingText, warnings)
+	}
+}
+
+func TestConfigWarningsUnknownLint(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DisabledLints = []string{LintLargeHTML, "no_such_lint"}
+
+	warnings := config.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "no_such_lint") {
+		t.Errorf("Expected a warning about the unknown lint code, got %v", warnings)
+	}
+}
+
+func TestLooksLikeMarkup(t *testing.T) {
+	tests := []struct {
+		html string
+		want bool
+	}{
+		{"<p>Hello</p>", true},
+		{"<!DOCTYPE html><html></html>", true},
+		{"Hello<br/>world", true},
+		{"Tom &amp; Jerry", true},
+		{"It&#39;s here", true},
+		{"It&#x27;s here", true},
+		{"Hello </div>", true},
+		{"<p>I <3 you</p>", true},
+		{"templates/welcome.html", false},
+		{"/var/mail/welcome.html", false},
+		{`C:\mail\welcome.html`, false},
+		{`{"html": "<p>Hello</p>"}`, false},
+		{`[{"to": "a@example.com"}]`, false},
+		{"I <3 you", false},
+		{"a < b > c", false},
+		{"AT&T", false},
+		{"Fish & chips; peas", false},
+		{"<", false},
+		{"&", false},
+	}
+
+	for _, tt := range tests {
+		if got := looksLikeMarkup(tt.html); got != tt.want {
+			t.Errorf("looksLikeMarkup(%q) = %v, expected %v", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestLooksLikeHTML(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"<!doctype html>\n<html><body>Hi</body></html>", true},
+		{"  <html><body>Hi</body></html>", true},
+		{"<p>Hello</p>", true},
+		{"Hello<br>world", true},
+		{"Hello<br />world", true},
+		{"<table><tr><td>1</td></tr></table>", true},
+		{"I <3 you", false},
+		{"a < b and b > c", false},
+		{"x -> y <- z", false},
+		{"Jane Doe <jane@example.com>", false},
+		{"Use <b>bold</b> for emphasis", false},
+		{"<brand> and </pre>", false},
+		{"Press <Enter> to continue", false},
+		{"<<< 3 >>>", false},
+		{"Tom &amp; Jerry", false},
+		{"<", false},
+	}
+
+	for _, tt := range tests {
+		if got := looksLikeHTML(tt.text); got != tt.want {
+			t.Errorf("looksLikeHTML(%q) = %v, expected %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestClientStrictContentChecks(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.StrictContentChecks = true
+
+	requests := 0
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return acceptedResponse(), nil
+	})
+
+	_, err := client.SendHTML("from@example.com", "to@example.com", "Welcome", "templates/welcome.html")
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Errors["html"]) != 1 {
+		t.Fatalf("Expected an html ValidationError, got: %v", err)
+	}
+
+	for _, text := range []string{"I <3 you", "a < b", "AT&T", "Jane <jane@example.com>"} {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Hi", text); err != nil {
+			t.Errorf("Expected text %q to be sent, got: %v", text, err)
+		}
+	}
+	if requests != 4 {
+		t.Errorf("Expected 4 requests, got %d", requests)
+	}
+
+	config.DisabledLints = []string{LintHTMLNotMarkup}
+	if _, err := client.SendHTML("from@example.com", "to@example.com", "Welcome", "templates/welcome.html"); err != nil {
+		t.Errorf("Expected a disabled check not to fail the send, got: %v", err)
+	}
+}
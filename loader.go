@@ -0,0 +1,370 @@
+package poodle
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxJSONLLineSize is the longest line LoadRecipientsJSONL accepts
+const MaxJSONLLineSize = 1024 * 1024 // 1MB
+
+// Recipient is one row of a recipient list: an address and the variables
+// from the row's other columns or fields
+type Recipient struct {
+	// Row is the line of the file the recipient was read from, counting
+	// from 1
+	Row       int
+	Email     string
+	Name      string
+	Variables map[string]string
+	// SubjectOverride replaces the subject of the base email for this
+	// recipient if set. It may contain {{variable}} tokens.
+	SubjectOverride string
+}
+
+// Address returns the recipient's address
+func (r Recipient) Address() *Address {
+	return &Address{Name: r.Name, Email: r.Email}
+}
+
+// Apply returns a copy of base addressed to the recipient, with the
+// {{variable}} tokens in the subject and bodies replaced by the
+// recipient's values, or else by those of base.Variables (see
+// Email.Render). Values are HTML-escaped in the HTML body, and tokens
+// without a value are left as they are, as are subject tokens whose value
+// contains a line break. Use Render to have these reported. A
+// SubjectOverride containing a line break is kept, so that sending the
+// email fails with a ValidationError for this recipient.
+func (r Recipient) Apply(base *Email) *Email {
+	email := r.prepare(base)
+	variables := email.Variables
+	email.Variables = nil
+
+	email.Subject, _, _ = renderSubject(email.Subject, variables)
+	email.Text, _ = renderTemplate(email.Text, variables, nil)
+	email.HTML, _ = renderTemplate(email.HTML, variables, html.EscapeString)
+	return email
+}
+
+// Render is like Apply, but returns the ValidationError of Email.Render
+// if a token has no value, a subject value contains a line break or a
+// body is too large once rendered, or if SubjectOverride contains a line
+// break.
+func (r Recipient) Render(base *Email) (*Email, error) {
+	if strings.ContainsAny(r.SubjectOverride, "\r\n") {
+		return nil, NewValidationError("Email template could not be rendered", map[string][]string{
+			"subject": {"Subject override contains a line break"},
+		})
+	}
+	return r.prepare(base).Render()
+}
+
+// prepare returns a copy of base addressed to the recipient, with the
+// subject override and the variables of the recipient merged over those of
+// base
+func (r Recipient) prepare(base *Email) *Email {
+	email := base.clone()
+	email.To = ""
+	email.ToAddress = r.Address()
+	if r.SubjectOverride != "" {
+		email.Subject = r.SubjectOverride
+	}
+
+	variables := make(map[string]string, len(base.Variables)+len(r.Variables))
+	for key, value := range base.Variables {
+		variables[key] = value
+	}
+	for key, value := range r.Variables {
+		variables[key] = value
+	}
+	email.Variables = variables
+	return email
+}
+
+// RowError reports a row of a recipient list that could not be loaded
+type RowError struct {
+	// Row is the line of the file, counting from 1
+	Row    int
+	Reason string
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Reason)
+}
+
+// RecipientRow is a row received from StreamRecipientsCSV or
+// StreamRecipientsJSONL. Err is a *RowError if the row was skipped, or
+// the read error that ended the stream; Recipient is set otherwise.
+type RecipientRow struct {
+	Recipient *Recipient
+	Err       error
+}
+
+// CSVHeader says whether a CSV recipient list starts with a header row
+type CSVHeader int
+
+const (
+	// CSVHeaderAuto treats the first row as a header unless one of its
+	// fields is a valid address
+	CSVHeaderAuto CSVHeader = iota
+	// CSVHeaderPresent always treats the first row as a header
+	CSVHeaderPresent
+	// CSVHeaderAbsent treats every row as a recipient
+	CSVHeaderAbsent
+)
+
+// CSVOptions configures how a CSV recipient list is read
+type CSVOptions struct {
+	// Header says whether the first row names the columns
+	Header CSVHeader
+
+	// EmailColumn and NameColumn name the header columns holding the
+	// address and display name. They default to "email" and "name" and are
+	// matched case-insensitively. Without a header the address is the
+	// first column and the name the second.
+	EmailColumn string
+	NameColumn  string
+
+	// Comma is the field delimiter. It defaults to ','.
+	Comma rune
+}
+
+// LoadRecipientsCSV reads a CSV recipient list. Every column other than
+// the email and name becomes a variable, named by the header or, without
+// one, by the column number counting from 1. Rows that cannot be loaded
+// are reported as RowErrors and do not stop the rest of the file; the
+// error is only set if the file could not be read.
+func LoadRecipientsCSV(r io.Reader, opts CSVOptions) ([]Recipient, []RowError, error) {
+	return collectRecipients(StreamRecipientsCSV(context.Background(), r, opts))
+}
+
+// LoadRecipientsJSONL reads a recipient list with one JSON object per
+// line. The email and name fields give the address; every other field
+// becomes a variable, with strings used as they are and other values in
+// their JSON form. Blank lines are skipped. Rows that cannot be loaded are
+// reported as RowErrors and do not stop the rest of the file; the error
+// is only set if the file could not be read.
+func LoadRecipientsJSONL(r io.Reader) ([]Recipient, []RowError, error) {
+	return collectRecipients(StreamRecipientsJSONL(context.Background(), r))
+}
+
+// StreamRecipientsCSV is like LoadRecipientsCSV but sends each row on the
+// returned channel as it is read, for files too large to hold in memory.
+// The channel is closed at the end of the file or when ctx is cancelled.
+func StreamRecipientsCSV(ctx context.Context, r io.Reader, opts CSVOptions) <-chan RecipientRow {
+	rows := make(chan RecipientRow)
+	go func() {
+		defer close(rows)
+		readRecipientsCSV(ctx, r, opts, rows)
+	}()
+	return rows
+}
+
+// StreamRecipientsJSONL is like LoadRecipientsJSONL but sends each row on
+// the returned channel as it is read, for files too large to hold in
+// memory. The channel is closed at the end of the file or when ctx is
+// cancelled.
+func StreamRecipientsJSONL(ctx context.Context, r io.Reader) <-chan RecipientRow {
+	rows := make(chan RecipientRow)
+	go func() {
+		defer close(rows)
+		readRecipientsJSONL(ctx, r, rows)
+	}()
+	return rows
+}
+
+// collectRecipients drains a stream into recipients and row errors
+func collectRecipients(rows <-chan RecipientRow) ([]Recipient, []RowError, error) {
+	var recipients []Recipient
+	var rowErrors []RowError
+	var err error
+	for row := range rows {
+		var rowErr *RowError
+		switch {
+		case row.Recipient != nil:
+			recipients = append(recipients, *row.Recipient)
+		case errors.As(row.Err, &rowErr):
+			rowErrors = append(rowErrors, *rowErr)
+		default:
+			err = row.Err
+		}
+	}
+	return recipients, rowErrors, err
+}
+
+// emit sends a row unless ctx is cancelled first
+func emit(ctx context.Context, rows chan<- RecipientRow, row RecipientRow) bool {
+	select {
+	case rows <- row:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// newRecipient validates the address of a row
+func newRecipient(row int, email, name string, variables map[string]string) RecipientRow {
+	email = strings.TrimSpace(email)
+	switch {
+	case email == "":
+		return RecipientRow{Err: &RowError{Row: row, Reason: "email is missing"}}
+	case !isValidEmail(email):
+		return RecipientRow{Err: &RowError{Row: row, Reason: fmt.Sprintf("%q is not a valid email", email)}}
+	case strings.ContainsAny(name, "\r\n"):
+		return RecipientRow{Err: &RowError{Row: row, Reason: "name must not contain line breaks"}}
+	}
+	return RecipientRow{Recipient: &Recipient{Row: row, Email: email, Name: strings.TrimSpace(name), Variables: variables}}
+}
+
+func readRecipientsCSV(ctx context.Context, r io.Reader, opts CSVOptions, rows chan<- RecipientRow) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+	emailColumn, nameColumn := opts.EmailColumn, opts.NameColumn
+	if emailColumn == "" {
+		emailColumn = "email"
+	}
+	if nameColumn == "" {
+		nameColumn = "name"
+	}
+
+	var names []string
+	emailIndex, nameIndex := 0, 1
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if !emit(ctx, rows, RecipientRow{Err: &RowError{Row: parseErr.StartLine, Reason: parseErr.Err.Error()}}) {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			emit(ctx, rows, RecipientRow{Err: err})
+			return
+		}
+		row, _ := reader.FieldPos(0)
+
+		if first {
+			first = false
+			header := opts.Header == CSVHeaderPresent ||
+				opts.Header == CSVHeaderAuto && !containsEmail(record)
+			if header {
+				names = make([]string, len(record))
+				emailIndex, nameIndex = -1, -1
+				for i, name := range record {
+					names[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+					switch {
+					case strings.EqualFold(names[i], emailColumn):
+						emailIndex = i
+					case strings.EqualFold(names[i], nameColumn):
+						nameIndex = i
+					}
+				}
+				if emailIndex < 0 {
+					emit(ctx, rows, RecipientRow{Err: fmt.Errorf("poodle: CSV header has no %q column", emailColumn)})
+					return
+				}
+				continue
+			}
+		}
+
+		var email, name string
+		variables := make(map[string]string)
+		for i, value := range record {
+			switch {
+			case i == emailIndex:
+				email = value
+			case i == nameIndex:
+				name = value
+			case i < len(names):
+				variables[names[i]] = value
+			default:
+				variables[strconv.Itoa(i+1)] = value
+			}
+		}
+		if !emit(ctx, rows, newRecipient(row, email, name, variables)) {
+			return
+		}
+	}
+}
+
+// containsEmail reports whether any field is a valid address
+func containsEmail(fields []string) bool {
+	for _, field := range fields {
+		if isValidEmail(field) {
+			return true
+		}
+	}
+	return false
+}
+
+func readRecipientsJSONL(ctx context.Context, r io.Reader, rows chan<- RecipientRow) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MaxJSONLLineSize)
+
+	row := 0
+	for scanner.Scan() {
+		row++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(line, &fields); err != nil {
+			if !emit(ctx, rows, RecipientRow{Err: &RowError{Row: row, Reason: "invalid JSON: " + err.Error()}}) {
+				return
+			}
+			continue
+		}
+
+		var email, name string
+		var reason string
+		variables := make(map[string]string)
+		for key, raw := range fields {
+			var s string
+			isString := json.Unmarshal(raw, &s) == nil
+			switch {
+			case (key == "email" || key == "name") && !isString:
+				reason = key + " must be a string"
+			case key == "email":
+				email = s
+			case key == "name":
+				name = s
+			case isString:
+				variables[key] = s
+			default:
+				variables[key] = string(raw)
+			}
+		}
+
+		result := newRecipient(row, email, name, variables)
+		if reason != "" {
+			result = RecipientRow{Err: &RowError{Row: row, Reason: reason}}
+		}
+		if !emit(ctx, rows, result) {
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		emit(ctx, rows, RecipientRow{Err: err})
+	}
+}
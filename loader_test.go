@@ -0,0 +1,241 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+	file, err := os.Open("testdata/recipients/" + name)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func TestLoadRecipientsCSV(t *testing.T) {
+	recipients, rowErrors, err := LoadRecipientsCSV(openFixture(t, "malformed.csv"), CSVOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []Recipient{
+		{Row: 2, Email: "jane@example.com", Name: "Jane Doe", Variables: map[string]string{"Plan": "Pro", "Seats": "5"}},
+		{Row: 3, Email: "bob@example.com", Name: `Bob "Bobby" Smith`, Variables: map[string]string{"Plan": "Free", "Seats": "1"}},
+		{Row: 6, Email: "alice@example.com", Name: "Alice", Variables: map[string]string{"Plan": "Team,\nEnterprise", "Seats": "10"}},
+	}
+	if !reflect.DeepEqual(recipients, want) {
+		t.Errorf("Expected %+v, got %+v", want, recipients)
+	}
+
+	rows := make([]int, len(rowErrors))
+	for i, rowErr := range rowErrors {
+		rows[i] = rowErr.Row
+	}
+	if !reflect.DeepEqual(rows, []int{4, 5, 8}) {
+		t.Errorf("Expected errors on rows 4, 5 and 8, got %+v", rowErrors)
+	}
+	if !strings.Contains(rowErrors[0].Error(), `row 4: "not-an-email" is not a valid email`) {
+		t.Errorf("Unexpected reason: %s", rowErrors[0].Error())
+	}
+}
+
+func TestLoadRecipientsCSVWithoutHeader(t *testing.T) {
+	recipients, rowErrors, err := LoadRecipientsCSV(openFixture(t, "no_header.csv"), CSVOptions{Comma: ';'})
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("Unexpected errors: %v %v", err, rowErrors)
+	}
+	want := []Recipient{
+		{Row: 1, Email: "jane@example.com", Name: "Jane", Variables: map[string]string{"3": "Pro"}},
+		{Row: 2, Email: "bob@example.com", Name: "Bob", Variables: map[string]string{"3": "Free"}},
+	}
+	if !reflect.DeepEqual(recipients, want) {
+		t.Errorf("Expected %+v, got %+v", want, recipients)
+	}
+
+	// Forcing a header treats the first row as column names
+	recipients, _, err = LoadRecipientsCSV(strings.NewReader("to,first\njane@example.com,Jane\n"), CSVOptions{
+		Header:      CSVHeaderPresent,
+		EmailColumn: "To",
+	})
+	if err != nil || len(recipients) != 1 || recipients[0].Variables["first"] != "Jane" {
+		t.Errorf("Expected the custom email column to be used, got %+v (%v)", recipients, err)
+	}
+
+	_, _, err = LoadRecipientsCSV(strings.NewReader("address,name\n"), CSVOptions{})
+	if err == nil || !strings.Contains(err.Error(), `no "email" column`) {
+		t.Errorf("Expected an error for a header without an email column, got %v", err)
+	}
+}
+
+func TestLoadRecipientsJSONL(t *testing.T) {
+	recipients, rowErrors, err := LoadRecipientsJSONL(openFixture(t, "malformed.jsonl"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []Recipient{
+		{Row: 1, Email: "jane@example.com", Name: "Jane Doe", Variables: map[string]string{"plan": "Pro", "seats": "5"}},
+		{Row: 3, Email: "bob@example.com", Variables: map[string]string{"vip": "true"}},
+		{Row: 8, Email: "alice@example.com", Variables: map[string]string{"tags": `["a","b"]`}},
+	}
+	if !reflect.DeepEqual(recipients, want) {
+		t.Errorf("Expected %+v, got %+v", want, recipients)
+	}
+
+	reasons := make(map[int]string)
+	for _, rowErr := range rowErrors {
+		reasons[rowErr.Row] = rowErr.Reason
+	}
+	if len(reasons) != 4 || reasons[5] != "email must be a string" || reasons[7] != "email is missing" ||
+		!strings.HasPrefix(reasons[6], "invalid JSON") || !strings.Contains(reasons[4], "not a valid email") {
+		t.Errorf("Unexpected row errors: %+v", rowErrors)
+	}
+}
+
+func TestStreamRecipientsCancelled(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("email\n")
+	for i := 0; i < 100; i++ {
+		b.WriteString("user@example.com\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := StreamRecipientsCSV(ctx, strings.NewReader(b.String()), CSVOptions{})
+	if row := <-rows; row.Recipient == nil || row.Recipient.Row != 2 {
+		t.Fatalf("Expected the first recipient, got %+v", row)
+	}
+	cancel()
+
+	count := 0
+	for range rows {
+		count++
+	}
+	if count > 1 {
+		t.Errorf("Expected the stream to stop after cancellation, got %d more rows", count)
+	}
+}
+
+func TestRecipientApply(t *testing.T) {
+	base := NewEmailWithBoth("from@example.com", "ignored@example.com", "Hi {{first}}",
+		"<p>Your plan: {{plan}} {{missing}}</p>", "Your plan: {{plan}}")
+	base.SetHeader("X-Campaign", "spring")
+
+	recipient := Recipient{Email: "jane@example.com", Name: "Jane", Variables: map[string]string{
+		"first": "Jane",
+		"plan":  "Pro & <Team>",
+	}}
+	email := recipient.Apply(base)
+
+	if email.Subject != "Hi Jane" || email.Text != "Your plan: Pro & <Team>" {
+		t.Errorf("Unexpected subject or text: %q, %q", email.Subject, email.Text)
+	}
+	if email.HTML != "<p>Your plan: Pro &amp; &lt;Team&gt; {{missing}}</p>" {
+		t.Errorf("Expected escaped values in the HTML, got %q", email.HTML)
+	}
+	to := email.toAddress()
+	if err := email.Validate(); err != nil || to.String() != "Jane <jane@example.com>" {
+		t.Errorf("Expected the email to be addressed to the recipient, got %v (%v)", to.String(), err)
+	}
+	if base.Subject != "Hi {{first}}" {
+		t.Error("Expected the base email to be unchanged")
+	}
+}
+
+func TestRecipientRenderSubject(t *testing.T) {
+	base := NewHTMLEmail("from@example.com", "", "Your March invoice, {{first_name}}", "<p>Hi {{first_name}}</p>")
+
+	tests := []struct {
+		name      string
+		recipient Recipient
+		subject   string
+		errors    []string
+	}{
+		{
+			name:      "unicode name",
+			recipient: Recipient{Email: "zoe@example.com", Variables: map[string]string{"first_name": "Zoë 山田 <&>"}},
+			subject:   "Your March invoice, Zoë 山田 <&>",
+		},
+		{
+			name:      "override",
+			recipient: Recipient{Email: "ana@example.com", Variables: map[string]string{"first_name": "Ana"}, SubjectOverride: "{{first_name}}, your invoice is overdue"},
+			subject:   "Ana, your invoice is overdue",
+		},
+		{
+			name:      "missing variable",
+			recipient: Recipient{Email: "bob@example.com"},
+			errors:    []string{"Unresolved variable {{first_name}}"},
+		},
+		{
+			name:      "header injection",
+			recipient: Recipient{Email: "eve@example.com", Variables: map[string]string{"first_name": "Eve\r\nBcc: victim@example.com"}},
+			errors:    []string{"Variable {{first_name}} contains a line break"},
+		},
+		{
+			name:      "override injection",
+			recipient: Recipient{Email: "mal@example.com", SubjectOverride: "Hi\nBcc: victim@example.com"},
+			errors:    []string{"Subject override contains a line break"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := tt.recipient.Render(base)
+			if tt.errors != nil {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Expected ValidationError, got %v", err)
+				}
+				if got := validationErr.Errors["subject"]; !reflect.DeepEqual(got, tt.errors) {
+					t.Errorf("Expected subject errors %v, got %v", tt.errors, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if email.Subject != tt.subject {
+				t.Errorf("Expected subject %q, got %q", tt.subject, email.Subject)
+			}
+		})
+	}
+
+	// Apply substitutes the values it can and never a line break
+	email := Recipient{Email: "eve@example.com", Variables: map[string]string{"first_name": "Eve\nBcc: victim@example.com"}}.Apply(base)
+	if email.Subject != "Your March invoice, {{first_name}}" || email.HTML != "<p>Hi Eve\nBcc: victim@example.com</p>" {
+		t.Errorf("Expected the value in the body only, got %q and %q", email.Subject, email.HTML)
+	}
+}
+
+func TestApplySubjectOverrideInjectionFailsSend(t *testing.T) {
+	client := NewClient("test_api_key")
+	requests := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return acceptedResponse(), nil
+	})
+
+	base := NewTextEmail("billing@example.com", "", "Your invoice", "Hello")
+	emails := []*Email{
+		Recipient{Email: "bob@example.com"}.Apply(base),
+		Recipient{Email: "mal@example.com", SubjectOverride: "Hi\r\nBcc: victim@example.com"}.Apply(base),
+	}
+	results, err := client.SendAll(context.Background(), emails)
+	if err == nil || requests != 1 {
+		t.Fatalf("Expected one email to be sent and one to fail, got %d requests and %v", requests, err)
+	}
+	var validationErr *ValidationError
+	if results[0].Err != nil || !errors.As(results[1].Err, &validationErr) {
+		t.Fatalf("Expected a ValidationError for the injected override only, got %v and %v", results[0].Err, results[1].Err)
+	}
+	if got := validationErr.Errors["subject"]; !reflect.DeepEqual(got, []string{"Subject contains a line break"}) {
+		t.Errorf("Expected a subject error, got %v", got)
+	}
+}
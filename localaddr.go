@@ -0,0 +1,52 @@
+package poodle
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// interfaceAddrs lists the addresses assigned to the host's interfaces.
+// Tests replace it.
+var interfaceAddrs = net.InterfaceAddrs
+
+// parseLocalAddr parses Config.LocalAddr, an IP address with an optional
+// port, and checks that the address is assigned to an interface of the
+// host; problem describes why the address cannot be used otherwise. The
+// unspecified addresses 0.0.0.0 and :: are always accepted, and loopback
+// addresses are accepted within an assigned loopback network, as Linux
+// routes all of 127.0.0.0/8 to the loopback interface.
+func parseLocalAddr(s string) (addr *net.TCPAddr, problem string) {
+	host, port := s, 0
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || n > 65535 {
+			return nil, fmt.Sprintf("Local address %q has an invalid port", s)
+		}
+		host, port = h, n
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Sprintf("Local address %q must be an IP address, optionally with a port", s)
+	}
+	addr = &net.TCPAddr{IP: ip, Port: port}
+	if ip.IsUnspecified() {
+		return addr, ""
+	}
+
+	assigned, err := interfaceAddrs()
+	if err != nil {
+		return nil, fmt.Sprintf("Local address %q could not be checked: %s", s, err.Error())
+	}
+	for _, a := range assigned {
+		network, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if network.IP.Equal(ip) || ip.IsLoopback() && network.IP.IsLoopback() && network.Contains(ip) {
+			return addr, ""
+		}
+	}
+	return nil, fmt.Sprintf("Local address %s is not assigned to an interface of this host", ip)
+}
@@ -0,0 +1,104 @@
+package poodle
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+func TestConfigValidateLocalAddr(t *testing.T) {
+	tests := []struct {
+		name        string
+		localAddr   string
+		expectError bool
+	}{
+		{"Loopback", "127.0.0.1", false},
+		{"Loopback with port", "127.0.0.1:0", false},
+		{"Loopback network", "127.0.0.2", false},
+		{"Unspecified", "0.0.0.0", false},
+		{"IPv6 unspecified with port", "[::]:0", false},
+		{"Hostname", "localhost", true},
+		{"Garbage", "not an address", true},
+		{"Invalid port", "127.0.0.1:http", true},
+		{"Port out of range", "127.0.0.1:70000", true},
+		{"Not assigned", "192.0.2.123", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.APIKey = "test_api_key"
+			config.LocalAddr = tt.localAddr
+
+			err := config.Validate()
+			if !tt.expectError {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+
+			validationErr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T", err)
+			}
+			if len(validationErr.Errors["local_addr"]) == 0 {
+				t.Errorf("Expected local_addr error entry, got %v", validationErr.Errors)
+			}
+		})
+	}
+}
+
+func TestNewClientPanicsOnUnassignedLocalAddr(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected NewClientWithConfig to fail fast on an unassigned address")
+		}
+	}()
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.LocalAddr = "192.0.2.123"
+	NewClientWithConfig(config)
+}
+
+func TestLocalAddrBindsConnections(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("binding to 127.0.0.2 requires Linux")
+	}
+
+	remote := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		remote <- host
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"success": true, "message": "Email queued"}`))
+	}))
+	defer server.Close()
+
+	var controlled int32
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = server.URL
+	config.LocalAddr = "127.0.0.2"
+	config.DialerControl = func(network, address string, c syscall.RawConn) error {
+		atomic.AddInt32(&controlled, 1)
+		return nil
+	}
+
+	client := NewClientWithConfig(config)
+	if _, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if host := <-remote; host != "127.0.0.2" {
+		t.Errorf("Expected the connection to come from 127.0.0.2, got %s", host)
+	}
+	if atomic.LoadInt32(&controlled) == 0 {
+		t.Error("Expected DialerControl to be called")
+	}
+}
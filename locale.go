@@ -0,0 +1,88 @@
+package poodle
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// localeKey is the context key used by WithLocale
+type localeKey struct{}
+
+// WithLocale returns a context whose requests ask for API messages in the
+// given locale, a BCP 47 language tag such as "de-DE". It overrides
+// Config.Locale for requests made with the context. Invalid tags are
+// logged at LogLevelError and ignored.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// normalizeLocale trims a language tag and replaces underscores, as in
+// "de_DE", with hyphens. It returns false if the result is not a plausible
+// BCP 47 tag: a primary language subtag of 2-3 or 5-8 letters, or the
+// private use or grandfathered singletons x and i, followed by subtags of
+// 1-8 letters and digits.
+func normalizeLocale(locale string) (string, bool) {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	if locale == "" {
+		return "", false
+	}
+
+	subtags := strings.Split(locale, "-")
+	for i, subtag := range subtags {
+		if len(subtag) < 1 || len(subtag) > 8 {
+			return "", false
+		}
+		for _, r := range subtag {
+			isLetter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+			if !isLetter && (i == 0 || r < '0' || r > '9') {
+				return "", false
+			}
+		}
+		if i == 0 && len(subtag) == 4 {
+			return "", false
+		}
+		if i == 0 && len(subtag) == 1 && (len(subtags) == 1 || !strings.EqualFold(subtag, "x") && !strings.EqualFold(subtag, "i")) {
+			return "", false
+		}
+	}
+	return locale, true
+}
+
+// applyLocale sets the Accept-Language header of a request to the locale
+// of its context, or else to Config.Locale
+func applyLocale(config *Config, req *http.Request) {
+	locale := config.Locale
+	if override, ok := req.Context().Value(localeKey{}).(string); ok {
+		normalized, valid := normalizeLocale(override)
+		if valid {
+			locale = normalized
+		} else if config.logs(LogLevelError) {
+			log.Printf("Poodle API Warning: ignoring invalid locale %q", truncate(override, 64))
+		}
+	}
+
+	if locale != "" {
+		req.Header.Set("Accept-Language", locale)
+	}
+}
+
+// recordContentLanguage adds the Content-Language of an error response to
+// the error's context as content_language, so that callers showing the
+// message know its language
+func recordContentLanguage(err error, header http.Header) error {
+	language := strings.TrimSpace(header.Get("Content-Language"))
+	if language == "" {
+		return err
+	}
+
+	if withBase, ok := err.(interface{ base() *BaseError }); ok {
+		base := withBase.base()
+		if base.ContextMap == nil {
+			base.ContextMap = make(map[string]interface{})
+		}
+		base.ContextMap["content_language"] = language
+	}
+	return err
+}
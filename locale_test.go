@@ -0,0 +1,100 @@
+package poodle
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+		valid  bool
+	}{
+		{"de-DE", "de-DE", true},
+		{" fr ", "fr", true},
+		{"pt_BR", "pt-BR", true},
+		{"zh-Hant-TW", "zh-Hant-TW", true},
+		{"es-419", "es-419", true},
+		{"x-klingon", "x-klingon", true},
+		{"", "", false},
+		{"d", "", false},
+		{"x", "", false},
+		{"abcd", "", false},
+		{"12-DE", "", false},
+		{"de--DE", "", false},
+		{"de-DE-", "", false},
+		{"de-toolongsubtag", "", false},
+		{"de-DE\r\nX-Injected: 1", "", false},
+		{"de-DE;q=0.8", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := normalizeLocale(tt.locale)
+		if got != tt.want || ok != tt.valid {
+			t.Errorf("normalizeLocale(%q) = %q, %t, want %q, %t", tt.locale, got, ok, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestLocaleHeader(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.LogLevel = LogLevelError
+	config.Locale = "de_DE"
+
+	var languages []string
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		languages = append(languages, req.Header.Get("Accept-Language"))
+		return acceptedResponse(), nil
+	})
+	logs := captureLog(t)
+
+	email := NewTextEmail("from@example.com", "to@example.com", "Hello", "Hi")
+	client.SendContext(context.Background(), email)
+	client.SendContext(WithLocale(context.Background(), "fr-CA"), email)
+	client.SendContext(WithLocale(context.Background(), "fr\r\nX-Injected: 1"), email)
+
+	expected := []string{"de-DE", "fr-CA", "de-DE"}
+	for i, language := range expected {
+		if languages[i] != language {
+			t.Errorf("Request %d: expected Accept-Language %q, got %q", i, language, languages[i])
+		}
+	}
+	if !strings.Contains(logs.String(), "invalid locale") {
+		t.Errorf("Expected the invalid locale to be logged, got %q", logs.String())
+	}
+}
+
+func TestLocaleValidation(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.Locale = "not a locale"
+
+	err := config.Validate()
+	validationErr, ok := err.(*ValidationError)
+	if !ok || len(validationErr.Errors["locale"]) == 0 {
+		t.Errorf("Expected a locale validation error, got %v", err)
+	}
+}
+
+func TestErrorContentLanguage(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		resp := jsonResponse(http.StatusUnauthorized, `{"message":"Ungültiger API-Schlüssel","code":"invalid_api_key"}`)
+		resp.Header = http.Header{"Content-Language": {"de"}}
+		return resp, nil
+	})
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	authErr, ok := err.(*AuthenticationError)
+	if !ok {
+		t.Fatalf("Expected AuthenticationError, got %T", err)
+	}
+	if language := authErr.Context()["content_language"]; language != "de" {
+		t.Errorf("Expected content_language de, got %v", language)
+	}
+}
@@ -0,0 +1,132 @@
+package poodle
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultDebugMaxBodyBytes is the number of bytes of a body logged at
+// LogLevelTrace unless Config.DebugMaxBodyBytes says otherwise
+const DefaultDebugMaxBodyBytes = 4 * 1024
+
+// LogLevel controls how much the client logs via the standard log package
+type LogLevel int
+
+// Log levels, from least to most verbose
+const (
+	// LogLevelOff disables logging
+	LogLevelOff LogLevel = iota
+	// LogLevelError logs failed attempts, retries and failovers
+	LogLevelError
+	// LogLevelInfo additionally logs the method, URL, status and duration
+	// of every request
+	LogLevelInfo
+	// LogLevelTrace additionally logs request and response headers and
+	// bodies, with credentials redacted
+	LogLevelTrace
+)
+
+// String returns the name of the level as accepted by ParseLogLevel
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelOff:
+		return "off"
+	case LogLevelError:
+		return "error"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelTrace:
+		return "trace"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// ParseLogLevel parses a level name: off, error, info or trace. "debug" is
+// accepted as an alias for trace.
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "off", "none":
+		return LogLevelOff, nil
+	case "error":
+		return LogLevelError, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "trace", "debug":
+		return LogLevelTrace, nil
+	}
+	return LogLevelOff, fmt.Errorf("unknown log level %q", name)
+}
+
+// effectiveLogLevel returns the configured level, treating Debug as Trace
+func (c *Config) effectiveLogLevel() LogLevel {
+	if c.Debug && c.LogLevel < LogLevelTrace {
+		return LogLevelTrace
+	}
+	return c.LogLevel
+}
+
+// logs reports whether messages at the given level are logged
+func (c *Config) logs(level LogLevel) bool {
+	return c.effectiveLogLevel() >= level
+}
+
+// formatHeaders renders headers for trace logging with credentials redacted
+func formatHeaders(header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		for _, value := range header[key] {
+			if strings.EqualFold(key, "Authorization") {
+				value = "Bearer " + maskAPIKey(strings.TrimPrefix(value, "Bearer "))
+			}
+			fmt.Fprintf(&b, "\n  %s: %s", key, value)
+		}
+	}
+	return b.String()
+}
+
+// debugBody renders a request or response body for trace logging. JSON
+// bodies are scrubbed and truncated to DebugMaxBodyBytes; other bodies are
+// replaced by their size and content type.
+func (c *Config) debugBody(contentType string, body []byte) string {
+	if c.DebugFullBodies {
+		return c.PIIMode.scrubBytes(body)
+	}
+	if !isJSONBody(contentType, body) {
+		if contentType == "" {
+			return fmt.Sprintf("[%d bytes not logged]", len(body))
+		}
+		return fmt.Sprintf("[%d bytes of %s not logged]", len(body), contentType)
+	}
+
+	limit := c.DebugMaxBodyBytes
+	if limit == 0 {
+		limit = DefaultDebugMaxBodyBytes
+	}
+	s := c.PIIMode.scrubBytes(body)
+	if len(s) <= limit {
+		return s
+	}
+	cut := runeBoundary(s, limit)
+	return fmt.Sprintf("%s...[truncated %d bytes]", s[:cut], len(s)-cut)
+}
+
+// runeBoundary returns the largest index of at most n at which s can be
+// cut without splitting a UTF-8 encoded character
+func runeBoundary(s string, n int) int {
+	if n >= len(s) {
+		return len(s)
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
@@ -0,0 +1,251 @@
+package poodle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// captureLog redirects the standard logger to a buffer for the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buffer bytes.Buffer
+	log.SetOutput(&buffer)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buffer
+}
+
+func TestLogLevels(t *testing.T) {
+	const apiKey = "poodle_live_1234567890abcdef"
+
+	tests := []struct {
+		level      LogLevel
+		summary    bool
+		body       bool
+		retryError bool
+	}{
+		{LogLevelOff, false, false, false},
+		{LogLevelError, false, false, true},
+		{LogLevelInfo, true, false, true},
+		{LogLevelTrace, true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			config := NewConfig()
+			config.APIKey = apiKey
+			config.LogLevel = tt.level
+			config.MaxRetries = 1
+
+			client := NewClientWithConfig(config, WithClock(newTestClock()))
+			statuses := []int{http.StatusServiceUnavailable, http.StatusAccepted}
+			client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+				status := statuses[0]
+				statuses = statuses[1:]
+				if status == http.StatusAccepted {
+					return acceptedResponse(), nil
+				}
+				return jsonResponse(status, `{"message":"Unavailable"}`), nil
+			})
+
+			output := captureLog(t)
+			if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "secret body"); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			logged := output.String()
+
+			if strings.Contains(logged, "POST") != tt.summary {
+				t.Errorf("Expected request summary logged = %t, got:\n%s", tt.summary, logged)
+			}
+			if strings.Contains(logged, "secret body") != tt.body {
+				t.Errorf("Expected body logged = %t, got:\n%s", tt.body, logged)
+			}
+			if strings.Contains(logged, "Poodle API Retry") != tt.retryError {
+				t.Errorf("Expected retry logged = %t, got:\n%s", tt.retryError, logged)
+			}
+			if strings.Contains(logged, apiKey) {
+				t.Errorf("Expected the API key to be redacted, got:\n%s", logged)
+			}
+		})
+	}
+}
+
+func TestClientLogLevelCompatibility(t *testing.T) {
+	client := NewClient("test_api_key")
+
+	client.SetLogLevel(LogLevelInfo)
+	if client.IsDebug() || client.LogLevel() != LogLevelInfo {
+		t.Errorf("Expected Info not to count as debug, got %s", client.LogLevel())
+	}
+
+	client.SetDebug(true)
+	if !client.IsDebug() || client.LogLevel() != LogLevelTrace {
+		t.Errorf("Expected SetDebug(true) to map to trace, got %s", client.LogLevel())
+	}
+
+	client.SetDebug(false)
+	if client.IsDebug() || client.LogLevel() != LogLevelInfo {
+		t.Errorf("Expected SetDebug(false) to restore info, got %s", client.LogLevel())
+	}
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.Debug = true
+	debugClient := NewClientWithConfig(config)
+	if debugClient.LogLevel() != LogLevelTrace {
+		t.Error("Expected Config.Debug to map to trace")
+	}
+	debugClient.SetDebug(false)
+	if debugClient.IsDebug() || debugClient.LogLevel() != LogLevelError {
+		t.Errorf("Expected SetDebug(false) to fall back to error, got %s", debugClient.LogLevel())
+	}
+}
+
+func TestClientSetLogLevelValidation(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.SetLogLevel(LogLevelInfo)
+
+	for _, level := range []LogLevel{LogLevelOff - 1, LogLevelTrace + 1} {
+		var validationErr *ValidationError
+		if err := client.SetLogLevel(level); !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError for level %d, got %v", level, err)
+		}
+	}
+	if client.LogLevel() != LogLevelInfo {
+		t.Errorf("Expected the level to be unchanged, got %s", client.LogLevel())
+	}
+}
+
+func TestClientSetLogLevelConcurrency(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+	captureLog(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			client.SetLogLevel(LogLevel(i % 4))
+		}(i)
+		go func() {
+			defer wg.Done()
+			client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for name, expected := range map[string]LogLevel{"off": LogLevelOff, "ERROR": LogLevelError, " info ": LogLevelInfo, "trace": LogLevelTrace, "debug": LogLevelTrace} {
+		level, err := ParseLogLevel(name)
+		if err != nil || level != expected {
+			t.Errorf("ParseLogLevel(%q) = %s, %v; want %s", name, level, err, expected)
+		}
+	}
+
+	if _, err := ParseLogLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+
+	t.Setenv("POODLE_LOG_LEVEL", "verbose")
+	if _, err := NewConfigFromEnvStrict(); err == nil {
+		t.Error("Expected strict env loading to reject an unknown level")
+	}
+
+	t.Setenv("POODLE_LOG_LEVEL", "info")
+	if config := NewConfigFromEnv(); config.LogLevel != LogLevelInfo {
+		t.Errorf("Expected LogLevel info from env, got %s", config.LogLevel)
+	}
+}
+
+func TestDebugBodyTruncation(t *testing.T) {
+	config := NewConfig()
+	config.DebugMaxBodyBytes = 10
+
+	// "é" and "😀" are 2 and 4 bytes long, so most limits fall inside one
+	body := `"aé😀bé😀cé😀"`
+	for limit := 1; limit < len(body); limit++ {
+		config.DebugMaxBodyBytes = limit
+		logged := config.debugBody("application/json", []byte(body))
+
+		kept := logged[:strings.Index(logged, "...[truncated ")]
+		if !utf8.ValidString(kept) || len(kept) > limit || len(kept) < limit-3 {
+			t.Errorf("Expected a valid prefix of at most %d bytes, got %q", limit, kept)
+		}
+		if suffix := fmt.Sprintf("...[truncated %d bytes]", len(body)-len(kept)); !strings.HasSuffix(logged, suffix) {
+			t.Errorf("Expected %q to end with %q", logged, suffix)
+		}
+	}
+
+	config.DebugMaxBodyBytes = len(body)
+	if logged := config.debugBody("application/json", []byte(body)); logged != body {
+		t.Errorf("Expected a body at the limit to be logged in full, got %q", logged)
+	}
+}
+
+func TestDebugBodyContentTypes(t *testing.T) {
+	config := NewConfig()
+	large := `{"html":"` + strings.Repeat("x", 2*DefaultDebugMaxBodyBytes) + `"}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    string
+	}{
+		{"json", "application/json; charset=utf-8", `{"ok":true}`, `{"ok":true}`},
+		{"problem json", "application/problem+json", `{"ok":true}`, `{"ok":true}`},
+		{"sniffed json", "", `{"ok":true}`, `{"ok":true}`},
+		{"default limit", "application/json", large, large[:DefaultDebugMaxBodyBytes] + fmt.Sprintf("...[truncated %d bytes]", len(large)-DefaultDebugMaxBodyBytes)},
+		{"html", "text/html", "<html>Bad gateway</html>", "[24 bytes of text/html not logged]"},
+		{"unlabelled text", "", "Bad gateway", "[11 bytes not logged]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if logged := config.debugBody(tt.contentType, []byte(tt.body)); logged != tt.expected {
+				t.Errorf("Expected %.80q, got %.80q", tt.expected, logged)
+			}
+		})
+	}
+
+	config.DebugFullBodies = true
+	if logged := config.debugBody("text/html", []byte(large)); logged != large {
+		t.Errorf("Expected DebugFullBodies to log the full body, got %d bytes", len(logged))
+	}
+}
+
+func TestDebugLogTruncatesBodies(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.LogLevel = LogLevelTrace
+	config.DebugMaxBodyBytes = 64
+
+	client := NewClientWithConfig(config, WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusBadGateway,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			Body:       io.NopCloser(strings.NewReader("<html>secret page</html>")),
+		}, nil
+	})))
+
+	output := captureLog(t)
+	client.SendHTML("from@example.com", "to@example.com", "Subject", strings.Repeat("<p>Hello</p>", 1000))
+	logged := output.String()
+
+	if !strings.Contains(logged, "...[truncated ") || strings.Count(logged, "<p>Hello</p>") > 5 {
+		t.Errorf("Expected the request body to be truncated, got:\n%s", logged)
+	}
+	if strings.Contains(logged, "secret page") || !strings.Contains(logged, "[24 bytes of text/html not logged]") {
+		t.Errorf("Expected the HTML response body not to be logged, got:\n%s", logged)
+	}
+}
@@ -0,0 +1,19 @@
+package poodle
+
+import "time"
+
+// MetricsHook receives metrics from a client. Implementations must be safe
+// for concurrent use, and one hook may be shared by several clients.
+type MetricsHook interface {
+	// ObserveSend is called once per Send with "success" or the error_type
+	// of the returned error, e.g. "validation_error"
+	ObserveSend(status string)
+	// ObserveRequest is called after every HTTP attempt with the response
+	// status code, or 0 if no response was received
+	ObserveRequest(statusCode int, duration time.Duration)
+	// ObserveRetry is called before a failed attempt is retried
+	ObserveRetry()
+	// ObserveRateLimitRemaining is called with the ratelimit-remaining
+	// header value whenever a response carries it
+	ObserveRateLimitRemaining(remaining int)
+}
@@ -0,0 +1,95 @@
+package poodle
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics is a MetricsHook recording every observation
+type recordingMetrics struct {
+	mutex     sync.Mutex
+	sends     []string
+	requests  []int
+	retries   int
+	remaining []int
+}
+
+func (m *recordingMetrics) ObserveSend(status string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sends = append(m.sends, status)
+}
+
+func (m *recordingMetrics) ObserveRequest(statusCode int, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.requests = append(m.requests, statusCode)
+}
+
+func (m *recordingMetrics) ObserveRetry() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.retries++
+}
+
+func (m *recordingMetrics) ObserveRateLimitRemaining(remaining int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.remaining = append(m.remaining, remaining)
+}
+
+func TestMetricsHook(t *testing.T) {
+	metrics := &recordingMetrics{}
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 1
+	config.Metrics = metrics
+
+	calls := 0
+	client := NewClientWithConfig(config, WithClock(newTestClock()))
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Header:     http.Header{"Ratelimit-Remaining": {"9"}},
+				Body:       io.NopCloser(strings.NewReader(`{"message": "unavailable"}`)),
+			}, nil
+		}
+		if calls == 2 {
+			resp := acceptedResponse()
+			resp.Header = http.Header{"Ratelimit-Remaining": {"8"}}
+			return resp, nil
+		}
+		return nil, NewNetworkError("connection refused", "")
+	})
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected success after retry, got: %v", err)
+	}
+	if _, err := client.SendText("invalid", "to@example.com", "Subject", "Hello"); err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err == nil {
+		t.Fatal("Expected network error")
+	}
+
+	if expected := []string{"success", "validation_error", "network_error"}; !reflect.DeepEqual(metrics.sends, expected) {
+		t.Errorf("Expected sends %v, got %v", expected, metrics.sends)
+	}
+	if expected := []int{503, 202, 0, 0}; !reflect.DeepEqual(metrics.requests, expected) {
+		t.Errorf("Expected requests %v, got %v", expected, metrics.requests)
+	}
+	if metrics.retries != 2 {
+		t.Errorf("Expected 2 retries, got %d", metrics.retries)
+	}
+	if expected := []int{9, 8}; !reflect.DeepEqual(metrics.remaining, expected) {
+		t.Errorf("Expected rate limit remaining %v, got %v", expected, metrics.remaining)
+	}
+}
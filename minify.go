@@ -0,0 +1,132 @@
+package poodle
+
+import (
+	"strings"
+)
+
+// MinifyHTML removes comments and collapses runs of whitespace between
+// and inside the elements of the HTML body, and returns the email for
+// chaining. It is conservative and never changes how the email renders:
+//
+//   - conditional comments such as <!--[if mso]> are kept
+//   - a run of whitespace becomes a single space, or a line break if it
+//     contains one, so that lines stay short
+//   - the content of pre, textarea, style and script elements and of
+//     elements styled with white-space: pre is copied unchanged
+//   - tags and their attributes are copied unchanged
+func (e *Email) MinifyHTML() *Email {
+	if e.HasHTML() {
+		e.HTML = minifyHTML(e.HTML)
+	}
+	return e
+}
+
+// preformattedElements keep their whitespace
+var preformattedElements = map[string]bool{
+	"pre": true, "listing": true, "plaintext": true,
+}
+
+// minifyHTML returns the minified markup
+func minifyHTML(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	// preformatted holds the names of the open elements that keep their
+	// whitespace, innermost last
+	var preformatted []string
+	// space is true after collapsed whitespace, so that whitespace around
+	// a removed comment collapses too
+	space := false
+	for _, token := range tokenizeHTML(s) {
+		tag := token.tag
+		if token.tag != nil || (strings.HasPrefix(token.raw, "<") && !strings.HasPrefix(token.raw, "<!--")) || isConditionalComment(token.raw) {
+			space = false
+		}
+		switch {
+		case len(preformatted) > 0:
+			b.WriteString(token.raw)
+			if tag != nil {
+				if tag.end && tag.name == preformatted[len(preformatted)-1] {
+					preformatted = preformatted[:len(preformatted)-1]
+				} else if !tag.end && tag.name == preformatted[len(preformatted)-1] && !tag.selfClosing {
+					preformatted = append(preformatted, tag.name)
+				}
+			}
+		case tag != nil:
+			b.WriteString(token.raw)
+			if !tag.end && !tag.selfClosing && !voidElements[tag.name] && isPreformatted(tag) {
+				preformatted = append(preformatted, tag.name)
+			}
+		case strings.HasPrefix(token.raw, "<!--"):
+			if isConditionalComment(token.raw) {
+				b.WriteString(token.raw)
+			}
+			continue
+		case strings.HasPrefix(token.raw, "<"):
+			// Declarations such as <!DOCTYPE html> and <![endif]>
+			b.WriteString(token.raw)
+		default:
+			space = collapseWhitespace(&b, token.raw, space)
+		}
+		b.WriteString(token.content)
+		b.WriteString(token.closing)
+	}
+	return b.String()
+}
+
+// isPreformatted reports whether an element keeps its whitespace
+func isPreformatted(tag *htmlTag) bool {
+	if preformattedElements[tag.name] {
+		return true
+	}
+	style := strings.ToLower(tagAttr(tag, "style"))
+	if i := strings.Index(style, "white-space"); i >= 0 {
+		value := style[i+len("white-space"):]
+		if end := strings.IndexByte(value, ';'); end >= 0 {
+			value = value[:end]
+		}
+		return strings.Contains(value, "pre") || strings.Contains(value, "break-spaces")
+	}
+	return false
+}
+
+// isConditionalComment reports whether a comment is an Outlook
+// conditional comment, e.g. <!--[if mso]> or <!--<![endif]-->
+func isConditionalComment(comment string) bool {
+	body := strings.TrimSpace(strings.TrimPrefix(comment, "<!--"))
+	return strings.HasPrefix(body, "[if") || strings.HasPrefix(body, "<![endif]") || strings.HasPrefix(body, "[endif]")
+}
+
+// collapseWhitespace writes the text with each run of HTML whitespace
+// replaced by a line break if it contains one, or a space. Leading
+// whitespace is dropped if space is true, as the output already ends with
+// collapsed whitespace. It reports whether the text ends with whitespace.
+func collapseWhitespace(b *strings.Builder, text string, space bool) bool {
+	for i := 0; i < len(text); {
+		if !isHTMLSpace(text[i]) {
+			b.WriteByte(text[i])
+			space = false
+			i++
+			continue
+		}
+		newline := false
+		for ; i < len(text) && isHTMLSpace(text[i]); i++ {
+			newline = newline || text[i] == '\n' || text[i] == '\r'
+		}
+		switch {
+		case space:
+		case newline:
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(' ')
+		}
+		space = true
+	}
+	return space
+}
+
+// isHTMLSpace reports whether c is whitespace in HTML text. Other Unicode
+// spaces, such as non-breaking spaces, are content.
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,173 @@
+package poodle
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			"whitespace",
+			"<table>\n    <tr>\n        <td>Hello,   world</td>\n    </tr>\n</table>",
+			"<table>\n<tr>\n<td>Hello, world</td>\n</tr>\n</table>",
+		},
+		{
+			"comments",
+			"<p>a <!-- note --> b</p><!---->",
+			"<p>a b</p>",
+		},
+		{
+			"conditional comments",
+			`<!--[if mso]><table><tr><td><![endif]--><div>x</div><!--[if mso]></td></tr></table><![endif]--><!--[if !mso]><!--><p>y</p><!--<![endif]-->`,
+			`<!--[if mso]><table><tr><td><![endif]--><div>x</div><!--[if mso]></td></tr></table><![endif]--><!--[if !mso]><!--><p>y</p><!--<![endif]-->`,
+		},
+		{
+			"pre",
+			"<div>  a  </div><pre>  code\n\n  <b>  bold  </b> <!-- kept --></pre>  <p>  b  </p>",
+			"<div> a </div><pre>  code\n\n  <b>  bold  </b> <!-- kept --></pre> <p> b </p>",
+		},
+		{
+			"nested pre",
+			"<pre><pre>  a  </pre>  b  </pre>  c  ",
+			"<pre><pre>  a  </pre>  b  </pre> c ",
+		},
+		{
+			"white-space style",
+			`<td style="color: red; white-space: pre-wrap">  a  </td>  <td style="white-space: nowrap">  b  </td>`,
+			`<td style="color: red; white-space: pre-wrap">  a  </td> <td style="white-space: nowrap"> b </td>`,
+		},
+		{
+			"raw text",
+			"<style>\n  p  {  color: red  }\n</style>\n<textarea>  a\n  b</textarea><script>  var x;  </script>",
+			"<style>\n  p  {  color: red  }\n</style>\n<textarea>  a\n  b</textarea><script>  var x;  </script>",
+		},
+		{
+			"attributes",
+			"<img   alt=\"a   b\"\n  src=\"x.png\">   <!DOCTYPE html>",
+			"<img   alt=\"a   b\"\n  src=\"x.png\"> <!DOCTYPE html>",
+		},
+		{
+			"non-breaking spaces",
+			"a\u00a0\u00a0b &nbsp; c",
+			"a\u00a0\u00a0b &nbsp; c",
+		},
+	}
+
+	for _, tt := range tests {
+		email := NewHTMLEmail("from@example.com", "to@example.com", "Hello", tt.html)
+		if got := email.MinifyHTML().HTML; got != tt.expected {
+			t.Errorf("%s:\nexpected %q\ngot      %q", tt.name, tt.expected, got)
+		}
+		if again := minifyHTML(tt.expected); again != tt.expected {
+			t.Errorf("%s: expected minifying twice to change nothing, got %q", tt.name, again)
+		}
+	}
+}
+
+// indentedHTML returns a template of the given size with the indentation
+// and comments typical of generated emails
+func indentedHTML(size int) string {
+	row := "\n        <tr>\n            <!-- row -->\n            <td class=\"cell\">Item</td>\n        </tr>"
+	var b strings.Builder
+	b.WriteString("<table>")
+	for b.Len() < size-len("\n</table>") {
+		b.WriteString(row)
+	}
+	b.WriteString("\n</table>")
+	return b.String()
+}
+
+func TestMinifyHTMLSize(t *testing.T) {
+	html := indentedHTML(64 * 1024)
+	minified := minifyHTML(html)
+
+	// The row shrinks from 88 to 34 bytes
+	if len(minified) >= len(html)/2 {
+		t.Errorf("Expected the HTML to shrink by more than half, got %d bytes from %d", len(minified), len(html))
+	}
+	if strings.Count(minified, "<td class=\"cell\">Item</td>") != strings.Count(html, "<td class=\"cell\">Item</td>") {
+		t.Error("Expected every cell to be kept")
+	}
+}
+
+func TestValidationErrorMinifyAdvice(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		fits  bool
+		extra int
+	}{
+		{"compressible", indentedHTML(MaxContentSize + 1024), true, 1},
+		{"incompressible", strings.Repeat("x", MaxContentSize+1), false, 0},
+	}
+
+	for _, tt := range tests {
+		err := NewHTMLEmail("from@example.com", "to@example.com", "Hello", tt.html).Validate()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("%s: expected ValidationError, got %v", tt.name, err)
+		}
+
+		messages := validationErr.Errors["html"]
+		expected := contentSizeMessage("HTML", len(tt.html), "")
+		if len(messages) != 1+tt.extra || messages[0] != expected {
+			t.Errorf("%s: expected %q, got %v", tt.name, expected, messages)
+		}
+		if !strings.Contains(messages[0], "bytes over the limit of 10485760 bytes") {
+			t.Errorf("%s: expected the size and limit in the message, got %q", tt.name, messages[0])
+		}
+
+		context := validationErr.Context()
+		if context["minify_would_fit"] != tt.fits || context["minified_html_bytes"] != len(minifyHTML(tt.html)) {
+			t.Errorf("%s: expected minify_would_fit %t, got %v and %v bytes", tt.name, tt.fits, context["minify_would_fit"], context["minified_html_bytes"])
+		}
+		if tt.fits && !strings.Contains(messages[1], "Config.AutoMinifyHTML") {
+			t.Errorf("%s: expected the advice to minify, got %q", tt.name, messages[1])
+		}
+	}
+}
+
+func TestAutoMinifyHTML(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AutoMinifyHTML = true
+
+	var sent Email
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(body, &sent)
+		return acceptedResponse(), nil
+	})
+
+	html := indentedHTML(MaxContentSize + 1024)
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Hello", html)
+	if _, err := client.Send(email); err != nil {
+		t.Fatalf("Expected the minified email to be sent, got %v", err)
+	}
+	if sent.HTML != minifyHTML(html) || email.HTML != html {
+		t.Errorf("Expected a minified copy to be sent, got %d bytes and the caller's %d bytes", len(sent.HTML), len(email.HTML))
+	}
+
+	// Bodies within the limit are sent unchanged
+	small := "<p>\n    Hi\n</p>"
+	if _, err := client.Send(NewHTMLEmail("from@example.com", "to@example.com", "Hello", small)); err != nil || sent.HTML != small {
+		t.Errorf("Expected the HTML to be sent unchanged, got %q and %v", sent.HTML, err)
+	}
+
+	// Without the option, the email fails validation
+	config.AutoMinifyHTML = false
+	var validationErr *ValidationError
+	if _, err := NewClientWithConfig(config).Send(email); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ValidationError, got %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package poodle
+
+// Option configures a Client at construction time
+type Option func(*Client)
+
+// WithHTTPDoer replaces the HTTP client used to perform requests, e.g. to
+// add instrumentation or to use a test double
+func WithHTTPDoer(doer HTTPDoer) Option {
+	return func(c *Client) {
+		c.httpClient.httpClient = doer
+	}
+}
+
+// WithCassette wraps the client's HTTP transport in a RecordingTransport
+// backed by the cassette file at path. If the file exists, requests are
+// replayed from it; otherwise real requests are made and recorded to it.
+// Options that replace the HTTP client must be applied before this one.
+func WithCassette(path string) Option {
+	return func(c *Client) {
+		c.httpClient.httpClient = NewRecordingTransport(path, RecordModeAuto, c.httpClient.httpClient)
+	}
+}
@@ -0,0 +1,492 @@
+package poodle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outbox defaults
+const (
+	DefaultOutboxBackoff    = time.Second
+	DefaultOutboxMaxBackoff = 5 * time.Minute
+)
+
+// Outbox subdirectories for entries that are no longer pending
+const (
+	outboxFailedDir     = "failed"
+	outboxQuarantineDir = "quarantine"
+)
+
+// OutboxEntry is an email stored in the outbox
+type OutboxEntry struct {
+	ID    string `json:"id"`
+	Email Email  `json:"email"`
+	// Priority and Variables are not part of the encoded Email
+	Priority   Priority          `json:"priority"`
+	Variables  map[string]string `json:"variables,omitempty"`
+	EnqueuedAt time.Time         `json:"enqueued_at"`
+	Attempts   int               `json:"attempts"`
+	LastError  string            `json:"last_error,omitempty"`
+}
+
+// OutboxOption configures an Outbox
+type OutboxOption func(*Outbox)
+
+// WithOutboxBackoff sets the delay before retrying after a transient
+// failure, which doubles with every consecutive failure up to maxDelay
+func WithOutboxBackoff(initial, maxDelay time.Duration) OutboxOption {
+	return func(o *Outbox) {
+		o.backoff = initial
+		o.maxBackoff = maxDelay
+	}
+}
+
+// WithOutboxMaxAttempts moves an entry to the failed entries after n
+// transiently failed attempts. By default entries are retried until they
+// are delivered or fail permanently.
+func WithOutboxMaxAttempts(n int) OutboxOption {
+	return func(o *Outbox) {
+		o.maxAttempts = n
+	}
+}
+
+// Outbox is a durable queue of emails on disk. Every enqueued email is
+// written to its own JSON file and synced before Enqueue returns, so it
+// survives crashes and restarts. A background goroutine sends pending
+// emails through the client, higher priorities first and in enqueue order
+// within a priority, backing off while sends fail transiently, e.g. while
+// the network is down.
+//
+// Delivered entries are deleted. Entries that fail permanently, such as
+// invalid emails or sends from an account suspended for abuse, are moved
+// to the failed subdirectory, and files that cannot be decoded are moved to
+// the quarantine subdirectory. While the account is suspended for a reason
+// that can be resolved, such as a failed payment, entries are kept.
+type Outbox struct {
+	client      *Client
+	dir         string
+	clock       Clock
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	maxAttempts int
+
+	mutex sync.Mutex // guards seq
+	seq   uint64
+
+	sending sync.Mutex // serializes deliveries
+	wake    chan struct{}
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewOutbox opens the outbox in dir, creating the directory if needed, and
+// starts sending the entries already pending in it
+func NewOutbox(client *Client, dir string, opts ...OutboxOption) (*Outbox, error) {
+	o := &Outbox{
+		client:     client,
+		dir:        dir,
+		clock:      client.httpClient.clock,
+		backoff:    DefaultOutboxBackoff,
+		maxBackoff: DefaultOutboxMaxBackoff,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	for _, sub := range []string{dir, filepath.Join(dir, outboxFailedDir), filepath.Join(dir, outboxQuarantineDir)} {
+		if err := os.MkdirAll(sub, 0o700); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.recover(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	go o.run(ctx)
+
+	return o, nil
+}
+
+// Enqueue stores the email with its Email.Priority
+func (o *Outbox) Enqueue(email *Email) (*OutboxEntry, error) {
+	if email == nil {
+		return nil, newNilEmailError()
+	}
+	return o.EnqueuePriority(email, email.Priority)
+}
+
+// EnqueuePriority stores the email with the given priority. The email is
+// validated first as the client would send it, with its defaults and
+// AddressValidator, so that invalid emails are never stored. It is stored
+// as given, and the defaults are applied again when it is sent.
+func (o *Outbox) EnqueuePriority(email *Email, priority Priority) (*OutboxEntry, error) {
+	if !priority.valid() {
+		return nil, NewValidationError("Invalid outbox priority", map[string][]string{
+			"priority": {fmt.Sprintf("Priority %d is not one of the outbox priorities", priority)},
+		})
+	}
+	if err := o.client.validateEmail(context.Background(), email); err != nil {
+		return nil, err
+	}
+
+	o.mutex.Lock()
+	o.seq++
+	seq := o.seq
+	o.mutex.Unlock()
+
+	entry := &OutboxEntry{
+		ID:         fmt.Sprintf("%d-%020d", priority.rank(), seq),
+		Email:      *email.clone(),
+		Priority:   priority,
+		EnqueuedAt: o.clock.Now().UTC(),
+	}
+	entry.Email.Priority = priority
+	entry.Variables = entry.Email.Variables
+	if err := o.write(o.dir, entry); err != nil {
+		return nil, err
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return entry, nil
+}
+
+// Pending returns the number of emails waiting to be sent
+func (o *Outbox) Pending() int {
+	names, _ := o.entryNames(o.dir)
+	return len(names)
+}
+
+// Failed returns the entries that failed permanently, oldest first
+func (o *Outbox) Failed() []OutboxEntry {
+	names, _ := o.entryNames(filepath.Join(o.dir, outboxFailedDir))
+
+	entries := make([]OutboxEntry, 0, len(names))
+	for _, name := range names {
+		if entry, err := o.read(filepath.Join(o.dir, outboxFailedDir, name)); err == nil {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+// Drain sends pending emails until the outbox is empty, retrying transient
+// failures with backoff. It returns the context's error if the context is
+// done first.
+func (o *Outbox) Drain(ctx context.Context) error {
+	failures := 0
+	for {
+		empty, err := o.deliverNext(ctx)
+		if empty {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		failures++
+		if err := o.clock.Sleep(ctx, o.delay(failures)); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops the background sender. Pending entries stay on disk and are
+// sent by the next Outbox opened on the directory.
+func (o *Outbox) Close() error {
+	o.cancel()
+	<-o.done
+	return nil
+}
+
+// run sends pending entries in the background
+func (o *Outbox) run(ctx context.Context) {
+	defer close(o.done)
+
+	failures := 0
+	for {
+		empty, err := o.deliverNext(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case err != nil:
+			failures++
+			if o.clock.Sleep(ctx, o.delay(failures)) != nil {
+				return
+			}
+		case empty:
+			failures = 0
+			select {
+			case <-o.wake:
+			case <-ctx.Done():
+				return
+			}
+		default:
+			failures = 0
+		}
+	}
+}
+
+// deliverNext sends the first pending entry. empty is true if there was
+// nothing to send; err is the send or file error if the entry is kept for a
+// retry.
+func (o *Outbox) deliverNext(ctx context.Context) (empty bool, err error) {
+	o.sending.Lock()
+	defer o.sending.Unlock()
+
+	names, err := o.entryNames(o.dir)
+	if err != nil {
+		return false, err
+	}
+	if len(names) == 0 {
+		return true, nil
+	}
+
+	// A corrupt entry is moved aside; other read errors, such as running
+	// out of file descriptors, are retried with backoff
+	name := names[0]
+	path := filepath.Join(o.dir, name)
+	entry, err := o.read(path)
+	if err != nil {
+		var corruptErr *corruptEntryError
+		if !errors.As(err, &corruptErr) {
+			return false, err
+		}
+		return false, o.quarantine(name, err)
+	}
+
+	_, sendErr := o.client.SendContext(ctx, &entry.Email)
+	if sendErr == nil {
+		if err := os.Remove(path); err != nil {
+			return false, err
+		}
+		syncDir(o.dir)
+		return false, nil
+	}
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	entry.Attempts++
+	entry.LastError = sendErr.Error()
+
+	// Entries are kept while the failure is transient or the account is
+	// suspended for a reason that can be resolved
+	var suspendedErr *AccountSuspendedError
+	parked := errors.As(sendErr, &suspendedErr) && !suspendedErr.IsPermanent()
+	if (IsRetryable(sendErr) || parked) && (o.maxAttempts <= 0 || entry.Attempts < o.maxAttempts) {
+		if err := o.write(o.dir, entry); err != nil {
+			return false, err
+		}
+		return false, sendErr
+	}
+
+	// Move the entry to the failed directory, writing the final attempt
+	// count before removing the pending file
+	if err := o.write(filepath.Join(o.dir, outboxFailedDir), entry); err != nil {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil {
+		return false, err
+	}
+	syncDir(o.dir)
+
+	if o.client.snapshotConfig().logs(LogLevelError) {
+		log.Printf("Poodle Outbox: entry %s failed after %d attempts: %s", entry.ID, entry.Attempts, entry.LastError)
+	}
+	return false, nil
+}
+
+// delay returns the backoff after the given number of consecutive failures
+func (o *Outbox) delay(failures int) time.Duration {
+	delay := o.backoff
+	for i := 1; i < failures && delay < o.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.maxBackoff {
+		delay = o.maxBackoff
+	}
+	return delay
+}
+
+// recover removes temporary files left by an interrupted write, quarantines
+// unreadable entries and continues the sequence after the highest entry
+func (o *Outbox) recover() error {
+	files, err := os.ReadDir(o.dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".tmp") {
+			os.Remove(filepath.Join(o.dir, file.Name()))
+		}
+	}
+
+	names, err := o.entryNames(o.dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		var corruptErr *corruptEntryError
+		if _, err := o.read(filepath.Join(o.dir, name)); errors.As(err, &corruptErr) {
+			// An entry that cannot be moved is moved when it is sent
+			_ = o.quarantine(name, err)
+		}
+	}
+
+	for _, sub := range []string{"", outboxFailedDir, outboxQuarantineDir} {
+		names, err := o.entryNames(filepath.Join(o.dir, sub))
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if seq, ok := entrySeq(name); ok && seq > o.seq {
+				o.seq = seq
+			}
+		}
+	}
+	return nil
+}
+
+// quarantine moves a corrupt entry aside so that it does not block the
+// entries after it. It returns the error of the move.
+func (o *Outbox) quarantine(name string, cause error) error {
+	err := os.Rename(filepath.Join(o.dir, name), filepath.Join(o.dir, outboxQuarantineDir, name))
+	if err != nil {
+		if o.client.snapshotConfig().logs(LogLevelError) {
+			log.Printf("Poodle Outbox: could not quarantine unreadable entry %s: %v", name, err)
+		}
+		return err
+	}
+	syncDir(o.dir)
+
+	if o.client.snapshotConfig().logs(LogLevelError) {
+		log.Printf("Poodle Outbox: quarantined unreadable entry %s: %v", name, cause)
+	}
+	return nil
+}
+
+// corruptEntryError is returned by read for an entry file that cannot be
+// decoded or does not match its name, as opposed to one that could not be
+// read
+type corruptEntryError struct {
+	err error
+}
+
+func (e *corruptEntryError) Error() string { return e.err.Error() }
+
+func (e *corruptEntryError) Unwrap() error { return e.err }
+
+// entryNames returns the entry file names in dir in delivery order
+func (o *Outbox) entryNames(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// read decodes the entry file at path. Entries that cannot be decoded
+// are reported as a *corruptEntryError.
+func (o *Outbox) read(path string) (*OutboxEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry OutboxEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, &corruptEntryError{err}
+	}
+	if entry.ID+".json" != filepath.Base(path) {
+		return nil, &corruptEntryError{fmt.Errorf("poodle: outbox entry ID %q does not match file name", entry.ID)}
+	}
+	entry.Email.Priority = entry.Priority
+	entry.Email.Variables = entry.Variables
+	return &entry, nil
+}
+
+// write stores the entry in dir, replacing any previous version. The data
+// is synced to a temporary file that is then renamed into place, so a
+// crash never leaves a partially written entry.
+func (o *Outbox) write(dir string, entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, entry.ID+".json")
+	tmp, err := os.CreateTemp(dir, entry.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// entrySeq parses the sequence number from an entry file name
+func entrySeq(name string) (uint64, bool) {
+	dash := strings.IndexByte(name, '-')
+	if dash < 0 {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(name[dash+1:], ".json"), 10, 64)
+	return seq, err == nil
+}
+
+// syncDir flushes directory entries to disk so that renames and removals
+// survive a crash. Errors are ignored since some platforms cannot sync
+// directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,382 @@
+package poodle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// outboxTransport records the subjects of sent emails and fails while
+// offline is set
+type outboxTransport struct {
+	mutex   sync.Mutex
+	offline bool
+	status  int
+	body    string
+	sent    []string
+}
+
+func (tr *outboxTransport) Do(req *http.Request) (*http.Response, error) {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+
+	if tr.offline {
+		return nil, errors.New("network is unreachable")
+	}
+	if tr.status != 0 {
+		body := tr.body
+		if body == "" {
+			body = `{"message":"Invalid email"}`
+		}
+		return jsonResponse(tr.status, body), nil
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	var email Email
+	json.Unmarshal(body, &email)
+	tr.sent = append(tr.sent, email.Subject)
+	return acceptedResponse(), nil
+}
+
+func (tr *outboxTransport) setOffline(offline bool) {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	tr.offline = offline
+}
+
+func (tr *outboxTransport) sentSubjects() []string {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	return append([]string(nil), tr.sent...)
+}
+
+func newOutboxClient(tr *outboxTransport) *Client {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = tr
+	return client
+}
+
+func outboxEmail(subject string) *Email {
+	return NewTextEmail("from@example.com", "to@example.com", subject, "Hello")
+}
+
+func TestOutboxSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	// Enqueue while offline, then "crash" with everything still pending
+	offline := &outboxTransport{offline: true}
+	outbox, err := NewOutbox(newOutboxClient(offline), dir, WithOutboxBackoff(time.Hour, time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+
+	enqueue := []struct {
+		subject  string
+		priority Priority
+	}{
+		{"low 1", PriorityLow},
+		{"normal 1", PriorityNormal},
+		{"high 1", PriorityHigh},
+		{"normal 2", PriorityNormal},
+		{"low 2", PriorityLow},
+	}
+	for _, e := range enqueue {
+		if _, err := outbox.EnqueuePriority(outboxEmail(e.subject), e.priority); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+	}
+	outbox.Close()
+
+	if outbox.Pending() != len(enqueue) {
+		t.Fatalf("Expected %d pending entries, got %d", len(enqueue), outbox.Pending())
+	}
+
+	// Leave a corrupted entry and an interrupted write behind
+	os.WriteFile(filepath.Join(dir, "1-00000000000000000003.json"), []byte(`{"id":`), 0o600)
+	os.WriteFile(filepath.Join(dir, "1-00000000000000000099.json.123.tmp"), []byte(`{}`), 0o600)
+
+	online := &outboxTransport{}
+	reopened, err := NewOutbox(newOutboxClient(online), dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen outbox: %v", err)
+	}
+	defer reopened.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := reopened.Drain(ctx); err != nil {
+		t.Fatalf("Failed to drain outbox: %v", err)
+	}
+
+	want := []string{"high 1", "normal 1", "normal 2", "low 1", "low 2"}
+	got := online.sentSubjects()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v to be sent, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected send order %v, got %v", want, got)
+			break
+		}
+	}
+
+	if reopened.Pending() != 0 {
+		t.Errorf("Expected no pending entries, got %d", reopened.Pending())
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 2 {
+		t.Errorf("Expected only the failed and quarantine directories to remain, got %v", files)
+	}
+	if quarantined, _ := os.ReadDir(filepath.Join(dir, "quarantine")); len(quarantined) != 1 {
+		t.Errorf("Expected the corrupted entry to be quarantined, got %d files", len(quarantined))
+	}
+
+	// New entries continue the sequence after the recovered ones
+	entry, err := reopened.Enqueue(outboxEmail("after restart"))
+	if err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	if entry.ID <= "1-00000000000000000005" {
+		t.Errorf("Expected the sequence to continue, got ID %s", entry.ID)
+	}
+}
+
+func TestOutboxRetriesWhenConnectivityReturns(t *testing.T) {
+	tr := &outboxTransport{offline: true}
+	outbox, err := NewOutbox(newOutboxClient(tr), t.TempDir(), WithOutboxBackoff(time.Millisecond, 5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	defer outbox.Close()
+
+	if _, err := outbox.Enqueue(outboxEmail("alert")); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if outbox.Pending() != 1 {
+		t.Fatalf("Expected the entry to stay pending while offline, got %d", outbox.Pending())
+	}
+
+	tr.setOffline(false)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for outbox.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if outbox.Pending() != 0 {
+		t.Fatal("Expected the background sender to deliver the entry")
+	}
+	if sent := tr.sentSubjects(); len(sent) != 1 || sent[0] != "alert" {
+		t.Errorf("Expected the alert to be sent once, got %v", sent)
+	}
+}
+
+func TestOutboxFailedEntries(t *testing.T) {
+	tr := &outboxTransport{status: http.StatusBadRequest}
+	outbox, err := NewOutbox(newOutboxClient(tr), t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	defer outbox.Close()
+
+	outbox.Enqueue(outboxEmail("rejected"))
+	if err := outbox.Drain(context.Background()); err != nil {
+		t.Fatalf("Failed to drain outbox: %v", err)
+	}
+
+	failed := outbox.Failed()
+	if len(failed) != 1 {
+		t.Fatalf("Expected 1 failed entry, got %d", len(failed))
+	}
+	if failed[0].Email.Subject != "rejected" || failed[0].Attempts != 1 || failed[0].LastError == "" {
+		t.Errorf("Unexpected failed entry: %+v", failed[0])
+	}
+	if outbox.Pending() != 0 {
+		t.Errorf("Expected no pending entries, got %d", outbox.Pending())
+	}
+}
+
+func TestOutboxMaxAttempts(t *testing.T) {
+	tr := &outboxTransport{offline: true}
+	client := newOutboxClient(tr)
+	clock := newTestClock()
+	WithClock(clock)(client)
+
+	outbox, err := NewOutbox(client, t.TempDir(), WithOutboxMaxAttempts(3))
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	defer outbox.Close()
+
+	outbox.Enqueue(outboxEmail("unreachable"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := outbox.Drain(ctx); err != nil {
+		t.Fatalf("Failed to drain outbox: %v", err)
+	}
+
+	failed := outbox.Failed()
+	if len(failed) != 1 || failed[0].Attempts != 3 {
+		t.Fatalf("Expected the entry to fail after 3 attempts, got %+v", failed)
+	}
+}
+
+func TestOutboxRejectsInvalidEmails(t *testing.T) {
+	outbox, err := NewOutbox(newOutboxClient(&outboxTransport{}), t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	defer outbox.Close()
+
+	if _, err := outbox.Enqueue(NewTextEmail("from@example.com", "invalid", "Subject", "Hello")); err == nil {
+		t.Error("Expected invalid email to be rejected")
+	}
+	if _, err := outbox.Enqueue(nil); err == nil {
+		t.Error("Expected nil email to be rejected")
+	}
+	if _, err := outbox.EnqueuePriority(outboxEmail("Subject"), Priority(7)); err == nil {
+		t.Error("Expected unknown priority to be rejected")
+	}
+	if outbox.Pending() != 0 {
+		t.Errorf("Expected nothing to be stored, got %d", outbox.Pending())
+	}
+}
+
+func TestOutboxKeepsEntriesOnReadErrors(t *testing.T) {
+	dir := t.TempDir()
+	outbox, err := NewOutbox(newOutboxClient(&outboxTransport{}), dir)
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	outbox.Close()
+
+	// An entry that cannot be read is retried, not quarantined
+	name := "1-00000000000000000001.json"
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, name)); err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+	if _, err := outbox.deliverNext(context.Background()); err == nil {
+		t.Error("Expected the read error to be returned")
+	}
+	if _, err := os.Lstat(filepath.Join(dir, name)); err != nil {
+		t.Errorf("Expected the entry to be kept: %v", err)
+	}
+
+	// A corrupt entry that cannot be quarantined is reported too, so that
+	// the sender backs off instead of spinning on it
+	os.Remove(filepath.Join(dir, name))
+	os.WriteFile(filepath.Join(dir, name), []byte(`{"id":`), 0o600)
+	os.Remove(filepath.Join(dir, "quarantine"))
+	os.WriteFile(filepath.Join(dir, "quarantine"), nil, 0o600)
+	if _, err := outbox.deliverNext(context.Background()); err == nil {
+		t.Error("Expected the quarantine error to be returned")
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+		t.Errorf("Expected the entry to stay in place: %v", err)
+	}
+}
+
+func TestOutboxValidatesWithClientConfig(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.DefaultFrom = "from@example.com"
+	config.AddressValidator = NewValidator(AllowQuotedLocalPart())
+	transport := &outboxTransport{}
+	client := NewClientWithConfig(config, WithHTTPDoer(transport))
+
+	outbox, err := NewOutbox(client, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	defer outbox.Close()
+
+	// The sender comes from DefaultFrom and the quoted recipient is
+	// accepted by the client's validator, though not by DefaultValidator
+	if _, err := outbox.Enqueue(NewTextEmail("", `"john smith"@example.com`, "Defaults", "Hello")); err != nil {
+		t.Fatalf("Expected the email to be valid for the client, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := outbox.Drain(ctx); err != nil {
+		t.Fatalf("Failed to drain outbox: %v", err)
+	}
+	if got := transport.sentSubjects(); len(got) != 1 || got[0] != "Defaults" {
+		t.Errorf("Expected the email to be sent, got %v", got)
+	}
+}
+
+func TestOutboxParksResolvableSuspensions(t *testing.T) {
+	for _, tt := range []struct {
+		reason   string
+		attempts int
+	}{
+		{"payment_failure", 3},
+		{"manual_review", 3},
+		{"something_new", 3},
+		{"abuse_report", 1},
+	} {
+		tr := &outboxTransport{status: http.StatusForbidden, body: `{"message":"Account suspended","error":"` + tt.reason + `"}`}
+		client := newOutboxClient(tr)
+		WithClock(newTestClock())(client)
+
+		outbox, err := NewOutbox(client, t.TempDir(), WithOutboxMaxAttempts(3))
+		if err != nil {
+			t.Fatalf("Failed to open outbox: %v", err)
+		}
+		outbox.Enqueue(outboxEmail("suspended"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := outbox.Drain(ctx); err != nil {
+			t.Fatalf("Failed to drain outbox: %v", err)
+		}
+		cancel()
+
+		failed := outbox.Failed()
+		if len(failed) != 1 || failed[0].Attempts != tt.attempts {
+			t.Errorf("%s: expected the entry to fail after %d attempts, got %+v", tt.reason, tt.attempts, failed)
+		}
+		outbox.Close()
+	}
+}
+
+func TestOutboxKeepsVariables(t *testing.T) {
+	dir := t.TempDir()
+	offline := &outboxTransport{offline: true}
+	outbox, err := NewOutbox(newOutboxClient(offline), dir, WithOutboxBackoff(time.Hour, time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	email := outboxEmail("Hi {{name}}")
+	email.Variables = map[string]string{"name": "Jane"}
+	if _, err := outbox.EnqueuePriority(email, PriorityHigh); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	outbox.Close()
+
+	online := &outboxTransport{}
+	client := newOutboxClient(online)
+	client.config.AutoRender = true
+	reopened, err := NewOutbox(client, dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen outbox: %v", err)
+	}
+	defer reopened.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := reopened.Drain(ctx); err != nil {
+		t.Fatalf("Failed to drain outbox: %v", err)
+	}
+	if got := online.sentSubjects(); len(got) != 1 || got[0] != "Hi Jane" {
+		t.Errorf("Expected the variables to be rendered after a restart, got %v", got)
+	}
+}
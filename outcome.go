@@ -0,0 +1,106 @@
+package poodle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SendOutcome is the result of the Detailed send methods. It bundles the
+// email exactly as it was sent with the response, so that e.g. an audit
+// log can record what was sent without building the email again.
+type SendOutcome struct {
+	// Email is the email as sent: after AutoNormalize, AutoRender, PreSend
+	// hooks and SanitizeHTML, and with only its text part if it was sent
+	// again under FallbackToText. It is the email passed in if nothing
+	// changed it. If the send failed before the email was transformed, it
+	// is the email as far as it got.
+	Email    *Email
+	Response *EmailResponse
+	// RequestID is the X-Request-Id header of the last API response, or
+	// empty if no response was received
+	RequestID string
+	// Attempts is the number of requests made, including retries and the
+	// text fallback. It is zero if the email was not sent, e.g. because it
+	// failed validation or was suppressed as a duplicate.
+	Attempts  int
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// outcomeKey is the context key of the outcomeRecorder of a detailed send
+type outcomeKey struct{}
+
+// outcomeRecorder collects the parts of a SendOutcome from the send path
+type outcomeRecorder struct {
+	mutex     sync.Mutex
+	email     *Email
+	requestID string
+	attempts  int
+}
+
+// recordEmail notes the email as transformed so far
+func recordEmail(ctx context.Context, email *Email) {
+	if recorder, ok := ctx.Value(outcomeKey{}).(*outcomeRecorder); ok {
+		recorder.mutex.Lock()
+		recorder.email = email
+		recorder.mutex.Unlock()
+	}
+}
+
+// recordRequestID notes the request ID of an API response
+func recordRequestID(ctx context.Context, requestID string) {
+	if recorder, ok := ctx.Value(outcomeKey{}).(*outcomeRecorder); ok {
+		recorder.mutex.Lock()
+		recorder.requestID = requestID
+		recorder.mutex.Unlock()
+	}
+}
+
+// recordAttempts notes the number of requests a send made
+func recordAttempts(ctx context.Context, attempts int) {
+	if recorder, ok := ctx.Value(outcomeKey{}).(*outcomeRecorder); ok {
+		recorder.mutex.Lock()
+		recorder.attempts = attempts
+		recorder.mutex.Unlock()
+	}
+}
+
+// SendDetailed sends the email like SendContext and returns the outcome.
+// The outcome is returned on failure too, with the error, so that failed
+// sends can be recorded; its Response is then nil.
+func (c *Client) SendDetailed(ctx context.Context, email *Email) (*SendOutcome, error) {
+	recorder := &outcomeRecorder{email: email}
+	started := c.httpClient.clock.Now()
+
+	response, err := c.SendContext(context.WithValue(ctx, outcomeKey{}, recorder), email)
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return &SendOutcome{
+		Email:     recorder.email,
+		Response:  response,
+		RequestID: recorder.requestID,
+		Attempts:  recorder.attempts,
+		StartedAt: started,
+		Duration:  c.httpClient.clock.Now().Sub(started),
+	}, err
+}
+
+// SendHTMLDetailed sends an HTML email like SendHTML and returns the
+// outcome, including the email it built
+func (c *Client) SendHTMLDetailed(from, to, subject, html string) (*SendOutcome, error) {
+	return c.SendDetailed(context.Background(), NewHTMLEmail(from, to, subject, html))
+}
+
+// SendTextDetailed sends a plain text email like SendText and returns the
+// outcome, including the email it built
+func (c *Client) SendTextDetailed(from, to, subject, text string) (*SendOutcome, error) {
+	return c.SendDetailed(context.Background(), NewTextEmail(from, to, subject, text))
+}
+
+// SendWithBothDetailed sends an email with both HTML and text content like
+// SendWithBoth and returns the outcome, including the email it built
+func (c *Client) SendWithBothDetailed(from, to, subject, html, text string) (*SendOutcome, error) {
+	return c.SendDetailed(context.Background(), NewEmailWithBoth(from, to, subject, html, text))
+}
@@ -0,0 +1,144 @@
+package poodle
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// requestIDDoer answers with the given statuses in turn, numbering the
+// responses with X-Request-Id
+func requestIDDoer(statuses ...int) mockDoerFunc {
+	requests := 0
+	return func(req *http.Request) (*http.Response, error) {
+		status := statuses[requests]
+		requests++
+
+		var resp *http.Response
+		if status == http.StatusAccepted {
+			resp = acceptedResponse()
+			resp.Header = http.Header{}
+		} else {
+			resp = jsonResponse(status, `{"message":"HTML content rejected"}`)
+		}
+		resp.Header.Set("X-Request-Id", "req_"+strconv.Itoa(requests))
+		return resp, nil
+	}
+}
+
+func TestSendHTMLDetailed(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = requestIDDoer(http.StatusAccepted)
+
+	outcome, err := client.SendHTMLDetailed("from@example.com", "to@example.com", "Welcome", "<h1>Hello</h1>")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if outcome.Email == nil || outcome.Email.From != "from@example.com" || outcome.Email.To != "to@example.com" || outcome.Email.HTML != "<h1>Hello</h1>" {
+		t.Errorf("Expected the built email, got %+v", outcome.Email)
+	}
+	if outcome.Response == nil || !outcome.Response.Success {
+		t.Errorf("Expected the response, got %+v", outcome.Response)
+	}
+	if outcome.RequestID != "req_1" || outcome.Attempts != 1 {
+		t.Errorf("Expected request req_1 after 1 attempt, got %q after %d", outcome.RequestID, outcome.Attempts)
+	}
+	if outcome.StartedAt.IsZero() || outcome.Duration < 0 {
+		t.Errorf("Expected the timing to be recorded, got %v for %v", outcome.StartedAt, outcome.Duration)
+	}
+}
+
+func TestSendDetailedTransformedEmail(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AutoNormalize = true
+	config.PreSend = []func(*Email) error{func(email *Email) error {
+		email.Text += "\n\nUnsubscribe: https://example.com/u"
+		return nil
+	}}
+
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = requestIDDoer(http.StatusAccepted)
+
+	email := NewTextEmail("from@example.com", " To@Example.COM ", "Hello", "Hi")
+	outcome, err := client.SendDetailed(context.Background(), email)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if outcome.Email == email {
+		t.Fatal("Expected the outcome to hold the transformed copy")
+	}
+	if outcome.Email.To != "To@example.com" || outcome.Email.Text != "Hi\n\nUnsubscribe: https://example.com/u" {
+		t.Errorf("Expected the normalized email with the footer, got %+v", outcome.Email)
+	}
+	if email.To != " To@Example.COM " || email.Text != "Hi" {
+		t.Errorf("Expected the caller's email to be unchanged, got %+v", email)
+	}
+}
+
+func TestSendDetailedRetries(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 2
+
+	client := NewClientWithConfig(config, WithClock(newTestClock()))
+	client.httpClient.httpClient = requestIDDoer(http.StatusInternalServerError, http.StatusAccepted)
+
+	outcome, err := client.SendTextDetailed("from@example.com", "to@example.com", "Hello", "Hi")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if outcome.Attempts != 2 || outcome.RequestID != "req_2" {
+		t.Errorf("Expected request req_2 after 2 attempts, got %q after %d", outcome.RequestID, outcome.Attempts)
+	}
+	if outcome.Duration <= 0 {
+		t.Errorf("Expected the retry backoff in the duration, got %v", outcome.Duration)
+	}
+}
+
+func TestSendDetailedFallbackToText(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.FallbackToText = true
+
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = requestIDDoer(http.StatusUnprocessableEntity, http.StatusAccepted)
+
+	outcome, err := client.SendWithBothDetailed("from@example.com", "to@example.com", "Sale", "<h1>Sale</h1>", "Sale")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if outcome.Email.HTML != "" || outcome.Email.Text != "Sale" {
+		t.Errorf("Expected the text-only email that was sent, got %+v", outcome.Email)
+	}
+	if outcome.Attempts != 2 || outcome.RequestID != "req_2" || !outcome.Response.FallbackToText {
+		t.Errorf("Expected the fallback response after 2 attempts, got %+v", outcome)
+	}
+}
+
+func TestSendDetailedFailure(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = requestIDDoer(http.StatusBadRequest)
+
+	outcome, err := client.SendTextDetailed("from@example.com", "", "Hello", "Hi")
+	if err == nil {
+		t.Fatal("Expected a validation error")
+	}
+	if outcome == nil || outcome.Email == nil || outcome.Email.Subject != "Hello" {
+		t.Fatalf("Expected the outcome with the built email, got %+v", outcome)
+	}
+	if outcome.Response != nil || outcome.Attempts != 0 || outcome.RequestID != "" {
+		t.Errorf("Expected no request to have been made, got %+v", outcome)
+	}
+
+	outcome, err = client.SendTextDetailed("from@example.com", "to@example.com", "Hello", "Hi")
+	if err == nil {
+		t.Fatal("Expected the API error")
+	}
+	if outcome.Attempts != 1 || outcome.RequestID != "req_1" {
+		t.Errorf("Expected the failed request to be recorded, got %+v", outcome)
+	}
+}
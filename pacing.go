@@ -0,0 +1,96 @@
+package poodle
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// PacingThreshold is the number of remaining requests in the rate-limit
+// window below which adaptive pacing spaces out requests
+const PacingThreshold = 10
+
+// PacingEvent describes a change of the adaptive pacing interval
+type PacingEvent struct {
+	// Remaining is the ratelimit-remaining value that caused the change
+	Remaining int
+	// Reset is the time until the rate-limit window resets
+	Reset time.Duration
+	// Interval is the new minimum delay between requests; zero means
+	// pacing has stopped
+	Interval time.Duration
+}
+
+// pacingInterval spreads the remaining request budget evenly over the time
+// until the rate-limit window resets
+func pacingInterval(remaining int, reset time.Duration) time.Duration {
+	if remaining >= PacingThreshold || reset <= 0 {
+		return 0
+	}
+	if remaining <= 0 {
+		return reset
+	}
+	return reset / time.Duration(remaining)
+}
+
+// resetDuration interprets a ratelimit-reset header, which is either the
+// number of seconds until the window resets or a Unix timestamp
+func resetDuration(header http.Header, now time.Time) time.Duration {
+	reset := headerInt(header, "ratelimit-reset")
+	if reset < 0 {
+		return 0
+	}
+	// Values this large cannot be a number of seconds within a window
+	if reset > 1000000000 {
+		return time.Unix(int64(reset), 0).Sub(now)
+	}
+	return time.Duration(reset) * time.Second
+}
+
+// observePacing updates the adaptive pacing interval from the rate-limit
+// headers of a response. engaged is true if pacing started with this
+// response, active is true while pacing spaces out requests.
+func (c *HTTPClient) observePacing(config *Config, header http.Header) (engaged, active bool) {
+	remaining := headerInt(header, "ratelimit-remaining")
+	if remaining < 0 {
+		return false, c.pacer.currentInterval() > 0
+	}
+
+	reset := readRateLimitTiming(config, header, c.clock.Now()).reset
+	interval := pacingInterval(remaining, reset)
+	previous := c.pacer.setInterval(interval)
+	if previous != interval {
+		if config.logs(LogLevelInfo) {
+			log.Printf("Poodle API Pacing: %d requests remaining, spacing requests %s apart", remaining, interval)
+		}
+		if config.OnPacing != nil {
+			config.OnPacing(PacingEvent{Remaining: remaining, Reset: reset, Interval: interval})
+		}
+	}
+	return previous == 0 && interval > 0, interval > 0
+}
+
+// throttle waits until the client-side rate limits and adaptive pacing allow
+// the next request to start
+func (c *HTTPClient) throttle(ctx context.Context) error {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	if c.shared != nil {
+		if err := c.shared.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	if c.pacer != nil {
+		if err := c.pacer.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
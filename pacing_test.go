@@ -0,0 +1,126 @@
+package poodle
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacingInterval(t *testing.T) {
+	tests := []struct {
+		remaining int
+		reset     time.Duration
+		expected  time.Duration
+	}{
+		{100, time.Minute, 0},
+		{PacingThreshold, time.Minute, 0},
+		{5, 10 * time.Second, 2 * time.Second},
+		{1, 10 * time.Second, 10 * time.Second},
+		{0, 10 * time.Second, 10 * time.Second},
+		{5, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if interval := pacingInterval(tt.remaining, tt.reset); interval != tt.expected {
+			t.Errorf("pacingInterval(%d, %s) = %s, want %s", tt.remaining, tt.reset, interval, tt.expected)
+		}
+	}
+}
+
+func TestResetDuration(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	if d := resetDuration(http.Header{"Ratelimit-Reset": {"30"}}, now); d != 30*time.Second {
+		t.Errorf("Expected 30s for a delta, got %s", d)
+	}
+	if d := resetDuration(http.Header{"Ratelimit-Reset": {"1700000045"}}, now); d != 45*time.Second {
+		t.Errorf("Expected 45s for a Unix timestamp, got %s", d)
+	}
+	if d := resetDuration(http.Header{}, now); d != 0 {
+		t.Errorf("Expected 0 without the header, got %s", d)
+	}
+}
+
+func TestAdaptivePacing(t *testing.T) {
+	var events []PacingEvent
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AdaptivePacing = true
+	config.OnPacing = func(event PacingEvent) {
+		events = append(events, event)
+	}
+
+	clock := newTestClock()
+	client := NewClientWithConfig(config, WithClock(clock))
+
+	// The remaining budget shrinks from 12 while the window resets in 12s
+	remaining := 12
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		remaining--
+		return &http.Response{
+			StatusCode: http.StatusAccepted,
+			Header: http.Header{
+				"Ratelimit-Remaining": {strconv.Itoa(remaining)},
+				"Ratelimit-Reset":     {"12"},
+			},
+			Body: io.NopCloser(strings.NewReader(`{"success": true, "message": "Email queued"}`)),
+		}, nil
+	})
+
+	for i := 0; i < 8; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	sleeps := clock.Sleeps()
+	if len(sleeps) == 0 {
+		t.Fatal("Expected requests to be paced once the budget got low")
+	}
+	for i := 1; i < len(sleeps); i++ {
+		if sleeps[i] <= sleeps[i-1] {
+			t.Errorf("Expected delays to grow as the budget shrinks, got %v", sleeps)
+			break
+		}
+	}
+
+	if len(events) == 0 || events[0].Remaining != PacingThreshold-1 || events[0].Interval != 12*time.Second/time.Duration(PacingThreshold-1) {
+		t.Errorf("Expected a pacing event when pacing kicked in, got %+v", events)
+	}
+}
+
+func TestAdaptivePacingStops(t *testing.T) {
+	var events []PacingEvent
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AdaptivePacing = true
+	config.OnPacing = func(event PacingEvent) {
+		events = append(events, event)
+	}
+
+	client := NewClientWithConfig(config, WithClock(newTestClock()))
+
+	budgets := []string{"2", "100"}
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		response := acceptedResponse()
+		response.Header = http.Header{
+			"Ratelimit-Remaining": {budgets[0]},
+			"Ratelimit-Reset":     {"10"},
+		}
+		budgets = budgets[1:]
+		return response, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if len(events) != 2 || events[0].Interval != 5*time.Second || events[1].Interval != 0 {
+		t.Errorf("Expected pacing to start and stop, got %+v", events)
+	}
+}
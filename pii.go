@@ -0,0 +1,114 @@
+package poodle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PIIMode controls how recipient addresses appear in errors, their
+// context maps, logs and captured exchanges
+type PIIMode string
+
+// PII modes
+const (
+	// PIIModeFull shows addresses as they are. The zero value is
+	// equivalent.
+	PIIModeFull PIIMode = "full"
+	// PIIModeHashed replaces addresses with a prefix of their SHA-256
+	// digest, so that occurrences of the same address can be correlated
+	PIIModeHashed PIIMode = "hashed"
+	// PIIModeOmit replaces addresses with a placeholder
+	PIIModeOmit PIIMode = "omit"
+)
+
+// omittedAddress replaces addresses in PIIModeOmit
+const omittedAddress = "[email]"
+
+// piiAddress matches email-looking tokens
+var piiAddress = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// ParsePIIMode parses a mode name: full, hashed or omit
+func ParsePIIMode(name string) (PIIMode, error) {
+	mode := PIIMode(strings.ToLower(strings.TrimSpace(name)))
+	if mode == "" || mode.valid() {
+		return mode, nil
+	}
+	return PIIModeFull, fmt.Errorf("unknown PII mode %q", name)
+}
+
+// valid reports whether the mode is one of the PII modes
+func (m PIIMode) valid() bool {
+	return m == "" || m == PIIModeFull || m == PIIModeHashed || m == PIIModeOmit
+}
+
+// HashAddress returns the token PIIModeHashed replaces the address with:
+// "sha256:" followed by the first 12 hex digits of the digest of the
+// lowercased address
+func HashAddress(address string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(address))))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// scrub replaces the addresses in s according to the mode
+func (m PIIMode) scrub(s string) string {
+	switch m {
+	case PIIModeHashed:
+		return piiAddress.ReplaceAllStringFunc(s, HashAddress)
+	case PIIModeOmit:
+		return piiAddress.ReplaceAllLiteralString(s, omittedAddress)
+	default:
+		return s
+	}
+}
+
+// scrubBytes is scrub for request and response bodies
+func (m PIIMode) scrubBytes(b []byte) string {
+	return m.scrub(string(b))
+}
+
+// base gives access to the BaseError embedded in every error type
+func (e *BaseError) base() *BaseError {
+	return e
+}
+
+// scrubError replaces the addresses in the message and context of a
+// Poodle error, as well as in ValidationError field errors and
+// HTTPError response bodies. The error is modified in place and returned.
+func (m PIIMode) scrubError(err error) error {
+	if err == nil || m == "" || m == PIIModeFull {
+		return err
+	}
+
+	var withBase interface{ base() *BaseError }
+	if errors.As(err, &withBase) {
+		base := withBase.base()
+		base.Message = m.scrub(base.Message)
+		for key, value := range base.ContextMap {
+			if s, ok := value.(string); ok {
+				base.ContextMap[key] = m.scrub(s)
+			}
+		}
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		for field, messages := range validationErr.Errors {
+			scrubbed := make([]string, len(messages))
+			for i, message := range messages {
+				scrubbed[i] = m.scrub(message)
+			}
+			validationErr.Errors[field] = scrubbed
+		}
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		httpErr.ResponseBody = m.scrub(httpErr.ResponseBody)
+	}
+
+	return err
+}
@@ -0,0 +1,120 @@
+package poodle
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const piiAddressUnderTest = "secret.person@example.com"
+
+// serializeError returns everything an error tracker would receive
+func serializeError(t *testing.T, err error) string {
+	t.Helper()
+
+	var poodleErr PoodleError
+	if !errors.As(err, &poodleErr) {
+		t.Fatalf("Expected a Poodle error, got %T", err)
+	}
+	context, marshalErr := json.Marshal(poodleErr.Context())
+	if marshalErr != nil {
+		t.Fatalf("Unexpected error: %v", marshalErr)
+	}
+
+	serialized := err.Error() + "\n" + string(context)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		serialized += "\n" + httpErr.ResponseBody
+	}
+	return serialized
+}
+
+func TestPIIModes(t *testing.T) {
+	responses := map[string]struct {
+		status int
+		body   string
+	}{
+		"api validation": {http.StatusBadRequest, `{"message": "Recipient ` + piiAddressUnderTest + ` is suppressed", "error": "` + piiAddressUnderTest + ` bounced"}`},
+		"http error":     {http.StatusInternalServerError, `{"message": "Failed to send to ` + piiAddressUnderTest + `"}`},
+		"blocked domain": {},
+	}
+
+	for _, mode := range []PIIMode{"", PIIModeFull, PIIModeHashed, PIIModeOmit} {
+		for name, response := range responses {
+			logs := captureLog(t)
+
+			config := NewConfig()
+			config.APIKey = "test_api_key"
+			config.PIIMode = mode
+			config.LogLevel = LogLevelTrace
+			config.CaptureExchanges = true
+			if response.status == 0 {
+				config.BlockedRecipientDomains = []string{"example.com"}
+			}
+
+			client := NewClientWithConfig(config)
+			client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: response.status,
+					Body:       io.NopCloser(strings.NewReader(response.body)),
+				}, nil
+			})
+
+			_, err := client.SendText("from@sender.com", piiAddressUnderTest, "Subject", "Hello")
+			serialized := serializeError(t, err)
+			if mode == PIIModeHashed && !strings.Contains(serialized, HashAddress(piiAddressUnderTest)) {
+				t.Errorf("%s: expected the hashed address in the error, got:\n%s", name, serialized)
+			}
+			if exchange := client.LastExchange(); exchange != nil {
+				var dump strings.Builder
+				exchange.WriteTo(&dump)
+				serialized += "\n" + dump.String()
+			}
+			serialized += "\n" + logs.String()
+
+			switch mode {
+			case "", PIIModeFull:
+				if !strings.Contains(serialized, piiAddressUnderTest) {
+					t.Errorf("%s/%s: expected the address to be shown, got:\n%s", mode, name, serialized)
+				}
+			case PIIModeHashed:
+				if strings.Contains(serialized, piiAddressUnderTest) || !strings.Contains(serialized, HashAddress(piiAddressUnderTest)) {
+					t.Errorf("%s/%s: expected the address to be hashed, got:\n%s", mode, name, serialized)
+				}
+			case PIIModeOmit:
+				if strings.Contains(serialized, piiAddressUnderTest) || strings.Contains(serialized, "sha256:") || !strings.Contains(serialized, omittedAddress) {
+					t.Errorf("%s/%s: expected the address to be omitted, got:\n%s", mode, name, serialized)
+				}
+			}
+		}
+	}
+}
+
+func TestHashAddress(t *testing.T) {
+	hash := HashAddress("Jane@Example.com")
+	if hash != HashAddress(" jane@example.com ") {
+		t.Error("Expected the hash to ignore case and whitespace")
+	}
+	if !strings.HasPrefix(hash, "sha256:") || len(hash) != len("sha256:")+12 {
+		t.Errorf("Unexpected hash format %s", hash)
+	}
+}
+
+func TestParsePIIMode(t *testing.T) {
+	if mode, err := ParsePIIMode(" Hashed "); err != nil || mode != PIIModeHashed {
+		t.Errorf("Expected hashed, got %s (%v)", mode, err)
+	}
+	if _, err := ParsePIIMode("masked"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.PIIMode = "masked"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected Validate to reject an unknown PII mode")
+	}
+}
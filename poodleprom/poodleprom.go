@@ -0,0 +1,120 @@
+// Package poodleprom exports Poodle client metrics to Prometheus.
+//
+// Create the collectors on a registry and attach them to one or more
+// clients through Config.Metrics:
+//
+//	metrics, err := poodleprom.New(registry)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	config := poodle.NewConfigFromEnv()
+//	config.Metrics = metrics
+//	client := poodle.NewClientWithConfig(config)
+package poodleprom
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/usepoodle/poodle-go"
+)
+
+// Metrics implements poodle.MetricsHook with Prometheus collectors:
+//
+//   - poodle_emails_sent_total{status}: sends by outcome ("success" or the error type)
+//   - poodle_request_duration_seconds{code}: HTTP attempt latency by status code
+//   - poodle_retries_total: retried attempts
+//   - poodle_rate_limit_remaining: last ratelimit-remaining header value
+//
+// A single Metrics value may be attached to any number of clients.
+type Metrics struct {
+	emailsSent         *prometheus.CounterVec
+	requestDuration    *prometheus.HistogramVec
+	retries            prometheus.Counter
+	rateLimitRemaining prometheus.Gauge
+}
+
+var _ poodle.MetricsHook = (*Metrics)(nil)
+
+// New creates the collectors and registers them on reg. If collectors with
+// the same descriptors are already registered, for example by a previous
+// call with the same registry, the existing collectors are reused.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		emailsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "poodle_emails_sent_total",
+			Help: "Emails sent through the Poodle API by outcome.",
+		}, []string{"status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "poodle_request_duration_seconds",
+			Help:    "Duration of HTTP requests to the Poodle API.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"code"}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "poodle_retries_total",
+			Help: "Requests to the Poodle API that were retried.",
+		}),
+		rateLimitRemaining: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "poodle_rate_limit_remaining",
+			Help: "Requests remaining in the current Poodle API rate limit window.",
+		}),
+	}
+
+	var err error
+	if m.emailsSent, err = register(reg, m.emailsSent); err != nil {
+		return nil, err
+	}
+	if m.requestDuration, err = register(reg, m.requestDuration); err != nil {
+		return nil, err
+	}
+	if m.retries, err = register(reg, m.retries); err != nil {
+		return nil, err
+	}
+	if m.rateLimitRemaining, err = register(reg, m.rateLimitRemaining); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// register registers c, returning the already registered collector if an
+// identical one exists
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		var alreadyRegistered prometheus.AlreadyRegisteredError
+		if errors.As(err, &alreadyRegistered) {
+			if existing, ok := alreadyRegistered.ExistingCollector.(C); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+// ObserveSend implements poodle.MetricsHook
+func (m *Metrics) ObserveSend(status string) {
+	m.emailsSent.WithLabelValues(status).Inc()
+}
+
+// ObserveRequest implements poodle.MetricsHook
+func (m *Metrics) ObserveRequest(statusCode int, duration time.Duration) {
+	code := "error"
+	if statusCode > 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	m.requestDuration.WithLabelValues(code).Observe(duration.Seconds())
+}
+
+// ObserveRetry implements poodle.MetricsHook
+func (m *Metrics) ObserveRetry() {
+	m.retries.Inc()
+}
+
+// ObserveRateLimitRemaining implements poodle.MetricsHook
+func (m *Metrics) ObserveRateLimitRemaining(remaining int) {
+	m.rateLimitRemaining.Set(float64(remaining))
+}
@@ -0,0 +1,127 @@
+package poodleprom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/usepoodle/poodle-go"
+	"github.com/usepoodle/poodle-go/poodletest"
+)
+
+func TestMetricsAfterSimulatedSends(t *testing.T) {
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := New(registry)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	config := server.Config()
+	config.Metrics = metrics
+	config.MaxRetries = 1
+	config.RetryBackoff = time.Millisecond
+	client := poodle.NewClientWithConfig(config)
+
+	server.Enqueue(poodletest.ServerError())
+	if _, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi"); err != nil {
+		t.Fatalf("Expected success after retry, got: %v", err)
+	}
+
+	server.Enqueue(poodletest.Unauthorized())
+	if _, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi"); err == nil {
+		t.Fatal("Expected authentication error")
+	}
+
+	expected := `
+# HELP poodle_emails_sent_total Emails sent through the Poodle API by outcome.
+# TYPE poodle_emails_sent_total counter
+poodle_emails_sent_total{status="authentication_error"} 1
+poodle_emails_sent_total{status="success"} 1
+# HELP poodle_retries_total Requests to the Poodle API that were retried.
+# TYPE poodle_retries_total counter
+poodle_retries_total 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "poodle_emails_sent_total", "poodle_retries_total"); err != nil {
+		t.Error(err)
+	}
+
+	if count := testutil.CollectAndCount(metrics.requestDuration); count != 3 {
+		t.Errorf("Expected histograms for 3 status codes, got %d", count)
+	}
+}
+
+func TestRateLimitRemainingGauge(t *testing.T) {
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := New(registry)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	config := server.Config()
+	config.Metrics = metrics
+	client := poodle.NewClientWithConfig(config)
+
+	server.Enqueue(poodletest.RateLimited(time.Minute, 100))
+	_, _ = client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+
+	if value := testutil.ToFloat64(metrics.rateLimitRemaining); value != 0 {
+		t.Errorf("Expected remaining gauge to be 0, got %v", value)
+	}
+
+	metrics.ObserveRateLimitRemaining(42)
+	if value := testutil.ToFloat64(metrics.rateLimitRemaining); value != 42 {
+		t.Errorf("Expected remaining gauge to be 42, got %v", value)
+	}
+}
+
+func TestNewReusesRegisteredCollectors(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	first, err := New(registry)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	second, err := New(registry)
+	if err != nil {
+		t.Fatalf("Expected registering twice to succeed, got: %v", err)
+	}
+
+	first.ObserveSend("success")
+	second.ObserveSend("success")
+
+	if value := testutil.ToFloat64(first.emailsSent.WithLabelValues("success")); value != 2 {
+		t.Errorf("Expected both instances to share counters, got %v", value)
+	}
+}
+
+func TestMetricsSharedAcrossClients(t *testing.T) {
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := New(registry)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		config := server.Config()
+		config.Metrics = metrics
+		client := poodle.NewClientWithConfig(config)
+		if _, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if value := testutil.ToFloat64(metrics.emailsSent.WithLabelValues("success")); value != 3 {
+		t.Errorf("Expected 3 successful sends, got %v", value)
+	}
+}
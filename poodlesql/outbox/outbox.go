@@ -0,0 +1,422 @@
+// Package outbox implements the transactional outbox pattern with
+// database/sql: an email is inserted into an outbox table in the same
+// transaction as the business data it belongs to, so that it is stored if
+// and only if the transaction commits, and a Relay sends the stored emails
+// afterwards.
+//
+// Create the table with Migrate, or with the statements of Schema in your
+// migration tool, enqueue emails in your transactions and run a relay:
+//
+//	box, err := outbox.New(poodlesql.Postgres)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	tx, err := db.BeginTx(ctx, nil)
+//	...
+//	if _, err := tx.ExecContext(ctx, "INSERT INTO orders ...", ...); err != nil {
+//		return err
+//	}
+//	if err := box.EnqueueTx(ctx, tx, email); err != nil {
+//		return err
+//	}
+//	return tx.Commit()
+//
+//	relay := box.NewRelay(db, client)
+//	go relay.Run(ctx)
+//
+// Emails are sent at least once: a relay that stops between sending an
+// email and marking its row sent leaves the row to be sent again once its
+// lease expires.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+	"github.com/usepoodle/poodle-go/poodlesql"
+)
+
+// DefaultTable is the name of the outbox table
+const DefaultTable = "poodle_outbox"
+
+// Row statuses
+const (
+	// StatusPending rows wait to be sent, or to be retried after a
+	// transient failure
+	StatusPending = "pending"
+	// StatusSent rows were accepted by the API
+	StatusSent = "sent"
+	// StatusFailed rows failed permanently or ran out of attempts
+	StatusFailed = "failed"
+)
+
+// Option configures an Outbox
+type Option func(*Outbox)
+
+// WithTable sets the name of the outbox table. The name may be qualified
+// with a schema, such as "mail.outbox".
+func WithTable(name string) Option {
+	return func(o *Outbox) {
+		o.table = name
+	}
+}
+
+// WithClock sets the time source for enqueue times, leases and retry
+// delays, which defaults to the time package. Relays created from the
+// outbox use it too.
+func WithClock(clock poodle.Clock) Option {
+	return func(o *Outbox) {
+		if clock != nil {
+			o.now = clock.Now
+		}
+	}
+}
+
+// Outbox describes the outbox table of a database. Each email is a row
+// holding its JSON encoding, its template variables, which are not part of
+// the encoding, its status, the number of send attempts and
+// the last error. Times are stored as Unix milliseconds.
+type Outbox struct {
+	dialect poodlesql.Dialect
+	table   string
+	now     func() time.Time
+}
+
+// New returns the outbox of a database of the dialect
+func New(dialect poodlesql.Dialect, opts ...Option) (*Outbox, error) {
+	o := &Outbox{dialect: dialect, table: DefaultTable, now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if dialect != poodlesql.Postgres && dialect != poodlesql.SQLite {
+		return nil, fmt.Errorf("outbox: unsupported dialect %q", dialect)
+	}
+	if !poodlesql.ValidTableName(o.table) {
+		return nil, fmt.Errorf("outbox: invalid table name %q", o.table)
+	}
+	return o, nil
+}
+
+// Schema returns the statements creating the table and its index. They
+// can be run more than once.
+func (o *Outbox) Schema() []string {
+	id := "BIGSERIAL PRIMARY KEY"
+	if o.dialect == poodlesql.SQLite {
+		id = "INTEGER PRIMARY KEY AUTOINCREMENT"
+	}
+	index := strings.ReplaceAll(o.table, ".", "_") + "_due"
+	if schema, _, ok := strings.Cut(o.table, "."); ok && o.dialect == poodlesql.SQLite {
+		index = schema + "." + index
+	}
+
+	return []string{
+		`CREATE TABLE IF NOT EXISTS ` + o.table + ` (
+	id ` + id + `,
+	email TEXT NOT NULL,
+	variables TEXT,
+	status TEXT NOT NULL DEFAULT '` + StatusPending + `',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	created_at BIGINT NOT NULL,
+	next_attempt_at BIGINT NOT NULL,
+	sent_at BIGINT
+)`,
+		`CREATE INDEX IF NOT EXISTS ` + index + ` ON ` + o.table + ` (status, next_attempt_at, id)`,
+	}
+}
+
+// Migrate creates the table and its index if they do not exist
+func (o *Outbox) Migrate(ctx context.Context, db *sql.DB) error {
+	for _, statement := range o.Schema() {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("outbox: migrate: %w", err)
+		}
+	}
+	return nil
+}
+
+// EnqueueTx inserts the email into the outbox within the transaction, so
+// that it is only sent if the transaction commits. The email is stored as
+// given: the defaults, rendering and validation of the relay's client
+// apply when it is sent, and an email the client rejects is marked failed.
+// Only a nil email is rejected, with a poodle.ValidationError. Variables
+// are stored with the email, so that a client with AutoRender renders them
+// when the email is relayed. Priority is not stored.
+func (o *Outbox) EnqueueTx(ctx context.Context, tx *sql.Tx, email *poodle.Email) error {
+	if email == nil {
+		// Validate reports the missing email
+		return email.Validate()
+	}
+	encoded, err := json.Marshal(email)
+	if err != nil {
+		return fmt.Errorf("outbox: encode email: %w", err)
+	}
+	var variables sql.NullString
+	if email.Variables != nil {
+		values, err := json.Marshal(email.Variables)
+		if err != nil {
+			return fmt.Errorf("outbox: encode variables: %w", err)
+		}
+		variables = sql.NullString{String: string(values), Valid: true}
+	}
+
+	now := o.now().UnixMilli()
+	if _, err := tx.ExecContext(ctx, o.dialect.Rebind(`INSERT INTO `+o.table+`
+	(email, variables, status, attempts, created_at, next_attempt_at)
+	VALUES (?, ?, ?, 0, ?, ?)`),
+		string(encoded), variables, StatusPending, now, now,
+	); err != nil {
+		return fmt.Errorf("outbox: enqueue: %w", err)
+	}
+	return nil
+}
+
+// Relay defaults
+const (
+	DefaultPollInterval = time.Second
+	DefaultBatchSize    = 10
+	DefaultLease        = 5 * time.Minute
+	DefaultMaxAttempts  = 10
+)
+
+// recordTimeout bounds recording the outcome of a row, which is not
+// cancelled with the relay's context
+const recordTimeout = 10 * time.Second
+
+// RelayOption configures a Relay
+type RelayOption func(*Relay)
+
+// WithPollInterval sets how long Run waits before polling again once the
+// outbox has no due rows
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = d
+	}
+}
+
+// WithBatchSize sets the number of rows claimed at a time
+func WithBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = n
+	}
+}
+
+// WithLease sets how long claimed rows are hidden from other relays. A
+// relay that stops while sending leaves its rows to be sent again once
+// the lease expires, so it should be well above the time a batch takes.
+func WithLease(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.lease = d
+	}
+}
+
+// WithMaxAttempts marks a row failed after n attempts that failed
+// transiently
+func WithMaxAttempts(n int) RelayOption {
+	return func(r *Relay) {
+		r.maxAttempts = n
+	}
+}
+
+// WithRetryPolicy sets the delay before a transiently failed row is sent
+// again. The default is poodle.ExponentialBackoff with its default base
+// and cap. A policy that stops retrying marks the row failed.
+func WithRetryPolicy(policy poodle.RetryPolicy) RelayOption {
+	return func(r *Relay) {
+		r.retryPolicy = policy
+	}
+}
+
+// Relay sends the emails of an outbox table. Any number of relays, in one
+// or several processes, may share a table: a relay claims due rows by
+// pushing their next attempt past its lease in a single statement, with
+// FOR UPDATE SKIP LOCKED on Postgres, so that no two relays claim the same
+// row. Sent rows are marked sent. Rows that fail transiently, according to
+// poodle.IsRetryable, are retried with backoff; other failures and rows
+// that run out of attempts are marked failed with their last error.
+type Relay struct {
+	outbox       *Outbox
+	db           *sql.DB
+	sender       poodle.Sender
+	pollInterval time.Duration
+	batchSize    int
+	lease        time.Duration
+	maxAttempts  int
+	retryPolicy  poodle.RetryPolicy
+}
+
+// NewRelay returns a relay sending the emails of the outbox in db through
+// the sender, usually a *poodle.Client
+func (o *Outbox) NewRelay(db *sql.DB, sender poodle.Sender, opts ...RelayOption) *Relay {
+	r := &Relay{
+		outbox:       o,
+		db:           db,
+		sender:       sender,
+		pollInterval: DefaultPollInterval,
+		batchSize:    DefaultBatchSize,
+		lease:        DefaultLease,
+		maxAttempts:  DefaultMaxAttempts,
+		retryPolicy:  poodle.ExponentialBackoff{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = DefaultBatchSize
+	}
+	return r
+}
+
+// Run relays emails until the context is done, polling for due rows while
+// the outbox is empty. Errors of the database are retried at the next
+// poll. It returns the context's error.
+func (r *Relay) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		n, err := r.RelayOnce(ctx)
+		if err == nil && n == r.batchSize {
+			// There may be more due rows
+			timer.Reset(0)
+			continue
+		}
+		timer.Reset(r.pollInterval)
+	}
+}
+
+// RelayOnce claims a batch of due rows, sends their emails and records
+// the outcomes. It returns the number of rows claimed.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	rows, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		if err := r.deliver(ctx, row); err != nil {
+			return len(rows), err
+		}
+	}
+	return len(rows), nil
+}
+
+// outboxRow is a claimed row
+type outboxRow struct {
+	id        int64
+	email     string
+	variables sql.NullString
+	attempts  int
+}
+
+// claim leases a batch of due rows
+func (r *Relay) claim(ctx context.Context) ([]outboxRow, error) {
+	now := r.outbox.now()
+	lock := ""
+	if r.outbox.dialect == poodlesql.Postgres {
+		lock = " FOR UPDATE SKIP LOCKED"
+	}
+
+	table := r.outbox.table
+	rows, err := r.db.QueryContext(ctx, r.outbox.dialect.Rebind(`UPDATE `+table+`
+	SET next_attempt_at = ?
+	WHERE id IN (SELECT id FROM `+table+`
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`+lock+`)
+	RETURNING id, email, variables, attempts`),
+		now.Add(r.lease).UnixMilli(), StatusPending, now.UnixMilli(), r.batchSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: claim: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []outboxRow
+	for rows.Next() {
+		var row outboxRow
+		if err := rows.Scan(&row.id, &row.email, &row.variables, &row.attempts); err != nil {
+			return nil, fmt.Errorf("outbox: claim: %w", err)
+		}
+		claimed = append(claimed, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("outbox: claim: %w", err)
+	}
+	return claimed, nil
+}
+
+// deliver sends the email of a claimed row and records the outcome. The
+// outcome is recorded even if ctx is done once the send returned, so that
+// an email sent just before the relay stopped is not sent again.
+func (r *Relay) deliver(ctx context.Context, row outboxRow) error {
+	attempts := row.attempts + 1
+	record, cancel := context.WithTimeout(withoutCancel{ctx}, recordTimeout)
+	defer cancel()
+
+	var email poodle.Email
+	if err := json.Unmarshal([]byte(row.email), &email); err != nil {
+		return r.fail(record, row.id, attempts, fmt.Errorf("decode email: %w", err))
+	}
+	if row.variables.Valid {
+		if err := json.Unmarshal([]byte(row.variables.String), &email.Variables); err != nil {
+			return r.fail(record, row.id, attempts, fmt.Errorf("decode variables: %w", err))
+		}
+	}
+
+	_, sendErr := r.sender.SendContext(ctx, &email)
+	if sendErr == nil {
+		return r.update(record, `UPDATE `+r.outbox.table+`
+	SET status = ?, attempts = ?, last_error = NULL, sent_at = ?
+	WHERE id = ?`, StatusSent, attempts, r.outbox.now().UnixMilli(), row.id)
+	}
+	if ctx.Err() != nil {
+		// The lease expires and another poll sends the row again
+		return ctx.Err()
+	}
+
+	if poodle.IsRetryable(sendErr) && (r.maxAttempts <= 0 || attempts < r.maxAttempts) {
+		if delay, ok := r.retryPolicy.NextDelay(attempts, sendErr); ok {
+			return r.update(record, `UPDATE `+r.outbox.table+`
+	SET attempts = ?, last_error = ?, next_attempt_at = ?
+	WHERE id = ?`, attempts, sendErr.Error(), r.outbox.now().Add(delay).UnixMilli(), row.id)
+		}
+	}
+	return r.fail(record, row.id, attempts, sendErr)
+}
+
+// withoutCancel is a context with the values of its parent that is never
+// cancelled, as context.WithoutCancel returns from Go 1.21
+type withoutCancel struct {
+	context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (withoutCancel) Done() <-chan struct{}       { return nil }
+func (withoutCancel) Err() error                  { return nil }
+
+// fail marks a row failed
+func (r *Relay) fail(ctx context.Context, id int64, attempts int, cause error) error {
+	return r.update(ctx, `UPDATE `+r.outbox.table+`
+	SET status = ?, attempts = ?, last_error = ?
+	WHERE id = ?`, StatusFailed, attempts, cause.Error(), id)
+}
+
+// update runs a statement recording the outcome of a row
+func (r *Relay) update(ctx context.Context, query string, args ...interface{}) error {
+	if _, err := r.db.ExecContext(ctx, r.outbox.dialect.Rebind(query), args...); err != nil {
+		return fmt.Errorf("outbox: record outcome: %w", err)
+	}
+	return nil
+}
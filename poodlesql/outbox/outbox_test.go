@@ -0,0 +1,340 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+	"github.com/usepoodle/poodle-go/poodlesql"
+	"github.com/usepoodle/poodle-go/poodletest"
+	_ "modernc.org/sqlite"
+)
+
+// newSQLiteOutbox returns a migrated outbox in an in-memory database
+func newSQLiteOutbox(t *testing.T, opts ...Option) (*Outbox, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	// Every connection to :memory: opens its own database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	box, err := New(poodlesql.SQLite, opts...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := box.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("Expected migration to succeed, got %v", err)
+	}
+	if err := box.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("Expected a second migration to succeed, got %v", err)
+	}
+	return box, db
+}
+
+// enqueue commits the emails in one transaction
+func enqueue(t *testing.T, box *Outbox, db *sql.DB, emails ...*poodle.Email) {
+	t.Helper()
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range emails {
+		if err := box.EnqueueTx(context.Background(), tx, email); err != nil {
+			t.Fatalf("Expected enqueue to succeed, got %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rowState is the recorded outcome of a row
+type rowState struct {
+	status    string
+	attempts  int
+	lastError sql.NullString
+	sent      bool
+}
+
+func readRow(t *testing.T, db *sql.DB, id int) rowState {
+	t.Helper()
+	var state rowState
+	var sentAt sql.NullInt64
+	err := db.QueryRow(`SELECT status, attempts, last_error, sent_at FROM `+DefaultTable+` WHERE id = ?`, id).
+		Scan(&state.status, &state.attempts, &state.lastError, &sentAt)
+	if err != nil {
+		t.Fatalf("Failed to read row %d: %v", id, err)
+	}
+	state.sent = sentAt.Valid
+	return state
+}
+
+func textEmail(subject string) *poodle.Email {
+	return poodle.NewTextEmail("from@example.com", "to@example.com", subject, "Hello")
+}
+
+func TestEnqueueTxCommitsWithTransaction(t *testing.T) {
+	box, db := newSQLiteOutbox(t)
+	ctx := context.Background()
+
+	tx, _ := db.BeginTx(ctx, nil)
+	if err := box.EnqueueTx(ctx, tx, textEmail("Rolled back")); err != nil {
+		t.Fatalf("Expected enqueue to succeed, got %v", err)
+	}
+	tx.Rollback()
+	enqueue(t, box, db, textEmail("Committed"))
+
+	tx, _ = db.BeginTx(ctx, nil)
+	var validationErr *poodle.ValidationError
+	if err := box.EnqueueTx(ctx, tx, nil); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ValidationError, got %v", err)
+	}
+	tx.Commit()
+
+	sender := poodletest.NewMockSender()
+	n, err := box.NewRelay(db, sender).RelayOnce(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 relayed row, got %d, %v", n, err)
+	}
+	poodletest.AssertSentCount(t, sender, 1)
+	poodletest.AssertSubjectContains(t, sender, "Committed")
+
+	if state := readRow(t, db, 1); state.status != StatusSent || state.attempts != 1 || !state.sent || state.lastError.Valid {
+		t.Errorf("Expected the row to be marked sent, got %+v", state)
+	}
+	if n, err := box.NewRelay(db, sender).RelayOnce(ctx); err != nil || n != 0 {
+		t.Errorf("Expected nothing left to relay, got %d, %v", n, err)
+	}
+}
+
+func TestRelayRendersTemplates(t *testing.T) {
+	box, db := newSQLiteOutbox(t)
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	config := server.Config()
+	config.AutoRender = true
+	client := poodle.NewClientWithConfig(config)
+
+	email := poodle.NewTextEmail("from@example.com", "to@example.com", "Hi {{name}}", "Hello {{name}}")
+	email.Variables = map[string]string{"name": "Jane"}
+	enqueue(t, box, db, email, textEmail("No {{template}}"))
+
+	if n, err := box.NewRelay(db, client).RelayOnce(context.Background()); err != nil || n != 2 {
+		t.Fatalf("Expected 2 relayed rows, got %d, %v", n, err)
+	}
+	poodletest.AssertSubjectContains(t, server, "Hi Jane")
+	poodletest.AssertSubjectContains(t, server, "No {{template}}")
+	for _, sent := range server.Sent() {
+		if sent.Subject == "Hi Jane" && sent.Text != "Hello Jane" {
+			t.Errorf("Expected the body to be rendered, got %q", sent.Text)
+		}
+	}
+}
+
+// newTestClock returns a fake clock for the outbox and its relays
+func newTestClock() *poodletest.FakeClock {
+	return poodletest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestRelayFailures(t *testing.T) {
+	clock := newTestClock()
+	box, db := newSQLiteOutbox(t, WithClock(clock))
+	ctx := context.Background()
+	enqueue(t, box, db, textEmail("Transient"), textEmail("Permanent"), textEmail("Exhausted"))
+
+	sender := poodletest.NewMockSender()
+	relay := box.NewRelay(db, sender, WithMaxAttempts(2), WithRetryPolicy(poodle.ConstantBackoff{Delay: time.Minute}))
+
+	sender.Fail(
+		poodle.NewNetworkError("connection reset", ""),
+		poodle.NewValidationError("Invalid sender", nil),
+		poodle.NewNetworkError("connection reset", ""),
+	)
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 3 {
+		t.Fatalf("Expected 3 relayed rows, got %d, %v", n, err)
+	}
+
+	if state := readRow(t, db, 1); state.status != StatusPending || state.attempts != 1 || state.lastError.String != "connection reset" {
+		t.Errorf("Expected the transient failure to be retried, got %+v", state)
+	}
+	if state := readRow(t, db, 2); state.status != StatusFailed || state.attempts != 1 || state.lastError.String != "Invalid sender" {
+		t.Errorf("Expected the permanent failure to be marked failed, got %+v", state)
+	}
+
+	// Retries wait for the backoff
+	if n, _ := relay.RelayOnce(ctx); n != 0 {
+		t.Errorf("Expected no rows before the backoff, got %d", n)
+	}
+	clock.Advance(time.Minute)
+	sender.Fail(poodle.NewNetworkError("connection reset", ""))
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 2 {
+		t.Fatalf("Expected 2 retried rows, got %d, %v", n, err)
+	}
+
+	if state := readRow(t, db, 1); state.status != StatusFailed || state.attempts != 2 {
+		t.Errorf("Expected the row to fail after 2 attempts, got %+v", state)
+	}
+	if state := readRow(t, db, 3); state.status != StatusSent || state.attempts != 2 {
+		t.Errorf("Expected the retry to be sent, got %+v", state)
+	}
+	poodletest.AssertSentCount(t, sender, 1)
+}
+
+// cancellingSender sends through the mock and then cancels the relay's
+// context, as a relay stopped right after a send does
+type cancellingSender struct {
+	*poodletest.MockSender
+	cancel context.CancelFunc
+}
+
+func (s *cancellingSender) SendContext(ctx context.Context, email *poodle.Email) (*poodle.EmailResponse, error) {
+	response, err := s.MockSender.SendContext(ctx, email)
+	s.cancel()
+	return response, err
+}
+
+func TestRelayRecordsOutcomeAfterCancel(t *testing.T) {
+	box, db := newSQLiteOutbox(t)
+	enqueue(t, box, db, textEmail("Sent before stopping"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sender := &cancellingSender{MockSender: poodletest.NewMockSender(), cancel: cancel}
+	if n, err := box.NewRelay(db, sender).RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected 1 relayed row, got %d, %v", n, err)
+	}
+	if state := readRow(t, db, 1); state.status != StatusSent || !state.sent {
+		t.Errorf("Expected the row to be marked sent, got %+v", state)
+	}
+}
+
+func TestRelayLeaseExpires(t *testing.T) {
+	clock := newTestClock()
+	box, db := newSQLiteOutbox(t, WithClock(clock))
+	enqueue(t, box, db, textEmail("Abandoned"))
+
+	crashed := box.NewRelay(db, poodletest.NewMockSender(), WithLease(time.Minute))
+	if rows, err := crashed.claim(context.Background()); err != nil || len(rows) != 1 {
+		t.Fatalf("Expected the row to be claimed, got %v, %v", rows, err)
+	}
+
+	sender := poodletest.NewMockSender()
+	relay := box.NewRelay(db, sender)
+	clock.Advance(59 * time.Second)
+	if n, _ := relay.RelayOnce(context.Background()); n != 0 {
+		t.Errorf("Expected the leased row to be hidden, got %d rows", n)
+	}
+	clock.Advance(time.Second)
+	if n, _ := relay.RelayOnce(context.Background()); n != 1 {
+		t.Errorf("Expected the row to be relayed once the lease expired, got %d rows", n)
+	}
+	poodletest.AssertSentCount(t, sender, 1)
+}
+
+func TestConcurrentRelays(t *testing.T) {
+	box, db := newSQLiteOutbox(t)
+	var emails []*poodle.Email
+	for i := 0; i < 60; i++ {
+		emails = append(emails, textEmail(fmt.Sprintf("Email %d", i)))
+	}
+	enqueue(t, box, db, emails...)
+
+	sender := poodletest.NewMockSender()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			relay := box.NewRelay(db, sender, WithBatchSize(3))
+			for {
+				n, err := relay.RelayOnce(context.Background())
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+					return
+				}
+				if n == 0 {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, email := range sender.Sent() {
+		if seen[email.Subject] {
+			t.Errorf("Expected %q to be sent once", email.Subject)
+		}
+		seen[email.Subject] = true
+	}
+	if len(seen) != len(emails) {
+		t.Errorf("Expected %d emails to be sent, got %d", len(emails), len(seen))
+	}
+}
+
+func TestRelayAppliesClientDefaults(t *testing.T) {
+	box, db := newSQLiteOutbox(t)
+	server := poodletest.NewServer()
+	defer server.Close()
+	config := server.Config()
+	config.DefaultFrom = "from@example.com"
+
+	// The email relies on the client's DefaultFrom, which applies when it
+	// is relayed
+	enqueue(t, box, db, poodle.NewTextEmail("", "to@example.com", "Defaults", "Hello"))
+	if n, err := box.NewRelay(db, poodle.NewClientWithConfig(config)).RelayOnce(context.Background()); err != nil || n != 1 {
+		t.Fatalf("Expected 1 relayed row, got %d, %v", n, err)
+	}
+
+	poodletest.AssertSubjectContains(t, server, "Defaults")
+	if state := readRow(t, db, 1); state.status != StatusSent {
+		t.Errorf("Expected the row to be marked sent, got %+v", state)
+	}
+}
+
+func TestRelayRun(t *testing.T) {
+	box, db := newSQLiteOutbox(t)
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- box.NewRelay(db, server.NewClient(), WithPollInterval(time.Millisecond)).Run(ctx)
+	}()
+
+	enqueue(t, box, db, textEmail("Welcome"))
+	deadline := time.Now().Add(5 * time.Second)
+	for server.Requests() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the relay to stop with the context, got %v", err)
+	}
+	poodletest.AssertSubjectContains(t, server, "Welcome")
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	if _, err := New(poodlesql.Dialect("oracle")); err == nil {
+		t.Error("Expected an unsupported dialect to be rejected")
+	}
+	if _, err := New(poodlesql.Postgres, WithTable("outbox; DROP TABLE users")); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+}
+
+func TestPostgresSchema(t *testing.T) {
+	box, _ := New(poodlesql.Postgres, WithTable("mail.outbox"))
+	if schema := box.Schema(); len(schema) != 2 || schema[1] != `CREATE INDEX IF NOT EXISTS mail_outbox_due ON mail.outbox (status, next_attempt_at, id)` {
+		t.Errorf("Expected the index on the qualified table, got %v", schema)
+	}
+}
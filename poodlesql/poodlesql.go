@@ -0,0 +1,341 @@
+// Package poodlesql implements poodle.QueueStore with database/sql, so
+// that the emails of a poodle.Queue survive restarts and can be shared by
+// the queues of several processes.
+//
+// Open the database with the driver of your choice, create the table and
+// pass the store to the queue:
+//
+//	db, err := sql.Open("pgx", os.Getenv("DATABASE_URL"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	store, err := poodlesql.New(db, poodlesql.Postgres)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := store.Migrate(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	queue := poodle.NewQueue(client, poodle.WithQueueStore(store))
+package poodlesql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+// DefaultTable is the name of the table holding the queued emails
+const DefaultTable = "poodle_queue"
+
+// Dialect is the SQL dialect of the database
+type Dialect string
+
+// Supported dialects
+const (
+	Postgres Dialect = "postgres"
+	// SQLite requires SQLite 3.35 or later
+	SQLite Dialect = "sqlite"
+)
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithTable sets the table holding the queued emails. The name may be
+// qualified with a schema, such as "mail.queue".
+func WithTable(name string) Option {
+	return func(s *Store) {
+		s.table = name
+	}
+}
+
+// WithClock sets the time source for lease expiry and enqueue times, which
+// defaults to the time package. Pass the clock given to the client with
+// poodle.WithClock so that both agree.
+func WithClock(clock poodle.Clock) Option {
+	return func(s *Store) {
+		if clock != nil {
+			s.now = clock.Now
+		}
+	}
+}
+
+// tableName matches the table names WithTable accepts
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ValidTableName reports whether name is a table name, optionally
+// qualified with a schema, that can be put into a query unquoted
+func ValidTableName(name string) bool {
+	return tableName.MatchString(name)
+}
+
+// Rebind rewrites the ? placeholders of a query for the dialect
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Store is a poodle.QueueStore backed by a SQL table. Each email is a row
+// holding its JSON encoding, its template variables, which are not part
+// of the encoding, and its lease: a dequeued row is hidden until
+// visible_at, and dequeues claim a row by incrementing lease_count only if
+// it is unchanged since the row was read, so that two processes never
+// lease the same row at once. The lease_count of the claim is the item's
+// Lease, and Ack and Nack only match the row while it is unchanged. Times
+// are stored as Unix milliseconds.
+//
+// Every dequeue reads the oldest visible row of the priority, so workers
+// of a busy queue contend for the same row and retry when they lose it.
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+	now     func() time.Time
+}
+
+var _ poodle.QueueStore = (*Store)(nil)
+
+// New creates a store on db. Call Migrate, or apply the statements of
+// Schema with your migration tool, before using it.
+func New(db *sql.DB, dialect Dialect, opts ...Option) (*Store, error) {
+	s := &Store{db: db, dialect: dialect, table: DefaultTable, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if dialect != Postgres && dialect != SQLite {
+		return nil, fmt.Errorf("poodlesql: unsupported dialect %q", dialect)
+	}
+	if !ValidTableName(s.table) {
+		return nil, fmt.Errorf("poodlesql: invalid table name %q", s.table)
+	}
+	return s, nil
+}
+
+// Schema returns the statements creating the table and its index. They
+// can be run more than once.
+func (s *Store) Schema() []string {
+	id := "BIGSERIAL PRIMARY KEY"
+	if s.dialect == SQLite {
+		id = "INTEGER PRIMARY KEY AUTOINCREMENT"
+	}
+	index := strings.ReplaceAll(s.table, ".", "_") + "_ready"
+	if schema, _, ok := strings.Cut(s.table, "."); ok && s.dialect == SQLite {
+		index = schema + "." + index
+	}
+
+	return []string{
+		`CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+	id ` + id + `,
+	priority INTEGER NOT NULL,
+	item_index BIGINT NOT NULL,
+	email TEXT NOT NULL,
+	variables TEXT,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	enqueued_at BIGINT NOT NULL,
+	visible_at BIGINT NOT NULL,
+	leased INTEGER NOT NULL DEFAULT 0,
+	lease_count BIGINT NOT NULL DEFAULT 0
+)`,
+		`CREATE INDEX IF NOT EXISTS ` + index + ` ON ` + s.table + ` (priority, visible_at, id)`,
+	}
+}
+
+// Migrate creates the table and its index if they do not exist
+func (s *Store) Migrate(ctx context.Context) error {
+	for _, statement := range s.Schema() {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("poodlesql: migrate: %w", err)
+		}
+	}
+	return nil
+}
+
+// Enqueue implements poodle.QueueStore. Items whose priority is not one
+// of the defined priorities are rejected, as Dequeue would never return
+// them.
+func (s *Store) Enqueue(ctx context.Context, item *poodle.QueueItem) error {
+	switch item.Priority {
+	case poodle.PriorityHigh, poodle.PriorityNormal, poodle.PriorityLow:
+	default:
+		return fmt.Errorf("poodlesql: enqueue: invalid priority %d", item.Priority)
+	}
+
+	email, err := json.Marshal(item.Email)
+	if err != nil {
+		return fmt.Errorf("poodlesql: encode email: %w", err)
+	}
+	var variables sql.NullString
+	if item.Email.Variables != nil {
+		encoded, err := json.Marshal(item.Email.Variables)
+		if err != nil {
+			return fmt.Errorf("poodlesql: encode variables: %w", err)
+		}
+		variables = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, s.dialect.Rebind(`INSERT INTO `+s.table+`
+	(priority, item_index, email, variables, attempts, enqueued_at, visible_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		int(item.Priority), item.Index, string(email), variables, item.Attempts,
+		item.EnqueuedAt.UnixMilli(), s.now().UnixMilli(),
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("poodlesql: enqueue: %w", err)
+	}
+	item.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// Dequeue implements poodle.QueueStore
+func (s *Store) Dequeue(ctx context.Context, priority poodle.Priority, visibility time.Duration) (*poodle.QueueItem, error) {
+	for {
+		now := s.now()
+		var (
+			id, leaseCount, enqueuedAt int64
+			email                      string
+			variables                  sql.NullString
+			item                       poodle.QueueItem
+		)
+		err := s.db.QueryRowContext(ctx, s.dialect.Rebind(`SELECT id, item_index, email, variables, attempts, enqueued_at, lease_count
+	FROM `+s.table+` WHERE priority = ? AND visible_at <= ? ORDER BY id LIMIT 1`),
+			int(priority), now.UnixMilli(),
+		).Scan(&id, &item.Index, &email, &variables, &item.Attempts, &enqueuedAt, &leaseCount)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("poodlesql: dequeue: %w", err)
+		}
+
+		// Claim the row unless another dequeue claimed it first
+		result, err := s.db.ExecContext(ctx, s.dialect.Rebind(`UPDATE `+s.table+`
+	SET leased = 1, visible_at = ?, lease_count = lease_count + 1
+	WHERE id = ? AND lease_count = ?`),
+			now.Add(visibility).UnixMilli(), id, leaseCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("poodlesql: dequeue: %w", err)
+		}
+		if claimed, err := result.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("poodlesql: dequeue: %w", err)
+		} else if claimed == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		item.Email = &poodle.Email{}
+		if err := json.Unmarshal([]byte(email), item.Email); err != nil {
+			return nil, fmt.Errorf("poodlesql: decode email %d: %w", id, err)
+		}
+		if variables.Valid {
+			if err := json.Unmarshal([]byte(variables.String), &item.Email.Variables); err != nil {
+				return nil, fmt.Errorf("poodlesql: decode variables %d: %w", id, err)
+			}
+		}
+		item.ID = strconv.FormatInt(id, 10)
+		item.Lease = strconv.FormatInt(leaseCount+1, 10)
+		item.Priority = priority
+		item.Email.Priority = priority
+		item.EnqueuedAt = time.UnixMilli(enqueuedAt).UTC()
+		return &item, nil
+	}
+}
+
+// Ack implements poodle.QueueStore
+func (s *Store) Ack(ctx context.Context, id string) error {
+	return s.update(ctx, "ack", `DELETE FROM `+s.table+` WHERE id = ?`, id)
+}
+
+// Nack implements poodle.QueueStore
+func (s *Store) Nack(ctx context.Context, id string, delay time.Duration) error {
+	return s.update(ctx, "nack", `UPDATE `+s.table+`
+	SET leased = 0, attempts = attempts + 1, visible_at = ?
+	WHERE id = ?`, id, s.now().Add(delay).UnixMilli())
+}
+
+// Len implements poodle.QueueStore
+func (s *Store) Len(ctx context.Context) (map[poodle.Priority]int, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(`SELECT priority, COUNT(*) FROM `+s.table+`
+	WHERE leased = 0 OR visible_at <= ? GROUP BY priority`), s.now().UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("poodlesql: len: %w", err)
+	}
+	defer rows.Close()
+
+	depth := map[poodle.Priority]int{
+		poodle.PriorityHigh:   0,
+		poodle.PriorityNormal: 0,
+		poodle.PriorityLow:    0,
+	}
+	for rows.Next() {
+		var priority, n int
+		if err := rows.Scan(&priority, &n); err != nil {
+			return nil, fmt.Errorf("poodlesql: len: %w", err)
+		}
+		depth[poodle.Priority(priority)] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("poodlesql: len: %w", err)
+	}
+	return depth, nil
+}
+
+// update runs a statement on the row with the ID, which query must match
+// last, and returns poodle.ErrQueueItemNotFound if there is no such row.
+// If the ID has a lease, the row must still have that lease_count.
+func (s *Store) update(ctx context.Context, op, query, id string, args ...interface{}) error {
+	id, lease := poodle.SplitLeaseID(id)
+	rowID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return poodle.ErrQueueItemNotFound
+	}
+	args = append(args, rowID)
+	if lease != "" {
+		leaseCount, err := strconv.ParseInt(lease, 10, 64)
+		if err != nil {
+			return poodle.ErrQueueItemNotFound
+		}
+		query += " AND lease_count = ?"
+		args = append(args, leaseCount)
+	}
+
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("poodlesql: %s: %w", op, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("poodlesql: %s: %w", op, err)
+	}
+	if n == 0 {
+		return poodle.ErrQueueItemNotFound
+	}
+	return nil
+}
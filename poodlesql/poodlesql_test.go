@@ -0,0 +1,170 @@
+package poodlesql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+	"github.com/usepoodle/poodle-go/poodletest"
+	_ "modernc.org/sqlite"
+)
+
+func newSQLiteStore(t *testing.T) *Store {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "queue.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := New(db, SQLite)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("Expected migration to succeed, got %v", err)
+	}
+	return store
+}
+
+func TestStoreConformance(t *testing.T) {
+	poodletest.RunQueueStoreTests(t, func(t *testing.T) poodle.QueueStore {
+		return newSQLiteStore(t)
+	})
+}
+
+func TestStoreClock(t *testing.T) {
+	clock := poodletest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := newSQLiteStore(t)
+	WithClock(clock)(store)
+	ctx := context.Background()
+
+	item := &poodle.QueueItem{Email: poodle.NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello"), Priority: poodle.PriorityNormal}
+	if err := store.Enqueue(ctx, item); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if leased, err := store.Dequeue(ctx, poodle.PriorityNormal, time.Minute); err != nil || leased == nil {
+		t.Fatalf("Expected the item to be leased, got %v, %v", leased, err)
+	}
+
+	// The lease expires on the store's clock, not the wall clock
+	if leased, err := store.Dequeue(ctx, poodle.PriorityNormal, time.Minute); err != nil || leased != nil {
+		t.Fatalf("Expected the item to stay leased, got %v, %v", leased, err)
+	}
+	clock.Advance(2 * time.Minute)
+	if leased, err := store.Dequeue(ctx, poodle.PriorityNormal, time.Minute); err != nil || leased == nil {
+		t.Errorf("Expected the item to be leased again, got %v, %v", leased, err)
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	store := newSQLiteStore(t)
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Errorf("Expected a second migration to succeed, got %v", err)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	if _, err := New(nil, Dialect("oracle")); err == nil {
+		t.Error("Expected an unsupported dialect to be rejected")
+	}
+	if _, err := New(nil, Postgres, WithTable("queue; DROP TABLE users")); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+	if _, err := New(nil, Postgres, WithTable("mail.queue")); err != nil {
+		t.Errorf("Expected a schema-qualified table to be accepted, got %v", err)
+	}
+}
+
+func TestPostgresPlaceholders(t *testing.T) {
+	got := Postgres.Rebind("UPDATE t SET a = ? WHERE id = ? AND b = ?")
+	if want := "UPDATE t SET a = $1 WHERE id = $2 AND b = $3"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if query := "SELECT ?"; SQLite.Rebind(query) != query {
+		t.Errorf("Expected SQLite queries to be left alone, got %q", SQLite.Rebind(query))
+	}
+}
+
+func TestEnqueueRejectsInvalidPriority(t *testing.T) {
+	store := newSQLiteStore(t)
+	err := store.Enqueue(context.Background(), &poodle.QueueItem{
+		Priority: poodle.Priority(7),
+		Email:    poodle.NewTextEmail("from@example.com", "to@example.com", "Unknown", "Hello"),
+	})
+	if err == nil {
+		t.Fatal("Expected an invalid priority to be rejected")
+	}
+	depth, err := store.Len(context.Background())
+	if err != nil || depth[poodle.PriorityNormal] != 0 || len(depth) != 3 {
+		t.Errorf("Expected the store to be empty, got %v, %v", depth, err)
+	}
+}
+
+func TestQueueWithStore(t *testing.T) {
+	store := newSQLiteStore(t)
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	client := server.NewClient()
+
+	// Emails left by a stopped queue are sent by the next one
+	stopped := poodle.NewQueue(client, poodle.WithQueueStore(store), poodle.WithQueueWorkers(1))
+	stopped.Stop()
+	if err := store.Enqueue(context.Background(), &poodle.QueueItem{
+		Email: poodle.NewTextEmail("from@example.com", "to@example.com", "Left behind", "Hello"),
+	}); err != nil {
+		t.Fatalf("Expected enqueue to succeed, got %v", err)
+	}
+
+	queue := poodle.NewQueue(client, poodle.WithQueueStore(store))
+	if err := queue.Enqueue(poodle.NewTextEmail("from@example.com", "to@example.com", "Fresh", "Hello")); err != nil {
+		t.Fatalf("Expected enqueue to succeed, got %v", err)
+	}
+	queue.Close()
+
+	poodletest.AssertSentCount(t, server, 2)
+	poodletest.AssertSubjectContains(t, server, "Left behind")
+	depth, err := store.Len(context.Background())
+	if err != nil || depth[poodle.PriorityNormal] != 0 {
+		t.Errorf("Expected the store to be empty, got %v, %v", depth, err)
+	}
+}
+
+func TestQueueWithStoreRendersTemplates(t *testing.T) {
+	store := newSQLiteStore(t)
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	config := server.Config()
+	config.AutoRender = true
+	client := poodle.NewClientWithConfig(config)
+
+	// Variables survive the store, so an email left by a stopped queue is
+	// rendered by the next one
+	email := poodle.NewTextEmail("from@example.com", "to@example.com", "Hi {{name}}", "Hello {{name}}")
+	email.Variables = map[string]string{"name": "Jane"}
+	if err := store.Enqueue(context.Background(), &poodle.QueueItem{Email: email}); err != nil {
+		t.Fatalf("Expected enqueue to succeed, got %v", err)
+	}
+
+	queue := poodle.NewQueue(client, poodle.WithQueueStore(store))
+	plain := poodle.NewTextEmail("from@example.com", "to@example.com", "No {{template}}", "Hello")
+	if err := queue.Enqueue(plain); err != nil {
+		t.Fatalf("Expected enqueue to succeed, got %v", err)
+	}
+	queue.Close()
+
+	poodletest.AssertSentCount(t, server, 2)
+	poodletest.AssertSubjectContains(t, server, "Hi Jane")
+	poodletest.AssertSubjectContains(t, server, "No {{template}}")
+	for _, sent := range server.Sent() {
+		if sent.Subject == "Hi Jane" && sent.Text != "Hello Jane" {
+			t.Errorf("Expected the body to be rendered, got %q", sent.Text)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package poodletest
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+// SentRecorder records the emails sent in a test. Server and MockSender
+// implement it.
+type SentRecorder interface {
+	Sent() []poodle.Email
+}
+
+// AssertSentCount checks that n emails were sent
+func AssertSentCount(t testing.TB, recorder SentRecorder, n int) bool {
+	t.Helper()
+
+	sent := recorder.Sent()
+	if len(sent) != n {
+		t.Errorf("Expected %d emails sent, got %d\n%s", n, len(sent), describe(sent))
+		return false
+	}
+	return true
+}
+
+// AssertSentTo checks that an email was sent to the address. Addresses
+// are compared without their display names and ignoring case.
+func AssertSentTo(t testing.TB, recorder SentRecorder, address string) bool {
+	t.Helper()
+
+	sent := recorder.Sent()
+	for i := range sent {
+		if strings.EqualFold(recipient(&sent[i]), recipientOf(address)) {
+			return true
+		}
+	}
+	t.Errorf("Expected an email sent to %s\n%s", address, describe(sent))
+	return false
+}
+
+// AssertSubjectContains checks that the subject of an email contains s
+func AssertSubjectContains(t testing.TB, recorder SentRecorder, s string) bool {
+	t.Helper()
+
+	sent := recorder.Sent()
+	for _, email := range sent {
+		if strings.Contains(email.Subject, s) {
+			return true
+		}
+	}
+	t.Errorf("Expected an email with a subject containing %q\n%s", s, describe(sent))
+	return false
+}
+
+// AssertBodyMatches checks that the HTML or text body of an email matches
+// the regular expression
+func AssertBodyMatches(t testing.TB, recorder SentRecorder, re *regexp.Regexp) bool {
+	t.Helper()
+
+	sent := recorder.Sent()
+	for _, email := range sent {
+		if re.MatchString(email.HTML) || re.MatchString(email.Text) {
+			return true
+		}
+	}
+	t.Errorf("Expected an email with a body matching %s\n%s", re, describe(sent))
+	return false
+}
+
+// LastSent returns the most recently sent email. The test fails
+// immediately if no email was sent.
+func LastSent(t testing.TB, recorder SentRecorder) *poodle.Email {
+	t.Helper()
+
+	sent := recorder.Sent()
+	if len(sent) == 0 {
+		t.Fatalf("Expected an email to have been sent, got none")
+		return nil
+	}
+	return &sent[len(sent)-1]
+}
+
+// maxDescribedBody is the length of the bodies shown in failure messages
+const maxDescribedBody = 60
+
+// describe lists the recorded emails for a failure message
+func describe(sent []poodle.Email) string {
+	if len(sent) == 0 {
+		return "No emails were sent"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Sent emails (%d):", len(sent))
+	for i := range sent {
+		email := &sent[i]
+		body := email.Text
+		if body == "" {
+			body = email.HTML
+		}
+		fmt.Fprintf(&b, "\n  %d. to: %s, subject: %q, body: %q", i+1, recipient(email), email.Subject, excerpt(body))
+	}
+	return b.String()
+}
+
+// excerpt shortens a body to maxDescribedBody characters
+func excerpt(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxDescribedBody {
+		return s
+	}
+	return string(runes[:maxDescribedBody]) + "..."
+}
+
+// recipient returns the address an email is sent to
+func recipient(email *poodle.Email) string {
+	if email.ToAddress != nil {
+		return email.ToAddress.Email
+	}
+	return recipientOf(email.To)
+}
+
+// recipientOf returns the address of s without its display name
+func recipientOf(s string) string {
+	if address, err := mail.ParseAddress(s); err == nil {
+		return address.Address
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,103 @@
+package poodletest
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+var (
+	_ poodle.Sender = (*poodle.Client)(nil)
+	_ poodle.Sender = (*MockSender)(nil)
+	_ SentRecorder  = (*MockSender)(nil)
+	_ SentRecorder  = (*Server)(nil)
+)
+
+// recordingT records failures instead of failing the test
+type recordingT struct {
+	testing.TB
+	failures []string
+	fatal    bool
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	t.fatal = true
+}
+
+func TestAssertions(t *testing.T) {
+	sender := NewMockSender()
+	sender.Send(poodle.NewHTMLEmail("from@example.com", "Jane Doe <Jane@Example.com>", "Invoice #42", "<p>Total: $10.00</p>"))
+	email := poodle.NewTextEmail("from@example.com", "", "Welcome", "Hello Bob")
+	email.ToAddress = &poodle.Address{Name: "Bob", Email: "bob@example.com"}
+	sender.Send(email)
+
+	passing := &recordingT{TB: t}
+	AssertSentCount(passing, sender, 2)
+	AssertSentTo(passing, sender, "jane@example.com")
+	AssertSentTo(passing, sender, "Bob <bob@example.com>")
+	AssertSubjectContains(passing, sender, "Invoice")
+	AssertBodyMatches(passing, sender, regexp.MustCompile(`Total: \$\d+\.\d{2}`))
+	if last := LastSent(passing, sender); last == nil || last.Subject != "Welcome" {
+		t.Errorf("Expected the last email, got %+v", last)
+	}
+	if len(passing.failures) != 0 {
+		t.Errorf("Expected no failures, got %v", passing.failures)
+	}
+
+	failing := &recordingT{TB: t}
+	if AssertSentCount(failing, sender, 3) || AssertSentTo(failing, sender, "carol@example.com") ||
+		AssertSubjectContains(failing, sender, "Receipt") || AssertBodyMatches(failing, sender, regexp.MustCompile(`refund`)) {
+		t.Error("Expected the assertions to fail")
+	}
+	if len(failing.failures) != 4 {
+		t.Fatalf("Expected 4 failures, got %v", failing.failures)
+	}
+	for _, failure := range failing.failures {
+		if !strings.Contains(failure, `1. to: Jane@Example.com, subject: "Invoice #42", body: "<p>Total: $10.00</p>"`) ||
+			!strings.Contains(failure, `2. to: bob@example.com, subject: "Welcome"`) {
+			t.Errorf("Expected the recorded emails in the failure, got:\n%s", failure)
+		}
+	}
+}
+
+func TestLastSentWithoutEmails(t *testing.T) {
+	recorder := &recordingT{TB: t}
+	if LastSent(recorder, NewMockSender()) != nil || !recorder.fatal {
+		t.Error("Expected LastSent to fail the test immediately")
+	}
+	if !strings.Contains(recorder.failures[0], "got none") {
+		t.Errorf("Unexpected failure: %v", recorder.failures)
+	}
+}
+
+func TestMockSender(t *testing.T) {
+	sender := NewMockSender()
+	rateLimited := poodle.NewRateLimitError("Rate limit exceeded", 30, 100, 0, 0)
+	sender.Fail(rateLimited)
+
+	email := poodle.NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	if _, err := sender.Send(email); !errors.Is(err, rateLimited) {
+		t.Errorf("Expected the programmed error, got %v", err)
+	}
+	if _, err := sender.Send(poodle.NewTextEmail("from@example.com", "invalid", "Subject", "Hello")); err == nil {
+		t.Error("Expected an invalid email to be rejected")
+	}
+	if response, err := sender.Send(email); err != nil || !response.Success {
+		t.Errorf("Expected the email to be accepted, got %v", err)
+	}
+	AssertSentCount(t, sender, 1)
+
+	sender.Reset()
+	AssertSentCount(t, sender, 0)
+}
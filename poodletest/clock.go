@@ -0,0 +1,64 @@
+package poodletest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FakeClock is a poodle.Clock that never blocks. Sleep advances the fake
+// time instantly and records the requested duration, so tests can assert
+// the sequence of waits without slowing down.
+type FakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+// NewFakeClock creates a fake clock starting at the given time
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the current fake time
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+// Sleep advances the fake time by d and records the wait. It returns the
+// context's error without advancing if the context is already done.
+func (c *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if d > 0 {
+		c.sleeps = append(c.sleeps, d)
+		c.now = c.now.Add(d)
+	}
+	return nil
+}
+
+// Advance moves the fake time forward without recording a wait
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Sleeps returns the recorded waits in order
+func (c *FakeClock) Sleeps() []time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	sleeps := make([]time.Duration, len(c.sleeps))
+	copy(sleeps, c.sleeps)
+	return sleeps
+}
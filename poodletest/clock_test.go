@@ -0,0 +1,82 @@
+package poodletest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	if err := clock.Sleep(context.Background(), 2*time.Second); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	clock.Advance(time.Second)
+
+	if got := clock.Now(); !got.Equal(start.Add(3 * time.Second)) {
+		t.Errorf("Expected fake time to advance by 3s, got %v", got.Sub(start))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := clock.Sleep(ctx, time.Hour); err == nil {
+		t.Error("Expected error when sleeping with a cancelled context")
+	}
+
+	if sleeps := clock.Sleeps(); !reflect.DeepEqual(sleeps, []time.Duration{2 * time.Second}) {
+		t.Errorf("Unexpected recorded sleeps: %v", sleeps)
+	}
+}
+
+func TestFakeClockDrivesClientRetries(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.Enqueue(ServerError(), RateLimited(7*time.Second, 10))
+
+	config := server.Config()
+	config.MaxRetries = 2
+	config.RetryBackoff = time.Second
+	config.WaitOnRateLimit = true
+
+	clock := NewFakeClock(time.Now())
+	client := poodle.NewClientWithConfig(config, poodle.WithClock(clock))
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi"); err != nil {
+		t.Fatalf("Expected success after retries, got: %v", err)
+	}
+
+	expected := []time.Duration{time.Second, 7 * time.Second}
+	if sleeps := clock.Sleeps(); !reflect.DeepEqual(sleeps, expected) {
+		t.Errorf("Expected waits %v, got %v", expected, sleeps)
+	}
+}
+
+func TestFakeClockIdleQueue(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	server := NewServer()
+	defer server.Close()
+	client := poodle.NewClientWithConfig(server.Config(), poodle.WithClock(clock))
+
+	// An idle queue and its progress reports wait on real timers, so a
+	// clock that never blocks does not make them spin
+	reports := 0
+	queue := poodle.NewQueue(client,
+		poodle.WithQueueWorkers(2),
+		poodle.WithQueuePollInterval(10*time.Millisecond),
+		poodle.WithQueueProgress(func(poodle.Progress) { reports++ }, poodle.ProgressInterval(10*time.Millisecond)))
+	time.Sleep(100 * time.Millisecond)
+	queue.Close()
+
+	if sleeps := clock.Sleeps(); len(sleeps) != 0 {
+		t.Errorf("Expected an idle queue not to sleep on the clock, got %d sleeps", len(sleeps))
+	}
+	if reports > 20 {
+		t.Errorf("Expected about 10 progress reports, got %d", reports)
+	}
+}
@@ -0,0 +1,57 @@
+package poodletest_test
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/usepoodle/poodle-go"
+	"github.com/usepoodle/poodle-go/poodletest"
+)
+
+// sendInvoice is application code that depends on poodle.Sender, so tests
+// can pass a MockSender and production code a *poodle.Client
+func sendInvoice(sender poodle.Sender, to string, number int) error {
+	email := poodle.NewEmailWithBoth(
+		"billing@example.com",
+		to,
+		"Invoice #{{number}}",
+		"<p>Invoice #{{number}} is attached.</p>",
+		"Invoice #{{number}} is attached.",
+	)
+	email.Variables = map[string]string{"number": strconv.Itoa(number)}
+
+	rendered, err := email.Render()
+	if err != nil {
+		return err
+	}
+	_, err = sender.Send(rendered)
+	return err
+}
+
+func TestSendInvoiceWithMockSender(t *testing.T) {
+	sender := poodletest.NewMockSender()
+
+	if err := sendInvoice(sender, "Jane Doe <jane@example.com>", 42); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	poodletest.AssertSentCount(t, sender, 1)
+	poodletest.AssertSentTo(t, sender, "jane@example.com")
+	poodletest.AssertSubjectContains(t, sender, "Invoice #42")
+	poodletest.AssertBodyMatches(t, sender, regexp.MustCompile(`Invoice #\d+ is attached`))
+}
+
+func TestSendInvoiceWithFakeServer(t *testing.T) {
+	server := poodletest.NewServer()
+	defer server.Close()
+
+	if err := sendInvoice(server.NewClient(), "jane@example.com", 7); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	poodletest.AssertSentTo(t, server, "jane@example.com")
+	if last := poodletest.LastSent(t, server); last.From != "billing@example.com" || last.Subject != "Invoice #7" {
+		t.Errorf("Unexpected email %+v", last)
+	}
+}
@@ -0,0 +1,79 @@
+package poodletest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+// MockSender is a poodle.Sender that records emails instead of sending
+// them. Emails are validated like the real client validates them, and
+// errors programmed with Fail are returned for the next sends.
+type MockSender struct {
+	mutex  sync.Mutex
+	sent   []poodle.Email
+	errors []error
+}
+
+// NewMockSender returns a MockSender that accepts every valid email
+func NewMockSender() *MockSender {
+	return &MockSender{}
+}
+
+// Send records the email
+func (m *MockSender) Send(email *poodle.Email) (*poodle.EmailResponse, error) {
+	return m.SendContext(context.Background(), email)
+}
+
+// SendContext records the email, or returns the next programmed error.
+// Invalid emails and emails failed with a programmed error are not
+// recorded.
+func (m *MockSender) SendContext(ctx context.Context, email *poodle.Email) (*poodle.EmailResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, poodle.NewNetworkError("Request cancelled: "+err.Error(), "")
+	}
+	if err := email.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.errors) > 0 {
+		err := m.errors[0]
+		m.errors = m.errors[1:]
+		return nil, err
+	}
+
+	m.sent = append(m.sent, *email)
+	return poodle.NewEmailResponse(true, "Email queued for sending"), nil
+}
+
+// Fail programs errors returned for the next sends, in order. Once they
+// are used up the sender accepts emails again.
+func (m *MockSender) Fail(errs ...error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.errors = append(m.errors, errs...)
+}
+
+// Sent returns a copy of the recorded emails, in order
+func (m *MockSender) Sent() []poodle.Email {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sent := make([]poodle.Email, len(m.sent))
+	copy(sent, m.sent)
+	return sent
+}
+
+// Reset clears recorded emails and programmed errors
+func (m *MockSender) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sent = nil
+	m.errors = nil
+}
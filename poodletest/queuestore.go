@@ -0,0 +1,299 @@
+package poodletest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+// RunQueueStoreTests checks that a poodle.QueueStore implements the
+// semantics the Queue relies on: ordering, leasing, visibility timeouts,
+// acknowledgement, rejecting Ack and Nack of an expired lease, atomic
+// dequeues under concurrency, and keeping the template variables of the
+// emails. newStore is
+// called for every subtest and must return an empty store. The tests use
+// real time, with visibility timeouts and delays of a few hundred
+// milliseconds.
+func RunQueueStoreTests(t *testing.T, newStore func(t *testing.T) poodle.QueueStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("Empty", func(t *testing.T) {
+		store := newStore(t)
+		item, err := store.Dequeue(ctx, poodle.PriorityNormal, time.Minute)
+		if err != nil || item != nil {
+			t.Errorf("Expected no item from an empty store, got %+v, %v", item, err)
+		}
+		expectLen(t, store, 0, 0, 0)
+	})
+
+	t.Run("FIFOWithinPriority", func(t *testing.T) {
+		store := newStore(t)
+		enqueued := make([]*poodle.QueueItem, 3)
+		for i := range enqueued {
+			enqueued[i] = enqueueItem(t, store, i, poodle.PriorityNormal)
+		}
+		expectLen(t, store, 0, 3, 0)
+
+		seen := make(map[string]bool)
+		for i, want := range enqueued {
+			item := dequeueItem(t, store, poodle.PriorityNormal, time.Minute)
+			if item == nil {
+				t.Fatalf("Expected item %d, got none", i)
+			}
+			if item.ID == "" || seen[item.ID] {
+				t.Errorf("Expected a unique ID, got %q", item.ID)
+			}
+			seen[item.ID] = true
+			if item.ID != want.ID || item.Index != want.Index || item.Email.Subject != want.Email.Subject {
+				t.Errorf("Expected item %d (%s), got %d (%s)", want.Index, want.ID, item.Index, item.ID)
+			}
+			if item.Priority != poodle.PriorityNormal || item.Attempts != 0 || !item.EnqueuedAt.Equal(want.EnqueuedAt) {
+				t.Errorf("Expected the enqueued fields to be kept, got %+v", item)
+			}
+		}
+	})
+
+	t.Run("KeepsVariables", func(t *testing.T) {
+		store := newStore(t)
+		templated := &poodle.QueueItem{
+			Priority:   poodle.PriorityNormal,
+			Email:      poodle.NewTextEmail("from@example.com", "to@example.com", "Hi {{name}}", "Hello"),
+			EnqueuedAt: time.Now().UTC().Truncate(time.Millisecond),
+		}
+		templated.Email.Variables = map[string]string{"name": "Jane"}
+		if err := store.Enqueue(ctx, templated); err != nil {
+			t.Fatalf("Expected enqueue to succeed, got %v", err)
+		}
+		enqueueItem(t, store, 1, poodle.PriorityNormal)
+
+		item := dequeueItem(t, store, poodle.PriorityNormal, time.Minute)
+		if item == nil || item.Email.Variables["name"] != "Jane" {
+			t.Errorf("Expected the template variables to be kept, got %+v", item)
+		}
+		item = dequeueItem(t, store, poodle.PriorityNormal, time.Minute)
+		if item == nil || item.Email.Variables != nil {
+			t.Errorf("Expected an email without variables to have none, got %+v", item)
+		}
+	})
+
+	t.Run("PrioritiesAreSeparate", func(t *testing.T) {
+		store := newStore(t)
+		enqueueItem(t, store, 0, poodle.PriorityLow)
+		enqueueItem(t, store, 1, poodle.PriorityHigh)
+		enqueueItem(t, store, 2, poodle.PriorityHigh)
+		expectLen(t, store, 2, 0, 1)
+
+		if item := dequeueItem(t, store, poodle.PriorityNormal, time.Minute); item != nil {
+			t.Errorf("Expected no normal item, got %+v", item)
+		}
+		if item := dequeueItem(t, store, poodle.PriorityLow, time.Minute); item == nil || item.Index != 0 {
+			t.Errorf("Expected the low item, got %+v", item)
+		}
+		if item := dequeueItem(t, store, poodle.PriorityHigh, time.Minute); item == nil || item.Index != 1 {
+			t.Errorf("Expected the first high item, got %+v", item)
+		}
+	})
+
+	t.Run("LeasedItemsAreHidden", func(t *testing.T) {
+		store := newStore(t)
+		enqueueItem(t, store, 0, poodle.PriorityNormal)
+
+		if item := dequeueItem(t, store, poodle.PriorityNormal, time.Minute); item == nil {
+			t.Fatal("Expected an item")
+		}
+		expectLen(t, store, 0, 0, 0)
+		if item := dequeueItem(t, store, poodle.PriorityNormal, time.Minute); item != nil {
+			t.Errorf("Expected the leased item to be hidden, got %+v", item)
+		}
+	})
+
+	t.Run("Ack", func(t *testing.T) {
+		store := newStore(t)
+		enqueueItem(t, store, 0, poodle.PriorityNormal)
+		item := dequeueItem(t, store, poodle.PriorityNormal, 200*time.Millisecond)
+
+		if err := store.Ack(ctx, item.LeaseID()); err != nil {
+			t.Fatalf("Expected ack to succeed, got %v", err)
+		}
+		if err := store.Ack(ctx, item.LeaseID()); !errors.Is(err, poodle.ErrQueueItemNotFound) {
+			t.Errorf("Expected ErrQueueItemNotFound acking twice, got %v", err)
+		}
+
+		time.Sleep(300 * time.Millisecond)
+		expectLen(t, store, 0, 0, 0)
+		if item := dequeueItem(t, store, poodle.PriorityNormal, time.Minute); item != nil {
+			t.Errorf("Expected an acked item not to return, got %+v", item)
+		}
+	})
+
+	t.Run("Nack", func(t *testing.T) {
+		store := newStore(t)
+		enqueueItem(t, store, 0, poodle.PriorityNormal)
+		enqueueItem(t, store, 1, poodle.PriorityNormal)
+		first := dequeueItem(t, store, poodle.PriorityNormal, time.Minute)
+
+		if err := store.Nack(ctx, first.LeaseID(), 0); err != nil {
+			t.Fatalf("Expected nack to succeed, got %v", err)
+		}
+		expectLen(t, store, 0, 2, 0)
+
+		item := dequeueItem(t, store, poodle.PriorityNormal, time.Minute)
+		if item == nil || item.ID != first.ID || item.Attempts != 1 {
+			t.Errorf("Expected the nacked item first with 1 attempt, got %+v", item)
+		}
+	})
+
+	t.Run("NackDelay", func(t *testing.T) {
+		store := newStore(t)
+		enqueueItem(t, store, 0, poodle.PriorityNormal)
+		item := dequeueItem(t, store, poodle.PriorityNormal, time.Minute)
+
+		if err := store.Nack(ctx, item.LeaseID(), 300*time.Millisecond); err != nil {
+			t.Fatalf("Expected nack to succeed, got %v", err)
+		}
+		expectLen(t, store, 0, 1, 0)
+		if again := dequeueItem(t, store, poodle.PriorityNormal, time.Minute); again != nil {
+			t.Errorf("Expected the item to be hidden during the delay, got %+v", again)
+		}
+
+		time.Sleep(400 * time.Millisecond)
+		if again := dequeueItem(t, store, poodle.PriorityNormal, time.Minute); again == nil || again.ID != item.ID {
+			t.Errorf("Expected the item after the delay, got %+v", again)
+		}
+	})
+
+	t.Run("VisibilityTimeout", func(t *testing.T) {
+		store := newStore(t)
+		enqueueItem(t, store, 0, poodle.PriorityNormal)
+		item := dequeueItem(t, store, poodle.PriorityNormal, 200*time.Millisecond)
+
+		time.Sleep(300 * time.Millisecond)
+		expectLen(t, store, 0, 1, 0)
+		again := dequeueItem(t, store, poodle.PriorityNormal, time.Minute)
+		if again == nil || again.ID != item.ID || again.Attempts != 0 {
+			t.Fatalf("Expected the item again after its lease expired, got %+v", again)
+		}
+		if err := store.Ack(ctx, again.LeaseID()); err != nil {
+			t.Errorf("Expected the late ack to succeed, got %v", err)
+		}
+	})
+
+	t.Run("ExpiredLease", func(t *testing.T) {
+		store := newStore(t)
+		enqueueItem(t, store, 0, poodle.PriorityNormal)
+		first := dequeueItem(t, store, poodle.PriorityNormal, 200*time.Millisecond)
+
+		time.Sleep(300 * time.Millisecond)
+		second := dequeueItem(t, store, poodle.PriorityNormal, time.Minute)
+		if second == nil || second.ID != first.ID || second.LeaseID() == first.LeaseID() {
+			t.Fatalf("Expected the item again with a new lease, got %+v after %+v", second, first)
+		}
+
+		// The first worker's lease expired, so it must not end the second's
+		if err := store.Nack(ctx, first.LeaseID(), 0); !errors.Is(err, poodle.ErrQueueItemNotFound) {
+			t.Errorf("Expected ErrQueueItemNotFound from Nack of an expired lease, got %v", err)
+		}
+		if err := store.Ack(ctx, first.LeaseID()); !errors.Is(err, poodle.ErrQueueItemNotFound) {
+			t.Errorf("Expected ErrQueueItemNotFound from Ack of an expired lease, got %v", err)
+		}
+		expectLen(t, store, 0, 0, 0)
+		if err := store.Ack(ctx, second.LeaseID()); err != nil {
+			t.Errorf("Expected the current lease to be acked, got %v", err)
+		}
+	})
+
+	t.Run("UnknownItem", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.Ack(ctx, "999999999"); !errors.Is(err, poodle.ErrQueueItemNotFound) {
+			t.Errorf("Expected ErrQueueItemNotFound from Ack, got %v", err)
+		}
+		if err := store.Nack(ctx, "999999999", 0); !errors.Is(err, poodle.ErrQueueItemNotFound) {
+			t.Errorf("Expected ErrQueueItemNotFound from Nack, got %v", err)
+		}
+	})
+
+	t.Run("ConcurrentDequeue", func(t *testing.T) {
+		store := newStore(t)
+		const items = 50
+		for i := 0; i < items; i++ {
+			enqueueItem(t, store, i, poodle.PriorityNormal)
+		}
+
+		var mutex sync.Mutex
+		leased := make(map[string]int)
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					item, err := store.Dequeue(ctx, poodle.PriorityNormal, time.Minute)
+					if err != nil {
+						t.Errorf("Expected dequeue to succeed, got %v", err)
+						return
+					}
+					if item == nil {
+						return
+					}
+					mutex.Lock()
+					leased[item.ID]++
+					mutex.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(leased) != items {
+			t.Errorf("Expected %d items leased, got %d", items, len(leased))
+		}
+		for id, n := range leased {
+			if n != 1 {
+				t.Errorf("Expected item %s to be leased once, got %d", id, n)
+			}
+		}
+	})
+}
+
+func enqueueItem(t *testing.T, store poodle.QueueStore, index int, priority poodle.Priority) *poodle.QueueItem {
+	t.Helper()
+	subject := fmt.Sprintf("Email %d", index)
+	item := &poodle.QueueItem{
+		Index:      index,
+		Priority:   priority,
+		Email:      poodle.NewTextEmail("from@example.com", "to@example.com", subject, "Hello"),
+		EnqueuedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if err := store.Enqueue(context.Background(), item); err != nil {
+		t.Fatalf("Expected enqueue to succeed, got %v", err)
+	}
+	if item.ID == "" {
+		t.Fatal("Expected Enqueue to set the item ID")
+	}
+	return item
+}
+
+func dequeueItem(t *testing.T, store poodle.QueueStore, priority poodle.Priority, visibility time.Duration) *poodle.QueueItem {
+	t.Helper()
+	item, err := store.Dequeue(context.Background(), priority, visibility)
+	if err != nil {
+		t.Fatalf("Expected dequeue to succeed, got %v", err)
+	}
+	return item
+}
+
+func expectLen(t *testing.T, store poodle.QueueStore, high, normal, low int) {
+	t.Helper()
+	depth, err := store.Len(context.Background())
+	if err != nil {
+		t.Fatalf("Expected Len to succeed, got %v", err)
+	}
+	if depth[poodle.PriorityHigh] != high || depth[poodle.PriorityNormal] != normal || depth[poodle.PriorityLow] != low {
+		t.Errorf("Expected %d high, %d normal and %d low items, got %v", high, normal, low, depth)
+	}
+}
@@ -0,0 +1,13 @@
+package poodletest
+
+import (
+	"testing"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+func TestMemoryQueueStore(t *testing.T) {
+	RunQueueStoreTests(t, func(t *testing.T) poodle.QueueStore {
+		return poodle.NewMemoryQueueStore()
+	})
+}
@@ -0,0 +1,279 @@
+// Package poodletest provides an in-process fake Poodle API server for
+// testing code that uses the Poodle Go SDK.
+package poodletest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+// DefaultAPIKey is the API key accepted by a new Server
+const DefaultAPIKey = "poodletest_api_key"
+
+// Response is a programmed response returned by the fake server
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// Server is a fake Poodle API speaking the send-email contract
+type Server struct {
+	*httptest.Server
+
+	// APIKey is the key expected in the Authorization header
+	APIKey string
+
+	mutex     sync.Mutex
+	sent      []poodle.Email
+	responses []Response
+	requests  int
+}
+
+// NewServer starts a fake Poodle API server. Callers should Close it when
+// done.
+func NewServer() *Server {
+	s := &Server{APIKey: DefaultAPIKey}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Config returns a configuration pointing at the server
+func (s *Server) Config() *poodle.Config {
+	config := poodle.NewConfig()
+	config.APIKey = s.APIKey
+	config.BaseURL = s.URL
+	return config
+}
+
+// NewClient returns a Poodle client pointing at the server
+func (s *Server) NewClient() *poodle.Client {
+	return poodle.NewClientWithConfig(s.Config())
+}
+
+// Sent returns a copy of the emails accepted by the server, in order
+func (s *Server) Sent() []poodle.Email {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sent := make([]poodle.Email, len(s.sent))
+	copy(sent, s.sent)
+	return sent
+}
+
+// Requests returns the number of requests received, including rejected ones
+func (s *Server) Requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.requests
+}
+
+// Enqueue programs responses returned for the next requests, in order.
+// Once the programmed responses are used up the server accepts emails again.
+func (s *Server) Enqueue(responses ...Response) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.responses = append(s.responses, responses...)
+}
+
+// Reset clears recorded emails, request counts and programmed responses
+func (s *Server) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sent = nil
+	s.responses = nil
+	s.requests = 0
+}
+
+// handle serves a single send-email request
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests++
+
+	if r.URL.Path != "/v1/send-email" {
+		writeResponse(w, Response{
+			StatusCode: http.StatusNotFound,
+			Body:       `{"success":false,"message":"Not found"}`,
+		})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeResponse(w, Response{
+			StatusCode: http.StatusMethodNotAllowed,
+			Body:       `{"success":false,"message":"Method not allowed"}`,
+		})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.APIKey {
+		writeResponse(w, Unauthorized())
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeResponse(w, ValidationFailed("Content-Type must be application/json"))
+		return
+	}
+
+	var email poodle.Email
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&email); err != nil {
+		writeResponse(w, ValidationFailed("Invalid JSON body: "+err.Error()))
+		return
+	}
+
+	if reason := checkEmail(&email); reason != "" {
+		writeResponse(w, ValidationFailed(reason))
+		return
+	}
+
+	if len(s.responses) > 0 {
+		response := s.responses[0]
+		s.responses = s.responses[1:]
+		if response.StatusCode == http.StatusAccepted {
+			s.sent = append(s.sent, email)
+		}
+		writeResponse(w, response)
+		return
+	}
+
+	s.sent = append(s.sent, email)
+	writeResponse(w, Accepted())
+}
+
+// checkEmail applies the API's schema checks to a decoded email
+func checkEmail(email *poodle.Email) string {
+	var missing []string
+	if email.From == "" {
+		missing = append(missing, "from")
+	}
+	if email.To == "" {
+		missing = append(missing, "to")
+	}
+	if email.Subject == "" {
+		missing = append(missing, "subject")
+	}
+	if len(missing) > 0 {
+		return "Missing required fields: " + strings.Join(missing, ", ")
+	}
+
+	if email.HTML == "" && email.Text == "" {
+		return "Either html or text content is required"
+	}
+
+	return ""
+}
+
+// writeResponse writes a programmed response
+func writeResponse(w http.ResponseWriter, response Response) {
+	for key, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	if w.Header().Get("Content-Type") == "" && response.Body != "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	statusCode := response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusAccepted
+	}
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte(response.Body))
+}
+
+// Accepted returns a 202 response for a queued email
+func Accepted() Response {
+	return Response{
+		StatusCode: http.StatusAccepted,
+		Body:       `{"success":true,"message":"Email queued for sending"}`,
+	}
+}
+
+// ValidationFailed returns a 400 response with the given error details
+func ValidationFailed(details string) Response {
+	return Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       jsonBody(map[string]interface{}{"success": false, "message": "Validation failed", "error": details}),
+	}
+}
+
+// Unauthorized returns a 401 response for an invalid API key
+func Unauthorized() Response {
+	return Response{
+		StatusCode: http.StatusUnauthorized,
+		Body:       `{"success":false,"message":"Invalid or missing API key","error":"invalid_api_key"}`,
+	}
+}
+
+// PaymentRequired returns a 402 response with the given subscription message
+func PaymentRequired(message string) Response {
+	return Response{
+		StatusCode: http.StatusPaymentRequired,
+		Body:       jsonBody(map[string]interface{}{"success": false, "message": message}),
+	}
+}
+
+// Suspended returns a 403 response for a suspended account
+func Suspended(reason string) Response {
+	return Response{
+		StatusCode: http.StatusForbidden,
+		Body:       jsonBody(map[string]interface{}{"success": false, "message": "Account suspended", "error": reason}),
+	}
+}
+
+// QueueFailure returns a 422 response for a job queue failure
+func QueueFailure(message string) Response {
+	return Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Body:       jsonBody(map[string]interface{}{"success": false, "message": message}),
+	}
+}
+
+// RateLimited returns a 429 response with rate limit headers
+func RateLimited(retryAfter time.Duration, limit int) Response {
+	seconds := int(retryAfter / time.Second)
+	return Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header: http.Header{
+			"Retry-After":         {strconv.Itoa(seconds)},
+			"Ratelimit-Limit":     {strconv.Itoa(limit)},
+			"Ratelimit-Remaining": {"0"},
+			"Ratelimit-Reset":     {strconv.FormatInt(time.Now().Add(retryAfter).Unix(), 10)},
+		},
+		Body: jsonBody(map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("Rate limit exceeded. Retry after %d seconds.", seconds),
+		}),
+	}
+}
+
+// ServerError returns a 500 response
+func ServerError() Response {
+	return Response{
+		StatusCode: http.StatusInternalServerError,
+		Body:       `{"success":false,"message":"Internal server error"}`,
+	}
+}
+
+// jsonBody encodes a response body
+func jsonBody(body map[string]interface{}) string {
+	data, _ := json.Marshal(body)
+	return string(data)
+}
@@ -0,0 +1,144 @@
+package poodletest
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/usepoodle/poodle-go"
+)
+
+func TestServerRecordsSentEmails(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	client := server.NewClient()
+
+	response, err := client.SendHTML("from@example.com", "to@example.com", "Hello", "<h1>Hi</h1>")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !response.Success {
+		t.Error("Expected successful response")
+	}
+
+	sent := server.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 sent email, got %d", len(sent))
+	}
+	if sent[0].To != "to@example.com" || sent[0].HTML != "<h1>Hi</h1>" {
+		t.Errorf("Unexpected sent email: %+v", sent[0])
+	}
+}
+
+func TestServerProgrammedResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		response Response
+		check    func(err error) bool
+	}{
+		{"Validation", ValidationFailed("bad"), func(err error) bool { _, ok := err.(*poodle.ValidationError); return ok }},
+		{"Unauthorized", Unauthorized(), func(err error) bool { _, ok := err.(*poodle.AuthenticationError); return ok }},
+		{"Payment required", PaymentRequired("Subscription expired"), func(err error) bool { _, ok := err.(*poodle.SubscriptionError); return ok }},
+		{"Suspended", Suspended("abuse"), func(err error) bool { _, ok := err.(*poodle.AccountSuspendedError); return ok }},
+		{"Queue failure", QueueFailure("Queue unavailable"), func(err error) bool { return err != nil }},
+		{"Rate limited", RateLimited(30*time.Second, 100), func(err error) bool {
+			rateLimitErr, ok := err.(*poodle.RateLimitError)
+			return ok && rateLimitErr.RetryAfter == 30 && rateLimitErr.Limit == 100
+		}},
+		{"Server error", ServerError(), func(err error) bool { _, ok := err.(*poodle.HTTPError); return ok }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer()
+			defer server.Close()
+
+			server.Enqueue(tt.response)
+			client := server.NewClient()
+
+			_, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+			if !tt.check(err) {
+				t.Errorf("Unexpected error: %T %v", err, err)
+			}
+			if len(server.Sent()) != 0 {
+				t.Error("Expected rejected email not to be recorded")
+			}
+
+			// Once the programmed response is used the server accepts again
+			if _, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi"); err != nil {
+				t.Errorf("Expected no error after programmed response, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestServerRejectsInvalidAPIKey(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	config := server.Config()
+	config.APIKey = "wrong_key"
+	client := poodle.NewClientWithConfig(config)
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	if _, ok := err.(*poodle.AuthenticationError); !ok {
+		t.Fatalf("Expected AuthenticationError, got %T", err)
+	}
+	if server.Requests() != 1 {
+		t.Errorf("Expected 1 request, got %d", server.Requests())
+	}
+}
+
+func TestServerValidatesRequestSchema(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", `{"from":`},
+		{"Unknown field", `{"from":"a@b.com","to":"c@d.com","subject":"s","text":"t","bcc":"x@y.com"}`},
+		{"Missing fields", `{"text":"t"}`},
+		{"Missing content", `{"from":"a@b.com","to":"c@d.com","subject":"s"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/send-email", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+server.APIKey)
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", resp.StatusCode)
+			}
+		})
+	}
+
+	if len(server.Sent()) != 0 {
+		t.Error("Expected invalid requests not to be recorded")
+	}
+}
+
+func TestServerReset(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	client := server.NewClient()
+	server.Enqueue(ServerError())
+	_, _ = client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	_, _ = client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+
+	server.Reset()
+	if len(server.Sent()) != 0 || server.Requests() != 0 {
+		t.Error("Expected Reset to clear recorded state")
+	}
+}
@@ -0,0 +1,184 @@
+package poodle
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Preview file names written by Email.WritePreview
+const (
+	PreviewHTMLFile = "preview.html"
+	PreviewTextFile = "preview.txt"
+	PreviewMetaFile = "meta.json"
+	// PreviewImagesDir holds the images embedded in the HTML body as
+	// data: URIs, e.g. by InlineAssets
+	PreviewImagesDir = "images"
+)
+
+// previewMeta is the content of meta.json
+type previewMeta struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Subject string            `json:"subject"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Images are the files written to PreviewImagesDir, relative to the
+	// preview directory
+	Images []string `json:"images,omitempty"`
+}
+
+// WritePreview writes the email into dir, creating it if needed, to be
+// looked at before it is sent: the HTML body as preview.html, the text
+// body as preview.txt and the addresses, subject and headers as
+// meta.json. Images embedded in the HTML as data: URIs are written to the
+// images directory and referenced from preview.html by relative path, so
+// the preview opens from disk without a network. Existing files are
+// replaced.
+//
+// The email is written as it is; call Render first to preview an email
+// with variables as AutoRender would send it.
+func (e *Email) WritePreview(dir string) error {
+	if e == nil {
+		return newNilEmailError()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	html, images := materializeImages(e.HTML)
+	meta := previewMeta{
+		From:    e.wireFrom(),
+		To:      e.wireTo(),
+		Subject: e.Subject,
+		Headers: e.Headers,
+	}
+	if len(images) > 0 {
+		if err := os.MkdirAll(filepath.Join(dir, PreviewImagesDir), 0o755); err != nil {
+			return err
+		}
+	}
+	for _, image := range images {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(image.name)), image.data, 0o644); err != nil {
+			return err
+		}
+		meta.Images = append(meta.Images, image.name)
+	}
+
+	metaData, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{PreviewHTMLFile, []byte(html)},
+		{PreviewTextFile, []byte(e.Text)},
+		{PreviewMetaFile, append(metaData, '\n')},
+	}
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, file.name), file.data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PreviewDataURL returns the HTML body as a data:text/html;base64 URL, to
+// open the email in a browser without writing it to disk. It returns a
+// ValidationError if the email has no HTML body.
+func (e *Email) PreviewDataURL() (string, error) {
+	if e == nil {
+		return "", newNilEmailError()
+	}
+	if strings.TrimSpace(e.HTML) == "" {
+		return "", NewValidationError("Email has no HTML to preview", map[string][]string{
+			"html": {"HTML body is required for a preview URL"},
+		})
+	}
+	return "data:text/html;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte(e.HTML)), nil
+}
+
+// previewImage is an image of the HTML body written by WritePreview
+type previewImage struct {
+	name string
+	data []byte
+}
+
+// materializeImages returns the markup with the src of img tags holding a
+// base64 data: URI replaced by the path of a file in PreviewImagesDir, and
+// the files. Identical images share a file; URIs that cannot be decoded
+// are left in place.
+func materializeImages(s string) (string, []previewImage) {
+	var images []previewImage
+	names := make(map[string]string)
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, token := range tokenizeHTML(s) {
+		tag := token.tag
+		if tag == nil {
+			b.WriteString(token.raw)
+			continue
+		}
+
+		raw := token.raw
+		if tag.name == "img" && !tag.end {
+			src := strings.TrimSpace(tagAttr(tag, "src"))
+			name, seen := names[src]
+			if !seen {
+				if contentType, data, ok := decodeDataURI(src); ok {
+					name = fmt.Sprintf("%s/image-%d%s", PreviewImagesDir, len(images)+1, imageExtension(contentType))
+					images = append(images, previewImage{name: name, data: data})
+					names[src] = name
+				}
+			}
+			if name != "" {
+				raw = tag.rebuild(setTagAttr(tag.attrs, "src", name))
+			}
+		}
+		b.WriteString(raw)
+		b.WriteString(token.content)
+		b.WriteString(token.closing)
+	}
+	return b.String(), images
+}
+
+// decodeDataURI decodes a base64 data: URI
+func decodeDataURI(uri string) (contentType string, data []byte, ok bool) {
+	if !strings.HasPrefix(strings.ToLower(uri), "data:") {
+		return "", nil, false
+	}
+	header, payload, found := strings.Cut(uri[len("data:"):], ",")
+	if !found || !strings.HasSuffix(strings.ToLower(header), ";base64") {
+		return "", nil, false
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(payload), ""))
+	if err != nil {
+		return "", nil, false
+	}
+	contentType, _, _ = mime.ParseMediaType(strings.TrimSuffix(header[:len(header)-len(";base64")], ";"))
+	return contentType, data, true
+}
+
+// imageExtension returns the file extension of an image content type
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	if extensions, _ := mime.ExtensionsByType(contentType); len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ".bin"
+}
@@ -0,0 +1,106 @@
+package poodle
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWritePreview(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake image")
+	uri := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	email := NewEmailWithBoth(
+		"from@example.com",
+		"to@example.com",
+		"Spring sale",
+		`<p>Hello</p><img src="`+uri+`" alt="logo"><img src='`+uri+`'><img src="https://cdn.example.com/a.png">`,
+		"Hello",
+	)
+	email.ToAddress = &Address{Name: "Jane Doe", Email: "jane@example.com"}
+	email.SetHeader("List-Unsubscribe", "<https://example.com/unsubscribe>")
+
+	dir := filepath.Join(t.TempDir(), "preview")
+	if err := email.WritePreview(dir); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	html, err := os.ReadFile(filepath.Join(dir, PreviewHTMLFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<p>Hello</p><img src="images/image-1.png" alt="logo"><img src="images/image-1.png"><img src="https://cdn.example.com/a.png">`
+	if string(html) != want {
+		t.Errorf("Expected %s, got %s", want, html)
+	}
+	if image, err := os.ReadFile(filepath.Join(dir, "images", "image-1.png")); err != nil || !bytes.Equal(image, png) {
+		t.Errorf("Expected the decoded image, got %q, %v", image, err)
+	}
+	if text, err := os.ReadFile(filepath.Join(dir, PreviewTextFile)); err != nil || string(text) != "Hello" {
+		t.Errorf("Expected the text body, got %q, %v", text, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, PreviewMetaFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	wantMeta := map[string]interface{}{
+		"from":    "from@example.com",
+		"to":      "Jane Doe <jane@example.com>",
+		"subject": "Spring sale",
+		"headers": map[string]interface{}{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+		"images":  []interface{}{"images/image-1.png"},
+	}
+	if !reflect.DeepEqual(meta, wantMeta) {
+		t.Errorf("Expected %v, got %v", wantMeta, meta)
+	}
+	if !strings.Contains(email.HTML, uri) {
+		t.Error("Expected the email to be left alone")
+	}
+
+	// Writing again replaces the files
+	email.HTML = "<p>Updated</p>"
+	if err := email.WritePreview(dir); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if html, _ := os.ReadFile(filepath.Join(dir, PreviewHTMLFile)); string(html) != email.HTML {
+		t.Errorf("Expected the file to be replaced, got %s", html)
+	}
+
+	var nilEmail *Email
+	if err := nilEmail.WritePreview(dir); err == nil {
+		t.Error("Expected an error for a nil email")
+	}
+}
+
+func TestPreviewDataURL(t *testing.T) {
+	html := "<h1>Grüße</h1>\n<p>50% off &amp; more +/=</p>"
+	url, err := NewHTMLEmail("from@example.com", "to@example.com", "Subject", html).PreviewDataURL()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	const prefix = "data:text/html;charset=utf-8;base64,"
+	if !strings.HasPrefix(url, prefix) {
+		t.Fatalf("Expected a base64 HTML data URL, got %s", url)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(url, prefix))
+	if err != nil || !bytes.Equal(decoded, []byte(html)) {
+		t.Errorf("Expected the URL to decode to the HTML, got %q, %v", decoded, err)
+	}
+
+	_, err = NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello").PreviewDataURL()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected ValidationError for an email without HTML, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package poodle
+
+// DefaultStarvationLimit is how many higher-priority emails are dispatched
+// in a row while a lower-priority email waits before the lower-priority
+// email is dispatched
+const DefaultStarvationLimit = 10
+
+// Priority orders emails in the SDK's queues. It is not sent to the API.
+type Priority int
+
+// Email priorities. The zero value is PriorityNormal.
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+	PriorityLow
+)
+
+// priorityClasses is the number of priorities
+const priorityClasses = 3
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityNormal:
+		return "normal"
+	case PriorityLow:
+		return "low"
+	default:
+		return "unknown"
+	}
+}
+
+// rank returns the dispatch position of the priority, 0 being dispatched
+// first. Unknown priorities are treated as normal.
+func (p Priority) rank() int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// valid reports whether p is one of the defined priorities
+func (p Priority) valid() bool {
+	return p == PriorityHigh || p == PriorityNormal || p == PriorityLow
+}
+
+// priorityOfRank is the inverse of rank
+func priorityOfRank(rank int) Priority {
+	return [...]Priority{PriorityHigh, PriorityNormal, PriorityLow}[rank]
+}
+
+// starvationGuard picks the priority class to dispatch next: the highest
+// waiting class, unless a lower class has been passed over limit times in
+// a row
+type starvationGuard struct {
+	limit   int
+	skipped [priorityClasses]int
+}
+
+// pick returns the rank of the class to dispatch from, given the number of
+// waiting emails per rank. ok is false if nothing is waiting.
+func (g *starvationGuard) pick(depth [priorityClasses]int) (rank int, ok bool) {
+	rank = -1
+	if g.limit > 0 {
+		for r := 1; r < priorityClasses; r++ {
+			if depth[r] > 0 && g.skipped[r] >= g.limit {
+				rank = r
+				break
+			}
+		}
+	}
+	if rank < 0 {
+		for r := 0; r < priorityClasses; r++ {
+			if depth[r] > 0 {
+				rank = r
+				break
+			}
+		}
+	}
+	if rank < 0 {
+		return 0, false
+	}
+
+	g.skipped[rank] = 0
+	for r := rank + 1; r < priorityClasses; r++ {
+		if depth[r] > 0 {
+			g.skipped[r]++
+		}
+	}
+	return rank, true
+}
@@ -0,0 +1,238 @@
+package poodle
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Progress reporting defaults
+const (
+	// DefaultProgressInterval is how often progress is reported unless
+	// ProgressInterval says otherwise
+	DefaultProgressInterval = time.Second
+	// ProgressRateWindow is the time constant of the moving average in
+	// Progress.Rate: sends older than the window weigh about a third as
+	// much as current ones
+	ProgressRateWindow = 10 * time.Second
+)
+
+// Progress reports how far a SendAll batch or a Queue has got
+type Progress struct {
+	// Completed is the number of emails sent successfully
+	Completed int
+	// Failed is the number of emails that failed or, in a batch, were
+	// skipped. Emails a Queue sends again are counted once they are
+	// acknowledged.
+	Failed int
+	// Remaining is the number of emails not yet completed or failed,
+	// including those being sent. For a Queue it is zero if the store
+	// could not be read.
+	Remaining int
+	// ElapsedTime is the time since the batch or queue started
+	ElapsedTime time.Duration
+	// Rate is an exponentially weighted moving average of the emails
+	// completed or failed per second, over ProgressRateWindow
+	Rate float64
+}
+
+// ProgressOption configures when progress is reported
+type ProgressOption func(*progressSettings)
+
+// progressSettings holds the triggers of a progress callback
+type progressSettings struct {
+	interval time.Duration
+	every    int
+}
+
+// ProgressInterval reports progress every d. Zero disables the interval,
+// which with ProgressEvery reports only every n emails.
+func ProgressInterval(d time.Duration) ProgressOption {
+	return func(s *progressSettings) {
+		if d >= 0 {
+			s.interval = d
+		}
+	}
+}
+
+// ProgressEvery also reports progress whenever n more emails have completed
+// or failed
+func ProgressEvery(n int) ProgressOption {
+	return func(s *progressSettings) {
+		if n >= 0 {
+			s.every = n
+		}
+	}
+}
+
+func newProgressSettings(opts []ProgressOption) progressSettings {
+	settings := progressSettings{interval: DefaultProgressInterval}
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	return settings
+}
+
+// WithProgress calls fn with the progress of a SendAll batch every
+// DefaultProgressInterval, or as set with ProgressInterval and
+// ProgressEvery, and once more when the batch is done. fn is never called
+// concurrently with itself, and the counters it sees never decrease. A
+// trigger that fires while fn is running is skipped. SendStream ignores
+// this option.
+func WithProgress(fn func(Progress), opts ...ProgressOption) BatchOption {
+	return func(o *batchOptions) {
+		o.progress = fn
+		o.progressSettings = newProgressSettings(opts)
+	}
+}
+
+// progressTracker counts the outcomes of a batch or queue and reports them
+type progressTracker struct {
+	clock     Clock
+	fn        func(Progress)
+	settings  progressSettings
+	remaining func(done int) int
+	started   time.Time
+
+	mutex     sync.Mutex
+	completed int
+	failed    int
+	unticked  int // outcomes since the last report triggered by ProgressEvery
+
+	// reporting is held while a report is computed and passed to fn, so
+	// that reports are ordered and fn is not called concurrently
+	reporting  sync.Mutex
+	lastReport time.Time
+	lastDone   int
+	rate       float64
+	sampled    bool
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// newProgressTracker starts tracking. remaining returns the number of
+// emails left given the number completed or failed. fn may be nil, in
+// which case only snapshots are taken.
+func newProgressTracker(clock Clock, fn func(Progress), settings progressSettings, remaining func(done int) int) *progressTracker {
+	now := clock.Now()
+	p := &progressTracker{
+		clock:      clock,
+		fn:         fn,
+		settings:   settings,
+		remaining:  remaining,
+		started:    now,
+		lastReport: now,
+	}
+	if fn != nil && settings.interval > 0 {
+		p.stop = make(chan struct{})
+		p.done = make(chan struct{})
+		go p.tick()
+	}
+	return p
+}
+
+// tick reports progress at the configured interval until close
+func (p *progressTracker) tick() {
+	defer close(p.done)
+
+	// A real ticker rather than the clock, so that a fake clock whose
+	// Sleep returns at once does not make the reports spin
+	ticker := time.NewTicker(p.settings.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.tryReport()
+		}
+	}
+}
+
+// record counts the outcome of an email and reports progress if
+// ProgressEvery emails have finished since the last such report
+func (p *progressTracker) record(err error) {
+	p.mutex.Lock()
+	if err == nil {
+		p.completed++
+	} else {
+		p.failed++
+	}
+	p.unticked++
+	trigger := p.fn != nil && p.settings.every > 0 && p.unticked >= p.settings.every
+	if trigger {
+		p.unticked = 0
+	}
+	p.mutex.Unlock()
+
+	if trigger {
+		p.tryReport()
+	}
+}
+
+// tryReport reports progress unless a report is already running
+func (p *progressTracker) tryReport() {
+	if !p.reporting.TryLock() {
+		return
+	}
+	defer p.reporting.Unlock()
+	p.fn(p.snapshotLocked())
+}
+
+// snapshot returns the current progress
+func (p *progressTracker) snapshot() Progress {
+	p.reporting.Lock()
+	defer p.reporting.Unlock()
+	return p.snapshotLocked()
+}
+
+// close stops the interval reports and makes a final report. Only the
+// first call has an effect.
+func (p *progressTracker) close() {
+	p.closeOnce.Do(func() {
+		if p.stop != nil {
+			close(p.stop)
+			<-p.done
+		}
+		if p.fn == nil {
+			return
+		}
+
+		p.reporting.Lock()
+		defer p.reporting.Unlock()
+		p.fn(p.snapshotLocked())
+	})
+}
+
+// snapshotLocked computes the progress and updates the moving average. It
+// must be called with the reporting mutex held.
+func (p *progressTracker) snapshotLocked() Progress {
+	p.mutex.Lock()
+	completed, failed := p.completed, p.failed
+	p.mutex.Unlock()
+
+	now := p.clock.Now()
+	done := completed + failed
+	if elapsed := now.Sub(p.lastReport); elapsed > 0 {
+		current := float64(done-p.lastDone) / elapsed.Seconds()
+		if !p.sampled {
+			p.rate = current
+			p.sampled = true
+		} else {
+			weight := 1 - math.Exp(-float64(elapsed)/float64(ProgressRateWindow))
+			p.rate += weight * (current - p.rate)
+		}
+		p.lastReport = now
+		p.lastDone = done
+	}
+
+	return Progress{
+		Completed:   completed,
+		Failed:      failed,
+		Remaining:   p.remaining(done),
+		ElapsedTime: now.Sub(p.started),
+		Rate:        p.rate,
+	}
+}
@@ -0,0 +1,202 @@
+package poodle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// progressRecorder collects progress reports and checks that they are not
+// delivered concurrently
+type progressRecorder struct {
+	t       *testing.T
+	delay   time.Duration
+	running int32
+	mutex   sync.Mutex
+	reports []Progress
+}
+
+func (r *progressRecorder) report(p Progress) {
+	if atomic.AddInt32(&r.running, 1) != 1 {
+		r.t.Error("Expected the progress callback not to run concurrently")
+	}
+	time.Sleep(r.delay)
+	r.mutex.Lock()
+	r.reports = append(r.reports, p)
+	r.mutex.Unlock()
+	atomic.AddInt32(&r.running, -1)
+}
+
+// check verifies that the counters never decrease and returns the last
+// report
+func (r *progressRecorder) check(total int) Progress {
+	r.t.Helper()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.reports) == 0 {
+		r.t.Fatal("Expected progress to be reported")
+	}
+	for i, p := range r.reports {
+		if p.Remaining < 0 || p.Completed+p.Failed+p.Remaining > total || p.Rate < 0 {
+			r.t.Errorf("Report %d is inconsistent: %+v", i, p)
+		}
+		if i == 0 {
+			continue
+		}
+		previous := r.reports[i-1]
+		if p.Completed < previous.Completed || p.Failed < previous.Failed || p.ElapsedTime < previous.ElapsedTime || p.Remaining > previous.Remaining {
+			r.t.Errorf("Expected monotonic progress, got %+v after %+v", p, previous)
+		}
+	}
+	return r.reports[len(r.reports)-1]
+}
+
+func progressTestClient(delay time.Duration) *Client {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		time.Sleep(delay)
+		body, _ := io.ReadAll(req.Body)
+		if strings.Contains(string(body), "broken@") {
+			return jsonResponse(http.StatusBadRequest, `{"message":"Invalid recipient"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+	return client
+}
+
+func progressEmails(n int) []*Email {
+	emails := make([]*Email, n)
+	for i := range emails {
+		to := "to@example.com"
+		if i%5 == 0 {
+			to = "broken@example.com"
+		}
+		emails[i] = NewTextEmail("from@example.com", to, "Hello", "Hello")
+	}
+	return emails
+}
+
+func TestSendAllProgressEvery(t *testing.T) {
+	client := progressTestClient(time.Millisecond)
+	recorder := &progressRecorder{t: t, delay: 2 * time.Millisecond}
+
+	_, err := client.SendAll(context.Background(), progressEmails(40),
+		WithConcurrency(8),
+		WithProgress(recorder.report, ProgressInterval(0), ProgressEvery(5)))
+	if err == nil {
+		t.Fatal("Expected a MultiError")
+	}
+
+	last := recorder.check(40)
+	if last.Completed != 32 || last.Failed != 8 || last.Remaining != 0 {
+		t.Errorf("Expected a final report of 32 completed and 8 failed, got %+v", last)
+	}
+	// A report triggered while the callback runs is skipped, so there are
+	// at most 8 reports from ProgressEvery and the final one
+	if n := len(recorder.reports); n < 2 || n > 9 {
+		t.Errorf("Expected 2 to 9 reports, got %d", n)
+	}
+}
+
+func TestSendAllProgressInterval(t *testing.T) {
+	client := progressTestClient(5 * time.Millisecond)
+	recorder := &progressRecorder{t: t}
+
+	client.SendAll(context.Background(), progressEmails(30),
+		WithConcurrency(2),
+		WithProgress(recorder.report, ProgressInterval(10*time.Millisecond)))
+
+	last := recorder.check(30)
+	if len(recorder.reports) < 3 {
+		t.Errorf("Expected several interval reports, got %d", len(recorder.reports))
+	}
+	if last.Completed+last.Failed != 30 || last.Remaining != 0 {
+		t.Errorf("Expected every email in the final report, got %+v", last)
+	}
+	if last.Rate <= 0 || last.ElapsedTime <= 0 {
+		t.Errorf("Expected a positive rate and elapsed time, got %+v", last)
+	}
+}
+
+func TestSendAllProgressCountsSkipped(t *testing.T) {
+	client := progressTestClient(0)
+	recorder := &progressRecorder{t: t}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.SendAll(ctx, progressEmails(10), WithProgress(recorder.report, ProgressInterval(time.Hour)))
+
+	last := recorder.check(10)
+	if len(recorder.reports) != 1 || last.Failed != 10 || last.Remaining != 0 {
+		t.Errorf("Expected only a final report with 10 skipped emails, got %+v", recorder.reports)
+	}
+}
+
+func TestQueueProgress(t *testing.T) {
+	tr := newGatedTransport()
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = tr
+
+	recorder := &progressRecorder{t: t, delay: time.Millisecond}
+	queue := NewQueue(client, WithQueueWorkers(2), WithQueueProgress(recorder.report, ProgressInterval(5*time.Millisecond), ProgressEvery(3)))
+
+	queue.Enqueue(priorityEmail("warmup", PriorityNormal))
+	<-tr.started
+	for i := 0; i < 9; i++ {
+		if err := queue.Enqueue(priorityEmail("Hello", PriorityNormal)); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+	}
+
+	// The gated worker holds one email; the other worker is blocked
+	// behind the gate's sync.Once, so both are in flight
+	snapshot := queue.Snapshot()
+	if snapshot.Completed != 0 || snapshot.Remaining != 10 {
+		t.Errorf("Expected 10 emails remaining, got %+v", snapshot)
+	}
+
+	close(tr.release)
+	queue.Close()
+
+	last := recorder.check(10)
+	if last.Completed != 10 || last.Failed != 0 || last.Remaining != 0 {
+		t.Errorf("Expected a final report of 10 completed, got %+v", last)
+	}
+	if snapshot := queue.Snapshot(); snapshot.Completed != 10 || snapshot.Remaining != 0 {
+		t.Errorf("Expected the snapshot to match the final report, got %+v", snapshot)
+	}
+}
+
+func TestProgressRate(t *testing.T) {
+	clock := newTestClock()
+	p := newProgressTracker(clock, nil, progressSettings{}, func(done int) int { return 100 - done })
+
+	for i := 0; i < 10; i++ {
+		p.record(nil)
+	}
+	clock.Sleep(context.Background(), time.Second)
+	if rate := p.snapshot().Rate; rate != 10 {
+		t.Errorf("Expected the first rate to be 10/s, got %v", rate)
+	}
+
+	// A slower second isn't weighted fully
+	for i := 0; i < 2; i++ {
+		p.record(nil)
+	}
+	clock.Sleep(context.Background(), time.Second)
+	rate := p.snapshot().Rate
+	if rate >= 10 || rate <= 2 {
+		t.Errorf("Expected the rate to fall between 2/s and 10/s, got %v", rate)
+	}
+
+	// A snapshot taken without time passing leaves the rate alone
+	if again := p.snapshot().Rate; again != rate {
+		t.Errorf("Expected the rate to stay %v, got %v", rate, again)
+	}
+}
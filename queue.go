@@ -0,0 +1,724 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Queue defaults
+const (
+	// DefaultQueueWorkers is the number of emails a Queue sends at once
+	DefaultQueueWorkers = 4
+	// DefaultQueueVisibilityTimeout is how long an email being sent is
+	// hidden from other workers before it is sent again
+	DefaultQueueVisibilityTimeout = 5 * time.Minute
+	// DefaultQueuePollInterval is how often an idle Queue checks its store
+	// for emails enqueued by other processes
+	DefaultQueuePollInterval = time.Second
+	DefaultQueueBackoff      = time.Second
+	DefaultQueueMaxBackoff   = time.Minute
+)
+
+// ErrQueueClosed is returned when enqueuing to a Queue that is closed,
+// stopped or shutting down
+var ErrQueueClosed = errors.New("poodle: queue is closed")
+
+// QueueStats is a snapshot of a Queue
+type QueueStats struct {
+	// Depth is the number of waiting emails per priority. It is nil if
+	// the store could not be read.
+	Depth map[Priority]int
+	// InFlight is the number of emails being sent
+	InFlight int
+}
+
+// ShutdownItem is an email accounted for in a ShutdownReport
+type ShutdownItem struct {
+	// ID is the QueueItem.ID set by the store
+	ID       string
+	Index    int
+	Priority Priority
+	// Fingerprint is Email.Fingerprint, to match the email with the
+	// application's records
+	Fingerprint string
+	// Err is why the email was not delivered: the error of its last
+	// attempt, or the Shutdown context's error if it was not sent in time
+	Err error
+}
+
+// ShutdownReport lists what happened to the emails a Queue sent or held
+// from the call to Shutdown until it returned
+type ShutdownReport struct {
+	// Delivered are the emails sent during the drain
+	Delivered []ShutdownItem
+	// Persisted are the emails stored in Config.DeadLetter
+	Persisted []ShutdownItem
+	// Abandoned are the emails neither sent nor stored in
+	// Config.DeadLetter. Emails that failed for good are dropped; emails
+	// whose send was cancelled or that were still waiting are left in the
+	// QueueStore for the next Queue on it, and with the default in-memory
+	// store are dropped with it.
+	Abandoned []ShutdownItem
+	// Unlisted is the number of emails left in the store that were
+	// waiting to be retried after a failed attempt, and so could not be
+	// dequeued to be listed
+	Unlisted int
+}
+
+// QueueOption configures a Queue
+type QueueOption func(*Queue)
+
+// WithQueueWorkers sets how many emails the queue sends at once
+func WithQueueWorkers(n int) QueueOption {
+	return func(q *Queue) {
+		if n > 0 {
+			q.workers = n
+		}
+	}
+}
+
+// WithStarvationLimit sets how many higher-priority emails are sent in a
+// row before a waiting lower-priority email is sent. Zero disables the
+// guard, so lower priorities wait until higher ones are empty.
+func WithStarvationLimit(n int) QueueOption {
+	return func(q *Queue) {
+		q.guard.limit = n
+	}
+}
+
+// WithResultHandler sets a function called with the result of every email
+// the queue sends. It is called from the queue's workers.
+func WithResultHandler(handler func(SendResult)) QueueOption {
+	return func(q *Queue) {
+		q.onResult = handler
+	}
+}
+
+// WithQueueProgress calls fn with the progress of the queue every
+// DefaultProgressInterval, or as set with ProgressInterval and
+// ProgressEvery, and once more when the queue is closed or stopped. fn is
+// never called concurrently with itself, and the counters it sees never
+// decrease. A trigger that fires while fn is running is skipped.
+func WithQueueProgress(fn func(Progress), opts ...ProgressOption) QueueOption {
+	return func(q *Queue) {
+		q.onProgress = fn
+		q.progressSettings = newProgressSettings(opts)
+	}
+}
+
+// WithQueueStore keeps the queued emails in store instead of in memory
+func WithQueueStore(store QueueStore) QueueOption {
+	return func(q *Queue) {
+		q.store = store
+	}
+}
+
+// WithQueueVisibilityTimeout sets how long an email being sent is hidden
+// from other workers. An email that is not acknowledged in time, e.g.
+// because its process crashed, is sent again, so the timeout should be
+// longer than a send including its retries.
+func WithQueueVisibilityTimeout(d time.Duration) QueueOption {
+	return func(q *Queue) {
+		if d > 0 {
+			q.visibility = d
+		}
+	}
+}
+
+// WithQueuePollInterval sets how often an idle queue checks its store.
+// Emails enqueued through the same Queue are sent without waiting.
+func WithQueuePollInterval(d time.Duration) QueueOption {
+	return func(q *Queue) {
+		if d > 0 {
+			q.pollInterval = d
+		}
+	}
+}
+
+// WithQueueBackoff sets the delay after a store error, and before sending
+// an email again after a retryable failure, which doubles with every
+// consecutive failure up to maxDelay
+func WithQueueBackoff(initial, maxDelay time.Duration) QueueOption {
+	return func(q *Queue) {
+		q.backoff = initial
+		q.maxBackoff = maxDelay
+	}
+}
+
+// WithQueueMaxAttempts sends an email up to n times while it fails with a
+// retryable error, on top of the client's own retries. By default every
+// email is sent once.
+func WithQueueMaxAttempts(n int) QueueOption {
+	return func(q *Queue) {
+		if n > 0 {
+			q.maxAttempts = n
+		}
+	}
+}
+
+// Queue sends emails in the background, dispatching them by Email.Priority
+// so that e.g. password resets do not wait behind a newsletter. Emails of
+// the same priority are dispatched in the order they were enqueued; a
+// starvation guard keeps lower priorities progressing under a steady
+// stream of higher-priority emails.
+//
+// The emails are held in a QueueStore, in memory by default. When the
+// store fails, the workers log the error and back off until it recovers,
+// with the delay set by WithQueueBackoff.
+type Queue struct {
+	client       *Client
+	store        QueueStore
+	workers      int
+	onResult     func(SendResult)
+	visibility   time.Duration
+	pollInterval time.Duration
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	maxAttempts  int
+
+	ctx    context.Context // cancelled when a Shutdown runs out of time
+	cancel context.CancelFunc
+
+	onProgress       func(Progress)
+	progressSettings progressSettings
+	progress         *progressTracker
+
+	mutex    sync.Mutex
+	history  map[string][]AttemptError // failed attempts by item ID
+	guard    starvationGuard
+	next     int
+	inFlight int
+	// enqueuing is the number of Enqueue calls writing to the store
+	enqueuing int
+	report    *ShutdownReport // set while Shutdown runs
+	parked    []string        // IDs of the emails park kept leased
+	closed    bool
+	stopped   bool
+	notify    chan struct{} // closed when there may be work
+	wg        sync.WaitGroup
+}
+
+// NewQueue starts a queue sending through the client
+func NewQueue(client *Client, opts ...QueueOption) *Queue {
+	q := &Queue{
+		client:       client,
+		workers:      DefaultQueueWorkers,
+		visibility:   DefaultQueueVisibilityTimeout,
+		pollInterval: DefaultQueuePollInterval,
+		backoff:      DefaultQueueBackoff,
+		maxBackoff:   DefaultQueueMaxBackoff,
+		maxAttempts:  1,
+		history:      make(map[string][]AttemptError),
+		guard:        starvationGuard{limit: DefaultStarvationLimit},
+		notify:       make(chan struct{}),
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.store == nil {
+		q.store = newMemoryQueueStore(client.httpClient.clock)
+	}
+	q.progress = newProgressTracker(client.httpClient.clock, q.onProgress, q.progressSettings, q.remaining)
+
+	for w := 0; w < q.workers; w++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue adds the email to the queue. The result is passed to the
+// handler set with WithResultHandler, with Index counting the emails
+// enqueued through this Queue from zero; an email the store fails to
+// take uses up its Index. It returns a ValidationError if Email.Priority
+// is not one of the defined priorities, and the store's error if the email
+// could not be stored.
+func (q *Queue) Enqueue(email *Email) error {
+	if email == nil {
+		return newNilEmailError()
+	}
+	if !email.Priority.valid() {
+		return NewValidationError("Invalid queue priority", map[string][]string{
+			"priority": {fmt.Sprintf("Priority %d is not one of the queue priorities", email.Priority)},
+		})
+	}
+
+	// The store is written without the mutex, so that enqueuing does not
+	// hold up the workers. Counting the pending write keeps Close from
+	// finishing before the email is stored.
+	q.mutex.Lock()
+	if q.closed || q.stopped {
+		q.mutex.Unlock()
+		return ErrQueueClosed
+	}
+	item := &QueueItem{
+		Index:      q.next,
+		Priority:   email.Priority,
+		Email:      email,
+		EnqueuedAt: q.client.httpClient.clock.Now().UTC(),
+	}
+	q.next++
+	q.enqueuing++
+	q.mutex.Unlock()
+
+	err := q.store.Enqueue(context.Background(), item)
+
+	q.mutex.Lock()
+	q.enqueuing--
+	q.wake()
+	q.mutex.Unlock()
+	return err
+}
+
+// Stats returns the number of waiting emails per priority and the number
+// of emails being sent
+func (q *Queue) Stats() QueueStats {
+	depth, err := q.store.Len(context.Background())
+	if err != nil {
+		depth = nil
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return QueueStats{Depth: depth, InFlight: q.inFlight}
+}
+
+// Snapshot returns the progress of the queue since it was created, for
+// callers that poll rather than use WithQueueProgress
+func (q *Queue) Snapshot() Progress {
+	return q.progress.snapshot()
+}
+
+// remaining returns the number of waiting and in-flight emails
+func (q *Queue) remaining(int) int {
+	depth, err := q.store.Len(context.Background())
+	if err != nil {
+		return 0
+	}
+
+	q.mutex.Lock()
+	remaining := q.inFlight
+	q.mutex.Unlock()
+	for _, n := range depth {
+		remaining += n
+	}
+	return remaining
+}
+
+// Close stops accepting emails and waits until the queued emails are sent,
+// retrying while the store fails. Call Stop, e.g. from another goroutine
+// after a timeout, to stop waiting.
+func (q *Queue) Close() error {
+	q.mutex.Lock()
+	q.closed = true
+	q.wake()
+	q.mutex.Unlock()
+
+	q.wg.Wait()
+	q.cancel()
+	q.progress.close()
+	return nil
+}
+
+// Stop stops accepting emails and waits until the emails being sent are
+// done, ending a Close in progress. Waiting emails are left in the store,
+// to be sent by the next Queue on the same store; with the default
+// in-memory store they are dropped.
+func (q *Queue) Stop() error {
+	q.mutex.Lock()
+	q.stopped = true
+	q.wake()
+	q.mutex.Unlock()
+
+	q.wg.Wait()
+	q.cancel()
+	q.progress.close()
+	return nil
+}
+
+// Shutdown stops accepting emails and sends the queued emails until ctx is
+// done, as Close does. When ctx is done first, the sends in flight are
+// cancelled and the emails still waiting are dequeued; both are stored in
+// Config.DeadLetter if it is set and otherwise left in the store, and
+// Shutdown returns the context's error. The report lists the emails sent,
+// dead-lettered and abandoned from the call until Shutdown returned, when
+// every worker has exited.
+//
+// The waiting emails are dequeued from the store, so with a store shared
+// by several processes, Shutdown also takes the emails the other
+// processes enqueued. Use Stop to leave them for the other processes.
+func (q *Queue) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	q.mutex.Lock()
+	q.closed = true
+	if q.report == nil {
+		q.report = &ShutdownReport{}
+	}
+	q.wake()
+	q.mutex.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		q.mutex.Lock()
+		q.stopped = true
+		q.wake()
+		q.mutex.Unlock()
+
+		q.cancel()
+		<-drained
+		q.abandonWaiting(err)
+	}
+	q.cancel()
+	q.progress.close()
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	report := *q.report
+	return report, err
+}
+
+// abandonWaiting dequeues the emails left in the store after the Shutdown
+// context is done and parks them. cause is the context's error.
+func (q *Queue) abandonWaiting(cause error) {
+	var items []*QueueItem
+	for r := 0; r < priorityClasses; r++ {
+		for {
+			item, err := q.store.Dequeue(context.Background(), priorityOfRank(r), q.visibility)
+			if err != nil {
+				q.logf("Poodle Queue: dequeue failed during shutdown, the waiting emails are not listed: %s", err.Error())
+				break
+			}
+			if item == nil {
+				break
+			}
+			items = append(items, item)
+		}
+	}
+
+	// The listed emails are leased, so the store counts only those
+	// waiting to be retried
+	unlisted := 0
+	if depth, err := q.store.Len(context.Background()); err == nil {
+		for _, n := range depth {
+			unlisted += n
+		}
+	}
+
+	for _, item := range items {
+		persisted := q.park(item, NewDeadLetterError(item.Attempts, nil, cause), item.Attempts)
+		q.mutex.Lock()
+		q.account(item, cause, persisted)
+		q.mutex.Unlock()
+	}
+
+	// Release the leases only now, so that the emails are not dequeued
+	// again above
+	q.mutex.Lock()
+	q.report.Unlisted += unlisted
+	parked := q.parked
+	q.parked = nil
+	q.mutex.Unlock()
+	for _, id := range parked {
+		if nackErr := q.store.Nack(context.Background(), id, 0); nackErr != nil {
+			q.logf("Poodle Queue: nack failed, the email is sent again after the visibility timeout: %s", nackErr.Error())
+		}
+	}
+}
+
+// park stores an email that Shutdown did not send in Config.DeadLetter and
+// acknowledges it. Without a sink, or if the sink fails, the email is kept
+// leased until abandonWaiting ends the lease, so that the next Queue on the
+// store sends it; this counts as an attempt. It reports whether the email
+// was stored.
+func (q *Queue) park(item *QueueItem, err *DeadLetterError, attempts int) bool {
+	if q.deadLetter(item.Email, err, attempts) {
+		if ackErr := q.store.Ack(context.Background(), item.LeaseID()); ackErr != nil {
+			q.logf("Poodle Queue: ack failed, the email may be sent again: %s", ackErr.Error())
+		}
+		return true
+	}
+
+	q.mutex.Lock()
+	q.parked = append(q.parked, item.LeaseID())
+	q.mutex.Unlock()
+	return false
+}
+
+// account adds an email to the report of a Shutdown in progress: as
+// delivered if err is nil, and otherwise as persisted or abandoned. It
+// must be called with the mutex held.
+func (q *Queue) account(item *QueueItem, err error, persisted bool) {
+	if q.report == nil {
+		return
+	}
+	entry := ShutdownItem{ID: item.ID, Index: item.Index, Priority: item.Priority, Fingerprint: item.Email.Fingerprint(), Err: err}
+	switch {
+	case err == nil:
+		q.report.Delivered = append(q.report.Delivered, entry)
+	case persisted:
+		q.report.Persisted = append(q.report.Persisted, entry)
+	default:
+		q.report.Abandoned = append(q.report.Abandoned, entry)
+	}
+}
+
+// wake tells the workers that there may be work, or that they should exit.
+// It must be called with the mutex held.
+func (q *Queue) wake() {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// work sends queued emails until the queue is stopped, or closed and empty
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	failures := 0
+	for {
+		item, empty, notify, err := q.dequeue()
+		if err != nil {
+			failures++
+			if q.exiting(false, notify) {
+				return
+			}
+			delay := q.delay(failures)
+			q.logf("Poodle Queue: store failed, retrying in %s: %s", delay, err.Error())
+			q.waitBackoff(notify, delay)
+			continue
+		}
+		failures = 0
+
+		if item != nil {
+			q.deliver(item)
+			continue
+		}
+		if q.exiting(empty, notify) {
+			return
+		}
+		q.wait(notify, q.pollInterval)
+	}
+}
+
+// dequeue leases the next email to send, choosing its priority with the
+// starvation guard. empty is true if no email is waiting; notify is closed
+// when there may be more work. The mutex is not held while the store is
+// read, so that the workers and Enqueue do not wait on each other's store
+// calls.
+func (q *Queue) dequeue() (item *QueueItem, empty bool, notify chan struct{}, err error) {
+	q.mutex.Lock()
+	notify = q.notify
+	stopped := q.stopped
+	q.mutex.Unlock()
+	if stopped {
+		return nil, true, notify, nil
+	}
+
+	counts, err := q.store.Len(context.Background())
+	if err != nil {
+		return nil, false, notify, err
+	}
+	var depth [priorityClasses]int
+	for priority, n := range counts {
+		depth[priority.rank()] += n
+	}
+
+	q.mutex.Lock()
+	rank, ok := q.guard.pick(depth)
+	q.mutex.Unlock()
+	if !ok {
+		return nil, true, notify, nil
+	}
+
+	// The chosen priority may only hold emails waiting to be retried, so
+	// fall back to the others in priority order
+	ranks := []int{rank}
+	for r := 0; r < priorityClasses; r++ {
+		if r != rank && depth[r] > 0 {
+			ranks = append(ranks, r)
+		}
+	}
+	for _, r := range ranks {
+		item, err = q.store.Dequeue(context.Background(), priorityOfRank(r), q.visibility)
+		if err != nil || item != nil {
+			break
+		}
+	}
+	if item != nil {
+		q.mutex.Lock()
+		q.inFlight++
+		q.mutex.Unlock()
+	}
+	return item, false, notify, err
+}
+
+// exiting reports whether a worker should exit: when the queue is stopped,
+// or closed and empty. A queue is not empty while an email is being
+// enqueued, or if one was enqueued since the worker took notify.
+func (q *Queue) exiting(empty bool, notify chan struct{}) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.stopped {
+		return true
+	}
+	if !q.closed || !empty || q.enqueuing > 0 {
+		return false
+	}
+	select {
+	case <-notify:
+		return false
+	default:
+		return true
+	}
+}
+
+// wait waits for d or until notify is closed. Idle polling uses a real
+// timer rather than the client's clock, so that a fake clock whose Sleep
+// returns at once does not make idle workers spin.
+func (q *Queue) wait(notify chan struct{}, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-notify:
+	case <-timer.C:
+	}
+}
+
+// waitBackoff waits on the client's clock for d or until notify is closed
+func (q *Queue) waitBackoff(notify chan struct{}, d time.Duration) {
+	waitClock(q.client.httpClient.clock, notify, d)
+}
+
+// deliver sends a leased email. Emails that fail with a retryable error
+// are nacked until WithQueueMaxAttempts is reached; all others are
+// acknowledged and their result reported. Emails that failed are passed
+// to Config.DeadLetter, if set, before they are acknowledged. Emails whose
+// send is cancelled by Shutdown are parked.
+func (q *Queue) deliver(item *QueueItem) {
+	response, err := q.client.SendContext(q.ctx, item.Email)
+
+	attempts := item.Attempts + 1
+	history := q.recordAttempt(item.ID, err)
+	cancelled := err != nil && q.ctx.Err() != nil
+	retried := !cancelled && err != nil && IsRetryable(err) && attempts < q.maxAttempts
+	persisted := false
+	if cancelled {
+		persisted = q.park(item, NewDeadLetterError(attempts, history, err), attempts)
+		if q.onResult != nil {
+			q.onResult(SendResult{Index: item.Index, Email: item.Email, Response: response, Err: err})
+		}
+	} else if retried {
+		if nackErr := q.store.Nack(context.Background(), item.LeaseID(), q.delay(attempts)); nackErr != nil {
+			q.logf("Poodle Queue: nack failed, the email is sent again after the visibility timeout: %s", nackErr.Error())
+		}
+	} else {
+		if err != nil {
+			persisted = q.deadLetter(item.Email, NewDeadLetterError(attempts, history, err), attempts)
+		}
+		if ackErr := q.store.Ack(context.Background(), item.LeaseID()); ackErr != nil {
+			q.logf("Poodle Queue: ack failed, the email may be sent again: %s", ackErr.Error())
+		}
+		if q.onResult != nil {
+			q.onResult(SendResult{Index: item.Index, Email: item.Email, Response: response, Err: err})
+		}
+	}
+
+	q.mutex.Lock()
+	q.inFlight--
+	if !retried {
+		delete(q.history, item.ID)
+		q.account(item, err, persisted)
+	}
+	q.mutex.Unlock()
+
+	if !retried {
+		q.progress.record(err)
+	}
+}
+
+// recordAttempt adds a failed attempt to the history of the item and
+// returns the history
+func (q *Queue) recordAttempt(id string, err error) []AttemptError {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if err != nil {
+		q.history[id] = append(q.history[id], newAttemptError(q.client.httpClient.clock.Now(), err))
+	}
+	return append([]AttemptError(nil), q.history[id]...)
+}
+
+// deadLetter passes an email that failed for good to Config.DeadLetter.
+// It reports whether the email was stored.
+func (q *Queue) deadLetter(email *Email, err *DeadLetterError, attempts int) bool {
+	sink := q.client.snapshotConfig().DeadLetter
+	if sink == nil {
+		return false
+	}
+	if storeErr := sink.Store(context.Background(), email, err, attempts); storeErr != nil {
+		q.logf("Poodle Queue: dead letter failed, the email is dropped: %s", storeErr.Error())
+		return false
+	}
+	return true
+}
+
+// ReplayDeadLetters enqueues the emails stored in Config.DeadLetter again,
+// e.g. after an incident is resolved, and removes them from the sink. It
+// returns the number of emails enqueued. The sink must implement
+// DeadLetterReplayer, as FileDeadLetterSink does; otherwise
+// ErrDeadLettersNotReplayable is returned.
+func (q *Queue) ReplayDeadLetters(ctx context.Context) (int, error) {
+	replayer, ok := q.client.snapshotConfig().DeadLetter.(DeadLetterReplayer)
+	if !ok {
+		return 0, ErrDeadLettersNotReplayable
+	}
+
+	replayed := 0
+	err := replayer.ReplayDeadLetters(ctx, func(letter DeadLetter) error {
+		if letter.Email == nil {
+			return nil
+		}
+		email := letter.Email.clone()
+		email.Priority = letter.Priority
+		email.Variables = letter.Variables
+		if err := q.Enqueue(email); err != nil {
+			return err
+		}
+		replayed++
+		return nil
+	})
+	return replayed, err
+}
+
+// delay returns the backoff after the given number of consecutive failures
+func (q *Queue) delay(failures int) time.Duration {
+	delay := q.backoff
+	for i := 1; i < failures && delay < q.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.maxBackoff {
+		delay = q.maxBackoff
+	}
+	return delay
+}
+
+// logf logs at LogLevelError. The level is read under the client's lock,
+// as SetLogLevel may be called while the queue drains.
+func (q *Queue) logf(format string, args ...interface{}) {
+	if q.client.LogLevel() >= LogLevelError {
+		log.Printf(format, args...)
+	}
+}
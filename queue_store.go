@@ -0,0 +1,243 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQueueItemNotFound is returned by a QueueStore when acknowledging an
+// item that is not in the store, e.g. because it was acknowledged already
+var ErrQueueItemNotFound = errors.New("poodle: queue item not found")
+
+// QueueItem is an email held by a QueueStore
+type QueueItem struct {
+	// ID is set by the store's Enqueue
+	ID string
+	// Lease is set by the store's Dequeue and identifies the lease, so
+	// that a worker whose lease expired cannot end the lease of the worker
+	// that dequeued the item next (see LeaseID)
+	Lease    string
+	Index    int
+	Priority Priority
+	Email    *Email
+	// Attempts is the number of times the item was nacked
+	Attempts   int
+	EnqueuedAt time.Time
+}
+
+// LeaseID returns the ID to pass to the store's Ack and Nack: the ID
+// followed by "@" and the Lease, or the ID if Lease is empty
+func (i *QueueItem) LeaseID() string {
+	if i.Lease == "" {
+		return i.ID
+	}
+	return i.ID + "@" + i.Lease
+}
+
+// SplitLeaseID splits an ID passed to Ack or Nack into the item ID and the
+// lease, which is empty if the ID has none
+func SplitLeaseID(id string) (itemID, lease string) {
+	if at := strings.LastIndexByte(id, '@'); at >= 0 {
+		return id[:at], id[at+1:]
+	}
+	return id, ""
+}
+
+// QueueStore holds the emails waiting in a Queue. MemoryQueueStore is used
+// by default; a store backed by a database keeps emails across restarts
+// and can be shared by the queues of several processes. The poodlesql
+// module implements QueueStore with database/sql, and a Redis store can
+// keep a sorted set per priority, scored by the time each item becomes
+// visible.
+//
+// A store must be safe for concurrent use. Dequeue must lease an item
+// atomically, so that an item is never leased to two callers at once,
+// even from different processes. Delivery is at least once: an item whose
+// visibility timeout expires before it is acknowledged is dequeued again.
+// Each Dequeue sets a new Lease, and Ack and Nack are passed the item's
+// LeaseID; they fail with ErrQueueItemNotFound once the item was leased
+// again, so that a worker whose lease expired does not end the lease of
+// the next one. An ID without a lease matches the item whatever its lease.
+// poodletest.RunQueueStoreTests checks these semantics.
+type QueueStore interface {
+	// Enqueue stores the item and sets its ID
+	Enqueue(ctx context.Context, item *QueueItem) error
+	// Dequeue leases the oldest visible item of the priority, hiding it
+	// from other Dequeue calls for the visibility timeout. It returns nil
+	// and no error if no item of the priority is visible.
+	Dequeue(ctx context.Context, priority Priority, visibility time.Duration) (*QueueItem, error)
+	// Ack removes the item. It returns ErrQueueItemNotFound if the item
+	// is not in the store or the lease in id is not its latest lease.
+	Ack(ctx context.Context, id string) error
+	// Nack ends the lease of the item and increments its Attempts. The
+	// item can be dequeued again after delay. It returns
+	// ErrQueueItemNotFound if the item is not in the store or the lease in
+	// id is not its latest lease.
+	Nack(ctx context.Context, id string, delay time.Duration) error
+	// Len returns the number of items per priority that are not leased,
+	// including items nacked with a delay that has not passed yet
+	Len(ctx context.Context) (map[Priority]int, error)
+}
+
+// MemoryQueueStore is the in-memory QueueStore used by default. Its items
+// are lost when the process exits.
+type MemoryQueueStore struct {
+	clock   Clock
+	mutex   sync.Mutex
+	seq     uint64
+	waiting [priorityClasses][]*memoryQueueItem
+	leased  map[string]*memoryQueueItem
+}
+
+// memoryQueueItem is an item held by a MemoryQueueStore
+type memoryQueueItem struct {
+	item      QueueItem
+	seq       uint64
+	leases    uint64
+	visibleAt time.Time
+}
+
+var _ QueueStore = (*MemoryQueueStore)(nil)
+
+// NewMemoryQueueStore creates an empty in-memory queue store
+func NewMemoryQueueStore() *MemoryQueueStore {
+	return newMemoryQueueStore(realClock{})
+}
+
+func newMemoryQueueStore(clock Clock) *MemoryQueueStore {
+	return &MemoryQueueStore{clock: clock, leased: make(map[string]*memoryQueueItem)}
+}
+
+// Enqueue implements QueueStore
+func (s *MemoryQueueStore) Enqueue(ctx context.Context, item *QueueItem) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.seq++
+	item.ID = strconv.FormatUint(s.seq, 10)
+	stored := &memoryQueueItem{item: *item, seq: s.seq}
+	stored.item.Email = item.Email.clone()
+
+	rank := item.Priority.rank()
+	s.waiting[rank] = append(s.waiting[rank], stored)
+	return nil
+}
+
+// Dequeue implements QueueStore
+func (s *MemoryQueueStore) Dequeue(ctx context.Context, priority Priority, visibility time.Duration) (*QueueItem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.clock.Now()
+	s.reclaim(now)
+
+	rank := priority.rank()
+	for i, stored := range s.waiting[rank] {
+		if now.Before(stored.visibleAt) {
+			continue
+		}
+		s.waiting[rank] = append(s.waiting[rank][:i], s.waiting[rank][i+1:]...)
+		stored.visibleAt = now.Add(visibility)
+		stored.leases++
+		s.leased[stored.item.ID] = stored
+
+		item := stored.item
+		item.Lease = strconv.FormatUint(stored.leases, 10)
+		item.Email = stored.item.Email.clone()
+		return &item, nil
+	}
+	return nil, nil
+}
+
+// Ack implements QueueStore
+func (s *MemoryQueueStore) Ack(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err := s.take(id)
+	return err
+}
+
+// Nack implements QueueStore
+func (s *MemoryQueueStore) Nack(ctx context.Context, id string, delay time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, err := s.take(id)
+	if err != nil {
+		return err
+	}
+	stored.item.Attempts++
+	stored.visibleAt = s.clock.Now().Add(delay)
+	s.insert(stored)
+	return nil
+}
+
+// Len implements QueueStore
+func (s *MemoryQueueStore) Len(ctx context.Context) (map[Priority]int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.reclaim(s.clock.Now())
+	depth := make(map[Priority]int, priorityClasses)
+	for rank, waiting := range s.waiting {
+		depth[priorityOfRank(rank)] = len(waiting)
+	}
+	return depth, nil
+}
+
+// reclaim returns leased items whose visibility timeout has expired to the
+// waiting items
+func (s *MemoryQueueStore) reclaim(now time.Time) {
+	for id, stored := range s.leased {
+		if !now.Before(stored.visibleAt) {
+			delete(s.leased, id)
+			s.insert(stored)
+		}
+	}
+}
+
+// insert adds an item to the waiting items in enqueue order
+func (s *MemoryQueueStore) insert(stored *memoryQueueItem) {
+	rank := stored.item.Priority.rank()
+	waiting := s.waiting[rank]
+	i := sort.Search(len(waiting), func(i int) bool { return waiting[i].seq > stored.seq })
+	waiting = append(waiting, nil)
+	copy(waiting[i+1:], waiting[i:])
+	waiting[i] = stored
+	s.waiting[rank] = waiting
+}
+
+// take removes an item, leased or waiting, from the store. The lease in
+// id, if any, must be the item's latest lease.
+func (s *MemoryQueueStore) take(id string) (*memoryQueueItem, error) {
+	id, lease := SplitLeaseID(id)
+	current := func(stored *memoryQueueItem) bool {
+		return lease == "" || lease == strconv.FormatUint(stored.leases, 10)
+	}
+
+	if stored, ok := s.leased[id]; ok {
+		if !current(stored) {
+			return nil, ErrQueueItemNotFound
+		}
+		delete(s.leased, id)
+		return stored, nil
+	}
+	for rank, waiting := range s.waiting {
+		for i, stored := range waiting {
+			if stored.item.ID == id {
+				if !current(stored) {
+					return nil, ErrQueueItemNotFound
+				}
+				s.waiting[rank] = append(waiting[:i], waiting[i+1:]...)
+				return stored, nil
+			}
+		}
+	}
+	return nil, ErrQueueItemNotFound
+}
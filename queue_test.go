@@ -0,0 +1,568 @@
+package poodle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedTransport records the subjects of sent emails. The first request
+// blocks until release is closed.
+type gatedTransport struct {
+	mutex   sync.Mutex
+	sent    []string
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newGatedTransport() *gatedTransport {
+	return &gatedTransport{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (tr *gatedTransport) Do(req *http.Request) (*http.Response, error) {
+	tr.once.Do(func() {
+		close(tr.started)
+		<-tr.release
+	})
+
+	body, _ := io.ReadAll(req.Body)
+	var email Email
+	json.Unmarshal(body, &email)
+
+	tr.mutex.Lock()
+	tr.sent = append(tr.sent, email.Subject)
+	tr.mutex.Unlock()
+	return acceptedResponse(), nil
+}
+
+func priorityEmail(subject string, priority Priority) *Email {
+	email := NewTextEmail("from@example.com", "to@example.com", subject, "Hello")
+	email.Priority = priority
+	return email
+}
+
+func TestStarvationGuard(t *testing.T) {
+	guard := starvationGuard{limit: 2}
+	depth := [priorityClasses]int{4, 1, 2}
+
+	var order []int
+	for {
+		rank, ok := guard.pick(depth)
+		if !ok {
+			break
+		}
+		depth[rank]--
+		order = append(order, rank)
+	}
+
+	want := []int{0, 0, 1, 2, 0, 0, 2}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected dispatch order %v, got %v", want, order)
+	}
+}
+
+func TestQueuePriorityOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{"starvation guard", 2, []string{"warmup", "H1", "H2", "N1", "L1", "H3", "H4", "L2"}},
+		{"strict priority", 0, []string{"warmup", "H1", "H2", "H3", "H4", "N1", "L1", "L2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newGatedTransport()
+			client := NewClient("test_api_key")
+			client.httpClient.httpClient = tr
+
+			var failures []error
+			queue := NewQueue(client, WithQueueWorkers(1), WithStarvationLimit(tt.limit), WithResultHandler(func(result SendResult) {
+				if result.Err != nil {
+					failures = append(failures, result.Err)
+				}
+			}))
+
+			// Hold the only worker so that the rest queues up
+			queue.Enqueue(priorityEmail("warmup", PriorityNormal))
+			<-tr.started
+
+			for _, email := range []*Email{
+				priorityEmail("L1", PriorityLow),
+				priorityEmail("L2", PriorityLow),
+				priorityEmail("N1", PriorityNormal),
+				priorityEmail("H1", PriorityHigh),
+				priorityEmail("H2", PriorityHigh),
+				priorityEmail("H3", PriorityHigh),
+				priorityEmail("H4", PriorityHigh),
+			} {
+				if err := queue.Enqueue(email); err != nil {
+					t.Fatalf("Failed to enqueue: %v", err)
+				}
+			}
+
+			stats := queue.Stats()
+			if stats.Depth[PriorityHigh] != 4 || stats.Depth[PriorityNormal] != 1 || stats.Depth[PriorityLow] != 2 || stats.InFlight != 1 {
+				t.Errorf("Unexpected queue stats: %+v", stats)
+			}
+
+			close(tr.release)
+			queue.Close()
+
+			if !reflect.DeepEqual(tr.sent, tt.want) {
+				t.Errorf("Expected dispatch order %v, got %v", tt.want, tr.sent)
+			}
+			if len(failures) != 0 {
+				t.Errorf("Expected no failures, got %v", failures)
+			}
+		})
+	}
+}
+
+func TestQueueClosed(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	queue := NewQueue(client)
+	queue.Close()
+
+	if err := queue.Enqueue(priorityEmail("late", PriorityHigh)); err != ErrQueueClosed {
+		t.Errorf("Expected ErrQueueClosed, got %v", err)
+	}
+}
+
+func TestSendAllPriorityOrder(t *testing.T) {
+	tr := newGatedTransport()
+	close(tr.release)
+
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = tr
+
+	emails := []*Email{
+		priorityEmail("low", PriorityLow),
+		priorityEmail("normal", PriorityNormal),
+		priorityEmail("high", PriorityHigh),
+	}
+	results, err := client.SendAll(context.Background(), emails, WithConcurrency(1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if want := []string{"high", "normal", "low"}; !reflect.DeepEqual(tr.sent, want) {
+		t.Errorf("Expected dispatch order %v, got %v", want, tr.sent)
+	}
+	for i, result := range results {
+		if result.Index != i || result.Email != emails[i] {
+			t.Errorf("Expected results in batch order, got %+v at %d", result, i)
+		}
+	}
+}
+
+// flakyQueueStore fails the first calls to Len
+type flakyQueueStore struct {
+	*MemoryQueueStore
+	mutex    sync.Mutex
+	failures int
+}
+
+func (s *flakyQueueStore) Len(ctx context.Context) (map[Priority]int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("connection refused")
+	}
+	return s.MemoryQueueStore.Len(ctx)
+}
+
+// slowQueueStore blocks every Len until release is closed, as a database
+// that is slow to answer does
+type slowQueueStore struct {
+	*MemoryQueueStore
+	reading chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *slowQueueStore) Len(ctx context.Context) (map[Priority]int, error) {
+	s.once.Do(func() { close(s.reading) })
+	<-s.release
+	return s.MemoryQueueStore.Len(ctx)
+}
+
+func TestQueueEnqueueDoesNotWaitForWorkers(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	store := &slowQueueStore{MemoryQueueStore: NewMemoryQueueStore(), reading: make(chan struct{}), release: make(chan struct{})}
+	var sent int32
+	queue := NewQueue(client, WithQueueStore(store), WithQueueWorkers(2), WithResultHandler(func(SendResult) {
+		atomic.AddInt32(&sent, 1)
+	}))
+	<-store.reading
+
+	// A worker is waiting on the store, which must not hold up Enqueue
+	enqueued := make(chan error, 1)
+	go func() { enqueued <- queue.Enqueue(priorityEmail("while reading", PriorityNormal)) }()
+	select {
+	case err := <-enqueued:
+		if err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Enqueue to return while a worker reads the store")
+	}
+
+	close(store.release)
+	queue.Close()
+	if got := atomic.LoadInt32(&sent); got != 1 {
+		t.Errorf("Expected the email to be sent before Close returned, got %d", got)
+	}
+}
+
+func TestQueueRejectsInvalidPriority(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	store := NewMemoryQueueStore()
+	queue := NewQueue(client, WithQueueStore(store))
+	defer queue.Close()
+
+	err := queue.Enqueue(priorityEmail("unknown", Priority(7)))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Errors["priority"]) != 1 {
+		t.Fatalf("Expected a ValidationError for the priority, got %v", err)
+	}
+	if depth, _ := store.Len(context.Background()); depth[PriorityNormal] != 0 {
+		t.Errorf("Expected the email not to be stored, got %v", depth)
+	}
+}
+
+func TestQueueBacksOffOnStoreErrors(t *testing.T) {
+	output := captureLog(t)
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.LogLevel = LogLevelError
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})
+
+	store := &flakyQueueStore{MemoryQueueStore: NewMemoryQueueStore(), failures: 3}
+	var results []SendResult
+	queue := NewQueue(client,
+		WithQueueStore(store),
+		WithQueueWorkers(1),
+		WithQueueBackoff(time.Millisecond, 5*time.Millisecond),
+		WithResultHandler(func(result SendResult) {
+			results = append(results, result)
+		}))
+	if err := queue.Enqueue(priorityEmail("after outage", PriorityNormal)); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	queue.Close()
+
+	if len(results) != 1 || results[0].Err != nil {
+		t.Errorf("Expected the email to be sent once the store recovered, got %+v", results)
+	}
+	if !strings.Contains(output.String(), "Poodle Queue: store failed, retrying in") {
+		t.Errorf("Expected store errors to be logged, got %q", output.String())
+	}
+}
+
+func TestQueueRetriesWithNack(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 0
+	client := NewClientWithConfig(config)
+
+	var mutex sync.Mutex
+	requests := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests++
+		if requests < 3 {
+			return jsonResponse(http.StatusServiceUnavailable, `{"message": "Unavailable"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	var results []SendResult
+	queue := NewQueue(client,
+		WithQueueMaxAttempts(3),
+		WithQueueBackoff(time.Millisecond, time.Millisecond),
+		WithResultHandler(func(result SendResult) {
+			results = append(results, result)
+		}))
+	if err := queue.Enqueue(priorityEmail("retried", PriorityNormal)); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	queue.Close()
+
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+	if len(results) != 1 || results[0].Err != nil || results[0].Index != 0 {
+		t.Errorf("Expected a single successful result, got %+v", results)
+	}
+}
+
+func TestQueueStopLeavesWaitingEmails(t *testing.T) {
+	tr := newGatedTransport()
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = tr
+
+	store := NewMemoryQueueStore()
+	queue := NewQueue(client, WithQueueStore(store), WithQueueWorkers(1))
+	queue.Enqueue(priorityEmail("in flight", PriorityNormal))
+	<-tr.started
+	queue.Enqueue(priorityEmail("waiting", PriorityNormal))
+
+	stopped := make(chan struct{})
+	go func() {
+		queue.Stop()
+		close(stopped)
+	}()
+	for {
+		queue.mutex.Lock()
+		stopping := queue.stopped
+		queue.mutex.Unlock()
+		if stopping {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(tr.release)
+	<-stopped
+
+	if want := []string{"in flight"}; !reflect.DeepEqual(tr.sent, want) {
+		t.Errorf("Expected only %v to be sent, got %v", want, tr.sent)
+	}
+	if depth, _ := store.Len(context.Background()); depth[PriorityNormal] != 1 {
+		t.Errorf("Expected the waiting email to stay in the store, got %v", depth)
+	}
+	if err := queue.Enqueue(priorityEmail("late", PriorityNormal)); err != ErrQueueClosed {
+		t.Errorf("Expected ErrQueueClosed, got %v", err)
+	}
+}
+
+func TestQueueShutdownDeadline(t *testing.T) {
+	for _, withSink := range []bool{true, false} {
+		name := "without dead letters"
+		if withSink {
+			name = "with dead letters"
+		}
+		t.Run(name, func(t *testing.T) {
+			goroutines := runtime.NumGoroutine()
+
+			config := NewConfig()
+			config.APIKey = "test_api_key"
+			config.MaxRetries = 0
+			var sink *FileDeadLetterSink
+			if withSink {
+				var err error
+				sink, err = NewFileDeadLetterSink(filepath.Join(t.TempDir(), "dead.jsonl"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				config.DeadLetter = sink
+			}
+
+			// The first email is sent at once; the second hangs until its
+			// request is cancelled
+			started := make(chan struct{})
+			requests := 0
+			client := NewClientWithConfig(config, WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+				requests++
+				if requests == 1 {
+					return acceptedResponse(), nil
+				}
+				close(started)
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			})))
+
+			store := NewMemoryQueueStore()
+			queue := NewQueue(client, WithQueueStore(store), WithQueueWorkers(1))
+			emails := []*Email{
+				priorityEmail("sent", PriorityHigh),
+				priorityEmail("hung", PriorityNormal),
+				priorityEmail("waiting", PriorityLow),
+				priorityEmail("also waiting", PriorityLow),
+			}
+			for _, email := range emails {
+				if err := queue.Enqueue(email); err != nil {
+					t.Fatalf("Failed to enqueue: %v", err)
+				}
+			}
+			<-started
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			report, err := queue.Shutdown(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected the deadline to expire, got %v", err)
+			}
+			if err := queue.Enqueue(priorityEmail("late", PriorityHigh)); !errors.Is(err, ErrQueueClosed) {
+				t.Errorf("Expected ErrQueueClosed, got %v", err)
+			}
+
+			// The first email may have been sent before Shutdown was called
+			if len(report.Delivered) > 1 {
+				t.Errorf("Expected at most one delivered email, got %+v", report.Delivered)
+			}
+			undelivered, other := report.Abandoned, report.Persisted
+			if withSink {
+				undelivered, other = report.Persisted, report.Abandoned
+			}
+			if len(undelivered) != 3 || len(other) != 0 || report.Unlisted != 0 {
+				t.Fatalf("Expected 3 undelivered emails, got %+v", report)
+			}
+			for i, item := range undelivered {
+				if want := emails[i+1].Fingerprint(); item.Fingerprint != want || item.Index != i+1 {
+					t.Errorf("Expected email %d with fingerprint %s, got %+v", i+1, want, item)
+				}
+				if i == 0 && item.Err == nil || i > 0 && !errors.Is(item.Err, context.DeadlineExceeded) {
+					t.Errorf("Expected the error of the cancelled send or the deadline, got %v", item.Err)
+				}
+			}
+
+			depth, _ := store.Len(context.Background())
+			left := depth[PriorityNormal] + depth[PriorityLow]
+			if withSink {
+				letters, err := sink.DeadLetters()
+				if err != nil || len(letters) != 3 {
+					t.Errorf("Expected 3 dead letters, got %d and %v", len(letters), err)
+				}
+				if left != 0 {
+					t.Errorf("Expected the dead-lettered emails to leave the store, got %v", depth)
+				}
+			} else if left != 3 {
+				t.Errorf("Expected the abandoned emails to stay in the store, got %v", depth)
+			}
+
+			// The workers, the wait for them and the progress ticker exit
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if n := runtime.NumGoroutine(); n > goroutines {
+				t.Errorf("Expected no leaked goroutines, got %d more", n-goroutines)
+			}
+		})
+	}
+}
+
+func TestQueueShutdownDrains(t *testing.T) {
+	client := NewClient("test_api_key", WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return acceptedResponse(), nil
+	})))
+	queue := NewQueue(client, WithQueueWorkers(1))
+	for _, subject := range []string{"one", "two"} {
+		queue.Enqueue(priorityEmail(subject, PriorityNormal))
+	}
+
+	report, err := queue.Shutdown(context.Background())
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(report.Delivered)+len(report.Persisted)+len(report.Abandoned) > 2 || len(report.Persisted)+len(report.Abandoned) != 0 {
+		t.Errorf("Expected only delivered emails, got %+v", report)
+	}
+}
+
+func TestQueueLogsWhileLogLevelChanges(t *testing.T) {
+	output := captureLog(t)
+	client := NewClient("test_api_key")
+	queue := NewQueue(client, WithQueueWorkers(1))
+	defer queue.Close()
+
+	// Run with -race: the queue logs while shutdown drains, when the log
+	// level may be changed concurrently
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			client.SetLogLevel(LogLevelError)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		queue.logf("Poodle Queue: test %d", i)
+	}
+	<-done
+
+	client.SetLogLevel(LogLevelOff)
+	output.Reset()
+	queue.logf("Poodle Queue: hidden")
+	if output.Len() != 0 {
+		t.Errorf("Expected no logs at LogLevelOff, got %q", output.String())
+	}
+}
+
+func TestQueueRetriesOnClock(t *testing.T) {
+	clock := newTestClock()
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 0
+	client := NewClientWithConfig(config, WithClock(clock))
+
+	var requests int32
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&requests, 1) < 2 {
+			return jsonResponse(http.StatusServiceUnavailable, `{"message": "Unavailable"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	// The retry is an hour away on the test clock. Idle polling uses real
+	// time, so the retry waits until the test advances the clock.
+	queue := NewQueue(client,
+		WithQueueWorkers(1),
+		WithQueueMaxAttempts(2),
+		WithQueueBackoff(time.Hour, time.Hour),
+		WithQueuePollInterval(time.Millisecond))
+	if err := queue.Enqueue(priorityEmail("retried", PriorityNormal)); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Expected the retry to wait for the clock, got %d requests", n)
+	}
+	if sleeps := clock.Sleeps(); len(sleeps) != 0 {
+		t.Errorf("Expected idle polling not to sleep on the clock, got %d sleeps", len(sleeps))
+	}
+
+	clock.Sleep(context.Background(), time.Hour)
+	closed := make(chan struct{})
+	go func() {
+		queue.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the retry once the clock passed the backoff")
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected 2 requests, got %d", n)
+	}
+}
@@ -0,0 +1,254 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitInfo describes a rate-limit event passed to Config.OnRateLimit
+type RateLimitInfo struct {
+	// Limit and Remaining are the ratelimit-limit and ratelimit-remaining
+	// values, or -1 if the response did not include them
+	Limit     int
+	Remaining int
+	// Reset is the time until the rate-limit window resets
+	Reset time.Duration
+	// RetryAfter is the Retry-After delay of a 429 response
+	RetryAfter time.Duration
+	// Skew is how far the server's clock is ahead of the local clock,
+	// estimated from the Date header. Reset and RetryAfter are corrected
+	// for it.
+	Skew time.Duration
+	// Entered is true for the first event of a rate-limited episode and
+	// false while the client is still rate limited
+	Entered bool
+}
+
+// rateLimitEpisode tracks whether the client is currently rate limited,
+// so that OnRateLimit can tell a new episode from an ongoing one
+type rateLimitEpisode struct {
+	mutex   sync.Mutex
+	limited bool
+}
+
+// enter marks the client as rate limited and reports whether it was not
+// already
+func (e *rateLimitEpisode) enter() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	entered := !e.limited
+	e.limited = true
+	return entered
+}
+
+// leave ends the current episode
+func (e *rateLimitEpisode) leave() {
+	e.mutex.Lock()
+	e.limited = false
+	e.mutex.Unlock()
+}
+
+// observeRateLimit updates the rate-limit episode from a response and
+// calls OnRateLimit for 429 responses and when pacing engaged
+func (c *HTTPClient) observeRateLimit(config *Config, resp *http.Response, body []byte, pacingEngaged, paced bool) {
+	limited := resp.StatusCode == http.StatusTooManyRequests
+	if !limited && !pacingEngaged {
+		if !paced {
+			c.rateLimit.leave()
+		}
+		return
+	}
+
+	entered := c.rateLimit.enter()
+	if config.OnRateLimit == nil {
+		return
+	}
+
+	timing := readRateLimitTiming(config, resp.Header, c.clock.Now())
+	info := RateLimitInfo{
+		Limit:      headerInt(resp.Header, "ratelimit-limit"),
+		Remaining:  headerInt(resp.Header, "ratelimit-remaining"),
+		Reset:      timing.reset,
+		RetryAfter: timing.retryAfter,
+		Skew:       timing.skew,
+		Entered:    entered,
+	}
+
+	// The error is parsed again here rather than passed in, since the
+	// status is mapped to an error after the response is observed. A body
+	// that cannot be parsed still yields an error from the headers.
+	var rateLimitErr *RateLimitError
+	if limited && !errors.As(c.parseRateLimitError(config, resp, body), &rateLimitErr) {
+		rateLimitErr = NewRateLimitError("", int(timing.retryAfter/time.Second), nonNegative(info.Limit), nonNegative(info.Remaining), 0)
+		rateLimitErr.clock = c.clock
+	}
+
+	callOnRateLimit(config, info, rateLimitErr)
+}
+
+// callOnRateLimit calls the OnRateLimit callback, recovering and logging a
+// panic so that it does not fail the send
+func callOnRateLimit(config *Config, info RateLimitInfo, err *RateLimitError) {
+	defer func() {
+		if r := recover(); r != nil && config.logs(LogLevelError) {
+			log.Printf("Poodle OnRateLimit: callback panicked: %v", r)
+		}
+	}()
+
+	config.OnRateLimit(info, err)
+}
+
+// nonNegative returns n, or 0 for the -1 that headerInt returns for a
+// missing header
+func nonNegative(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// RetryDelay returns how long to wait before sending again: the
+// Retry-After delay, or else the time until the rate-limit window resets,
+// or DefaultRetryBackoff if the API sent neither
+func (e *RateLimitError) RetryDelay() time.Duration {
+	if e.RetryAfter > 0 {
+		return time.Duration(e.RetryAfter) * time.Second
+	}
+	if e.ResetIn > 0 {
+		return e.ResetIn
+	}
+	return DefaultRetryBackoff
+}
+
+// Wait blocks for RetryDelay or until the context is done, in which case
+// the context's error is returned. It waits on the Clock of the client
+// that returned the error, or with the time package for errors created
+// with NewRateLimitError.
+func (e *RateLimitError) Wait(ctx context.Context) error {
+	if e.clock != nil {
+		return e.clock.Sleep(ctx, e.RetryDelay())
+	}
+	return sleepContext(ctx, e.RetryDelay())
+}
+
+// SendWithWait sends the email and, while the API answers with a
+// RateLimitError, waits for its RetryDelay and sends it again. Any other
+// error, such as a ValidationError or AuthenticationError, is returned
+// immediately.
+//
+// If the next wait would pass the context's deadline, or the context is
+// cancelled while waiting, the last RateLimitError is returned with the
+// total time waited in its context under "waited".
+func (c *Client) SendWithWait(ctx context.Context, email *Email) (*EmailResponse, error) {
+	// The context's deadline is on the wall clock, so the time left is
+	// taken from the time package once and then spent on the client's
+	// clock
+	clock := c.httpClient.clock
+	start := clock.Now()
+	deadline, hasDeadline := ctx.Deadline()
+	budget := time.Until(deadline)
+
+	var waited time.Duration
+	for {
+		response, err := c.SendContext(ctx, email)
+
+		var rateLimitErr *RateLimitError
+		if err == nil || !errors.As(err, &rateLimitErr) {
+			return response, err
+		}
+
+		delay := rateLimitErr.RetryDelay()
+		if hasDeadline && clock.Now().Sub(start)+delay > budget {
+			return nil, withWaited(rateLimitErr, waited)
+		}
+		if clock.Sleep(ctx, delay) != nil {
+			return nil, withWaited(rateLimitErr, waited)
+		}
+		waited += delay
+	}
+}
+
+// withWaited records the time SendWithWait waited in the error's context
+func withWaited(err *RateLimitError, waited time.Duration) *RateLimitError {
+	if err.ContextMap == nil {
+		err.ContextMap = make(map[string]interface{})
+	}
+	err.ContextMap["waited"] = waited
+	return err
+}
+
+// DefaultMaxRateLimitWait caps the waits derived from rate-limit headers
+// unless Config.MaxRateLimitWait says otherwise
+const DefaultMaxRateLimitWait = 15 * time.Minute
+
+// rateLimitTiming holds the waits derived from the rate-limit headers of a
+// response, in local time
+type rateLimitTiming struct {
+	retryAfter time.Duration
+	reset      time.Duration
+	skew       time.Duration
+}
+
+// readRateLimitTiming reads the Retry-After and ratelimit-reset headers.
+// Dates and timestamps are read on the server's clock, estimated from the
+// Date header, so that a skewed local clock does not stretch or shorten
+// the waits. Waits are at least zero and at most the maximum wait.
+func readRateLimitTiming(config *Config, header http.Header, now time.Time) rateLimitTiming {
+	timing := rateLimitTiming{skew: serverSkew(header, now)}
+	serverNow := now.Add(timing.skew)
+
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			timing.retryAfter = time.Duration(seconds) * time.Second
+		} else if date, err := http.ParseTime(value); err == nil {
+			timing.retryAfter = date.Sub(serverNow)
+		}
+	}
+	timing.reset = resetDuration(header, serverNow)
+
+	limit := config.maxRateLimitWait()
+	timing.retryAfter = clampWait(timing.retryAfter, limit)
+	timing.reset = clampWait(timing.reset, limit)
+	return timing
+}
+
+// serverSkew estimates how far the server's clock is ahead of the local
+// clock from the Date header of a response. The header has a resolution
+// of one second, so smaller differences are taken as no skew.
+func serverSkew(header http.Header, now time.Time) time.Duration {
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return 0
+	}
+	skew := date.Sub(now.Truncate(time.Second))
+	if skew > -time.Second && skew < time.Second {
+		return 0
+	}
+	return skew
+}
+
+// clampWait limits a wait to between zero and limit
+func clampWait(wait, limit time.Duration) time.Duration {
+	if wait < 0 {
+		return 0
+	}
+	if wait > limit {
+		return limit
+	}
+	return wait
+}
+
+// maxRateLimitWait returns MaxRateLimitWait or its default
+func (c *Config) maxRateLimitWait() time.Duration {
+	if c.MaxRateLimitWait > 0 {
+		return c.MaxRateLimitWait
+	}
+	return DefaultMaxRateLimitWait
+}
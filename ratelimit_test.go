@@ -0,0 +1,370 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rateLimitedResponse returns a 429 response with rate-limit headers
+func rateLimitedResponse() *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header: http.Header{
+			"Retry-After":         {"30"},
+			"Ratelimit-Limit":     {"100"},
+			"Ratelimit-Remaining": {"0"},
+			"Ratelimit-Reset":     {"30"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"message": "Slow down"}`)),
+	}
+}
+
+func TestOnRateLimitEpisodes(t *testing.T) {
+	type event struct {
+		info RateLimitInfo
+		err  *RateLimitError
+	}
+	var events []event
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.OnRateLimit = func(info RateLimitInfo, err *RateLimitError) {
+		events = append(events, event{info, err})
+	}
+
+	client := NewClientWithConfig(config)
+	statuses := []int{429, 429, 202, 429}
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		status := statuses[0]
+		statuses = statuses[1:]
+		if status == http.StatusTooManyRequests {
+			return rateLimitedResponse(), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	for i := 0; i < 4; i++ {
+		client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %+v", events)
+	}
+	for i, entered := range []bool{true, false, true} {
+		if events[i].info.Entered != entered {
+			t.Errorf("Event %d: expected Entered %t, got %t", i, entered, events[i].info.Entered)
+		}
+	}
+
+	first := events[0]
+	want := RateLimitInfo{Limit: 100, Remaining: 0, Reset: 30 * time.Second, RetryAfter: 30 * time.Second, Entered: true}
+	if first.info != want {
+		t.Errorf("Expected %+v, got %+v", want, first.info)
+	}
+	if first.err == nil || first.err.Message != "Slow down" || first.err.RetryAfter != 30 {
+		t.Errorf("Expected the parsed RateLimitError, got %+v", first.err)
+	}
+}
+
+func TestOnRateLimitPacing(t *testing.T) {
+	var infos []RateLimitInfo
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AdaptivePacing = true
+	config.OnRateLimit = func(info RateLimitInfo, err *RateLimitError) {
+		if err != nil {
+			t.Errorf("Expected no error when pacing engages, got %v", err)
+		}
+		infos = append(infos, info)
+	}
+
+	client := NewClientWithConfig(config, WithClock(newTestClock()))
+	budgets := []string{"50", "5", "4", "100", "5"}
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		response := acceptedResponse()
+		response.Header = http.Header{
+			"Ratelimit-Remaining": {budgets[0]},
+			"Ratelimit-Reset":     {"10"},
+		}
+		budgets = budgets[1:]
+		return response, nil
+	})
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if len(infos) != 2 || !infos[0].Entered || !infos[1].Entered || infos[0].Remaining != 5 {
+		t.Errorf("Expected one event each time pacing engaged, got %+v", infos)
+	}
+}
+
+func TestOnRateLimitPanicRecovered(t *testing.T) {
+	logs := captureLog(t)
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.LogLevel = LogLevelError
+	config.OnRateLimit = func(RateLimitInfo, *RateLimitError) {
+		panic("alerting is down")
+	}
+
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return rateLimitedResponse(), nil
+	})
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected RateLimitError, got %T", err)
+	}
+
+	if !strings.Contains(logs.String(), "alerting is down") {
+		t.Errorf("Expected the panic to be logged, got %q", logs.String())
+	}
+}
+
+func TestSendWithWait(t *testing.T) {
+	clock := newTestClock()
+	client := NewClient("test_api_key", WithClock(clock))
+
+	calls := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls <= 2 {
+			return rateLimitedResponse(), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	response, err := client.SendWithWait(context.Background(), NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello"))
+	if err != nil || !response.Success {
+		t.Fatalf("Expected the email to be sent after waiting, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 requests, got %d", calls)
+	}
+	if sleeps := clock.Sleeps(); len(sleeps) != 2 || sleeps[0] != 30*time.Second || sleeps[1] != 30*time.Second {
+		t.Errorf("Expected two waits of 30s, got %v", sleeps)
+	}
+}
+
+func TestSendWithWaitReturnsOtherErrors(t *testing.T) {
+	clock := newTestClock()
+	client := NewClient("test_api_key", WithClock(clock))
+
+	calls := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Body:       io.NopCloser(strings.NewReader(`{"message": "Invalid API Key"}`)),
+		}, nil
+	})
+
+	_, err := client.SendWithWait(context.Background(), NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello"))
+	if _, ok := err.(*AuthenticationError); !ok {
+		t.Errorf("Expected AuthenticationError, got %T", err)
+	}
+
+	_, err = client.SendWithWait(context.Background(), NewTextEmail("invalid", "to@example.com", "Subject", "Hello"))
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	}
+
+	if calls != 1 || len(clock.Sleeps()) != 0 {
+		t.Errorf("Expected 1 request and no waits, got %d and %v", calls, clock.Sleeps())
+	}
+}
+
+func TestSendWithWaitDeadline(t *testing.T) {
+	clock := newTestClock()
+	client := NewClient("test_api_key", WithClock(clock))
+
+	calls := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return rateLimitedResponse(), nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := client.SendWithWait(ctx, NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello"))
+	rateLimitErr, ok := err.(*RateLimitError)
+	if !ok {
+		t.Fatalf("Expected RateLimitError, got %T", err)
+	}
+	if waited := rateLimitErr.Context()["waited"]; waited != 30*time.Second {
+		t.Errorf("Expected to have waited 30s before the deadline, got %v", waited)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 requests, got %d", calls)
+	}
+}
+
+func TestSendWithWaitCancelled(t *testing.T) {
+	client := NewClient("test_api_key", WithClock(newTestClock()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		cancel()
+		return rateLimitedResponse(), nil
+	})
+
+	_, err := client.SendWithWait(ctx, NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello"))
+	rateLimitErr, ok := err.(*RateLimitError)
+	if !ok {
+		t.Fatalf("Expected RateLimitError, got %T", err)
+	}
+	if waited := rateLimitErr.Context()["waited"]; waited != time.Duration(0) {
+		t.Errorf("Expected no time waited, got %v", waited)
+	}
+}
+
+func TestRateLimitErrorWait(t *testing.T) {
+	err := NewRateLimitError("", 0, 100, 0, 0)
+	if err.RetryDelay() != DefaultRetryBackoff {
+		t.Errorf("Expected %s without Retry-After, got %s", DefaultRetryBackoff, err.RetryDelay())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if waitErr := NewRateLimitError("", 60, 100, 0, 0).Wait(ctx); waitErr != context.Canceled {
+		t.Errorf("Expected Wait to return the context error, got %v", waitErr)
+	}
+
+	// Errors returned by a client wait on its clock
+	clock := newTestClock()
+	client := NewClient("test_api_key", WithClock(clock), WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return rateLimitedResponse(), nil
+	})))
+	_, sendErr := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	var rateLimitErr *RateLimitError
+	if !errors.As(sendErr, &rateLimitErr) {
+		t.Fatalf("Expected RateLimitError, got %T: %v", sendErr, sendErr)
+	}
+	before := len(clock.Sleeps())
+	if waitErr := rateLimitErr.Wait(context.Background()); waitErr != nil {
+		t.Fatalf("Expected Wait to succeed, got %v", waitErr)
+	}
+	if sleeps := clock.Sleeps(); len(sleeps) != before+1 || sleeps[before] != rateLimitErr.RetryDelay() {
+		t.Errorf("Expected Wait to sleep %s on the client's clock, got %v", rateLimitErr.RetryDelay(), sleeps)
+	}
+}
+
+func TestRateLimitClockSkew(t *testing.T) {
+	for _, skew := range []time.Duration{5 * time.Minute, -5 * time.Minute} {
+		for _, retryAfter := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s retry-after=%t", skew, retryAfter), func(t *testing.T) {
+				clock := newTestClock()
+				serverNow := clock.Now().Add(skew)
+
+				var info RateLimitInfo
+				config := NewConfig()
+				config.APIKey = "test_api_key"
+				config.OnRateLimit = func(i RateLimitInfo, err *RateLimitError) { info = i }
+				client := NewClientWithConfig(config, WithClock(clock), WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+					response := jsonResponse(http.StatusTooManyRequests, `{"message": "Slow down"}`)
+					response.Header.Set("Date", serverNow.Format(http.TimeFormat))
+					response.Header.Set("Ratelimit-Reset", strconv.FormatInt(serverNow.Add(30*time.Second).Unix(), 10))
+					if retryAfter {
+						response.Header.Set("Retry-After", serverNow.Add(45*time.Second).Format(http.TimeFormat))
+					}
+					return response, nil
+				})))
+
+				_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+				var rateLimitErr *RateLimitError
+				if !errors.As(err, &rateLimitErr) {
+					t.Fatalf("Expected RateLimitError, got %T", err)
+				}
+
+				expected := 30 * time.Second
+				if retryAfter {
+					expected = 45 * time.Second
+				}
+				if rateLimitErr.Skew != skew || info.Skew != skew {
+					t.Errorf("Expected a skew of %s, got %s and %s", skew, rateLimitErr.Skew, info.Skew)
+				}
+				if rateLimitErr.ResetIn != 30*time.Second || info.Reset != 30*time.Second {
+					t.Errorf("Expected the window to reset in 30s, got %s and %s", rateLimitErr.ResetIn, info.Reset)
+				}
+				if delay := rateLimitErr.RetryDelay(); delay != expected {
+					t.Errorf("Expected a delay of %s, got %s", expected, delay)
+				}
+				if delay, ok := RetryAfter(err); !ok || delay != expected {
+					t.Errorf("Expected RetryAfter to return %s, got %s", expected, delay)
+				}
+			})
+		}
+	}
+}
+
+func TestAdaptivePacingClockSkew(t *testing.T) {
+	clock := newTestClock()
+	serverNow := clock.Now().Add(-5 * time.Minute)
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AdaptivePacing = true
+	client := NewClientWithConfig(config, WithClock(clock), WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		response := acceptedResponse()
+		response.Header = http.Header{
+			"Date":                {serverNow.Format(http.TimeFormat)},
+			"Ratelimit-Remaining": {"2"},
+			"Ratelimit-Reset":     {strconv.FormatInt(serverNow.Add(10*time.Second).Unix(), 10)},
+		}
+		return response, nil
+	})))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	// Without the skew correction the reset would be in the past
+	if sleeps := clock.Sleeps(); len(sleeps) != 1 || sleeps[0] != 5*time.Second {
+		t.Errorf("Expected requests to be paced 5s apart, got %v", sleeps)
+	}
+}
+
+func TestRateLimitWaitBounds(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 500000000, time.UTC)
+	config := NewConfig()
+	config.MaxRateLimitWait = time.Minute
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   rateLimitTiming
+	}{
+		{"capped", http.Header{"Retry-After": {"86400"}, "Ratelimit-Reset": {"3600"}}, rateLimitTiming{retryAfter: time.Minute, reset: time.Minute}},
+		{"negative", http.Header{"Retry-After": {"-5"}, "Ratelimit-Reset": {strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)}}, rateLimitTiming{}},
+		{"past date", http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}}, rateLimitTiming{}},
+		{"subsecond skew", http.Header{"Date": {now.Format(http.TimeFormat)}, "Retry-After": {"10"}}, rateLimitTiming{retryAfter: 10 * time.Second}},
+		{"unparsable date", http.Header{"Date": {"yesterday"}, "Ratelimit-Reset": {"20"}}, rateLimitTiming{reset: 20 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readRateLimitTiming(config, tt.header, now); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	if delay := NewRateLimitError("", 0, 100, 0, 0).RetryDelay(); delay != DefaultRetryBackoff {
+		t.Errorf("Expected %s without Retry-After or reset, got %s", DefaultRetryBackoff, delay)
+	}
+}
@@ -0,0 +1,82 @@
+package poodle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// recipient is an address an email is delivered to, with the name of the
+// email field it came from
+type recipient struct {
+	field   string
+	address string
+}
+
+// recipients returns every address the email is delivered to
+func (e *Email) recipients() []recipient {
+	return []recipient{{field: "to", address: e.toAddress().Email}}
+}
+
+// checkRecipientDomains enforces the configured allow-list and deny-list
+// for every recipient of the email
+func checkRecipientDomains(config *Config, email *Email) error {
+	if len(config.AllowedRecipientDomains) == 0 && len(config.BlockedRecipientDomains) == 0 {
+		return nil
+	}
+
+	errors := make(map[string][]string)
+	for _, r := range email.recipients() {
+		domain := strings.ToLower(r.address[strings.LastIndex(r.address, "@")+1:])
+
+		if rule, ok := matchDomain(config.BlockedRecipientDomains, domain); ok {
+			errors[r.field] = append(errors[r.field], fmt.Sprintf("Recipient %s is blocked by domain rule %s", r.address, rule))
+			continue
+		}
+
+		if len(config.AllowedRecipientDomains) > 0 {
+			if _, ok := matchDomain(config.AllowedRecipientDomains, domain); !ok {
+				errors[r.field] = append(errors[r.field], fmt.Sprintf("Recipient %s is not in the allowed domains %s", r.address, strings.Join(config.AllowedRecipientDomains, ", ")))
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return NewValidationError("Recipient domain not permitted", errors)
+	}
+	return nil
+}
+
+// matchDomain returns the first rule matching the domain. A rule is either
+// an exact domain or a wildcard such as *.example.com, which matches any
+// subdomain of example.com but not example.com itself.
+func matchDomain(rules []string, domain string) (string, bool) {
+	for _, rule := range rules {
+		pattern := strings.ToLower(rule)
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(domain, pattern[1:]) {
+				return rule, true
+			}
+		} else if domain == pattern {
+			return rule, true
+		}
+	}
+	return "", false
+}
+
+// validateDomainRules checks that every rule is an exact domain or a
+// leading wildcard
+func validateDomainRules(field string, rules []string) error {
+	for _, rule := range rules {
+		name := strings.TrimPrefix(rule, "*.")
+		if name == "" || strings.ContainsAny(name, "*@/ ") || !strings.Contains(name, ".") {
+			message := fmt.Sprintf("Domain rule %q must be a domain such as example.com or *.example.com", rule)
+			return &ValidationError{
+				BaseError: BaseError{Message: message},
+				Errors: map[string][]string{
+					field: {message},
+				},
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package poodle
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMatchDomain(t *testing.T) {
+	rules := []string{"example.com", "*.ourcompany-test.com"}
+
+	tests := []struct {
+		domain  string
+		matches bool
+	}{
+		{"example.com", true},
+		{"mail.example.com", false},
+		{"staging.ourcompany-test.com", true},
+		{"a.b.ourcompany-test.com", true},
+		{"ourcompany-test.com", false},
+		{"evilourcompany-test.com", false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := matchDomain(rules, tt.domain); ok != tt.matches {
+			t.Errorf("matchDomain(%s) = %t, want %t", tt.domain, ok, tt.matches)
+		}
+	}
+}
+
+func TestRecipientDomainRules(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AllowedRecipientDomains = []string{"*.ourcompany-test.com", "partner.com"}
+	config.BlockedRecipientDomains = []string{"blocked.ourcompany-test.com"}
+
+	client := NewClientWithConfig(config)
+	requests := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return acceptedResponse(), nil
+	})
+
+	tests := []struct {
+		to      string
+		allowed bool
+		reason  string
+	}{
+		{"qa@staging.ourcompany-test.com", true, ""},
+		{"ops@Partner.com", true, ""},
+		{"customer@gmail.com", false, "not in the allowed domains"},
+		{"trap@blocked.ourcompany-test.com", false, "blocked by domain rule blocked.ourcompany-test.com"},
+	}
+
+	for _, tt := range tests {
+		_, err := client.SendText("from@example.com", tt.to, "Subject", "Hello")
+		if tt.allowed {
+			if err != nil {
+				t.Errorf("Expected %s to be allowed, got: %v", tt.to, err)
+			}
+			continue
+		}
+
+		validationErr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("Expected ValidationError for %s, got %T", tt.to, err)
+			continue
+		}
+		if messages := validationErr.Errors["to"]; len(messages) != 1 || !strings.Contains(messages[0], tt.to) || !strings.Contains(messages[0], tt.reason) {
+			t.Errorf("Expected an error naming %s and the rule, got %v", tt.to, validationErr.Errors)
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected only allowed emails to be sent, got %d requests", requests)
+	}
+}
+
+func TestConfigValidateDomainRules(t *testing.T) {
+	for _, rule := range []string{"", "*", "*.", "example", "user@example.com", "*.*.example.com"} {
+		config := NewConfig()
+		config.APIKey = "test_api_key"
+		config.BlockedRecipientDomains = []string{rule}
+
+		err := config.Validate()
+		validationErr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("Expected ValidationError for rule %q, got %T", rule, err)
+			continue
+		}
+		if _, exists := validationErr.Errors["blocked_recipient_domains"]; !exists {
+			t.Errorf("Expected blocked_recipient_domains error for rule %q, got %v", rule, validationErr.Errors)
+		}
+	}
+}
+
+func TestNewConfigFromEnvDomainRules(t *testing.T) {
+	t.Setenv("POODLE_ALLOWED_DOMAINS", "*.ourcompany-test.com, partner.com,")
+	t.Setenv("POODLE_BLOCKED_DOMAINS", "honeypot.example")
+
+	config := NewConfigFromEnv()
+	if strings.Join(config.AllowedRecipientDomains, "|") != "*.ourcompany-test.com|partner.com" {
+		t.Errorf("Unexpected allowed domains: %v", config.AllowedRecipientDomains)
+	}
+	if strings.Join(config.BlockedRecipientDomains, "|") != "honeypot.example" {
+		t.Errorf("Unexpected blocked domains: %v", config.BlockedRecipientDomains)
+	}
+}
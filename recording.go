@@ -0,0 +1,325 @@
+package poodle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RecordMode controls whether a RecordingTransport records or replays
+type RecordMode int
+
+const (
+	// RecordModeAuto replays when the cassette file exists and records otherwise
+	RecordModeAuto RecordMode = iota
+	// RecordModeRecord always performs real requests and overwrites the cassette
+	RecordModeRecord
+	// RecordModeReplay only serves requests from the cassette
+	RecordModeReplay
+)
+
+// Cassette holds recorded HTTP interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request/response pair
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a sanitized recorded request
+type RecordedRequest struct {
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyDigest string      `json:"body_digest"`
+}
+
+// RecordedResponse is a recorded response
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// RecordingTransport is an HTTPDoer that records real API interactions to a
+// JSON cassette file and replays them later without network access.
+// Recorded requests never contain the Authorization or Cookie header, and
+// recorded responses never contain Set-Cookie. Replayed requests are
+// matched by method, path and a digest of the request body, and each
+// recorded interaction is used at most once.
+type RecordingTransport struct {
+	// HashRecipients replaces the addresses of the to field and of the
+	// Cc, Bcc and Reply-To headers in recorded request bodies with
+	// HashAddress, the token PIIModeHashed uses
+	HashRecipients bool
+	// ScrubHeaders names further headers left out of recorded requests
+	// and responses, such as "X-Request-Id"
+	ScrubHeaders []string
+
+	path  string
+	mode  RecordMode
+	next  HTTPDoer
+	mutex sync.Mutex
+
+	cassette *Cassette
+	used     []bool
+	loadErr  error
+}
+
+// NewRecordingTransport creates a recording transport for the cassette at
+// path. In record mode requests are forwarded to next.
+func NewRecordingTransport(path string, mode RecordMode, next HTTPDoer) *RecordingTransport {
+	if next == nil {
+		next = http.DefaultClient
+	}
+
+	if mode == RecordModeAuto {
+		mode = RecordModeRecord
+		if _, err := os.Stat(path); err == nil {
+			mode = RecordModeReplay
+		}
+	}
+
+	t := &RecordingTransport{
+		path: path,
+		mode: mode,
+		next: next,
+	}
+
+	if mode == RecordModeReplay {
+		t.cassette, t.loadErr = loadCassette(path)
+		if t.cassette != nil {
+			t.used = make([]bool, len(t.cassette.Interactions))
+		}
+	} else {
+		t.cassette = &Cassette{}
+	}
+
+	return t
+}
+
+// Mode returns the resolved mode of the transport
+func (t *RecordingTransport) Mode() RecordMode {
+	return t.mode
+}
+
+// Do records or replays the request depending on the mode
+func (t *RecordingTransport) Do(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.mode == RecordModeReplay {
+		return t.replay(req, body)
+	}
+	return t.record(req, body)
+}
+
+// record performs the real request and appends the interaction to the cassette
+func (t *RecordingTransport) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := t.next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method:     req.Method,
+			Path:       req.URL.Path,
+			Header:     t.scrubHeader(req.Header, "Authorization", "Cookie"),
+			Body:       t.sanitizeBody(body),
+			BodyDigest: bodyDigest(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     t.scrubHeader(resp.Header, "Set-Cookie"),
+			Body:       string(responseBody),
+		},
+	}
+
+	t.mutex.Lock()
+	t.cassette.Interactions = append(t.cassette.Interactions, interaction)
+	err = saveCassette(t.path, t.cassette)
+	t.mutex.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("poodle: failed to write cassette %s: %w", t.path, err)
+	}
+
+	return buildResponse(req, interaction.Response), nil
+}
+
+// replay returns the first unused recorded interaction matching the request
+func (t *RecordingTransport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	if t.loadErr != nil {
+		return nil, fmt.Errorf("poodle: failed to load cassette %s: %w", t.path, t.loadErr)
+	}
+
+	digest := bodyDigest(body)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for i, interaction := range t.cassette.Interactions {
+		recorded := interaction.Request
+		if t.used[i] || recorded.Method != req.Method || recorded.Path != req.URL.Path || recorded.BodyDigest != digest {
+			continue
+		}
+
+		t.used[i] = true
+		return buildResponse(req, interaction.Response), nil
+	}
+
+	return nil, fmt.Errorf("poodle: no recorded interaction in cassette %s matches %s %s (body %s)",
+		t.path, req.Method, req.URL.Path, digest)
+}
+
+// sanitizeBody hashes recipient addresses in a JSON request body if enabled
+func (t *RecordingTransport) sanitizeBody(body []byte) string {
+	if !t.HashRecipients {
+		return string(body)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return string(body)
+	}
+
+	for _, name := range []string{"to", "cc", "bcc", "reply_to"} {
+		if value, ok := fields[name].(string); ok {
+			fields[name] = hashAddressList(value)
+		}
+	}
+	if headers, ok := fields["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			switch http.CanonicalHeaderKey(name) {
+			case "To", "Cc", "Bcc", "Reply-To":
+				if value, ok := value.(string); ok {
+					headers[name] = hashAddressList(value)
+				}
+			}
+		}
+	}
+
+	sanitized, err := json.Marshal(fields)
+	if err != nil {
+		return string(body)
+	}
+	return string(sanitized)
+}
+
+// readRequestBody reads the request body and restores it for reuse
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// scrubHeader returns a copy of the header without the given headers and
+// those in ScrubHeaders
+func (t *RecordingTransport) scrubHeader(header http.Header, names ...string) http.Header {
+	scrubbed := header.Clone()
+	for _, name := range append(names, t.ScrubHeaders...) {
+		scrubbed.Del(name)
+	}
+	return scrubbed
+}
+
+// buildResponse creates an HTTP response from a recorded response
+func buildResponse(req *http.Request, recorded RecordedResponse) *http.Response {
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}
+}
+
+// bodyDigest returns a SHA-256 digest of a request body
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// hashAddressList hashes each address of a comma-separated list, keeping
+// the list as one string. A list that cannot be parsed is hashed whole.
+func hashAddressList(list string) string {
+	addresses, err := mail.ParseAddressList(list)
+	if err != nil {
+		return HashAddress(list)
+	}
+
+	hashed := make([]string, len(addresses))
+	for i, address := range addresses {
+		hashed[i] = HashAddress(address.Address)
+	}
+	return strings.Join(hashed, ", ")
+}
+
+// loadCassette reads a cassette file
+func loadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, err
+	}
+	return &cassette, nil
+}
+
+// saveCassette writes a cassette file, creating its directory if needed
+func saveCassette(path string, cassette *Cassette) error {
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
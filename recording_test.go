@@ -0,0 +1,131 @@
+package poodle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordingTransportRecordThenReplay(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"success": true, "message": "Email queued"}`))
+	}))
+
+	cassette := filepath.Join(t.TempDir(), "fixtures", "send.json")
+	apiKey := "sk_live_recording_secret"
+
+	config := NewConfig()
+	config.APIKey = apiKey
+	config.BaseURL = server.URL
+
+	// Record against the real server
+	recorder := NewClientWithConfig(config, WithCassette(cassette))
+	if _, err := recorder.SendText("from@example.com", "to@example.com", "Hello", "Hi"); err != nil {
+		t.Fatalf("Expected no error while recording, got: %v", err)
+	}
+	server.Close()
+
+	if requests != 1 {
+		t.Fatalf("Expected 1 real request, got %d", requests)
+	}
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("Expected cassette to be written: %v", err)
+	}
+	if strings.Contains(string(data), apiKey) {
+		t.Error("Expected API key to be stripped from the cassette")
+	}
+
+	// Replay without the server
+	replayer := NewClientWithConfig(config, WithCassette(cassette))
+	response, err := replayer.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	if err != nil {
+		t.Fatalf("Expected no error while replaying, got: %v", err)
+	}
+	if !response.Success || response.Message != "Email queued" {
+		t.Errorf("Unexpected replayed response: %+v", response)
+	}
+
+	// A request that was not recorded fails loudly
+	_, err = replayer.SendText("from@example.com", "other@example.com", "Hello", "Hi")
+	if err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("Expected unmatched request error, got: %v", err)
+	}
+
+	// Each interaction is replayed once
+	_, err = replayer.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	if err == nil {
+		t.Error("Expected error when replaying an already used interaction")
+	}
+}
+
+func TestRecordingTransportHashRecipients(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret-session")
+		w.Header().Set("X-Request-Id", "req-secret-id")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"success": true, "message": "Email queued"}`))
+	}))
+	defer server.Close()
+
+	cassette := filepath.Join(t.TempDir(), "send.json")
+	transport := NewRecordingTransport(cassette, RecordModeRecord, http.DefaultClient)
+	transport.HashRecipients = true
+	transport.ScrubHeaders = []string{"X-Request-Id"}
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = server.URL
+	client := NewClientWithConfig(config, WithHTTPDoer(transport))
+
+	email := func() *Email {
+		email := NewTextEmail("from@example.com", "secret.person@example.com", "Hello", "Hi")
+		email.Headers = map[string]string{
+			"Cc":       "Copied Person <copied.person@example.com>, other.copy@example.com",
+			"bcc":      "blind.person@example.com",
+			"Reply-To": "reply.person@example.com",
+		}
+		return email
+	}
+	if _, err := client.Send(email()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("Expected cassette to be written: %v", err)
+	}
+	for _, secret := range []string{"secret.person", "copied.person", "Copied Person", "other.copy", "blind.person", "reply.person", "secret-session", "req-secret-id"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be left out of the cassette", secret)
+		}
+	}
+	if !strings.Contains(string(data), HashAddress("secret.person@example.com")) {
+		t.Error("Expected recipients to be hashed like PIIModeHashed")
+	}
+
+	// Matching uses the digest of the original body, so replay still works
+	replay := NewRecordingTransport(cassette, RecordModeReplay, nil)
+	client = NewClientWithConfig(config, WithHTTPDoer(replay))
+	if _, err := client.Send(email()); err != nil {
+		t.Errorf("Expected replay to match hashed recording, got: %v", err)
+	}
+}
+
+func TestRecordingTransportMissingCassette(t *testing.T) {
+	transport := NewRecordingTransport(filepath.Join(t.TempDir(), "missing.json"), RecordModeReplay, nil)
+	client := NewClient("test_api_key", WithHTTPDoer(transport))
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	if err == nil || !strings.Contains(err.Error(), "failed to load cassette") {
+		t.Errorf("Expected cassette load error, got: %v", err)
+	}
+}
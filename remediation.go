@@ -0,0 +1,232 @@
+package poodle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// hintContext is the context of an error as seen by the hints
+type hintContext struct {
+	code    int
+	context map[string]interface{}
+}
+
+func (c hintContext) string(key string) string {
+	switch value := c.context[key].(type) {
+	case string:
+		return value
+	case fmt.Stringer:
+		return value.String()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func (c hintContext) int(key string) int {
+	switch value := c.context[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	}
+	return 0
+}
+
+// requestSent reports whether the request reached the API before the
+// failure (see NetworkError.RequestSent)
+func (c hintContext) requestSent() bool {
+	sent, _ := c.context["request_sent"].(bool)
+	return sent
+}
+
+// contactSupport asks the reader to contact support, with the request ID
+// of the response if there is one
+func (c hintContext) contactSupport() string {
+	if id := c.string("request_id"); id != "" {
+		return "contact support with request ID " + id
+	}
+	return "contact support"
+}
+
+// hints holds the remediation of each error_type. Every error_type set by
+// the package must have an entry; TestHintsCoverErrorTypes checks this.
+var hints = map[string]func(c hintContext) string{
+	"validation_error": func(c hintContext) string {
+		if c.string("validation_code") == DisposableAddressCode {
+			return "Ask the recipient for a permanent address; disposable addresses are rejected by RejectDisposable."
+		}
+		fieldErrors, _ := c.context["errors"].(map[string][]string)
+		fields := make([]string, 0, len(fieldErrors))
+		for field := range fieldErrors {
+			fields = append(fields, field)
+		}
+		if len(fields) == 0 {
+			return "Correct the email and send it again."
+		}
+		sort.Strings(fields)
+		return fmt.Sprintf("Correct the invalid fields (%s) and send the email again.", strings.Join(fields, ", "))
+	},
+	"authentication_error": func(c hintContext) string {
+		switch AuthReason(c.string("reason")) {
+		case AuthReasonMissingKey:
+			return "Set Config.APIKey or the POODLE_API_KEY environment variable."
+		case AuthReasonInvalidKey:
+			return "Copy the API key from the dashboard again; the key sent was not recognized."
+		case AuthReasonExpiredKey:
+			return "Rotate your API key in the dashboard and update the configuration with the new key."
+		default:
+			return "Check in the dashboard that the API key is correct and active."
+		}
+	},
+	"account_suspended": func(c hintContext) string {
+		switch SuspensionReason(c.string("suspension_reason")) {
+		case SuspensionReasonPaymentFailure:
+			return "Update the payment method in the dashboard; sending resumes once the payment succeeds."
+		case SuspensionReasonAbuseReport:
+			return "The account was suspended after abuse reports and will not be restored; " + c.contactSupport() + "."
+		case SuspensionReasonManualReview:
+			return "The account is under review; wait for the review to finish or " + c.contactSupport() + "."
+		default:
+			return "The account is suspended; " + c.contactSupport() + "."
+		}
+	},
+	"subscription_error": func(c hintContext) string {
+		return "Check the plan and billing in the dashboard; the subscription does not allow this send."
+	},
+	"rate_limit_exceeded": func(c hintContext) string {
+		if wait := c.int("retry_after"); wait > 0 {
+			return fmt.Sprintf("Wait %d seconds before sending again, or send more slowly.", wait)
+		}
+		return "Send more slowly and try again later."
+	},
+	"payload_too_large": func(c hintContext) string {
+		if limit := c.int("limit"); limit > 0 {
+			return fmt.Sprintf("Shrink the email from %d to under %d bytes; Email.SizeReport() shows which parts are largest.", c.int("payload_size"), limit)
+		}
+		return fmt.Sprintf("Shrink the email, which was %d bytes; Email.SizeReport() shows which parts are largest.", c.int("payload_size"))
+	},
+	"network_error": func(c hintContext) string {
+		if c.requestSent() {
+			return "Check whether the email arrived before sending it again; the connection failed after the request was sent."
+		}
+		switch NetworkErrorKind(c.string("kind")) {
+		case NetworkErrorKindDNS:
+			return "Check the DNS configuration; the API host name could not be resolved."
+		case NetworkErrorKindUnreachable:
+			return "Check the network route to the API; the host could not be reached."
+		case NetworkErrorKindRefused:
+			return "Check Config.BaseURL and any proxy; the connection was refused."
+		case NetworkErrorKindTLS:
+			return "Check the system clock, proxy and CA certificates; the API's TLS certificate was not accepted."
+		default:
+			return "Check the network connection and send the email again."
+		}
+	},
+	"connection_timeout": func(c hintContext) string {
+		return fmt.Sprintf("Check the network connection; no connection to the API could be opened within %s.", c.string("timeout"))
+	},
+	"timeout": func(c hintContext) string {
+		if c.requestSent() {
+			return fmt.Sprintf("Check whether the email arrived before sending it again; the API did not answer within %s of receiving the request.", c.string("timeout"))
+		}
+		return fmt.Sprintf("Send the email again later, or raise Config.Timeout; the API did not answer within %s.", c.string("timeout"))
+	},
+	"http_error": func(c hintContext) string {
+		if c.code >= http.StatusInternalServerError {
+			return "The API had a temporary problem; send the email again later, and if it persists " + c.contactSupport() + "."
+		}
+		return fmt.Sprintf("Check the request and Config.BaseURL; the API answered with status %d.", c.code)
+	},
+	"queue_error": func(c hintContext) string {
+		if retryable, _ := c.context["retryable"].(bool); retryable {
+			return "Send the email again later; the API could not queue it for now."
+		}
+		return "The API could not queue the email; " + c.contactSupport() + "."
+	},
+	"duplicate_email": func(c hintContext) string {
+		return "No action is needed; an identical email was sent within Config.DedupeWindow."
+	},
+	"deadline_exceeded": func(c hintContext) string {
+		return fmt.Sprintf("Send the email again later, or raise Config.OverallDeadline; the send stopped after %s and %d attempts.", c.string("deadline"), c.int("attempts"))
+	},
+	"security_policy": func(c hintContext) string {
+		return fmt.Sprintf("Change the configuration that breaks the %s rule of Config.HardenedTransport.", c.string("policy"))
+	},
+	"dns_lookup_warning": func(c hintContext) string {
+		return fmt.Sprintf("Check the recipient domain %s again later; its DNS lookup did not complete.", c.string("domain"))
+	},
+	"no_route_error": func(c hintContext) string {
+		return fmt.Sprintf("Add a route for the From domain %s, or a default route, to the Router.", c.string("domain"))
+	},
+	"response_parse_error": func(c hintContext) string {
+		if c.code >= 200 && c.code < 300 {
+			return "Do not send the email again; the API accepted it but its response could not be read."
+		}
+		return "Send the email again later; the API's response could not be read. If it persists " + c.contactSupport() + "."
+	},
+	"response_read_error": func(c hintContext) string {
+		if c.code >= 200 && c.code < 300 {
+			return "Do not send the email again; the API accepted it but its response could not be read."
+		}
+		return "Check whether the email arrived before sending it again; the API's response could not be read."
+	},
+	"encode_error": func(c hintContext) string {
+		return "Correct the values that cannot be encoded as JSON; sending them again fails the same way."
+	},
+	"dead_letter": func(c hintContext) string {
+		return fmt.Sprintf("Fix the cause of the last of %d failed attempts, then replay the email with Queue.ReplayDeadLetters.", c.int("attempts"))
+	},
+	"unresolved_assets": func(c hintContext) string {
+		return "Add the missing files to InlineOptions.FS, or link the assets with absolute URLs."
+	},
+}
+
+// Hint returns what to do about the error, as a sentence for the people
+// operating the sender, e.g. "Wait 30 seconds before sending again, or
+// send more slowly." Numbers and the request ID of the response are taken
+// from the error's context. It returns an empty string if there is no
+// advice for the error.
+func (e *BaseError) Hint() string {
+	errorType, _ := e.ContextMap["error_type"].(string)
+	if hint, ok := hints[errorType]; ok {
+		return hint(hintContext{code: e.Code, context: e.ContextMap})
+	}
+	return ""
+}
+
+// Remediation returns the Hint of the Poodle error in err's chain, or an
+// empty string if there is none. An email skipped by a cancelled batch
+// is advised to be sent again.
+func Remediation(err error) string {
+	if errors.Is(err, ErrSkipped) {
+		return "Send the email again; its batch was cancelled before it was attempted."
+	}
+	var poodleErr PoodleError
+	if errors.As(err, &poodleErr) {
+		return poodleErr.Hint()
+	}
+	return ""
+}
+
+// recordErrorRequestID adds the X-Request-Id of an error response to the
+// error's context as request_id, for support requests
+func recordErrorRequestID(err error, header http.Header) error {
+	requestID := strings.TrimSpace(header.Get("X-Request-Id"))
+	if requestID == "" {
+		return err
+	}
+
+	if withBase, ok := err.(interface{ base() *BaseError }); ok {
+		base := withBase.base()
+		if base.ContextMap == nil {
+			base.ContextMap = make(map[string]interface{})
+		}
+		base.ContextMap["request_id"] = requestID
+	}
+	return err
+}
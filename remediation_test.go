@@ -0,0 +1,108 @@
+package poodle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHintsCoverErrorTypes(t *testing.T) {
+	// Collect the error types set anywhere in the package. NewTimeoutError
+	// picks its type by phase.
+	pattern := regexp.MustCompile(`"error_type":\s+"([a-z_]+)"`)
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorTypes := map[string]bool{"timeout": true, "connection_timeout": true}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range pattern.FindAllStringSubmatch(string(source), -1) {
+			errorTypes[match[1]] = true
+		}
+	}
+	if len(errorTypes) < 15 {
+		t.Fatalf("Expected to find the error types, got %v", errorTypes)
+	}
+	for errorType := range errorTypes {
+		if _, ok := hints[errorType]; !ok {
+			t.Errorf("Expected a hint for error type %s", errorType)
+		}
+	}
+
+	// Errors without an error_type, such as configuration errors, have no
+	// hint
+	for name, newErr := range formattedErrors {
+		err := newErr()
+		if _, ok := err.Context()["error_type"]; !ok {
+			continue
+		}
+		hint := err.Hint()
+		if hint == "" || !strings.HasSuffix(hint, ".") || hint[:1] != strings.ToUpper(hint[:1]) {
+			t.Errorf("Expected a sentence as the hint of %s, got %q", name, hint)
+		}
+	}
+}
+
+func TestRemediation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"rate limit", NewRateLimitError("Rate limit exceeded", 30, 100, 0, 30), "Wait 30 seconds before sending again, or send more slowly."},
+		{"rate limit without delay", NewRateLimitError("Rate limit exceeded", 0, 100, 0, 0), "Send more slowly and try again later."},
+		{"expired key", NewAuthenticationErrorWithReason("", AuthReasonExpiredKey), "Rotate your API key in the dashboard and update the configuration with the new key."},
+		{"missing key", NewAuthenticationErrorWithReason("", AuthReasonMissingKey), "Set Config.APIKey or the POODLE_API_KEY environment variable."},
+		{"payment", NewAccountSuspendedError("", "billing"), "Update the payment method in the dashboard; sending resumes once the payment succeeds."},
+		{"suspended", NewAccountSuspendedError("", "other"), "The account is suspended; contact support."},
+		{"payload", NewPayloadTooLargeError("", 2048, 1024), "Shrink the email from 2048 to under 1024 bytes; Email.SizeReport() shows which parts are largest."},
+		{"fields", NewValidationError("Invalid", map[string][]string{"to": {"bad"}, "from": {"bad"}}), "Correct the invalid fields (from, to) and send the email again."},
+		{"DNS", newNetworkErrorKind(NetworkErrorKindDNS, "lookup failed", "https://api.example.com"), "Check the DNS configuration; the API host name could not be resolved."},
+		{"timeout", NewTimeoutError(TimeoutPhaseTotal, TimeoutPhaseResponseHeader, 30*time.Second, "https://api.example.com", nil), "Send the email again later, or raise Config.Timeout; the API did not answer within 30s."},
+		{"server error", NewHTTPError(503, "", "https://api.example.com", ""), "The API had a temporary problem; send the email again later, and if it persists contact support."},
+		{"wrapped", fmt.Errorf("welcome email: %w", NewDuplicateEmailError("abc")), "No action is needed; an identical email was sent within Config.DedupeWindow."},
+		{"skipped", ErrSkipped, "Send the email again; its batch was cancelled before it was attempted."},
+		{"other", errors.New("boom"), ""},
+		{"nil", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Remediation(tt.err); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRemediationRequestID(t *testing.T) {
+	client := NewClient("test_api_key", WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		response := jsonResponse(http.StatusForbidden, `{"message":"Account suspended","error":"abuse_report"}`)
+		response.Header.Set("X-Request-Id", "req_123")
+		return response, nil
+	})))
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	var suspendedErr *AccountSuspendedError
+	if !errors.As(err, &suspendedErr) {
+		t.Fatalf("Expected AccountSuspendedError, got %v", err)
+	}
+	if id := suspendedErr.Context()["request_id"]; id != "req_123" {
+		t.Errorf("Expected the request ID in the context, got %v", id)
+	}
+	if want := "The account was suspended after abuse reports and will not be restored; contact support with request ID req_123."; Remediation(err) != want {
+		t.Errorf("Expected %q, got %q", want, Remediation(err))
+	}
+}
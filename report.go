@@ -0,0 +1,208 @@
+package poodle
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Report statuses of a ReportRow
+const (
+	ReportStatusSent    = "sent"
+	ReportStatusFailed  = "failed"
+	ReportStatusSkipped = "skipped"
+)
+
+// reportColumns are the columns of SendReport.WriteCSV, in order
+var reportColumns = []string{"index", "recipient", "subject", "status", "message_id", "error_code", "error", "attempts", "count"}
+
+// ReportRow is the outcome of one email of a SendReport
+type ReportRow struct {
+	// Index is the position of the email in the batch or stream
+	Index     int    `json:"index"`
+	Recipient string `json:"recipient"`
+	Subject   string `json:"subject"`
+	// Status is ReportStatusSent, ReportStatusFailed or
+	// ReportStatusSkipped
+	Status    string `json:"status"`
+	MessageID string `json:"message_id,omitempty"`
+	// ErrorCode is the error_type of the error, as grouped by
+	// MultiError.ByType, e.g. "rate_limit_exceeded", or "skipped"
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Attempts  int    `json:"attempts"`
+}
+
+// ReportSummary totals the rows of a SendReport
+type ReportSummary struct {
+	Total   int `json:"total"`
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	// ByErrorCode counts the failed and skipped emails by ErrorCode
+	ByErrorCode map[string]int `json:"by_error_code"`
+}
+
+// SendReport lists the outcome of every email of a bulk send, e.g. for
+// operations to review after a campaign. Build one from the results of
+// SendAll with NewSendReport, or pass WithReport to SendAll, SendStream or
+// SendIndividually to fill one as the emails complete. A report keeps the
+// recipient, subject and outcome of each email but not the email itself,
+// so it stays small for large batches. The zero value is an empty report,
+// and a SendReport is safe for concurrent use.
+type SendReport struct {
+	mutex sync.Mutex
+	rows  []ReportRow
+}
+
+// NewSendReport returns a report of the results
+func NewSendReport(results []SendResult) *SendReport {
+	report := &SendReport{}
+	for _, result := range results {
+		report.Add(result)
+	}
+	return report
+}
+
+// WithReport adds a row to report for every email of a SendAll,
+// SendStream or SendIndividually batch as it completes
+func WithReport(report *SendReport) BatchOption {
+	return func(o *batchOptions) {
+		o.report = report
+	}
+}
+
+// Add adds the outcome of an email to the report
+func (r *SendReport) Add(result SendResult) {
+	row := ReportRow{
+		Index:     result.Index,
+		Recipient: result.Recipient,
+		Status:    ReportStatusSent,
+		Attempts:  result.Attempts,
+	}
+	if result.Email != nil {
+		if row.Recipient == "" {
+			row.Recipient = result.Email.toAddress().Email
+		}
+		row.Subject = result.Email.Subject
+	}
+	if result.Response != nil {
+		row.MessageID = result.Response.MessageID()
+	}
+	switch {
+	case result.Skipped():
+		row.Status = ReportStatusSkipped
+		row.ErrorCode = ReportStatusSkipped
+		row.Error = result.Err.Error()
+	case result.Err != nil:
+		row.Status = ReportStatusFailed
+		row.ErrorCode = errorTypeOf(result.Err)
+		row.Error = result.Err.Error()
+	}
+
+	r.mutex.Lock()
+	r.rows = append(r.rows, row)
+	r.mutex.Unlock()
+}
+
+// Rows returns the rows of the report ordered by Index
+func (r *SendReport) Rows() []ReportRow {
+	r.mutex.Lock()
+	rows := append([]ReportRow(nil), r.rows...)
+	r.mutex.Unlock()
+
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Index < rows[j].Index })
+	return rows
+}
+
+// Summary returns the totals of the report
+func (r *SendReport) Summary() ReportSummary {
+	return summarizeRows(r.Rows())
+}
+
+func summarizeRows(rows []ReportRow) ReportSummary {
+	summary := ReportSummary{Total: len(rows), ByErrorCode: make(map[string]int)}
+	for _, row := range rows {
+		switch row.Status {
+		case ReportStatusSent:
+			summary.Sent++
+		case ReportStatusFailed:
+			summary.Failed++
+		case ReportStatusSkipped:
+			summary.Skipped++
+		}
+		if row.ErrorCode != "" {
+			summary.ByErrorCode[row.ErrorCode]++
+		}
+	}
+	return summary
+}
+
+// WriteCSV writes the report as CSV: a header row with the columns index,
+// recipient, subject, status, message_id, error_code, error, attempts and
+// count, a row per email ordered by index, and then summary rows, which
+// have "total" as their index and the number of emails in count. The
+// first summary row counts every email, and the others the emails of
+// each status and, for failed and skipped emails, of each error code in
+// alphabetical order. The columns are only ever added to at the end.
+func (r *SendReport) WriteCSV(w io.Writer) error {
+	rows := r.Rows()
+	summary := summarizeRows(rows)
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write(reportColumns)
+	for _, row := range rows {
+		_ = writer.Write([]string{
+			strconv.Itoa(row.Index),
+			row.Recipient,
+			row.Subject,
+			row.Status,
+			row.MessageID,
+			row.ErrorCode,
+			row.Error,
+			strconv.Itoa(row.Attempts),
+			"",
+		})
+	}
+
+	total := func(status, code string, count int) {
+		_ = writer.Write([]string{"total", "", "", status, "", code, "", "", strconv.Itoa(count)})
+	}
+	total("", "", summary.Total)
+	total(ReportStatusSent, "", summary.Sent)
+	total(ReportStatusFailed, "", summary.Failed)
+	total(ReportStatusSkipped, "", summary.Skipped)
+	codes := make([]string, 0, len(summary.ByErrorCode))
+	for code := range summary.ByErrorCode {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		status := ReportStatusFailed
+		if code == ReportStatusSkipped {
+			status = ReportStatusSkipped
+		}
+		total(status, code, summary.ByErrorCode[code])
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the report as a JSON object with the rows ordered by
+// index under "rows" and the ReportSummary under "summary"
+func (r *SendReport) WriteJSON(w io.Writer) error {
+	rows := r.Rows()
+	if rows == nil {
+		rows = []ReportRow{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Rows    []ReportRow   `json:"rows"`
+		Summary ReportSummary `json:"summary"`
+	}{rows, summarizeRows(rows)})
+}
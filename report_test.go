@@ -0,0 +1,135 @@
+package poodle
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reportResults returns the results of a batch with a sent, a failed, a
+// rate-limited and a skipped email, with subjects that need CSV escaping
+func reportResults() []SendResult {
+	sent := &EmailResponse{Success: true, Message: "Email queued", Data: map[string]json.RawMessage{"message_id": []byte(`"msg_1"`)}}
+	return []SendResult{
+		{Index: 0, Email: NewTextEmail("from@example.com", "Jane <jane@example.com>", `Sale: 50% off, "today" only`, "Hi"), Response: sent, Attempts: 1},
+		{Index: 3, Email: NewTextEmail("from@example.com", "skipped@example.com", "Hello", "Hi"), Err: ErrSkipped},
+		{Index: 1, Email: NewTextEmail("from@example.com", "invalid", "Line one\nline two", "Hi"), Err: NewValidationError("Email validation failed", map[string][]string{"to": {"To address is not a valid email"}})},
+		{Index: 2, Recipient: "limited@example.com", Email: NewTextEmail("from@example.com", "limited@example.com", "Hello", "Hi"), Err: NewRateLimitError("Rate limit exceeded", 30, 100, 0, 30), Attempts: 3},
+	}
+}
+
+func TestSendReportGolden(t *testing.T) {
+	report := NewSendReport(reportResults())
+
+	for _, format := range []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"report.csv", report.WriteCSV},
+		{"report.json", report.WriteJSON},
+	} {
+		t.Run(format.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := format.write(&buf); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			golden := filepath.Join("testdata", "report", format.name)
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read %s, run go test -run SendReport -update: %v", golden, err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("Report differs from %s\nwant: %s\ngot:  %s", golden, want, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestSendReportCSVRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewSendReport(reportResults()).WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got %v", err)
+	}
+
+	if strings.Join(records[0], ",") != "index,recipient,subject,status,message_id,error_code,error,attempts,count" {
+		t.Errorf("Unexpected header %v", records[0])
+	}
+	if records[1][2] != `Sale: 50% off, "today" only` || records[2][2] != "Line one\nline two" {
+		t.Errorf("Expected the subjects to survive escaping, got %q, %q", records[1][2], records[2][2])
+	}
+	for i, index := range []string{"0", "1", "2", "3"} {
+		if records[i+1][0] != index {
+			t.Errorf("Expected rows ordered by index, got %s at %d", records[i+1][0], i)
+		}
+	}
+	if total := records[5]; total[0] != "total" || total[8] != "4" {
+		t.Errorf("Expected the total to follow the rows, got %v", total)
+	}
+}
+
+func TestSendReportSummary(t *testing.T) {
+	var empty SendReport
+	if summary := empty.Summary(); summary.Total != 0 || len(summary.ByErrorCode) != 0 {
+		t.Errorf("Expected an empty summary, got %+v", summary)
+	}
+
+	summary := NewSendReport(reportResults()).Summary()
+	if summary.Total != 4 || summary.Sent != 1 || summary.Failed != 2 || summary.Skipped != 1 {
+		t.Errorf("Unexpected totals %+v", summary)
+	}
+	if summary.ByErrorCode["rate_limit_exceeded"] != 1 || summary.ByErrorCode["validation_error"] != 1 || summary.ByErrorCode["skipped"] != 1 {
+		t.Errorf("Unexpected error codes %v", summary.ByErrorCode)
+	}
+}
+
+func TestWithReport(t *testing.T) {
+	client := NewClient("test_api_key", WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		if strings.Contains(string(body), "broken@") {
+			return jsonResponse(http.StatusBadRequest, `{"message":"Bad request"}`), nil
+		}
+		return jsonResponse(http.StatusAccepted, `{"success": true, "message": "Email queued", "message_id": "msg_1"}`), nil
+	})))
+	emails := []*Email{
+		NewTextEmail("from@example.com", "a@example.com", "Subject", "Hello"),
+		NewTextEmail("from@example.com", "broken@example.com", "Subject", "Hello"),
+	}
+
+	var report SendReport
+	if _, err := client.SendAll(context.Background(), emails, WithReport(&report)); err == nil {
+		t.Fatal("Expected the broken email to fail")
+	}
+	rows := report.Rows()
+	if len(rows) != 2 || rows[0].MessageID != "msg_1" || rows[0].Attempts != 1 || rows[1].Status != ReportStatusFailed || rows[1].Recipient != "broken@example.com" {
+		t.Errorf("Unexpected rows %+v", rows)
+	}
+
+	var streamed SendReport
+	in := make(chan *Email, len(emails))
+	for _, email := range emails {
+		in <- email
+	}
+	close(in)
+	for range client.SendStream(context.Background(), in, 2, WithReport(&streamed)) {
+	}
+	if summary := streamed.Summary(); summary.Sent != 1 || summary.Failed != 1 {
+		t.Errorf("Expected SendStream to fill the report, got %+v", summary)
+	}
+}
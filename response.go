@@ -2,6 +2,8 @@ package poodle
 
 import (
 	"encoding/json"
+	"strconv"
+	"time"
 )
 
 // EmailResponse represents the API response after sending an email
@@ -9,6 +11,103 @@ type EmailResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 	Error   string `json:"error,omitempty"`
+
+	// RawBody holds the response body when the API accepted the email but
+	// the body could not be parsed as JSON
+	RawBody string `json:"raw_body,omitempty"`
+
+	// FallbackToText is true if the HTML content was rejected and the
+	// email was sent as text instead (see Config.FallbackToText).
+	// FallbackError is the error of the rejected send.
+	FallbackToText bool  `json:"fallback_to_text,omitempty"`
+	FallbackError  error `json:"-"`
+
+	// Dropped is true if a BestEffort send failed with a retryable error
+	// and the email was dropped instead. DropError is that error.
+	Dropped   bool  `json:"dropped,omitempty"`
+	DropError error `json:"-"`
+
+	// FromCache is true if the email was a duplicate and the response is
+	// that of the original send (see Config.DedupeReturnCached)
+	FromCache bool `json:"from_cache,omitempty"`
+
+	// Data holds the top-level fields of the response that have no field
+	// above, such as fields added to the API after this SDK version. It is
+	// left out of ToJSON; use ToJSONWithData to include it.
+	Data map[string]json.RawMessage `json:"-"`
+}
+
+// emailResponseFields are the JSON fields of EmailResponse, which are not
+// kept in Data
+var emailResponseFields = []string{"success", "message", "error", "raw_body", "fallback_to_text", "dropped", "from_cache"}
+
+// UnmarshalJSON decodes the response, keeping unknown top-level fields in
+// Data
+func (r *EmailResponse) UnmarshalJSON(data []byte) error {
+	type plain EmailResponse
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, name := range emailResponseFields {
+		delete(fields, name)
+	}
+	p.Data = nil
+	if len(fields) > 0 {
+		p.Data = fields
+	}
+
+	*r = EmailResponse(p)
+	return nil
+}
+
+// GetString returns the string value of a field in Data. ok is false if
+// the field is missing or not a string.
+func (r *EmailResponse) GetString(key string) (value string, ok bool) {
+	raw, found := r.Data[key]
+	if !found || json.Unmarshal(raw, &value) != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// GetTime returns the time value of a field in Data, which is either an
+// RFC 3339 string or a number of seconds since the Unix epoch. ok is false
+// if the field is missing or not a time.
+func (r *EmailResponse) GetTime(key string) (value time.Time, ok bool) {
+	raw, found := r.Data[key]
+	if !found {
+		return time.Time{}, false
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return t, err == nil
+	}
+
+	seconds, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	whole := int64(seconds)
+	return time.Unix(whole, int64((seconds-float64(whole))*1e9)).UTC(), true
+}
+
+// MessageID returns the ID the API gave the sent email, from the
+// "message_id" field in Data or else the "id" field. It is empty if the
+// response has neither.
+func (r *EmailResponse) MessageID() string {
+	if id, ok := r.GetString("message_id"); ok {
+		return id
+	}
+	id, _ := r.GetString("id")
+	return id
 }
 
 // NewEmailResponse creates a new EmailResponse
@@ -47,7 +146,34 @@ func (r *EmailResponse) ToJSON() (string, error) {
 	return string(data), nil
 }
 
-// FromJSON creates an EmailResponse from JSON string
+// ToJSONWithData converts the response to JSON string like ToJSON, with
+// the fields of Data at the top level. Fields of the response take
+// precedence over Data fields of the same name.
+func (r *EmailResponse) ToJSONWithData() (string, error) {
+	data, err := json.Marshal(r)
+	if err != nil || len(r.Data) == 0 {
+		return string(data), err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+	for key, value := range r.Data {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FromJSON creates an EmailResponse from JSON string. Unknown fields are
+// kept in Data.
 func FromJSON(jsonStr string) (*EmailResponse, error) {
 	var response EmailResponse
 	err := json.Unmarshal([]byte(jsonStr), &response)
@@ -0,0 +1,77 @@
+package poodle
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailResponseData(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusAccepted, `{"success":true,"message":"Email queued","id":"msg_123","queued_at":"2024-05-01T12:30:00Z","sent_at":1714566600.5,"attempt":2}`), nil
+	})
+
+	response, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.Message != "Email queued" || len(response.Data) != 4 {
+		t.Fatalf("Expected the typed fields and 4 unknown fields, got %+v", response)
+	}
+	if _, ok := response.Data["message"]; ok {
+		t.Error("Expected typed fields to be left out of Data")
+	}
+
+	if id, ok := response.GetString("id"); !ok || id != "msg_123" {
+		t.Errorf("Expected id msg_123, got %q", id)
+	}
+	if _, ok := response.GetString("attempt"); ok {
+		t.Error("Expected GetString to reject a number")
+	}
+	if _, ok := response.GetString("missing"); ok {
+		t.Error("Expected GetString to report a missing field")
+	}
+
+	queuedAt, ok := response.GetTime("queued_at")
+	if !ok || !queuedAt.Equal(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected queued_at %v", queuedAt)
+	}
+	sentAt, ok := response.GetTime("sent_at")
+	if !ok || !sentAt.Equal(time.Date(2024, 5, 1, 12, 30, 0, 500000000, time.UTC)) {
+		t.Errorf("Unexpected sent_at %v", sentAt)
+	}
+	if _, ok := response.GetTime("id"); ok {
+		t.Error("Expected GetTime to reject a string that is not a time")
+	}
+}
+
+func TestEmailResponseJSONWithData(t *testing.T) {
+	response, err := FromJSON(`{"success":true,"message":"Email queued","id":"msg_123","meta":{"region":"eu"}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	plain, err := response.ToJSON()
+	if err != nil || strings.Contains(plain, "msg_123") {
+		t.Errorf("Expected ToJSON to leave out Data, got %s", plain)
+	}
+
+	withData, err := response.ToJSONWithData()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	roundTrip, err := FromJSON(withData)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if roundTrip.Message != "Email queued" || string(roundTrip.Data["id"]) != `"msg_123"` || string(roundTrip.Data["meta"]) != `{"region":"eu"}` {
+		t.Errorf("Expected Data to round-trip, got %+v from %s", roundTrip, withData)
+	}
+
+	response, _ = FromJSON(`{"success":true,"message":"ok"}`)
+	if response.Data != nil {
+		t.Errorf("Expected no Data without unknown fields, got %v", response.Data)
+	}
+}
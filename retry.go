@@ -0,0 +1,81 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Retry configuration values
+const (
+	DefaultRetryBackoff = 500 * time.Millisecond
+	MaxRetryBackoff     = 30 * time.Second
+	MaxRetriesLimit     = 10
+)
+
+// IsRetryable reports whether err is a transient failure that may succeed
+// when the request is repeated: network errors and timeouts that happened
+// before the request was sent, rate limits, 5xx server errors and
+// QueueErrors marked Retryable. Network errors with RequestSent set are not
+// retryable, since the API may have processed the send. Validation,
+// authentication, subscription, suspension, EncodeError and
+// ResponseReadError errors are never retryable.
+func IsRetryable(err error) bool {
+	var networkErr *NetworkError
+	if errors.As(err, &networkErr) {
+		return !networkErr.RequestSent
+	}
+
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return true
+	}
+
+	var queueErr *QueueError
+	if errors.As(err, &queueErr) {
+		return queueErr.Retryable
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode() >= http.StatusInternalServerError
+	}
+
+	return false
+}
+
+// retryDelay returns how long to wait before the next attempt after the
+// given attempt failed with err, and whether another attempt should be made.
+func retryDelay(config *Config, attempt int, err error) (time.Duration, bool) {
+	if attempt > config.MaxRetries || !IsRetryable(err) {
+		return 0, false
+	}
+
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) && !config.WaitOnRateLimit {
+		return 0, false
+	}
+
+	if config.RetryPolicy != nil {
+		return config.RetryPolicy.NextDelay(attempt, err)
+	}
+	return ExponentialBackoff{Base: config.RetryBackoff}.NextDelay(attempt, err)
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
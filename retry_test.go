@@ -0,0 +1,332 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"Network error", NewNetworkError("connection refused", ""), true},
+		{"Timeout", NewConnectionTimeoutError(30, ""), true},
+		{"Rate limit", NewRateLimitError("", 1, 10, 0, 0), true},
+		{"Server error", NewHTTPError(http.StatusServiceUnavailable, "", "", ""), true},
+		{"Client error", NewHTTPError(http.StatusNotFound, "", "", ""), false},
+		{"Transient queue error", NewQueueError("", "queue_unavailable", true, -1), true},
+		{"Queue error", NewQueueError("", "message_too_large", false, -1), false},
+		{"Validation", NewValidationError("invalid", nil), false},
+		{"Authentication", NewAuthenticationError(""), false},
+		{"Subscription", NewSubscriptionError("", "limit_reached"), false},
+		{"Suspended", NewAccountSuspendedError("", ""), false},
+		{"Network error after sending", sentNetworkError(), false},
+		{"Encode error", NewEncodeError(errors.New("json: unsupported value")), false},
+		{"Response read error", NewResponseReadError(http.StatusAccepted, "", io.ErrUnexpectedEOF), false},
+		{"Other", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.retryable {
+				t.Errorf("IsRetryable(%T) = %v, want %v", tt.err, got, tt.retryable)
+			}
+		})
+	}
+}
+
+func sentNetworkError() error {
+	err := NewNetworkError("Request failed: EOF", "")
+	err.markRequestSent()
+	return err
+}
+
+func TestSendDoesNotRetryAfterRequestSent(t *testing.T) {
+	// The server reads the request and hangs up without answering, so it
+	// may have processed the send
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		io.Copy(io.Discard, r.Body)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = server.URL
+	config.MaxRetries = 2
+	config.RetryBackoff = time.Millisecond
+	client := NewClientWithConfig(config)
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	var networkErr *NetworkError
+	if !errors.As(err, &networkErr) || !networkErr.RequestSent {
+		t.Fatalf("Expected a NetworkError with RequestSent, got %T: %v", err, err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("Expected a single attempt, got %d", got)
+	}
+}
+
+// newRetryTestClient creates a client with a test clock whose mock returns
+// the given status codes in order, followed by 202 responses
+func newRetryTestClient(config *Config, statuses ...int) (*Client, *int32, *testClock) {
+	var calls int32
+	clock := newTestClock()
+	client := NewClientWithConfig(config, WithClock(clock))
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		call := int(atomic.AddInt32(&calls, 1))
+		if call <= len(statuses) {
+			return &http.Response{
+				StatusCode: statuses[call-1],
+				Header:     http.Header{"Retry-After": {"3"}},
+				Body:       io.NopCloser(strings.NewReader(`{"message": "failure"}`)),
+			}, nil
+		}
+		return acceptedResponse(), nil
+	})
+	return client, &calls, clock
+}
+
+func TestSendRetriesServerErrors(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 2
+	config.RetryBackoff = 100 * time.Millisecond
+
+	client, calls, clock := newRetryTestClient(config, http.StatusServiceUnavailable, http.StatusBadGateway)
+
+	response, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	if err != nil {
+		t.Fatalf("Expected success after retries, got: %v", err)
+	}
+	if !response.Success {
+		t.Error("Expected successful response")
+	}
+	if *calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", *calls)
+	}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if sleeps := clock.Sleeps(); !reflect.DeepEqual(sleeps, expected) {
+		t.Errorf("Expected backoff waits %v, got %v", expected, sleeps)
+	}
+}
+
+func TestSendStopsAfterMaxRetries(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 1
+	config.RetryBackoff = time.Millisecond
+
+	client, calls, _ := newRetryTestClient(config, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	if _, ok := err.(*HTTPError); !ok {
+		t.Fatalf("Expected HTTPError, got %T", err)
+	}
+	if *calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", *calls)
+	}
+}
+
+func TestSendRetriesTransientQueueErrors(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 2
+	config.RetryBackoff = 100 * time.Millisecond
+
+	clock := newTestClock()
+	calls := 0
+	client := NewClientWithConfig(config, WithClock(clock))
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			resp := jsonResponse(http.StatusUnprocessableEntity, `{"message":"Mail queue unavailable, retry later"}`)
+			resp.Header = http.Header{"Retry-After": {"3"}}
+			return resp, nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected success after a retry, got: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", calls)
+	}
+	if sleeps := clock.Sleeps(); !reflect.DeepEqual(sleeps, []time.Duration{3 * time.Second}) {
+		t.Errorf("Expected to wait for Retry-After, got %v", sleeps)
+	}
+}
+
+func TestSendDoesNotRetryClientErrors(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 3
+	config.RetryBackoff = time.Millisecond
+
+	client, calls, _ := newRetryTestClient(config, http.StatusUnauthorized)
+
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	if _, ok := err.(*AuthenticationError); !ok {
+		t.Fatalf("Expected AuthenticationError, got %T", err)
+	}
+	if *calls != 1 {
+		t.Errorf("Expected 1 attempt, got %d", *calls)
+	}
+}
+
+func TestSendWaitOnRateLimit(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 2
+	config.RetryBackoff = time.Millisecond
+
+	// Without WaitOnRateLimit a 429 is returned immediately
+	client, calls, _ := newRetryTestClient(config, http.StatusTooManyRequests)
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err == nil {
+		t.Fatal("Expected RateLimitError, got nil")
+	}
+	if *calls != 1 {
+		t.Errorf("Expected 1 attempt, got %d", *calls)
+	}
+
+	config.WaitOnRateLimit = true
+	client, calls, clock := newRetryTestClient(config, http.StatusTooManyRequests)
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected success after waiting, got: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", *calls)
+	}
+
+	// The wait follows the Retry-After header rather than the backoff
+	if sleeps := clock.Sleeps(); !reflect.DeepEqual(sleeps, []time.Duration{3 * time.Second}) {
+		t.Errorf("Expected a single 3s wait, got %v", sleeps)
+	}
+}
+
+func TestSendRetryMaxElapsed(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 5
+	config.RetryBackoff = time.Hour
+	config.RetryMaxElapsed = time.Second
+
+	client, calls, clock := newRetryTestClient(config, http.StatusServiceUnavailable)
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if len(clock.Sleeps()) != 0 {
+		t.Error("Expected the retry to be skipped when it would exceed RetryMaxElapsed")
+	}
+	if *calls != 1 {
+		t.Errorf("Expected 1 attempt, got %d", *calls)
+	}
+}
+
+func TestSendMaxRequestsPerSecond(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRequestsPerSecond = 100
+
+	client, _, clock := newRetryTestClient(config)
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	// The first request starts immediately, the next four are spaced 10ms apart
+	expected := []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond}
+	if sleeps := clock.Sleeps(); !reflect.DeepEqual(sleeps, expected) {
+		t.Errorf("Expected paced waits %v, got %v", expected, sleeps)
+	}
+}
+
+func TestSendOverallDeadline(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 10
+	config.RetryBackoff = time.Millisecond
+	config.OverallDeadline = 50 * time.Millisecond
+
+	client := NewClientWithConfig(config)
+	var calls int32
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		// A slow server that answers just before each request would time out
+		select {
+		case <-time.After(20 * time.Millisecond):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(strings.NewReader(`{"message": "overloaded"}`)),
+		}, nil
+	})
+
+	started := time.Now()
+	_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Expected the send to stop at the overall deadline, took %s", elapsed)
+	}
+
+	var deadlineErr *DeadlineExceededError
+	if !errors.As(err, &deadlineErr) {
+		t.Fatalf("Expected DeadlineExceededError, got %T: %v", err, err)
+	}
+	if deadlineErr.Attempts != int(atomic.LoadInt32(&calls)) || deadlineErr.Attempts < 2 {
+		t.Errorf("Expected the attempts to be recorded, got %d for %d calls", deadlineErr.Attempts, calls)
+	}
+	if deadlineErr.LastError == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the last error and context.DeadlineExceeded to be reported, got %v", err)
+	}
+	if deadlineErr.Context()["error_type"] != "deadline_exceeded" {
+		t.Errorf("Expected error_type deadline_exceeded, got %v", deadlineErr.Context())
+	}
+}
+
+func TestSendOverallDeadlineEarlierContext(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.OverallDeadline = time.Minute
+
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := client.SendContext(ctx, NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello"))
+	if err == nil {
+		t.Fatal("Expected the send to fail at the context deadline")
+	}
+	var deadlineErr *DeadlineExceededError
+	if errors.As(err, &deadlineErr) {
+		t.Errorf("Expected the caller's earlier deadline to apply, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package poodle
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Route sends the emails from a domain through a client, e.g. the client
+// of the Poodle account of one brand
+type Route struct {
+	// Domain is the From domain the route applies to: "brand.com" matches
+	// brand.com only, "*.brand.com" matches its subdomains but not
+	// brand.com itself, and "*" matches every domain not matched by
+	// another route. Domains are compared case-insensitively.
+	Domain string
+	// Client sends the emails of the route
+	Client Sender
+	// APIKey, if Client is nil, is the API key of a client created with
+	// the default configuration. Routes with the same key share a client,
+	// which is kept when the routes are replaced.
+	APIKey string
+}
+
+// RouteStats are the send counts of a route
+type RouteStats struct {
+	Domain    string
+	Succeeded int64
+	Failed    int64
+}
+
+// Router is a Sender that sends each email through the client of the route
+// matching its From domain. An exact domain takes precedence over
+// wildcards, a longer wildcard over a shorter one, and "*" is used only if
+// nothing else matches. Routes can be replaced with SetRoutes while emails
+// are being sent.
+type Router struct {
+	mutex   sync.RWMutex
+	table   routeTable
+	clients map[string]*Client
+	stats   map[string]*routeCounters
+}
+
+var _ Sender = (*Router)(nil)
+
+// routeTable is the lookup structure of a set of routes
+type routeTable struct {
+	exact     map[string]Sender
+	wildcards []wildcardRoute // longest suffix first
+	fallback  Sender
+}
+
+type wildcardRoute struct {
+	suffix string // ".brand.com"
+	domain string // "*.brand.com"
+	sender Sender
+}
+
+// routeCounters maintains the counters behind RouteStats
+type routeCounters struct {
+	succeeded atomic.Int64
+	failed    atomic.Int64
+}
+
+// NewRouter returns a router with the routes. It returns a ValidationError
+// if a route is invalid.
+func NewRouter(routes ...Route) (*Router, error) {
+	router := &Router{}
+	if err := router.SetRoutes(routes...); err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+// SetRoutes replaces the routes of the router. Sends in progress complete
+// with the route they started with. If a route is invalid, a
+// ValidationError is returned and the routes are left unchanged.
+func (r *Router) SetRoutes(routes ...Route) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	table := routeTable{exact: make(map[string]Sender)}
+	clients := make(map[string]*Client)
+	seen := make(map[string]bool)
+	var problems []string
+
+	for _, route := range routes {
+		domain := strings.ToLower(strings.TrimSpace(route.Domain))
+		if reason := checkRouteDomain(domain); reason != "" {
+			problems = append(problems, reason)
+			continue
+		}
+		if seen[domain] {
+			problems = append(problems, "Route for "+domain+" is listed more than once")
+			continue
+		}
+		seen[domain] = true
+
+		sender := route.Client
+		if sender == nil {
+			if strings.TrimSpace(route.APIKey) == "" {
+				problems = append(problems, "Route for "+domain+" needs a client or an API key")
+				continue
+			}
+			client, ok := clients[route.APIKey]
+			if !ok {
+				if client, ok = r.clients[route.APIKey]; !ok {
+					client = NewClient(route.APIKey)
+				}
+				clients[route.APIKey] = client
+			}
+			sender = client
+		}
+
+		switch {
+		case domain == "*":
+			table.fallback = sender
+		case strings.HasPrefix(domain, "*."):
+			table.wildcards = append(table.wildcards, wildcardRoute{suffix: domain[1:], domain: domain, sender: sender})
+		default:
+			table.exact[domain] = sender
+		}
+	}
+
+	if len(problems) > 0 {
+		for key, client := range clients {
+			if _, ok := r.clients[key]; !ok {
+				client.Close()
+			}
+		}
+		return NewValidationError("Invalid routes", map[string][]string{
+			"routes": problems,
+		})
+	}
+
+	sort.SliceStable(table.wildcards, func(i, j int) bool {
+		return len(table.wildcards[i].suffix) > len(table.wildcards[j].suffix)
+	})
+
+	// Clients of API keys that are no longer routed are closed
+	for key, client := range r.clients {
+		if _, ok := clients[key]; !ok {
+			client.Close()
+		}
+	}
+
+	stats := make(map[string]*routeCounters, len(seen))
+	for domain := range seen {
+		if counters, ok := r.stats[domain]; ok {
+			stats[domain] = counters
+		} else {
+			stats[domain] = &routeCounters{}
+		}
+	}
+
+	r.table = table
+	r.clients = clients
+	r.stats = stats
+	return nil
+}
+
+// checkRouteDomain returns a reason why the domain is not a valid route
+// domain, or an empty string if it is
+func checkRouteDomain(domain string) string {
+	if domain == "*" {
+		return ""
+	}
+	if !isValidEmail("route@" + strings.TrimPrefix(domain, "*.")) {
+		return "Route domain " + domain + " must be a domain, *.domain or *"
+	}
+	return ""
+}
+
+// Send sends the email through the client of its route
+func (r *Router) Send(email *Email) (*EmailResponse, error) {
+	return r.SendContext(context.Background(), email)
+}
+
+// SendContext sends the email through the client of its route, honoring
+// cancellation and deadlines from the context. It returns a NoRouteError
+// if no route matches the From domain.
+func (r *Router) SendContext(ctx context.Context, email *Email) (*EmailResponse, error) {
+	sender, counters, err := r.route(email)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := sender.SendContext(ctx, email)
+	if err != nil {
+		counters.failed.Add(1)
+	} else {
+		counters.succeeded.Add(1)
+	}
+	return response, err
+}
+
+// Route returns the client the email would be sent through, or a
+// NoRouteError if no route matches its From domain
+func (r *Router) Route(email *Email) (Sender, error) {
+	sender, _, err := r.route(email)
+	return sender, err
+}
+
+// route finds the route of the email's From domain
+func (r *Router) route(email *Email) (Sender, *routeCounters, error) {
+	if email == nil {
+		return nil, nil, newNilEmailError()
+	}
+	from := email.fromAddress().Email
+	domain := ""
+	if at := strings.LastIndexByte(from, '@'); at >= 0 {
+		domain = strings.ToLower(strings.TrimSpace(from[at+1:]))
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if domain != "" {
+		if sender, ok := r.table.exact[domain]; ok {
+			return sender, r.stats[domain], nil
+		}
+		for _, route := range r.table.wildcards {
+			if strings.HasSuffix(domain, route.suffix) {
+				return route.sender, r.stats[route.domain], nil
+			}
+		}
+	}
+	if r.table.fallback != nil {
+		return r.table.fallback, r.stats["*"], nil
+	}
+	return nil, nil, NewNoRouteError(domain)
+}
+
+// Stats returns the send counts of the current routes, sorted by domain.
+// The counts of a route are kept when the routes are replaced with a set
+// that contains the same domain.
+func (r *Router) Stats() []RouteStats {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stats := make([]RouteStats, 0, len(r.stats))
+	for domain, counters := range r.stats {
+		stats = append(stats, RouteStats{
+			Domain:    domain,
+			Succeeded: counters.succeeded.Load(),
+			Failed:    counters.failed.Load(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Domain < stats[j].Domain
+	})
+	return stats
+}
+
+// Close closes the clients the router created from API keys. Clients
+// passed in routes are left to their owners.
+func (r *Router) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, client := range r.clients {
+		client.Close()
+	}
+	r.clients = nil
+	return nil
+}
@@ -0,0 +1,200 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// namedSender is a Sender that counts its sends and fails them if err is
+// set
+type namedSender struct {
+	name  string
+	err   error
+	sends atomic.Int64
+}
+
+func (s *namedSender) Send(email *Email) (*EmailResponse, error) {
+	return s.SendContext(context.Background(), email)
+}
+
+func (s *namedSender) SendContext(ctx context.Context, email *Email) (*EmailResponse, error) {
+	s.sends.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return NewEmailResponse(true, s.name), nil
+}
+
+func TestRouterPrecedence(t *testing.T) {
+	exact := &namedSender{name: "exact"}
+	wildcard := &namedSender{name: "wildcard"}
+	nested := &namedSender{name: "nested"}
+	fallback := &namedSender{name: "fallback"}
+
+	router, err := NewRouter(
+		Route{Domain: "*", Client: fallback},
+		Route{Domain: "*.brand.com", Client: wildcard},
+		Route{Domain: "*.eu.brand.com", Client: nested},
+		Route{Domain: "Brand.com", Client: exact},
+		Route{Domain: "news.eu.brand.com", Client: exact},
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	tests := []struct {
+		from   string
+		sender string
+	}{
+		{"hello@brand.com", "exact"},
+		{"hello@BRAND.COM", "exact"},
+		{"hello@mail.brand.com", "wildcard"},
+		{"hello@a.b.brand.com", "wildcard"},
+		{"hello@shop.eu.brand.com", "nested"},
+		{"hello@news.eu.brand.com", "exact"},
+		{"hello@otherbrand.com", "fallback"},
+		{"hello@brand.com.evil.com", "fallback"},
+	}
+
+	for _, tt := range tests {
+		response, err := router.Send(NewTextEmail(tt.from, "to@example.com", "Hello", "Hi"))
+		if err != nil || response.Message != tt.sender {
+			t.Errorf("%s: expected the %s route, got %v and %v", tt.from, tt.sender, response, err)
+		}
+	}
+
+	sender, err := router.Route(NewTextEmail("", "to@example.com", "Hello", "Hi").SetText("x"))
+	if err != nil || sender != fallback {
+		t.Errorf("Expected an email without a From domain to use the default route, got %v and %v", sender, err)
+	}
+}
+
+func TestRouterNoRoute(t *testing.T) {
+	router, err := NewRouter(Route{Domain: "brand.com", Client: &namedSender{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email := NewTextEmail("", "to@example.com", "Hello", "Hi")
+	email.FromAddress = &Address{Name: "Other", Email: "hello@Other.com"}
+	_, err = router.Send(email)
+
+	var noRoute *NoRouteError
+	if !errors.As(err, &noRoute) || noRoute.Domain != "other.com" || noRoute.Context()["error_type"] != "no_route_error" {
+		t.Errorf("Expected a NoRouteError for other.com, got %v", err)
+	}
+}
+
+func TestRouterInvalidRoutes(t *testing.T) {
+	sender := &namedSender{name: "brand"}
+	router, err := NewRouter(Route{Domain: "brand.com", Client: sender})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := [][]Route{
+		{{Domain: "", Client: sender}},
+		{{Domain: "brand", Client: sender}},
+		{{Domain: "*brand.com", Client: sender}},
+		{{Domain: "*.*.brand.com", Client: sender}},
+		{{Domain: "other.com"}},
+		{{Domain: "brand.com", Client: sender}, {Domain: "BRAND.com", Client: sender}},
+	}
+
+	for _, routes := range tests {
+		err := router.SetRoutes(routes...)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%v: expected a ValidationError, got %v", routes, err)
+		}
+	}
+
+	if response, err := router.Send(NewTextEmail("hello@brand.com", "to@example.com", "Hello", "Hi")); err != nil || response.Message != "brand" {
+		t.Errorf("Expected the routes to be unchanged after errors, got %v and %v", response, err)
+	}
+}
+
+func TestRouterAPIKeys(t *testing.T) {
+	router, err := NewRouter(
+		Route{Domain: "brand.com", APIKey: "key_one"},
+		Route{Domain: "*.brand.com", APIKey: "key_one"},
+		Route{Domain: "other.com", APIKey: "key_two"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer router.Close()
+
+	first, _ := router.Route(NewTextEmail("a@brand.com", "to@example.com", "Hello", "Hi"))
+	second, _ := router.Route(NewTextEmail("a@mail.brand.com", "to@example.com", "Hello", "Hi"))
+	other, _ := router.Route(NewTextEmail("a@other.com", "to@example.com", "Hello", "Hi"))
+	if first != second || first == other || first.(*Client).GetConfig().APIKey != "key_one" {
+		t.Errorf("Expected routes with the same key to share a client, got %p, %p and %p", first, second, other)
+	}
+
+	// The client is kept across a swap
+	if err := router.SetRoutes(Route{Domain: "*", APIKey: "key_one"}); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := router.Route(NewTextEmail("a@any.com", "to@example.com", "Hello", "Hi")); again != first {
+		t.Errorf("Expected the client of key_one to be reused")
+	}
+}
+
+func TestRouterStats(t *testing.T) {
+	ok := &namedSender{name: "ok"}
+	failing := &namedSender{name: "failing", err: NewNetworkError("down", "")}
+	router, _ := NewRouter(Route{Domain: "ok.com", Client: ok}, Route{Domain: "*.failing.com", Client: failing})
+
+	router.Send(NewTextEmail("a@ok.com", "to@example.com", "Hello", "Hi"))
+	router.Send(NewTextEmail("a@ok.com", "to@example.com", "Hello", "Hi"))
+	router.Send(NewTextEmail("a@x.failing.com", "to@example.com", "Hello", "Hi"))
+
+	expected := []RouteStats{{Domain: "*.failing.com", Failed: 1}, {Domain: "ok.com", Succeeded: 2}}
+	if stats := router.Stats(); !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected %v, got %v", expected, stats)
+	}
+
+	// Counts are kept for domains that stay routed
+	router.SetRoutes(Route{Domain: "ok.com", Client: failing}, Route{Domain: "new.com", Client: ok})
+	expected = []RouteStats{{Domain: "new.com"}, {Domain: "ok.com", Succeeded: 2}}
+	if stats := router.Stats(); !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected %v after the swap, got %v", expected, stats)
+	}
+}
+
+func TestRouterConcurrentSwap(t *testing.T) {
+	a := &namedSender{name: "a"}
+	b := &namedSender{name: "b"}
+	router, _ := NewRouter(Route{Domain: "*", Client: a})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if _, err := router.Send(NewTextEmail("x@brand.com", "to@example.com", "Hello", "Hi")); err != nil {
+					t.Errorf("Expected a route during swaps, got %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		sender := Sender(a)
+		if i%2 == 1 {
+			sender = b
+		}
+		router.SetRoutes(Route{Domain: "*", Client: sender}, Route{Domain: "brand.com", Client: sender})
+	}
+	wg.Wait()
+
+	if total := a.sends.Load() + b.sends.Load(); total != 1600 {
+		t.Errorf("Expected 1600 sends, got %d", total)
+	}
+}
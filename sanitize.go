@@ -0,0 +1,429 @@
+package poodle
+
+import (
+	"html"
+	"strings"
+)
+
+// DefaultSanitizeTags are the elements kept by the default sanitize policy:
+// the structural and formatting elements email clients rely on
+var DefaultSanitizeTags = []string{
+	"a", "abbr", "b", "blockquote", "body", "br", "center", "code", "col",
+	"colgroup", "dd", "div", "dl", "dt", "em", "font", "h1", "h2", "h3",
+	"h4", "h5", "h6", "head", "hr", "html", "i", "img", "li", "meta", "ol",
+	"p", "pre", "s", "small", "span", "strike", "strong", "sub", "sup",
+	"table", "tbody", "td", "tfoot", "th", "thead", "title", "tr", "u", "ul",
+}
+
+// DefaultSanitizeAttributes are the attributes kept by the default sanitize
+// policy, including inline styles
+var DefaultSanitizeAttributes = []string{
+	"align", "alt", "background", "bgcolor", "border", "cellpadding",
+	"cellspacing", "charset", "class", "color", "colspan", "content", "dir",
+	"face", "height", "href", "id", "lang", "name", "rowspan", "size", "src",
+	"style", "target", "title", "valign", "width",
+}
+
+// SanitizePolicy controls which markup Email.SanitizeHTML keeps
+type SanitizePolicy struct {
+	// AllowedTags lists the elements that are kept. Other elements are
+	// removed but their content is kept, except for script, style, iframe,
+	// object and similar elements, which are removed with their content.
+	// Script elements are removed even if listed. Nil uses
+	// DefaultSanitizeTags.
+	AllowedTags []string
+	// AllowedAttributes lists the attributes kept on allowed elements.
+	// Event handler attributes such as onclick, javascript: URLs and
+	// scripted inline styles are removed even if listed. Nil uses
+	// DefaultSanitizeAttributes.
+	AllowedAttributes []string
+	// AllowComments keeps HTML comments, such as Outlook conditional
+	// comments
+	AllowComments bool
+}
+
+// DefaultSanitizePolicy returns the policy used by Config.SanitizeHTML
+func DefaultSanitizePolicy() SanitizePolicy {
+	return SanitizePolicy{
+		AllowedTags:       append([]string(nil), DefaultSanitizeTags...),
+		AllowedAttributes: append([]string(nil), DefaultSanitizeAttributes...),
+	}
+}
+
+// rawTextElements hold text rather than markup, so their content is
+// skipped or copied up to the closing tag without being parsed
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "noembed": true,
+	"noframes": true, "noscript": true, "xmp": true, "title": true,
+	"textarea": true,
+}
+
+// removedElements are removed together with their content unless allowed
+var removedElements = map[string]bool{
+	"object": true, "applet": true,
+}
+
+// urlAttributes hold URLs that are checked for script schemes
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "background": true, "action": true,
+	"formaction": true, "poster": true, "lowsrc": true, "dynsrc": true,
+}
+
+// SanitizeHTML removes scripts, embedded frames and objects, event handler
+// attributes and javascript: URLs from the HTML body, along with any
+// element or attribute the policy does not allow. Markup that is kept is
+// copied unchanged; a tag is only rewritten when attributes are removed
+// from it.
+func (e *Email) SanitizeHTML(policy SanitizePolicy) *Email {
+	e.HTML = sanitizeHTML(e.HTML, policy)
+	return e
+}
+
+// sanitizeHTML returns the sanitized markup
+func sanitizeHTML(s string, policy SanitizePolicy) string {
+	if policy.AllowedTags == nil {
+		policy.AllowedTags = DefaultSanitizeTags
+	}
+	if policy.AllowedAttributes == nil {
+		policy.AllowedAttributes = DefaultSanitizeAttributes
+	}
+	allowedTags := lowerSet(policy.AllowedTags)
+	allowedAttributes := lowerSet(policy.AllowedAttributes)
+	delete(allowedTags, "script")
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	// skipping is the name of a removed element whose content is dropped,
+	// and depth the number of its open elements
+	skipping, depth := "", 0
+
+	for i := 0; i < len(s); {
+		lt := strings.IndexByte(s[i:], '<')
+		if lt < 0 {
+			if skipping == "" {
+				b.WriteString(s[i:])
+			}
+			break
+		}
+		if skipping == "" {
+			b.WriteString(s[i : i+lt])
+		}
+		i += lt
+
+		switch {
+		case strings.HasPrefix(s[i:], "<!--"):
+			next := len(s)
+			if end := strings.Index(s[i+4:], "-->"); end >= 0 {
+				next = i + 4 + end + 3
+				if policy.AllowComments && skipping == "" {
+					b.WriteString(s[i:next])
+				}
+			}
+			i = next
+			continue
+
+		case strings.HasPrefix(s[i:], "<!") || strings.HasPrefix(s[i:], "<?"):
+			next := len(s)
+			if end := strings.IndexByte(s[i:], '>'); end >= 0 {
+				next = i + end + 1
+				if skipping == "" && len(s)-i >= 9 && strings.EqualFold(s[i:i+9], "<!doctype") {
+					b.WriteString(s[i:next])
+				}
+			}
+			i = next
+			continue
+		}
+
+		tag, next, ok := parseTag(s, i)
+		if !ok {
+			// A '<' that does not start a tag is text
+			if skipping == "" {
+				b.WriteByte('<')
+			}
+			i++
+			continue
+		}
+		i = next
+
+		if tag.unterminated {
+			// Browsers drop a tag cut off by the end of the document
+			break
+		}
+
+		if skipping != "" {
+			if tag.name == skipping {
+				if tag.end {
+					depth--
+				} else {
+					depth++
+				}
+				if depth == 0 {
+					skipping = ""
+				}
+			}
+			continue
+		}
+
+		allowed := allowedTags[tag.name]
+
+		if !tag.end && rawTextElements[tag.name] {
+			content, closing, next := rawText(s, i, tag.name)
+			i = next
+			if allowed {
+				b.WriteString(sanitizeTag(tag, allowedAttributes))
+				b.WriteString(content)
+				b.WriteString(closing)
+			}
+			continue
+		}
+
+		if !allowed {
+			if !tag.end && !tag.selfClosing && removedElements[tag.name] {
+				skipping, depth = tag.name, 1
+			}
+			continue
+		}
+
+		b.WriteString(sanitizeTag(tag, allowedAttributes))
+	}
+
+	return b.String()
+}
+
+// rawText returns the content of a raw text element starting at i, its
+// closing tag and the index after the closing tag. An unclosed element
+// extends to the end of the document.
+func rawText(s string, i int, name string) (content, closing string, next int) {
+	lower := strings.ToLower(s[i:])
+	for offset := 0; ; {
+		end := strings.Index(lower[offset:], "</"+name)
+		if end < 0 {
+			return s[i:], "", len(s)
+		}
+		end += offset
+		after := end + 2 + len(name)
+		if after < len(lower) && (isTagSpace(lower[after]) || lower[after] == '/' || lower[after] == '>') {
+			gt := strings.IndexByte(lower[after:], '>')
+			if gt < 0 {
+				return s[i:], "", len(s)
+			}
+			return s[i : i+end], s[i+end : i+after+gt+1], i + after + gt + 1
+		}
+		offset = after
+	}
+}
+
+// sanitizeTag returns the tag without disallowed or unsafe attributes. The
+// original markup is returned if every attribute is kept.
+func sanitizeTag(tag htmlTag, allowedAttributes map[string]bool) string {
+	if tag.end {
+		return tag.raw
+	}
+
+	kept := tag.attrs[:0:0]
+	for _, attr := range tag.attrs {
+		switch {
+		case strings.HasPrefix(attr.name, "on"), !allowedAttributes[attr.name]:
+		case urlAttributes[attr.name] && isUnsafeURL(attr.value):
+		case attr.name == "style" && isUnsafeStyle(attr.value):
+		default:
+			kept = append(kept, attr)
+		}
+	}
+	if len(kept) == len(tag.attrs) {
+		return tag.raw
+	}
+	return tag.rebuild(kept)
+}
+
+// isUnsafeURL reports whether a URL uses a scheme that runs script. The
+// value has entities decoded; whitespace and control characters, which
+// browsers ignore in a scheme, are removed before checking.
+func isUnsafeURL(value string) bool {
+	url := strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value))
+
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 || strings.ContainsAny(url[:colon], "/?#") {
+		return false
+	}
+
+	switch url[:colon] {
+	case "javascript", "vbscript", "livescript":
+		return true
+	case "data":
+		return !strings.HasPrefix(url, "data:image/") || strings.HasPrefix(url, "data:image/svg")
+	}
+	return false
+}
+
+// isUnsafeStyle reports whether an inline style can run script through
+// expressions, bindings or script URLs. Escaped styles are rejected since
+// escapes can hide any of these.
+func isUnsafeStyle(value string) bool {
+	style := strings.ToLower(value)
+	for {
+		start := strings.Index(style, "/*")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(style[start+2:], "*/")
+		if end < 0 {
+			style = style[:start]
+			break
+		}
+		style = style[:start] + style[start+2+end+2:]
+	}
+	style = strings.Join(strings.Fields(style), "")
+
+	for _, pattern := range []string{"\\", "expression(", "javascript:", "vbscript:", "behavior:", "-moz-binding"} {
+		if strings.Contains(style, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerSet returns the lowercased values as a set
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
+
+// htmlTag is a start or end tag parsed from HTML markup
+type htmlTag struct {
+	name    string // lowercased element name
+	rawName string
+	end     bool
+	attrs   []htmlAttr
+	// tail is the markup after the last attribute, including the closing >
+	tail         string
+	selfClosing  bool
+	unterminated bool
+	raw          string
+}
+
+// htmlAttr is an attribute of a tag
+type htmlAttr struct {
+	name     string // lowercased attribute name
+	value    string // value with entities decoded
+	rawValue string
+	quote    byte
+	hasValue bool
+	// raw is the original markup of the attribute, including the
+	// whitespace before it
+	raw string
+}
+
+// rebuild returns the tag markup with only the given attributes, which are
+// copied as written
+func (t htmlTag) rebuild(attrs []htmlAttr) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	if t.end {
+		b.WriteByte('/')
+	}
+	b.WriteString(t.rawName)
+	for _, attr := range attrs {
+		b.WriteString(attr.raw)
+	}
+	b.WriteString(t.tail)
+	return b.String()
+}
+
+// parseTag parses the tag starting at s[start], which is '<', following the
+// HTML tokenizer rules for tag names and attributes. ok is false if the '<'
+// does not start a tag.
+func parseTag(s string, start int) (tag htmlTag, next int, ok bool) {
+	i := start + 1
+	if i < len(s) && s[i] == '/' {
+		tag.end = true
+		i++
+	}
+	if i >= len(s) || !isASCIILetter(s[i]) {
+		return tag, start, false
+	}
+
+	nameStart := i
+	for i < len(s) && !isTagSpace(s[i]) && s[i] != '/' && s[i] != '>' {
+		i++
+	}
+	tag.rawName = s[nameStart:i]
+	tag.name = strings.ToLower(tag.rawName)
+
+	for i < len(s) {
+		attrStart := i
+		for i < len(s) && (isTagSpace(s[i]) || s[i] == '/') {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			tag.selfClosing = i > attrStart && s[i-1] == '/'
+			tag.tail = s[attrStart : i+1]
+			tag.raw = s[start : i+1]
+			return tag, i + 1, true
+		}
+
+		// The first character of a name may be '='
+		attrNameStart := i
+		i++
+		for i < len(s) && !isTagSpace(s[i]) && s[i] != '/' && s[i] != '>' && s[i] != '=' {
+			i++
+		}
+		attr := htmlAttr{name: strings.ToLower(s[attrNameStart:i])}
+
+		j := i
+		for j < len(s) && isTagSpace(s[j]) {
+			j++
+		}
+		if j < len(s) && s[j] == '=' {
+			j++
+			for j < len(s) && isTagSpace(s[j]) {
+				j++
+			}
+			if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+				attr.quote = s[j]
+				end := strings.IndexByte(s[j+1:], attr.quote)
+				if end < 0 {
+					break
+				}
+				attr.rawValue = s[j+1 : j+1+end]
+				i = j + 1 + end + 1
+			} else {
+				valueStart := j
+				for j < len(s) && !isTagSpace(s[j]) && s[j] != '>' {
+					j++
+				}
+				attr.rawValue = s[valueStart:j]
+				i = j
+			}
+			attr.hasValue = true
+			attr.value = html.UnescapeString(attr.rawValue)
+		}
+
+		attr.raw = s[attrStart:i]
+		tag.attrs = append(tag.attrs, attr)
+	}
+
+	tag.unterminated = true
+	tag.raw = s[start:]
+	return tag, len(s), true
+}
+
+func isTagSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,118 @@
+package poodle
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTMLPreservesValidMarkup(t *testing.T) {
+	markup := `<!DOCTYPE html><html><head><meta charset="utf-8"><title>Hi &amp; welcome</title></head>` +
+		`<body style="margin:0"><table width="100%" cellpadding=0><tr><td align='center' style="color: #333; font-family: Arial, sans-serif">` +
+		`<a href="https://example.com/?a=1&amp;b=2" target=_blank>Link</a><br/><img src="cid:logo" alt="Logo" />` +
+		`3 < 4 &copy; 2024</td></tr></table></body></html>`
+
+	if got := sanitizeHTML(markup, DefaultSanitizePolicy()); got != markup {
+		t.Errorf("Expected valid markup unchanged\nwant: %s\ngot:  %s", markup, got)
+	}
+}
+
+func TestSanitizeHTMLRemovesUnsafeMarkup(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"script", `<p>Hi<script>alert(1)</script></p>`, `<p>Hi</p>`},
+		{"uppercase script", `<p>Hi<SCRIPT type="text/javascript">alert(1)</SCRIPT ></p>`, `<p>Hi</p>`},
+		{"script with slash", `<script/src="//evil.example/x.js"></script>ok`, `ok`},
+		{"fake closing tag", `<script>var s = "</scriptx>"; alert(1)</script>ok`, `ok`},
+		{"unclosed script", `<p>Hi</p><script>alert(1)`, `<p>Hi</p>`},
+		{"nested script name", `<scr<script>ipt>alert(1)</script>`, `ipt>alert(1)`},
+		{"style element", `<style>body{background:url(javascript:alert(1))}</style><p>x</p>`, `<p>x</p>`},
+		{"iframe", `<iframe src="https://evil.example"><p>fallback</p></iframe>ok`, `ok`},
+		{"nested object", `<object data="x"><object data="y">a</object>b</object>ok`, `ok`},
+		{"embed", `<embed src="x.swf">ok`, `ok`},
+		{"event handler", `<img src="x.png" onerror="alert(1)">`, `<img src="x.png">`},
+		{"event handler casing", `<a href="/" OnClick='alert(1)' title=t>x</a>`, `<a href="/" title=t>x</a>`},
+		{"event handler after slash", `<img/src="x.png"/onerror=alert(1)>`, `<img/src="x.png">`},
+		{"self closing kept", `<br onclick="x" />`, `<br />`},
+		{"javascript url", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript url casing and spaces", `<a href="  JaVaScRiPt:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript url with entities", `<a href="jav&#x09;ascript&colon;alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript url with numeric entities", `<a href="&#106;&#97;&#118;&#97;&#115;&#99;&#114;&#105;&#112;&#116;&#58;alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript url with newline", "<a href=\"java\nscript:alert(1)\">x</a>", `<a>x</a>`},
+		{"vbscript url", `<a href=vbscript:msgbox(1)>x</a>`, `<a>x</a>`},
+		{"data html url", `<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`, `<a>x</a>`},
+		{"data svg image", `<img src="data:image/svg+xml;base64,PHN2Zz4=">`, `<img>`},
+		{"data png image kept", `<img src="data:image/png;base64,iVBORw0=">`, `<img src="data:image/png;base64,iVBORw0=">`},
+		{"style expression", `<div style="width: expression(alert(1))">x</div>`, `<div>x</div>`},
+		{"style with comment", `<div style="width: expr/**/ession(alert(1))">x</div>`, `<div>x</div>`},
+		{"style escape", `<div style="background:url(\6a avascript:alert(1))">x</div>`, `<div>x</div>`},
+		{"unknown tag keeps text", `<svg><circle r="1"/></svg><marquee>hi</marquee>`, `hi`},
+		{"comment removed", `<!-- <script>alert(1)</script> -->ok`, `ok`},
+		{"unterminated tag", `ok<img src=x onerror="alert(1)`, `ok`},
+		{"noscript", `<noscript><p title="</noscript><img src=x onerror=alert(1)>">`, `<img src=x>">`},
+		{"title attribute confusion", `<title><a title="</title><img src=x onerror=alert(1)>"></title>`, `<title><a title="</title><img src=x>"></title>`},
+		{"meta refresh", `<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`, `<meta content="0;url=javascript:alert(1)">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeHTML(tt.in, DefaultSanitizePolicy()); got != tt.want {
+				t.Errorf("sanitizeHTML(%q)\nwant: %q\ngot:  %q", tt.in, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSanitizeHTMLPolicy(t *testing.T) {
+	policy := SanitizePolicy{
+		AllowedTags:       []string{"p", "style", "script"},
+		AllowedAttributes: []string{"class", "onclick"},
+		AllowComments:     true,
+	}
+
+	in := `<!--[if mso]><p>Outlook</p><![endif]--><style>.x{color:red}</style><p class="x" id="y" onclick="go()">Hi <b>there</b></p><script>alert(1)</script>`
+	want := `<!--[if mso]><p>Outlook</p><![endif]--><style>.x{color:red}</style><p class="x">Hi there</p>`
+
+	if got := sanitizeHTML(in, policy); got != want {
+		t.Errorf("Expected policy to be applied\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
+func TestClientSanitizeHTML(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.SanitizeHTML = true
+
+	client := NewClientWithConfig(config)
+	var sent string
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		var email Email
+		if err := json.Unmarshal(body, &email); err != nil {
+			t.Fatalf("Failed to decode request body: %v", err)
+		}
+		sent = email.HTML
+		return acceptedResponse(), nil
+	})
+
+	html := `<p onclick="steal()">Hello</p><script>alert(1)</script>`
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Subject", html)
+	if _, err := client.Send(email); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sent != "<p>Hello</p>" {
+		t.Errorf("Expected sanitized HTML to be sent, got %q", sent)
+	}
+	if email.HTML != html {
+		t.Error("Expected caller's email to be unchanged")
+	}
+	if strings.Contains(sent, "script") {
+		t.Errorf("Expected script to be removed, got %q", sent)
+	}
+}
@@ -0,0 +1,26 @@
+package poodle
+
+import (
+	"net/http"
+	"time"
+)
+
+// Transport settings used when Config.Serverless is set
+const (
+	// ServerlessIdleConnTimeout closes idle connections quickly, so that a
+	// connection is rarely reused after the environment was frozen
+	ServerlessIdleConnTimeout = 5 * time.Second
+	// ServerlessMaxIdleConnsPerHost keeps a single idle connection, which
+	// is all a function handling one invocation at a time needs
+	ServerlessMaxIdleConnsPerHost = 1
+)
+
+// configureTransport applies the serverless and keep-alive settings
+func configureTransport(transport *http.Transport, config *Config) {
+	if config.Serverless {
+		transport.IdleConnTimeout = ServerlessIdleConnTimeout
+		transport.MaxIdleConns = ServerlessMaxIdleConnsPerHost
+		transport.MaxIdleConnsPerHost = ServerlessMaxIdleConnsPerHost
+	}
+	transport.DisableKeepAlives = config.DisableKeepAlives
+}
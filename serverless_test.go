@@ -0,0 +1,109 @@
+package poodle
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"syscall"
+	"testing"
+)
+
+func TestServerlessTransport(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.Serverless = true
+	config.DisableKeepAlives = true
+
+	client := NewClientWithConfig(config)
+	transport := client.httpClient.httpClient.(*http.Client).Transport.(*http.Transport)
+
+	if transport.IdleConnTimeout != ServerlessIdleConnTimeout {
+		t.Errorf("Expected idle timeout %s, got %s", ServerlessIdleConnTimeout, transport.IdleConnTimeout)
+	}
+	if transport.MaxIdleConnsPerHost != ServerlessMaxIdleConnsPerHost {
+		t.Errorf("Expected %d idle connections per host, got %d", ServerlessMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	}
+	if !transport.DisableKeepAlives {
+		t.Error("Expected keep-alives to be disabled")
+	}
+}
+
+// gotConn reports a connection to the request's trace, as the transport
+// does before writing the request
+func gotConn(req *http.Request, reused bool) {
+	if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Reused: reused})
+	}
+}
+
+func TestServerlessRetriesConnectionReset(t *testing.T) {
+	for _, serverless := range []bool{true, false} {
+		config := NewConfig()
+		config.APIKey = "test_api_key"
+		config.Serverless = serverless
+
+		client := NewClientWithConfig(config)
+		var bodies []string
+		client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(body))
+			if len(bodies) == 1 {
+				gotConn(req, true)
+				return nil, &url.Error{Op: "Post", URL: req.URL.String(), Err: syscall.ECONNRESET}
+			}
+			gotConn(req, false)
+			return acceptedResponse(), nil
+		})
+
+		_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+
+		if err != nil {
+			t.Fatalf("Expected the reset request to be repeated (serverless %t), got: %v", serverless, err)
+		}
+		if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] == "" {
+			t.Errorf("Expected the same body to be sent twice, got %q", bodies)
+		}
+		if stats := client.Stats(); stats.StaleConnectionRetries != 1 || stats.Retries != 0 {
+			t.Errorf("Expected the repeat to be counted as a stale connection retry, got %+v", stats)
+		}
+	}
+}
+
+func TestServerlessDoesNotRepeatAmbiguousFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		reused bool
+		err    error
+	}{
+		{"reset on a new connection", false, syscall.ECONNRESET},
+		{"EOF on a reused connection", true, io.EOF},
+		{"unexpected EOF on a reused connection", true, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.APIKey = "test_api_key"
+			config.Serverless = true
+
+			client := NewClientWithConfig(config)
+			attempts := 0
+			client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				gotConn(req, tt.reused)
+				return nil, &url.Error{Op: "Post", URL: req.URL.String(), Err: tt.err}
+			})
+
+			_, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello")
+			var networkErr *NetworkError
+			if !errors.As(err, &networkErr) || attempts != 1 {
+				t.Errorf("Expected a NetworkError after 1 attempt, got %v after %d", err, attempts)
+			}
+			if stats := client.Stats(); stats.StaleConnectionRetries != 0 {
+				t.Errorf("Expected no stale connection retry, got %d", stats.StaleConnectionRetries)
+			}
+		})
+	}
+}
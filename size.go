@@ -0,0 +1,121 @@
+package poodle
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SizeReport breaks down the size of an email, e.g. to find out which
+// part of an email that failed the MaxContentSize check is too big and by
+// how much. Sizes are in bytes unless noted otherwise; the limits apply to
+// bytes, not characters.
+type SizeReport struct {
+	HTMLBytes int
+	// HTMLChars is the number of characters (runes) of the HTML body,
+	// which is smaller than HTMLBytes for non-ASCII content
+	HTMLChars int
+	TextBytes int
+	TextChars int
+	// SubjectBytes and HeaderBytes are the sizes of the subject and of
+	// the names and values of the additional headers
+	SubjectBytes int
+	HeaderBytes  int
+	// AttachmentBytes is the size of the attachments. Emails have no
+	// attachments yet, so it is zero.
+	AttachmentBytes int
+	// PayloadBytes is the size of the JSON request body sent to the API,
+	// including escaping
+	PayloadBytes int
+	// MaxContentSize is the limit of the HTML and the text body
+	MaxContentSize int
+}
+
+// SizeReport returns the size breakdown of the email
+func (e *Email) SizeReport() SizeReport {
+	report := SizeReport{
+		HTMLBytes:      len(e.HTML),
+		HTMLChars:      utf8.RuneCountInString(e.HTML),
+		TextBytes:      len(e.Text),
+		TextChars:      utf8.RuneCountInString(e.Text),
+		SubjectBytes:   len(e.Subject),
+		MaxContentSize: MaxContentSize,
+	}
+	for key, value := range e.Headers {
+		report.HeaderBytes += len(key) + len(value)
+	}
+	if payload, err := json.Marshal(e); err == nil {
+		report.PayloadBytes = len(payload)
+	}
+	return report
+}
+
+// Exceeded reports whether the HTML or the text body exceeds
+// MaxContentSize
+func (r SizeReport) Exceeded() bool {
+	return r.HTMLBytes > r.MaxContentSize || r.TextBytes > r.MaxContentSize
+}
+
+// String returns a readable breakdown of the sizes, noting by how much a
+// body exceeds its limit
+func (r SizeReport) String() string {
+	var b strings.Builder
+	body := func(name string, bytes, chars int) {
+		fmt.Fprintf(&b, "%-12s %s, %d characters", name+":", formatSize(bytes), chars)
+		if bytes > r.MaxContentSize {
+			fmt.Fprintf(&b, ", %s over the %s limit", formatSize(bytes-r.MaxContentSize), formatSize(r.MaxContentSize))
+		}
+		b.WriteByte('\n')
+	}
+	body("HTML", r.HTMLBytes, r.HTMLChars)
+	body("Text", r.TextBytes, r.TextChars)
+	fmt.Fprintf(&b, "%-12s %s\n", "Subject:", formatSize(r.SubjectBytes))
+	fmt.Fprintf(&b, "%-12s %s\n", "Headers:", formatSize(r.HeaderBytes))
+	fmt.Fprintf(&b, "%-12s %s\n", "Attachments:", formatSize(r.AttachmentBytes))
+	fmt.Fprintf(&b, "%-12s %s", "Payload:", formatSize(r.PayloadBytes))
+	return b.String()
+}
+
+// formatSize formats a byte count, adding the size in KiB or MiB for
+// larger counts
+func formatSize(bytes int) string {
+	switch {
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%d bytes (%.1f MiB)", bytes, float64(bytes)/(1024*1024))
+	case bytes >= 1024:
+		return fmt.Sprintf("%d bytes (%.1f KiB)", bytes, float64(bytes)/1024)
+	default:
+		return fmt.Sprintf("%d bytes", bytes)
+	}
+}
+
+// contentSizeMessage returns the validation message of a body over
+// MaxContentSize, with its size and the limit
+func contentSizeMessage(part string, size int, after string) string {
+	if after != "" {
+		after = " " + after
+	}
+	return fmt.Sprintf("%s content exceeds maximum size limit%s: %d bytes, %d bytes over the limit of %d bytes",
+		part, after, size, size-MaxContentSize, MaxContentSize)
+}
+
+// withSizeReport adds the size report of the email to the context of a
+// validation error as size_report. If the HTML body is over the limit, the
+// context also holds its size once minified as minified_html_bytes and
+// whether that is within the limit as minify_would_fit, which is also
+// suggested in the html errors.
+func withSizeReport(err *ValidationError, email *Email) *ValidationError {
+	err.ContextMap["size_report"] = email.SizeReport()
+
+	if len(email.HTML) > MaxContentSize {
+		minified := len(minifyHTML(email.HTML))
+		err.ContextMap["minified_html_bytes"] = minified
+		err.ContextMap["minify_would_fit"] = minified <= MaxContentSize
+		if minified <= MaxContentSize {
+			err.Errors["html"] = append(err.Errors["html"], fmt.Sprintf(
+				"Minifying the HTML would bring it to %d bytes, within the limit (see Email.MinifyHTML and Config.AutoMinifyHTML)", minified))
+		}
+	}
+	return err
+}
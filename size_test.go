@@ -0,0 +1,98 @@
+package poodle
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSizeReportMultiByte(t *testing.T) {
+	email := NewEmailWithBoth("from@example.com", "to@example.com", "Grüße", "<p>日本語</p>", "Ünïcødé 🎉")
+	email.SetHeader("X-Tenant", "äcme")
+
+	report := email.SizeReport()
+	if report.HTMLBytes != 16 || report.HTMLChars != 10 {
+		t.Errorf("Expected 16 bytes and 10 characters of HTML, got %d and %d", report.HTMLBytes, report.HTMLChars)
+	}
+	if report.TextBytes != 16 || report.TextChars != 9 {
+		t.Errorf("Expected 16 bytes and 9 characters of text, got %d and %d", report.TextBytes, report.TextChars)
+	}
+	if report.SubjectBytes != 7 || report.HeaderBytes != len("X-Tenant")+5 {
+		t.Errorf("Expected a 7-byte subject and 13 bytes of headers, got %d and %d", report.SubjectBytes, report.HeaderBytes)
+	}
+	if report.AttachmentBytes != 0 || report.MaxContentSize != MaxContentSize {
+		t.Errorf("Expected no attachments and the content limit, got %+v", report)
+	}
+
+	payload, _ := email.MarshalJSON()
+	if report.PayloadBytes != len(payload) {
+		t.Errorf("Expected a payload of %d bytes, got %d", len(payload), report.PayloadBytes)
+	}
+	if report.Exceeded() {
+		t.Error("Expected the email to be within the limits")
+	}
+}
+
+func TestSizeReportString(t *testing.T) {
+	report := SizeReport{
+		HTMLBytes:      MaxContentSize + 3*1024*1024/2,
+		HTMLChars:      MaxContentSize / 2,
+		TextBytes:      2048,
+		TextChars:      2048,
+		MaxContentSize: MaxContentSize,
+	}
+
+	s := report.String()
+	for _, want := range []string{
+		"HTML:        12058624 bytes (11.5 MiB), 5242880 characters, 1572864 bytes (1.5 MiB) over the 10485760 bytes (10.0 MiB) limit",
+		"Text:        2048 bytes (2.0 KiB), 2048 characters\n",
+		"Attachments: 0 bytes",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Expected %q in the report, got:\n%s", want, s)
+		}
+	}
+	if strings.Count(s, "over the") != 1 {
+		t.Errorf("Expected only the HTML to be over the limit, got:\n%s", s)
+	}
+}
+
+func TestValidationErrorSizeReport(t *testing.T) {
+	// Each character is 3 bytes, so the body is within the limit in
+	// characters but not in bytes
+	html := strings.Repeat("語", MaxContentSize/3+1)
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Hello", html)
+
+	err := email.Validate()
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %T", err)
+	}
+	report, ok := validationErr.Context()["size_report"].(SizeReport)
+	if !ok {
+		t.Fatalf("Expected a size report in the context, got %v", validationErr.Context())
+	}
+	if report.HTMLBytes != len(html) || report.HTMLChars != MaxContentSize/3+1 || !report.Exceeded() {
+		t.Errorf("Expected the oversized HTML in the report, got %+v", report)
+	}
+
+	// Other validation errors have no size report
+	err = NewHTMLEmail("from@example.com", "", "Hello", "<p>Hi</p>").Validate()
+	if _, ok := err.(*ValidationError).Context()["size_report"]; ok {
+		t.Error("Expected no size report for a missing recipient")
+	}
+}
+
+func TestRenderSizeReport(t *testing.T) {
+	email := NewTextEmail("from@example.com", "to@example.com", "Hello", "{{body}}")
+	email.Variables = map[string]string{"body": strings.Repeat("é", MaxContentSize/2+1)}
+
+	_, err := email.Render()
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %T", err)
+	}
+	report, ok := validationErr.Context()["size_report"].(SizeReport)
+	if !ok || report.TextBytes != MaxContentSize+2 || report.TextChars != MaxContentSize/2+1 {
+		t.Errorf("Expected the rendered size in the report, got %+v", report)
+	}
+}
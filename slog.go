@@ -0,0 +1,86 @@
+//go:build go1.21
+
+package poodle
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// WithSlog emits structured diagnostics to the given logger: "poodle.request"
+// (method, url, body_bytes) and "poodle.response" (status, duration_ms,
+// request_id, rate_limit_remaining) at Debug level, and "poodle.error"
+// (code, error_type, attempt, error) at Warn level. Nothing is allocated
+// for events whose level is disabled.
+//
+// WithSlog requires Go 1.21 or later. It is an option rather than a
+// Config field because the module supports Go 1.20, which has no log/slog:
+// a *slog.Logger field would keep Config from compiling there, while this
+// file is only built with Go 1.21 or later.
+func WithSlog(logger *slog.Logger) Option {
+	return func(c *Client) {
+		if logger == nil {
+			c.httpClient.events = nil
+			return
+		}
+		c.httpClient.events = slogSink{logger: logger}
+	}
+}
+
+// slogSink is an eventSink writing to a slog.Logger
+type slogSink struct {
+	logger *slog.Logger
+}
+
+func (s slogSink) request(ctx context.Context, method, url string, bodyBytes int) {
+	if !s.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	s.logger.LogAttrs(ctx, slog.LevelDebug, "poodle.request",
+		slog.String("method", method),
+		slog.String("url", url),
+		slog.Int("body_bytes", bodyBytes),
+	)
+}
+
+func (s slogSink) response(ctx context.Context, status int, duration time.Duration, requestID string, rateLimitRemaining int) {
+	if !s.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.Int("status", status),
+		slog.Float64("duration_ms", float64(duration)/float64(time.Millisecond)),
+	}
+	if requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	if rateLimitRemaining >= 0 {
+		attrs = append(attrs, slog.Int("rate_limit_remaining", rateLimitRemaining))
+	}
+
+	s.logger.LogAttrs(ctx, slog.LevelDebug, "poodle.response", attrs...)
+}
+
+func (s slogSink) error(ctx context.Context, err error, attempt int) {
+	if !s.logger.Enabled(ctx, slog.LevelWarn) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.Int("attempt", attempt),
+		slog.String("error", err.Error()),
+	}
+	var poodleErr PoodleError
+	if errors.As(err, &poodleErr) {
+		attrs = append(attrs, slog.Int("code", poodleErr.StatusCode()))
+		if errorType, ok := poodleErr.Context()["error_type"].(string); ok {
+			attrs = append(attrs, slog.String("error_type", errorType))
+		}
+	}
+
+	s.logger.LogAttrs(ctx, slog.LevelWarn, "poodle.error", attrs...)
+}
@@ -0,0 +1,108 @@
+//go:build go1.21
+
+package poodle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithSlogEmitsEvents(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := NewClient("test_api_key", WithSlog(logger))
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(readBody(req), "fail@example.com") {
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Header:     http.Header{"X-Request-Id": {"req_456"}},
+				Body:       io.NopCloser(strings.NewReader(`{"message": "Invalid API key"}`)),
+			}, nil
+		}
+		resp := acceptedResponse()
+		resp.Header = http.Header{"X-Request-Id": {"req_123"}, "Ratelimit-Remaining": {"42"}}
+		return resp, nil
+	})
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Subject", "Hello"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := client.SendText("from@example.com", "fail@example.com", "Subject", "Hello"); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 5 {
+		t.Fatalf("Expected 5 log records, got %d: %s", len(records), buf.String())
+	}
+
+	request := records[0]
+	if request["msg"] != "poodle.request" || request["level"] != "DEBUG" || request["method"] != "POST" || request["body_bytes"] == nil {
+		t.Errorf("Unexpected request record: %v", request)
+	}
+
+	response := records[1]
+	if response["msg"] != "poodle.response" || response["status"] != float64(202) ||
+		response["request_id"] != "req_123" || response["rate_limit_remaining"] != float64(42) || response["duration_ms"] == nil {
+		t.Errorf("Unexpected response record: %v", response)
+	}
+
+	failure := records[4]
+	if failure["msg"] != "poodle.error" || failure["level"] != "WARN" || failure["code"] != float64(401) || failure["error_type"] != "authentication_error" {
+		t.Errorf("Unexpected error record: %v", failure)
+	}
+}
+
+func TestSlogSinkWrappedError(t *testing.T) {
+	var buf bytes.Buffer
+	sink := slogSink{logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+
+	sink.error(context.Background(), fmt.Errorf("sending receipt: %w", NewAuthenticationError("Invalid API key")), 1)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode record: %v", err)
+	}
+	if record["code"] != float64(401) || record["error_type"] != "authentication_error" {
+		t.Errorf("Expected the code of the wrapped error, got %v", record)
+	}
+}
+
+func TestSlogSinkDisabledDoesNotAllocate(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+	sink := slogSink{logger: logger}
+	ctx := context.Background()
+	err := NewNetworkError("boom", "")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		sink.request(ctx, "POST", "https://api.usepoodle.com/v1/send-email", 128)
+		sink.response(ctx, 202, 15*time.Millisecond, "req_123", 10)
+		sink.error(ctx, err, 1)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations for disabled levels, got %v", allocs)
+	}
+}
+
+func readBody(req *http.Request) string {
+	body, _ := io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body)
+}
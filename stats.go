@@ -0,0 +1,127 @@
+package poodle
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyWeight is the weight of the newest sample in the rolling average
+// latency
+const latencyWeight = 0.2
+
+// ClientStats is a snapshot of a client's send statistics
+type ClientStats struct {
+	// Attempted is the number of emails the client was asked to send,
+	// including ones rejected by validation
+	Attempted int64
+	Succeeded int64
+	Failed    int64
+	// FailedByType counts failures by error_type, e.g. "validation_error"
+	FailedByType map[string]int64
+	// Retries is the number of retries performed
+	Retries int64
+	// StaleConnectionRetries is the number of requests repeated on a new
+	// connection because the server had closed the idle keep-alive
+	// connection they were sent on, including the repeats after a reset
+	// in serverless mode. They are not counted in Retries or Requests.
+	StaleConnectionRetries int64
+	// BytesUploaded is the size of all request bodies sent, including
+	// retries
+	BytesUploaded int64
+	// Requests is the number of HTTP requests made
+	Requests int64
+	// AverageLatency is an exponentially weighted moving average of the
+	// request latency
+	AverageLatency time.Duration
+}
+
+// clientStats maintains the counters behind ClientStats
+type clientStats struct {
+	attempted     atomic.Int64
+	succeeded     atomic.Int64
+	retries       atomic.Int64
+	staleRetries  atomic.Int64
+	bytesUploaded atomic.Int64
+
+	mutex          sync.Mutex
+	failedByType   map[string]int64
+	requests       int64
+	averageLatency float64
+}
+
+func (s *clientStats) recordSend(err error) {
+	s.attempted.Add(1)
+	if err == nil {
+		s.succeeded.Add(1)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failedByType == nil {
+		s.failedByType = make(map[string]int64)
+	}
+	s.failedByType[errorTypeOf(err)]++
+}
+
+func (s *clientStats) recordRequest(bytes int, latency time.Duration) {
+	s.bytesUploaded.Add(int64(bytes))
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests++
+	if s.requests == 1 {
+		s.averageLatency = float64(latency)
+	} else {
+		s.averageLatency += latencyWeight * (float64(latency) - s.averageLatency)
+	}
+}
+
+func (s *clientStats) snapshot() ClientStats {
+	stats := ClientStats{
+		Attempted:              s.attempted.Load(),
+		Succeeded:              s.succeeded.Load(),
+		Retries:                s.retries.Load(),
+		StaleConnectionRetries: s.staleRetries.Load(),
+		BytesUploaded:          s.bytesUploaded.Load(),
+		FailedByType:           make(map[string]int64),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for errorType, count := range s.failedByType {
+		stats.FailedByType[errorType] = count
+		stats.Failed += count
+	}
+	stats.Requests = s.requests
+	stats.AverageLatency = time.Duration(s.averageLatency)
+	return stats
+}
+
+func (s *clientStats) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attempted.Store(0)
+	s.succeeded.Store(0)
+	s.retries.Store(0)
+	s.staleRetries.Store(0)
+	s.bytesUploaded.Store(0)
+	s.failedByType = nil
+	s.requests = 0
+	s.averageLatency = 0
+}
+
+// Stats returns a snapshot of the client's send statistics
+func (c *Client) Stats() ClientStats {
+	return c.httpClient.stats.snapshot()
+}
+
+// ResetStats sets all send statistics back to zero
+func (c *Client) ResetStats() {
+	c.httpClient.stats.reset()
+}
@@ -0,0 +1,92 @@
+package poodle
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientStats(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.MaxRetries = 1
+
+	clock := newTestClock()
+	client := NewClientWithConfig(config, WithClock(clock))
+
+	var mutex sync.Mutex
+	attempts := make(map[string]int)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		clock.Sleep(req.Context(), 100*time.Millisecond)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		switch {
+		case strings.Contains(string(body), "flaky@"):
+			attempts["flaky"]++
+			if attempts["flaky"] == 1 {
+				return jsonResponse(http.StatusServiceUnavailable, `{"message":"Unavailable"}`), nil
+			}
+		case strings.Contains(string(body), "unauthorized@"):
+			return jsonResponse(http.StatusUnauthorized, `{"message":"Invalid API key"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	recipients := []string{
+		"a@example.com", "b@example.com", "c@example.com", // 3 successes
+		"flaky@example.com",        // success after a retry
+		"unauthorized@example.com", // authentication error
+		"invalid", "invalid",       // validation errors, no request
+	}
+
+	var wg sync.WaitGroup
+	for _, to := range recipients {
+		wg.Add(1)
+		go func(to string) {
+			defer wg.Done()
+			client.SendText("from@example.com", to, "Subject", "Hello")
+		}(to)
+	}
+	wg.Wait()
+
+	stats := client.Stats()
+	if stats.Attempted != 7 || stats.Succeeded != 4 || stats.Failed != 3 {
+		t.Errorf("Expected 7 attempted, 4 succeeded, 3 failed, got %+v", stats)
+	}
+	if stats.FailedByType["validation_error"] != 2 || stats.FailedByType["authentication_error"] != 1 {
+		t.Errorf("Unexpected failures by type: %v", stats.FailedByType)
+	}
+	if stats.Retries != 1 || stats.Requests != 6 {
+		t.Errorf("Expected 1 retry and 6 requests, got %d and %d", stats.Retries, stats.Requests)
+	}
+	if stats.BytesUploaded <= 0 {
+		t.Errorf("Expected uploaded bytes to be counted, got %d", stats.BytesUploaded)
+	}
+	if stats.AverageLatency <= 0 {
+		t.Errorf("Expected an average latency, got %s", stats.AverageLatency)
+	}
+
+	client.ResetStats()
+	if stats := client.Stats(); stats.Attempted != 0 || stats.Failed != 0 || stats.Requests != 0 || len(stats.FailedByType) != 0 {
+		t.Errorf("Expected stats to be reset, got %+v", stats)
+	}
+}
+
+func TestClientStatsAverageLatency(t *testing.T) {
+	var stats clientStats
+	stats.recordRequest(10, 100*time.Millisecond)
+	if average := stats.snapshot().AverageLatency; average != 100*time.Millisecond {
+		t.Errorf("Expected the first sample to be the average, got %s", average)
+	}
+
+	stats.recordRequest(10, 200*time.Millisecond)
+	if average := stats.snapshot().AverageLatency; average != 120*time.Millisecond {
+		t.Errorf("Expected a weighted average of 120ms, got %s", average)
+	}
+}
@@ -0,0 +1,298 @@
+package poodle
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/usepoodle/poodle-go/webhook"
+)
+
+// StatusTracker defaults
+const (
+	DefaultStatusCapacity = 10000
+	DefaultStatusTTL      = 24 * time.Hour
+)
+
+// EmailState is the delivery state of a sent email, as reported by webhook
+// events
+type EmailState string
+
+// Email states, from earliest to latest. A soft bounce is EmailStateDeferred
+// and a hard bounce EmailStateBounced.
+const (
+	EmailStateSent       EmailState = "sent"
+	EmailStateDeferred   EmailState = "deferred"
+	EmailStateDelivered  EmailState = "delivered"
+	EmailStateOpened     EmailState = "opened"
+	EmailStateClicked    EmailState = "clicked"
+	EmailStateComplained EmailState = "complained"
+	EmailStateBounced    EmailState = "bounced"
+)
+
+// emailStateRanks orders the states. Events can arrive out of order, so a
+// state only replaces one of a lower rank. A hard bounce is final.
+var emailStateRanks = map[EmailState]int{
+	EmailStateSent:       1,
+	EmailStateDeferred:   2,
+	EmailStateDelivered:  3,
+	EmailStateOpened:     4,
+	EmailStateClicked:    5,
+	EmailStateComplained: 6,
+	EmailStateBounced:    7,
+}
+
+// EmailStatus is the tracked state of a sent email
+type EmailStatus struct {
+	MessageID string
+	State     EmailState
+	Recipient string
+	// Sent is true once the tracker has seen the send, which may be after
+	// its first webhook event
+	Sent bool
+	// UpdatedAt is the time of the event that set the state, or of the
+	// send
+	UpdatedAt time.Time
+	// Event is the event that set the state, or nil if it was the send
+	Event webhook.Event
+}
+
+// StatusTrackerOption configures a StatusTracker
+type StatusTrackerOption func(*StatusTracker)
+
+// WithStatusCapacity sets how many emails the tracker holds before
+// forgetting the least recently updated ones
+func WithStatusCapacity(n int) StatusTrackerOption {
+	return func(t *StatusTracker) {
+		if n > 0 {
+			t.capacity = n
+		}
+	}
+}
+
+// WithStatusTTL sets how long an email is tracked after its last update
+func WithStatusTTL(d time.Duration) StatusTrackerOption {
+	return func(t *StatusTracker) {
+		if d > 0 {
+			t.ttl = d
+		}
+	}
+}
+
+// StatusTracker answers where a sent email is without polling the API. It
+// records the message ID of every email the client sends successfully,
+// and webhook events passed to HandleEvent update the state of their
+// message. Events for a message may arrive before its send has returned,
+// and in any order; a later state is never replaced by an earlier one.
+//
+// The tracker holds up to WithStatusCapacity emails, evicting the least
+// recently updated, and forgets emails not updated within WithStatusTTL.
+// It is safe for concurrent use.
+type StatusTracker struct {
+	clock    Clock
+	capacity int
+	ttl      time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently updated first
+	waiters map[string]chan struct{}
+}
+
+// statusEntry is an email held by a StatusTracker
+type statusEntry struct {
+	status  EmailStatus
+	expires time.Time
+}
+
+// NewStatusTracker creates a tracker recording the sends of the client.
+// The tracker adds a PostSend hook to the client's configuration.
+func NewStatusTracker(client *Client, opts ...StatusTrackerOption) *StatusTracker {
+	t := &StatusTracker{
+		clock:    client.httpClient.clock,
+		capacity: DefaultStatusCapacity,
+		ttl:      DefaultStatusTTL,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		waiters:  make(map[string]chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	client.mutex.Lock()
+	hooks := make([]func(*Email, *EmailResponse, error), 0, len(client.config.PostSend)+1)
+	hooks = append(hooks, client.config.PostSend...)
+	client.config.PostSend = append(hooks, t.recordSend)
+	client.mutex.Unlock()
+
+	return t
+}
+
+// recordSend is the PostSend hook recording successful sends
+func (t *StatusTracker) recordSend(email *Email, response *EmailResponse, err error) {
+	if err != nil || response == nil {
+		return
+	}
+	messageID := response.MessageID()
+	if messageID == "" {
+		return
+	}
+
+	recipient := email.To
+	if email.ToAddress != nil {
+		recipient = email.ToAddress.Email
+	}
+	t.update(messageID, func(status *EmailStatus) {
+		status.Sent = true
+		if status.Recipient == "" {
+			status.Recipient = recipient
+		}
+		if status.State == "" {
+			status.State = EmailStateSent
+			status.UpdatedAt = t.clock.Now()
+		}
+	})
+}
+
+// HandleEvent updates the status of the event's message. Events without a
+// message ID and event types without a state, such as *webhook.EventUnknown,
+// are ignored.
+func (t *StatusTracker) HandleEvent(event webhook.Event) {
+	envelope := event.EventEnvelope()
+	state := eventState(event)
+	if envelope.MessageID == "" || state == "" {
+		return
+	}
+
+	t.update(envelope.MessageID, func(status *EmailStatus) {
+		if envelope.Recipient != "" {
+			status.Recipient = envelope.Recipient
+		}
+		current := emailStateRanks[status.State]
+		next := emailStateRanks[state]
+		if next > current || next == current && envelope.Timestamp.After(status.UpdatedAt) {
+			status.State = state
+			status.UpdatedAt = envelope.Timestamp
+			status.Event = event
+		}
+	})
+}
+
+// eventState returns the state an event moves its message to
+func eventState(event webhook.Event) EmailState {
+	switch e := event.(type) {
+	case *webhook.EventDelivered:
+		return EmailStateDelivered
+	case *webhook.EventBounced:
+		if e.IsPermanent() {
+			return EmailStateBounced
+		}
+		return EmailStateDeferred
+	case *webhook.EventComplained:
+		return EmailStateComplained
+	case *webhook.EventOpened:
+		return EmailStateOpened
+	case *webhook.EventClicked:
+		return EmailStateClicked
+	default:
+		return ""
+	}
+}
+
+// Status returns the status of the message. ok is false if the message is
+// not tracked.
+func (t *StatusTracker) Status(messageID string) (status EmailStatus, ok bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.lookup(messageID)
+}
+
+// WaitFor waits until the message reaches the state or a later one and
+// returns its status. As a hard bounce is final, waiting for any state
+// ends when the email bounces. It returns the context's error if the
+// context is done first.
+func (t *StatusTracker) WaitFor(ctx context.Context, messageID string, state EmailState) (EmailStatus, error) {
+	for {
+		t.mutex.Lock()
+		status, ok := t.lookup(messageID)
+		if ok && emailStateRanks[status.State] >= emailStateRanks[state] {
+			t.mutex.Unlock()
+			return status, nil
+		}
+		changed, waiting := t.waiters[messageID]
+		if !waiting {
+			changed = make(chan struct{})
+			t.waiters[messageID] = changed
+		}
+		t.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return status, ctx.Err()
+		}
+	}
+}
+
+// Len returns the number of tracked messages, including expired ones not
+// yet evicted
+func (t *StatusTracker) Len() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.order.Len()
+}
+
+// lookup returns the status of a message that has not expired. It must be
+// called with the mutex held.
+func (t *StatusTracker) lookup(messageID string) (EmailStatus, bool) {
+	element, ok := t.entries[messageID]
+	if !ok {
+		return EmailStatus{}, false
+	}
+	entry := element.Value.(*statusEntry)
+	if !t.clock.Now().Before(entry.expires) {
+		t.remove(element)
+		return EmailStatus{}, false
+	}
+	return entry.status, true
+}
+
+// update applies change to the status of a message, tracking it if needed,
+// and wakes its waiters
+func (t *StatusTracker) update(messageID string, change func(*EmailStatus)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.clock.Now()
+	element, ok := t.entries[messageID]
+	if ok && !now.Before(element.Value.(*statusEntry).expires) {
+		t.remove(element)
+		ok = false
+	}
+	if ok {
+		t.order.MoveToFront(element)
+	} else {
+		element = t.order.PushFront(&statusEntry{status: EmailStatus{MessageID: messageID}})
+		t.entries[messageID] = element
+	}
+
+	entry := element.Value.(*statusEntry)
+	change(&entry.status)
+	entry.expires = now.Add(t.ttl)
+
+	for t.order.Len() > t.capacity {
+		t.remove(t.order.Back())
+	}
+
+	if changed, waiting := t.waiters[messageID]; waiting {
+		close(changed)
+		delete(t.waiters, messageID)
+	}
+}
+
+func (t *StatusTracker) remove(element *list.Element) {
+	t.order.Remove(element)
+	delete(t.entries, element.Value.(*statusEntry).status.MessageID)
+}
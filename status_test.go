@@ -0,0 +1,246 @@
+package poodle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/usepoodle/poodle-go/webhook"
+)
+
+func newStatusTestClient(t *testing.T, opts ...StatusTrackerOption) (*Client, *StatusTracker, *testClock) {
+	t.Helper()
+	clock := newTestClock()
+	client := NewClient("test_api_key", WithClock(clock))
+
+	var mutex sync.Mutex
+	sent := 0
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		sent++
+		id := fmt.Sprintf("msg_%d", sent)
+		mutex.Unlock()
+		return &http.Response{
+			StatusCode: http.StatusAccepted,
+			Body:       io.NopCloser(strings.NewReader(`{"success": true, "message": "Email queued", "message_id": "` + id + `"}`)),
+		}, nil
+	})
+
+	return client, NewStatusTracker(client, opts...), clock
+}
+
+func statusEvent(event webhook.Event, messageID string, at time.Time) webhook.Event {
+	envelope := event.EventEnvelope()
+	envelope.MessageID = messageID
+	envelope.Recipient = "to@example.com"
+	envelope.Timestamp = at
+	return event
+}
+
+func TestStatusTrackerRecordsSends(t *testing.T) {
+	client, tracker, clock := newStatusTestClient(t)
+
+	response, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if response.MessageID() != "msg_1" {
+		t.Fatalf("Expected message ID msg_1, got %q", response.MessageID())
+	}
+
+	status, ok := tracker.Status("msg_1")
+	if !ok {
+		t.Fatal("Expected the sent email to be tracked")
+	}
+	if status.State != EmailStateSent || !status.Sent || status.Recipient != "to@example.com" || !status.UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("Expected a sent status, got %+v", status)
+	}
+
+	tracker.HandleEvent(statusEvent(&webhook.EventDelivered{}, "msg_1", clock.Now().Add(time.Second)))
+	if status, _ := tracker.Status("msg_1"); status.State != EmailStateDelivered || status.Event == nil {
+		t.Errorf("Expected a delivered status, got %+v", status)
+	}
+}
+
+func TestStatusTrackerFailedSendIsNotTracked(t *testing.T) {
+	clock := newTestClock()
+	client := NewClient("test_api_key", WithClock(clock))
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusBadRequest, `{"message":"Bad request","message_id":"msg_1"}`), nil
+	})
+	tracker := NewStatusTracker(client)
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if tracker.Len() != 0 {
+		t.Errorf("Expected no tracked emails, got %d", tracker.Len())
+	}
+}
+
+func TestStatusTrackerOutOfOrderEvents(t *testing.T) {
+	client, tracker, clock := newStatusTestClient(t)
+	start := clock.Now()
+
+	// The webhook arrives before the send returns
+	tracker.HandleEvent(statusEvent(&webhook.EventDelivered{}, "msg_1", start.Add(time.Second)))
+	if status, ok := tracker.Status("msg_1"); !ok || status.State != EmailStateDelivered || status.Sent {
+		t.Fatalf("Expected a delivered status not yet sent, got %+v", status)
+	}
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	status, _ := tracker.Status("msg_1")
+	if status.State != EmailStateDelivered || !status.Sent {
+		t.Errorf("Expected the send not to downgrade the status, got %+v", status)
+	}
+
+	tests := []struct {
+		name  string
+		event webhook.Event
+		want  EmailState
+	}{
+		{"OpenedAfterDelivered", &webhook.EventOpened{}, EmailStateOpened},
+		{"LateDelivered", &webhook.EventDelivered{}, EmailStateOpened},
+		{"Clicked", &webhook.EventClicked{}, EmailStateClicked},
+		{"LateSoftBounce", &webhook.EventBounced{BounceType: "soft"}, EmailStateClicked},
+		{"Complained", &webhook.EventComplained{}, EmailStateComplained},
+		{"HardBounce", &webhook.EventBounced{BounceType: "hard"}, EmailStateBounced},
+		{"OpenedAfterBounce", &webhook.EventOpened{}, EmailStateBounced},
+		{"Unknown", &webhook.EventUnknown{}, EmailStateBounced},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker.HandleEvent(statusEvent(tt.event, "msg_1", start.Add(time.Duration(i)*time.Second)))
+			if status, _ := tracker.Status("msg_1"); status.State != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, status.State)
+			}
+		})
+	}
+}
+
+func TestStatusTrackerIgnoresEventsWithoutMessageID(t *testing.T) {
+	_, tracker, clock := newStatusTestClient(t)
+
+	tracker.HandleEvent(statusEvent(&webhook.EventDelivered{}, "", clock.Now()))
+	if tracker.Len() != 0 {
+		t.Errorf("Expected no tracked emails, got %d", tracker.Len())
+	}
+}
+
+func TestStatusTrackerEviction(t *testing.T) {
+	_, tracker, clock := newStatusTestClient(t, WithStatusCapacity(2), WithStatusTTL(time.Hour))
+
+	for _, id := range []string{"a", "b", "c"} {
+		tracker.HandleEvent(statusEvent(&webhook.EventDelivered{}, id, clock.Now()))
+	}
+	if tracker.Len() != 2 {
+		t.Errorf("Expected 2 tracked emails, got %d", tracker.Len())
+	}
+	if _, ok := tracker.Status("a"); ok {
+		t.Error("Expected the least recently updated email to be evicted")
+	}
+
+	clock.Sleep(context.Background(), 30*time.Minute)
+	tracker.HandleEvent(statusEvent(&webhook.EventOpened{}, "b", clock.Now()))
+	clock.Sleep(context.Background(), 30*time.Minute)
+
+	if _, ok := tracker.Status("c"); ok {
+		t.Error("Expected an email not updated within the TTL to expire")
+	}
+	if _, ok := tracker.Status("b"); !ok {
+		t.Error("Expected an update to extend the TTL")
+	}
+	if tracker.Len() != 1 {
+		t.Errorf("Expected 1 tracked email, got %d", tracker.Len())
+	}
+}
+
+func TestStatusTrackerWaitFor(t *testing.T) {
+	client, tracker, clock := newStatusTestClient(t)
+
+	done := make(chan EmailStatus)
+	go func() {
+		status, err := tracker.WaitFor(context.Background(), "msg_1", EmailStateDelivered)
+		if err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+		done <- status
+	}()
+
+	if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	select {
+	case status := <-done:
+		t.Fatalf("Expected WaitFor to wait for delivery, got %+v", status)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tracker.HandleEvent(statusEvent(&webhook.EventDelivered{}, "msg_1", clock.Now()))
+	select {
+	case status := <-done:
+		if status.State != EmailStateDelivered {
+			t.Errorf("Expected delivered, got %s", status.State)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected WaitFor to return after delivery")
+	}
+
+	// A later state satisfies the wait immediately
+	if status, err := tracker.WaitFor(context.Background(), "msg_1", EmailStateSent); err != nil || status.State != EmailStateDelivered {
+		t.Errorf("Expected the delivered status, got %+v, %v", status, err)
+	}
+}
+
+func TestStatusTrackerWaitForContextDone(t *testing.T) {
+	_, tracker, _ := newStatusTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := tracker.WaitFor(ctx, "msg_1", EmailStateDelivered); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestStatusTrackerConcurrent(t *testing.T) {
+	client, tracker, clock := newStatusTestClient(t)
+	const emails = 20
+
+	var wg sync.WaitGroup
+	for i := 1; i <= emails; i++ {
+		id := fmt.Sprintf("msg_%d", i)
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if _, err := tracker.WaitFor(context.Background(), id, EmailStateOpened); err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			tracker.HandleEvent(statusEvent(&webhook.EventOpened{}, id, clock.Now()))
+			tracker.HandleEvent(statusEvent(&webhook.EventDelivered{}, id, clock.Now()))
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := client.SendText("from@example.com", "to@example.com", "Receipt", "Thanks"); err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 1; i <= emails; i++ {
+		status, ok := tracker.Status(fmt.Sprintf("msg_%d", i))
+		if !ok || status.State != EmailStateOpened || !status.Sent {
+			t.Errorf("Expected msg_%d to be opened and sent, got %+v", i, status)
+		}
+	}
+}
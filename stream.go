@@ -0,0 +1,83 @@
+package poodle
+
+import (
+	"context"
+	"sync"
+)
+
+// SendStream sends the emails received from in, at most concurrency at a
+// time, and emits a result for each on the returned channel as it
+// completes. Result.Index is the position of the email in the stream, so
+// results can be matched to their input even though they arrive out of
+// order.
+//
+// The output channel is closed once in is closed and every email has been
+// sent. When ctx is cancelled, or a failure cancels the stream under the
+// FailureMode set with WithFailureMode, no further emails are taken from
+// in, but the results of emails already being sent are still emitted. The
+// caller must receive from the output channel until it is closed.
+// WithConcurrency is ignored in favour of the concurrency argument.
+func (c *Client) SendStream(ctx context.Context, in <-chan *Email, concurrency int, opts ...BatchOption) <-chan SendResult {
+	options := newBatchOptions(opts)
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	out := make(chan SendResult, concurrency)
+
+	// Cancelling the stream stops taking emails; sends in flight use ctx
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	var mu sync.Mutex
+	next := 0
+	// receive takes the next email from in and numbers it. The lock keeps
+	// the numbering in the order the emails were received.
+	receive := func() (*Email, int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if streamCtx.Err() != nil {
+			return nil, 0, false
+		}
+		select {
+		case <-streamCtx.Done():
+			return nil, 0, false
+		case email, ok := <-in:
+			if !ok {
+				return nil, 0, false
+			}
+			index := next
+			next++
+			return email, index, true
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				email, index, ok := receive()
+				if !ok {
+					return
+				}
+				outcome, err := c.SendDetailed(ctx, email)
+				if err != nil && options.failureMode.cancels(err) {
+					cancel()
+				}
+				result := SendResult{Index: index, Email: email, Response: outcome.Response, Err: err, Attempts: outcome.Attempts}
+				if options.report != nil {
+					options.report.Add(result)
+				}
+				out <- result
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,131 @@
+package poodle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSendStream(t *testing.T) {
+	client := NewClient("test_api_key")
+
+	var inFlight, maxInFlight int32
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			highest := atomic.LoadInt32(&maxInFlight)
+			if current <= highest || atomic.CompareAndSwapInt32(&maxInFlight, highest, current) {
+				break
+			}
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		if strings.Contains(string(body), "broken@") {
+			return jsonResponse(http.StatusInternalServerError, `{"message":"Server error"}`), nil
+		}
+		return acceptedResponse(), nil
+	})
+
+	emails := make([]*Email, 20)
+	for i := range emails {
+		to := fmt.Sprintf("user%d@example.com", i)
+		if i == 7 {
+			to = "broken@example.com"
+		}
+		emails[i] = NewTextEmail("from@example.com", to, "Subject", "Hello")
+	}
+
+	in := make(chan *Email)
+	go func() {
+		for _, email := range emails {
+			in <- email
+		}
+		close(in)
+	}()
+
+	seen := make(map[int]bool)
+	for result := range client.SendStream(context.Background(), in, 3) {
+		if seen[result.Index] {
+			t.Errorf("Duplicate result for index %d", result.Index)
+		}
+		seen[result.Index] = true
+		if result.Email != emails[result.Index] {
+			t.Errorf("Expected result %d to carry its email", result.Index)
+		}
+		if result.OK() != (result.Index != 7) {
+			t.Errorf("Unexpected outcome for index %d: %v", result.Index, result.Err)
+		}
+	}
+
+	if len(seen) != len(emails) {
+		t.Errorf("Expected %d results, got %d", len(emails), len(seen))
+	}
+	if maxInFlight > 3 {
+		t.Errorf("Expected at most 3 concurrent sends, got %d", maxInFlight)
+	}
+}
+
+func TestSendStreamCancelled(t *testing.T) {
+	client := NewClient("test_api_key")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		started <- struct{}{}
+		<-release
+		return acceptedResponse(), nil
+	})
+
+	// The input is never closed; cancellation alone must end the stream
+	in := make(chan *Email, 10)
+	for i := 0; i < 10; i++ {
+		in <- NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	}
+
+	out := client.SendStream(ctx, in, 2)
+	<-started
+	<-started
+	cancel()
+	close(release)
+
+	count := 0
+	for result := range out {
+		count++
+		if !result.OK() {
+			t.Errorf("Expected the in-flight send to complete, got %v", result.Err)
+		}
+	}
+	if count != 2 {
+		t.Errorf("Expected results for the 2 in-flight sends, got %d", count)
+	}
+	if len(in) != 8 {
+		t.Errorf("Expected 8 emails left unsent, got %d", len(in))
+	}
+}
+
+func TestSendStreamFailFast(t *testing.T) {
+	client := NewClient("test_api_key")
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusUnauthorized, `{"message":"Invalid API Key"}`), nil
+	})
+
+	in := make(chan *Email, 5)
+	for i := 0; i < 5; i++ {
+		in <- NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	}
+	close(in)
+
+	count := 0
+	for range client.SendStream(context.Background(), in, 1, WithFailureMode(FailFastOnAuth)) {
+		count++
+	}
+	if count != 1 || len(in) != 4 {
+		t.Errorf("Expected the stream to stop after the first auth failure, got %d results and %d left", count, len(in))
+	}
+}
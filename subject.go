@@ -0,0 +1,61 @@
+package poodle
+
+import (
+	"encoding/json"
+	"mime"
+	"strings"
+)
+
+// EncodeSubject encodes a subject containing non-ASCII characters as RFC
+// 2047 B-encoded words in UTF-8. Each encoded word is at most 75 characters
+// and never splits a character; words are folded onto continuation lines.
+// Pure ASCII subjects are returned unchanged.
+func EncodeSubject(s string) string {
+	if isASCII(s) {
+		return s
+	}
+
+	words := strings.Split(mime.BEncoding.Encode("UTF-8", s), " ")
+	return strings.Join(words, "\r\n ")
+}
+
+// hasHeaderBreak reports whether s contains a line break that would end
+// the header it is sent in, i.e. one not followed by the space or tab of
+// a folded line
+func hasHeaderBreak(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\r' && s[i] != '\n' {
+			continue
+		}
+		if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+			i++
+		}
+		if i+1 >= len(s) || (s[i+1] != ' ' && s[i+1] != '\t') {
+			return true
+		}
+	}
+	return false
+}
+
+// isASCII reports whether s contains only ASCII bytes
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// marshalEmail encodes the email as the request body, applying subject
+// encoding and the canonical form if they are enabled
+func marshalEmail(config *Config, email *Email) ([]byte, error) {
+	if config.EncodeSubjects && !isASCII(email.Subject) {
+		email = email.clone()
+		email.Subject = EncodeSubject(email.Subject)
+	}
+	if config.CanonicalPayloads {
+		return email.MarshalCanonical()
+	}
+	return json.Marshal(email)
+}
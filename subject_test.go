@@ -0,0 +1,112 @@
+package poodle
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEncodeSubjectASCII(t *testing.T) {
+	for _, subject := range []string{"Hello", "Order #42 =?not encoded?=", ""} {
+		if got := EncodeSubject(subject); got != subject {
+			t.Errorf("Expected ASCII subject %q unchanged, got %q", subject, got)
+		}
+	}
+}
+
+func TestEncodeSubjectRoundTrip(t *testing.T) {
+	subjects := []string{
+		"Café",
+		"🎉 Your order has shipped",
+		"Grüße aus München – Ihre Bestellung ist unterwegs und kommt bald bei Ihnen an 🚚",
+		strings.Repeat("日本語の件名", 20),
+	}
+
+	decoder := new(mime.WordDecoder)
+	for _, subject := range subjects {
+		encoded := EncodeSubject(subject)
+
+		for _, word := range strings.Split(encoded, "\r\n ") {
+			if len(word) > 75 {
+				t.Errorf("Expected encoded words of at most 75 characters, got %d: %s", len(word), word)
+			}
+			if !strings.HasPrefix(word, "=?UTF-8?b?") || !strings.HasSuffix(word, "?=") {
+				t.Errorf("Expected a B-encoded word, got %s", word)
+			}
+		}
+
+		decoded, err := decoder.DecodeHeader(encoded)
+		if err != nil {
+			t.Fatalf("Failed to decode %q: %v", encoded, err)
+		}
+		if decoded != subject {
+			t.Errorf("Expected round trip to return %q, got %q", subject, decoded)
+		}
+	}
+}
+
+func TestHasHeaderBreak(t *testing.T) {
+	tests := []struct {
+		subject string
+		broken  bool
+	}{
+		{"Hello", false},
+		{EncodeSubject(strings.Repeat("日本語の件名", 20)), false},
+		{"Folded\r\n\tline", false},
+		{"Hi\r\nBcc: victim@example.com", true},
+		{"Hi\nBcc: victim@example.com", true},
+		{"Hi\rBcc: victim@example.com", true},
+		{"Trailing\r\n", true},
+	}
+
+	for _, tt := range tests {
+		if got := hasHeaderBreak(tt.subject); got != tt.broken {
+			t.Errorf("hasHeaderBreak(%q) = %t, want %t", tt.subject, got, tt.broken)
+		}
+	}
+}
+
+func TestClientEncodeSubjects(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		config := NewConfig()
+		config.APIKey = "test_api_key"
+		config.EncodeSubjects = enabled
+
+		client := NewClientWithConfig(config)
+		var sent []string
+		client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			var email Email
+			if err := json.Unmarshal(body, &email); err != nil {
+				t.Fatalf("Failed to decode request body: %v", err)
+			}
+			sent = append(sent, email.Subject)
+			return acceptedResponse(), nil
+		})
+
+		email := NewTextEmail("from@example.com", "to@example.com", "Café ☕", "Hello")
+		if _, err := client.Send(email); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := client.SendText("from@example.com", "to@example.com", "Plain", "Hello"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := "Café ☕"
+		if enabled {
+			want = EncodeSubject("Café ☕")
+		}
+		if sent[0] != want {
+			t.Errorf("EncodeSubjects=%t: expected subject %q, got %q", enabled, want, sent[0])
+		}
+		if sent[1] != "Plain" {
+			t.Errorf("Expected ASCII subject to be sent unchanged, got %q", sent[1])
+		}
+		if email.Subject != "Café ☕" {
+			t.Errorf("Expected caller's email to be unchanged, got %q", email.Subject)
+		}
+	}
+}
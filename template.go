@@ -0,0 +1,185 @@
+package poodle
+
+import (
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Render returns a copy of the email with each {{name}} token in the
+// subject and bodies replaced by the value of Variables["name"]. Values
+// are HTML-escaped in the HTML body and used as they are in the subject
+// and text. A quoted string such as {{"{{"}} is replaced by its content,
+// for literal braces. The copy has no Variables, so it is not rendered
+// again.
+//
+// Render returns a ValidationError listing the tokens without a value,
+// the subject tokens whose value contains a line break, which would
+// inject headers, or a body that exceeds MaxContentSize once rendered.
+func (e *Email) Render() (*Email, error) {
+	rendered := e.clone()
+	rendered.Variables = nil
+
+	errors := make(map[string][]string)
+	var unresolved, unsafe []string
+	rendered.Subject, unresolved, unsafe = renderSubject(rendered.Subject, e.Variables)
+	for _, name := range unresolved {
+		errors["subject"] = append(errors["subject"], "Unresolved variable {{"+name+"}}")
+	}
+	for _, name := range unsafe {
+		errors["subject"] = append(errors["subject"], "Variable {{"+name+"}} contains a line break")
+	}
+	for _, field := range []struct {
+		name   string
+		value  *string
+		escape func(string) string
+	}{
+		{"html", &rendered.HTML, html.EscapeString},
+		{"text", &rendered.Text, nil},
+	} {
+		var unresolved []string
+		*field.value, unresolved = renderTemplate(*field.value, e.Variables, field.escape)
+		for _, name := range unresolved {
+			errors[field.name] = append(errors[field.name], "Unresolved variable {{"+name+"}}")
+		}
+	}
+
+	if len(rendered.HTML) > MaxContentSize {
+		errors["html"] = append(errors["html"], contentSizeMessage("HTML", len(rendered.HTML), "after rendering"))
+	}
+	if len(rendered.Text) > MaxContentSize {
+		errors["text"] = append(errors["text"], contentSizeMessage("Text", len(rendered.Text), "after rendering"))
+	}
+
+	if len(errors) > 0 {
+		err := NewValidationError("Email template could not be rendered", errors)
+		if len(rendered.HTML) > MaxContentSize || len(rendered.Text) > MaxContentSize {
+			withSizeReport(err, rendered)
+		}
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// renderSubject renders a subject template. Values are used as they are,
+// except values with a line break, which are not substituted: their
+// names are returned in unsafe rather than unresolved.
+func renderSubject(s string, variables map[string]string) (rendered string, unresolved, unsafe []string) {
+	safe := make(map[string]string, len(variables))
+	broken := make(map[string]bool)
+	for name, value := range variables {
+		if strings.ContainsAny(value, "\r\n") {
+			broken[name] = true
+			continue
+		}
+		safe[name] = value
+	}
+
+	rendered, missing := renderTemplate(s, safe, nil)
+	for _, name := range missing {
+		if broken[name] {
+			unsafe = append(unsafe, name)
+		} else {
+			unresolved = append(unresolved, name)
+		}
+	}
+	return rendered, unresolved, unsafe
+}
+
+// renderTemplate replaces the {{name}} tokens of s with their values,
+// escaped with escape if it is not nil, and quoted string tokens with
+// their content. Tokens without a value are kept as written and their
+// names returned, sorted and without duplicates.
+func renderTemplate(s string, variables map[string]string, escape func(string) string) (string, []string) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	missing := make(map[string]bool)
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:start])
+
+		token, n, ok := parseTemplateToken(s[start:])
+		if !ok {
+			b.WriteByte('{')
+			s = s[start+1:]
+			continue
+		}
+		s = s[start+n:]
+
+		switch {
+		case token.literal:
+			b.WriteString(token.value)
+		case hasVariable(variables, token.value):
+			value := variables[token.value]
+			if escape != nil {
+				value = escape(value)
+			}
+			b.WriteString(value)
+		default:
+			missing[token.value] = true
+			b.WriteString(token.raw)
+		}
+	}
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return b.String(), names
+}
+
+func hasVariable(variables map[string]string, name string) bool {
+	_, ok := variables[name]
+	return ok
+}
+
+// templateToken is a {{...}} token of a template
+type templateToken struct {
+	// value is the variable name, or the content of a quoted string
+	value   string
+	literal bool
+	raw     string
+}
+
+// parseTemplateToken parses the token at the start of s, which begins
+// with "{{", and returns it with its length. ok is false if s does not
+// start with a complete token.
+func parseTemplateToken(s string) (token templateToken, n int, ok bool) {
+	inner := s[2:]
+	trimmed := strings.TrimLeft(inner, " ")
+	offset := 2 + len(inner) - len(trimmed)
+
+	if strings.HasPrefix(trimmed, `"`) {
+		quoted, err := strconv.QuotedPrefix(trimmed)
+		if err != nil {
+			return token, 0, false
+		}
+		value, _ := strconv.Unquote(quoted)
+		rest := strings.TrimLeft(trimmed[len(quoted):], " ")
+		if !strings.HasPrefix(rest, "}}") {
+			return token, 0, false
+		}
+		n = len(s) - len(rest) + 2
+		return templateToken{value: value, literal: true, raw: s[:n]}, n, true
+	}
+
+	end := strings.Index(trimmed, "}}")
+	if end < 0 {
+		return token, 0, false
+	}
+	name := strings.TrimSpace(trimmed[:end])
+	if name == "" || strings.ContainsAny(name, "{}") {
+		return token, 0, false
+	}
+	n = offset + end + 2
+	return templateToken{value: name, raw: s[:n]}, n, true
+}
@@ -0,0 +1,104 @@
+package poodle
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEmailRender(t *testing.T) {
+	email := NewEmailWithBoth("from@example.com", "to@example.com", "Hi {{name}}",
+		`<p>{{ name }} ordered {{item}}</p>`, "{{name}} ordered {{item}}")
+	email.Variables = map[string]string{"name": "Tom & Jerry", "item": "<cheese>"}
+
+	rendered, err := email.Render()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rendered.Subject != "Hi Tom & Jerry" || rendered.Text != "Tom & Jerry ordered <cheese>" {
+		t.Errorf("Expected raw values in the subject and text, got %q and %q", rendered.Subject, rendered.Text)
+	}
+	if rendered.HTML != "<p>Tom &amp; Jerry ordered &lt;cheese&gt;</p>" {
+		t.Errorf("Expected escaped values in the HTML, got %q", rendered.HTML)
+	}
+	if rendered.Variables != nil || email.Subject != "Hi {{name}}" {
+		t.Error("Expected a rendered copy without variables and the original unchanged")
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	variables := map[string]string{"name": "Jane", "empty": ""}
+	tests := []struct {
+		template   string
+		want       string
+		unresolved string
+	}{
+		{"Hello {{name}}", "Hello Jane", ""},
+		{"[{{empty}}]", "[]", ""},
+		{`Use {{"{{"}}name{{"}}"}} to insert a name`, "Use {{name}} to insert a name", ""},
+		{`{{ "quoted \"text\"" }}`, `quoted "text"`, ""},
+		{"{{missing}} and {{other}} and {{missing}}", "{{missing}} and {{other}} and {{missing}}", "missing other"},
+		{"{{ not closed", "{{ not closed", ""},
+		{"{{}} {{{name}}}", "{{}} {Jane}", ""},
+	}
+
+	for _, tt := range tests {
+		got, unresolved := renderTemplate(tt.template, variables, nil)
+		if got != tt.want || strings.Join(unresolved, " ") != tt.unresolved {
+			t.Errorf("renderTemplate(%q) = %q, %v; want %q, %s", tt.template, got, unresolved, tt.want, tt.unresolved)
+		}
+	}
+}
+
+func TestEmailRenderErrors(t *testing.T) {
+	email := NewEmailWithBoth("from@example.com", "to@example.com", "Hi {{name}}", "<p>{{body}}</p>", "{{body}}")
+	email.Variables = map[string]string{"body": strings.Repeat("x", MaxContentSize)}
+
+	_, err := email.Render()
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %T", err)
+	}
+	if got := validationErr.Errors["subject"]; len(got) != 1 || got[0] != "Unresolved variable {{name}}" {
+		t.Errorf("Expected the unresolved subject variable, got %v", got)
+	}
+	if len(validationErr.Errors["html"]) != 1 || validationErr.Errors["text"] != nil {
+		t.Errorf("Expected the rendered HTML to exceed the size limit, got %v", validationErr.Errors)
+	}
+}
+
+func TestAutoRender(t *testing.T) {
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AutoRender = true
+
+	var sent map[string]interface{}
+	client := NewClientWithConfig(config)
+	client.httpClient.httpClient = mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		json.Unmarshal(data, &sent)
+		return acceptedResponse(), nil
+	})
+
+	email := NewTextEmail("from@example.com", "to@example.com", "Welcome {{name}}", "Hello {{name}}")
+	email.Variables = map[string]string{"name": "Jane"}
+	if _, err := client.Send(email); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sent["subject"] != "Welcome Jane" || sent["text"] != "Hello Jane" {
+		t.Errorf("Expected the rendered email to be sent, got %v", sent)
+	}
+
+	email.Variables = map[string]string{}
+	if _, err := client.Send(email); err == nil {
+		t.Error("Expected unresolved variables to fail the send")
+	}
+
+	// Emails without variables are sent as they are
+	sent = nil
+	if _, err := client.Send(NewTextEmail("from@example.com", "to@example.com", "Subject", "Use {{name}}")); err != nil || sent["text"] != "Use {{name}}" {
+		t.Errorf("Expected an email without variables to be sent unchanged, got %v (%v)", sent, err)
+	}
+}
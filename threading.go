@@ -0,0 +1,100 @@
+package poodle
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetInReplyTo sets the In-Reply-To header to the Message-ID of the email
+// being replied to, e.g. "<1234@example.com>", so that mail clients show
+// the email in the same thread. The ID is also added to the References
+// header if it is not listed there yet, as mail clients that ignore
+// In-Reply-To thread by References.
+func (e *Email) SetInReplyTo(messageID string) error {
+	messageID = strings.TrimSpace(messageID)
+	if reason := checkMessageID(messageID); reason != "" {
+		return NewValidationError("Invalid In-Reply-To header", map[string][]string{
+			"in_reply_to": {reason},
+		})
+	}
+
+	e.SetHeader("In-Reply-To", messageID)
+	e.appendReference(messageID)
+	return nil
+}
+
+// AddReference appends a Message-ID to the References header, which lists
+// the emails of the thread from the oldest to the one being replied to.
+// IDs already listed are not added again, so the order of the first
+// occurrences is kept.
+func (e *Email) AddReference(messageID string) error {
+	messageID = strings.TrimSpace(messageID)
+	if reason := checkMessageID(messageID); reason != "" {
+		return NewValidationError("Invalid References header", map[string][]string{
+			"references": {reason},
+		})
+	}
+
+	e.appendReference(messageID)
+	return nil
+}
+
+// References returns the Message-IDs of the References header in order
+func (e *Email) References() []string {
+	return strings.Fields(e.Headers["References"])
+}
+
+// appendReference adds a valid Message-ID to the end of the References
+// header unless it is already listed
+func (e *Email) appendReference(messageID string) {
+	references := e.References()
+	for _, reference := range references {
+		if reference == messageID {
+			return
+		}
+	}
+	e.SetHeader("References", strings.Join(append(references, messageID), " "))
+}
+
+// GenerateMessageID returns a new, globally unique Message-ID in the given
+// domain, e.g. "<lq2x0k8c.3f9a1b2c4d5e6f708192a3b4c5d6e7f8@example.com>",
+// for callers that assign their own IDs to be able to thread replies. The
+// domain should be one the sender controls, such as the From domain.
+func GenerateMessageID(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if !isValidEmail("id@" + domain) {
+		return "", NewValidationError("Invalid Message-ID domain", map[string][]string{
+			"domain": {"Message-ID domain is not a valid domain name"},
+		})
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return "<" + strconv.FormatInt(time.Now().UnixNano(), 36) + "." + hex.EncodeToString(random) + "@" + domain + ">", nil
+}
+
+// checkMessageID returns a reason why the value is not a Message-ID of the
+// form <id-left@id-right> (RFC 5322), or an empty string if it is
+func checkMessageID(messageID string) string {
+	if !strings.HasPrefix(messageID, "<") || !strings.HasSuffix(messageID, ">") {
+		return "Message-ID must be enclosed in angle brackets"
+	}
+
+	id := messageID[1 : len(messageID)-1]
+	for _, r := range id {
+		if r <= ' ' || r > '~' || r == '<' || r == '>' {
+			return "Message-ID must not contain spaces, angle brackets or non-ASCII characters"
+		}
+	}
+
+	left, right, ok := strings.Cut(id, "@")
+	if !ok || left == "" || right == "" || strings.Contains(right, "@") {
+		return "Message-ID must have the form <id@domain>"
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package poodle
+
+import (
+	"encoding/json"
+	"errors"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetInReplyTo(t *testing.T) {
+	email := NewTextEmail("support@example.com", "jane@example.com", "Re: Printer on fire", "Have you tried turning it off?")
+	if err := email.AddReference("<first@example.com>"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := email.SetInReplyTo(" <second@example.com> "); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := email.AddReference("<first@example.com>"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if email.Headers["In-Reply-To"] != "<second@example.com>" {
+		t.Errorf("Expected In-Reply-To to be set, got %v", email.Headers)
+	}
+	expected := []string{"<first@example.com>", "<second@example.com>"}
+	if !reflect.DeepEqual(email.References(), expected) {
+		t.Errorf("Expected references %v, got %v", expected, email.References())
+	}
+	if err := email.Validate(); err != nil {
+		t.Errorf("Expected the threaded email to be valid, got: %v", err)
+	}
+}
+
+func TestMessageIDValidation(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"<abc.123@example.com>", true},
+		{"<a+b=c@[127.0.0.1]>", true},
+		{"abc@example.com", false},
+		{"<abc@example.com", false},
+		{"<ab c@example.com>", false},
+		{"<abc@example.com> <def@example.com>", false},
+		{"<abc>", false},
+		{"<@example.com>", false},
+		{"<abc@>", false},
+		{"<a@b@c>", false},
+		{"<ab<c@example.com>", false},
+		{"<\r\nBcc: x@example.com>", false},
+		{"<é@example.com>", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		email := NewTextEmail("from@example.com", "to@example.com", "Hello", "Hi")
+		err := email.SetInReplyTo(tt.id)
+		if (err == nil) != tt.valid {
+			t.Errorf("SetInReplyTo(%q): expected valid %t, got %v", tt.id, tt.valid, err)
+		}
+		if err2 := email.AddReference(tt.id); (err2 == nil) != tt.valid {
+			t.Errorf("AddReference(%q): expected valid %t, got %v", tt.id, tt.valid, err2)
+		}
+
+		var validationErr *ValidationError
+		if err != nil && (!errors.As(err, &validationErr) || len(email.Headers) != 0) {
+			t.Errorf("%q: expected a ValidationError and no headers, got %v and %v", tt.id, err, email.Headers)
+		}
+	}
+}
+
+func TestGenerateMessageID(t *testing.T) {
+	first, err := GenerateMessageID("Mail.Example.com")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	second, _ := GenerateMessageID("mail.example.com")
+
+	if checkMessageID(first) != "" || !strings.HasSuffix(first, "@mail.example.com>") {
+		t.Errorf("Expected a valid Message-ID in the domain, got %q", first)
+	}
+	if first == second {
+		t.Errorf("Expected unique IDs, got %q twice", first)
+	}
+
+	for _, domain := range []string{"", "localhost", "exa mple.com", "a@b.com"} {
+		if id, err := GenerateMessageID(domain); err == nil {
+			t.Errorf("Expected an error for domain %q, got %q", domain, id)
+		}
+	}
+}
+
+// TestThreadingHeaderBlock writes the headers of a reply to the end of a
+// thread as a message header block and reads them back with net/mail, as
+// the recipient's mail client would
+func TestThreadingHeaderBlock(t *testing.T) {
+	var thread []string
+	for i := 0; i < 3; i++ {
+		id, err := GenerateMessageID("example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		thread = append(thread, id)
+	}
+
+	reply := NewTextEmail("support@example.com", "jane@example.com", "Re: Ticket #42", "Fixed.")
+	for _, id := range thread[:2] {
+		reply.AddReference(id)
+	}
+	reply.SetInReplyTo(thread[2])
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent struct {
+		Headers map[string]string `json:"headers"`
+	}
+	json.Unmarshal(data, &sent)
+
+	var block strings.Builder
+	for key, value := range sent.Headers {
+		block.WriteString(key + ": " + value + "\r\n")
+	}
+	block.WriteString("\r\n")
+
+	message, err := mail.ReadMessage(strings.NewReader(block.String()))
+	if err != nil {
+		t.Fatalf("Expected a parseable header block, got: %v\n%s", err, block.String())
+	}
+	if message.Header.Get("In-Reply-To") != thread[2] {
+		t.Errorf("Expected In-Reply-To %s, got %q", thread[2], message.Header.Get("In-Reply-To"))
+	}
+	if references := strings.Fields(message.Header.Get("References")); !reflect.DeepEqual(references, thread) {
+		t.Errorf("Expected the thread %v in References, got %v", thread, references)
+	}
+}
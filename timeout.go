@@ -0,0 +1,148 @@
+package poodle
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// requestTrace follows the progress of a request, so that a timeout can
+// be attributed to the phase the request was in
+type requestTrace struct {
+	mutex        sync.Mutex
+	tlsStarted   bool
+	tlsDone      bool
+	gotConn      bool
+	connReused   bool
+	wroteRequest bool
+	gotFirstByte bool
+	readingBody  bool
+}
+
+// withRequestTrace returns ctx with a trace of the request's progress,
+// which also counts the connections in stats
+func withRequestTrace(ctx context.Context, stats *transportStats) (context.Context, *requestTrace) {
+	t := &requestTrace{}
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeStart: func() { t.set(&t.tlsStarted) },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			// A failed handshake is reported as done too
+			if err == nil {
+				t.set(&t.tlsDone)
+				stats.recordTLS(state)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mutex.Lock()
+			t.gotConn = true
+			t.connReused = info.Reused
+			// A repeated request starts over on a new connection
+			t.wroteRequest = false
+			t.mutex.Unlock()
+			stats.recordConn(info)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				t.set(&t.wroteRequest)
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.set(&t.gotFirstByte)
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+func (t *requestTrace) set(flag *bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	*flag = true
+}
+
+// reused reports whether the request got a reused connection
+func (t *requestTrace) reused() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.connReused
+}
+
+// sent reports whether the request was written to the connection, so that
+// the API may have received it
+func (t *requestTrace) sent() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.wroteRequest
+}
+
+// phase returns the phase the request is in
+func (t *requestTrace) phase() TimeoutPhase {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	switch {
+	case t.readingBody:
+		return TimeoutPhaseBody
+	case t.gotConn:
+		return TimeoutPhaseResponseHeader
+	case t.tlsStarted && !t.tlsDone:
+		return TimeoutPhaseTLS
+	default:
+		return TimeoutPhaseDial
+	}
+}
+
+// timeoutError returns a TimeoutError if err was caused by a timeout, or
+// nil. An expired request context is reported as TimeoutPhaseTotal; other
+// timeouts, such as the dialer's ConnectTimeout, get the phase the request
+// was in. The transport's own timeouts also match context.DeadlineExceeded,
+// so the context is checked rather than the error.
+func (c *HTTPClient) timeoutError(config *Config, ctx context.Context, t *requestTrace, err error, started time.Time, url string) *TimeoutError {
+	if ctx.Err() == context.DeadlineExceeded {
+		timeout := config.Timeout
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = deadline.Sub(started).Round(time.Millisecond)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			err = context.DeadlineExceeded
+		}
+		return NewTimeoutError(TimeoutPhaseTotal, t.phase(), timeout, url, err)
+	}
+
+	// A lookup that timed out is reported as a DNS error
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() || networkErrorKind(err) != "" {
+		return nil
+	}
+
+	phase := t.phase()
+	var timeout time.Duration
+	switch phase {
+	case TimeoutPhaseDial:
+		timeout = config.ConnectTimeout
+	case TimeoutPhaseTLS:
+		timeout = c.transportTimeout(func(t *http.Transport) time.Duration { return t.TLSHandshakeTimeout })
+	case TimeoutPhaseResponseHeader:
+		timeout = c.transportTimeout(func(t *http.Transport) time.Duration { return t.ResponseHeaderTimeout })
+	}
+	return NewTimeoutError(phase, phase, timeout, url, err)
+}
+
+// transportTimeout returns a timeout of the client's transport, or zero
+// if the client does not use an *http.Transport
+func (c *HTTPClient) transportTimeout(get func(*http.Transport) time.Duration) time.Duration {
+	doer := c.httpClient
+	if hardened, ok := doer.(*hardenedDoer); ok {
+		doer = hardened.next
+	}
+	if client, ok := doer.(*http.Client); ok {
+		if transport, ok := client.Transport.(*http.Transport); ok {
+			return get(transport)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,187 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// timeoutTestClient returns a client for baseURL and its transport
+func timeoutTestClient(t *testing.T, baseURL string, timeout time.Duration) (*Client, *http.Transport) {
+	t.Helper()
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = baseURL
+	config.Timeout = timeout
+	client := NewClientWithConfig(config)
+	transport := client.httpClient.httpClient.(*http.Client).Transport.(*http.Transport)
+	return client, transport
+}
+
+// stallingListener accepts connections and never answers them
+func stallingListener(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected listener, got: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+			select {
+			case <-done:
+				return
+			default:
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(done)
+		listener.Close()
+	})
+	return listener
+}
+
+func sendForTimeout(t *testing.T, client *Client) *TimeoutError {
+	t.Helper()
+	_, err := client.SendText("from@example.com", "to@example.com", "Hello", "Hi")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Expected TimeoutError, got %T: %v", err, err)
+	}
+	return timeoutErr
+}
+
+type dialTimeout struct{}
+
+func (dialTimeout) Error() string   { return "i/o timeout" }
+func (dialTimeout) Timeout() bool   { return true }
+func (dialTimeout) Temporary() bool { return true }
+
+func TestTimeoutErrorDial(t *testing.T) {
+	client, transport := timeoutTestClient(t, "http://api.example.com", 5*time.Second)
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: dialTimeout{}}
+	}
+
+	err := sendForTimeout(t, client)
+	if err.Phase != TimeoutPhaseDial {
+		t.Errorf("Expected phase dial, got %q", err.Phase)
+	}
+	if err.Timeout != DefaultConnectTimeout {
+		t.Errorf("Expected the connect timeout, got %s", err.Timeout)
+	}
+	if err.Context()["error_type"] != "connection_timeout" {
+		t.Errorf("Expected connection_timeout error type, got %v", err.Context()["error_type"])
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected a dial timeout not to be a deadline expiry")
+	}
+}
+
+func TestTimeoutErrorTLS(t *testing.T) {
+	listener := stallingListener(t)
+	client, transport := timeoutTestClient(t, "https://"+listener.Addr().String(), 5*time.Second)
+	transport.TLSHandshakeTimeout = 50 * time.Millisecond
+
+	err := sendForTimeout(t, client)
+	if err.Phase != TimeoutPhaseTLS {
+		t.Errorf("Expected phase tls, got %q", err.Phase)
+	}
+	if err.Timeout != 50*time.Millisecond {
+		t.Errorf("Expected the TLS handshake timeout, got %s", err.Timeout)
+	}
+}
+
+func TestTimeoutErrorResponseHeader(t *testing.T) {
+	listener := stallingListener(t)
+	client, transport := timeoutTestClient(t, "http://"+listener.Addr().String(), 5*time.Second)
+	transport.ResponseHeaderTimeout = 50 * time.Millisecond
+
+	err := sendForTimeout(t, client)
+	if err.Phase != TimeoutPhaseResponseHeader {
+		t.Errorf("Expected phase response_header, got %q", err.Phase)
+	}
+	if err.Context()["error_type"] != "timeout" {
+		t.Errorf("Expected timeout error type, got %v", err.Context()["error_type"])
+	}
+}
+
+func TestTimeoutErrorTotal(t *testing.T) {
+	listener := stallingListener(t)
+	client, _ := timeoutTestClient(t, "http://"+listener.Addr().String(), 50*time.Millisecond)
+
+	err := sendForTimeout(t, client)
+	if err.Phase != TimeoutPhaseTotal {
+		t.Errorf("Expected phase total, got %q", err.Phase)
+	}
+	if err.During != TimeoutPhaseResponseHeader {
+		t.Errorf("Expected the deadline during response_header, got %q", err.During)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected the error to unwrap to context.DeadlineExceeded")
+	}
+}
+
+func TestTimeoutErrorTotalWhileReadingBody(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"success":`))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	client, _ := timeoutTestClient(t, server.URL, 100*time.Millisecond)
+
+	err := sendForTimeout(t, client)
+	if err.Phase != TimeoutPhaseTotal || err.During != TimeoutPhaseBody {
+		t.Errorf("Expected phase total during body, got %q during %q", err.Phase, err.During)
+	}
+}
+
+func TestTimeoutErrorIsNetworkError(t *testing.T) {
+	err := error(NewTimeoutError(TimeoutPhaseResponseHeader, TimeoutPhaseResponseHeader, time.Second, "https://api.example.com", nil))
+
+	var networkErr *NetworkError
+	if !errors.As(err, &networkErr) {
+		t.Fatal("Expected errors.As to find a NetworkError")
+	}
+	if networkErr.URL != "https://api.example.com" {
+		t.Errorf("Expected URL to be kept, got %q", networkErr.URL)
+	}
+	if !IsRetryable(err) {
+		t.Error("Expected timeouts to be retryable")
+	}
+}
+
+func TestCallerCancellationIsNotTimeout(t *testing.T) {
+	listener := stallingListener(t)
+	client, _ := timeoutTestClient(t, "http://"+listener.Addr().String(), 5*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := client.SendContext(ctx, NewTextEmail("from@example.com", "to@example.com", "Hello", "Hi"))
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		t.Errorf("Expected cancellation not to be a timeout, got: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package poodle
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// UnsubscribeFooter holds the templates of the footer that SetUnsubscribe
+// appends to the bodies. {{url}} is replaced with the unsubscribe link,
+// which is the URL if one is given and the mailto: link otherwise.
+type UnsubscribeFooter struct {
+	HTML string
+	Text string
+}
+
+// DefaultUnsubscribeFooter is a short footer with the unsubscribe link
+var DefaultUnsubscribeFooter = UnsubscribeFooter{
+	HTML: `<p style="font-size:12px;color:#666666">Don't want these emails? <a href="{{url}}">Unsubscribe</a>.</p>`,
+	Text: "\n\nDon't want these emails? Unsubscribe: {{url}}\n",
+}
+
+// SetUnsubscribe sets the List-Unsubscribe header to the URL and mailto
+// address, at least one of which is required. With an https URL it also
+// sets List-Unsubscribe-Post for one-click unsubscribe (RFC 8058), which
+// Gmail and Yahoo require of bulk senders.
+//
+// If a footer is given, it is appended to the HTML body (before </body> if
+// present) and to the text body, whichever the email has. Calling
+// SetUnsubscribe again with the same arguments does not add a second
+// footer.
+func (e *Email) SetUnsubscribe(unsubscribeURL, mailto string, footer ...UnsubscribeFooter) error {
+	unsubscribeURL = strings.TrimSpace(unsubscribeURL)
+	mailto = strings.TrimPrefix(strings.TrimSpace(mailto), "mailto:")
+
+	var problems []string
+	if unsubscribeURL == "" && mailto == "" {
+		problems = append(problems, "An unsubscribe URL or mailto address is required")
+	}
+	if unsubscribeURL != "" {
+		u, err := url.Parse(unsubscribeURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || strings.ContainsAny(unsubscribeURL, "<>, \t") {
+			problems = append(problems, "Unsubscribe URL must be an absolute http or https URL")
+		}
+	}
+	if mailto != "" && (!isValidEmail(strings.SplitN(mailto, "?", 2)[0]) || strings.ContainsAny(mailto, "<>, \t")) {
+		problems = append(problems, "Unsubscribe mailto address is not a valid email")
+	}
+	if len(problems) > 0 {
+		return NewValidationError("Invalid unsubscribe options", map[string][]string{
+			"unsubscribe": problems,
+		})
+	}
+
+	var targets []string
+	if unsubscribeURL != "" {
+		targets = append(targets, "<"+unsubscribeURL+">")
+	}
+	if mailto != "" {
+		targets = append(targets, "<mailto:"+mailto+">")
+	}
+	e.SetHeader("List-Unsubscribe", strings.Join(targets, ", "))
+
+	if strings.HasPrefix(unsubscribeURL, "https://") {
+		e.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	} else if e.Headers != nil {
+		delete(e.Headers, "List-Unsubscribe-Post")
+	}
+
+	link := unsubscribeURL
+	if link == "" {
+		link = "mailto:" + mailto
+	}
+	for _, f := range footer {
+		e.appendUnsubscribeFooter(f, link)
+	}
+
+	return nil
+}
+
+// appendUnsubscribeFooter appends the rendered footer to the bodies that
+// do not contain it yet
+func (e *Email) appendUnsubscribeFooter(footer UnsubscribeFooter, link string) {
+	if footer.HTML != "" && e.HasHTML() {
+		rendered := strings.ReplaceAll(footer.HTML, "{{url}}", html.EscapeString(link))
+		if !strings.Contains(e.HTML, rendered) {
+			if end := strings.LastIndex(strings.ToLower(e.HTML), "</body>"); end >= 0 {
+				e.HTML = e.HTML[:end] + rendered + e.HTML[end:]
+			} else {
+				e.HTML += rendered
+			}
+		}
+	}
+
+	if footer.Text != "" && e.HasText() {
+		rendered := strings.ReplaceAll(footer.Text, "{{url}}", link)
+		if !strings.Contains(e.Text, rendered) {
+			e.Text += rendered
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package poodle
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetUnsubscribeHeaders(t *testing.T) {
+	tests := []struct {
+		url    string
+		mailto string
+		header string
+		post   bool
+	}{
+		{"https://example.com/unsub?u=1", "", "<https://example.com/unsub?u=1>", true},
+		{"", "unsubscribe@example.com", "<mailto:unsubscribe@example.com>", false},
+		{"https://example.com/u", "mailto:unsub@example.com?subject=stop", "<https://example.com/u>, <mailto:unsub@example.com?subject=stop>", true},
+		{"http://example.com/u", "", "<http://example.com/u>", false},
+	}
+
+	for _, tt := range tests {
+		email := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+		if err := email.SetUnsubscribe(tt.url, tt.mailto); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if got := email.Headers["List-Unsubscribe"]; got != tt.header {
+			t.Errorf("Expected List-Unsubscribe %q, got %q", tt.header, got)
+		}
+		if _, ok := email.Headers["List-Unsubscribe-Post"]; ok != tt.post {
+			t.Errorf("Expected List-Unsubscribe-Post set to be %t for %q", tt.post, tt.url)
+		}
+		if email.Text != "Hello" {
+			t.Errorf("Expected no footer without a template, got %q", email.Text)
+		}
+		if err := email.Validate(); err != nil {
+			t.Errorf("Expected email to be valid, got: %v", err)
+		}
+	}
+}
+
+func TestSetUnsubscribeValidation(t *testing.T) {
+	tests := []struct {
+		url    string
+		mailto string
+	}{
+		{"", ""},
+		{"/unsubscribe", ""},
+		{"ftp://example.com/u", ""},
+		{"https://example.com/a,b", ""},
+		{"", "not-an-email"},
+		{"https://example.com/u", "bad@"},
+	}
+
+	for _, tt := range tests {
+		email := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+		err := email.SetUnsubscribe(tt.url, tt.mailto)
+		validationErr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("Expected ValidationError for (%q, %q), got %v", tt.url, tt.mailto, err)
+			continue
+		}
+		if len(validationErr.Errors["unsubscribe"]) == 0 {
+			t.Errorf("Expected an unsubscribe error, got %v", validationErr.Errors)
+		}
+		if len(email.Headers) != 0 {
+			t.Errorf("Expected no headers on failure, got %v", email.Headers)
+		}
+	}
+}
+
+func TestSetUnsubscribeFooterIdempotent(t *testing.T) {
+	email := NewEmailWithBoth("from@example.com", "to@example.com", "Subject",
+		"<html><body><p>Sale!</p></body></html>", "Sale!")
+
+	for i := 0; i < 2; i++ {
+		if err := email.SetUnsubscribe("https://example.com/u?a=1&b=2", "", DefaultUnsubscribeFooter); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	wantHTML := `<html><body><p>Sale!</p><p style="font-size:12px;color:#666666">Don't want these emails? <a href="https://example.com/u?a=1&amp;b=2">Unsubscribe</a>.</p></body></html>`
+	if email.HTML != wantHTML {
+		t.Errorf("Expected footer before </body> once\nwant: %s\ngot:  %s", wantHTML, email.HTML)
+	}
+
+	wantText := "Sale!\n\nDon't want these emails? Unsubscribe: https://example.com/u?a=1&b=2\n"
+	if email.Text != wantText {
+		t.Errorf("Expected text footer once, got %q", email.Text)
+	}
+
+	if len(email.Headers) != 2 {
+		t.Errorf("Expected 2 headers, got %v", email.Headers)
+	}
+}
+
+func TestSetUnsubscribeCustomFooter(t *testing.T) {
+	email := NewHTMLEmail("from@example.com", "to@example.com", "Subject", "<p>News</p>")
+	footer := UnsubscribeFooter{HTML: `<small><a href="{{url}}">Opt out</a></small>`}
+
+	if err := email.SetUnsubscribe("", "stop@example.com", footer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(email.HTML, `<small><a href="mailto:stop@example.com">Opt out</a></small>`) {
+		t.Errorf("Expected custom footer with mailto link, got %s", email.HTML)
+	}
+	if email.HasText() {
+		t.Errorf("Expected no text body to be added, got %q", email.Text)
+	}
+}
+
+func TestEmailHeadersValidation(t *testing.T) {
+	email := NewTextEmail("from@example.com", "to@example.com", "Subject", "Hello")
+	email.SetHeader("x-campaign", "spring")
+	if email.Headers["X-Campaign"] != "spring" {
+		t.Errorf("Expected canonical header name, got %v", email.Headers)
+	}
+	if err := email.Validate(); err != nil {
+		t.Errorf("Expected valid headers, got: %v", err)
+	}
+
+	email.SetHeader("X-Injected", "a\r\nBcc: victim@example.com")
+	email.SetHeader("Subject", "Other")
+	err := email.Validate()
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %T", err)
+	}
+	if len(validationErr.Errors["headers"]) != 2 {
+		t.Errorf("Expected 2 header errors, got %v", validationErr.Errors["headers"])
+	}
+}
@@ -0,0 +1,160 @@
+package poodle
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxAddressLength is the default maximum length of an address,
+// from RFC 5321
+const DefaultMaxAddressLength = 254
+
+// DefaultValidator is the address validator used by Email.Validate,
+// Address.Validate and clients without a Config.AddressValidator. It
+// accepts unquoted local parts of letters, digits and ._%+- and domain
+// names with a top-level domain of two letters or more. Replacing it
+// changes the validation of every email that is not sent by a client with
+// its own validator, including those added to an Outbox.
+var DefaultValidator = NewValidator()
+
+// The patterns are compiled on first use, so that programs that never
+// validate an address do not pay for them
+var (
+	addressPatternsOnce sync.Once
+	dotAtomPattern      *regexp.Regexp
+	quotedStringPattern *regexp.Regexp
+	domainPattern       *regexp.Regexp
+)
+
+func compileAddressPatterns() {
+	addressPatternsOnce.Do(func() {
+		dotAtomPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+$`)
+		// Printable ASCII and spaces, with quotes and backslashes escaped
+		quotedStringPattern = regexp.MustCompile(`^"(?:[ !#-\[\]-~]|\\[ -~])*"$`)
+		domainPattern = regexp.MustCompile(`^[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+	})
+}
+
+// Validator checks the format of email addresses. A Validator is
+// immutable and safe for concurrent use; create one with NewValidator.
+type Validator struct {
+	allowQuotedLocalPart bool
+	allowIPLiteral       bool
+	maxLength            int
+	maxLocalPartLength   int
+}
+
+// ValidatorOption configures a Validator
+type ValidatorOption func(*Validator)
+
+// AllowQuotedLocalPart accepts local parts in quotes, such as
+// "john smith"@example.com, as RFC 5321 allows. Quotes and backslashes
+// inside the quotes must be escaped with a backslash.
+func AllowQuotedLocalPart() ValidatorOption {
+	return func(v *Validator) {
+		v.allowQuotedLocalPart = true
+	}
+}
+
+// AllowIPLiteralDomain accepts IP addresses in brackets in place of a
+// domain name, such as user@[192.0.2.1] or user@[IPv6:2001:db8::1]
+func AllowIPLiteralDomain() ValidatorOption {
+	return func(v *Validator) {
+		v.allowIPLiteral = true
+	}
+}
+
+// MaxAddressLength sets the maximum length of an address in bytes. The
+// default is DefaultMaxAddressLength.
+func MaxAddressLength(n int) ValidatorOption {
+	return func(v *Validator) {
+		if n > 0 {
+			v.maxLength = n
+		}
+	}
+}
+
+// MaxLocalPartLength sets the maximum length in bytes of the part of an
+// address before the @, quotes included. RFC 5321 sets it at 64; by
+// default only the length of the whole address is limited.
+func MaxLocalPartLength(n int) ValidatorOption {
+	return func(v *Validator) {
+		if n > 0 {
+			v.maxLocalPartLength = n
+		}
+	}
+}
+
+// NewValidator returns a Validator with the given options. Without options
+// it behaves as DefaultValidator.
+func NewValidator(opts ...ValidatorOption) *Validator {
+	v := &Validator{maxLength: DefaultMaxAddressLength}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// IsValid reports whether email is a valid address, without a display name.
+// Surrounding whitespace is ignored.
+func (v *Validator) IsValid(email string) bool {
+	email = strings.TrimSpace(email)
+	if len(email) == 0 || len(email) > v.maxLength {
+		return false
+	}
+
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	localPart, domainPart := email[:at], email[at+1:]
+	if v.maxLocalPartLength > 0 && len(localPart) > v.maxLocalPartLength {
+		return false
+	}
+	return v.validLocalPart(localPart) && v.validDomain(domainPart)
+}
+
+// validLocalPart reports whether s is a valid part of an address before
+// the @
+func (v *Validator) validLocalPart(s string) bool {
+	compileAddressPatterns()
+	if v.allowQuotedLocalPart && strings.HasPrefix(s, `"`) {
+		return len(s) > 2 && quotedStringPattern.MatchString(s)
+	}
+	if !dotAtomPattern.MatchString(s) {
+		return false
+	}
+	return !strings.HasPrefix(s, ".") && !strings.HasSuffix(s, ".") && !strings.Contains(s, "..")
+}
+
+// validDomain reports whether s is a valid part of an address after the @
+func (v *Validator) validDomain(s string) bool {
+	if v.allowIPLiteral && strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		literal := s[1 : len(s)-1]
+		if ipv6 := strings.TrimPrefix(literal, "IPv6:"); ipv6 != literal {
+			ip := net.ParseIP(ipv6)
+			return ip != nil && strings.Contains(ipv6, ":")
+		}
+		ip := net.ParseIP(literal)
+		return ip != nil && ip.To4() != nil && !strings.Contains(literal, ":")
+	}
+
+	compileAddressPatterns()
+	if !domainPattern.MatchString(s) {
+		return false
+	}
+	// A trailing dot is valid in DNS but not in an address
+	for _, label := range strings.Split(s, ".") {
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") || len(label) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidEmail validates email address format with DefaultValidator
+func isValidEmail(email string) bool {
+	return DefaultValidator.IsValid(email)
+}
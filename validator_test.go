@@ -0,0 +1,116 @@
+package poodle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	quoted := NewValidator(AllowQuotedLocalPart())
+	ipLiteral := NewValidator(AllowIPLiteralDomain())
+	short := NewValidator(MaxAddressLength(20), MaxLocalPartLength(5))
+
+	tests := []struct {
+		name      string
+		validator *Validator
+		email     string
+		valid     bool
+	}{
+		{"default rejects quoted", DefaultValidator, `"john smith"@example.com`, false},
+		{"default rejects IP literal", DefaultValidator, "user@[192.0.2.1]", false},
+		{"default allows long local part", DefaultValidator, strings.Repeat("a", 70) + "@example.com", true},
+
+		{"quoted", quoted, `"john smith"@example.com`, true},
+		{"quoted with escapes", quoted, `"john \"j\\s\" smith"@example.com`, true},
+		{"quoted with @", quoted, `"john@home"@example.com`, true},
+		{"quoted dot-atom still valid", quoted, "john.smith@example.com", true},
+		{"quoted unescaped quote", quoted, `"john"smith"@example.com`, false},
+		{"quoted unterminated", quoted, `"john smith@example.com`, false},
+		{"quoted empty", quoted, `""@example.com`, false},
+		{"quoted line break", quoted, "\"john\r\nsmith\"@example.com", false},
+		{"quoted IP literal", quoted, `"john smith"@[192.0.2.1]`, false},
+
+		{"IPv4 literal", ipLiteral, "user@[192.0.2.1]", true},
+		{"IPv6 literal", ipLiteral, "user@[IPv6:2001:db8::1]", true},
+		{"domain still valid", ipLiteral, "user@example.com", true},
+		{"IPv6 without tag", ipLiteral, "user@[2001:db8::1]", false},
+		{"IPv4 with IPv6 tag", ipLiteral, "user@[IPv6:192.0.2.1]", false},
+		{"bad IPv4 literal", ipLiteral, "user@[192.0.2.300]", false},
+		{"unbracketed IP", ipLiteral, "user@192.0.2.1", false},
+		{"IP literal quoted local part", ipLiteral, `"john smith"@[192.0.2.1]`, false},
+
+		{"within limits", short, "abcde@example.com", true},
+		{"local part too long", short, "abcdef@example.com", false},
+		{"address too long", short, "abc@example-domain.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid := tt.validator.IsValid(tt.email); valid != tt.valid {
+				t.Errorf("Expected IsValid(%s) = %v, got %v", tt.email, tt.valid, valid)
+			}
+		})
+	}
+
+	if isValidEmail(`"john smith"@example.com`) {
+		t.Error("Expected isValidEmail to use DefaultValidator")
+	}
+}
+
+func TestConfigAddressValidator(t *testing.T) {
+	var sentTo string
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.AddressValidator = NewValidator(AllowQuotedLocalPart(), AllowIPLiteralDomain())
+	client := NewClientWithConfig(config, WithHTTPDoer(mockDoerFunc(func(req *http.Request) (*http.Response, error) {
+		var payload struct{ To string }
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		sentTo = payload.To
+		return acceptedResponse(), nil
+	})))
+
+	email := NewTextEmail("from@example.com", `"john smith"@example.com`, "Subject", "Hello")
+	if err := email.Validate(); err == nil {
+		t.Error("Expected Email.Validate to use DefaultValidator")
+	}
+	if _, err := client.SendContext(context.Background(), email); err != nil {
+		t.Fatalf("Expected the configured validator to accept the address, got %v", err)
+	}
+	if sentTo != `"john smith"@example.com` {
+		t.Errorf("Expected the quotes to be kept, got %s", sentTo)
+	}
+
+	email.ToAddress = &Address{Name: "John", Email: "john@[192.0.2.1]"}
+	if _, err := client.SendContext(context.Background(), email); err != nil {
+		t.Errorf("Expected an IP literal to be accepted, got %v", err)
+	}
+
+	email.ToAddress = &Address{Email: "john@[192.0.2.300]"}
+	var validationErr *ValidationError
+	if _, err := client.SendContext(context.Background(), email); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ValidationError, got %v", err)
+	}
+}
+
+func TestAddressFromMailQuotedLocalPart(t *testing.T) {
+	if _, err := ParseAddress(`John <"john \"js\" smith"@example.com>`); err == nil {
+		t.Fatal("Expected ParseAddress to validate with DefaultValidator")
+	}
+
+	address := addressOf(`John <"john \"js\" smith"@example.com>`)
+	if address.Email != `"john \"js\" smith"@example.com` {
+		t.Errorf("Expected the local part to be quoted again, got %s", address.Email)
+	}
+	if m := address.MailAddress(); m.Address != `john "js" smith@example.com` {
+		t.Errorf("Expected the net/mail form to be unquoted, got %s", m.Address)
+	}
+	if a := addressOf("jane.doe@example.com"); a.Email != "jane.doe@example.com" {
+		t.Errorf("Expected a dot-atom to be left alone, got %s", a.Email)
+	}
+}
@@ -0,0 +1,78 @@
+package poodle
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// modulePath is the import path of the SDK module
+const modulePath = "github.com/usepoodle/poodle-go"
+
+var (
+	moduleVersionOnce sync.Once
+	moduleVersion     string
+)
+
+// Version returns the SDK version, SDKVersion
+func Version() string {
+	return SDKVersion
+}
+
+// ModuleVersion returns the version of the SDK module recorded in the
+// binary's build information, such as "v1.2.0". For a replaced module it
+// names the replacement, as in "(devel) => ../poodle-go", so vendored and
+// patched builds are identifiable. It returns an empty string when the
+// build information is unavailable.
+func ModuleVersion() string {
+	moduleVersionOnce.Do(func() {
+		moduleVersion = moduleVersionFrom(debug.ReadBuildInfo())
+	})
+	return moduleVersion
+}
+
+// moduleVersionFrom finds the SDK module in the build information
+func moduleVersionFrom(info *debug.BuildInfo, ok bool) string {
+	if !ok || info == nil {
+		return ""
+	}
+
+	module := &info.Main
+	if module.Path != modulePath {
+		module = nil
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				module = dep
+				break
+			}
+		}
+	}
+	if module == nil {
+		return ""
+	}
+
+	if module.Replace != nil {
+		version := module.Replace.Version
+		if version == "" {
+			version = "(devel)"
+		}
+		return fmt.Sprintf("%s => %s", version, module.Replace.Path)
+	}
+	return module.Version
+}
+
+// UserAgent returns the User-Agent the client sends. When the module
+// version from the build information differs from SDKVersion, as for
+// replaced or development builds, it is appended in parentheses.
+func UserAgent() string {
+	return userAgent(ModuleVersion())
+}
+
+// userAgent builds the User-Agent for the given module version
+func userAgent(moduleVersion string) string {
+	ua := "poodle-go/" + SDKVersion
+	if moduleVersion != "" && moduleVersion != "v"+SDKVersion {
+		ua += " (" + moduleVersion + ")"
+	}
+	return ua
+}
@@ -0,0 +1,77 @@
+package poodle
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestModuleVersionFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		ok   bool
+		want string
+	}{
+		{"unavailable", nil, false, ""},
+		{"not ok", &debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "v1.0.0"}}, false, ""},
+		{"main module", &debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "(devel)"}}, true, "(devel)"},
+		{"dependency", &debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/app"},
+			Deps: []*debug.Module{{Path: modulePath, Version: "v1.2.0"}},
+		}, true, "v1.2.0"},
+		{"replaced", &debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/app"},
+			Deps: []*debug.Module{{Path: modulePath, Version: "v1.2.0", Replace: &debug.Module{Path: "../poodle-go"}}},
+		}, true, "(devel) => ../poodle-go"},
+		{"replaced with version", &debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/app"},
+			Deps: []*debug.Module{{Path: modulePath, Replace: &debug.Module{Path: "example.com/fork", Version: "v1.2.1"}}},
+		}, true, "v1.2.1 => example.com/fork"},
+		{"missing", &debug.BuildInfo{Main: debug.Module{Path: "example.com/app"}}, true, ""},
+	}
+
+	for _, tt := range tests {
+		if got := moduleVersionFrom(tt.info, tt.ok); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	tests := []struct {
+		moduleVersion string
+		want          string
+	}{
+		{"", "poodle-go/" + SDKVersion},
+		{"v" + SDKVersion, "poodle-go/" + SDKVersion},
+		{"(devel) => ../poodle-go", "poodle-go/" + SDKVersion + " ((devel) => ../poodle-go)"},
+	}
+
+	for _, tt := range tests {
+		if got := userAgent(tt.moduleVersion); got != tt.want {
+			t.Errorf("userAgent(%q) = %q, want %q", tt.moduleVersion, got, tt.want)
+		}
+	}
+
+	if Version() != SDKVersion {
+		t.Errorf("Expected Version to return %s, got %s", SDKVersion, Version())
+	}
+	if NewConfig().GetUserAgent() != UserAgent() {
+		t.Errorf("Expected the config to send %s, got %s", UserAgent(), NewConfig().GetUserAgent())
+	}
+}
+
+func TestErrorContextIncludesSDKVersion(t *testing.T) {
+	errs := []PoodleError{
+		NewValidationError("Invalid", nil),
+		NewRateLimitError("Slow down", 30, 100, 0, 0),
+		NewNetworkError("Connection failed", "https://api.usepoodle.com"),
+		&BaseError{Message: "bare"},
+	}
+
+	for _, err := range errs {
+		if version := err.Context()["sdk_version"]; version != SDKVersion {
+			t.Errorf("%T: expected sdk_version %s, got %v", err, SDKVersion, version)
+		}
+	}
+}
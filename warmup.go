@@ -0,0 +1,62 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// Warmup resolves the host of the base URL and opens a connection to it
+// with a HEAD request, completing the TLS handshake, so that the next send
+// reuses the pooled connection instead of paying for them. Any response
+// counts as success; no API key is sent. A failed lookup is a NetworkError
+// of kind NetworkErrorKindDNS and a rejected certificate or handshake one
+// of kind NetworkErrorKindTLS; a Warmup exceeding Config.Timeout fails
+// with a TimeoutError.
+//
+// With a custom HTTPDoer, the connection is pooled only if the doer pools
+// connections, as an *http.Client does.
+func (c *Client) Warmup(ctx context.Context) error {
+	return c.httpClient.warmup(ctx, c.snapshotConfig())
+}
+
+// warmup makes the HEAD request of Warmup to the active base URL
+func (c *HTTPClient) warmup(ctx context.Context, config *Config) error {
+	baseURL := c.failover.active(config)
+	if parsed, err := url.Parse(baseURL); err != nil || parsed.Host == "" {
+		return NewNetworkError("Invalid base URL", baseURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	ctx, trace := withRequestTrace(ctx, &c.transport)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL, nil)
+	if err != nil {
+		return NewNetworkError("Failed to create request", baseURL)
+	}
+	req.Header.Set("User-Agent", config.GetUserAgent())
+
+	started := c.clock.Now()
+	resp, err := c.doRetryingReset(config, req, trace)
+	if err != nil {
+		var policyErr *SecurityPolicyError
+		if errors.As(err, &policyErr) {
+			return policyErr
+		}
+		if timeoutErr := c.timeoutError(config, ctx, trace, err, started, baseURL); timeoutErr != nil {
+			return timeoutErr
+		}
+		return newNetworkErrorKind(networkErrorKind(err), "Warmup failed: "+err.Error(), baseURL)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if config.logs(LogLevelInfo) {
+		log.Printf("%s (conn_reused=%t)", requestLogLine(req, resp.StatusCode, c.clock.Now().Sub(started)), trace.reused())
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package poodle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// warmupServer is a TLS server counting the HEAD requests it receives
+func warmupServer(t *testing.T, heads *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			if r.Header.Get("Authorization") != "" {
+				t.Error("Expected the warmup request to carry no API key")
+			}
+			atomic.AddInt32(heads, 1)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"success": true, "message": "Email queued"}`))
+	}))
+	// Handshakes rejected by the client are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWarmupReusesConnection(t *testing.T) {
+	var heads int32
+	server := warmupServer(t, &heads)
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = server.URL
+	client := NewClientWithConfig(config, WithHTTPDoer(server.Client()))
+
+	if err := client.Warmup(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if heads != 1 {
+		t.Errorf("Expected 1 HEAD request, got %d", heads)
+	}
+
+	var reused, gotConn bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			gotConn, reused = true, info.Reused
+		},
+	})
+	if _, err := client.SendContext(ctx, NewTextEmail("from@example.com", "to@example.com", "Reset", "Hi")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !gotConn || !reused {
+		t.Errorf("Expected the send to reuse the warmed-up connection, got conn %t, reused %t", gotConn, reused)
+	}
+	if stats := client.TransportStats(); stats.TLSHandshakes != 1 || stats.NewConnections != 1 {
+		t.Errorf("Expected a single connection and handshake, got %+v", stats)
+	}
+}
+
+func TestWarmupErrors(t *testing.T) {
+	var heads int32
+	server := warmupServer(t, &heads)
+
+	tests := []struct {
+		name    string
+		baseURL string
+		kind    NetworkErrorKind
+	}{
+		{"untrusted certificate", server.URL, NetworkErrorKindTLS},
+		{"unknown host", "https://poodle-warmup.invalid", NetworkErrorKindDNS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.APIKey = "test_api_key"
+			config.BaseURL = tt.baseURL
+			config.Timeout = 5 * time.Second
+
+			err := NewClientWithConfig(config).Warmup(context.Background())
+			var networkErr *NetworkError
+			if !errors.As(err, &networkErr) || networkErr.Kind != tt.kind {
+				t.Errorf("Expected a NetworkError of kind %q, got %v", tt.kind, err)
+			}
+		})
+	}
+}
+
+func TestWarmupOnCreate(t *testing.T) {
+	var heads int32
+	server := warmupServer(t, &heads)
+
+	config := NewConfig()
+	config.APIKey = "test_api_key"
+	config.BaseURL = server.URL
+	config.WarmupOnCreate = true
+	NewClientWithConfig(config, WithHTTPDoer(server.Client()))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&heads) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(&heads) != 1 {
+		t.Errorf("Expected the client to warm up in the background, got %d HEAD requests", heads)
+	}
+}
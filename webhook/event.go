@@ -0,0 +1,122 @@
+// Package webhook parses and verifies the delivery events Poodle posts to
+// webhook endpoints.
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Event types sent by Poodle
+const (
+	TypeDelivered  = "email.delivered"
+	TypeBounced    = "email.bounced"
+	TypeComplained = "email.complained"
+	TypeOpened     = "email.opened"
+	TypeClicked    = "email.clicked"
+)
+
+// Event is implemented by all webhook event types
+type Event interface {
+	// EventEnvelope returns the fields common to all events
+	EventEnvelope() *Envelope
+}
+
+// Envelope holds the fields common to all events
+type Envelope struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	MessageID string    `json:"message_id"`
+	Recipient string    `json:"recipient"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// EventEnvelope implements Event
+func (e *Envelope) EventEnvelope() *Envelope {
+	return e
+}
+
+// EventDelivered is sent when the recipient's mail server accepted the email
+type EventDelivered struct {
+	Envelope
+}
+
+// EventBounced is sent when the email could not be delivered
+type EventBounced struct {
+	Envelope
+	// BounceType is "hard" for permanent failures and "soft" for temporary ones
+	BounceType string `json:"bounce_type"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// IsPermanent reports whether the bounce is a hard bounce
+func (e *EventBounced) IsPermanent() bool {
+	return e.BounceType == "hard"
+}
+
+// EventComplained is sent when the recipient marked the email as spam
+type EventComplained struct {
+	Envelope
+	FeedbackType string `json:"feedback_type,omitempty"`
+}
+
+// EventOpened is sent when the recipient opened the email
+type EventOpened struct {
+	Envelope
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
+
+// EventClicked is sent when the recipient clicked a tracked link
+type EventClicked struct {
+	Envelope
+	URL       string `json:"url"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
+
+// EventUnknown is returned for event types this package does not model yet
+type EventUnknown struct {
+	Envelope
+	Raw json.RawMessage `json:"-"`
+}
+
+// ErrMissingType is returned when an event payload has no type
+var ErrMissingType = errors.New("webhook: event type is missing")
+
+// ParseEvent decodes an event payload into its typed struct. Unrecognized
+// event types are returned as *EventUnknown rather than an error so that
+// new event types do not break existing handlers.
+func ParseEvent(body []byte) (Event, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("webhook: invalid event payload: %w", err)
+	}
+
+	var event Event
+	switch envelope.Type {
+	case "":
+		return nil, ErrMissingType
+	case TypeDelivered:
+		event = &EventDelivered{}
+	case TypeBounced:
+		event = &EventBounced{}
+	case TypeComplained:
+		event = &EventComplained{}
+	case TypeOpened:
+		event = &EventOpened{}
+	case TypeClicked:
+		event = &EventClicked{}
+	default:
+		raw := make(json.RawMessage, len(body))
+		copy(raw, body)
+		return &EventUnknown{Envelope: envelope, Raw: raw}, nil
+	}
+
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, fmt.Errorf("webhook: invalid %s payload: %w", envelope.Type, err)
+	}
+	return event, nil
+}
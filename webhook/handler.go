@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MaxBodyBytes is the largest webhook payload the Handler accepts
+const MaxBodyBytes = 1 << 20
+
+// Handler is an http.Handler that verifies, parses and dispatches webhook
+// events. It responds with 400 for unverifiable or malformed requests, 500
+// when a callback returns an error so that Poodle retries the delivery, and
+// 200 otherwise. Events without a matching callback are acknowledged.
+type Handler struct {
+	// Secret is the endpoint's signing secret
+	Secret string
+	// Tolerance is the maximum event age; zero uses DefaultTolerance
+	Tolerance time.Duration
+
+	OnDelivered  func(ctx context.Context, event *EventDelivered) error
+	OnBounced    func(ctx context.Context, event *EventBounced) error
+	OnComplained func(ctx context.Context, event *EventComplained) error
+	OnOpened     func(ctx context.Context, event *EventOpened) error
+	OnClicked    func(ctx context.Context, event *EventClicked) error
+	// OnEvent is called for every event after its typed callback, including
+	// unknown event types
+	OnEvent func(ctx context.Context, event Event) error
+
+	// now is the time source used for signature verification
+	now func() time.Time
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > MaxBodyBytes {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	tolerance := h.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+
+	if err := VerifySignatureAt(body, r.Header.Get(SignatureHeader), h.Secret, tolerance, now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	event, err := ParseEvent(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dispatch(r.Context(), event); err != nil {
+		http.Error(w, "event handling failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// dispatch invokes the callbacks registered for the event
+func (h *Handler) dispatch(ctx context.Context, event Event) error {
+	var err error
+	switch e := event.(type) {
+	case *EventDelivered:
+		if h.OnDelivered != nil {
+			err = h.OnDelivered(ctx, e)
+		}
+	case *EventBounced:
+		if h.OnBounced != nil {
+			err = h.OnBounced(ctx, e)
+		}
+	case *EventComplained:
+		if h.OnComplained != nil {
+			err = h.OnComplained(ctx, e)
+		}
+	case *EventOpened:
+		if h.OnOpened != nil {
+			err = h.OnOpened(ctx, e)
+		}
+	case *EventClicked:
+		if h.OnClicked != nil {
+			err = h.OnClicked(ctx, e)
+		}
+	}
+
+	if h.OnEvent != nil {
+		err = errors.Join(err, h.OnEvent(ctx, event))
+	}
+
+	return err
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the request header carrying the event signature
+const SignatureHeader = "Poodle-Signature"
+
+// DefaultTolerance is the maximum age of a signed event before it is
+// rejected as a possible replay
+const DefaultTolerance = 5 * time.Minute
+
+// Signature verification errors
+var (
+	ErrInvalidHeader    = errors.New("webhook: signature header is malformed")
+	ErrNoValidSignature = errors.New("webhook: no signature matches the payload")
+	ErrTimestampTooOld  = errors.New("webhook: signature timestamp is outside the tolerance")
+	ErrMissingSecret    = errors.New("webhook: signing secret is empty")
+)
+
+// Sign computes the signature header value for a payload. The signature is
+// the hex-encoded HMAC-SHA256 of "<unix timestamp>.<payload>" keyed with the
+// endpoint's signing secret, sent as "t=<unix timestamp>,v1=<signature>".
+func Sign(payload []byte, secret string, timestamp time.Time) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + computeSignature(payload, secret, unix)
+}
+
+// VerifySignature verifies the signature header of a payload using
+// DefaultTolerance and the current time
+func VerifySignature(payload []byte, header, secret string) error {
+	return VerifySignatureAt(payload, header, secret, DefaultTolerance, time.Now())
+}
+
+// VerifySignatureAt verifies the signature header of a payload at the given
+// time. A header may carry several v1 signatures during secret rotation;
+// the payload is accepted if any of them matches. Signatures are compared
+// in constant time. A non-positive tolerance disables the timestamp check.
+func VerifySignatureAt(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	if secret == "" {
+		return ErrMissingSecret
+	}
+
+	timestamp, signatures, err := parseSignatureHeader(header)
+	if err != nil {
+		return err
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidHeader
+	}
+
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(unix, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrTimestampTooOld
+		}
+	}
+
+	expected := []byte(computeSignature(payload, secret, timestamp))
+	for _, signature := range signatures {
+		if hmac.Equal(expected, []byte(signature)) {
+			return nil
+		}
+	}
+
+	return ErrNoValidSignature
+}
+
+// parseSignatureHeader splits a header into its timestamp and v1 signatures
+func parseSignatureHeader(header string) (string, []string, error) {
+	var timestamp string
+	var signatures []string
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			return "", nil, ErrInvalidHeader
+		}
+
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if timestamp == "" || len(signatures) == 0 {
+		return "", nil, ErrInvalidHeader
+	}
+
+	return timestamp, signatures, nil
+}
+
+// computeSignature returns the hex-encoded HMAC-SHA256 of the signed payload
+func computeSignature(payload []byte, secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
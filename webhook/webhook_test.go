@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test_secret"
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		check func(t *testing.T, event Event)
+	}{
+		{
+			name: "Delivered",
+			body: `{"id":"evt_1","type":"email.delivered","message_id":"msg_1","recipient":"to@example.com","timestamp":"2024-03-01T12:00:00Z"}`,
+			check: func(t *testing.T, event Event) {
+				delivered, ok := event.(*EventDelivered)
+				if !ok {
+					t.Fatalf("Expected *EventDelivered, got %T", event)
+				}
+				if delivered.MessageID != "msg_1" || delivered.Recipient != "to@example.com" {
+					t.Errorf("Unexpected envelope: %+v", delivered.Envelope)
+				}
+				if !delivered.Timestamp.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+					t.Errorf("Unexpected timestamp: %v", delivered.Timestamp)
+				}
+			},
+		},
+		{
+			name: "Bounced",
+			body: `{"id":"evt_2","type":"email.bounced","message_id":"msg_2","recipient":"to@example.com","timestamp":"2024-03-01T12:00:00Z","bounce_type":"hard","reason":"mailbox does not exist"}`,
+			check: func(t *testing.T, event Event) {
+				bounced, ok := event.(*EventBounced)
+				if !ok {
+					t.Fatalf("Expected *EventBounced, got %T", event)
+				}
+				if !bounced.IsPermanent() || bounced.Reason != "mailbox does not exist" {
+					t.Errorf("Unexpected bounce: %+v", bounced)
+				}
+			},
+		},
+		{
+			name: "Complained",
+			body: `{"id":"evt_3","type":"email.complained","message_id":"msg_3","feedback_type":"abuse"}`,
+			check: func(t *testing.T, event Event) {
+				if complained, ok := event.(*EventComplained); !ok || complained.FeedbackType != "abuse" {
+					t.Errorf("Unexpected event: %#v", event)
+				}
+			},
+		},
+		{
+			name: "Opened",
+			body: `{"id":"evt_4","type":"email.opened","message_id":"msg_4","user_agent":"Mail/1.0"}`,
+			check: func(t *testing.T, event Event) {
+				if opened, ok := event.(*EventOpened); !ok || opened.UserAgent != "Mail/1.0" {
+					t.Errorf("Unexpected event: %#v", event)
+				}
+			},
+		},
+		{
+			name: "Clicked",
+			body: `{"id":"evt_5","type":"email.clicked","message_id":"msg_5","url":"https://example.com"}`,
+			check: func(t *testing.T, event Event) {
+				if clicked, ok := event.(*EventClicked); !ok || clicked.URL != "https://example.com" {
+					t.Errorf("Unexpected event: %#v", event)
+				}
+			},
+		},
+		{
+			name: "Unknown type",
+			body: `{"id":"evt_6","type":"email.deferred","message_id":"msg_6","attempts":3}`,
+			check: func(t *testing.T, event Event) {
+				unknown, ok := event.(*EventUnknown)
+				if !ok {
+					t.Fatalf("Expected *EventUnknown, got %T", event)
+				}
+				if unknown.Type != "email.deferred" || !bytes.Contains(unknown.Raw, []byte(`"attempts":3`)) {
+					t.Errorf("Unexpected unknown event: %+v", unknown)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ParseEvent([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if event.EventEnvelope().ID == "" {
+				t.Error("Expected envelope ID to be set")
+			}
+			tt.check(t, event)
+		})
+	}
+}
+
+func TestParseEventErrors(t *testing.T) {
+	if _, err := ParseEvent([]byte(`not json`)); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+	if _, err := ParseEvent([]byte(`{"id":"evt_1"}`)); !errors.Is(err, ErrMissingType) {
+		t.Errorf("Expected ErrMissingType, got: %v", err)
+	}
+	if _, err := ParseEvent([]byte(`{"type":"email.bounced","timestamp":"yesterday"}`)); err == nil {
+		t.Error("Expected error for invalid timestamp")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"email.delivered"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign(payload, testSecret, now)
+
+	tests := []struct {
+		name     string
+		payload  []byte
+		header   string
+		secret   string
+		now      time.Time
+		expected error
+	}{
+		{"Valid", payload, header, testSecret, now, nil},
+		{"Valid within tolerance", payload, header, testSecret, now.Add(4 * time.Minute), nil},
+		{"Rotated secrets", payload, header + ",v1=deadbeef", testSecret, now, nil},
+		{"Tampered payload", []byte(`{"id":"evt_2"}`), header, testSecret, now, ErrNoValidSignature},
+		{"Wrong secret", payload, header, "whsec_other", now, ErrNoValidSignature},
+		{"Replayed", payload, header, testSecret, now.Add(10 * time.Minute), ErrTimestampTooOld},
+		{"From the future", payload, header, testSecret, now.Add(-10 * time.Minute), ErrTimestampTooOld},
+		{"Missing header", payload, "", testSecret, now, ErrInvalidHeader},
+		{"Missing signature", payload, "t=1700000000", testSecret, now, ErrInvalidHeader},
+		{"Invalid timestamp", payload, "t=abc,v1=00", testSecret, now, ErrInvalidHeader},
+		{"Missing secret", payload, header, "", now, ErrMissingSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignatureAt(tt.payload, tt.header, tt.secret, DefaultTolerance, tt.now)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	payload := []byte(`{"id":"evt_1","type":"email.bounced","message_id":"msg_1","bounce_type":"soft"}`)
+
+	var bounced *EventBounced
+	var all []Event
+	handler := &Handler{
+		Secret: testSecret,
+		OnBounced: func(ctx context.Context, event *EventBounced) error {
+			bounced = event
+			return nil
+		},
+		OnEvent: func(ctx context.Context, event Event) error {
+			all = append(all, event)
+			return nil
+		},
+		now: func() time.Time { return now },
+	}
+
+	post := func(body []byte, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/poodle", bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, signature)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := post(payload, Sign(payload, testSecret, now)); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if bounced == nil || bounced.MessageID != "msg_1" || len(all) != 1 {
+		t.Errorf("Expected bounce callback to be invoked, got %+v, %d events", bounced, len(all))
+	}
+
+	if code := post(payload, Sign(payload, "whsec_other", now)); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid signature, got %d", code)
+	}
+
+	invalid := []byte(`{"id":"evt_2"}`)
+	if code := post(invalid, Sign(invalid, testSecret, now)); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid event, got %d", code)
+	}
+
+	handler.OnBounced = func(ctx context.Context, event *EventBounced) error {
+		return errors.New("database unavailable")
+	}
+	if code := post(payload, Sign(payload, testSecret, now)); code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 when the callback fails, got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/poodle", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package poodle
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultTextWidth is the line width used by WrapText when width is not
+// positive, following the RFC 5322 recommendation of 78 characters
+const DefaultTextWidth = 78
+
+var (
+	// quotePrefix matches the quote markers of a replied-to line, such as
+	// "> " or ">> "
+	quotePrefix = regexp.MustCompile(`^(?:> ?)+`)
+	// listMarker matches a bullet or numbered list marker and the space
+	// after it
+	listMarker = regexp.MustCompile(`^(?:[-*+•]|\d{1,3}[.)])[ \t]+`)
+)
+
+// WrapText wraps lines longer than width characters at word boundaries.
+// Width is measured in runes; a width of zero or less uses
+// DefaultTextWidth. Existing line breaks are kept, lines that fit are left
+// unchanged, quoted lines keep their "> " prefix and list items are
+// continued aligned with the item text. Words longer than the width, such
+// as URLs, are never broken and get a line of their own.
+func WrapText(s string, width int) string {
+	if width <= 0 {
+		width = DefaultTextWidth
+	}
+
+	newline := "\n"
+	if strings.Contains(s, "\r\n") {
+		newline = "\r\n"
+	}
+
+	lines := strings.Split(s, newline)
+	wrapped := make([]string, 0, len(lines))
+	for _, line := range lines {
+		wrapped = append(wrapped, wrapLine(line, width)...)
+	}
+	return strings.Join(wrapped, newline)
+}
+
+// wrapLine wraps a single line
+func wrapLine(line string, width int) []string {
+	if utf8.RuneCountInString(line) <= width {
+		return []string{line}
+	}
+
+	quote := quotePrefix.FindString(line)
+	rest := line[len(quote):]
+	indent := rest[:len(rest)-len(strings.TrimLeft(rest, " \t"))]
+	rest = rest[len(indent):]
+	marker := listMarker.FindString(rest)
+	rest = rest[len(marker):]
+
+	words := strings.Fields(rest)
+	if len(words) == 0 {
+		return []string{line}
+	}
+
+	prefix := quote + indent + marker
+	continuation := quote + indent + strings.Repeat(" ", utf8.RuneCountInString(marker))
+
+	var lines []string
+	current := prefix + words[0]
+	currentWidth := utf8.RuneCountInString(current)
+	for _, word := range words[1:] {
+		wordWidth := utf8.RuneCountInString(word)
+		if currentWidth+1+wordWidth <= width {
+			current += " " + word
+			currentWidth += 1 + wordWidth
+			continue
+		}
+		lines = append(lines, current)
+		current = continuation + word
+		currentWidth = utf8.RuneCountInString(current)
+	}
+	return append(lines, current)
+}
+
+// FormatText wraps the text body with WrapText
+func (e *Email) FormatText(width int) *Email {
+	e.Text = WrapText(e.Text, width)
+	return e
+}
@@ -0,0 +1,82 @@
+package poodle
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestWrapTextGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "wrap", "*.input"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("Expected golden inputs, got %v (%v)", inputs, err)
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".input")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := WrapText(string(data), 60)
+			golden := strings.TrimSuffix(input, ".input") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("WrapText output differs from %s\nwant:\n%s\ngot:\n%s", golden, want, got)
+			}
+		})
+	}
+}
+
+func TestWrapTextWidth(t *testing.T) {
+	text := strings.Repeat("word ", 40) + "https://example.com/" + strings.Repeat("x", 100)
+
+	for _, line := range strings.Split(WrapText(text, 0), "\n") {
+		if width := utf8.RuneCountInString(line); width > DefaultTextWidth && strings.Contains(line, " ") {
+			t.Errorf("Expected lines of at most %d characters, got %d: %q", DefaultTextWidth, width, line)
+		}
+		if strings.Contains(line, "example.com") && !strings.HasSuffix(line, strings.Repeat("x", 100)) {
+			t.Errorf("Expected URL not to be broken, got %q", line)
+		}
+	}
+}
+
+func TestWrapTextMultiByte(t *testing.T) {
+	// Ten 5-rune words are 59 runes but 119 bytes
+	text := strings.TrimSpace(strings.Repeat("éééé€ ", 10))
+	if got := WrapText(text, 60); got != text {
+		t.Errorf("Expected width to be measured in runes, got %q", got)
+	}
+}
+
+func TestWrapTextPreservesShortLines(t *testing.T) {
+	text := "Hello,\r\n\r\n  indented   text\r\n> quoted\r\n"
+	if got := WrapText(text, 20); got != text {
+		t.Errorf("Expected short lines unchanged, got %q", got)
+	}
+}
+
+func TestEmailFormatText(t *testing.T) {
+	email := NewTextEmail("from@example.com", "to@example.com", "Subject", strings.Repeat("lorem ipsum ", 20))
+	email.FormatText(40)
+
+	if !strings.Contains(email.Text, "\n") {
+		t.Errorf("Expected text to be wrapped, got %q", email.Text)
+	}
+}